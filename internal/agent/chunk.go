@@ -0,0 +1,229 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dotcommander/yai/internal/errs"
+)
+
+// SplitChunks splits text into pieces of at most size bytes. Each piece ends
+// at the last paragraph break, line break, or space that fits, in that
+// order, and never inside a rune. A size of 0 or less returns text whole.
+func SplitChunks(text string, size int) []string {
+	if size <= 0 || len(text) <= size {
+		return []string{text}
+	}
+	var chunks []string
+	for len(text) > size {
+		cut := chunkCut(text, size)
+		if chunk := strings.TrimSpace(text[:cut]); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		text = text[cut:]
+	}
+	if chunk := strings.TrimSpace(text); chunk != "" {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// chunkCut returns where to end a chunk of text of at most size bytes. A
+// boundary in the first half is not worth the short chunk it makes.
+func chunkCut(text string, size int) int {
+	for _, sep := range []string{"\n\n", "\n", " "} {
+		if i := strings.LastIndex(text[:size], sep); i > size/2 {
+			return i + len(sep)
+		}
+	}
+	cut := size
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	if cut == 0 {
+		// size is smaller than the first rune.
+		_, cut = utf8.DecodeRuneInString(text)
+	}
+	return cut
+}
+
+// Chunk strategies: map-reduce answers every part on its own and combines
+// the answers; refine answers the first part and improves the answer with
+// each part after it.
+const (
+	ChunkMapReduce = "map-reduce"
+	ChunkRefine    = "refine"
+)
+
+// ChunkStrategies are the chunk-strategy values, for completion.
+var ChunkStrategies = []string{ChunkMapReduce, ChunkRefine}
+
+// maxChunkRounds bounds how often map-reduce combines the answers of
+// groups of parts before the answers fit in the last request.
+const maxChunkRounds = 3
+
+// The prompts of chunked input. The request comes first in those of the
+// last request, whose first line titles the conversation.
+const (
+	chunkPartPrompt    = "This is part %d of %d of a longer input. %s"
+	chunkCombinePrompt = "%s\n\nThe following are the results for consecutive parts of one longer input, in order. " +
+		"Use them to answer the request above as if you had been given the whole input."
+	chunkMergePrompt = "The following are the results for consecutive parts of one longer input, in order. " +
+		"Combine them into one, keeping their details."
+	chunkRefinePrompt = "%s\n\nThis is part %d of %d of a longer input. Below is your answer to the request above " +
+		"for the parts before this one. Improve that answer with this part, keeping what still holds, and give only " +
+		"the new answer.\n\nAnswer so far:\n%s"
+)
+
+// ChunkOptions says how [Service.Chunk] splits and answers input.
+type ChunkOptions struct {
+	// Size is the most bytes of input sent in one request.
+	Size int
+	// Strategy is ChunkMapReduce, the default, or ChunkRefine.
+	Strategy string
+	// PartInstruction, when set, is asked of each part by map-reduce
+	// instead of the instruction, such as a detailed summary for a short
+	// one.
+	PartInstruction string
+	// Progress, when not nil, is called before each request with its index
+	// and the number of requests of the round.
+	Progress func(i, n int)
+}
+
+// ChunkedPrompt is the last request of input answered in parts: the prompt
+// and the input to send with it, left to the caller so its answer can
+// stream like any other, and the requests made before it.
+type ChunkedPrompt struct {
+	Prompt      string
+	Input       string
+	Completions []Completion
+}
+
+// Chunk answers instruction about input too long for one request. Input
+// that fits in opts.Size is returned as it is.
+func (s *Service) Chunk(ctx context.Context, instruction, input string, opts ChunkOptions) (ChunkedPrompt, error) {
+	if opts.Size <= 0 || len(input) <= opts.Size {
+		return ChunkedPrompt{Prompt: instruction, Input: input}, nil
+	}
+	if err := CheckChunkStrategy(opts.Strategy); err != nil {
+		return ChunkedPrompt{}, err
+	}
+	if opts.Strategy == ChunkRefine {
+		return s.refine(ctx, instruction, input, opts)
+	}
+	return s.mapReduce(ctx, instruction, input, opts)
+}
+
+// CheckChunkStrategy returns an error when strategy is not a chunk-strategy
+// value. Empty is map-reduce.
+func CheckChunkStrategy(strategy string) error {
+	switch strategy {
+	case "", ChunkMapReduce, ChunkRefine:
+		return nil
+	default:
+		return errs.Wrap(
+			errs.UserErrorf("chunk-strategy is %q; want %s or %s", strategy, ChunkMapReduce, ChunkRefine),
+			"Invalid chunk settings.",
+		)
+	}
+}
+
+func (s *Service) mapReduce(ctx context.Context, instruction, input string, opts ChunkOptions) (ChunkedPrompt, error) {
+	out := ChunkedPrompt{Prompt: fmt.Sprintf(chunkCombinePrompt, instruction)}
+	partInstruction := cmp.Or(opts.PartInstruction, instruction)
+	for round := 1; ; round++ {
+		chunks := SplitChunks(input, opts.Size)
+		results, err := s.MapChunks(ctx, chunks, func(i int, chunk string) string {
+			if round > 1 {
+				// Later rounds combine the results of groups of parts.
+				return chunkMergePrompt + "\n\n" + chunk
+			}
+			return fmt.Sprintf(chunkPartPrompt, i+1, len(chunks), partInstruction) + "\n\n" + chunk
+		}, opts.Progress)
+		out.Completions = append(out.Completions, results...)
+		if err != nil {
+			return out, err
+		}
+		input = joinParts(results)
+		if len(input) <= opts.Size || round == maxChunkRounds {
+			break
+		}
+	}
+	out.Input = input
+	return out, nil
+}
+
+// refine answers all parts but the last; the last request, which the
+// caller makes, improves the answer with it.
+func (s *Service) refine(ctx context.Context, instruction, input string, opts ChunkOptions) (ChunkedPrompt, error) {
+	chunks := SplitChunks(input, opts.Size)
+	n := len(chunks)
+	if n < 2 {
+		return ChunkedPrompt{Prompt: instruction, Input: input}, nil
+	}
+	svc := s.partService()
+	var out ChunkedPrompt
+	answer := ""
+	for i, chunk := range chunks[:n-1] {
+		if opts.Progress != nil {
+			opts.Progress(i, n)
+		}
+		prompt := fmt.Sprintf(chunkPartPrompt, 1, n, instruction)
+		if i > 0 {
+			prompt = fmt.Sprintf(chunkRefinePrompt, instruction, i+1, n, answer)
+		}
+		res, err := svc.Complete(ctx, nil, prompt+"\n\n"+chunk)
+		if err != nil {
+			return out, err
+		}
+		out.Completions = append(out.Completions, res)
+		answer = strings.TrimSpace(res.Response)
+	}
+	out.Prompt = fmt.Sprintf(chunkRefinePrompt, instruction, n, n, answer)
+	out.Input = chunks[n-1]
+	return out, nil
+}
+
+// joinParts joins the answers for the parts of an input, numbered.
+func joinParts(results []Completion) string {
+	parts := make([]string, len(results))
+	for i, res := range results {
+		parts[i] = fmt.Sprintf("Part %d:\n%s", i+1, strings.TrimSpace(res.Response))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// MapChunks asks the model prompt(i, chunk) for every chunk, one after the
+// other, and returns the completions in order. progress, when not nil, is
+// called before each request with its index and the number of chunks.
+func (s *Service) MapChunks(ctx context.Context, chunks []string, prompt func(i int, chunk string) string, progress func(i, n int)) ([]Completion, error) {
+	svc := s.partService()
+	out := make([]Completion, 0, len(chunks))
+	for i, chunk := range chunks {
+		if progress != nil {
+			progress(i, len(chunks))
+		}
+		res, err := svc.Complete(ctx, nil, prompt(i, chunk))
+		if err != nil {
+			return out, err
+		}
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+// partService is the service answering the parts of an input. Roles,
+// format text, reply-language, and MCP tools are left out: they shape the
+// last answer, not the parts it is made of.
+func (s *Service) partService() *Service {
+	cfg := *s.cfg
+	cfg.Role = ""
+	cfg.Format = false
+	cfg.ReplyLanguage = ""
+	cfg.Prefix = ""
+	cfg.MCPDisable = []string{"*"}
+	return New(&cfg, nil, nil, s.clientFactory)
+}
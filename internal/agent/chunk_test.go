@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/provider"
+	"github.com/dotcommander/yai/internal/stream"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitChunks(t *testing.T) {
+	require.Equal(t, []string{"short"}, SplitChunks("short", 100))
+	require.Equal(t, []string{"no limit"}, SplitChunks("no limit", 0))
+
+	text := "first paragraph here.\n\nsecond paragraph, a bit longer.\nand a line\n\nthird."
+	chunks := SplitChunks(text, 40)
+	require.Equal(t, []string{"first paragraph here.", "second paragraph, a bit longer.", "and a line\n\nthird."}, chunks)
+
+	chunks = SplitChunks(strings.Repeat("é", 10), 5)
+	for _, chunk := range chunks {
+		require.LessOrEqual(t, len(chunk), 5)
+		require.True(t, strings.Trim(chunk, "é") == "", "runes are not cut: %q", chunk)
+	}
+	require.Equal(t, strings.Repeat("é", 10), strings.Join(chunks, ""))
+}
+
+// recordingClient is a scriptedClient that keeps the requests it gets. Its
+// streams report the messages of their request followed by the answer.
+type recordingClient struct {
+	scriptedClient
+	requests []proto.Request
+}
+
+func (c *recordingClient) Request(ctx context.Context, req proto.Request) stream.Stream {
+	c.requests = append(c.requests, req)
+	st := c.scriptedClient.Request(ctx, req).(*scriptedStream)
+	st.messages = append(slices.Clone(req.Messages), proto.Message{
+		Role:    proto.RoleAssistant,
+		Content: strings.Join(st.chunks, ""),
+	})
+	return st
+}
+
+func TestMapChunks(t *testing.T) {
+	client := &recordingClient{scriptedClient: scriptedClient{streams: []*scriptedStream{
+		{chunks: []string{"one"}},
+		{chunks: []string{"two"}},
+	}}}
+	cfg := testCompleteConfig()
+	cfg.Format = true
+	cfg.Role = "missing-role"
+	svc := New(cfg, nil, nil, func(provider.Config) (stream.Client, error) {
+		return client, nil
+	})
+
+	var progress []string
+	res, err := svc.MapChunks(context.Background(), []string{"a", "b"}, func(i int, chunk string) string {
+		return fmt.Sprintf("part %d: %s", i+1, chunk)
+	}, func(i, n int) {
+		progress = append(progress, fmt.Sprintf("%d/%d", i+1, n))
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	require.Equal(t, "one", res[0].Response)
+	require.Equal(t, "two", res[1].Response)
+	require.Equal(t, []string{"1/2", "2/2"}, progress)
+	require.Len(t, client.requests, 2)
+	require.Equal(t, []proto.Message{{Role: proto.RoleUser, Content: "part 2: b"}}, withoutTimes(client.requests[1].Messages),
+		"roles and format text are left out")
+	require.Equal(t, "missing-role", cfg.Role, "caller config must not change")
+}
+
+func withoutTimes(msgs []proto.Message) []proto.Message {
+	out := make([]proto.Message, len(msgs))
+	for i, msg := range msgs {
+		out[i] = proto.Message{Role: msg.Role, Content: msg.Content}
+	}
+	return out
+}
+
+func TestChunk(t *testing.T) {
+	input := "first part of the input.\n\nsecond part of the input.\n\nthird part of the input."
+	newService := func(answers ...string) (*Service, *recordingClient) {
+		client := &recordingClient{}
+		for _, answer := range answers {
+			client.streams = append(client.streams, &scriptedStream{chunks: []string{answer}})
+		}
+		return New(testCompleteConfig(), nil, nil, func(provider.Config) (stream.Client, error) {
+			return client, nil
+		}), client
+	}
+	lastPrompt := func(c *recordingClient) string {
+		msgs := c.requests[len(c.requests)-1].Messages
+		return msgs[len(msgs)-1].Content
+	}
+
+	t.Run("fits", func(t *testing.T) {
+		svc, client := newService()
+		res, err := svc.Chunk(context.Background(), "count words", input, ChunkOptions{Size: len(input)})
+		require.NoError(t, err)
+		require.Equal(t, ChunkedPrompt{Prompt: "count words", Input: input}, res)
+		require.Empty(t, client.requests)
+	})
+
+	t.Run("map-reduce", func(t *testing.T) {
+		svc, client := newService("4", "4", "4")
+		res, err := svc.Chunk(context.Background(), "count words", input, ChunkOptions{Size: 40, PartInstruction: "list words"})
+		require.NoError(t, err)
+		require.Len(t, res.Completions, 3)
+		require.Equal(t, "This is part 3 of 3 of a longer input. list words\n\nthird part of the input.", lastPrompt(client))
+		require.True(t, strings.HasPrefix(res.Prompt, "count words\n\n"), "the request titles the conversation")
+		require.Equal(t, "Part 1:\n4\n\nPart 2:\n4\n\nPart 3:\n4", res.Input)
+	})
+
+	t.Run("map-reduce combines long results", func(t *testing.T) {
+		long := strings.Repeat("word ", 10)
+		svc, client := newService(long, long, "combined", "combined")
+		res, err := svc.Chunk(context.Background(), "count words", input, ChunkOptions{Size: 70})
+		require.NoError(t, err)
+		require.Len(t, res.Completions, 4)
+		require.True(t, strings.HasPrefix(lastPrompt(client), chunkMergePrompt))
+		require.Equal(t, "Part 1:\ncombined\n\nPart 2:\ncombined", res.Input)
+	})
+
+	t.Run("refine", func(t *testing.T) {
+		var progress []string
+		svc, client := newService("4 words", "8 words")
+		res, err := svc.Chunk(context.Background(), "count words", input, ChunkOptions{
+			Size:     30,
+			Strategy: ChunkRefine,
+			Progress: func(i, n int) { progress = append(progress, fmt.Sprintf("%d/%d", i+1, n)) },
+		})
+		require.NoError(t, err)
+		require.Len(t, res.Completions, 2)
+		require.Equal(t, []string{"1/3", "2/3"}, progress)
+		require.Contains(t, lastPrompt(client), "Answer so far:\n4 words\n\nsecond part of the input.")
+		require.Contains(t, res.Prompt, "This is part 3 of 3")
+		require.Contains(t, res.Prompt, "Answer so far:\n8 words")
+		require.Equal(t, "third part of the input.", res.Input)
+	})
+
+	t.Run("invalid strategy", func(t *testing.T) {
+		svc, _ := newService()
+		_, err := svc.Chunk(context.Background(), "count words", input, ChunkOptions{Size: 30, Strategy: "stuff"})
+		require.ErrorContains(t, err, `chunk-strategy is "stuff"; want map-reduce or refine`)
+	})
+}
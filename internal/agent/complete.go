@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/stream"
+)
+
+// Completion is the outcome of a request that was drained without a UI.
+type Completion struct {
+	Response string
+	Messages []proto.Message
+	Model    config.Model
+	Usage    proto.Usage
+	Meta     proto.ResponseMeta
+	Warnings []string
+	Retries  int
+}
+
+// EventType identifies what an [Event] reports.
+type EventType string
+
+// Event types reported by [Service.CompleteStream].
+const (
+	EventChunk    EventType = "chunk"
+	EventToolCall EventType = "tool_call"
+	EventRetry    EventType = "retry"
+)
+
+// Event is a step of a streaming completion. Content is set for chunks, Tool
+// for tool calls, and Err for retries, after which any content already
+// reported should be discarded.
+type Event struct {
+	Type    EventType
+	Content string
+	Tool    proto.ToolCallStatus
+	Err     error
+}
+
+// Complete runs prompt on top of history until the model stops, executing
+// tool calls between steps. Retryable errors are retried within the retry
+// budgets using the same decisions as the interactive UI.
+func (s *Service) Complete(ctx context.Context, history []proto.Message, prompt string) (Completion, error) {
+	return s.CompleteStream(ctx, history, prompt, nil)
+}
+
+// CompleteStream is [Service.Complete] that also reports every chunk, tool
+// call, and retry to onEvent as it happens. onEvent may be nil.
+func (s *Service) CompleteStream(ctx context.Context, history []proto.Message, prompt string, onEvent func(Event)) (Completion, error) {
+	if onEvent == nil {
+		onEvent = func(Event) {}
+	}
+	var out Completion
+	budget := NewRetryBudget(s.cfg)
+	for {
+		res, err := s.completeOnce(ctx, history, prompt, &out, onEvent)
+		if err == nil {
+			return res, nil
+		}
+
+		action := s.ActionForStreamError(err, out.Model, prompt, s.cfg.NoLimit)
+		if action.ModelOverride != "" {
+			UseFallback(s.cfg, action.ModelOverride)
+		}
+		if !action.Retry {
+			if action.Err.Err == nil {
+				return out, errs.Error{Err: err}
+			}
+			return out, action.Err
+		}
+		delay, ok := budget.Next(action)
+		out.Retries = budget.Total()
+		if !ok {
+			return out, action.Err
+		}
+		if action.Prompt != "" {
+			prompt = action.Prompt
+		}
+		onEvent(Event{Type: EventRetry, Err: err})
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return out, ctx.Err() //nolint:wrapcheck
+		}
+	}
+}
+
+func (s *Service) completeOnce(ctx context.Context, history []proto.Message, prompt string, out *Completion, onEvent func(Event)) (Completion, error) {
+	if s.cfg.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.RequestTimeout)
+		defer cancel()
+	}
+
+	res, err := s.StreamContinue(ctx, history, prompt)
+	if err != nil {
+		return *out, err
+	}
+	defer res.Stream.Close() //nolint:errcheck
+	out.Model = res.Model
+
+	response, err := drainStream(res.Stream, onEvent)
+	out.Warnings = append(out.Warnings, res.Stream.DrainWarnings()...)
+	if err != nil {
+		return *out, err
+	}
+
+	done := *out
+	done.Response = response
+	done.Messages = res.Stream.Messages()
+	done.Usage = res.Stream.Usage()
+	done.Meta = res.Stream.ResponseMeta()
+	return done, nil
+}
+
+// drainStream reads st to the end, running any pending tool calls between
+// steps, and returns the concatenated text content.
+func drainStream(st stream.Stream, onEvent func(Event)) (string, error) {
+	var sb strings.Builder
+	for {
+		for st.Next() {
+			chunk, err := st.Current()
+			if err != nil && !errors.Is(err, stream.ErrNoContent) {
+				return "", err //nolint:wrapcheck
+			}
+			if chunk.Content != "" {
+				sb.WriteString(chunk.Content)
+				onEvent(Event{Type: EventChunk, Content: chunk.Content})
+			}
+		}
+		if err := st.Err(); err != nil {
+			return "", err //nolint:wrapcheck
+		}
+		statuses := st.CallTools()
+		if len(statuses) == 0 {
+			return sb.String(), nil
+		}
+		for _, status := range statuses {
+			onEvent(Event{Type: EventToolCall, Tool: status})
+		}
+	}
+}
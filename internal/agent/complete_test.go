@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/provider"
+	"github.com/dotcommander/yai/internal/stream"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedStream replays chunks and then ends with err.
+type scriptedStream struct {
+	chunks   []string
+	pos      int
+	err      error
+	usage    proto.Usage
+	meta     proto.ResponseMeta
+	messages []proto.Message
+}
+
+func (s *scriptedStream) Next() bool {
+	if s.pos >= len(s.chunks) {
+		return false
+	}
+	s.pos++
+	return true
+}
+
+func (s *scriptedStream) Current() (proto.Chunk, error) {
+	return proto.Chunk{Content: s.chunks[s.pos-1]}, nil
+}
+func (s *scriptedStream) Close() error                      { return nil }
+func (s *scriptedStream) Err() error                        { return s.err }
+func (s *scriptedStream) Messages() []proto.Message         { return s.messages }
+func (s *scriptedStream) CallTools() []proto.ToolCallStatus { return nil }
+func (s *scriptedStream) DrainWarnings() []string           { return nil }
+func (s *scriptedStream) Usage() proto.Usage                { return s.usage }
+func (s *scriptedStream) ResponseMeta() proto.ResponseMeta  { return s.meta }
+
+type scriptedClient struct {
+	streams []*scriptedStream
+	calls   int
+}
+
+func (c *scriptedClient) Request(context.Context, proto.Request) stream.Stream {
+	st := c.streams[c.calls]
+	c.calls++
+	return st
+}
+
+func testCompleteConfig() *config.Config {
+	return &config.Config{
+		Settings: config.Settings{
+			APIs: config.APIs{
+				{
+					Name:   "openai",
+					APIKey: "test-key",
+					Models: map[string]config.Model{
+						"gpt-4.1-mini": {MaxChars: 100000},
+					},
+				},
+			},
+			Model:      "gpt-4.1-mini",
+			API:        "openai",
+			MaxRetries: 3,
+		},
+	}
+}
+
+func TestComplete(t *testing.T) {
+	t.Run("collects response and usage", func(t *testing.T) {
+		client := &scriptedClient{streams: []*scriptedStream{{
+			chunks: []string{"hello", " world"},
+			usage:  proto.Usage{InputTokens: 3, OutputTokens: 2, TotalTokens: 5},
+		}}}
+		svc := New(testCompleteConfig(), nil, nil, func(provider.Config) (stream.Client, error) {
+			return client, nil
+		})
+
+		res, err := svc.Complete(context.Background(), nil, "hi")
+		require.NoError(t, err)
+		require.Equal(t, "hello world", res.Response)
+		require.Equal(t, int64(5), res.Usage.TotalTokens)
+		require.Equal(t, "gpt-4.1-mini", res.Model.Name)
+		require.Zero(t, res.Retries)
+	})
+
+	t.Run("retries retryable provider errors", func(t *testing.T) {
+		client := &scriptedClient{streams: []*scriptedStream{
+			{err: &fantasy.ProviderError{
+				StatusCode:      http.StatusServiceUnavailable,
+				ResponseHeaders: map[string]string{"retry-after-ms": "1"},
+			}},
+			{chunks: []string{"ok"}},
+		}}
+		svc := New(testCompleteConfig(), nil, nil, func(provider.Config) (stream.Client, error) {
+			return client, nil
+		})
+
+		res, err := svc.Complete(context.Background(), nil, "hi")
+		require.NoError(t, err)
+		require.Equal(t, "ok", res.Response)
+		require.Equal(t, 1, res.Retries)
+		require.Equal(t, 2, client.calls)
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		client := &scriptedClient{streams: []*scriptedStream{
+			{err: &fantasy.ProviderError{StatusCode: http.StatusUnauthorized}},
+		}}
+		svc := New(testCompleteConfig(), nil, nil, func(provider.Config) (stream.Client, error) {
+			return client, nil
+		})
+
+		_, err := svc.Complete(context.Background(), nil, "hi")
+		require.Error(t, err)
+		require.Equal(t, 1, client.calls)
+	})
+}
+
+func TestCompleteStream(t *testing.T) {
+	t.Run("reports chunks and retries", func(t *testing.T) {
+		client := &scriptedClient{streams: []*scriptedStream{
+			{chunks: []string{"par"}, err: &fantasy.ProviderError{
+				StatusCode:      http.StatusServiceUnavailable,
+				ResponseHeaders: map[string]string{"retry-after-ms": "1"},
+			}},
+			{chunks: []string{"hello", " world"}},
+		}}
+		svc := New(testCompleteConfig(), nil, nil, func(provider.Config) (stream.Client, error) {
+			return client, nil
+		})
+
+		var events []Event
+		res, err := svc.CompleteStream(context.Background(), nil, "hi", func(ev Event) {
+			events = append(events, ev)
+		})
+		require.NoError(t, err)
+		require.Equal(t, "hello world", res.Response)
+		require.Len(t, events, 4)
+		require.Equal(t, Event{Type: EventChunk, Content: "par"}, events[0])
+		require.Equal(t, EventRetry, events[1].Type)
+		require.Error(t, events[1].Err)
+		require.Equal(t, Event{Type: EventChunk, Content: "hello"}, events[2])
+		require.Equal(t, Event{Type: EventChunk, Content: " world"}, events[3])
+	})
+}
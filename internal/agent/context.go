@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/requestbuilder"
+	"github.com/dotcommander/yai/internal/stream"
+)
+
+// Context strategies: what to do with a conversation that outgrows the
+// model's context window. drop-oldest leaves out the oldest turns,
+// summarize replaces them with a summary, and error refuses to send.
+const (
+	ContextDropOldest = "drop-oldest"
+	ContextSummarize  = "summarize"
+	ContextError      = "error"
+)
+
+// ContextStrategies are the context-strategy values, for completion.
+var ContextStrategies = []string{ContextDropOldest, ContextSummarize, ContextError}
+
+// The exchange that stands in for the turns a summary replaces.
+const (
+	historySummaryPrompt = "Summarize the conversation below in at most %d words, for the assistant to continue " +
+		"it from the summary alone. Keep the facts, decisions, names, code, and open questions; leave out pleasantries. " +
+		"Reply with the summary only.\n\n%s"
+	historySummaryRequest = "Summarize our conversation so far."
+)
+
+// CheckContextStrategy returns an error when strategy is not a
+// context-strategy value. Empty is drop-oldest.
+func CheckContextStrategy(strategy string) error {
+	switch strategy {
+	case "", ContextDropOldest, ContextSummarize, ContextError:
+		return nil
+	default:
+		return errs.Wrap(
+			errs.UserErrorf("context-strategy is %q; want %s, %s, or %s", strategy, ContextDropOldest, ContextSummarize, ContextError),
+			"Invalid context settings.",
+		)
+	}
+}
+
+// fitContext fits the conversation in prepared, between its system
+// messages and the prompt, into the history budget of its model with
+// context-strategy, and reports whether it had to.
+func (s *Service) fitContext(ctx context.Context, prepared *PreparedStream) (bool, error) {
+	if err := CheckContextStrategy(s.cfg.ContextStrategy); err != nil {
+		return false, err
+	}
+	messages := prepared.Request.Messages
+	if len(messages) == 0 {
+		return false, nil
+	}
+	system, history := requestbuilder.SplitSystem(messages[:len(messages)-1])
+	budget := requestbuilder.HistoryBudget(s.cfg, prepared.Model)
+	tokens := proto.EstimateTokens(history)
+	if budget <= 0 || tokens <= budget {
+		return false, nil
+	}
+
+	switch s.cfg.ContextStrategy {
+	case ContextError:
+		return false, errContextTooLong(prepared.Model, tokens, budget)
+	case ContextSummarize:
+		summarized, err := s.summarizeHistory(ctx, history, budget)
+		if err != nil {
+			return false, errs.Wrap(err, "Could not summarize the start of the conversation.")
+		}
+		history = summarized
+	}
+	// A summary longer than asked for goes the way of the turns it replaced.
+	history = requestbuilder.WindowHistory(history, budget)
+
+	fitted := make([]proto.Message, 0, len(system)+len(history)+1)
+	fitted = append(fitted, system...)
+	fitted = append(fitted, history...)
+	prepared.Request.Messages = append(fitted, messages[len(messages)-1])
+	return true, nil
+}
+
+// fullHistoryStream is a stream of a request fitContext cut down. It
+// reports the whole conversation, full, followed by what the stream added
+// after the sent messages it was started with.
+type fullHistoryStream struct {
+	stream.Stream
+	full []proto.Message
+	sent int
+}
+
+func (s *fullHistoryStream) Messages() []proto.Message {
+	messages := s.Stream.Messages()
+	if len(messages) < s.sent {
+		return messages
+	}
+	return slices.Concat(s.full, messages[s.sent:])
+}
+
+// errContextTooLong is the error of the error context-strategy, for a
+// conversation of tokens that is over the history budget of mod.
+func errContextTooLong(mod config.Model, tokens, budget int64) error {
+	return errs.Wrap(
+		errs.UserErrorf("The conversation is about %d tokens and %s has room for %d. Start a new conversation, "+
+			"or set context-strategy to %s or %s.", tokens, mod.Name, budget, ContextDropOldest, ContextSummarize),
+		"Conversation too long for the model.",
+	)
+}
+
+// summarizeHistory keeps the latest turns of history that fit in half of
+// budget and asks the utility model to summarize the turns before them in
+// the other half. The summary request goes through the Spend of ctx.
+func (s *Service) summarizeHistory(ctx context.Context, history []proto.Message, budget int64) ([]proto.Message, error) {
+	kept := requestbuilder.WindowHistory(history, budget/2)
+	older := history[:len(history)-len(kept)]
+	if len(older) == 0 {
+		return history, nil
+	}
+	// A word is about four thirds of a token.
+	words := budget / 2 * 3 / 4
+	utility, spend := s.utilityService(), spendOf(ctx)
+	if err := spend.Check(utility.cfg); err != nil {
+		return nil, err
+	}
+	res, err := utility.Complete(ctx, nil, fmt.Sprintf(historySummaryPrompt, words, transcript(older)))
+	if err != nil {
+		return nil, err
+	}
+	spend.Record(utility.cfg, res.Messages)
+	summary := []proto.Message{
+		{Role: proto.RoleUser, Content: historySummaryRequest},
+		{Role: proto.RoleAssistant, Content: strings.TrimSpace(res.Response)},
+	}
+	return append(summary, kept...), nil
+}
+
+// transcript writes messages out as text, one "role: content" paragraph
+// each.
+func transcript(messages []proto.Message) string {
+	var sb strings.Builder
+	for _, msg := range messages {
+		content := strings.TrimSpace(msg.Content)
+		for _, call := range msg.ToolCalls {
+			content = strings.TrimSpace(content + "\n(called " + call.Function.Name + ")")
+		}
+		if content == "" {
+			continue
+		}
+		fmt.Fprintf(&sb, "%s: %s\n\n", msg.Role, content)
+	}
+	return strings.TrimSpace(sb.String())
+}
@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/provider"
+	"github.com/dotcommander/yai/internal/stream"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFitContext(t *testing.T) {
+	// Four turns of 200 tokens; 525 of the 700 tokens of input fit.
+	var history []proto.Message
+	for i := range 4 {
+		history = append(history,
+			proto.Message{Role: proto.RoleUser, Content: fmt.Sprintf("question %d ", i) + strings.Repeat("q", 388)},
+			proto.Message{Role: proto.RoleAssistant, Content: fmt.Sprintf("answer %d ", i) + strings.Repeat("a", 391)},
+		)
+	}
+	newService := func(strategy string, answers ...string) (*Service, *recordingClient) {
+		cfg := testCompleteConfig()
+		cfg.APIs[0].Models["gpt-4.1-mini"] = config.Model{ContextWindow: 800}
+		cfg.ContextStrategy = strategy
+		client := &recordingClient{}
+		for _, answer := range answers {
+			client.streams = append(client.streams, &scriptedStream{chunks: []string{answer}})
+		}
+		return New(cfg, nil, nil, func(provider.Config) (stream.Client, error) {
+			return client, nil
+		}), client
+	}
+
+	t.Run("fits", func(t *testing.T) {
+		svc, client := newService(ContextError, "ok")
+		_, err := svc.Complete(context.Background(), history[6:], "next")
+		require.NoError(t, err)
+		require.Len(t, client.requests[0].Messages, 3)
+	})
+
+	t.Run("drop-oldest", func(t *testing.T) {
+		svc, client := newService(ContextDropOldest, "ok")
+		res, err := svc.Complete(context.Background(), history, "next")
+		require.NoError(t, err)
+		require.Equal(t, withoutTimes(append(history[4:], proto.Message{Role: proto.RoleUser, Content: "next"})),
+			withoutTimes(client.requests[0].Messages))
+		require.Equal(t, slices.Concat(history, []proto.Message{
+			{Role: proto.RoleUser, Content: "next"},
+			{Role: proto.RoleAssistant, Content: "ok"},
+		}), withoutTimes(res.Messages), "the turns left out are kept")
+	})
+
+	t.Run("summarize", func(t *testing.T) {
+		svc, client := newService(ContextSummarize, "they asked three questions", "ok")
+		var recorded [][]proto.Message
+		ctx := WithSpend(context.Background(), Spend{
+			Check: func(*config.Config) error { return nil },
+			Record: func(_ *config.Config, messages []proto.Message) {
+				recorded = append(recorded, withoutTimes(messages))
+			},
+		})
+		res, err := svc.Complete(ctx, history, "next")
+		require.NoError(t, err)
+		require.Len(t, client.requests, 2)
+		require.Len(t, recorded, 1, "the summary is recorded")
+		require.Equal(t, "they asked three questions", recorded[0][len(recorded[0])-1].Content)
+
+		summary := client.requests[0].Messages
+		require.Len(t, summary, 1)
+		require.Contains(t, summary[0].Content, "in at most 196 words")
+		require.Contains(t, summary[0].Content, "user: question 0 ")
+		require.Contains(t, summary[0].Content, "assistant: answer 2 ")
+		require.NotContains(t, summary[0].Content, "question 3")
+
+		msgs := withoutTimes(client.requests[1].Messages)
+		require.Equal(t, []proto.Message{
+			{Role: proto.RoleUser, Content: historySummaryRequest},
+			{Role: proto.RoleAssistant, Content: "they asked three questions"},
+			history[6],
+			history[7],
+			{Role: proto.RoleUser, Content: "next"},
+		}, msgs)
+		require.Equal(t, slices.Concat(history, []proto.Message{
+			{Role: proto.RoleUser, Content: "next"},
+			{Role: proto.RoleAssistant, Content: "ok"},
+		}), withoutTimes(res.Messages), "the summary is not kept")
+	})
+
+	t.Run("summarize over budget", func(t *testing.T) {
+		svc, client := newService(ContextSummarize, "they asked three questions", "ok")
+		ctx := WithSpend(context.Background(), Spend{
+			Check: func(*config.Config) error { return errors.New("over budget") },
+		})
+		_, err := svc.Complete(ctx, history, "next")
+		require.ErrorContains(t, err, "over budget")
+		require.Empty(t, client.requests)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		svc, client := newService(ContextError)
+		_, err := svc.Complete(context.Background(), history, "next")
+		require.ErrorContains(t, err, "about 799 tokens and gpt-4.1-mini has room for 525")
+		require.Empty(t, client.requests)
+	})
+
+	t.Run("invalid strategy", func(t *testing.T) {
+		svc, _ := newService("forget")
+		_, err := svc.Complete(context.Background(), nil, "next")
+		require.ErrorContains(t, err, `context-strategy is "forget"; want drop-oldest, summarize, or error`)
+	})
+}
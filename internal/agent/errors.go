@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -12,11 +13,13 @@ import (
 
 	"github.com/dotcommander/yai/internal/config"
 	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/requestbuilder"
 )
 
 // StreamErrorAction describes how yai should respond to a streaming error.
 type StreamErrorAction struct {
 	Retry         bool
+	Class         RetryClass
 	Prompt        string
 	ModelOverride string
 	Err           errs.Error
@@ -29,11 +32,41 @@ func (s *Service) ActionForStreamError(err error, mod config.Model, prompt strin
 	if errors.As(err, &providerErr) {
 		return s.actionForProviderError(providerErr, mod, prompt, noLimit)
 	}
+	var hookErr *hookError
+	if errors.As(err, &hookErr) {
+		return StreamErrorAction{Err: hookErr.userError()}
+	}
+	var timeoutErr *StreamTimeoutError
+	if errors.As(err, &timeoutErr) {
+		return StreamErrorAction{
+			Retry:  true,
+			Class:  RetryNetwork,
+			Prompt: prompt,
+			Err:    errs.Wrap(err, fmt.Sprintf("The %s API stopped responding.", mod.API)),
+		}
+	}
+	if isNetworkError(err) {
+		return StreamErrorAction{
+			Retry:  true,
+			Class:  RetryNetwork,
+			Prompt: prompt,
+			Err:    errs.Wrap(err, fmt.Sprintf("Could not reach the %s API.", mod.API)),
+		}
+	}
 	return StreamErrorAction{
 		Err: errs.Wrap(err, fmt.Sprintf("There was a problem with the %s API request.", mod.API)),
 	}
 }
 
+// UseFallback switches cfg to the fallback model, remembering the model that
+// was asked for so the substitution can be reported.
+func UseFallback(cfg *config.Config, fallback string) {
+	if cfg.FallbackFrom == "" {
+		cfg.FallbackFrom = cfg.Model
+	}
+	cfg.Model = fallback
+}
+
 func (s *Service) actionForProviderError(err *fantasy.ProviderError, mod config.Model, prompt string, noLimit bool) StreamErrorAction {
 	switch err.StatusCode {
 	case http.StatusNotFound:
@@ -44,6 +77,7 @@ func (s *Service) actionForProviderError(err *fantasy.ProviderError, mod config.
 			}
 			return StreamErrorAction{
 				Retry:         true,
+				Class:         RetryOther,
 				Prompt:        prompt,
 				ModelOverride: mod.Fallback,
 				Err:           errs.Wrap(err, reason),
@@ -61,7 +95,8 @@ func (s *Service) actionForProviderError(err *fantasy.ProviderError, mod config.
 			}
 			return StreamErrorAction{
 				Retry:  true,
-				Prompt: cutPrompt(err.Error(), prompt),
+				Class:  RetryOther,
+				Prompt: cutPrompt(err.Error(), prompt, requestbuilder.HeadRatio(s.cfg)),
 				Err:    pe,
 			}
 		}
@@ -77,8 +112,13 @@ func (s *Service) actionForProviderError(err *fantasy.ProviderError, mod config.
 		if reason == "" {
 			reason = "Retryable API error."
 		}
+		class := RetryServerError
+		if err.StatusCode == http.StatusTooManyRequests {
+			class = RetryRateLimit
+		}
 		return StreamErrorAction{
 			Retry:  true,
+			Class:  class,
 			Prompt: prompt,
 			Err:    errs.Wrap(err, reason),
 		}
@@ -91,6 +131,51 @@ func (s *Service) actionForProviderError(err *fantasy.ProviderError, mod config.
 	return StreamErrorAction{Err: errs.Wrap(err, reason)}
 }
 
+// IsContextOverflow reports whether err is a provider refusing a prompt
+// longer than the model's context window.
+func IsContextOverflow(err error) bool {
+	var providerErr *fantasy.ProviderError
+	return errors.As(err, &providerErr) &&
+		providerErr.StatusCode == http.StatusBadRequest &&
+		isContextLengthExceeded(providerErr)
+}
+
+// IsProviderFailure reports whether err came from a request to the
+// provider: an error response, a dropped connection, or a stalled stream.
+func IsProviderFailure(err error) bool {
+	var providerErr *fantasy.ProviderError
+	var timeoutErr *StreamTimeoutError
+	return errors.As(err, &providerErr) || errors.As(err, &timeoutErr) || isNetworkError(err)
+}
+
+// Kinds of provider failures, as FailureKind reports them.
+const (
+	FailureTimeout   = "timeout"
+	FailureServer    = "server"
+	FailureRateLimit = "rate-limit"
+	FailureNetwork   = "network"
+)
+
+// FailureKind reports how err shows the provider to be unhealthy: a timeout,
+// a server error, a rate limit, or a network failure. It returns "" for
+// other errors, such as a rejected request, which say nothing about the
+// provider's health.
+func FailureKind(err error) string {
+	var providerErr *fantasy.ProviderError
+	var timeoutErr *StreamTimeoutError
+	switch {
+	case errors.As(err, &timeoutErr), errors.Is(err, context.DeadlineExceeded):
+		return FailureTimeout
+	case errors.As(err, &providerErr) && providerErr.StatusCode == http.StatusTooManyRequests:
+		return FailureRateLimit
+	case errors.As(err, &providerErr) && providerErr.StatusCode >= http.StatusInternalServerError:
+		return FailureServer
+	case isNetworkError(err):
+		return FailureNetwork
+	}
+	return ""
+}
+
 func isContextLengthExceeded(err *fantasy.ProviderError) bool {
 	if strings.Contains(strings.ToLower(err.Message), "context_length_exceeded") {
 		return true
@@ -103,7 +188,9 @@ func isContextLengthExceeded(err *fantasy.ProviderError) bool {
 
 var tokenErrRe = regexp.MustCompile(`This model's maximum context length is (\d+) tokens. However, your messages resulted in (\d+) tokens`)
 
-func cutPrompt(msg, prompt string) string {
+// cutPrompt shortens prompt by what msg says is too much, keeping a head
+// share of it from the beginning and the rest from the end.
+func cutPrompt(msg, prompt string, head float64) string {
 	found := tokenErrRe.FindStringSubmatch(msg)
 	if len(found) != 3 { //nolint:mnd
 		return prompt
@@ -120,7 +207,7 @@ func cutPrompt(msg, prompt string) string {
 	// cut 10 extra chars 'just in case'
 	reduceBy := 10 + (current-maxt)*4 //nolint:mnd
 	if len(prompt) > reduceBy {
-		return prompt[:len(prompt)-reduceBy]
+		return requestbuilder.Truncate(prompt, len(prompt)-reduceBy, head)
 	}
 
 	return prompt
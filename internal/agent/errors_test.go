@@ -1,15 +1,24 @@
 package agent
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"syscall"
 	"testing"
 
+	"charm.land/fantasy"
+
+	"github.com/dotcommander/yai/internal/config"
 	"github.com/stretchr/testify/require"
 )
 
 var cutPromptTests = map[string]struct {
 	msg      string
 	prompt   string
+	head     float64
 	expected string
 }{
 	"bad error": {
@@ -27,6 +36,12 @@ var cutPromptTests = map[string]struct {
 		prompt:   "this is a long prompt I have no idea if its really 10 tokens",
 		expected: "this is a long prompt ",
 	},
+	"keeps head and tail": {
+		msg:      tokenErrMsg(20, 10),
+		prompt:   strings.Repeat("a", 100) + strings.Repeat("b", 100),
+		head:     0.5,
+		expected: strings.Repeat("a", 51) + "\n\n[... 97 bytes omitted ...]\n\n" + strings.Repeat("b", 52),
+	},
 	"missmatch of token estimation vs api result": {
 		msg:      tokenErrMsg(30000, 100),
 		prompt:   "tell me a joke",
@@ -45,7 +60,41 @@ func tokenErrMsg(l, ml int) string {
 func TestCutPrompt(t *testing.T) {
 	for name, tc := range cutPromptTests {
 		t.Run(name, func(t *testing.T) {
-			require.Equal(t, tc.expected, cutPrompt(tc.msg, tc.prompt))
+			require.Equal(t, tc.expected, cutPrompt(tc.msg, tc.prompt, tc.head))
+		})
+	}
+}
+
+func TestUseFallback(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Model = "gpt-5"
+
+	UseFallback(cfg, "gpt-5-mini")
+	require.Equal(t, "gpt-5-mini", cfg.Model)
+	require.Equal(t, "gpt-5", cfg.FallbackFrom)
+
+	// A fallback of the fallback still reports the model that was asked for.
+	UseFallback(cfg, "gpt-5-nano")
+	require.Equal(t, "gpt-5-nano", cfg.Model)
+	require.Equal(t, "gpt-5", cfg.FallbackFrom)
+}
+
+func TestFailureKind(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err  error
+		kind string
+	}{
+		"server error":   {&fantasy.ProviderError{StatusCode: http.StatusBadGateway}, FailureServer},
+		"rate limit":     {&fantasy.ProviderError{StatusCode: http.StatusTooManyRequests}, FailureRateLimit},
+		"bad request":    {&fantasy.ProviderError{StatusCode: http.StatusBadRequest}, ""},
+		"stalled stream": {&StreamTimeoutError{}, FailureTimeout},
+		"deadline":       {fmt.Errorf("stream: %w", context.DeadlineExceeded), FailureTimeout},
+		"refused":        {fmt.Errorf("dial: %w", syscall.ECONNREFUSED), FailureNetwork},
+		"canceled":       {context.Canceled, ""},
+		"something else": {errors.New("nope"), ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.kind, FailureKind(tc.err))
 		})
 	}
 }
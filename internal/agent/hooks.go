@@ -0,0 +1,185 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/caarlos0/go-shellwords"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/stream"
+)
+
+// Hook events.
+const (
+	HookPreRequest   = "pre-request"
+	HookPostResponse = "post-response"
+)
+
+// HookPayload is what a hook reads on stdin, and what it may write to
+// stdout to change the messages.
+type HookPayload struct {
+	Event    string        `json:"event"`
+	API      string        `json:"api"`
+	Model    string        `json:"model"`
+	Messages []HookMessage `json:"messages"`
+}
+
+// HookMessage is a message of the conversation as hooks see it.
+type HookMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// hookError is a hook that rejected a request or an answer, or that could
+// not be run.
+type hookError struct {
+	event string
+	// reason is why the hook rejected it; empty when err is set.
+	reason string
+	err    error
+}
+
+func (e *hookError) Error() string {
+	if e.err != nil {
+		return fmt.Sprintf("%s hook: %v", e.event, e.err)
+	}
+	return e.reason
+}
+
+func (e *hookError) Unwrap() error { return e.err }
+
+// userError explains e to the user.
+func (e *hookError) userError() errs.Error {
+	if e.err != nil {
+		return errs.Wrap(e.err, fmt.Sprintf("Could not run the %s hook.", e.event))
+	}
+	what := "request"
+	if e.event == HookPostResponse {
+		what = "answer"
+	}
+	return errs.Wrap(e, fmt.Sprintf("The %s hook rejected the %s.", e.event, what))
+}
+
+// Rejected reports whether err is a hook rejecting a request or an answer.
+// A rejected answer is not kept, not even to be resumed.
+func Rejected(err error) bool {
+	var hookErr *hookError
+	return errors.As(err, &hookErr) && hookErr.err == nil
+}
+
+// runHook runs command with the conversation on stdin. A hook that exits
+// non-zero rejects it, with the last line of its stderr as the reason. A
+// hook that prints a payload replaces the messages with those it printed;
+// a message at the same position and with the same role as before keeps
+// what hooks do not see, such as its tool calls.
+func runHook(ctx context.Context, command, event string, mod config.Model, msgs []proto.Message) ([]proto.Message, error) {
+	fail := func(err error) error { return &hookError{event: event, err: err} }
+
+	args, err := shellwords.Parse(command)
+	if err != nil {
+		return nil, fail(fmt.Errorf("parse command: %w", err))
+	}
+	if len(args) == 0 {
+		return nil, fail(errors.New("empty command"))
+	}
+
+	payload := HookPayload{Event: event, API: mod.API, Model: mod.Name, Messages: make([]HookMessage, 0, len(msgs))}
+	for _, msg := range msgs {
+		payload.Messages = append(payload.Messages, HookMessage{Role: msg.Role, Content: msg.Content})
+	}
+	in, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fail(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...) //nolint:gosec // the user's configured hook
+	cmd.Env = append(os.Environ(), "YAI_HOOK="+event)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if ctx.Err() != nil || !errors.As(err, &exitErr) {
+			return nil, fail(err)
+		}
+		reason := err.Error()
+		if lines := strings.Split(strings.TrimSpace(stderr.String()), "\n"); lines[len(lines)-1] != "" {
+			reason = lines[len(lines)-1]
+		}
+		return nil, &hookError{event: event, reason: reason}
+	}
+
+	if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		return msgs, nil
+	}
+	var out HookPayload
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return nil, fail(fmt.Errorf("parse output: %w", err))
+	}
+	if out.Messages == nil {
+		return msgs, nil
+	}
+	changed := make([]proto.Message, len(out.Messages))
+	for i, msg := range out.Messages {
+		if i < len(msgs) && msgs[i].Role == msg.Role {
+			changed[i] = msgs[i]
+		} else {
+			changed[i] = proto.Message{Role: msg.Role}
+		}
+		changed[i].Content = msg.Content
+	}
+	return changed, nil
+}
+
+// hookedStream runs the post-response hook once the model has answered,
+// after the last round of tool calls.
+type hookedStream struct {
+	stream.Stream
+
+	ctx     context.Context
+	command string
+	mod     config.Model
+
+	done     bool
+	messages []proto.Message
+	err      error
+}
+
+func (h *hookedStream) Next() bool {
+	if h.Stream.Next() {
+		return true
+	}
+	if h.done || h.Stream.Err() != nil {
+		return false
+	}
+	msgs := h.Stream.Messages()
+	if n := len(msgs); n == 0 || msgs[n-1].Role != proto.RoleAssistant || len(msgs[n-1].ToolCalls) > 0 {
+		return false
+	}
+	h.done = true
+	h.messages, h.err = runHook(h.ctx, h.command, HookPostResponse, h.mod, msgs)
+	return false
+}
+
+func (h *hookedStream) Err() error {
+	if h.err != nil {
+		return h.err
+	}
+	return h.Stream.Err() //nolint:wrapcheck
+}
+
+func (h *hookedStream) Messages() []proto.Message {
+	if h.messages != nil {
+		return h.messages
+	}
+	return h.Stream.Messages()
+}
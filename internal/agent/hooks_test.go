@@ -0,0 +1,173 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/provider"
+	"github.com/dotcommander/yai/internal/stream"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain runs the test binary as a hook when YAI_TEST_HOOK is set.
+func TestMain(m *testing.M) {
+	if mode := os.Getenv("YAI_TEST_HOOK"); mode != "" {
+		os.Exit(testHook(mode))
+	}
+	os.Exit(m.Run())
+}
+
+func testHook(mode string) int {
+	var payload HookPayload
+	if err := json.NewDecoder(os.Stdin).Decode(&payload); err != nil {
+		fmt.Fprintln(os.Stderr, "bad payload:", err)
+		return 2
+	}
+	switch mode {
+	case "upper":
+		for i, msg := range payload.Messages {
+			payload.Messages[i].Content = strings.ToUpper(msg.Content)
+		}
+		payload.Messages = append(payload.Messages, HookMessage{Role: proto.RoleUser, Content: os.Getenv("YAI_HOOK")})
+		_ = json.NewEncoder(os.Stdout).Encode(payload)
+	case "veto":
+		fmt.Fprintln(os.Stderr, "checking", len(payload.Messages), "messages")
+		fmt.Fprintln(os.Stderr, "prompt mentions production credentials")
+		return 1
+	case "garbage":
+		fmt.Println("not json")
+	}
+	return 0
+}
+
+func hookCommand(t *testing.T, mode string) string {
+	t.Helper()
+	t.Setenv("YAI_TEST_HOOK", mode)
+	return "'" + os.Args[0] + "'"
+}
+
+func TestRunHook(t *testing.T) {
+	mod := config.Model{API: "openai", Name: "gpt-5"}
+	msgs := []proto.Message{
+		{Role: proto.RoleSystem, Content: "be brief"},
+		{Role: proto.RoleAssistant, Content: "calling", ToolCalls: []proto.ToolCall{{ID: "1"}}},
+	}
+
+	t.Run("changes messages", func(t *testing.T) {
+		out, err := runHook(context.Background(), hookCommand(t, "upper"), HookPreRequest, mod, msgs)
+		require.NoError(t, err)
+		require.Equal(t, []proto.Message{
+			{Role: proto.RoleSystem, Content: "BE BRIEF"},
+			{Role: proto.RoleAssistant, Content: "CALLING", ToolCalls: []proto.ToolCall{{ID: "1"}}},
+			{Role: proto.RoleUser, Content: HookPreRequest},
+		}, out)
+	})
+
+	t.Run("no output keeps messages", func(t *testing.T) {
+		out, err := runHook(context.Background(), hookCommand(t, "pass"), HookPreRequest, mod, msgs)
+		require.NoError(t, err)
+		require.Equal(t, msgs, out)
+	})
+
+	t.Run("non-zero exit rejects", func(t *testing.T) {
+		_, err := runHook(context.Background(), hookCommand(t, "veto"), HookPreRequest, mod, msgs)
+		var hookErr *hookError
+		require.ErrorAs(t, err, &hookErr)
+		uerr := hookErr.userError()
+		require.Equal(t, "The pre-request hook rejected the request.", uerr.ReasonText())
+		require.EqualError(t, uerr, "prompt mentions production credentials")
+	})
+
+	t.Run("bad output fails", func(t *testing.T) {
+		_, err := runHook(context.Background(), hookCommand(t, "garbage"), HookPostResponse, mod, msgs)
+		require.ErrorContains(t, err, "post-response hook: parse output")
+	})
+
+	t.Run("missing command fails", func(t *testing.T) {
+		_, err := runHook(context.Background(), "/nonexistent/yai-hook", HookPreRequest, mod, msgs)
+		var hookErr *hookError
+		require.ErrorAs(t, err, &hookErr)
+		require.Equal(t, "Could not run the pre-request hook.", hookErr.userError().ReasonText())
+		require.False(t, Rejected(err))
+	})
+}
+
+func TestStreamHooks(t *testing.T) {
+	newCfg := func() *config.Config {
+		return &config.Config{Settings: config.Settings{
+			APIs: config.APIs{{
+				Name:   "openai",
+				APIKey: "test-key",
+				Models: map[string]config.Model{"gpt-4.1-mini": {MaxChars: 100000}},
+			}},
+			Model: "gpt-4.1-mini",
+			API:   "openai",
+		}}
+	}
+
+	t.Run("pre-request changes the request", func(t *testing.T) {
+		capture := &captureClient{}
+		cfg := newCfg()
+		cfg.Hooks.PreRequest = hookCommand(t, "upper")
+		svc := New(cfg, nil, nil, func(provider.Config) (stream.Client, error) { return capture, nil })
+
+		res, err := svc.Stream(context.Background(), "hello")
+		require.NoError(t, err)
+		last := capture.lastRequest.Messages[len(capture.lastRequest.Messages)-2]
+		require.Equal(t, "HELLO", last.Content)
+		require.Equal(t, capture.lastRequest.Messages, res.Messages)
+	})
+
+	t.Run("pre-request rejects the request", func(t *testing.T) {
+		capture := &captureClient{}
+		cfg := newCfg()
+		cfg.Hooks.PreRequest = hookCommand(t, "veto")
+		svc := New(cfg, nil, nil, func(provider.Config) (stream.Client, error) { return capture, nil })
+
+		_, err := svc.Stream(context.Background(), "hello")
+		var uerr errs.Error
+		require.ErrorAs(t, err, &uerr)
+		require.Equal(t, "The pre-request hook rejected the request.", uerr.ReasonText())
+		require.Nil(t, capture.lastRequest, "nothing is sent")
+	})
+
+	t.Run("post-response runs after the answer", func(t *testing.T) {
+		answer := &answerStream{messages: []proto.Message{
+			{Role: proto.RoleUser, Content: "hi"},
+			{Role: proto.RoleAssistant, Content: "calling", ToolCalls: []proto.ToolCall{{ID: "1"}}},
+		}}
+		st := &hookedStream{Stream: answer, ctx: context.Background(), command: hookCommand(t, "upper"), mod: config.Model{API: "openai"}}
+
+		require.False(t, st.Next())
+		require.NoError(t, st.Err())
+		require.Equal(t, "calling", st.Messages()[1].Content, "tool calls are pending")
+
+		answer.messages = append(answer.messages, proto.Message{Role: proto.RoleAssistant, Content: "hello"})
+		require.False(t, st.Next())
+		require.NoError(t, st.Err())
+		require.Equal(t, "HELLO", st.Messages()[2].Content)
+		require.Equal(t, HookPostResponse, st.Messages()[3].Content)
+
+		st = &hookedStream{Stream: answer, ctx: context.Background(), command: hookCommand(t, "veto"), mod: config.Model{API: "openai"}}
+		require.False(t, st.Next())
+		action := (&Service{}).ActionForStreamError(st.Err(), config.Model{API: "openai"}, "hi", false)
+		require.False(t, action.Retry)
+		require.Equal(t, "The post-response hook rejected the answer.", action.Err.ReasonText())
+		require.True(t, Rejected(action.Err))
+	})
+}
+
+// answerStream is a finished stream with the given messages.
+type answerStream struct {
+	stubStream
+	messages []proto.Message
+}
+
+func (s *answerStream) Messages() []proto.Message { return s.messages }
@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/provider"
+	"github.com/dotcommander/yai/internal/requestbuilder"
+)
+
+// Embed computes embeddings for inputs using the configured API and
+// embed-model.
+func (s *Service) Embed(ctx context.Context, inputs []string) (provider.Embeddings, error) {
+	mod, providerCfg, err := s.prepareModality(ctx, s.cfg.EmbedModel)
+	if err != nil {
+		return provider.Embeddings{}, err
+	}
+
+	var res provider.Embeddings
+	err = s.withRetries(ctx, mod, func(ctx context.Context) error {
+		var err error
+		res, err = provider.Embed(ctx, providerCfg, mod.Name, inputs)
+		if err != nil {
+			return fmt.Errorf("embed: %w", err)
+		}
+		return nil
+	})
+	return res, err
+}
+
+// Transcribe converts audio to text using the configured API and
+// transcribe-model.
+func (s *Service) Transcribe(ctx context.Context, audio provider.Audio) (string, error) {
+	mod, providerCfg, err := s.prepareModality(ctx, s.cfg.TranscribeModel)
+	if err != nil {
+		return "", err
+	}
+
+	var text string
+	err = s.withRetries(ctx, mod, func(ctx context.Context) error {
+		var err error
+		text, err = provider.Transcribe(ctx, providerCfg, mod.Name, audio)
+		if err != nil {
+			return fmt.Errorf("transcribe: %w", err)
+		}
+		return nil
+	})
+	return text, err
+}
+
+// Ping checks that api is reachable and accepts its key, without spending
+// tokens; see provider.Ping. It is not retried.
+func (s *Service) Ping(ctx context.Context, api config.API) error {
+	// Preparing the provider may change the settings; APIs are checked
+	// side by side.
+	cfg := *s.cfg
+	providerCfg, err := requestbuilder.PrepareProviderConfig(ctx, config.Model{API: api.Name}, api, &cfg)
+	if err != nil {
+		return fmt.Errorf("prepare provider config: %w", err)
+	}
+	if err := ApplyHTTPConfig(cfg.HTTPProxy, cfg.ConnectTimeout, cfg.FirstTokenTimeout, &providerCfg); err != nil {
+		return err
+	}
+	return s.callWithTimeout(ctx, func(ctx context.Context) error {
+		return provider.Ping(ctx, providerCfg) //nolint:wrapcheck
+	})
+}
+
+// prepareModality resolves the provider config for a non-chat request against
+// the configured API.
+func (s *Service) prepareModality(ctx context.Context, model string) (config.Model, provider.Config, error) {
+	api, mod, err := requestbuilder.ResolveAPI(s.cfg, model)
+	if err != nil {
+		return config.Model{}, provider.Config{}, fmt.Errorf("resolve api: %w", err)
+	}
+	providerCfg, err := requestbuilder.PrepareProviderConfig(ctx, mod, api, s.cfg)
+	if err != nil {
+		return config.Model{}, provider.Config{}, fmt.Errorf("prepare provider config: %w", err)
+	}
+	if err := ApplyHTTPConfig(s.cfg.HTTPProxy, s.cfg.ConnectTimeout, s.cfg.FirstTokenTimeout, &providerCfg); err != nil {
+		return config.Model{}, provider.Config{}, err
+	}
+	return mod, providerCfg, nil
+}
+
+// withRetries runs fn with the request timeout applied, retrying retryable
+// errors within the retry budgets.
+func (s *Service) withRetries(ctx context.Context, mod config.Model, fn func(context.Context) error) error {
+	budget := NewRetryBudget(s.cfg)
+	for {
+		err := s.callWithTimeout(ctx, fn)
+		if err == nil {
+			return nil
+		}
+
+		action := s.ActionForStreamError(err, mod, "", true)
+		delay, ok := budget.Next(action)
+		if !ok {
+			return action.Err
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err() //nolint:wrapcheck
+		}
+	}
+}
+
+func (s *Service) callWithTimeout(ctx context.Context, fn func(context.Context) error) error {
+	if s.cfg.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.RequestTimeout)
+		defer cancel()
+	}
+	return fn(ctx)
+}
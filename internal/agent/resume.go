@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"slices"
+
+	"github.com/dotcommander/yai/internal/proto"
+)
+
+// ResumePrompt asks the model to continue a response that was cut off.
+const ResumePrompt = "Your previous response was cut off. " +
+	"Continue exactly where it stopped, without repeating anything or adding a preamble."
+
+// WithPartial returns messages followed by partial as an unfinished
+// assistant message, or messages unchanged when partial is empty.
+func WithPartial(messages []proto.Message, partial string) []proto.Message {
+	if partial == "" {
+		return messages
+	}
+	return append(slices.Clip(messages), proto.Message{
+		Role:    proto.RoleAssistant,
+		Content: partial,
+		Partial: true,
+	})
+}
+
+// CanResume reports whether messages end with an unfinished assistant
+// message.
+func CanResume(messages []proto.Message) bool {
+	if len(messages) == 0 {
+		return false
+	}
+	last := messages[len(messages)-1]
+	return last.Role == proto.RoleAssistant && last.Partial
+}
+
+// MergeResumed folds a resumed response into the message it continues:
+// the resume prompt is dropped and the continuation is appended to the
+// partial message, so the conversation reads as one uninterrupted answer.
+func MergeResumed(messages []proto.Message) []proto.Message {
+	for i := 0; i+1 < len(messages); i++ {
+		msg := messages[i]
+		next := messages[i+1]
+		if msg.Role != proto.RoleAssistant || !msg.Partial ||
+			next.Role != proto.RoleUser || next.Content != ResumePrompt {
+			continue
+		}
+		merged := slices.Clone(messages[:i+1])
+		rest := messages[i+2:]
+		if len(rest) > 0 && rest[0].Role == proto.RoleAssistant && len(rest[0].ToolCalls) == 0 {
+			merged[i].Content += rest[0].Content
+			merged[i].Partial = rest[0].Partial
+			rest = rest[1:]
+		} else {
+			merged[i].Partial = false
+		}
+		return MergeResumed(append(merged, rest...))
+	}
+	return messages
+}
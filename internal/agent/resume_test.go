@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResume(t *testing.T) {
+	prompt := proto.Message{Role: proto.RoleUser, Content: "count to ten"}
+
+	t.Run("partial", func(t *testing.T) {
+		require.False(t, CanResume(nil))
+		require.False(t, CanResume([]proto.Message{prompt}))
+		require.Equal(t, []proto.Message{prompt}, WithPartial([]proto.Message{prompt}, ""))
+
+		messages := WithPartial([]proto.Message{prompt}, "one, two, ")
+		require.True(t, CanResume(messages))
+		require.Equal(t, proto.Message{Role: proto.RoleAssistant, Content: "one, two, ", Partial: true}, messages[1])
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		messages := append(WithPartial([]proto.Message{prompt}, "one, two, "),
+			proto.Message{Role: proto.RoleUser, Content: ResumePrompt},
+			proto.Message{Role: proto.RoleAssistant, Content: "three."},
+		)
+		require.Equal(t, []proto.Message{
+			prompt,
+			{Role: proto.RoleAssistant, Content: "one, two, three."},
+		}, MergeResumed(messages))
+	})
+
+	t.Run("cut off again", func(t *testing.T) {
+		messages := append(WithPartial([]proto.Message{prompt}, "one, "),
+			proto.Message{Role: proto.RoleUser, Content: ResumePrompt},
+		)
+		messages = WithPartial(messages, "two, ")
+		merged := MergeResumed(messages)
+		require.Len(t, merged, 2)
+		require.Equal(t, "one, two, ", merged[1].Content)
+		require.True(t, CanResume(merged))
+	})
+
+	t.Run("continued with tools", func(t *testing.T) {
+		call := proto.Message{Role: proto.RoleAssistant, ToolCalls: []proto.ToolCall{{ID: "1"}}}
+		messages := append(WithPartial([]proto.Message{prompt}, "one, "),
+			proto.Message{Role: proto.RoleUser, Content: ResumePrompt},
+			call,
+		)
+		merged := MergeResumed(messages)
+		require.Equal(t, []proto.Message{prompt, {Role: proto.RoleAssistant, Content: "one, "}, call}, merged)
+	})
+
+	t.Run("nothing to merge", func(t *testing.T) {
+		messages := []proto.Message{prompt, {Role: proto.RoleAssistant, Content: "ten"}}
+		require.Equal(t, messages, MergeResumed(messages))
+	})
+}
@@ -1,14 +1,97 @@
 package agent
 
 import (
+	"context"
+	"errors"
+	"io"
 	"math/rand/v2"
+	"net"
 	"strconv"
+	"syscall"
 	"time"
+
+	"charm.land/fantasy"
+
+	"github.com/dotcommander/yai/internal/config"
 )
 
+// RetryClass groups retryable errors that share a retry budget.
+type RetryClass string
+
+// Retry classes. RetryOther covers recoveries that change the request (a
+// fallback model, a shortened prompt); only max-retries limits them.
+const (
+	RetryRateLimit   RetryClass = "rate-limit"
+	RetryServerError RetryClass = "server-error"
+	RetryNetwork     RetryClass = "network"
+	RetryOther       RetryClass = "other"
+)
+
+// RetryBudget tracks the retries of one request against max-retries and the
+// per-class budgets in settings.
+type RetryBudget struct {
+	cfg    *config.Config
+	counts map[RetryClass]int
+	total  int
+}
+
+// NewRetryBudget returns an unused budget for a request made with cfg.
+func NewRetryBudget(cfg *config.Config) *RetryBudget {
+	return &RetryBudget{cfg: cfg, counts: map[RetryClass]int{}}
+}
+
+// Next records a retry for action and returns how long to wait before it. It
+// returns false when the action is not retryable or its budget is spent.
+func (b *RetryBudget) Next(action StreamErrorAction) (time.Duration, bool) {
+	if !action.Retry {
+		return 0, false
+	}
+	b.total++
+	b.counts[action.Class]++
+	if b.total >= b.cfg.MaxRetries || b.counts[action.Class] > b.limit(action.Class) {
+		return 0, false
+	}
+	return RetryDelay(b.cfg.Retry, b.counts[action.Class], action.Err.Err), true
+}
+
+// Total returns the number of retries recorded so far.
+func (b *RetryBudget) Total() int {
+	return b.total
+}
+
+func (b *RetryBudget) limit(class RetryClass) int {
+	retry := b.cfg.Retry.WithDefaults()
+	var n int
+	switch class {
+	case RetryRateLimit:
+		n = retry.RateLimit
+	case RetryServerError:
+		n = retry.ServerError
+	case RetryNetwork:
+		n = retry.Network
+	default:
+		return b.cfg.MaxRetries
+	}
+	return max(n, 0)
+}
+
+// RetryDelay returns how long to wait before retry attempt number attempt.
+// Provider retry-after headers take precedence over exponential backoff.
+func RetryDelay(settings config.RetrySettings, attempt int, err error) time.Duration {
+	var providerErr *fantasy.ProviderError
+	if errors.As(err, &providerErr) {
+		if ra := RetryAfterFromHeaders(providerErr.ResponseHeaders); ra > 0 {
+			return ra
+		}
+	}
+	settings = settings.WithDefaults()
+	return CalculateBackoff(attempt, settings.InitialBackoff, settings.MaxBackoff, settings.Jitter)
+}
+
 // CalculateBackoff returns a jittered exponential backoff duration.
-// The result is initial * 2^attempt, capped at maxDur, with ±12.5% jitter.
-func CalculateBackoff(attempt int, initial, maxDur time.Duration) time.Duration {
+// The result is initial * 2^attempt, capped at maxDur, randomly varied by up
+// to ±jitter of itself. A negative jitter disables the variation.
+func CalculateBackoff(attempt int, initial, maxDur time.Duration, jitter float64) time.Duration {
 	if attempt > 62 {
 		attempt = 62
 	}
@@ -16,8 +99,28 @@ func CalculateBackoff(attempt int, initial, maxDur time.Duration) time.Duration
 	if d > maxDur || d <= 0 {
 		d = maxDur
 	}
-	jitter := 0.875 + rand.Float64()*0.25 //nolint:gosec // G404: jitter calculation does not require cryptographic randomness
-	return time.Duration(float64(d) * jitter)
+	if jitter <= 0 {
+		return d
+	}
+	factor := 1 - jitter + rand.Float64()*2*jitter //nolint:gosec // G404: jitter calculation does not require cryptographic randomness
+	return time.Duration(float64(d) * factor)
+}
+
+// isNetworkError reports whether err is a transport failure worth retrying:
+// a dropped or refused connection, a DNS failure, or a truncated response.
+// Cancellation and request timeouts are not.
+func isNetworkError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
 }
 
 // RetryAfterFromHeaders extracts a retry-after delay from provider response
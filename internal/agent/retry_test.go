@@ -1,10 +1,20 @@
 package agent
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
 	"testing"
 	"time"
 
+	"charm.land/fantasy"
+	"github.com/dotcommander/yai/internal/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCalculateBackoff(t *testing.T) {
@@ -47,7 +57,7 @@ func TestCalculateBackoff(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := CalculateBackoff(tt.attempt, tt.initial, tt.max)
+			got := CalculateBackoff(tt.attempt, tt.initial, tt.max, 0.125)
 			lo := time.Duration(float64(tt.wantBase) * 0.875)
 			hi := time.Duration(float64(tt.wantBase) * 1.125)
 			assert.GreaterOrEqual(t, got, lo, "backoff %v below lower bound %v", got, lo)
@@ -56,6 +66,86 @@ func TestCalculateBackoff(t *testing.T) {
 	}
 }
 
+func TestCalculateBackoffWithoutJitter(t *testing.T) {
+	require.Equal(t, 2*time.Second, CalculateBackoff(2, 500*time.Millisecond, 30*time.Second, -1))
+}
+
+func TestRetryBudget(t *testing.T) {
+	newBudget := func(maxRetries int, retry config.RetrySettings) *RetryBudget {
+		retry.InitialBackoff = time.Millisecond
+		retry.Jitter = -1
+		return NewRetryBudget(&config.Config{Settings: config.Settings{MaxRetries: maxRetries, Retry: retry}})
+	}
+	action := func(class RetryClass) StreamErrorAction {
+		return StreamErrorAction{Retry: true, Class: class}
+	}
+
+	t.Run("limits each class separately", func(t *testing.T) {
+		b := newBudget(10, config.RetrySettings{RateLimit: 2, ServerError: 1})
+		for range 2 {
+			_, ok := b.Next(action(RetryRateLimit))
+			require.True(t, ok)
+		}
+		_, ok := b.Next(action(RetryRateLimit))
+		require.False(t, ok)
+
+		delay, ok := b.Next(action(RetryServerError))
+		require.True(t, ok)
+		require.Equal(t, 2*time.Millisecond, delay)
+		_, ok = b.Next(action(RetryServerError))
+		require.False(t, ok)
+	})
+
+	t.Run("max-retries caps the total", func(t *testing.T) {
+		b := newBudget(3, config.RetrySettings{RateLimit: 5, ServerError: 5})
+		_, ok := b.Next(action(RetryRateLimit))
+		require.True(t, ok)
+		_, ok = b.Next(action(RetryServerError))
+		require.True(t, ok)
+		_, ok = b.Next(action(RetryRateLimit))
+		require.False(t, ok)
+		require.Equal(t, 3, b.Total())
+	})
+
+	t.Run("negative budgets disable a class", func(t *testing.T) {
+		b := newBudget(5, config.RetrySettings{Network: -1})
+		_, ok := b.Next(action(RetryNetwork))
+		require.False(t, ok)
+	})
+
+	t.Run("does not retry unretryable actions", func(t *testing.T) {
+		b := newBudget(5, config.RetrySettings{})
+		_, ok := b.Next(StreamErrorAction{})
+		require.False(t, ok)
+		require.Zero(t, b.Total())
+	})
+}
+
+func TestActionForStreamErrorClass(t *testing.T) {
+	svc := New(&config.Config{}, nil, nil)
+	mod := config.Model{Name: "m", API: "openai"}
+	for name, tc := range map[string]struct {
+		err   error
+		retry bool
+		class RetryClass
+	}{
+		"rate limit":    {&fantasy.ProviderError{StatusCode: http.StatusTooManyRequests}, true, RetryRateLimit},
+		"server error":  {&fantasy.ProviderError{StatusCode: http.StatusBadGateway}, true, RetryServerError},
+		"reset":         {fmt.Errorf("read: %w", syscall.ECONNRESET), true, RetryNetwork},
+		"truncated":     {io.ErrUnexpectedEOF, true, RetryNetwork},
+		"dns":           {&net.DNSError{Err: "no such host", Name: "api.example"}, true, RetryNetwork},
+		"canceled":      {context.Canceled, false, ""},
+		"timed out":     {fmt.Errorf("stream: %w", context.DeadlineExceeded), false, ""},
+		"unknown error": {errors.New("boom"), false, ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			action := svc.ActionForStreamError(tc.err, mod, "hi", false)
+			require.Equal(t, tc.retry, action.Retry)
+			require.Equal(t, tc.class, action.Class)
+		})
+	}
+}
+
 func TestRetryAfterFromHeaders(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/dotcommander/yai/internal/mcp"
+	"github.com/dotcommander/yai/internal/proto"
+)
+
+// sample answers the completion an MCP server asked for with the configured
+// model. Roles, format text, reply-language, and tools are left out: the
+// server writes the whole prompt. The request goes through the Spend of
+// ctx.
+func (s *Service) sample(ctx context.Context, req mcp.SamplingRequest) (string, string, error) {
+	cfg := *s.cfg
+	cfg.Role = ""
+	cfg.Format = false
+	cfg.ReplyLanguage = ""
+	cfg.Prefix = ""
+	cfg.MCPDisable = []string{"*"}
+	cfg.MaxTokens = req.MaxTokens
+	if req.Temperature > 0 {
+		cfg.Temperature = req.Temperature
+	}
+
+	last := len(req.Messages) - 1
+	history := req.Messages[:last]
+	if req.System != "" {
+		history = append([]proto.Message{{Role: proto.RoleSystem, Content: req.System}}, history...)
+	}
+	spend := spendOf(ctx)
+	if err := spend.Check(&cfg); err != nil {
+		return "", "", err
+	}
+	res, err := New(&cfg, nil, nil, s.clientFactory).Complete(ctx, history, req.Messages[last].Content)
+	if err != nil {
+		return "", "", err
+	}
+	spend.Record(&cfg, res.Messages)
+	return res.Response, res.Model.Name, nil
+}
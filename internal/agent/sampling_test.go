@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/provider"
+	"github.com/dotcommander/yai/internal/stream"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSample(t *testing.T) {
+	client := &recordingClient{scriptedClient: scriptedClient{streams: []*scriptedStream{{chunks: []string{"a summary"}}}}}
+	svc := New(testCompleteConfig(), nil, nil, func(provider.Config) (stream.Client, error) {
+		return client, nil
+	})
+	req := SamplingRequest{Server: "fs", System: "Be brief.", Messages: []proto.Message{{Role: proto.RoleUser, Content: "summarize this"}}}
+
+	var recorded []proto.Message
+	budget := errors.New("over budget")
+	ctx := WithSpend(context.Background(), Spend{
+		Check:  func(*config.Config) error { return budget },
+		Record: func(_ *config.Config, messages []proto.Message) { recorded = messages },
+	})
+	_, _, err := svc.sample(ctx, req)
+	require.ErrorIs(t, err, budget)
+	require.Empty(t, client.requests)
+
+	budget = nil
+	content, model, err := svc.sample(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, "a summary", content)
+	require.Equal(t, "gpt-4.1-mini", model)
+	require.Equal(t, "a summary", recorded[len(recorded)-1].Content)
+}
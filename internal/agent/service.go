@@ -2,13 +2,16 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	mmcp "github.com/mark3labs/mcp-go/mcp"
 
 	"github.com/dotcommander/yai/internal/config"
 	"github.com/dotcommander/yai/internal/errs"
 	"github.com/dotcommander/yai/internal/mcp"
+	"github.com/dotcommander/yai/internal/plugin"
 	"github.com/dotcommander/yai/internal/present"
 	"github.com/dotcommander/yai/internal/proto"
 	"github.com/dotcommander/yai/internal/provider"
@@ -50,6 +53,8 @@ type StreamStart struct {
 	Stream   stream.Stream
 	Model    config.Model
 	Messages []proto.Message
+	// Tools is the number of MCP tools offered to the model.
+	Tools int
 }
 
 // PreparedStream contains pre-resolved stream input prepared by higher layers.
@@ -61,21 +66,54 @@ func (s *Service) Stream(ctx context.Context, prompt string) (StreamStart, error
 	if err != nil {
 		return StreamStart{}, fmt.Errorf("build request: %w", err)
 	}
-
-	return s.StreamFromPrepared(ctx, prepared)
+	// A continued conversation comes from the cache.
+	return s.streamFitted(ctx, prepared)
 }
 
 // StreamContinue starts a streaming completion using pre-built conversation
 // history. It prepends system messages (format + role) to the provided history
 // and appends the new user message. This avoids per-turn disk I/O and prevents
-// system message duplication across turns.
+// system message duplication across turns. History that outgrows the
+// model's context window is cut down with context-strategy.
 func (s *Service) StreamContinue(ctx context.Context, history []proto.Message, prompt string) (StreamStart, error) {
 	prepared, err := requestbuilder.BuildPreparedFromHistory(ctx, s.cfg, history, prompt)
 	if err != nil {
 		return StreamStart{}, fmt.Errorf("build request: %w", err)
 	}
+	return s.streamFitted(ctx, prepared)
+}
+
+// streamFitted starts prepared after fitting its conversation into the
+// model's context window. Only the request is cut down: the stream still
+// reports the whole conversation, so what was left out is saved with it.
+func (s *Service) streamFitted(ctx context.Context, prepared PreparedStream) (StreamStart, error) {
+	full := prepared.Request.Messages
+	fitted, err := s.fitContext(ctx, &prepared)
+	if err != nil {
+		return StreamStart{}, err
+	}
+	start, err := s.StreamFromPrepared(ctx, prepared)
+	if err != nil || !fitted {
+		return start, err
+	}
+	start.Stream = &fullHistoryStream{Stream: start.Stream, full: full, sent: len(start.Messages)}
+	start.Messages = full
+	return start, nil
+}
+
+// WithToolProgress returns ctx passing the progress that MCP tools report
+// to report, for the streams started with it.
+func WithToolProgress(ctx context.Context, report func(proto.ToolProgress)) context.Context {
+	return mcp.WithProgress(ctx, report)
+}
+
+// SamplingRequest is a completion an MCP server asks the model for.
+type SamplingRequest = mcp.SamplingRequest
 
-	return s.StreamFromPrepared(ctx, prepared)
+// WithSamplingApproval returns ctx asking approve about the completions MCP
+// servers ask for while the tools of the streams started with it run.
+func WithSamplingApproval(ctx context.Context, approve func(context.Context, SamplingRequest) bool) context.Context {
+	return mcp.WithSamplingApproval(ctx, approve)
 }
 
 // StreamFromPrepared starts a stream from pre-built request data.
@@ -99,13 +137,35 @@ func (s *Service) startStream(ctx context.Context, req proto.Request, mod config
 		}
 	}
 
+	var guard *injectionGuard
 	if toolsEnabled {
+		var err error
+		guard, err = newInjectionGuard(cfg, s.looksInjected)
+		if err != nil {
+			return StreamStart{}, err
+		}
 		req.Tools = tools
 		req.ToolCaller = func(name string, data []byte) (string, error) {
-			callCtx, cancel := context.WithTimeout(ctx, cfg.MCPTimeout)
+			callCtx, cancel := context.WithTimeout(mcp.WithSampler(ctx, s.sample), cfg.MCPTimeout)
 			defer cancel()
-			return s.mcp.CallTool(callCtx, name, data)
+			content, err := s.mcp.CallTool(callCtx, name, data)
+			if err != nil || guard == nil {
+				return content, err
+			}
+			return guard.check(ctx, name, content), nil
+		}
+	}
+
+	if cfg.Hooks.PreRequest != "" {
+		msgs, err := runHook(ctx, cfg.Hooks.PreRequest, HookPreRequest, mod, req.Messages)
+		if err != nil {
+			var hookErr *hookError
+			if errors.As(err, &hookErr) {
+				return StreamStart{}, hookErr.userError()
+			}
+			return StreamStart{}, err
 		}
+		req.Messages = msgs
 	}
 
 	client, err := s.clientFactory(providerCfg)
@@ -113,24 +173,45 @@ func (s *Service) startStream(ctx context.Context, req proto.Request, mod config
 		return StreamStart{}, err
 	}
 
-	st := client.Request(ctx, req)
-	return StreamStart{Stream: st, Model: mod, Messages: req.Messages}, nil
+	reqCtx, cancel := context.WithCancel(ctx)
+	watch := newWatchedStream(cancel, cfg.FirstTokenTimeout, cfg.IdleTimeout)
+	st := watch.wrap(client.Request(reqCtx, req))
+	if guard != nil {
+		st = &guardedStream{Stream: st, guard: guard}
+	}
+	if cfg.Hooks.PostResponse != "" {
+		st = &hookedStream{Stream: st, ctx: ctx, command: cfg.Hooks.PostResponse, mod: mod}
+	}
+	n := 0
+	for _, serverTools := range tools {
+		n += len(serverTools)
+	}
+	return StreamStart{Stream: st, Model: mod, Messages: req.Messages, Tools: n}, nil
 }
 
 // ApplyHTTPConfig configures the provider HTTP client with hardened transport
-// timeouts and an optional HTTP proxy.
-func ApplyHTTPConfig(httpProxy string, providerCfg *provider.Config) error {
-	if err := requestbuilder.ApplyHTTPConfig(httpProxy, providerCfg); err != nil {
+// timeouts, a connect timeout, a wait for the response headers of
+// firstTokenTimeout, and an optional HTTP proxy.
+func ApplyHTTPConfig(httpProxy string, connectTimeout, firstTokenTimeout time.Duration, providerCfg *provider.Config) error {
+	if err := requestbuilder.ApplyHTTPConfig(httpProxy, connectTimeout, firstTokenTimeout, providerCfg); err != nil {
 		return fmt.Errorf("apply http config: %w", err)
 	}
 	return nil
 }
 
-// NewFantasyClient creates the fantasy bridge client.
+// NewFantasyClient creates the fantasy bridge client, or the plugin client
+// for APIs answered by a provider plugin.
 func NewFantasyClient(cfg provider.Config) (stream.Client, error) {
 	if cfg.API == "" {
 		return nil, errs.Error{Reason: "missing fantasy provider configuration"}
 	}
+	if cfg.Plugin != "" {
+		client, err := plugin.New(plugin.Config{Command: cfg.Plugin, API: cfg.API, BaseURL: cfg.BaseURL, APIKey: cfg.APIKey})
+		if err != nil {
+			return nil, errs.Wrap(err, "Invalid provider plugin of "+cfg.API+".")
+		}
+		return client, nil
+	}
 	client, err := provider.New(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("new fantasy bridge client: %w", err)
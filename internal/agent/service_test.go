@@ -5,8 +5,9 @@ import (
 	"testing"
 
 	"github.com/dotcommander/yai/internal/config"
-	"github.com/dotcommander/yai/internal/provider"
+	"github.com/dotcommander/yai/internal/plugin"
 	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/provider"
 	"github.com/dotcommander/yai/internal/stream"
 	"github.com/stretchr/testify/require"
 )
@@ -34,6 +35,15 @@ func TestNewFantasyClientRouting(t *testing.T) {
 		require.NotNil(t, client)
 	})
 
+	t.Run("plugin api returns plugin client", func(t *testing.T) {
+		client, err := NewFantasyClient(provider.Config{API: "gateway", Plugin: "yai-gateway --region eu"})
+		require.NoError(t, err)
+		require.IsType(t, &plugin.Client{}, client)
+
+		_, err = NewFantasyClient(provider.Config{API: "gateway", Plugin: `"unterminated`})
+		require.Error(t, err)
+	})
+
 	t.Run("cohere returns fantasy client", func(t *testing.T) {
 		client, err := NewFantasyClient(
 			provider.Config{API: "cohere", APIKey: "token", BaseURL: "https://api.cohere.com/v1"},
@@ -75,7 +85,7 @@ func TestNewFantasyClientRouting(t *testing.T) {
 
 func TestApplyHTTPConfigIncludesFantasyClient(t *testing.T) {
 	providerCfg := provider.Config{}
-	err := ApplyHTTPConfig("http://127.0.0.1:8080", &providerCfg)
+	err := ApplyHTTPConfig("http://127.0.0.1:8080", 0, 0, &providerCfg)
 	require.NoError(t, err)
 	require.NotNil(t, providerCfg.HTTPClient)
 }
@@ -216,6 +226,8 @@ func (s *stubStream) Close() error                      { return nil }
 func (s *stubStream) Messages() []proto.Message         { return nil }
 func (s *stubStream) CallTools() []proto.ToolCallStatus { return nil }
 func (s *stubStream) DrainWarnings() []string           { return nil }
+func (s *stubStream) Usage() proto.Usage                { return proto.Usage{} }
+func (s *stubStream) ResponseMeta() proto.ResponseMeta  { return proto.ResponseMeta{} }
 
 type captureClient struct {
 	lastRequest *proto.Request
@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+)
+
+// Spend keeps track of the requests a Service makes on its own, to
+// summarize the start of a conversation or to answer an MCP server. Check
+// is asked before each of them with the settings it is sent with, and
+// Record is given the messages of its answer.
+type Spend struct {
+	Check  func(cfg *config.Config) error
+	Record func(cfg *config.Config, messages []proto.Message)
+}
+
+type spendKey struct{}
+
+// WithSpend returns ctx keeping track of the requests the streams started
+// with it make on their own with spend.
+func WithSpend(ctx context.Context, spend Spend) context.Context {
+	return context.WithValue(ctx, spendKey{}, spend)
+}
+
+// spendOf returns the Spend of ctx, whose functions do nothing when it has
+// none.
+func spendOf(ctx context.Context) Spend {
+	spend, _ := ctx.Value(spendKey{}).(Spend)
+	if spend.Check == nil {
+		spend.Check = func(*config.Config) error { return nil }
+	}
+	if spend.Record == nil {
+		spend.Record = func(*config.Config, []proto.Message) {}
+	}
+	return spend
+}
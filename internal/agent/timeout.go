@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/stream"
+)
+
+// StreamTimeoutError reports that a stream was cancelled because the model
+// stopped sending chunks.
+type StreamTimeoutError struct {
+	// FirstToken is set when nothing arrived since the request (or the step
+	// after a tool call) started.
+	FirstToken bool
+	After      time.Duration
+}
+
+func (e *StreamTimeoutError) Error() string {
+	if e.FirstToken {
+		return fmt.Sprintf("no response from the model within %s (first-token-timeout)", e.After)
+	}
+	return fmt.Sprintf("the model sent nothing for %s (idle-timeout)", e.After)
+}
+
+// watchedStream cancels a stream whose first chunk takes longer than
+// firstToken, or that goes quiet for longer than idle between chunks. Tool
+// calls are not timed; the next step gets firstToken again. A zero or
+// negative duration disables that check.
+type watchedStream struct {
+	stream.Stream
+
+	cancel     context.CancelFunc
+	firstToken time.Duration
+	idle       time.Duration
+
+	mu    sync.Mutex
+	timer *time.Timer
+	gen   int
+	err   error
+}
+
+// newWatchedStream arms the first-token timer. Call it before starting the
+// request so that connecting counts towards the wait, then wrap the stream.
+func newWatchedStream(cancel context.CancelFunc, firstToken, idle time.Duration) *watchedStream {
+	w := &watchedStream{cancel: cancel, firstToken: firstToken, idle: idle}
+	w.arm(firstToken, true)
+	return w
+}
+
+func (w *watchedStream) wrap(st stream.Stream) stream.Stream {
+	w.Stream = st
+	return w
+}
+
+// arm replaces the running timer with one that fires after d. Timers from
+// earlier generations are ignored even if they already fired.
+func (w *watchedStream) arm(d time.Duration, firstToken bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	w.gen++
+	if d <= 0 || w.err != nil {
+		return
+	}
+	gen := w.gen
+	w.timer = time.AfterFunc(d, func() {
+		w.mu.Lock()
+		if gen != w.gen || w.err != nil {
+			w.mu.Unlock()
+			return
+		}
+		w.err = &StreamTimeoutError{FirstToken: firstToken, After: d}
+		w.mu.Unlock()
+		w.cancel()
+	})
+}
+
+func (w *watchedStream) Next() bool {
+	if w.Stream.Next() {
+		w.arm(w.idle, false)
+		return true
+	}
+	w.arm(0, false)
+	return false
+}
+
+func (w *watchedStream) CallTools() []proto.ToolCallStatus {
+	statuses := w.Stream.CallTools()
+	if len(statuses) > 0 {
+		w.arm(w.firstToken, true)
+	}
+	return statuses
+}
+
+func (w *watchedStream) Err() error {
+	w.mu.Lock()
+	err := w.err
+	w.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return w.Stream.Err() //nolint:wrapcheck
+}
+
+func (w *watchedStream) Close() error {
+	w.arm(0, false)
+	w.cancel()
+	return w.Stream.Close() //nolint:wrapcheck
+}
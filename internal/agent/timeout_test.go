@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// delayedStream yields one chunk after each delay, giving up when ctx is
+// cancelled.
+type delayedStream struct {
+	stubStream
+	ctx    context.Context
+	delays []time.Duration
+	err    error
+}
+
+func (s *delayedStream) Next() bool {
+	if len(s.delays) == 0 {
+		return false
+	}
+	select {
+	case <-time.After(s.delays[0]):
+		s.delays = s.delays[1:]
+		return true
+	case <-s.ctx.Done():
+		s.err = s.ctx.Err()
+		return false
+	}
+}
+
+func (s *delayedStream) Err() error { return s.err }
+
+func TestWatchedStream(t *testing.T) {
+	const tick = 20 * time.Millisecond
+
+	watch := func(firstToken, idle time.Duration, delays ...time.Duration) *watchedStream {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		w := newWatchedStream(cancel, firstToken, idle)
+		w.wrap(&delayedStream{ctx: ctx, delays: delays})
+		return w
+	}
+	drain := func(w *watchedStream) int {
+		n := 0
+		for w.Next() {
+			n++
+		}
+		return n
+	}
+
+	t.Run("lets steady streams run past both timeouts", func(t *testing.T) {
+		w := watch(5*tick, 5*tick, tick, 2*tick, 2*tick, 2*tick, 2*tick)
+		require.Equal(t, 5, drain(w))
+		require.NoError(t, w.Err())
+	})
+
+	t.Run("times out waiting for the first chunk", func(t *testing.T) {
+		w := watch(tick, time.Hour, time.Hour)
+		require.Zero(t, drain(w))
+		var timeoutErr *StreamTimeoutError
+		require.True(t, errors.As(w.Err(), &timeoutErr))
+		require.True(t, timeoutErr.FirstToken)
+		require.Equal(t, tick, timeoutErr.After)
+	})
+
+	t.Run("times out when the stream goes idle", func(t *testing.T) {
+		w := watch(time.Hour, 2*tick, 0, 0, time.Hour)
+		require.Equal(t, 2, drain(w))
+		var timeoutErr *StreamTimeoutError
+		require.True(t, errors.As(w.Err(), &timeoutErr))
+		require.False(t, timeoutErr.FirstToken)
+		require.EqualError(t, w.Err(), "the model sent nothing for 40ms (idle-timeout)")
+	})
+
+	t.Run("negative durations disable the checks", func(t *testing.T) {
+		w := watch(-1, -1, 3*tick, 3*tick)
+		require.Equal(t, 2, drain(w))
+		require.NoError(t, w.Err())
+	})
+}
+
+func TestActionForStreamTimeout(t *testing.T) {
+	svc := New(&config.Config{}, nil, nil)
+	mod := config.Model{API: "openai"}
+
+	action := svc.ActionForStreamError(&StreamTimeoutError{After: time.Minute}, mod, "hi", false)
+	require.True(t, action.Retry)
+	require.Equal(t, RetryNetwork, action.Class)
+	require.Equal(t, "hi", action.Prompt)
+	require.Equal(t, "The openai API stopped responding.", action.Err.ReasonText())
+}
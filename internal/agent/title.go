@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+)
+
+const (
+	titleInstruction = "Write a short title (at most 8 words) describing the topic of this conversation so far. " +
+		"Reply with the title only."
+	maxTitleChars = 80
+)
+
+// Title asks the utility model for a short title describing history.
+func (s *Service) Title(ctx context.Context, history []proto.Message) (string, error) {
+	res, err := s.utilityService().Complete(ctx, history, titleInstruction)
+	if err != nil {
+		return "", err
+	}
+	return cleanTitle(res.Response), nil
+}
+
+// utilityService is the service for auxiliary requests about a
+// conversation, on the utility model. Roles, format text, reply-language,
+// and MCP tools are left out so they do not steer the answer, and the
+// oldest turns of a long conversation are dropped to fit.
+func (s *Service) utilityService() *Service {
+	cfg := *s.cfg
+	cfg.Role = ""
+	cfg.Format = false
+	cfg.ReplyLanguage = ""
+	cfg.Prefix = ""
+	cfg.MCPDisable = []string{"*"}
+	cfg.ContextStrategy = ContextDropOldest
+	UseUtilityModel(&cfg)
+	return New(&cfg, nil, nil, s.clientFactory)
+}
+
+// cleanTitle keeps the first non-empty line of a model reply and strips the
+// decoration models tend to add around titles.
+func cleanTitle(s string) string {
+	for line := range strings.SplitSeq(s, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimPrefix(line, "Title:")
+		// Models wrap titles in quotes or markdown, with a period either
+		// inside or outside of them.
+		line = strings.TrimRight(line, ".")
+		line = strings.Trim(line, " \t\"'`*#")
+		line = strings.TrimRight(line, ".")
+		if line == "" {
+			continue
+		}
+		if r := []rune(line); len(r) > maxTitleChars {
+			line = strings.TrimSpace(string(r[:maxTitleChars]))
+		}
+		return line
+	}
+	return ""
+}
+
+// UseUtilityModel switches cfg to the utility-model, the model for auxiliary
+// requests such as titles, when one is set. It is looked up like --model:
+// in the current API when that API has it, in any API otherwise.
+func UseUtilityModel(cfg *config.Config) {
+	if cfg.UtilityModel == "" {
+		return
+	}
+	cfg.Model = cfg.UtilityModel
+	cfg.FallbackFrom = ""
+	for _, api := range cfg.APIs {
+		if api.Name != cfg.API {
+			continue
+		}
+		for name, mod := range api.Models {
+			if name == cfg.Model || slices.Contains(mod.Aliases, cfg.Model) {
+				return
+			}
+		}
+	}
+	cfg.API = ""
+}
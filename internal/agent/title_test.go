@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/provider"
+	"github.com/dotcommander/yai/internal/stream"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTitle(t *testing.T) {
+	client := &scriptedClient{streams: []*scriptedStream{{
+		chunks: []string{"Title: \"Retry budgets", " in Go\".\n"},
+	}}}
+	cfg := testCompleteConfig()
+	cfg.Role = "missing-role"
+	svc := New(cfg, nil, nil, func(provider.Config) (stream.Client, error) {
+		return client, nil
+	})
+
+	title, err := svc.Title(context.Background(), []proto.Message{
+		{Role: proto.RoleUser, Content: "how do I retry?"},
+		{Role: proto.RoleAssistant, Content: "with a budget"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Retry budgets in Go", title)
+	require.Equal(t, "missing-role", cfg.Role, "caller config must not change")
+}
+
+func TestTitle_UtilityModel(t *testing.T) {
+	cfg := testCompleteConfig()
+	cfg.APIs = append(cfg.APIs, config.API{
+		Name:   "anthropic",
+		APIKey: "test-key",
+		Models: map[string]config.Model{
+			"claude-haiku-4-5": {Aliases: []string{"haiku"}, MaxChars: 100000},
+		},
+	})
+	cfg.UtilityModel = "haiku"
+	var api string
+	svc := New(cfg, nil, nil, func(pc provider.Config) (stream.Client, error) {
+		api = pc.API
+		return &scriptedClient{streams: []*scriptedStream{{chunks: []string{"Haiku title"}}}}, nil
+	})
+
+	title, err := svc.Title(context.Background(), []proto.Message{{Role: proto.RoleUser, Content: "hi"}})
+	require.NoError(t, err)
+	require.Equal(t, "Haiku title", title)
+	require.Equal(t, "anthropic", api)
+	require.Equal(t, "gpt-4.1-mini", cfg.Model, "caller config must not change")
+}
+
+func TestUseUtilityModel(t *testing.T) {
+	cfg := testCompleteConfig()
+	UseUtilityModel(cfg)
+	require.Equal(t, "gpt-4.1-mini", cfg.Model, "no utility-model keeps the model")
+
+	cfg.APIs[0].Models["gpt-4.1-nano"] = config.Model{}
+	cfg.UtilityModel = "gpt-4.1-nano"
+	UseUtilityModel(cfg)
+	require.Equal(t, "gpt-4.1-nano", cfg.Model)
+	require.Equal(t, "openai", cfg.API, "a model of the current API keeps the API")
+
+	cfg.UtilityModel = "elsewhere"
+	UseUtilityModel(cfg)
+	require.Equal(t, "elsewhere", cfg.Model)
+	require.Empty(t, cfg.API, "other models are looked up in every API")
+}
+
+func TestCleanTitle(t *testing.T) {
+	require.Equal(t, "Shell tricks", cleanTitle("\n  **Shell tricks**  \nmore"))
+	require.Equal(t, "Quoted", cleanTitle(`'Quoted'.`))
+	require.Empty(t, cleanTitle(" \n\t"))
+	require.LessOrEqual(t, len(cleanTitle(strings.Repeat("word ", 40))), maxTitleChars)
+}
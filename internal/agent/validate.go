@@ -0,0 +1,204 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/requestbuilder"
+)
+
+// Check is one thing Validate checked. Hint is the detail behind a failed
+// check, when the error has one.
+type Check struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+	Hint   string `json:"hint,omitempty"`
+}
+
+// Report is what Validate found out about a request, in the order it was
+// checked: model, key, request, tools, and context. Checks stop at the
+// first that leaves nothing to check after it.
+type Report struct {
+	API   string `json:"api,omitempty"`
+	Model string `json:"model,omitempty"`
+	// Messages is the number of messages the request sends, and
+	// InputTokens an estimate of their tokens, after context-strategy.
+	Messages    int   `json:"messages"`
+	InputTokens int64 `json:"input_tokens"`
+	// InputLimit is the tokens of input the model's context-window leaves
+	// room for, or 0 when it has none.
+	InputLimit int64 `json:"input_limit,omitempty"`
+	// InputCost is the estimated price of the input, from the model's
+	// input-cost.
+	InputCost float64 `json:"input_cost,omitempty"`
+	// Tools are the MCP tools offered to the model, named as it sees them.
+	Tools  []string `json:"tools,omitempty"`
+	Checks []Check  `json:"checks"`
+}
+
+// OK reports whether every check passed.
+func (r Report) OK() bool {
+	for _, check := range r.Checks {
+		if !check.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// Add adds the check name to r: passed with detail when err is nil, failed
+// with err otherwise. Callers add the checks of their own that come before
+// a request is sent.
+func (r *Report) Add(name, detail string, err error) {
+	if err == nil {
+		r.Checks = append(r.Checks, Check{Name: name, OK: true, Detail: detail})
+		return
+	}
+	check := Check{Name: name, Detail: err.Error()}
+	var reason errs.Error
+	if errors.As(err, &reason) && reason.Err != nil {
+		check.Detail, check.Hint = reason.Reason, reason.Err.Error()
+	}
+	r.Checks = append(r.Checks, check)
+}
+
+// Validate goes through what Stream does before it streams prompt: it
+// resolves the model, finds its API key, builds the request, lists the MCP
+// tools, and estimates the tokens against the model's limits. Nothing is
+// sent to the model, and a conversation that context-strategy would
+// summarize is measured with its oldest turns left out instead.
+func (s *Service) Validate(ctx context.Context, prompt string) Report {
+	var report Report
+	pass := func(name, detail string) {
+		report.Add(name, detail, nil)
+	}
+	fail := func(name string, err error) {
+		report.Add(name, "", err)
+	}
+
+	cfg := *s.cfg
+	api, mod, err := requestbuilder.ResolveModel(&cfg)
+	if err != nil {
+		fail("model", err)
+		return report
+	}
+	report.API, report.Model = api.Name, mod.Name
+	pass("model", mod.Name+" on "+api.Name)
+
+	providerCfg, err := requestbuilder.PrepareProviderConfig(ctx, mod, api, &cfg)
+	if err == nil {
+		err = requestbuilder.ApplyHTTPConfig(cfg.HTTPProxy, cfg.ConnectTimeout, cfg.FirstTokenTimeout, &providerCfg)
+	}
+	switch {
+	case err != nil:
+		fail("key", err)
+	case providerCfg.APIKey == "":
+		pass("key", "none needed")
+	default:
+		pass("key", "found")
+	}
+
+	req, err := requestbuilder.BuildRequestFromPrompt(&cfg, mod, s.cache, prompt)
+	if err != nil {
+		fail("request", err)
+		return report
+	}
+	if len(req.Messages) == 1 {
+		pass("request", "1 message")
+	} else {
+		pass("request", fmt.Sprintf("%d messages", len(req.Messages)))
+	}
+
+	switch tools, err := s.validateTools(ctx, &cfg); {
+	case err != nil:
+		fail("tools", err)
+	case tools == nil:
+		pass("tools", "not offered to piped input without --mcp-allow-non-tty")
+	default:
+		report.Tools = tools
+		pass("tools", fmt.Sprintf("%d offered", len(tools)))
+	}
+
+	messages, notes, err := fitContextEstimate(&cfg, mod, req.Messages)
+	report.Messages = len(messages)
+	report.InputTokens = proto.EstimateTokens(messages)
+	report.InputLimit = requestbuilder.ContextBudget(&cfg, mod)
+	report.InputCost = float64(report.InputTokens) * mod.InputCost / 1_000_000
+	if err != nil {
+		fail("context", err)
+		return report
+	}
+	detail := fmt.Sprintf("about %d tokens", report.InputTokens)
+	if report.InputLimit > 0 {
+		detail += fmt.Sprintf(" of %d", report.InputLimit)
+	}
+	if report.InputCost > 0 {
+		detail += fmt.Sprintf(" (~$%.4f)", report.InputCost)
+	}
+	if cfg.Prefix != "" {
+		prompt = strings.TrimSpace(cfg.Prefix + "\n\n" + prompt)
+	}
+	if len(messages) > 0 && len(messages[len(messages)-1].Content) < len(prompt) {
+		notes = append(notes, "the input will be cut to fit")
+	}
+	pass("context", strings.Join(append([]string{detail}, notes...), "; "))
+	return report
+}
+
+// validateTools lists the MCP tools a request offers, or returns nil when
+// it offers none because the input is piped.
+func (s *Service) validateTools(ctx context.Context, cfg *config.Config) ([]string, error) {
+	if !cfg.MCPAllowNonTTY && !present.IsInputTTY() {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, cfg.MCPTimeout)
+	defer cancel()
+	servers, err := s.mcp.Tools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("mcp tools: %w", err)
+	}
+	names := []string{}
+	for _, server := range slices.Sorted(maps.Keys(servers)) {
+		for _, tool := range servers[server] {
+			names = append(names, fmt.Sprintf("%s_%s", server, tool.Name))
+		}
+	}
+	return names, nil
+}
+
+// fitContextEstimate fits messages into the history budget of mod the way
+// fitContext does, without asking for a summary, and notes what it would
+// do.
+func fitContextEstimate(cfg *config.Config, mod config.Model, messages []proto.Message) ([]proto.Message, []string, error) {
+	if err := CheckContextStrategy(cfg.ContextStrategy); err != nil {
+		return messages, nil, err
+	}
+	if len(messages) == 0 {
+		return messages, nil, nil
+	}
+	var notes []string
+	last := messages[len(messages)-1]
+	system, history := requestbuilder.SplitSystem(messages[:len(messages)-1])
+	budget := requestbuilder.HistoryBudget(cfg, mod)
+	if tokens := proto.EstimateTokens(history); budget > 0 && tokens > budget {
+		switch cfg.ContextStrategy {
+		case ContextError:
+			return messages, nil, errContextTooLong(mod, tokens, budget)
+		case ContextSummarize:
+			notes = append(notes, "the oldest turns will be summarized")
+		default:
+			notes = append(notes, "the oldest turns will be left out")
+		}
+		history = requestbuilder.WindowHistory(history, budget)
+	}
+	return slices.Concat(system, history, []proto.Message{last}), notes, nil
+}
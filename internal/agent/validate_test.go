@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/provider"
+	"github.com/dotcommander/yai/internal/storage/cache"
+	"github.com/dotcommander/yai/internal/stream"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	newService := func(cfg *config.Config, convos *cache.Conversations) (*Service, *recordingClient) {
+		client := &recordingClient{}
+		return New(cfg, convos, nil, func(provider.Config) (stream.Client, error) {
+			return client, nil
+		}), client
+	}
+	names := func(report Report) []string {
+		var names []string
+		for _, check := range report.Checks {
+			names = append(names, check.Name)
+		}
+		return names
+	}
+
+	t.Run("ready", func(t *testing.T) {
+		cfg := testCompleteConfig()
+		cfg.APIs[0].Models["gpt-4.1-mini"] = config.Model{MaxChars: 100000, InputCost: 2}
+		svc, client := newService(cfg, nil)
+
+		report := svc.Validate(context.Background(), strings.Repeat("x", 4000))
+		require.True(t, report.OK(), "%+v", report.Checks)
+		require.Equal(t, []string{"model", "key", "request", "tools", "context"}, names(report))
+		require.Equal(t, "openai", report.API)
+		require.Equal(t, "gpt-4.1-mini", report.Model)
+		require.Equal(t, 1, report.Messages)
+		require.Equal(t, int64(1000), report.InputTokens)
+		require.InDelta(t, 0.002, report.InputCost, 1e-9)
+		require.Equal(t, "about 1000 tokens (~$0.0020)", report.Checks[4].Detail)
+		require.Empty(t, client.requests)
+	})
+
+	t.Run("unknown model", func(t *testing.T) {
+		cfg := testCompleteConfig()
+		cfg.Model = "gpt-nope"
+		svc, _ := newService(cfg, nil)
+
+		report := svc.Validate(context.Background(), "hi")
+		require.False(t, report.OK())
+		require.Equal(t, []string{"model"}, names(report))
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		t.Setenv("OPENAI_API_KEY", "")
+		cfg := testCompleteConfig()
+		cfg.APIs[0].APIKey = ""
+		svc, _ := newService(cfg, nil)
+
+		report := svc.Validate(context.Background(), "hi")
+		require.False(t, report.OK())
+		require.Equal(t, []string{"model", "key", "request", "tools", "context"}, names(report))
+		require.False(t, report.Checks[1].OK)
+		require.Equal(t, "OpenAI authentication failed", report.Checks[1].Detail)
+	})
+
+	t.Run("input cut to fit", func(t *testing.T) {
+		cfg := testCompleteConfig()
+		cfg.APIs[0].Models["gpt-4.1-mini"] = config.Model{ContextWindow: 800}
+		svc, _ := newService(cfg, nil)
+
+		report := svc.Validate(context.Background(), strings.Repeat("x", 5000))
+		require.True(t, report.OK(), "%+v", report.Checks)
+		require.Equal(t, int64(700), report.InputLimit)
+		require.LessOrEqual(t, report.InputTokens, report.InputLimit)
+		require.Contains(t, report.Checks[4].Detail, "the input will be cut to fit")
+	})
+
+	t.Run("long conversation", func(t *testing.T) {
+		// Four turns of 200 tokens; 525 of the 700 tokens of input fit.
+		var history []proto.Message
+		for i := range 4 {
+			history = append(history,
+				proto.Message{Role: proto.RoleUser, Content: fmt.Sprintf("question %d ", i) + strings.Repeat("q", 388)},
+				proto.Message{Role: proto.RoleAssistant, Content: fmt.Sprintf("answer %d ", i) + strings.Repeat("a", 391)},
+			)
+		}
+		convos, err := cache.NewConversations(t.TempDir())
+		require.NoError(t, err)
+		require.NoError(t, convos.Write("long", &history))
+
+		for strategy, want := range map[string]string{
+			ContextDropOldest: "the oldest turns will be left out",
+			ContextSummarize:  "the oldest turns will be summarized",
+		} {
+			cfg := testCompleteConfig()
+			cfg.APIs[0].Models["gpt-4.1-mini"] = config.Model{ContextWindow: 800}
+			cfg.CacheReadFromID = "long"
+			cfg.ContextStrategy = strategy
+			svc, client := newService(cfg, convos)
+
+			report := svc.Validate(context.Background(), "next")
+			require.True(t, report.OK(), "%+v", report.Checks)
+			require.Equal(t, 5, report.Messages, strategy)
+			require.Contains(t, report.Checks[4].Detail, want)
+			require.Empty(t, client.requests, "a summary is not asked for")
+		}
+
+		cfg := testCompleteConfig()
+		cfg.APIs[0].Models["gpt-4.1-mini"] = config.Model{ContextWindow: 800}
+		cfg.CacheReadFromID = "long"
+		cfg.ContextStrategy = ContextError
+		svc, _ := newService(cfg, convos)
+
+		report := svc.Validate(context.Background(), "next")
+		require.False(t, report.OK())
+		require.Equal(t, "context", report.Checks[4].Name)
+		require.Equal(t, "Conversation too long for the model.", report.Checks[4].Detail)
+		require.Contains(t, report.Checks[4].Hint, "about 799 tokens and gpt-4.1-mini has room for 525")
+	})
+}
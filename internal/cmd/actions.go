@@ -3,16 +3,19 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/charmbracelet/huh"
 	"github.com/dotcommander/yai/internal/config"
 	"github.com/dotcommander/yai/internal/errs"
 	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/proto"
 	"github.com/dotcommander/yai/internal/storage"
 )
 
-func listConversations(cfg *config.Config, raw bool) error {
+func listConversations(cfg *config.Config, raw, verbose bool) error {
 	store, err := openConversationStore(cfg.CachePath)
 	if err != nil {
 		return errs.Wrap(err, "Could not open conversation store.")
@@ -25,11 +28,11 @@ func listConversations(cfg *config.Config, raw bool) error {
 		return nil
 	}
 
-	if present.IsInputTTY() && present.IsOutputTTY() && !raw {
+	if present.IsInputTTY() && present.IsOutputTTY() && !raw && !verbose {
 		selectFromList(conversations)
 		return nil
 	}
-	printList(conversations)
+	printList(conversations, verbose)
 	return nil
 }
 
@@ -49,18 +52,19 @@ func deleteConversations(cfg *config.Config, targets []string) error {
 			return err
 		}
 	}
-	return nil
+	return purgeExpiredTrash(cfg, store)
 }
 
 func deleteConversationByID(cfg *config.Config, store *conversationStore, id string) error {
-	if err := store.DB.Delete(id); err != nil {
-		return fmt.Errorf("delete conversation index: %w", err)
-	}
-	if err := store.Cache.Delete(id); err != nil {
-		return fmt.Errorf("delete conversation payload: %w", err)
+	if err := trashConversation(cfg, store, id); err != nil {
+		return err
 	}
 	if !cfg.Quiet {
-		fmt.Fprintln(os.Stderr, "Conversation deleted:", id[:storage.SHA1MinLen])
+		if cfg.TrashRetention < 0 {
+			fmt.Fprintln(os.Stderr, "Conversation deleted:", id[:storage.SHA1MinLen])
+		} else {
+			fmt.Fprintln(os.Stderr, "Conversation moved to trash:", id[:storage.SHA1MinLen])
+		}
 	}
 	return nil
 }
@@ -84,36 +88,267 @@ func deleteConversationsOlderThan(cfg *config.Config, olderThanDuration string)
 		return nil
 	}
 
+	if err := confirmBulkDelete(cfg, conversations, fmt.Sprintf("Delete conversations older than %s?", olderThanDuration)); err != nil {
+		return err
+	}
+	for _, c := range conversations {
+		if err := deleteConversationByID(cfg, store, c.ID); err != nil {
+			return err
+		}
+	}
+	return purgeExpiredTrash(cfg, store)
+}
+
+// pinConversations pins or unpins the given conversations.
+func pinConversations(cfg *config.Config, targets []string, pinned bool) error {
+	store, err := openConversationStore(cfg.CachePath)
+	if err != nil {
+		return errs.Wrap(err, "Could not open conversation store.")
+	}
+	defer store.Close() //nolint:errcheck
+
+	for _, target := range targets {
+		convo, err := store.DB.Find(target)
+		if err != nil {
+			return errs.Wrap(err, "Couldn't find conversation to pin.")
+		}
+		if err := store.DB.SetPinned(convo.ID, pinned); err != nil {
+			return errs.Wrap(err, "Couldn't pin conversation.")
+		}
+		if !cfg.Quiet {
+			action := "pinned"
+			if !pinned {
+				action = "unpinned"
+			}
+			fmt.Fprintf(os.Stderr, "Conversation %s: %s\n", action, convo.ID[:storage.SHA1MinLen])
+		}
+	}
+	return nil
+}
+
+// noteConversation sets, clears, or prints the note of a conversation.
+func noteConversation(cfg *config.Config, target string, note *string) error {
+	store, err := openConversationStore(cfg.CachePath)
+	if err != nil {
+		return errs.Wrap(err, "Could not open conversation store.")
+	}
+	defer store.Close() //nolint:errcheck
+
+	convo, err := store.DB.Find(target)
+	if err != nil {
+		return errs.Wrap(err, "Couldn't find conversation.")
+	}
+	if note == nil {
+		if convo.Note == "" {
+			fmt.Fprintln(os.Stderr, "No note.")
+			return nil
+		}
+		fmt.Println(convo.Note)
+		return nil
+	}
+	if err := store.DB.SetNote(convo.ID, *note); err != nil {
+		return errs.Wrap(err, "Couldn't save the note.")
+	}
 	if !cfg.Quiet {
-		printList(conversations)
-
-		if !present.IsOutputTTY() || !present.IsInputTTY() {
-			fmt.Fprintln(os.Stderr)
-			//nolint:wrapcheck // user-facing guidance error
-			return errs.UserErrorf(
-				"To delete the conversations above, run: %s",
-				strings.Join(append(os.Args, "--quiet"), " "),
-			)
+		action := "Note saved:"
+		if *note == "" {
+			action = "Note removed:"
 		}
-		var confirm bool
-		if err := huh.Run(
-			huh.NewConfirm().
-				Title(fmt.Sprintf("Delete conversations older than %s?", olderThanDuration)).
-				Description(fmt.Sprintf("This will delete all the %d conversations listed above.", len(conversations))).
-				Value(&confirm),
-		); err != nil {
-			return errs.Wrap(err, "Couldn't delete old conversations.")
+		fmt.Fprintln(os.Stderr, action, convo.ID[:storage.SHA1MinLen])
+	}
+	return nil
+}
+
+// compactConversationStore rewrites the index with one record per
+// conversation and removes stale lock files and empty shard directories.
+func compactConversationStore(cfg *config.Config) error {
+	store, err := openConversationStore(cfg.CachePath)
+	if err != nil {
+		return errs.Wrap(err, "Could not open conversation store.")
+	}
+	defer store.Close() //nolint:errcheck
+
+	dir := filepath.Join(cfg.CachePath, "conversations")
+	indexBefore, err := store.DB.IndexSize()
+	if err != nil {
+		return errs.Wrap(err, "Could not compact conversations.")
+	}
+	storeBefore, err := dirSize(dir)
+	if err != nil {
+		return errs.Wrap(err, "Could not compact conversations.")
+	}
+
+	if err := purgeExpiredTrash(cfg, store); err != nil {
+		return errs.Wrap(err, "Could not compact conversations.")
+	}
+	if err := store.DB.Compact(); err != nil {
+		return errs.Wrap(err, "Could not compact conversations.")
+	}
+	vacuum, err := store.Cache.Vacuum()
+	if err != nil {
+		return errs.Wrap(err, "Could not compact conversations.")
+	}
+
+	indexAfter, err := store.DB.IndexSize()
+	if err != nil {
+		return errs.Wrap(err, "Could not compact conversations.")
+	}
+	storeAfter, err := dirSize(dir)
+	if err != nil {
+		return errs.Wrap(err, "Could not compact conversations.")
+	}
+
+	if !cfg.Quiet {
+		fmt.Fprintf(os.Stderr, "Index: %s -> %s\n", formatBytes(indexBefore), formatBytes(indexAfter))
+		fmt.Fprintf(os.Stderr, "Store: %s -> %s\n", formatBytes(storeBefore), formatBytes(storeAfter))
+		fmt.Fprintf(os.Stderr, "Removed %d stale lock files and %d empty directories.\n", vacuum.StaleLocks, vacuum.EmptyDirs)
+	}
+	return nil
+}
+
+// conversationFilter selects conversations for `history delete --match`.
+// Unset fields match every conversation.
+type conversationFilter struct {
+	match *regexp.Regexp
+	api   string
+	model string
+}
+
+func (f conversationFilter) empty() bool {
+	return f.match == nil && f.api == "" && f.model == ""
+}
+
+func (f conversationFilter) matches(c storage.Conversation) bool {
+	if f.match != nil && !f.match.MatchString(c.Title) {
+		return false
+	}
+	if f.api != "" && (c.API == nil || *c.API != f.api) {
+		return false
+	}
+	if f.model != "" && (c.Model == nil || *c.Model != f.model) {
+		return false
+	}
+	return true
+}
+
+// deleteMatchingConversations deletes every conversation the filter selects,
+// after showing them and asking for confirmation.
+func deleteMatchingConversations(cfg *config.Config, filter conversationFilter) error {
+	store, err := openConversationStore(cfg.CachePath)
+	if err != nil {
+		return errs.Wrap(err, "Could not open conversation store.")
+	}
+	defer store.Close() //nolint:errcheck
+
+	var conversations []storage.Conversation
+	for _, c := range store.DB.List() {
+		if filter.matches(c) {
+			conversations = append(conversations, c)
 		}
-		if !confirm {
-			//nolint:wrapcheck // user-facing abort
-			return errs.UserErrorf("Aborted by user")
+	}
+	if len(conversations) == 0 {
+		if !cfg.Quiet {
+			fmt.Fprintln(os.Stderr, "No conversations found.")
 		}
+		return nil
 	}
 
+	if err := confirmBulkDelete(cfg, conversations, "Delete matching conversations?"); err != nil {
+		return err
+	}
 	for _, c := range conversations {
 		if err := deleteConversationByID(cfg, store, c.ID); err != nil {
 			return err
 		}
 	}
+	return purgeExpiredTrash(cfg, store)
+}
+
+// confirmBulkDelete lists the conversations about to be deleted and asks
+// before going ahead. --quiet skips both; without a terminal to ask on, it
+// fails with the command to run instead.
+func confirmBulkDelete(cfg *config.Config, conversations []storage.Conversation, title string) error {
+	if cfg.Quiet {
+		return nil
+	}
+	printList(conversations, false)
+
+	if !present.IsOutputTTY() || !present.IsInputTTY() {
+		fmt.Fprintln(os.Stderr)
+		//nolint:wrapcheck // user-facing guidance error
+		return errs.UserErrorf(
+			"To delete the conversations above, run: %s",
+			strings.Join(append(os.Args, "--quiet"), " "),
+		)
+	}
+	var confirm bool
+	if err := huh.Run(
+		huh.NewConfirm().
+			Title(title).
+			Description(fmt.Sprintf("This will delete all the %d conversations listed above.", len(conversations))).
+			Value(&confirm),
+	); err != nil {
+		return errs.Wrap(err, "Couldn't delete conversations.")
+	}
+	if !confirm {
+		//nolint:wrapcheck // user-facing abort
+		return errs.UserErrorf("Aborted by user")
+	}
+	return nil
+}
+
+// appendToConversation adds a message with the given role to a saved
+// conversation without calling a model. The conversation keeps its title and
+// API/model metadata.
+func appendToConversation(cfg *config.Config, target, role, content string) error {
+	if role != proto.RoleUser && role != proto.RoleAssistant {
+		return errs.Wrap(errs.UserErrorf("--role must be %q or %q, got %q", proto.RoleUser, proto.RoleAssistant, role), "Could not append to conversation.")
+	}
+	if strings.TrimSpace(content) == "" {
+		return errs.Wrap(errs.UserErrorf("message is empty"), "Could not append to conversation.")
+	}
+
+	store, err := openConversationStore(cfg.CachePath)
+	if err != nil {
+		return errs.Wrap(err, "Could not open conversation store.")
+	}
+	defer store.Close() //nolint:errcheck
+
+	convo, err := store.DB.Find(target)
+	if err != nil {
+		return errs.Wrap(err, "Couldn't find conversation to append to.")
+	}
+	if err := store.lock(convo.ID); err != nil {
+		return err
+	}
+
+	var messages []proto.Message
+	if err := store.Cache.Read(convo.ID, &messages); err != nil {
+		return errs.Wrap(err, "There was an error loading the conversation.")
+	}
+	messages = append(messages, proto.Message{Role: role, Content: content})
+
+	var api, model string
+	if convo.API != nil {
+		api = *convo.API
+	}
+	if convo.Model != nil {
+		model = *convo.Model
+	}
+	if err := store.Cache.Write(convo.ID, &messages); err != nil {
+		return errs.Wrap(err, "There was a problem writing the conversation.")
+	}
+	if err := store.DB.Save(convo.ID, convo.Title, api, model); err != nil {
+		return errs.Wrap(err, "There was a problem writing the conversation.")
+	}
+
+	if !cfg.Quiet {
+		fmt.Fprintln(
+			os.Stderr,
+			"Message appended:",
+			present.StderrStyles().InlineCode.Render(convo.ID[:storage.SHA1Short]),
+			present.StderrStyles().Comment.Render(convo.Title),
+		)
+	}
 	return nil
 }
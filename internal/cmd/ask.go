@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/provider"
+	"github.com/dotcommander/yai/internal/rag"
+	"github.com/dotcommander/yai/internal/storage"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+type askOptions struct {
+	knowledge []string
+	history   bool
+	sources   int
+}
+
+func newAskCmd(rt *runtime) *cobra.Command {
+	opts := askOptions{sources: 5}
+	cmd := &cobra.Command{
+		Use:   "ask [prompt]",
+		Short: "Answer a prompt using local files and saved conversations as knowledge",
+		Long: "Answer a prompt with the most relevant chunks of the given files and directories (and, optionally,\n" +
+			"saved conversations) included as numbered sources the model cites. Knowledge is embedded into a\n" +
+			"local index under the cache path and only changed files are re-embedded on later runs.",
+		Example: `  yai ask --knowledge ./docs "how do I configure retries?"
+  git diff | yai ask -k ./docs -k README.md "does this change need a docs update?"`,
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			if len(opts.knowledge) == 0 && !opts.history {
+				return errs.Wrap(
+					errs.UserErrorf("Pass --knowledge <path> and/or --knowledge-history."),
+					"No knowledge sources given.",
+				)
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return rt.runAsk(ctx, cmd.Flags(), args, opts)
+		},
+	}
+
+	registerSharedFlags(cmd, &rt.cfg)
+	flags := cmd.Flags()
+	s := present.StdoutStyles().FlagDesc
+	flags.StringArrayVarP(&opts.knowledge, "knowledge", "k", nil, s.Render(helpText["knowledge"]))
+	flags.BoolVar(&opts.history, "knowledge-history", false, s.Render(helpText["knowledge-history"]))
+	flags.IntVar(&opts.sources, "sources", opts.sources, s.Render(helpText["knowledge-sources"]))
+	flags.StringVar(&rt.cfg.EmbedModel, "embed-model", rt.cfg.EmbedModel, s.Render(helpText["embed-model"]))
+	flags.SortFlags = false
+	cmd.MarkFlagsMutuallyExclusive("continue", "continue-last")
+
+	return cmd
+}
+
+func (rt *runtime) runAsk(ctx context.Context, flags *pflag.FlagSet, args []string, opts askOptions) error {
+	rt.cfg.Prefix = present.RemoveWhitespace(strings.Join(args, " "))
+
+	store, err := rt.openAndPlanStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close() //nolint:errcheck
+
+	if err := validateCapabilities(flags, &rt.cfg); err != nil {
+		return err
+	}
+	if err := checkNotify(&rt.cfg); err != nil {
+		return err
+	}
+	if err := checkSpinner(&rt.cfg); err != nil {
+		return err
+	}
+	if err := checkBudget(&rt.cfg); err != nil {
+		return err
+	}
+	if err := rt.redactPrompt(); err != nil {
+		return err
+	}
+	if err := rt.confirmSend(store); err != nil {
+		return err
+	}
+
+	embedSvc := agent.New(&rt.cfg, nil, nil)
+	embed := func(ctx context.Context, inputs []string) ([][]float64, error) {
+		res, err := embedSvc.Embed(ctx, inputs)
+		return res.Vectors, err
+	}
+
+	ix, err := rt.syncKnowledge(ctx, store, opts, embed)
+	if err != nil {
+		return err
+	}
+
+	var (
+		lastPrompt string
+		sources    []rag.Result
+	)
+	rt.promptHook = func(ctx context.Context, prompt string) (string, error) {
+		// Retries restart the stream with the same prompt; reuse the lookup.
+		if prompt != lastPrompt || sources == nil {
+			results, err := rag.Retrieve(ctx, ix, prompt, opts.sources, embed)
+			if err != nil {
+				return "", errs.Wrap(err, "Could not search knowledge.")
+			}
+			lastPrompt, sources = prompt, results
+		}
+		return rag.BuildPrompt(prompt, sources), nil
+	}
+	defer func() { rt.promptHook = nil }()
+
+	yai, err := rt.runGenerateProgram(ctx, rt.programOptions(), store)
+	if err != nil {
+		return err
+	}
+	if err := rt.ensurePromptInput(yai); err != nil {
+		return err
+	}
+	rt.printGenerateOutput(yai)
+	printFallbackNote(&rt.cfg)
+	if len(sources) > 0 && !rt.cfg.Quiet {
+		fmt.Fprint(os.Stderr, formatSources(present.StderrStyles(), sources))
+	}
+	if err := saveConversation(&rt.cfg, store, yai.Messages()); err != nil {
+		return err
+	}
+	if yai.Canceled {
+		return rt.interruptError()
+	}
+	refreshTitle(ctx, &rt.cfg, store, yai.Messages(), 1)
+	return nil
+}
+
+// formatSources renders the citation list printed after an answer.
+func formatSources(s present.Styles, sources []rag.Result) string {
+	return "\n" + s.Comment.Render("Sources:") + "\n" + rag.FormatSources(sources)
+}
+
+// syncKnowledge loads the index for the requested knowledge set and embeds
+// any new or changed documents.
+func (rt *runtime) syncKnowledge(ctx context.Context, store *conversationStore, opts askOptions, embed rag.EmbedFunc) (*rag.Index, error) {
+	docs, err := rag.CollectFiles(opts.knowledge)
+	if err != nil {
+		return nil, errs.Wrap(err, "Could not read knowledge files.")
+	}
+	if opts.history {
+		historyDocs, err := conversationDocuments(store)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, historyDocs...)
+	}
+	if len(docs) == 0 {
+		return nil, errs.Wrap(errs.UserErrorf("No text files found in %s.", strings.Join(opts.knowledge, ", ")), "No knowledge to search.")
+	}
+
+	model := rt.cfg.EmbedModel
+	if model == "" {
+		model = provider.DefaultEmbedModel(rt.cfg.API)
+	}
+	key := []string{rt.cfg.API, model, fmt.Sprint(opts.history)}
+	for _, p := range opts.knowledge {
+		if abs, err := filepath.Abs(p); err == nil {
+			p = abs
+		}
+		key = append(key, p)
+	}
+	slices.Sort(key[3:])
+
+	ix, err := rag.Open(rag.IndexPath(rt.cfg.CachePath, key...), model)
+	if err != nil {
+		return nil, errs.Wrap(err, "Could not open knowledge index.")
+	}
+	stats, err := ix.Sync(ctx, docs, embed)
+	if err != nil {
+		return nil, errs.Wrap(err, "Could not index knowledge.")
+	}
+	if stats.Added+stats.Updated+stats.Removed > 0 {
+		if err := ix.Save(); err != nil {
+			return nil, errs.Wrap(err, "Could not save knowledge index.")
+		}
+		if !rt.cfg.Quiet {
+			fmt.Fprintf(os.Stderr, "Indexed knowledge: %d added, %d updated, %d removed (%d chunks).\n",
+				stats.Added, stats.Updated, stats.Removed, stats.Chunks)
+		}
+	}
+	return ix, nil
+}
+
+// conversationDocuments returns every saved conversation as a knowledge
+// document.
+func conversationDocuments(store *conversationStore) ([]rag.Document, error) {
+	var docs []rag.Document
+	for _, convo := range store.DB.List() {
+		var messages []proto.Message
+		if err := store.Cache.Read(convo.ID, &messages); err != nil {
+			return nil, errs.Wrap(err, "There was an error loading a saved conversation.")
+		}
+		docs = append(docs, rag.Document{
+			Source:  fmt.Sprintf("conversation %s (%s)", convo.ID[:storage.SHA1Short], convo.Title),
+			Version: convo.UpdatedAt.UTC().Format(time.RFC3339Nano),
+			Text:    proto.Conversation(messages).String(),
+		})
+	}
+	return docs, nil
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/dotcommander/yai/internal/present/presenttest"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/rag"
+	"github.com/dotcommander/yai/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConversationDocuments(t *testing.T) {
+	store, _ := newTestConversationStore(t)
+	id := storage.NewConversationID()
+	messages := []proto.Message{
+		{Role: proto.RoleUser, Content: "what is a monad?"},
+		{Role: proto.RoleAssistant, Content: "a monoid in the category of endofunctors"},
+	}
+	require.NoError(t, store.Cache.Write(id, &messages))
+	require.NoError(t, store.DB.Save(id, "monads", "openai", "gpt-4"))
+
+	docs, err := conversationDocuments(store)
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	require.Equal(t, "conversation "+id[:storage.SHA1Short]+" (monads)", docs[0].Source)
+	require.Equal(t, proto.Conversation(messages).String(), docs[0].Text)
+	require.NotEmpty(t, docs[0].Version)
+}
+
+func TestFormatSources(t *testing.T) {
+	sources := []rag.Result{
+		{Chunk: rag.Chunk{Source: "docs/setup.md", StartLine: 1, EndLine: 12}},
+		{Chunk: rag.Chunk{Source: "README.md"}},
+	}
+	presenttest.RequireEqual(t, formatSources(presenttest.Styles(), sources))
+}
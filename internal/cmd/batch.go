@@ -0,0 +1,348 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/errs"
+	imcp "github.com/dotcommander/yai/internal/mcp"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/spf13/cobra"
+)
+
+type batchOptions struct {
+	inputFile   string
+	outputFile  string
+	concurrency int
+	rate        float64
+}
+
+// batchJob is a single line of the batch input file.
+type batchJob struct {
+	ID     string `json:"id"`
+	Prompt string `json:"prompt"`
+}
+
+// batchResult is a single line of the batch output file.
+type batchResult struct {
+	ID        string           `json:"id"`
+	Prompt    string           `json:"prompt"`
+	Response  string           `json:"response,omitempty"`
+	Error     string           `json:"error,omitempty"`
+	API       string           `json:"api,omitempty"`
+	Model     string           `json:"model,omitempty"`
+	Usage     *proto.UsageJSON `json:"usage,omitempty"`
+	LatencyMS int64            `json:"latency_ms"`
+	Retries   int              `json:"retries,omitempty"`
+
+	// FallbackFrom is set when Model answered as the fallback of this model.
+	FallbackFrom string `json:"fallback_from,omitempty"`
+	// RequestID and ModelVersion are what the provider reported, if anything.
+	RequestID    string `json:"request_id,omitempty"`
+	ModelVersion string `json:"model_version,omitempty"`
+}
+
+func newBatchCmd(rt *runtime) *cobra.Command {
+	opts := batchOptions{concurrency: 4}
+	cmd := &cobra.Command{
+		Use:   "batch [prompt prefix]",
+		Short: "Run many prompts from a JSONL file concurrently",
+		Long: "Run every prompt in a JSONL input file through the model and write one JSONL result per prompt.\n" +
+			"Each input line is an object with a \"prompt\" and an optional \"id\". Results already present in\n" +
+			"--output-file are skipped, so an interrupted batch can be resumed by running the same command again.",
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return rt.runBatch(ctx, opts, strings.TrimSpace(strings.Join(args, " ")))
+		},
+	}
+
+	flags := cmd.Flags()
+	s := present.StdoutStyles().FlagDesc
+	flags.StringVarP(&opts.inputFile, "input-file", "i", "", s.Render(helpText["batch-input-file"]))
+	flags.StringVarP(&opts.outputFile, "output-file", "o", "", s.Render(helpText["batch-output-file"]))
+	flags.IntVar(&opts.concurrency, "concurrency", opts.concurrency, s.Render(helpText["batch-concurrency"]))
+	flags.Float64Var(&opts.rate, "rate", 0, s.Render(helpText["batch-rate"]))
+	flags.StringVarP(&rt.cfg.Model, "model", "m", rt.cfg.Model, s.Render(helpText["model"]))
+	flags.StringVarP(&rt.cfg.API, "api", "a", rt.cfg.API, s.Render(helpText["api"]))
+	flags.StringVarP(&rt.cfg.Role, "role", "R", rt.cfg.Role, s.Render(helpText["role"]))
+	flags.BoolVarP(&rt.cfg.Format, "format", "f", rt.cfg.Format, s.Render(helpText["format"]))
+	flags.StringVar(&rt.cfg.FormatAs, "format-as", rt.cfg.FormatAs, s.Render(helpText["format-as"]))
+	flags.BoolVarP(&rt.cfg.Quiet, "quiet", "q", rt.cfg.Quiet, s.Render(helpText["quiet"]))
+	flags.IntVar(&rt.cfg.MaxRetries, "max-retries", rt.cfg.MaxRetries, s.Render(helpText["max-retries"]))
+	flags.Var(newDurationFlag(rt.cfg.RequestTimeout, &rt.cfg.RequestTimeout), "request-timeout", s.Render(helpText["request-timeout"]))
+	flags.Var(newDurationFlag(rt.cfg.ConnectTimeout, &rt.cfg.ConnectTimeout), "connect-timeout", s.Render(helpText["connect-timeout"]))
+	flags.Var(newDurationFlag(rt.cfg.FirstTokenTimeout, &rt.cfg.FirstTokenTimeout), "first-token-timeout", s.Render(helpText["first-token-timeout"]))
+	flags.Var(newDurationFlag(rt.cfg.IdleTimeout, &rt.cfg.IdleTimeout), "idle-timeout", s.Render(helpText["idle-timeout"]))
+	flags.SortFlags = false
+	_ = cmd.MarkFlagRequired("input-file")
+	_ = cmd.RegisterFlagCompletionFunc("model", completeModels(&rt.cfg))
+	_ = cmd.RegisterFlagCompletionFunc("api", completeAPIs(&rt.cfg))
+	_ = cmd.RegisterFlagCompletionFunc("role", completeRoles(&rt.cfg))
+
+	return cmd
+}
+
+func (rt *runtime) runBatch(ctx context.Context, opts batchOptions, prefix string) error {
+	if opts.concurrency < 1 {
+		return errs.Wrap(errs.UserErrorf("--concurrency must be at least 1"), "Invalid batch options.")
+	}
+
+	jobs, err := readBatchJobs(opts.inputFile)
+	if err != nil {
+		return errs.Wrap(err, "Could not read batch input file.")
+	}
+
+	done := map[string]struct{}{}
+	out := io.Writer(os.Stdout)
+	if opts.outputFile != "" {
+		done, err = readCompletedBatchIDs(opts.outputFile)
+		if err != nil {
+			return errs.Wrap(err, "Could not read existing batch results.")
+		}
+		f, err := os.OpenFile(opts.outputFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return errs.Wrap(err, "Could not open batch output file.")
+		}
+		defer f.Close() //nolint:errcheck
+		out = f
+	}
+
+	mcpSvc := imcp.New(&rt.cfg)
+	defer mcpSvc.Close()
+
+	var (
+		mu      sync.Mutex
+		enc     = json.NewEncoder(out)
+		failed  int
+		skipped int
+		limiter = newRateLimiter(opts.rate)
+		g       errgroup.Group
+	)
+	enc.SetEscapeHTML(false)
+	g.SetLimit(opts.concurrency)
+
+	for _, job := range jobs {
+		if _, ok := done[job.ID]; ok {
+			skipped++
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		g.Go(func() error {
+			if err := limiter.Wait(ctx); err != nil {
+				return nil //nolint:nilerr // interrupted; remaining jobs are left for a resumed run
+			}
+			res := rt.runBatchJob(ctx, mcpSvc, job, prefix)
+			if ctx.Err() != nil && res.Error != "" {
+				return nil
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if res.Error != "" {
+				failed++
+			}
+			if err := enc.Encode(res); err != nil {
+				return fmt.Errorf("write batch result: %w", err)
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return errs.Wrap(err, "Could not write batch results.")
+	}
+
+	if !rt.cfg.Quiet {
+		fmt.Fprintf(os.Stderr, "Processed %d prompts (%d failed, %d skipped).\n", len(jobs)-skipped, failed, skipped)
+	}
+	if ctx.Err() != nil {
+		return errs.Wrap(ctx.Err(), "Batch interrupted; run the same command again to resume.")
+	}
+	if failed > 0 {
+		return errs.Wrap(errs.UserErrorf("%d of %d prompts failed", failed, len(jobs)), "Some batch prompts failed.")
+	}
+	return nil
+}
+
+func (rt *runtime) runBatchJob(ctx context.Context, mcpSvc *imcp.Service, job batchJob, prefix string) batchResult {
+	// Each job gets its own config copy: model resolution and fallback retries
+	// mutate the config, and jobs run concurrently.
+	cfg := rt.cfg
+	cfg.NoCache = true
+
+	prompt := job.Prompt
+	if prefix != "" {
+		prompt = strings.TrimSpace(prefix + "\n\n" + prompt)
+	}
+
+	start := time.Now()
+	res, err := agent.New(&cfg, nil, mcpSvc).Complete(ctx, nil, prompt)
+	result := batchResult{
+		ID:        job.ID,
+		Prompt:    job.Prompt,
+		API:       res.Model.API,
+		Model:     res.Model.Name,
+		LatencyMS: time.Since(start).Milliseconds(),
+		Retries:   res.Retries,
+
+		FallbackFrom: cfg.FallbackFrom,
+		RequestID:    res.Meta.RequestID,
+		ModelVersion: res.Meta.ModelVersion,
+	}
+	if !cfg.Quiet {
+		for _, warning := range res.Warnings {
+			fmt.Fprintln(os.Stderr, present.StderrStyles().Comment.Render("Warning: "+warning))
+		}
+	}
+	if err != nil {
+		result.Error = batchErrorText(err)
+		return result
+	}
+	result.Response = res.Response
+	usage := proto.NewUsageJSON(res.Usage)
+	result.Usage = &usage
+	return result
+}
+
+func batchErrorText(err error) string {
+	var merr errs.Error
+	if errors.As(err, &merr) && merr.Reason != "" {
+		if merr.Err == nil {
+			return merr.Reason
+		}
+		return merr.Reason + " " + merr.Err.Error()
+	}
+	return err.Error()
+}
+
+// readBatchJobs parses a JSONL batch input file. Lines without an id are
+// identified by their 1-based line number.
+func readBatchJobs(path string) ([]batchJob, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open batch input: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	var jobs []batchJob
+	seen := map[string]int{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var job batchJob
+		if err := json.Unmarshal([]byte(line), &job); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if strings.TrimSpace(job.Prompt) == "" {
+			return nil, fmt.Errorf("line %d: missing prompt", n)
+		}
+		if job.ID == "" {
+			job.ID = strconv.Itoa(n)
+		}
+		if prev, ok := seen[job.ID]; ok {
+			return nil, fmt.Errorf("line %d: duplicate id %q (first used on line %d)", n, job.ID, prev)
+		}
+		seen[job.ID] = n
+		jobs = append(jobs, job)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read batch input: %w", err)
+	}
+	return jobs, nil
+}
+
+// readCompletedBatchIDs returns the IDs of successful results already written
+// to a batch output file. A missing file yields an empty set.
+func readCompletedBatchIDs(path string) (map[string]struct{}, error) {
+	done := map[string]struct{}{}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open batch output: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var res batchResult
+		if err := json.Unmarshal(scanner.Bytes(), &res); err != nil {
+			// Tolerate a partially written last line from an interrupted run.
+			continue
+		}
+		if res.Error == "" && res.ID != "" {
+			done[res.ID] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read batch output: %w", err)
+	}
+	return done, nil
+}
+
+// rateLimiter spaces out calls to Wait so that at most rate calls start per
+// second. A zero or negative rate disables limiting.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(rate float64) *rateLimiter {
+	if rate <= 0 {
+		return &rateLimiter{}
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / rate)}
+}
+
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l.interval == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(wait):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err() //nolint:wrapcheck
+	}
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadBatchJobs(t *testing.T) {
+	t.Run("assigns line numbers to jobs without ids", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "in.jsonl")
+		require.NoError(t, os.WriteFile(path, []byte(
+			`{"prompt": "first"}`+"\n\n"+`{"id": "b", "prompt": "second"}`+"\n",
+		), 0o600))
+
+		jobs, err := readBatchJobs(path)
+		require.NoError(t, err)
+		require.Equal(t, []batchJob{
+			{ID: "1", Prompt: "first"},
+			{ID: "b", Prompt: "second"},
+		}, jobs)
+	})
+
+	t.Run("rejects missing prompts", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "in.jsonl")
+		require.NoError(t, os.WriteFile(path, []byte(`{"id": "a"}`+"\n"), 0o600))
+
+		_, err := readBatchJobs(path)
+		require.ErrorContains(t, err, "line 1: missing prompt")
+	})
+
+	t.Run("rejects duplicate ids", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "in.jsonl")
+		require.NoError(t, os.WriteFile(path, []byte(
+			`{"id": "a", "prompt": "x"}`+"\n"+`{"id": "a", "prompt": "y"}`+"\n",
+		), 0o600))
+
+		_, err := readBatchJobs(path)
+		require.ErrorContains(t, err, `duplicate id "a"`)
+	})
+}
+
+func TestReadCompletedBatchIDs(t *testing.T) {
+	t.Run("missing file is empty", func(t *testing.T) {
+		done, err := readCompletedBatchIDs(filepath.Join(t.TempDir(), "out.jsonl"))
+		require.NoError(t, err)
+		require.Empty(t, done)
+	})
+
+	t.Run("skips failed and partial results", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "out.jsonl")
+		require.NoError(t, os.WriteFile(path, []byte(
+			`{"id": "a", "prompt": "x", "response": "ok", "latency_ms": 1}`+"\n"+
+				`{"id": "b", "prompt": "y", "error": "boom", "latency_ms": 1}`+"\n"+
+				`{"id": "c", "prompt": "z", "respo`,
+		), 0o600))
+
+		done, err := readCompletedBatchIDs(path)
+		require.NoError(t, err)
+		require.Equal(t, map[string]struct{}{"a": {}}, done)
+	})
+}
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		l := newRateLimiter(0)
+		start := time.Now()
+		for range 10 {
+			require.NoError(t, l.Wait(context.Background()))
+		}
+		require.Less(t, time.Since(start), 50*time.Millisecond)
+	})
+
+	t.Run("spaces calls", func(t *testing.T) {
+		l := newRateLimiter(100)
+		start := time.Now()
+		for range 3 {
+			require.NoError(t, l.Wait(context.Background()))
+		}
+		require.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	})
+}
@@ -0,0 +1,309 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	imcp "github.com/dotcommander/yai/internal/mcp"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/spf13/cobra"
+)
+
+type benchOptions struct {
+	models     []string
+	promptFile string
+	runs       int
+	asJSON     bool
+}
+
+// benchReport is the JSON representation printed by `yai bench --json`.
+type benchReport struct {
+	Runs   int          `json:"runs"`
+	Models []benchModel `json:"models"`
+}
+
+// benchModel sums up the runs of a model: the medians are of its answered
+// runs.
+type benchModel struct {
+	API             string     `json:"api"`
+	Model           string     `json:"model"`
+	Failures        int        `json:"failures"`
+	FirstTokenMS    int64      `json:"first_token_ms"`
+	LatencyMS       int64      `json:"latency_ms"`
+	TokensPerSecond float64    `json:"tokens_per_second"`
+	OutputTokens    int64      `json:"output_tokens"`
+	Cost            float64    `json:"cost,omitempty"`
+	Runs            []benchRun `json:"runs"`
+	// Estimated is set when the provider reported no tokens, so they were
+	// estimated from the text.
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+// benchRun is one request of a benchmark. FirstTokenMS and LatencyMS are
+// measured from sending the request, retries included, and
+// TokensPerSecond over the time between the first token and the last.
+type benchRun struct {
+	FirstTokenMS    int64   `json:"first_token_ms,omitempty"`
+	LatencyMS       int64   `json:"latency_ms"`
+	OutputTokens    int64   `json:"output_tokens,omitempty"`
+	TokensPerSecond float64 `json:"tokens_per_second,omitempty"`
+	Retries         int     `json:"retries,omitempty"`
+	Error           string  `json:"error,omitempty"`
+
+	api       string
+	model     string
+	cost      float64
+	estimated bool
+}
+
+func newBenchCmd(rt *runtime) *cobra.Command {
+	opts := benchOptions{runs: 3}
+	cmd := &cobra.Command{
+		Use:   "bench [prompt]",
+		Short: "Compare the latency and speed of models on the same prompt",
+		Long: "Send the same prompt to each model several times and compare the median time to the first token, " +
+			"tokens per second, and total latency. Runs take turns between the models, so a slow moment of the " +
+			"network does not fall on one of them only. MCP tools are left out. Each run is a real request: it is " +
+			"recorded in the usage ledger, costs what a request costs, and counts against monthly-budget; the benchmark " +
+			"stops when the budget is reached.",
+		Example: `  yai bench -m gpt-4o -m claude-sonnet-4 --prompt prompt.md
+  yai bench -m gpt-4o-mini -n 5 --json "write a haiku about latency"`,
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			prompt, err := benchPrompt(opts.promptFile, args)
+			if err != nil {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			mcpSvc := imcp.New(&rt.cfg)
+			defer mcpSvc.Close()
+			complete := func(ctx context.Context, cfg *config.Config, history []proto.Message, prompt string, onEvent func(agent.Event)) (agent.Completion, error) {
+				return agent.New(cfg, nil, mcpSvc).CompleteStream(ctx, history, prompt, onEvent)
+			}
+			report, err := rt.runBench(ctx, opts, prompt, complete)
+			if err != nil {
+				return err
+			}
+			if opts.asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(report); err != nil {
+					return errs.Wrap(err, "Could not write the benchmark.")
+				}
+				return nil
+			}
+			printBench(os.Stdout, present.StdoutStyles(), report)
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	s := present.StdoutStyles().FlagDesc
+	flags.StringArrayVarP(&opts.models, "model", "m", nil, s.Render(helpText["bench-model"]))
+	flags.StringVarP(&rt.cfg.API, "api", "a", rt.cfg.API, s.Render(helpText["api"]))
+	flags.StringVar(&opts.promptFile, "prompt", "", s.Render(helpText["bench-prompt"]))
+	flags.IntVarP(&opts.runs, "runs", "n", opts.runs, s.Render(helpText["bench-runs"]))
+	flags.BoolVar(&opts.asJSON, "json", false, s.Render(helpText["bench-json"]))
+	flags.Int64Var(&rt.cfg.MaxTokens, "max-tokens", rt.cfg.MaxTokens, s.Render(helpText["max-tokens"]))
+	flags.BoolVarP(&rt.cfg.Quiet, "quiet", "q", rt.cfg.Quiet, s.Render(helpText["quiet"]))
+	flags.Var(newDurationFlag(rt.cfg.RequestTimeout, &rt.cfg.RequestTimeout), "request-timeout", s.Render(helpText["request-timeout"]))
+	flags.SortFlags = false
+	_ = cmd.MarkFlagFilename("prompt")
+	_ = cmd.RegisterFlagCompletionFunc("model", completeModels(&rt.cfg))
+	_ = cmd.RegisterFlagCompletionFunc("api", completeAPIs(&rt.cfg))
+
+	return cmd
+}
+
+// benchPrompt is the prompt to benchmark with: the --prompt file, followed
+// by the prompt arguments.
+func benchPrompt(path string, args []string) (string, error) {
+	var parts []string
+	if path != "" {
+		data, err := os.ReadFile(path) //nolint:gosec // G304: user-selected prompt file
+		if err != nil {
+			return "", errs.Wrap(err, "Could not read the prompt file.")
+		}
+		parts = append(parts, strings.TrimSpace(string(data)))
+	}
+	if arg := strings.TrimSpace(strings.Join(args, " ")); arg != "" {
+		parts = append(parts, arg)
+	}
+	prompt := strings.Join(parts, "\n\n")
+	if prompt == "" {
+		return "", errs.Wrap(
+			errs.UserErrorf("Give the prompt as arguments, or in a file with %s.", present.StdoutStyles().InlineCode.Render("--prompt")),
+			"Nothing to benchmark with.",
+		)
+	}
+	return prompt, nil
+}
+
+func (rt *runtime) runBench(ctx context.Context, opts benchOptions, prompt string, complete completeFunc) (benchReport, error) {
+	if opts.runs < 1 {
+		return benchReport{}, errs.Wrap(errs.UserErrorf("--runs must be at least 1"), "Invalid benchmark options.")
+	}
+	models := opts.models
+	if len(models) == 0 {
+		models = []string{rt.cfg.Model}
+	}
+
+	runs := make([][]benchRun, len(models))
+	for i := range opts.runs {
+		for j, model := range models {
+			if ctx.Err() != nil {
+				return benchReport{}, errs.Wrap(ctx.Err(), "Benchmark interrupted.")
+			}
+			// Each run costs, so the budget can run out along the way.
+			if err := checkBudget(&rt.cfg); err != nil {
+				return benchReport{}, err
+			}
+			run := rt.benchOnce(ctx, model, prompt, complete)
+			runs[j] = append(runs[j], run)
+			if !rt.cfg.Quiet {
+				status := fmt.Sprintf("%s total, first token in %s", formatMS(run.LatencyMS), formatMS(run.FirstTokenMS))
+				if run.Error != "" {
+					status = "failed: " + run.Error
+				}
+				fmt.Fprintln(os.Stderr, present.StderrStyles().Comment.Render(
+					fmt.Sprintf("%s run %d/%d: %s", model, i+1, opts.runs, status),
+				))
+			}
+		}
+	}
+
+	report := benchReport{Runs: opts.runs, Models: make([]benchModel, 0, len(models))}
+	for j, model := range models {
+		report.Models = append(report.Models, summarizeBench(model, runs[j]))
+	}
+	return report, nil
+}
+
+// benchOnce sends prompt to model once, and records it in the usage ledger
+// like any other request.
+func (rt *runtime) benchOnce(ctx context.Context, model, prompt string, complete completeFunc) benchRun {
+	cfg := rt.cfg
+	cfg.Model = model
+	cfg.MCPDisable = []string{"*"}
+
+	var first time.Duration
+	start := time.Now()
+	res, err := complete(ctx, &cfg, nil, prompt, func(ev agent.Event) {
+		switch ev.Type {
+		case agent.EventChunk:
+			if first == 0 {
+				first = time.Since(start)
+			}
+		case agent.EventRetry:
+			// What was streamed before the retry is dropped.
+			first = 0
+		}
+	})
+	total := time.Since(start)
+	run := benchRun{
+		LatencyMS: total.Milliseconds(),
+		Retries:   res.Retries,
+		api:       res.Model.API,
+		model:     res.Model.Name,
+	}
+	if err != nil {
+		recordFailure(&cfg, err)
+		run.Error = batchErrorText(err)
+		return run
+	}
+	recordAnswer(&cfg, res.Messages)
+
+	run.FirstTokenMS = first.Milliseconds()
+	input, output, estimated := turnTokens(res.Messages)
+	run.OutputTokens, run.estimated = output, estimated
+	if generating := total - first; first > 0 && generating > 0 {
+		run.TokensPerSecond = float64(output) / generating.Seconds()
+	}
+	run.cost = requestCost(res.Model, input, output)
+	return run
+}
+
+// summarizeBench sums up the runs of model, taking medians over the runs
+// that were answered.
+func summarizeBench(model string, runs []benchRun) benchModel {
+	sum := benchModel{Model: model, Runs: runs}
+	var firsts, latencies, outputs []int64
+	var speeds []float64
+	for _, run := range runs {
+		sum.API = cmp.Or(sum.API, run.api)
+		if run.Error != "" {
+			sum.Failures++
+			continue
+		}
+		sum.Model = cmp.Or(run.model, sum.Model)
+		sum.Cost += run.cost
+		sum.Estimated = sum.Estimated || run.estimated
+		firsts = append(firsts, run.FirstTokenMS)
+		latencies = append(latencies, run.LatencyMS)
+		outputs = append(outputs, run.OutputTokens)
+		speeds = append(speeds, run.TokensPerSecond)
+	}
+	sum.FirstTokenMS = median(firsts)
+	sum.LatencyMS = median(latencies)
+	sum.OutputTokens = median(outputs)
+	sum.TokensPerSecond = median(speeds)
+	return sum
+}
+
+func median[T int64 | float64](values []T) T {
+	if len(values) == 0 {
+		return 0
+	}
+	values = slices.Sorted(slices.Values(values))
+	mid := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[mid-1] + values[mid]) / 2
+	}
+	return values[mid]
+}
+
+func printBench(w io.Writer, s present.Styles, report benchReport) {
+	fmt.Fprintf(w, "%s\n", s.Comment.Render(fmt.Sprintf("%-32s %8s %12s %10s %10s %8s",
+		"model", "failed", "first token", "tokens/s", "total", "tokens")))
+	estimated := false
+	for _, m := range report.Models {
+		name := m.Model
+		if m.API != "" {
+			name = m.API + "/" + m.Model
+		}
+		failed := fmt.Sprintf("%d/%d", m.Failures, len(m.Runs))
+		if m.Failures == len(m.Runs) {
+			fmt.Fprintf(w, "%-32s %8s %s\n", name, failed, s.Warning.Render(m.Runs[len(m.Runs)-1].Error))
+			continue
+		}
+		fmt.Fprintf(w, "%-32s %8s %12s %10.1f %10s %8s\n",
+			name, failed, formatMS(m.FirstTokenMS), m.TokensPerSecond, formatMS(m.LatencyMS), formatCount(m.OutputTokens))
+		estimated = estimated || m.Estimated
+	}
+	fmt.Fprintln(w, "\n"+s.Comment.Render(fmt.Sprintf("Medians of %d runs per model.", report.Runs)))
+	if estimated {
+		fmt.Fprintln(w, s.Comment.Render("Some providers reported no tokens; those are estimated from the text."))
+	}
+}
+
+func formatMS(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
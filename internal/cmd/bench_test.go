@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunBench(t *testing.T) {
+	var order []string
+	fake := func(_ context.Context, cfg *config.Config, _ []proto.Message, prompt string, onEvent func(agent.Event)) (agent.Completion, error) {
+		order = append(order, cfg.Model)
+		require.Equal(t, []string{"*"}, cfg.MCPDisable)
+		require.Equal(t, "hi", prompt)
+		if cfg.Model == "broken" {
+			return agent.Completion{Model: config.Model{Name: cfg.Model, API: "openai"}}, errors.New("boom")
+		}
+		time.Sleep(5 * time.Millisecond)
+		onEvent(agent.Event{Type: agent.EventChunk, Content: "hel"})
+		time.Sleep(10 * time.Millisecond)
+		onEvent(agent.Event{Type: agent.EventChunk, Content: "lo"})
+		msgs := []proto.Message{
+			{Role: proto.RoleUser, Content: prompt},
+			{Role: proto.RoleAssistant, Content: "hello"},
+		}
+		if cfg.Model == "fast" {
+			msgs[1].Usage = proto.Usage{InputTokens: 10, OutputTokens: 20}
+		}
+		return agent.Completion{
+			Response: "hello",
+			Messages: msgs,
+			Model:    config.Model{Name: cfg.Model, API: "openai", OutputCost: 1},
+		}, nil
+	}
+	rt := &runtime{cfg: config.Config{Settings: config.Settings{Model: "fast", NoCache: true, Quiet: true}}}
+
+	t.Run("takes turns between models", func(t *testing.T) {
+		order = nil
+		report, err := rt.runBench(context.Background(), benchOptions{models: []string{"fast", "plain", "broken"}, runs: 2}, "hi", fake)
+		require.NoError(t, err)
+		require.Equal(t, []string{"fast", "plain", "broken", "fast", "plain", "broken"}, order)
+		require.Equal(t, 2, report.Runs)
+		require.Len(t, report.Models, 3)
+
+		fast := report.Models[0]
+		require.Equal(t, "openai", fast.API)
+		require.Zero(t, fast.Failures)
+		require.Len(t, fast.Runs, 2)
+		require.EqualValues(t, 20, fast.OutputTokens)
+		require.False(t, fast.Estimated)
+		require.GreaterOrEqual(t, fast.FirstTokenMS, int64(5))
+		require.GreaterOrEqual(t, fast.LatencyMS, fast.FirstTokenMS+10)
+		require.Positive(t, fast.TokensPerSecond)
+		require.InDelta(t, 40.0/1_000_000, fast.Cost, 1e-12)
+
+		require.True(t, report.Models[1].Estimated)
+
+		broken := report.Models[2]
+		require.Equal(t, 2, broken.Failures)
+		require.Equal(t, "boom", broken.Runs[0].Error)
+		require.Zero(t, broken.LatencyMS)
+	})
+
+	t.Run("defaults to the default model", func(t *testing.T) {
+		order = nil
+		report, err := rt.runBench(context.Background(), benchOptions{runs: 1}, "hi", fake)
+		require.NoError(t, err)
+		require.Equal(t, []string{"fast"}, order)
+		require.Equal(t, "fast", report.Models[0].Model)
+	})
+
+	t.Run("stops at the budget", func(t *testing.T) {
+		rt := &runtime{cfg: *fastestTestConfig(t)}
+		rt.cfg.NoCache = true
+		rt.cfg.MonthlyBudget = 1
+		ledger := storage.OpenLedger(rt.cfg.CachePath)
+		order = nil
+		spend := func(ctx context.Context, cfg *config.Config, history []proto.Message, prompt string, onEvent func(agent.Event)) (agent.Completion, error) {
+			require.NoError(t, ledger.Append(storage.LedgerEntry{Time: time.Now(), API: "openai", Cost: 0.75}))
+			return fake(ctx, cfg, history, prompt, onEvent)
+		}
+		_, err := rt.runBench(context.Background(), benchOptions{models: []string{"fast", "plain"}, runs: 2}, "hi", spend)
+		require.ErrorContains(t, err, "reached the monthly budget")
+		require.Equal(t, []string{"fast", "plain"}, order)
+	})
+
+	t.Run("needs a run", func(t *testing.T) {
+		_, err := rt.runBench(context.Background(), benchOptions{runs: 0}, "hi", fake)
+		require.ErrorContains(t, err, "--runs must be at least 1")
+	})
+}
+
+func TestBenchPrompt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompt.md")
+	require.NoError(t, os.WriteFile(path, []byte("from the file\n"), 0o600))
+
+	prompt, err := benchPrompt(path, []string{"and", "args"})
+	require.NoError(t, err)
+	require.Equal(t, "from the file\n\nand args", prompt)
+
+	_, err = benchPrompt("", nil)
+	require.Error(t, err)
+	_, err = benchPrompt(filepath.Join(t.TempDir(), "missing"), nil)
+	require.Error(t, err)
+}
+
+func TestPrintBench(t *testing.T) {
+	var buf bytes.Buffer
+	printBench(&buf, present.StdoutStyles(), benchReport{Runs: 1, Models: []benchModel{
+		{API: "openai", Model: "gpt-4o", FirstTokenMS: 300, LatencyMS: 1200, TokensPerSecond: 55.5, OutputTokens: 50, Runs: make([]benchRun, 1)},
+		{API: "openai", Model: "broken", Failures: 1, Runs: []benchRun{{Error: "boom"}}},
+	}})
+	out := buf.String()
+	require.Contains(t, out, "openai/gpt-4o")
+	require.Contains(t, out, "300ms")
+	require.Contains(t, out, "1.2s")
+	require.Contains(t, out, "55.5")
+	require.Contains(t, out, "boom")
+	require.Contains(t, out, "Medians of 1 runs per model.")
+}
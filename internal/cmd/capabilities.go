@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/provider"
+	"github.com/dotcommander/yai/internal/requestbuilder"
+	"github.com/spf13/pflag"
+)
+
+// validateCapabilities rejects flags that the selected model or its provider
+// would drop or refuse, before any request is sent. Only flags given on the
+// command line are checked: the same values in settings apply to every model
+// and are left out quietly where they do not fit. yai ends with exitConfig
+// after the errors, like after other invalid flags.
+func validateCapabilities(flags *pflag.FlagSet, cfg *config.Config) error {
+	// ResolveModel rewrites aliases in the config it is given.
+	resolved := *cfg
+	api, mod, err := requestbuilder.ResolveModel(&resolved)
+	if err != nil {
+		// Reported with more context when the request is built.
+		return nil //nolint:nilerr
+	}
+
+	if requestbuilder.IsReasoningModel(mod.Name) {
+		sampling := []struct {
+			flag string
+			set  bool
+		}{
+			{"temp", cfg.Temperature >= 0},
+			{"topp", cfg.TopP >= 0},
+			{"topk", cfg.TopK >= 0},
+			{"presence-penalty", cfg.PresencePenalty != 0},
+			{"frequency-penalty", cfg.FrequencyPenalty != 0},
+			{"logprobs", cfg.Logprobs > 0},
+		}
+		for _, s := range sampling {
+			if flags.Changed(s.flag) && s.set {
+				return configError{errs.Wrap(
+					errs.UserErrorf("Reasoning models choose their own sampling. Drop --%s, or pick another model with --model.", s.flag),
+					fmt.Sprintf("%s does not support --%s.", mod.Name, s.flag),
+				)}
+			}
+		}
+		if flags.Changed("max-tokens") && cfg.MaxTokens > 0 {
+			return configError{errs.Wrap(
+				errs.UserErrorf("Use --max-completion-tokens to cap the answers of reasoning models."),
+				fmt.Sprintf("%s does not support --max-tokens.", mod.Name),
+			)}
+		}
+	}
+
+	if flags.Changed("seed") && cfg.Seed != 0 && !provider.SupportsSeed(api.Name) {
+		return configError{errs.Wrap(
+			errs.UserErrorf("Use a model served by an OpenAI-compatible API, or drop --seed."),
+			fmt.Sprintf("The %s API does not support --seed.", api.Name),
+		)}
+	}
+
+	if flags.Changed("logprobs") && cfg.Logprobs > 0 && !provider.SupportsLogprobs(api.Name) {
+		return configError{errs.Wrap(
+			errs.UserErrorf("Use a model served by an OpenAI-compatible API, or drop --logprobs."),
+			fmt.Sprintf("The %s API does not report --logprobs.", api.Name),
+		)}
+	}
+
+	for _, penalty := range []struct {
+		flag string
+		set  bool
+	}{
+		{"presence-penalty", cfg.PresencePenalty != 0},
+		{"frequency-penalty", cfg.FrequencyPenalty != 0},
+	} {
+		if flags.Changed(penalty.flag) && penalty.set && !provider.SupportsPenalties(api.Name) {
+			return configError{errs.Wrap(
+				errs.UserErrorf("Drop --%s, or pick a model served by another API with --model.", penalty.flag),
+				fmt.Sprintf("The %s API does not support --%s.", api.Name, penalty.flag),
+			)}
+		}
+	}
+
+	if flags.Changed("thinking-budget") && cfg.ThinkingBudget > 0 && !provider.SupportsThinking(api.Name) {
+		return configError{errs.Wrap(
+			errs.UserErrorf("Use a model served by the anthropic, google, or bedrock API, or drop --thinking-budget."),
+			fmt.Sprintf("The %s API does not support --thinking-budget.", api.Name),
+		)}
+	}
+
+	if flags.Changed("topk") && cfg.TopK >= 0 && !provider.SupportsTopK(api.Name) {
+		return configError{errs.Wrap(
+			errs.UserErrorf("Use --topp to narrow sampling instead, or a model served by the anthropic, google, or bedrock API."),
+			fmt.Sprintf("The %s API does not support --topk.", api.Name),
+		)}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCapabilities(t *testing.T) {
+	newCase := func(api, model string, args ...string) (*pflag.FlagSet, *config.Config) {
+		cfg := &config.Config{Settings: config.Settings{
+			API:         api,
+			Model:       model,
+			Temperature: 1,
+			TopP:        1,
+			TopK:        50,
+			APIs: config.APIs{
+				{Name: "openai", Models: map[string]config.Model{"gpt-5": {}, "gpt-4o": {Aliases: []string{"4o"}}}},
+				{Name: "anthropic", Models: map[string]config.Model{"claude-sonnet": {}}},
+			},
+		}}
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		flags.Float64Var(&cfg.Temperature, "temp", cfg.Temperature, "")
+		flags.Float64Var(&cfg.TopP, "topp", cfg.TopP, "")
+		flags.Int64Var(&cfg.TopK, "topk", cfg.TopK, "")
+		flags.Int64Var(&cfg.MaxTokens, "max-tokens", cfg.MaxTokens, "")
+		flags.IntVar(&cfg.ThinkingBudget, "thinking-budget", cfg.ThinkingBudget, "")
+		flags.Int64Var(&cfg.Seed, "seed", cfg.Seed, "")
+		flags.Int64Var(&cfg.Logprobs, "logprobs", cfg.Logprobs, "")
+		flags.Float64Var(&cfg.PresencePenalty, "presence-penalty", cfg.PresencePenalty, "")
+		flags.Float64Var(&cfg.FrequencyPenalty, "frequency-penalty", cfg.FrequencyPenalty, "")
+		require.NoError(t, flags.Parse(args))
+		return flags, cfg
+	}
+
+	t.Run("seed and penalties", func(t *testing.T) {
+		require.NoError(t, validateCapabilities(newCase("openai", "gpt-4o", "--seed", "42", "--presence-penalty", "0.5")))
+		err := validateCapabilities(newCase("anthropic", "claude-sonnet", "--seed", "42"))
+		require.ErrorContains(t, err, "drop --seed")
+		require.Equal(t, exitConfig, exitCode(err))
+		require.Contains(t, formatError(present.StderrStyles(), err), "The anthropic API does not support --seed.")
+		err = validateCapabilities(newCase("anthropic", "claude-sonnet", "--frequency-penalty", "1"))
+		require.ErrorContains(t, err, "Drop --frequency-penalty")
+		err = validateCapabilities(newCase("anthropic", "claude-sonnet", "--logprobs", "3"))
+		require.ErrorContains(t, err, "drop --logprobs")
+		err = validateCapabilities(newCase("openai", "gpt-5", "--presence-penalty", "1"))
+		require.ErrorContains(t, err, "Reasoning models choose their own sampling")
+	})
+
+	t.Run("thinking budget", func(t *testing.T) {
+		err := validateCapabilities(newCase("openai", "gpt-4o", "--thinking-budget", "2048"))
+		require.ErrorContains(t, err, "drop --thinking-budget")
+		require.NoError(t, validateCapabilities(newCase("anthropic", "claude-sonnet", "--thinking-budget", "2048")))
+		require.NoError(t, validateCapabilities(newCase("openai", "gpt-4o", "--thinking-budget", "-1")))
+	})
+
+	t.Run("settings are not checked", func(t *testing.T) {
+		require.NoError(t, validateCapabilities(newCase("openai", "gpt-5")))
+	})
+
+	t.Run("sampling with a reasoning model", func(t *testing.T) {
+		err := validateCapabilities(newCase("openai", "gpt-5", "--temp", "0.2"))
+		require.ErrorContains(t, err, "Reasoning models choose their own sampling")
+		require.NoError(t, validateCapabilities(newCase("openai", "gpt-5", "--temp", "-1")))
+	})
+
+	t.Run("max tokens with a reasoning model", func(t *testing.T) {
+		err := validateCapabilities(newCase("openai", "gpt-5", "--max-tokens", "100"))
+		require.ErrorContains(t, err, "--max-completion-tokens")
+	})
+
+	t.Run("topk", func(t *testing.T) {
+		err := validateCapabilities(newCase("openai", "4o", "--topk", "10"))
+		require.ErrorContains(t, err, "Use --topp")
+		require.NoError(t, validateCapabilities(newCase("anthropic", "claude-sonnet", "--topk", "10")))
+	})
+
+	t.Run("unknown model", func(t *testing.T) {
+		require.NoError(t, validateCapabilities(newCase("openai", "nope", "--topk", "10")))
+	})
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"os"
 	"os/signal"
+	"slices"
 	"strings"
 	"syscall"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/dotcommander/yai/internal/agent"
@@ -13,23 +15,29 @@ import (
 	"github.com/dotcommander/yai/internal/errs"
 	"github.com/dotcommander/yai/internal/present"
 	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/redact"
+	"github.com/dotcommander/yai/internal/storage"
 	"github.com/dotcommander/yai/internal/tui"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 func newChatCmd(rt *runtime) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "chat [initial prompt]",
 		Short: "Start an interactive multi-turn chat session",
-		Long:  "Start an interactive REPL for multi-turn conversations with an LLM. Type /exit or press Ctrl+C to quit.",
+		Long:  "Start an interactive REPL for multi-turn conversations with an LLM. Press Ctrl+P or type /model [name] or /api <name> to switch the model mid-session, and press Ctrl+F to search the conversation (n/N for the next and previous match). Type /export [md|json] to save the transcript to the current directory, /snippet <name> to add a saved snippet to your next prompt, /retry [--temp <value>] or Ctrl+R to regenerate the last answer, /edit [-e] to change the last prompt and send it again, /resume to finish an answer cut off by Ctrl+C, and /exit or press Ctrl+C to quit.",
 		Args:  cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if rt.cfgErr != nil {
 				return rt.cfgErr
 			}
+			if err := rt.maybeMigrateSettings(); err != nil {
+				return err
+			}
 			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
 			defer stop()
-			return rt.runChat(ctx, args)
+			return rt.runChat(ctx, cmd.Flags(), args)
 		},
 	}
 
@@ -44,7 +52,7 @@ func initChatFlags(cmd *cobra.Command, cfg *config.Config) {
 	cmd.MarkFlagsMutuallyExclusive("continue", "continue-last")
 }
 
-func (rt *runtime) runChat(ctx context.Context, args []string) error {
+func (rt *runtime) runChat(ctx context.Context, flags *pflag.FlagSet, args []string) error {
 	initialPrompt := strings.TrimSpace(strings.Join(args, " "))
 
 	store, err := rt.openAndPlanStore()
@@ -53,6 +61,27 @@ func (rt *runtime) runChat(ctx context.Context, args []string) error {
 	}
 	defer store.Close() //nolint:errcheck
 
+	if err := validateCapabilities(flags, &rt.cfg); err != nil {
+		return err
+	}
+	if err := checkNotify(&rt.cfg); err != nil {
+		return err
+	}
+	if err := checkSpinner(&rt.cfg); err != nil {
+		return err
+	}
+	if err := checkBudget(&rt.cfg); err != nil {
+		return err
+	}
+	snippets, err := readSnippets(&rt.cfg)
+	if err != nil {
+		return err
+	}
+	redactor, err := newRedactor(&rt.cfg)
+	if err != nil {
+		return err
+	}
+
 	// Load existing messages if continuing.
 	var history []proto.Message
 	if !rt.cfg.NoCache && rt.cfg.CacheReadFromID != "" {
@@ -62,11 +91,38 @@ func (rt *runtime) runChat(ctx context.Context, args []string) error {
 	}
 
 	agentSvc := agent.New(&rt.cfg, store.Cache, nil)
-	startStreamFn := agentSvc.StreamContinue
+	startStreamFn := func(ctx context.Context, msgs []proto.Message, prompt string) (agent.StreamStart, error) {
+		// A long chat can reach the budget between turns.
+		if err := checkBudget(&rt.cfg); err != nil {
+			return agent.StreamStart{}, err
+		}
+		var found redact.Matches
+		if !rt.cfg.NoRedact {
+			prompt, found = redactor.Redact(prompt)
+		}
+		res, err := agentSvc.StreamContinue(ctx, msgs, prompt)
+		if err == nil && found.Total() > 0 {
+			res.Stream = &redactedStream{Stream: res.Stream, note: redactedNote(found, "message")}
+		}
+		return res, err
+	}
 
+	var recorded time.Time
+	if n := len(history); n > 0 {
+		recorded = history[n-1].Time
+	}
 	saveFn := func(msgs []proto.Message) error {
+		// Saves also follow model switches; record each answer once.
+		if n := len(msgs); n > 0 && msgs[n-1].Time.After(recorded) {
+			recorded = msgs[n-1].Time
+			recordAnswer(&rt.cfg, msgs)
+			notifyDone(&rt.cfg, msgs[n-1].Latency, false)
+		}
 		return saveConversationWithFeedback(&rt.cfg, store, msgs, false)
 	}
+	exportFn := func(format string, msgs []proto.Message) (string, error) {
+		return exportTranscript(&rt.cfg, store, format, msgs)
+	}
 
 	chat := tui.NewChat(tui.ChatOptions{
 		Context:       ctx,
@@ -76,17 +132,26 @@ func (rt *runtime) runChat(ctx context.Context, args []string) error {
 		StartStream:   startStreamFn,
 		History:       history,
 		Save:          saveFn,
+		Export:        exportFn,
 		InitialPrompt: initialPrompt,
+		Snippets:      snippets,
+		RecentModels:  recentModels(store.DB.ListRecent()),
 	})
 
-	p := tea.NewProgram(chat, tea.WithAltScreen(), tea.WithOutput(os.Stderr))
-	m, err := p.Run()
+	// A crash leaves inflight set, so Execute can save the chat.
+	inflight = chat.Messages
+	m, err := runGuarded(chat, tea.WithAltScreen(), tea.WithOutput(os.Stderr))
+	inflight = nil
 	if err != nil {
 		return errs.Wrap(err, "Couldn't start chat program.")
 	}
 
 	c := m.(*tui.Chat)
 	if c.Error != nil {
+		recordFailure(&rt.cfg, *c.Error)
+		if agent.CanResume(c.Messages()) {
+			printResumeHint(&rt.cfg)
+		}
 		return *c.Error
 	}
 
@@ -94,7 +159,32 @@ func (rt *runtime) runChat(ctx context.Context, args []string) error {
 		if err := saveConversationWithFeedback(&rt.cfg, store, c.Messages(), true); err != nil {
 			return err
 		}
+		added := countUserTurns(c.Messages()) - countUserTurns(history)
+		refreshTitle(ctx, &rt.cfg, store, c.Messages(), added)
 	}
 
 	return nil
 }
+
+// maxRecentModels caps the recents shown first in the chat model picker.
+const maxRecentModels = 5
+
+// recentModels returns the distinct models of convos, which are ordered most
+// recently updated first.
+func recentModels(convos []storage.Conversation) []tui.ModelChoice {
+	var out []tui.ModelChoice
+	for _, convo := range convos {
+		if convo.API == nil || convo.Model == nil || *convo.API == "" || *convo.Model == "" {
+			continue
+		}
+		choice := tui.ModelChoice{API: *convo.API, Model: *convo.Model}
+		if slices.Contains(out, choice) {
+			continue
+		}
+		out = append(out, choice)
+		if len(out) == maxRecentModels {
+			break
+		}
+	}
+	return out
+}
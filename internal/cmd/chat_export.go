@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/storage"
+)
+
+// maxExportNameLen caps the title part of an export's file name.
+const maxExportNameLen = 50
+
+// exportTranscript writes the chat session to a new file in the working
+// directory, named from the conversation title and today's date, and
+// returns the file name. format is "md" or "json".
+func exportTranscript(cfg *config.Config, store *conversationStore, format string, messages []proto.Message) (string, error) {
+	convo := exportedConversation(cfg, store, messages)
+
+	var buf bytes.Buffer
+	switch format {
+	case "md":
+		fmt.Fprintf(&buf, "# %s\n\n", convo.Title)
+		buf.WriteString(proto.Conversation(messages).String())
+	case "json":
+		if err := writeConversationJSON(&buf, &convo, messages); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unknown export format %q", format)
+	}
+
+	return createExportFile(exportFileName(convo.Title, convo.UpdatedAt), format, buf.Bytes())
+}
+
+// createExportFile writes data to a new file named base.ext in the working
+// directory, or base-2.ext and so on when that exists, and returns its name.
+func createExportFile(base, ext string, data []byte) (string, error) {
+	for n := 1; ; n++ {
+		name := base + "." + ext
+		if n > 1 {
+			name = fmt.Sprintf("%s-%d.%s", base, n, ext)
+		}
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644) //nolint:gosec
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("export: %w", err)
+		}
+		_, err = f.Write(data)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return "", fmt.Errorf("export: %w", err)
+		}
+		return name, nil
+	}
+}
+
+// exportedConversation returns the saved record of the session, or one
+// made up from the settings when it has not been saved.
+func exportedConversation(cfg *config.Config, store *conversationStore, messages []proto.Message) storage.Conversation {
+	if cfg.CacheWriteToID != "" {
+		if convo, err := store.DB.Find(cfg.CacheWriteToID); err == nil {
+			return *convo
+		}
+	}
+	title := strings.TrimSpace(cfg.CacheWriteToTitle)
+	if autoTitle(cfg) {
+		title = firstLine(lastPrompt(messages))
+	}
+	return storage.Conversation{
+		ID:        cfg.CacheWriteToID,
+		Title:     title,
+		UpdatedAt: time.Now().UTC(),
+		API:       &cfg.API,
+		Model:     &cfg.Model,
+	}
+}
+
+// exportFileName turns a title into a file name such as
+// "fix-the-flaky-test-2026-01-02", without extension.
+func exportFileName(title string, when time.Time) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(r)
+			dash = false
+			if sb.Len() >= maxExportNameLen {
+				break
+			}
+			continue
+		}
+		dash = true
+	}
+	name := sb.String()
+	if name == "" {
+		name = "chat"
+	}
+	return name + "-" + when.Local().Format(time.DateOnly)
+}
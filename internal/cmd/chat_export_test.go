@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportTranscript(t *testing.T) {
+	store, _ := newTestConversationStore(t)
+	t.Chdir(t.TempDir())
+
+	const id = "abc123def456"
+	require.NoError(t, store.DB.Save(id, "Fix the flaky test!", "openai", "gpt-5"))
+	cfg := &config.Config{Settings: config.Settings{API: "openai", Model: "gpt-5"}}
+	cfg.CacheWriteToID = id
+	messages := []proto.Message{
+		{Role: proto.RoleUser, Content: "why is it flaky?"},
+		{Role: proto.RoleAssistant, Content: "a race"},
+	}
+	date := time.Now().Format(time.DateOnly)
+
+	t.Run("markdown", func(t *testing.T) {
+		name, err := exportTranscript(cfg, store, "md", messages)
+		require.NoError(t, err)
+		require.Equal(t, "fix-the-flaky-test-"+date+".md", name)
+		data, err := os.ReadFile(name)
+		require.NoError(t, err)
+		require.Equal(t, "# Fix the flaky test!\n\n"+proto.Conversation(messages).String(), string(data))
+	})
+
+	t.Run("does not overwrite", func(t *testing.T) {
+		name, err := exportTranscript(cfg, store, "md", messages)
+		require.NoError(t, err)
+		require.Equal(t, "fix-the-flaky-test-"+date+"-2.md", name)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		name, err := exportTranscript(cfg, store, "json", messages)
+		require.NoError(t, err)
+		data, err := os.ReadFile(name)
+		require.NoError(t, err)
+		var got conversationJSON
+		require.NoError(t, json.Unmarshal(data, &got))
+		require.Equal(t, id, got.ID)
+		require.Equal(t, "gpt-5", got.Model)
+		require.Len(t, got.Messages, 2)
+	})
+
+	t.Run("unsaved session", func(t *testing.T) {
+		cfg := &config.Config{Settings: config.Settings{API: "openai", Model: "gpt-5"}}
+		name, err := exportTranscript(cfg, store, "md", messages)
+		require.NoError(t, err)
+		require.Equal(t, "why-is-it-flaky-"+date+".md", name)
+	})
+}
+
+func TestExportFileName(t *testing.T) {
+	when := time.Date(2026, 1, 2, 12, 0, 0, 0, time.Local)
+	require.Equal(t, "chat-2026-01-02", exportFileName("", when))
+	require.Equal(t, "chat-2026-01-02", exportFileName("?!", when))
+	require.Equal(t, "a-b-c-2026-01-02", exportFileName("  A / b -- c ", when))
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/requestbuilder"
+)
+
+// promptLimit is the most piped input that fits in one request with
+// prompt, or 0 when there is no limit. Piped input is cut at
+// max-input-chars and the prompt at the model's, so the smaller one counts.
+func promptLimit(cfg *config.Config, prompt string) int {
+	maxChars := cfg.MaxInputChars
+	resolved := *cfg
+	if _, mod, err := requestbuilder.ResolveModel(&resolved); err == nil && mod.MaxChars > 0 {
+		if maxChars <= 0 || mod.MaxChars < maxChars {
+			maxChars = mod.MaxChars
+		}
+	}
+	if cfg.NoLimit || maxChars <= 0 {
+		return 0
+	}
+	// The prompt and input are sent separated by a blank line.
+	return max(int(maxChars)-len(prompt)-2, 1)
+}
+
+// chunkSize is the size of the parts of piped input: chunk-size, at most
+// three quarters of what fits in a request, leaving the rest for the
+// wording of each part and, with refine, the answer so far. Without
+// chunk-size, it is as much as fits.
+func chunkSize(cfg *config.Config) int {
+	size := cfg.ChunkSize
+	if limit := promptLimit(cfg, cfg.Prefix) * 3 / 4; limit > 0 && (size <= 0 || size > limit) {
+		size = limit
+	}
+	return size
+}
+
+// prepareChunks checks chunk-strategy and reads all of the piped input, for
+// [runtime.chunkInput] to answer in parts once the request is confirmed.
+func (rt *runtime) prepareChunks() error {
+	if err := agent.CheckChunkStrategy(rt.cfg.ChunkStrategy); err != nil {
+		return err
+	}
+	return rt.readAllPipedInput()
+}
+
+// readAllPipedInput reads piped input without cutting it at
+// max-input-chars, which applies to each part instead.
+func (rt *runtime) readAllPipedInput() error {
+	noLimit := rt.cfg.NoLimit
+	rt.cfg.NoLimit = true
+	_, err := rt.readPipedInput()
+	rt.cfg.NoLimit = noLimit
+	return err
+}
+
+// chunkInput answers the prompt about piped input longer than size in
+// parts, with chunk-strategy, and replaces the prompt and the input with
+// those of the last request. What is shown while the parts are answered
+// is "<verb> part N of M…".
+func (rt *runtime) chunkInput(ctx context.Context, size int, partInstruction, verb string) error {
+	input, err := rt.readPipedInput()
+	if err != nil {
+		return err
+	}
+	if size <= 0 || len(input) <= size {
+		return nil
+	}
+	if rt.cfg.Prefix == "" {
+		return errs.Wrap(
+			errs.UserErrorf("Give a prompt to ask of each part, or pass %s.", present.StdoutStyles().InlineCode.Render("--chunk-size 0")),
+			"Nothing to ask of the parts of the input.",
+		)
+	}
+
+	res, err := agent.New(&rt.cfg, nil, nil).Chunk(ctx, rt.cfg.Prefix, input, agent.ChunkOptions{
+		Size:            size,
+		Strategy:        rt.cfg.ChunkStrategy,
+		PartInstruction: partInstruction,
+		Progress: func(i, n int) {
+			if !rt.cfg.Quiet {
+				fmt.Fprintln(os.Stderr, present.StderrStyles().Comment.Render(fmt.Sprintf("%s part %d of %d…", verb, i+1, n)))
+			}
+		},
+	})
+	for _, c := range res.Completions {
+		recordAnswer(&rt.cfg, c.Messages)
+	}
+	if err != nil {
+		recordFailure(&rt.cfg, err)
+		return errs.Wrap(err, "Could not answer the input in parts.")
+	}
+	rt.cfg.Prefix = res.Prompt
+	rt.stdin = strings.NewReader(res.Input)
+	return nil
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkSize(t *testing.T) {
+	cfg := &config.Config{Settings: config.Settings{
+		APIs: config.APIs{{Name: "openai", Models: map[string]config.Model{
+			"small": {MaxChars: 1000},
+			"large": {MaxChars: 100000},
+		}}},
+		API:           "openai",
+		Model:         "small",
+		MaxInputChars: 12000,
+	}}
+	cfg.Prefix = "summarize"
+	require.Equal(t, 1000-len("summarize")-2, promptLimit(cfg, cfg.Prefix))
+	require.Equal(t, (1000-len("summarize")-2)*3/4, chunkSize(cfg))
+
+	cfg.Model = "large"
+	require.Equal(t, 12000-len("summarize")-2, promptLimit(cfg, cfg.Prefix), "stdin is cut at max-input-chars")
+
+	cfg.ChunkSize = 4000
+	require.Equal(t, 4000, chunkSize(cfg))
+	cfg.ChunkSize = 50000
+	require.Equal(t, (12000-len("summarize")-2)*3/4, chunkSize(cfg), "parts are not cut off")
+
+	cfg.NoLimit = true
+	require.Zero(t, promptLimit(cfg, cfg.Prefix))
+	require.Equal(t, 50000, chunkSize(cfg))
+}
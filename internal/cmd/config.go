@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -40,9 +41,43 @@ func newConfigCmd(rt *runtime) *cobra.Command {
 			return resetSettings(&rt.cfg)
 		},
 	})
+	var yes bool
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Update deprecated keys in the settings file",
+		Long:  "Show how the deprecated keys of the settings file would be renamed or removed, as a diff, and rewrite the file once confirmed, keeping the old one as a .bak file. Comments and everything else are kept as they are.",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			err := migrateSettings(&rt.cfg, os.Stdout, yes)
+			if err != nil && !errors.Is(err, errMigrationDeclined) {
+				return errs.Wrap(err, "Could not update your settings file.")
+			}
+			return nil
+		},
+	}
+	migrateCmd.Flags().BoolVarP(&yes, "yes", "y", false, "Rewrite the file without asking")
+	configCmd.AddCommand(migrateCmd)
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "get KEY",
+		Short: "Print a setting",
+		Long:  "Print the value of KEY in the settings file, a dotted path such as default-model or apis.openai.base-url. Mappings and lists are printed as YAML.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return getSetting(&rt.cfg, os.Stdout, args[0])
+		},
+	})
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "set KEY VALUE",
+		Short: "Change a setting",
+		Long:  "Set KEY, a dotted path such as default-model or apis.openai.base-url, to VALUE in the settings file. VALUE is read as YAML, so 100 is a number and [a, b] a list. Only the line of the key changes; missing keys are added. The file is left alone if the result is not valid settings.",
+		Args:  cobra.ExactArgs(2), //nolint:mnd
+		RunE: func(_ *cobra.Command, args []string) error {
+			return setSetting(&rt.cfg, args[0], args[1])
+		},
+	})
 	configCmd.AddCommand(&cobra.Command{
 		Use:   "dirs",
-		Short: "Print config and cache directories",
+		Short: "Print the config, cache, and state directories",
 		Args:  cobra.MaximumNArgs(1),
 		RunE: func(_ *cobra.Command, args []string) error {
 			printDirs(&rt.cfg, args)
@@ -117,6 +152,42 @@ func resetSettings(cfg *config.Config) error {
 	return nil
 }
 
+// getSetting prints the value of key in the settings file to w. A key that
+// is not set ends with exitConfig.
+func getSetting(cfg *config.Config, w io.Writer, key string) error {
+	content, err := os.ReadFile(cfg.SettingsPath)
+	if err != nil {
+		return errs.Wrap(err, "Could not read your settings file.")
+	}
+	value, err := config.GetSetting(content, key)
+	if err != nil {
+		return configError{errs.Wrap(err, "Could not read the setting.")}
+	}
+	fmt.Fprintln(w, value)
+	return nil
+}
+
+// setSetting sets key to value in the settings file, keeping the rest of
+// it as it is. An unknown key or a value of the wrong type ends with
+// exitConfig.
+func setSetting(cfg *config.Config, key, value string) error {
+	if err := config.WriteConfigFile(cfg.SettingsPath); err != nil {
+		return errs.Wrap(err, "Could not write your settings file.")
+	}
+	content, err := os.ReadFile(cfg.SettingsPath)
+	if err != nil {
+		return errs.Wrap(err, "Could not read your settings file.")
+	}
+	updated, err := config.SetSetting(content, key, value)
+	if err != nil {
+		return configError{errs.Wrap(err, "Could not change the setting.")}
+	}
+	if err := config.ReplaceSettingsFile(cfg.SettingsPath, updated); err != nil {
+		return errs.Wrap(err, "Could not write your settings file.")
+	}
+	return nil
+}
+
 func printDirs(cfg *config.Config, args []string) {
 	if len(args) > 0 {
 		switch args[0] {
@@ -126,10 +197,15 @@ func printDirs(cfg *config.Config, args []string) {
 		case "cache":
 			fmt.Println(cfg.CachePath)
 			return
+		case "state":
+			fmt.Println(cfg.StateDir())
+			return
 		}
 	}
 
 	fmt.Printf("Configuration: %s\n", filepath.Dir(cfg.SettingsPath))
 	//nolint:mnd
 	fmt.Printf("%*sCache: %s\n", 8, " ", cfg.CachePath)
+	//nolint:mnd
+	fmt.Printf("%*sState: %s\n", 8, " ", cfg.StateDir())
 }
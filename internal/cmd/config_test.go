@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigGetSet(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.SettingsPath = filepath.Join(t.TempDir(), "yai.yml")
+	require.NoError(t, os.WriteFile(cfg.SettingsPath, []byte("# The model.\ndefault-model: gpt-4o\n"), 0o600))
+
+	require.NoError(t, setSetting(cfg, "default-model", "gpt-4o-mini"))
+	require.NoError(t, setSetting(cfg, "apis.openai.base-url", "http://localhost:8080/v1"))
+	content, err := os.ReadFile(cfg.SettingsPath)
+	require.NoError(t, err)
+	require.Equal(t, "# The model.\ndefault-model: gpt-4o-mini\napis:\n  openai:\n    base-url: http://localhost:8080/v1\n", string(content))
+
+	var out bytes.Buffer
+	require.NoError(t, getSetting(cfg, &out, "apis.openai.base-url"))
+	require.Equal(t, "http://localhost:8080/v1\n", out.String())
+
+	err = getSetting(cfg, &out, "default-api")
+	require.ErrorContains(t, err, "default-api is not set")
+	require.Equal(t, exitConfig, exitCode(err))
+	err = setSetting(cfg, "max-tokens", "lots")
+	require.ErrorContains(t, err, "the settings would not be valid")
+	require.Equal(t, exitConfig, exitCode(err))
+	err = setSetting(cfg, "no-such-setting", "1")
+	require.ErrorContains(t, err, "is not a setting yai knows")
+	require.Equal(t, exitConfig, exitCode(err))
+	after, err := os.ReadFile(cfg.SettingsPath)
+	require.NoError(t, err)
+	require.Equal(t, content, after, "an invalid change is not written")
+}
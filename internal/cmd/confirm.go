@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/requestbuilder"
+	"github.com/dotcommander/yai/internal/tui"
+)
+
+// confirmSend asks before sending a request above confirm-tokens or
+// confirm-cost, or to a model in confirm-models, so a mistyped pipe does not
+// send a fortune's worth of input. Piped input is read here to be measured;
+// the program reads it from rt.stdin afterwards. When nobody can answer,
+// the request is refused unless --yes was given.
+func (rt *runtime) confirmSend(store *conversationStore) error {
+	cfg := &rt.cfg
+	if cfg.Yes || (cfg.ConfirmTokens <= 0 && cfg.ConfirmCost <= 0 && len(cfg.ConfirmModels) == 0) {
+		return nil
+	}
+	resolved := *cfg
+	_, mod, err := requestbuilder.ResolveModel(&resolved)
+	if err != nil {
+		// The request fails with a better error than this one.
+		return nil //nolint:nilerr
+	}
+
+	input, err := rt.readPipedInput()
+	if err != nil {
+		return err
+	}
+	var history []proto.Message
+	if !cfg.NoCache && cfg.CacheReadFromID != "" {
+		_ = store.Cache.Read(cfg.CacheReadFromID, &history)
+	}
+	msgs := append(history, proto.Message{Role: proto.RoleUser, Content: cfg.Prefix + "\n\n" + input})
+	tokens := proto.EstimateTokens(msgs)
+	cost := float64(tokens) * mod.InputCost / 1_000_000
+
+	var model string
+	if cfg.ConfirmsModel(mod) {
+		model = mod.Name
+	} else if !tui.NeedsConfirm(cfg, tokens, cost) {
+		return nil
+	}
+	preview := tui.SendPreview(tokens, cost, model)
+
+	if !present.IsErrorTTY() {
+		return refuseSend(preview)
+	}
+	send := false
+	form := huh.NewForm(huh.NewGroup(
+		huh.NewConfirm().
+			Title(preview + ".").
+			Description("Send it?").
+			Affirmative("Send").
+			Negative("Cancel").
+			Value(&send),
+	)).WithTheme(formTheme()).WithOutput(os.Stderr)
+	if !present.IsInputTTY() {
+		// Stdin is the pipe; ask on the terminal instead.
+		form = form.WithProgramOptions(tea.WithInputTTY())
+	}
+	if err := form.Run(); err != nil {
+		if errors.Is(err, huh.ErrUserAborted) {
+			return errs.Wrap(err, "User canceled.")
+		}
+		return refuseSend(preview)
+	}
+	if !send {
+		return errs.Wrap(huh.ErrUserAborted, "User canceled.")
+	}
+	return nil
+}
+
+// refuseSend is the error of a request that needs confirming when there is
+// no terminal to ask on.
+func refuseSend(preview string) error {
+	return fmt.Errorf("%w", errs.UserErrorf("%s, which needs confirming; pass --yes to send it anyway", preview))
+}
+
+// readPipedInput reads stdin when it is piped, as the program would, and
+// keeps it in rt.stdin for the program to read instead. Once read, it comes
+// from rt.stdin.
+func (rt *runtime) readPipedInput() (string, error) {
+	if rt.stdin != nil {
+		data, err := io.ReadAll(rt.stdin)
+		if err != nil {
+			return "", errs.Wrap(err, "Unable to read stdin.")
+		}
+		rt.stdin = bytes.NewReader(data)
+		return string(data), nil
+	}
+	if present.IsInputTTY() {
+		return "", nil
+	}
+	if !rt.cfg.NoLimit && rt.cfg.MaxInputChars > 0 {
+		input, err := requestbuilder.ReadTruncated(os.Stdin, rt.cfg.MaxInputChars, requestbuilder.HeadRatio(&rt.cfg))
+		if err != nil {
+			return "", errs.Wrap(err, "Unable to read stdin.")
+		}
+		rt.stdin = strings.NewReader(input)
+		return input, nil
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", errs.Wrap(err, "Unable to read stdin.")
+	}
+	rt.stdin = bytes.NewReader(data)
+	return string(data), nil
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirmSend(t *testing.T) {
+	newRuntime := func(prompt string, set func(*config.Config)) *runtime {
+		rt := &runtime{}
+		rt.cfg.API = "openai"
+		rt.cfg.Model = "big"
+		rt.cfg.NoCache = true
+		rt.cfg.Prefix = prompt
+		rt.cfg.APIs = config.APIs{{Name: "openai", Models: map[string]config.Model{
+			"big":   {Aliases: []string{"b"}, InputCost: 75},
+			"small": {},
+		}}}
+		set(&rt.cfg)
+		return rt
+	}
+	long := strings.Repeat("word ", 100)
+
+	t.Run("sends below the thresholds", func(t *testing.T) {
+		rt := newRuntime("hi", func(c *config.Config) { c.ConfirmTokens = 50 })
+		require.NoError(t, rt.confirmSend(nil))
+	})
+
+	t.Run("refuses above confirm-tokens without a terminal", func(t *testing.T) {
+		rt := newRuntime(long, func(c *config.Config) { c.ConfirmTokens = 50 })
+		err := rt.confirmSend(nil)
+		require.ErrorContains(t, err, "About to send ~126 input tokens (~$0.0095)")
+		require.ErrorContains(t, err, "--yes")
+	})
+
+	t.Run("refuses above confirm-cost", func(t *testing.T) {
+		rt := newRuntime(long, func(c *config.Config) { c.ConfirmCost = 0.001 })
+		require.Error(t, rt.confirmSend(nil))
+	})
+
+	t.Run("refuses a model in confirm-models by alias", func(t *testing.T) {
+		rt := newRuntime("hi", func(c *config.Config) { c.ConfirmModels = []string{"b"} })
+		require.ErrorContains(t, rt.confirmSend(nil), "input tokens to big")
+
+		rt = newRuntime("hi", func(c *config.Config) {
+			c.ConfirmModels = []string{"b"}
+			c.Model = "small"
+		})
+		require.NoError(t, rt.confirmSend(nil))
+	})
+
+	t.Run("sends with --yes", func(t *testing.T) {
+		rt := newRuntime(long, func(c *config.Config) {
+			c.ConfirmTokens = 50
+			c.Yes = true
+		})
+		require.NoError(t, rt.confirmSend(nil))
+	})
+}
@@ -1,12 +1,16 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
+	"github.com/dotcommander/yai/internal/agent"
 	"github.com/dotcommander/yai/internal/config"
 	"github.com/dotcommander/yai/internal/errs"
 	"github.com/dotcommander/yai/internal/present"
@@ -21,10 +25,26 @@ import (
 type conversationStore struct {
 	DB    *storage.DB
 	Cache *cache.Conversations
+
+	unlocks []func()
+	// tempDir holds a temporary store, used when the cache is not
+	// writable, and is removed on Close.
+	tempDir string
 }
 
 // openConversationStore opens both the metadata DB and the payload cache.
+// When the cache directory is not writable, it warns once and opens a
+// temporary store instead, so the run goes on without saving anything.
+// The conversations in the cache directory can still be read.
 func openConversationStore(cachePath string) (*conversationStore, error) {
+	store, err := openConversationStoreAt(cachePath)
+	if err != nil && config.IsUnwritable(err) {
+		return openTemporaryStore(cachePath, err)
+	}
+	return store, err
+}
+
+func openConversationStoreAt(cachePath string) (*conversationStore, error) {
 	convoCache, err := cache.NewConversations(cachePath)
 	if err != nil {
 		return nil, fmt.Errorf("open conversation cache: %w", err)
@@ -36,11 +56,75 @@ func openConversationStore(cachePath string) (*conversationStore, error) {
 	return &conversationStore{DB: db, Cache: convoCache}, nil
 }
 
-// Close releases the underlying DB resources.
+var unwritableCacheWarning sync.Once
+
+// openTemporaryStore opens a store in a temporary directory that reads the
+// conversations of cachePath but saves changes only to itself, after
+// warning that cachePath could not be written to because of cause.
+func openTemporaryStore(cachePath string, cause error) (*conversationStore, error) {
+	unwritableCacheWarning.Do(func() {
+		reason := cause.Error()
+		var pathErr *fs.PathError
+		if errors.As(cause, &pathErr) {
+			reason = pathErr.Err.Error()
+		}
+		fmt.Fprintln(os.Stderr, present.StderrStyles().Comment.Render(
+			"Warning: the cache directory "+cachePath+" is not writable ("+reason+"); "+
+				"conversations from this run will not be saved.",
+		))
+	})
+	dir, err := os.MkdirTemp("", "yai-cache-*")
+	if err != nil {
+		return nil, fmt.Errorf("create temporary conversation store: %w", err)
+	}
+	store, err := openConversationStoreAt(dir)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	store.tempDir = dir
+	// An index that cannot be read either leaves the store empty.
+	if store.DB.LoadReadOnly(filepath.Join(cachePath, "conversations")) == nil {
+		store.Cache.ReadFrom(cachePath)
+	}
+	return store, nil
+}
+
+// lock holds the conversation with the given ID until the store is closed,
+// so two runs continuing the same conversation cannot interleave their turns.
+func (s *conversationStore) lock(id string) error {
+	unlock, err := s.Cache.Lock(id)
+	if errors.Is(err, cache.ErrLocked) {
+		short := id
+		if len(short) > storage.SHA1Short {
+			short = short[:storage.SHA1Short]
+		}
+		return errs.Wrap(
+			errs.UserErrorf("conversation %s is in use by another yai process; try again once it finishes", short),
+			"Conversation is in use.",
+		)
+	}
+	if err != nil {
+		return errs.Wrap(err, "Could not lock the conversation.")
+	}
+	s.unlocks = append(s.unlocks, unlock)
+	return nil
+}
+
+// Close releases the conversation locks and the underlying DB resources.
 func (s *conversationStore) Close() error {
+	for _, unlock := range s.unlocks {
+		unlock()
+	}
+	s.unlocks = nil
 	if err := s.DB.Close(); err != nil {
 		return fmt.Errorf("close conversation store: %w", err)
 	}
+	if s.tempDir != "" {
+		if err := os.RemoveAll(s.tempDir); err != nil {
+			return fmt.Errorf("close conversation store: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -64,8 +148,18 @@ func saveConversationWithFeedback(cfg *config.Config, store *conversationStore,
 	id := cfg.CacheWriteToID
 	title := strings.TrimSpace(cfg.CacheWriteToTitle)
 
-	if storage.SHA1Regexp.MatchString(title) || title == "" {
+	if autoTitle(cfg) {
 		title = firstLine(lastPrompt(msgs))
+		// A title written by the model outlives later prompts until it is
+		// refreshed again.
+		if prev, err := store.DB.Find(id); err == nil && prev.TitleGenerated {
+			title = prev.Title
+		}
+		redacted, err := redactTitle(cfg, title)
+		if err != nil {
+			return err
+		}
+		title = redacted
 	}
 
 	errReason := fmt.Sprintf(
@@ -83,6 +177,16 @@ func saveConversationWithFeedback(cfg *config.Config, store *conversationStore,
 		}
 		return errs.Wrap(err, errReason)
 	}
+	if cfg.FallbackFrom != "" {
+		if err := store.DB.SetFallbackFrom(id, cfg.FallbackFrom); err != nil {
+			return errs.Wrap(err, errReason)
+		}
+	}
+	if cfg.RequestID != "" || cfg.ModelVersion != "" {
+		if err := store.DB.SetResponseMeta(id, cfg.RequestID, cfg.ModelVersion); err != nil {
+			return errs.Wrap(err, errReason)
+		}
+	}
 
 	if showSavedMessage && !cfg.Quiet {
 		fmt.Fprintln( //nolint:gosec // G705: writing to stderr, not an HTTP response; XSS is not applicable here
@@ -95,6 +199,90 @@ func saveConversationWithFeedback(cfg *config.Config, store *conversationStore,
 	return nil
 }
 
+// printFallbackNote tells the user when a fallback model answered instead of
+// the one they asked for.
+func printFallbackNote(cfg *config.Config) {
+	if cfg.FallbackFrom == "" || cfg.Quiet {
+		return
+	}
+	fmt.Fprintln(
+		os.Stderr,
+		present.StderrStyles().Comment.Render(fmt.Sprintf(
+			"\nNote: %s is not available; this answer is from %s (its fallback).",
+			cfg.FallbackFrom, cfg.Model,
+		)),
+	)
+}
+
+// autoTitle reports whether the conversation title is derived by yai rather
+// than given by the user.
+func autoTitle(cfg *config.Config) bool {
+	title := strings.TrimSpace(cfg.CacheWriteToTitle)
+	return title == "" || storage.SHA1Regexp.MatchString(title)
+}
+
+// refreshTitle asks the model for a new conversation title whenever the
+// number of user turns crosses a multiple of title-refresh-turns. added is
+// the number of user turns this run contributed. Failures are reported as
+// warnings since the conversation itself is already saved.
+func refreshTitle(ctx context.Context, cfg *config.Config, store *conversationStore, msgs []proto.Message, added int) {
+	every := cfg.TitleRefreshTurns
+	if cfg.NoCache || every <= 0 || added <= 0 || !autoTitle(cfg) {
+		return
+	}
+	turns := countUserTurns(msgs)
+	if turns/every == (turns-added)/every {
+		return
+	}
+
+	title, err := agent.New(cfg, nil, nil).Title(ctx, msgs)
+	if err == nil && title == "" {
+		return
+	}
+	if err == nil {
+		title, err = redactTitle(cfg, title)
+	}
+	if err == nil {
+		err = store.DB.SetGeneratedTitle(cfg.CacheWriteToID, title)
+	}
+	if cfg.Quiet {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, present.StderrStyles().Comment.Render("Warning: could not refresh the conversation title: "+err.Error()))
+		return
+	}
+	fmt.Fprintln( //nolint:gosec // G705: writing to stderr, not an HTTP response; XSS is not applicable here
+		os.Stderr,
+		"Conversation renamed:",
+		present.StderrStyles().Comment.Render(title),
+	)
+}
+
+// redactTitle redacts a title taken from a prompt or written by the model,
+// when title-redaction is set.
+func redactTitle(cfg *config.Config, title string) (string, error) {
+	if !cfg.TitleRedaction {
+		return title, nil
+	}
+	redactor, err := newRedactor(cfg)
+	if err != nil {
+		return "", err
+	}
+	title, _ = redactor.Redact(title)
+	return title, nil
+}
+
+func countUserTurns(messages []proto.Message) int {
+	n := 0
+	for _, msg := range messages {
+		if msg.Role == proto.RoleUser {
+			n++
+		}
+	}
+	return n
+}
+
 func lastPrompt(messages []proto.Message) string {
 	var result string
 	for _, msg := range messages {
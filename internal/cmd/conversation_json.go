@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/storage"
+)
+
+// conversationJSON is the JSON representation of a saved conversation
+// printed by `history show --json`, decoupled from the internal storage
+// format.
+type conversationJSON struct {
+	ID        string        `json:"id"`
+	Title     string        `json:"title"`
+	API       string        `json:"api,omitempty"`
+	Model     string        `json:"model,omitempty"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	Messages  []messageJSON `json:"messages"`
+
+	// FallbackFrom is the model that was asked for when Model answered as
+	// its fallback.
+	FallbackFrom string `json:"fallback_from,omitempty"`
+	Pinned       bool   `json:"pinned,omitempty"`
+	Note         string `json:"note,omitempty"`
+	// RequestID and ModelVersion are what the provider reported for the
+	// last answer.
+	RequestID    string `json:"request_id,omitempty"`
+	ModelVersion string `json:"model_version,omitempty"`
+}
+
+type messageJSON struct {
+	Role      string         `json:"role"`
+	Content   string         `json:"content"`
+	ToolCalls []toolCallJSON `json:"tool_calls,omitempty"`
+	Partial   bool           `json:"partial,omitempty"`
+	Time      time.Time      `json:"time,omitzero"`
+	// LatencyMS and FirstTokenMS are how long the model took to write the
+	// message, and to start it, in milliseconds.
+	LatencyMS    int64 `json:"latency_ms,omitempty"`
+	FirstTokenMS int64 `json:"first_token_ms,omitempty"`
+	// Logprobs are the log probabilities of the tokens of an answer, when
+	// they were asked for.
+	Logprobs []proto.LogprobJSON `json:"logprobs,omitempty"`
+}
+
+type toolCallJSON struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+func newConversationJSON(convo *storage.Conversation, messages []proto.Message) conversationJSON {
+	out := conversationJSON{
+		ID:        convo.ID,
+		Title:     convo.Title,
+		UpdatedAt: convo.UpdatedAt,
+		Messages:  newMessagesJSON(messages),
+		Pinned:    convo.Pinned,
+		Note:      convo.Note,
+
+		RequestID:    convo.RequestID,
+		ModelVersion: convo.ModelVersion,
+	}
+	if convo.API != nil {
+		out.API = *convo.API
+	}
+	if convo.Model != nil {
+		out.Model = *convo.Model
+	}
+	if convo.FallbackFrom != nil {
+		out.FallbackFrom = *convo.FallbackFrom
+	}
+	return out
+}
+
+func newMessagesJSON(messages []proto.Message) []messageJSON {
+	out := make([]messageJSON, 0, len(messages))
+	for _, msg := range messages {
+		m := messageJSON{
+			Role:         msg.Role,
+			Content:      msg.Content,
+			Partial:      msg.Partial,
+			Time:         msg.Time,
+			LatencyMS:    msg.Latency.Milliseconds(),
+			FirstTokenMS: msg.FirstToken.Milliseconds(),
+			Logprobs:     proto.NewLogprobsJSON(msg.Logprobs),
+		}
+		for _, call := range msg.ToolCalls {
+			m.ToolCalls = append(m.ToolCalls, toolCallJSON{
+				ID:        call.ID,
+				Name:      call.Function.Name,
+				Arguments: toolArgumentsJSON(call.Function.Arguments),
+				IsError:   call.IsError,
+			})
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// protoMessages converts messages decoded from JSON back into their internal
+// form; it reverses newMessagesJSON.
+func protoMessages(messages []messageJSON) []proto.Message {
+	out := make([]proto.Message, 0, len(messages))
+	for _, m := range messages {
+		msg := proto.Message{
+			Role:       m.Role,
+			Content:    m.Content,
+			Partial:    m.Partial,
+			Time:       m.Time,
+			Latency:    time.Duration(m.LatencyMS) * time.Millisecond,
+			FirstToken: time.Duration(m.FirstTokenMS) * time.Millisecond,
+			Logprobs:   proto.LogprobsFromJSON(m.Logprobs),
+		}
+		for _, call := range m.ToolCalls {
+			args := []byte(call.Arguments)
+			var quoted string
+			if json.Unmarshal(call.Arguments, &quoted) == nil {
+				args = []byte(quoted)
+			}
+			msg.ToolCalls = append(msg.ToolCalls, proto.ToolCall{
+				ID:       call.ID,
+				Function: proto.Function{Name: call.Name, Arguments: args},
+				IsError:  call.IsError,
+			})
+		}
+		out = append(out, msg)
+	}
+	return out
+}
+
+// toolArgumentsJSON embeds valid JSON arguments as-is and falls back to a
+// JSON string for anything else.
+func toolArgumentsJSON(args []byte) json.RawMessage {
+	if len(args) == 0 {
+		return nil
+	}
+	if json.Valid(args) {
+		return args
+	}
+	quoted, _ := json.Marshal(string(args))
+	return quoted
+}
+
+func writeConversationJSON(w io.Writer, convo *storage.Conversation, messages []proto.Message) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(newConversationJSON(convo, messages)); err != nil {
+		return errs.Wrap(err, "Could not write conversation.")
+	}
+	return nil
+}
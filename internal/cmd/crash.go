@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/proto"
+	"golang.org/x/term"
+)
+
+// issuesURL is where crashes are reported.
+const issuesURL = "https://github.com/dotcommander/yai/issues/new"
+
+// crash is a panic caught while yai ran, with the stack it was raised on.
+type crash struct {
+	value any
+	stack []byte
+}
+
+// inflight returns the messages of the chat that is open, for a crash to
+// save. runChat sets it; it is only called once the chat program stopped.
+var inflight func() []proto.Message //nolint:gochecknoglobals
+
+// crashHandler turns a panic that reaches Execute into a crash report
+// instead of a stack dump over a terminal left in raw mode.
+type crashHandler struct {
+	version  string
+	stateDir string
+	// term is the state of the terminal on stdin before yai ran, or nil
+	// when stdin is not a terminal.
+	term *term.State
+}
+
+func newCrashHandler(build BuildInfo, stateDir string) crashHandler {
+	h := crashHandler{version: normalizeBuildInfo(build).Version, stateDir: stateDir}
+	if present.IsInputTTY() {
+		h.term, _ = term.GetState(int(os.Stdin.Fd()))
+	}
+	return h
+}
+
+// recover handles a panic, if there is one: the terminal is restored, the
+// open chat is saved to a recovery file, and yai exits with a pointer to
+// the crash log. It must be deferred.
+func (h crashHandler) recover() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	c, ok := r.(crash)
+	if !ok {
+		c = crash{value: r, stack: debug.Stack()}
+	}
+	h.restoreTerminal()
+
+	var msgs []proto.Message
+	if inflight != nil {
+		msgs = inflight()
+	}
+	logPath, recoveryPath, err := h.write(c, msgs, time.Now())
+	fmt.Fprint(os.Stderr, crashReport(present.StderrStyles(), c, logPath, recoveryPath, err))
+	os.Exit(exitError)
+}
+
+// restoreTerminal undoes what a Bubble Tea program that did not get to
+// shut down may have left: raw mode, the alternate screen, bracketed paste,
+// and a hidden cursor.
+func (h crashHandler) restoreTerminal() {
+	if h.term != nil {
+		_ = term.Restore(int(os.Stdin.Fd()), h.term)
+	}
+	if present.IsErrorTTY() {
+		fmt.Fprint(os.Stderr, ansi.ResetModeAltScreenSaveCursor+ansi.ResetModeBracketedPaste+ansi.ShowCursor)
+	}
+}
+
+// write saves the crash log and, when msgs is not empty, the recovery file
+// in the crashes directory of the state directory. The recovery file has
+// the format of `history show --json`.
+func (h crashHandler) write(c crash, msgs []proto.Message, now time.Time) (logPath, recoveryPath string, err error) {
+	dir := os.TempDir()
+	if h.stateDir != "" {
+		dir = filepath.Join(h.stateDir, "crashes")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", "", fmt.Errorf("create crash directory: %w", err)
+	}
+	name := "yai-crash-" + now.Format("20060102-150405")
+
+	if len(msgs) > 0 {
+		data, err := json.MarshalIndent(conversationJSON{
+			Title:     "Recovered chat",
+			UpdatedAt: now,
+			Messages:  newMessagesJSON(msgs),
+		}, "", "  ")
+		if err == nil {
+			recoveryPath = filepath.Join(dir, name+".json")
+			if os.WriteFile(recoveryPath, data, 0o600) != nil {
+				recoveryPath = ""
+			}
+		}
+	}
+
+	var log strings.Builder
+	fmt.Fprintf(&log, "yai %s crashed at %s\n\n", h.version, now.Format(time.RFC3339))
+	fmt.Fprintf(&log, "panic: %v\n\n", c.value)
+	log.Write(c.stack)
+	logPath = filepath.Join(dir, name+".log")
+	if err := os.WriteFile(logPath, []byte(log.String()), 0o600); err != nil {
+		return "", recoveryPath, fmt.Errorf("write crash log: %w", err)
+	}
+	return logPath, recoveryPath, nil
+}
+
+// crashReport is what yai prints when it crashes. When the crash log could
+// not be written, the stack is printed instead.
+func crashReport(s present.Styles, c crash, logPath, recoveryPath string, err error) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n%s\n\n", s.ErrPadding.Render(s.ErrorHeader.String(), fmt.Sprintf("yai crashed: %v", c.value)))
+	if recoveryPath != "" {
+		fmt.Fprintf(&b, "  Your chat was saved to %s\n", s.InlineCode.Render(recoveryPath))
+	}
+	if err != nil {
+		fmt.Fprintf(&b, "  %s\n\n%s\n", s.Comment.Render("Could not save the crash log: "+err.Error()), c.stack)
+		fmt.Fprintf(&b, "  Please report this at %s with the stack above.\n\n", s.Link.Render(issuesURL))
+		return b.String()
+	}
+	fmt.Fprintf(&b, "  The stack trace is in %s\n", s.InlineCode.Render(logPath))
+	fmt.Fprintf(&b, "  Please report this at %s with the crash log.\n\n", s.Link.Render(issuesURL))
+	return b.String()
+}
+
+// crashGuard runs a Bubble Tea model with its panics caught. Bubble Tea's
+// own handler prints the panic over the output; the guard instead stops the
+// program, so the terminal is restored, and raises the panic again once Run
+// returned, for Execute to report.
+type crashGuard struct {
+	model   tea.Model
+	program *tea.Program
+
+	mu    sync.Mutex
+	crash *crash
+}
+
+// crashedMsg quits the program after a command panicked.
+type crashedMsg struct{}
+
+// runGuarded runs model as a Bubble Tea program, with a crashGuard, and
+// returns the final model. Commands of tea.Sequence are not guarded.
+func runGuarded(model tea.Model, opts ...tea.ProgramOption) (tea.Model, error) {
+	g := &crashGuard{model: model}
+	g.program = tea.NewProgram(g, append(opts, tea.WithoutCatchPanics())...)
+	_, err := g.program.Run()
+	g.mu.Lock()
+	c := g.crash
+	g.mu.Unlock()
+	if c != nil {
+		panic(*c)
+	}
+	return g.model, err
+}
+
+// record keeps the first panic. It must be called from the deferred
+// function that recovered r.
+func (g *crashGuard) record(r any) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.crash == nil {
+		g.crash = &crash{value: r, stack: debug.Stack()}
+	}
+}
+
+func (g *crashGuard) catch(cmd *tea.Cmd) {
+	if r := recover(); r != nil {
+		g.record(r)
+		*cmd = tea.Quit
+	}
+}
+
+func (g *crashGuard) Init() (cmd tea.Cmd) {
+	defer g.catch(&cmd)
+	return g.guard(g.model.Init())
+}
+
+func (g *crashGuard) Update(msg tea.Msg) (model tea.Model, cmd tea.Cmd) {
+	if _, ok := msg.(crashedMsg); ok {
+		return g, tea.Quit
+	}
+	model = g
+	defer g.catch(&cmd)
+	next, cmd := g.model.Update(msg)
+	g.model = next
+	return g, g.guard(cmd)
+}
+
+func (g *crashGuard) View() string {
+	defer func() {
+		if r := recover(); r != nil {
+			g.record(r)
+			// View runs in the event loop, which Send would block.
+			go g.program.Quit()
+		}
+	}()
+	return g.model.View()
+}
+
+// guard wraps cmd, and the commands of the batch it returns, so that a
+// panic in them quits the program.
+func (g *crashGuard) guard(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() (msg tea.Msg) {
+		defer func() {
+			if r := recover(); r != nil {
+				g.record(r)
+				msg = crashedMsg{}
+			}
+		}()
+		msg = cmd()
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			guarded := make(tea.BatchMsg, len(batch))
+			for i, c := range batch {
+				guarded[i] = g.guard(c)
+			}
+			return guarded
+		}
+		return msg
+	}
+}
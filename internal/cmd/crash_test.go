@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/dotcommander/yai/internal/present/presenttest"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/stretchr/testify/require"
+)
+
+// panicModel panics in the command its Init returns.
+type panicModel struct{}
+
+func (panicModel) Init() tea.Cmd {
+	return tea.Batch(nil, func() tea.Msg { panic("boom") })
+}
+func (m panicModel) Update(tea.Msg) (tea.Model, tea.Cmd) { return m, nil }
+func (panicModel) View() string                          { return "" }
+
+func TestRunGuarded_RaisesCommandPanic(t *testing.T) {
+	var got any
+	func() {
+		defer func() { got = recover() }()
+		_, _ = runGuarded(panicModel{}, tea.WithInput(nil), tea.WithOutput(io.Discard), tea.WithoutRenderer())
+	}()
+	c, ok := got.(crash)
+	require.True(t, ok, "got %#v", got)
+	require.Equal(t, "boom", c.value)
+	require.Contains(t, string(c.stack), "crash_test.go")
+}
+
+func TestCrashHandler_Write(t *testing.T) {
+	h := crashHandler{version: "v1.2.3", stateDir: t.TempDir()}
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	msgs := []proto.Message{
+		{Role: proto.RoleUser, Content: "hello"},
+		{Role: proto.RoleAssistant, Content: "hi"},
+	}
+	logPath, recoveryPath, err := h.write(crash{value: "boom", stack: []byte("goroutine 1 [running]:\n")}, msgs, now)
+	require.NoError(t, err)
+
+	log, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.Contains(t, string(log), "yai v1.2.3 crashed at 2026-01-02T03:04:05Z")
+	require.Contains(t, string(log), "panic: boom")
+	require.Contains(t, string(log), "goroutine 1 [running]:")
+
+	data, err := os.ReadFile(recoveryPath)
+	require.NoError(t, err)
+	var convo conversationJSON
+	require.NoError(t, json.Unmarshal(data, &convo))
+	require.Equal(t, "Recovered chat", convo.Title)
+	require.Len(t, convo.Messages, 2)
+	require.Equal(t, "hi", convo.Messages[1].Content)
+}
+
+func TestCrashHandler_WriteWithoutChat(t *testing.T) {
+	h := crashHandler{stateDir: t.TempDir()}
+	logPath, recoveryPath, err := h.write(crash{value: "boom"}, nil, time.Now())
+	require.NoError(t, err)
+	require.FileExists(t, logPath)
+	require.Empty(t, recoveryPath)
+}
+
+func TestCrashReport(t *testing.T) {
+	s := presenttest.Styles()
+	c := crash{value: "boom", stack: []byte("goroutine 1 [running]:")}
+
+	report := ansi.Strip(crashReport(s, c, "/cache/crashes/yai-crash.log", "/cache/crashes/yai-crash.json", nil))
+	require.Contains(t, report, "yai crashed: boom")
+	require.Contains(t, report, "/cache/crashes/yai-crash.log")
+	require.Contains(t, report, "/cache/crashes/yai-crash.json")
+	require.Contains(t, report, issuesURL)
+	require.NotContains(t, report, "goroutine 1")
+
+	report = ansi.Strip(crashReport(s, c, "", "", errors.New("read-only file system")))
+	require.Contains(t, report, "read-only file system")
+	require.Contains(t, report, "goroutine 1 [running]:")
+}
@@ -0,0 +1,435 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	imcp "github.com/dotcommander/yai/internal/mcp"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/provider"
+	"github.com/dotcommander/yai/internal/requestbuilder"
+	"github.com/dotcommander/yai/internal/storage/cache"
+	"github.com/dotcommander/yai/internal/stream"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// daemonDialTimeout bounds how long the CLI waits to find out whether a
+// daemon is listening before it runs the request itself.
+const daemonDialTimeout = 100 * time.Millisecond
+
+func newDaemonCmd(rt *runtime) *cobra.Command {
+	var socket string
+	cmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Keep MCP and provider connections warm and answer requests over a Unix socket",
+		Long: "Run yai in the background, keeping MCP servers and provider connections open between requests.\n" +
+			"While it is running, yai sends its requests to the daemon instead of starting MCP servers\n" +
+			"on every invocation. Use --no-daemon to bypass it for a single run.",
+		Example: `  yai daemon &
+  git diff | yai "write a commit message"`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			if socket == "" {
+				socket = rt.cfg.DaemonSocket
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return rt.runDaemon(ctx, socket)
+		},
+	}
+
+	flags := cmd.Flags()
+	s := present.StdoutStyles().FlagDesc
+	flags.StringVar(&socket, "socket", "", s.Render(helpText["daemon-socket"]))
+
+	return cmd
+}
+
+func (rt *runtime) runDaemon(ctx context.Context, socket string) error {
+	if dialDaemon(socket) != nil {
+		return errs.Wrap(errs.UserErrorf("A daemon is already listening on %s", socket), "Could not start the daemon.")
+	}
+	// Nothing answered, so any socket file left behind is stale.
+	if err := os.Remove(socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errs.Wrap(err, "Could not remove the stale daemon socket.")
+	}
+	if err := os.MkdirAll(filepath.Dir(socket), 0o700); err != nil {
+		return errs.Wrap(err, "Could not create the daemon socket directory.")
+	}
+	ln, err := listenPrivate(socket)
+	if err != nil {
+		return errs.Wrap(err, "Could not start the daemon.")
+	}
+
+	mcpSvc := imcp.New(&rt.cfg)
+	defer mcpSvc.Close()
+	clients := &warmClients{}
+
+	srv := &server{
+		cfg:     rt.cfg,
+		trusted: true,
+		complete: func(ctx context.Context, cfg *config.Config, history []proto.Message, prompt string, onEvent func(agent.Event)) (agent.Completion, error) {
+			svc := mcpSvc
+			if slices.Contains(cfg.MCPDisable, "*") {
+				// The client has tools turned off; leave the shared
+				// connections out of it.
+				svc = nil
+			}
+			return agent.New(cfg, nil, svc, clients.factory(cfg)).CompleteStream(ctx, history, prompt, onEvent)
+		},
+	}
+
+	if !rt.cfg.Quiet {
+		fmt.Fprintf(os.Stderr, "yai daemon listening on %s\n", socket)
+	}
+	return serveHTTP(ctx, ln, srv.handler())
+}
+
+// warmClients keeps a provider client for each provider config the daemon
+// has answered with, so later requests reuse its connections.
+type warmClients struct {
+	mu      sync.Mutex
+	clients map[warmClientKey]stream.Client
+}
+
+// warmClientKey is what tells provider clients apart: the provider config,
+// without the HTTP client that is made anew for each request, and the
+// settings that HTTP client was made from.
+type warmClientKey struct {
+	api, baseURL, apiKey, plugin string
+	thinkingBudget               int
+	httpProxy                    string
+	connectTimeout               time.Duration
+}
+
+// factory returns the client factory of a request with cfg.
+func (w *warmClients) factory(cfg *config.Config) agent.ClientFactory {
+	return func(pcfg provider.Config) (stream.Client, error) {
+		key := warmClientKey{
+			api:            pcfg.API,
+			baseURL:        pcfg.BaseURL,
+			apiKey:         pcfg.APIKey,
+			plugin:         pcfg.Plugin,
+			thinkingBudget: pcfg.ThinkingBudget,
+			httpProxy:      cfg.HTTPProxy,
+			connectTimeout: cfg.ConnectTimeout,
+		}
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if client, ok := w.clients[key]; ok {
+			return client, nil
+		}
+		client, err := agent.NewFantasyClient(pcfg)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		if w.clients == nil {
+			w.clients = map[warmClientKey]stream.Client{}
+		}
+		w.clients[key] = client
+		return client, nil
+	}
+}
+
+// parseDaemonFlags applies the flags a client sent to cfg, the daemon's
+// copy of its settings. Flags of other commands are ignored.
+func parseDaemonFlags(cfg *config.Config, args []string) error {
+	cmd := &cobra.Command{FParseErrWhitelist: cobra.FParseErrWhitelist{UnknownFlags: true}}
+	registerSharedFlags(cmd, cfg)
+	flags := cmd.Flags()
+	flags.Int64Var(&cfg.Logprobs, "logprobs", cfg.Logprobs, "")
+	flags.BoolVar(&cfg.MCPAllowNonTTY, "mcp-allow-non-tty", cfg.MCPAllowNonTTY, "")
+	return cmd.ParseFlags(args) //nolint:wrapcheck
+}
+
+// daemonFlags are the flags of a run to send to the daemon: the ones given
+// on the command line, then the model, role, and format this run settled
+// on, and whether it may use tools.
+func daemonFlags(flags *pflag.FlagSet, cfg *config.Config, mod config.Model) []string {
+	var args []string
+	if flags != nil {
+		flags.Visit(func(f *pflag.Flag) {
+			if values, ok := f.Value.(pflag.SliceValue); ok {
+				for _, v := range values.GetSlice() {
+					args = append(args, "--"+f.Name+"="+v)
+				}
+				return
+			}
+			args = append(args, "--"+f.Name+"="+f.Value.String())
+		})
+	}
+	args = append(args,
+		"--api="+mod.API,
+		"--model="+mod.Name,
+		"--role="+cfg.Role,
+		"--format="+strconv.FormatBool(cfg.Format),
+	)
+	// The daemon's stdin says nothing about this client's, so decide here
+	// whether tools are allowed.
+	if cfg.MCPAllowNonTTY || present.IsInputTTY() {
+		args = append(args, "--mcp-allow-non-tty=true")
+	} else {
+		args = append(args, "--mcp-disable=*")
+	}
+	return args
+}
+
+// daemonClient sends completion requests to a running yai daemon.
+type daemonClient struct {
+	http *http.Client
+	// flags are the flags of the run; see daemonFlags.
+	flags *pflag.FlagSet
+}
+
+// daemon returns a client for the running daemon, or nil when the request
+// should run in this process.
+func (rt *runtime) daemon() *daemonClient {
+	if rt.cfg.NoDaemon {
+		return nil
+	}
+	d := dialDaemon(rt.cfg.DaemonSocket)
+	if d != nil {
+		d.flags = rt.flags
+	}
+	return d
+}
+
+// dialDaemon returns a client for the daemon listening on socket, or nil when
+// there is none.
+func dialDaemon(socket string) *daemonClient {
+	if socket == "" {
+		return nil
+	}
+	conn, err := net.DialTimeout("unix", socket, daemonDialTimeout)
+	if err != nil {
+		return nil
+	}
+	conn.Close() //nolint:errcheck
+
+	return &daemonClient{http: &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}}}
+}
+
+// Stream builds the request locally, so history and prompt limits behave as
+// they do without a daemon, and streams the response from the daemon. The
+// flags of the run travel with the request, and the daemon applies them to
+// its own settings, API keys included.
+func (d *daemonClient) Stream(ctx context.Context, cfg *config.Config, cacheStore *cache.Conversations, prompt string) (agent.StreamStart, error) {
+	_, mod, err := requestbuilder.ResolveModel(cfg)
+	if err != nil {
+		return agent.StreamStart{}, fmt.Errorf("resolve model: %w", err)
+	}
+	built, err := requestbuilder.BuildRequestFromPrompt(cfg, mod, cacheStore, prompt)
+	if err != nil {
+		return agent.StreamStart{}, fmt.Errorf("build request: %w", err)
+	}
+
+	messages := built.Messages
+	last := messages[len(messages)-1]
+	var history []proto.Message
+	for _, msg := range messages[:len(messages)-1] {
+		if msg.Role != proto.RoleSystem {
+			history = append(history, msg)
+		}
+	}
+
+	body, err := json.Marshal(serveRequest{
+		Prompt:   last.Content,
+		Messages: newMessagesJSON(history),
+		Stream:   true,
+		Flags:    daemonFlags(d.flags, cfg, mod),
+	})
+	if err != nil {
+		return agent.StreamStart{}, fmt.Errorf("encode daemon request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://yai/v1/completions", bytes.NewReader(body))
+	if err != nil {
+		return agent.StreamStart{}, fmt.Errorf("new daemon request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.http.Do(req)
+	if err != nil {
+		return agent.StreamStart{}, fmt.Errorf("send daemon request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close() //nolint:errcheck
+		var serr serveError
+		if err := json.NewDecoder(resp.Body).Decode(&serr); err != nil || serr.Error == "" {
+			serr.Error = resp.Status
+		}
+		return agent.StreamStart{}, errs.Wrap(errors.New(serr.Error), "The yai daemon rejected the request.")
+	}
+
+	return agent.StreamStart{
+		Stream:   &daemonStream{body: resp.Body, events: bufio.NewReader(resp.Body), cfg: cfg},
+		Model:    mod,
+		Messages: messages,
+	}, nil
+}
+
+// daemonStream reads the daemon's Server-Sent Events as a stream.Stream.
+// Tool calls already ran in the daemon; CallTools only reports them.
+type daemonStream struct {
+	body   io.ReadCloser
+	events *bufio.Reader
+	cfg    *config.Config
+
+	chunk    string
+	tools    []proto.ToolCallStatus
+	messages []proto.Message
+	usage    proto.Usage
+	meta     proto.ResponseMeta
+	warnings []string
+	err      error
+	done     bool
+}
+
+func (s *daemonStream) Next() bool {
+	for !s.done && s.err == nil {
+		event, data, err := s.readEvent()
+		if err != nil {
+			s.err = err
+			return false
+		}
+		switch event {
+		case "chunk":
+			var chunk serveChunk
+			if s.decode(data, &chunk) {
+				s.chunk = chunk.Content
+				return true
+			}
+		case "tool_call":
+			var call serveToolCall
+			if s.decode(data, &call) {
+				status := proto.ToolCallStatus{Name: call.Name}
+				if call.Error != "" {
+					status.Err = errors.New(call.Error)
+				}
+				s.tools = append(s.tools, status)
+				return false
+			}
+		case "retry":
+			var serr serveError
+			if s.decode(data, &serr) {
+				s.warnings = append(s.warnings, "The daemon is retrying after an error: "+serr.Error)
+			}
+		case "error":
+			var serr serveError
+			if s.decode(data, &serr) {
+				s.err = errors.New(serr.Error)
+			}
+		case "done":
+			var res serveResponse
+			if s.decode(data, &res) {
+				s.finish(res)
+			}
+		}
+	}
+	return false
+}
+
+func (s *daemonStream) decode(data []byte, v any) bool {
+	if err := json.Unmarshal(data, v); err != nil {
+		s.err = fmt.Errorf("decode daemon event: %w", err)
+		return false
+	}
+	return true
+}
+
+func (s *daemonStream) finish(res serveResponse) {
+	s.done = true
+	s.messages = protoMessages(res.Messages)
+	s.usage = proto.Usage{
+		InputTokens:      res.Usage.InputTokens,
+		OutputTokens:     res.Usage.OutputTokens,
+		TotalTokens:      res.Usage.TotalTokens,
+		CacheWriteTokens: res.Usage.CacheWriteTokens,
+		CacheReadTokens:  res.Usage.CacheReadTokens,
+	}
+	s.meta = proto.ResponseMeta{RequestID: res.RequestID, ModelVersion: res.ModelVersion}
+	s.warnings = append(s.warnings, res.Warnings...)
+	if res.FallbackFrom != "" {
+		agent.UseFallback(s.cfg, res.Model)
+	}
+}
+
+// readEvent reads the next event from the stream.
+func (s *daemonStream) readEvent() (event string, data []byte, err error) {
+	for {
+		line, err := s.events.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return "", nil, errors.New("the yai daemon closed the connection before the response finished")
+			}
+			return "", nil, fmt.Errorf("read daemon response: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "":
+			if event != "" {
+				return event, data, nil
+			}
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = append(data, strings.TrimPrefix(line, "data: ")...)
+		}
+	}
+}
+
+func (s *daemonStream) Current() (proto.Chunk, error) {
+	return proto.Chunk{Content: s.chunk}, nil
+}
+
+func (s *daemonStream) Close() error {
+	return s.body.Close() //nolint:wrapcheck
+}
+
+func (s *daemonStream) Err() error { return s.err }
+
+func (s *daemonStream) Messages() []proto.Message { return s.messages }
+
+func (s *daemonStream) CallTools() []proto.ToolCallStatus {
+	tools := s.tools
+	s.tools = nil
+	return tools
+}
+
+func (s *daemonStream) DrainWarnings() []string {
+	warnings := s.warnings
+	s.warnings = nil
+	return warnings
+}
+
+func (s *daemonStream) Usage() proto.Usage { return s.usage }
+
+func (s *daemonStream) ResponseMeta() proto.ResponseMeta { return s.meta }
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"testing"
+
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/provider"
+	"github.com/dotcommander/yai/internal/storage/cache"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenPrivate(t *testing.T) {
+	if goruntime.GOOS == "windows" {
+		t.Skip("Windows has no socket file modes")
+	}
+	socket := filepath.Join(t.TempDir(), "daemon.sock")
+	ln, err := listenPrivate(socket)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	info, err := os.Stat(socket)
+	require.NoError(t, err)
+	require.Zero(t, info.Mode().Perm()&0o077, "the socket is created for this user only")
+}
+
+func TestDaemonClient(t *testing.T) {
+	t.Run("returns nil without a daemon", func(t *testing.T) {
+		require.Nil(t, dialDaemon(filepath.Join(t.TempDir(), "daemon.sock")))
+		require.Nil(t, dialDaemon(""))
+	})
+
+	t.Run("streams the response", func(t *testing.T) {
+		var (
+			gotCfg     config.Config
+			gotHistory []proto.Message
+			gotPrompt  string
+		)
+		socket := filepath.Join(t.TempDir(), "daemon.sock")
+		ln, err := net.Listen("unix", socket)
+		require.NoError(t, err)
+		daemonCfg := config.Config{Settings: config.Settings{
+			APIs:        config.APIs{{Name: "openai", APIKey: "daemon-key"}},
+			Temperature: 0.3,
+		}}
+		srv := &http.Server{Handler: (&server{cfg: daemonCfg, trusted: true, complete: func(_ context.Context, cfg *config.Config, history []proto.Message, prompt string, onEvent func(agent.Event)) (agent.Completion, error) {
+			gotCfg, gotHistory, gotPrompt = *cfg, history, prompt
+			onEvent(agent.Event{Type: agent.EventChunk, Content: "hel"})
+			onEvent(agent.Event{Type: agent.EventToolCall, Tool: proto.ToolCallStatus{Name: "fs_read", Err: errors.New("denied")}})
+			onEvent(agent.Event{Type: agent.EventChunk, Content: "lo"})
+			return agent.Completion{
+				Response: "hello",
+				Model:    config.Model{Name: cfg.Model, API: cfg.API},
+				Messages: append(history,
+					proto.Message{Role: proto.RoleUser, Content: prompt},
+					proto.Message{Role: proto.RoleAssistant, Content: "hello"},
+				),
+				Usage: proto.Usage{InputTokens: 2, OutputTokens: 1, TotalTokens: 3},
+			}, nil
+		}}).handler()} //nolint:gosec
+		go srv.Serve(ln) //nolint:errcheck
+		t.Cleanup(func() { _ = srv.Close() })
+
+		convos, err := cache.NewConversations(t.TempDir())
+		require.NoError(t, err)
+		saved := []proto.Message{
+			{Role: proto.RoleSystem, Content: "be brief"},
+			{Role: proto.RoleUser, Content: "earlier"},
+			{Role: proto.RoleAssistant, Content: "reply"},
+		}
+		require.NoError(t, convos.Write("abc", &saved))
+
+		cfg := &config.Config{Settings: config.Settings{
+			API:   "openai",
+			Model: "mini",
+			APIs: config.APIs{{
+				Name:   "openai",
+				Models: map[string]config.Model{"gpt-4.1-mini": {Aliases: []string{"mini"}}},
+			}},
+			Temperature: 0.3,
+		}}
+		cfg.Prefix = "hi"
+		cfg.CacheReadFromID = "abc"
+		cmd := &cobra.Command{}
+		initRootFlags(cmd, cfg)
+		require.NoError(t, cmd.ParseFlags([]string{"--temp", "0.7", "--logprobs", "2", "--stop", "a", "--stop", "b"}))
+
+		daemon := dialDaemon(socket)
+		require.NotNil(t, daemon)
+		daemon.flags = cmd.Flags()
+		res, err := daemon.Stream(context.Background(), cfg, convos, "")
+		require.NoError(t, err)
+		require.Equal(t, "gpt-4.1-mini", res.Model.Name)
+		st := res.Stream
+		defer st.Close() //nolint:errcheck
+
+		var chunks []string
+		var tools []proto.ToolCallStatus
+		for {
+			for st.Next() {
+				chunk, err := st.Current()
+				require.NoError(t, err)
+				chunks = append(chunks, chunk.Content)
+			}
+			require.NoError(t, st.Err())
+			calls := st.CallTools()
+			if len(calls) == 0 {
+				break
+			}
+			tools = append(tools, calls...)
+		}
+
+		// Flags travel, and the daemon's own settings fill in the rest.
+		require.Equal(t, "gpt-4.1-mini", gotCfg.Model)
+		require.Equal(t, "openai", gotCfg.API)
+		require.InDelta(t, 0.7, gotCfg.Temperature, 1e-9)
+		require.Equal(t, int64(2), gotCfg.Logprobs)
+		require.Equal(t, []string{"a", "b"}, gotCfg.Stop)
+		require.Equal(t, "daemon-key", gotCfg.APIs[0].APIKey)
+		require.Equal(t, saved[1:], gotHistory)
+		require.Equal(t, "hi", gotPrompt)
+
+		require.Equal(t, []string{"hel", "lo"}, chunks)
+		require.Len(t, tools, 1)
+		require.Equal(t, "fs_read", tools[0].Name)
+		require.EqualError(t, tools[0].Err, "denied")
+		require.Equal(t, int64(3), st.Usage().TotalTokens)
+		require.Equal(t, append(saved[1:],
+			proto.Message{Role: proto.RoleUser, Content: "hi"},
+			proto.Message{Role: proto.RoleAssistant, Content: "hello"},
+		), st.Messages())
+	})
+}
+
+func TestWarmClients(t *testing.T) {
+	var clients warmClients
+	cfg := &config.Config{}
+	first, err := clients.factory(cfg)(provider.Config{API: "openai", APIKey: "a", HTTPClient: &http.Client{}})
+	require.NoError(t, err)
+	again, err := clients.factory(cfg)(provider.Config{API: "openai", APIKey: "a", HTTPClient: &http.Client{}})
+	require.NoError(t, err)
+	require.Same(t, first, again, "a request to the same API reuses the client")
+
+	other, err := clients.factory(cfg)(provider.Config{API: "openai", APIKey: "b"})
+	require.NoError(t, err)
+	require.NotSame(t, first, other)
+
+	cfg.HTTPProxy = "http://proxy:8080"
+	proxied, err := clients.factory(cfg)(provider.Config{API: "openai", APIKey: "a"})
+	require.NoError(t, err)
+	require.NotSame(t, first, proxied)
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/spf13/pflag"
+)
+
+// Values of the default-command setting.
+const (
+	defaultCommandGenerate = "generate"
+	defaultCommandChat     = "chat"
+)
+
+// defaultsToChat reports whether a bare yai runs chat instead of generate:
+// default-command is chat, stdin and stdout are terminals, so pipelines keep
+// working, and chat takes every flag that was set.
+func (rt *runtime) defaultsToChat(flags, chatFlags *pflag.FlagSet) (bool, error) {
+	switch rt.cfg.DefaultCommand {
+	case "", defaultCommandGenerate:
+		return false, nil
+	case defaultCommandChat:
+	default:
+		return false, errs.Wrap(
+			errs.UserErrorf("Set it to %s or %s.", defaultCommandGenerate, defaultCommandChat),
+			"Invalid default-command "+rt.cfg.DefaultCommand+".",
+		)
+	}
+	if !present.IsInputTTY() || !present.IsOutputTTY() {
+		return false, nil
+	}
+	return chatTakesFlags(flags, chatFlags), nil
+}
+
+// chatTakesFlags reports whether every flag set in flags is one of
+// chatFlags.
+func chatTakesFlags(flags, chatFlags *pflag.FlagSet) bool {
+	ok := true
+	flags.Visit(func(f *pflag.Flag) {
+		if chatFlags.Lookup(f.Name) == nil {
+			ok = false
+		}
+	})
+	return ok
+}
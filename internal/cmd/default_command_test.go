@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultsToChat(t *testing.T) {
+	t.Run("generate", func(t *testing.T) {
+		for _, value := range []string{"", defaultCommandGenerate} {
+			rt := &runtime{cfg: config.Config{}}
+			rt.cfg.DefaultCommand = value
+			chat, err := rt.defaultsToChat(nil, nil)
+			require.NoError(t, err)
+			require.False(t, chat)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		rt := &runtime{cfg: config.Config{}}
+		rt.cfg.DefaultCommand = "repl"
+		_, err := rt.defaultsToChat(nil, nil)
+		require.Error(t, err)
+	})
+}
+
+func TestChatTakesFlags(t *testing.T) {
+	root := NewRootCmd(BuildInfo{}, config.Config{}, nil)
+	chat, _, err := root.Find([]string{"chat"})
+	require.NoError(t, err)
+
+	require.True(t, chatTakesFlags(root.Flags(), chat.Flags()))
+
+	require.NoError(t, root.ParseFlags([]string{"--model", "gpt-4o", "--role", "shell"}))
+	require.True(t, chatTakesFlags(root.Flags(), chat.Flags()))
+
+	require.NoError(t, root.ParseFlags([]string{"--list"}))
+	require.False(t, chatTakesFlags(root.Flags(), chat.Flags()))
+}
@@ -0,0 +1,307 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	imcp "github.com/dotcommander/yai/internal/mcp"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/provider"
+	"github.com/dotcommander/yai/internal/requestbuilder"
+	"github.com/muesli/termenv"
+	"github.com/spf13/cobra"
+)
+
+// doctorTimeout bounds the check of each API.
+const doctorTimeout = 10 * time.Second
+
+// checkStatus is the outcome of a doctor check, from best to worst.
+type checkStatus int
+
+const (
+	checkOK checkStatus = iota
+	checkSkipped
+	checkWarn
+	checkFailed
+)
+
+var checkLabels = map[checkStatus]string{
+	checkOK:      "ok",
+	checkSkipped: "skip",
+	checkWarn:    "warn",
+	checkFailed:  "fail",
+}
+
+// doctorCheck is one thing `yai doctor` checked.
+type doctorCheck struct {
+	Name   string
+	Status checkStatus
+	Detail string
+	// Hint says how to fix a check that warned or failed.
+	Hint string
+}
+
+func newDoctorCmd(rt *runtime) *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the settings, APIs, MCP servers, cache, and terminal",
+		Long: "Check that the settings load, that the default model's API and every other configured API with a key " +
+			"can be reached and accept the key, that the enabled MCP servers start, that the cache directory is " +
+			"writable, and what the terminal supports, with a hint on how to fix each problem. APIs are checked " +
+			"by listing their models, which costs no tokens. Only the API of the default model fails the check; " +
+			"problems with other APIs are warnings.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return printDoctor(os.Stdout, present.StdoutStyles(), runDoctor(cmd.Context(), rt))
+		},
+	}
+}
+
+// runDoctor runs the checks. Settings that do not load are the only check,
+// since the others depend on them.
+func runDoctor(ctx context.Context, rt *runtime) []doctorCheck {
+	if rt.cfgErr != nil {
+		return []doctorCheck{{
+			Name:   "settings",
+			Status: checkFailed,
+			Detail: rt.cfgErr.Error(),
+			Hint:   "Fix the settings file with `yai --settings`, or start over with `yai --reset-settings`.",
+		}}
+	}
+	cfg := &rt.cfg
+	checks := []doctorCheck{settingsCheck(cfg)}
+	model, defaultAPI := modelCheck(cfg)
+	checks = append(checks, model, cacheCheck(cfg), terminalCheck())
+	checks = append(checks, apiChecks(ctx, cfg, defaultAPI)...)
+	return append(checks, mcpChecks(ctx, cfg)...)
+}
+
+func settingsCheck(cfg *config.Config) doctorCheck {
+	check := doctorCheck{Name: "settings", Detail: cfg.SettingsPath}
+	if len(cfg.DeprecatedKeys) > 0 {
+		paths := make([]string, 0, len(cfg.DeprecatedKeys))
+		for _, key := range cfg.DeprecatedKeys {
+			paths = append(paths, key.Path)
+		}
+		check.Status = checkWarn
+		check.Detail = "deprecated keys: " + strings.Join(paths, ", ")
+		check.Hint = "Run `yai config migrate` to update them."
+	} else if len(cfg.UnknownKeys) > 0 {
+		paths := make([]string, 0, len(cfg.UnknownKeys))
+		for _, key := range cfg.UnknownKeys {
+			paths = append(paths, key.Path)
+		}
+		check.Status = checkWarn
+		check.Detail = "unknown keys: " + strings.Join(paths, ", ")
+		check.Hint = "They are ignored; fix or remove them."
+	}
+	return check
+}
+
+// modelCheck checks that the default model is in the settings, and returns
+// the API that serves it.
+func modelCheck(cfg *config.Config) (doctorCheck, string) {
+	resolved := *cfg
+	api, mod, err := requestbuilder.ResolveModel(&resolved)
+	if err != nil {
+		check := doctorCheck{Name: "default model", Status: checkFailed, Detail: err.Error()}
+		var reason errs.Error
+		if errors.As(err, &reason) {
+			check.Detail, check.Hint = reason.Reason, err.Error()
+		}
+		return check, ""
+	}
+	return doctorCheck{Name: "default model", Detail: mod.Name + " on " + api.Name}, api.Name
+}
+
+func cacheCheck(cfg *config.Config) doctorCheck {
+	check := doctorCheck{Name: "cache", Detail: cfg.CachePath + " is writable"}
+	err := os.MkdirAll(cfg.CachePath, 0o700)
+	if err == nil {
+		var f *os.File
+		if f, err = os.CreateTemp(cfg.CachePath, ".doctor-*"); err == nil {
+			f.Close() //nolint:errcheck,gosec
+			err = os.Remove(f.Name())
+		}
+	}
+	if err != nil {
+		check.Status = checkFailed
+		check.Detail = err.Error()
+		check.Hint = fmt.Sprintf("Make %s writable, or set cache-path to a directory that is; conversations are not saved until then.", cfg.CachePath)
+		if !config.IsUnwritable(err) {
+			check.Hint = "Set cache-path to a directory yai can create and write to; conversations are not saved until then."
+		}
+	}
+	return check
+}
+
+func terminalCheck() doctorCheck {
+	var ttys []string
+	for name, ok := range map[string]bool{
+		"stdin":  present.IsInputTTY(),
+		"stdout": present.IsOutputTTY(),
+		"stderr": present.IsErrorTTY(),
+	} {
+		if ok {
+			ttys = append(ttys, name)
+		}
+	}
+	slices.Sort(ttys)
+	profile := present.StdoutRenderer().ColorProfile()
+	check := doctorCheck{Name: "terminal", Detail: "no terminal"}
+	if len(ttys) > 0 {
+		check.Detail = "terminal on " + strings.Join(ttys, ", ")
+	}
+	check.Detail += "; colors: " + profile.Name()
+	if present.IsOutputTTY() && profile == termenv.Ascii && os.Getenv("NO_COLOR") == "" {
+		check.Status = checkWarn
+		check.Hint = fmt.Sprintf("The terminal reports no colors (TERM=%q); set TERM=xterm-256color or COLORTERM=truecolor if it has them.", os.Getenv("TERM"))
+	}
+	return check
+}
+
+// apiChecks checks the configured APIs side by side, in settings order.
+func apiChecks(ctx context.Context, cfg *config.Config, defaultAPI string) []doctorCheck {
+	svc := agent.New(cfg, nil, nil)
+	checks := make([]doctorCheck, len(cfg.APIs))
+	var wg sync.WaitGroup
+	for i, api := range cfg.APIs {
+		wg.Go(func() {
+			ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+			defer cancel()
+			checks[i] = apiCheck(api.Name, svc.Ping(ctx, api), api.Name == defaultAPI)
+		})
+	}
+	wg.Wait()
+	return checks
+}
+
+// apiCheck explains the result of pinging the API name. An API without a
+// key is skipped, and problems with APIs other than the default one are
+// only warnings: the settings template lists many APIs, and few people use
+// them all.
+func apiCheck(name string, err error, isDefault bool) doctorCheck {
+	check := doctorCheck{Name: "api " + name, Detail: "reachable, key accepted"}
+	var providerErr *fantasy.ProviderError
+	switch {
+	case err == nil:
+		return check
+	case errors.Is(err, provider.ErrPingUnsupported):
+		check.Status, check.Detail = checkSkipped, "not checked: "+err.Error()
+		return check
+	case errors.Is(err, requestbuilder.ErrMissingKey):
+		check.Status, check.Detail = checkSkipped, "no API key"
+		var reason errs.Error
+		for e := err; errors.As(e, &reason); e = reason.Err {
+			check.Hint = reason.Reason
+		}
+	case errors.As(err, &providerErr) && exitCode(err) == exitAuth:
+		check.Status = checkWarn
+		check.Detail = fmt.Sprintf("the key was rejected: %d %s", providerErr.StatusCode, providerErr.Title)
+		check.Hint = fmt.Sprintf("Check api-key, api-key-env, or api-key-cmd of apis.%s.", name)
+	case errors.As(err, &providerErr) && providerErr.StatusCode == http.StatusNotFound:
+		check.Status = checkWarn
+		check.Detail = "reachable, but it has no models list to check the key with"
+		check.Hint = fmt.Sprintf("Check base-url of apis.%s if requests to it fail.", name)
+		return check
+	case agent.FailureKind(err) == agent.FailureNetwork, agent.FailureKind(err) == agent.FailureTimeout:
+		check.Status, check.Detail = checkWarn, "could not reach it: "+err.Error()
+		check.Hint = fmt.Sprintf("Check base-url of apis.%s, the http-proxy setting, and your connection.", name)
+	default:
+		check.Status, check.Detail = checkWarn, err.Error()
+	}
+	if isDefault {
+		check.Status = checkFailed
+	}
+	return check
+}
+
+// mcpChecks starts the enabled MCP servers side by side, in name order.
+func mcpChecks(ctx context.Context, cfg *config.Config) []doctorCheck {
+	svc := imcp.New(cfg)
+	defer svc.Close()
+	var names []string
+	for name := range svc.EnabledServers() {
+		names = append(names, name)
+	}
+	checks := make([]doctorCheck, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Go(func() {
+			ctx, cancel := context.WithTimeout(ctx, cfg.MCPTimeout)
+			defer cancel()
+			tools, err := svc.Check(ctx, name)
+			checks[i] = mcpCheck(name, tools, err, cfg.MCPTimeout)
+		})
+	}
+	wg.Wait()
+	return checks
+}
+
+// mcpCheck explains the result of starting the MCP server name, which
+// offered tools.
+func mcpCheck(name string, tools int, err error, timeout time.Duration) doctorCheck {
+	check := doctorCheck{Name: "mcp " + name, Detail: fmt.Sprintf("started, %d tools", tools)}
+	if err == nil {
+		return check
+	}
+	check.Status, check.Detail = checkFailed, err.Error()
+	check.Hint = fmt.Sprintf("Check command, args, and env of mcp-servers.%s, or leave it out with --mcp-disable %s.", name, name)
+	if errors.Is(err, context.DeadlineExceeded) {
+		check.Hint = fmt.Sprintf("It did not answer within mcp-timeout (%s); if it runs in a container, make sure that is running.", timeout)
+	}
+	return check
+}
+
+// printDoctor writes the checks to w, and fails when any of them did.
+func printDoctor(w io.Writer, s present.Styles, checks []doctorCheck) error {
+	failed, worst := 0, checkOK
+	for _, check := range checks {
+		printCheck(w, s, check)
+		if check.Status == checkFailed {
+			failed++
+		}
+		worst = max(worst, check.Status)
+	}
+	if failed > 0 {
+		return errs.Wrap(
+			errs.UserErrorf("Follow the hints above, then run yai doctor again."),
+			fmt.Sprintf("%d of %d checks failed.", failed, len(checks)),
+		)
+	}
+	if worst <= checkSkipped {
+		fmt.Fprintln(w, s.Comment.Render("\nNo problems found."))
+	}
+	return nil
+}
+
+// printCheck writes check to w as one line of a doctor report, with its
+// hint below it when it warned or failed.
+func printCheck(w io.Writer, s present.Styles, check doctorCheck) {
+	label := fmt.Sprintf("%-4s", checkLabels[check.Status])
+	switch check.Status {
+	case checkOK:
+		label = s.Flag.Render(label)
+	case checkSkipped:
+		label = s.Comment.Render(label)
+	case checkWarn, checkFailed:
+		label = s.Warning.Render(label)
+	}
+	detail := strings.ReplaceAll(check.Detail, "\n", "\n"+strings.Repeat(" ", 24))
+	fmt.Fprintf(w, "%s  %-16s  %s\n", label, check.Name, detail)
+	if check.Hint != "" && check.Status >= checkWarn {
+		fmt.Fprintf(w, "%24s%s\n", "", s.Comment.Render(check.Hint))
+	}
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoctor(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			http.Error(w, `{"error": "invalid key"}`, http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": []}`))
+	}))
+	defer srv.Close()
+	t.Setenv("ANTHROPIC_API_KEY", "")
+
+	dir := t.TempDir()
+	rt := &runtime{cfg: config.Config{
+		Settings: config.Settings{
+			Model:          "gpt-test",
+			CachePath:      filepath.Join(dir, "cache"),
+			ConnectTimeout: time.Second,
+			MCPTimeout:     5 * time.Second,
+			APIs: config.APIs{
+				{Name: "local", APIKey: "test-key", BaseURL: srv.URL, Models: map[string]config.Model{"gpt-test": {}}},
+				{Name: "other", APIKey: "wrong", BaseURL: srv.URL},
+				{Name: "anthropic"},
+			},
+			MCPServers: map[string]config.MCPServerConfig{
+				"broken": {Command: "sh", Args: []string{"-c", "echo 'boom' >&2; exit 1"}},
+			},
+		},
+	}}
+	rt.cfg.SettingsPath = filepath.Join(dir, "yai.yml")
+	rt.cfg.DeprecatedKeys = []config.DeprecatedKey{{Path: "palette"}}
+
+	checks := runDoctor(context.Background(), rt)
+	statuses := map[string]checkStatus{}
+	for _, check := range checks {
+		statuses[check.Name] = check.Status
+		if check.Name == "api anthropic" {
+			require.Contains(t, check.Hint, "ANTHROPIC_API_KEY required")
+		}
+	}
+	require.Equal(t, map[string]checkStatus{
+		"settings":      checkWarn,
+		"default model": checkOK,
+		"cache":         checkOK,
+		"terminal":      checkOK,
+		"api local":     checkOK,
+		"api other":     checkWarn,
+		"api anthropic": checkSkipped,
+		"mcp broken":    checkFailed,
+	}, statuses)
+
+	var out bytes.Buffer
+	err := printDoctor(&out, present.StdoutStyles(), checks)
+	require.EqualError(t, err, "Follow the hints above, then run yai doctor again.")
+	require.Contains(t, out.String(), "the key was rejected: 401 Unauthorized")
+	require.NotContains(t, out.String(), "ANTHROPIC_API_KEY required", "skipped checks need no hint")
+	require.Contains(t, out.String(), "boom")
+	require.Contains(t, out.String(), "yai config migrate")
+
+	t.Run("the default API fails", func(t *testing.T) {
+		rt.cfg.APIs[0].APIKey = "wrong"
+		require.Equal(t, checkFailed, apiChecks(context.Background(), &rt.cfg, "local")[0].Status)
+	})
+
+	t.Run("settings that do not load", func(t *testing.T) {
+		checks := runDoctor(context.Background(), &runtime{cfgErr: configError{err: context.Canceled}})
+		require.Len(t, checks, 1)
+		require.Equal(t, checkFailed, checks[0].Status)
+	})
+}
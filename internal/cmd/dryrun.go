@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	imcp "github.com/dotcommander/yai/internal/mcp"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/spf13/pflag"
+)
+
+// dryRun checks the request with agent.Service.Validate instead of sending
+// it, and prints what it found: checks like yai doctor's, or with
+// --output-format jsonl the report as one JSON line, for editors.
+func (rt *runtime) dryRun(ctx context.Context, flags *pflag.FlagSet, store *conversationStore) error {
+	input, err := rt.readPipedInput()
+	if err != nil {
+		return err
+	}
+	mcpSvc := imcp.New(&rt.cfg)
+	defer mcpSvc.Close()
+	report := agent.New(&rt.cfg, store.Cache, mcpSvc).Validate(ctx, input)
+	report.Add("settings", "usable with the model", checkSettings(flags, &rt.cfg))
+	report.Add("budget", budgetDetail(&rt.cfg), checkBudget(&rt.cfg))
+	return printDryRun(os.Stdout, present.StdoutStyles(), report, rt.cfg.OutputFormat == outputFormatJSONL)
+}
+
+// checkSettings refuses the settings a request cannot be sent with: flags
+// the model does not take, and notify and spinner settings yai does not
+// know.
+func checkSettings(flags *pflag.FlagSet, cfg *config.Config) error {
+	if err := validateCapabilities(flags, cfg); err != nil {
+		return err
+	}
+	if err := checkNotify(cfg); err != nil {
+		return err
+	}
+	return checkSpinner(cfg)
+}
+
+// budgetDetail is what the budget check of --dry-run reports when it
+// passes.
+func budgetDetail(cfg *config.Config) string {
+	switch {
+	case cfg.MonthlyBudget <= 0:
+		return "no monthly-budget"
+	case cfg.IgnoreBudget:
+		return "ignored"
+	default:
+		return "under " + formatDollars(cfg.MonthlyBudget) + " this month"
+	}
+}
+
+// printDryRun writes report to w, and fails when any of its checks did.
+func printDryRun(w io.Writer, s present.Styles, report agent.Report, jsonl bool) error {
+	if jsonl {
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			return errs.Wrap(err, "Could not write the report.")
+		}
+	} else {
+		for _, check := range report.Checks {
+			status := checkOK
+			if !check.OK {
+				status = checkFailed
+			}
+			printCheck(w, s, doctorCheck{Name: check.Name, Status: status, Detail: check.Detail, Hint: check.Hint})
+		}
+	}
+	if !report.OK() {
+		return errs.Wrap(
+			errs.UserErrorf("Nothing was sent; fix the failed checks and try again."),
+			"The request would fail.",
+		)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/storage"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintDryRun(t *testing.T) {
+	report := agent.Report{
+		API:         "openai",
+		Model:       "gpt-4.1",
+		Messages:    1,
+		InputTokens: 12,
+		Checks: []agent.Check{
+			{Name: "model", OK: true, Detail: "gpt-4.1 on openai"},
+			{Name: "key", Detail: "OpenAI authentication failed", Hint: "OPENAI_API_KEY required"},
+		},
+	}
+
+	var out bytes.Buffer
+	err := printDryRun(&out, present.StdoutStyles(), report, false)
+	require.EqualError(t, err, "Nothing was sent; fix the failed checks and try again.")
+	require.Contains(t, out.String(), "gpt-4.1 on openai")
+	require.Contains(t, out.String(), "OPENAI_API_KEY required")
+
+	t.Run("jsonl", func(t *testing.T) {
+		var out bytes.Buffer
+		report.Checks = report.Checks[:1]
+		require.NoError(t, printDryRun(&out, present.StdoutStyles(), report, true))
+		require.Equal(t, 1, bytes.Count(out.Bytes(), []byte("\n")))
+
+		var got map[string]any
+		require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+		require.Equal(t, "gpt-4.1", got["model"])
+		require.InDelta(t, 12, got["input_tokens"], 0)
+		require.Len(t, got["checks"], 1)
+	})
+}
+
+func TestDryRunChecks(t *testing.T) {
+	cfg := fastestTestConfig(t)
+	var report agent.Report
+	report.Add("settings", "usable with the model", checkSettings(pflag.NewFlagSet("yai", pflag.ContinueOnError), cfg))
+	report.Add("budget", budgetDetail(cfg), checkBudget(cfg))
+	require.True(t, report.OK())
+	require.Equal(t, "no monthly-budget", report.Checks[1].Detail)
+
+	cfg.Notify = "pager"
+	cfg.MonthlyBudget = 1
+	require.NoError(t, storage.OpenLedger(cfg.CachePath).Append(storage.LedgerEntry{Time: time.Now(), API: "openai", Cost: 1.25}))
+	report = agent.Report{}
+	report.Add("settings", "usable with the model", checkSettings(pflag.NewFlagSet("yai", pflag.ContinueOnError), cfg))
+	report.Add("budget", budgetDetail(cfg), checkBudget(cfg))
+	require.False(t, report.Checks[0].OK)
+	require.Contains(t, report.Checks[0].Detail, `--notify must be`)
+	require.False(t, report.Checks[1].OK)
+	require.Contains(t, report.Checks[1].Detail, "reached the monthly budget")
+
+	report = agent.Report{}
+	report.Add("model", "", errs.Wrap(errs.UserErrorf("Set OPENAI_API_KEY."), "No key."))
+	require.Equal(t, agent.Check{Name: "model", Detail: "No key.", Hint: "Set OPENAI_API_KEY."}, report.Checks[0])
+}
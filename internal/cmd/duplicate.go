@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	timeago "github.com/caarlos0/timea.go"
+	"github.com/charmbracelet/huh"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/requestbuilder"
+	"github.com/dotcommander/yai/internal/storage"
+)
+
+const (
+	// maxDuplicateCandidates caps how many recent conversations are read when
+	// looking for a repeated question.
+	maxDuplicateCandidates = 50
+
+	// duplicateSimilarity is the minimum share of words two prompts must have
+	// in common, in the same order, to count as the same question.
+	duplicateSimilarity = 0.9
+)
+
+// duplicateAnswer is a saved single-turn conversation that answered a prompt
+// like the one about to be sent.
+type duplicateAnswer struct {
+	Conversation storage.Conversation
+	Answer       string
+}
+
+// maybeShowDuplicate offers the saved answer when the prompt was already asked
+// within duplicate-window. It returns true when the saved answer was shown and
+// no request should be sent.
+func (rt *runtime) maybeShowDuplicate(store *conversationStore) (bool, error) {
+	cfg := &rt.cfg
+	if cfg.DuplicateWindow <= 0 || cfg.CacheReadFromID != "" || strings.TrimSpace(cfg.Prefix) == "" {
+		return false, nil
+	}
+	// Only offer when the prompt is the whole question (nothing piped in) and
+	// someone is there to answer.
+	if !present.IsInputTTY() || !present.IsOutputTTY() {
+		return false, nil
+	}
+
+	dup := findDuplicate(cfg, store, time.Now())
+	if dup == nil {
+		return false, nil
+	}
+
+	var show bool
+	if err := huh.Run(
+		huh.NewConfirm().
+			Title(fmt.Sprintf("You asked this %s.", timeago.Of(dup.Conversation.UpdatedAt))).
+			Description("Show the saved answer instead of asking again?").
+			Affirmative("Show saved answer").
+			Negative("Ask again").
+			Value(&show),
+	); err != nil {
+		if errors.Is(err, huh.ErrUserAborted) {
+			return true, errs.Wrap(err, "User canceled.")
+		}
+		return false, errs.Wrap(err, "Prompt failed.")
+	}
+	if !show {
+		return false, nil
+	}
+
+	out := dup.Answer
+	if !cfg.Raw {
+		if formatted, err := present.RenderMarkdownForTTY(out, cfg.WordWrap); err == nil {
+			out = formatted
+		}
+	}
+	fmt.Print(out)
+	if !cfg.Quiet {
+		fmt.Fprintln( //nolint:gosec // G705: writing to stderr, not an HTTP response; XSS is not applicable here
+			os.Stderr,
+			"\nSaved answer from:",
+			present.StderrStyles().InlineCode.Render(dup.Conversation.ID[:storage.SHA1Short]),
+			present.StderrStyles().Comment.Render(dup.Conversation.Title),
+		)
+	}
+	return true, nil
+}
+
+// findDuplicate returns the most recent single-turn conversation updated
+// within duplicate-window that asked a prompt like cfg.Prefix with the same
+// model and system messages (format, role and reply language), or nil.
+func findDuplicate(cfg *config.Config, store *conversationStore, now time.Time) *duplicateAnswer {
+	words := promptWords(cfg.Prefix)
+	if len(words) == 0 {
+		return nil
+	}
+	// ResolveModel rewrites aliases in the config it is given.
+	resolved := *cfg
+	_, mod, err := requestbuilder.ResolveModel(&resolved)
+	if err != nil {
+		return nil
+	}
+	system, err := requestbuilder.SystemMessages(cfg, strings.TrimSpace(cfg.Prefix))
+	if err != nil {
+		return nil
+	}
+
+	checked := 0
+	for _, convo := range store.DB.ListRecent() {
+		if now.Sub(convo.UpdatedAt) > cfg.DuplicateWindow || checked >= maxDuplicateCandidates {
+			break
+		}
+		checked++
+		if !sameModel(cfg, convo, mod) {
+			continue
+		}
+
+		var messages []proto.Message
+		if err := store.Cache.Read(convo.ID, &messages); err != nil {
+			continue
+		}
+		question, answer, ok := singleTurn(messages)
+		if !ok || !sameSystemMessages(system, messages) || !similarPrompts(words, promptWords(question)) {
+			continue
+		}
+		return &duplicateAnswer{Conversation: convo, Answer: answer}
+	}
+	return nil
+}
+
+// sameModel reports whether convo was answered by mod, resolving the aliases
+// it was saved with against the current settings.
+func sameModel(cfg *config.Config, convo storage.Conversation, mod config.Model) bool {
+	if convo.Model == nil {
+		return false
+	}
+	saved := *cfg
+	saved.API, saved.Model = "", *convo.Model
+	if convo.API != nil {
+		saved.API = *convo.API
+	}
+	_, savedMod, err := requestbuilder.ResolveModel(&saved)
+	return err == nil && savedMod.API == mod.API && savedMod.Name == mod.Name
+}
+
+// sameSystemMessages reports whether the system messages saved in a
+// conversation are exactly the ones the current run would send.
+func sameSystemMessages(want, messages []proto.Message) bool {
+	var got []proto.Message
+	for _, msg := range messages {
+		if msg.Role == proto.RoleSystem {
+			got = append(got, msg)
+		}
+	}
+	return slices.EqualFunc(got, want, func(a, b proto.Message) bool {
+		return a.Content == b.Content
+	})
+}
+
+// singleTurn returns the only user prompt of a conversation and the final
+// answer to it. Conversations that went on for more than one turn are
+// skipped: their last answer depends on everything asked before.
+func singleTurn(messages []proto.Message) (question, answer string, ok bool) {
+	for _, msg := range messages {
+		switch msg.Role {
+		case proto.RoleUser:
+			if question != "" {
+				return "", "", false
+			}
+			question = msg.Content
+		case proto.RoleAssistant:
+			if msg.Content != "" {
+				answer = msg.Content
+			}
+		}
+	}
+	return question, answer, question != "" && answer != ""
+}
+
+// promptWords splits a prompt into its lowercase words, in order, ignoring
+// punctuation.
+func promptWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// similarPrompts reports whether two prompts are the same question: their
+// words, in order, differ by at most a tenth. Reordering words counts as a
+// change, so "is x bigger than y" and "is y bigger than x" do not match.
+func similarPrompts(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return false
+	}
+	return 1-float64(wordDistance(a, b))/float64(max(len(a), len(b))) >= duplicateSimilarity
+}
+
+// wordDistance is the edit (Levenshtein) distance between two word
+// sequences: how many words must be inserted, deleted or replaced to turn a
+// into b.
+func wordDistance(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindDuplicate(t *testing.T) {
+	save := func(t *testing.T, store *conversationStore, id string, messages []proto.Message) {
+		t.Helper()
+		require.NoError(t, store.Cache.Write(id, &messages))
+		require.NoError(t, store.DB.Save(id, "title "+id, "openai", "gpt-4o"))
+	}
+	newConfig := func(t *testing.T, prompt string) *config.Config {
+		t.Helper()
+		cfg := fastestTestConfig(t)
+		cfg.API = "openai"
+		cfg.Prefix = prompt
+		cfg.DuplicateWindow = time.Hour
+		cfg.Roles = map[string][]string{"brief": {"be brief"}}
+		cfg.Role = "brief"
+		return cfg
+	}
+	const (
+		idOne  = "1111111111111111111111111111111111111111"
+		idMany = "2222222222222222222222222222222222222222"
+	)
+
+	t.Run("finds a recent single-turn answer", func(t *testing.T) {
+		store, _ := newTestConversationStore(t)
+		save(t, store, idOne, []proto.Message{
+			{Role: proto.RoleSystem, Content: "be brief"},
+			{Role: proto.RoleUser, Content: "What is a monad?"},
+			{Role: proto.RoleAssistant, Content: "A monoid in the category of endofunctors."},
+		})
+
+		dup := findDuplicate(newConfig(t, "what is a   monad"), store, time.Now())
+		require.NotNil(t, dup)
+		require.Equal(t, idOne, dup.Conversation.ID)
+		require.Equal(t, "A monoid in the category of endofunctors.", dup.Answer)
+
+		require.Nil(t, findDuplicate(newConfig(t, "what is a functor"), store, time.Now()))
+	})
+
+	t.Run("matches model aliases", func(t *testing.T) {
+		store, _ := newTestConversationStore(t)
+		save(t, store, idOne, []proto.Message{
+			{Role: proto.RoleSystem, Content: "be brief"},
+			{Role: proto.RoleUser, Content: "what is a monad"},
+			{Role: proto.RoleAssistant, Content: "answer"},
+		})
+
+		cfg := newConfig(t, "what is a monad")
+		cfg.API, cfg.Model = "", "4o"
+		require.NotNil(t, findDuplicate(cfg, store, time.Now()))
+	})
+
+	t.Run("ignores answers from another model", func(t *testing.T) {
+		store, _ := newTestConversationStore(t)
+		save(t, store, idOne, []proto.Message{
+			{Role: proto.RoleSystem, Content: "be brief"},
+			{Role: proto.RoleUser, Content: "what is a monad"},
+			{Role: proto.RoleAssistant, Content: "answer"},
+		})
+
+		cfg := newConfig(t, "what is a monad")
+		cfg.API = "azure"
+		require.Nil(t, findDuplicate(cfg, store, time.Now()))
+
+		cfg = newConfig(t, "what is a monad")
+		cfg.API, cfg.Model = "anthropic", "claude-sonnet-4"
+		require.Nil(t, findDuplicate(cfg, store, time.Now()))
+	})
+
+	t.Run("ignores answers with other system messages", func(t *testing.T) {
+		store, _ := newTestConversationStore(t)
+		save(t, store, idOne, []proto.Message{
+			{Role: proto.RoleSystem, Content: "be brief"},
+			{Role: proto.RoleUser, Content: "what is a monad"},
+			{Role: proto.RoleAssistant, Content: "answer"},
+		})
+
+		cfg := newConfig(t, "what is a monad")
+		cfg.Roles["tldr"] = []string{"one line only"}
+		cfg.Role = "tldr"
+		require.Nil(t, findDuplicate(cfg, store, time.Now()), "other role")
+
+		cfg = newConfig(t, "what is a monad")
+		cfg.Role = ""
+		require.Nil(t, findDuplicate(cfg, store, time.Now()), "no role")
+
+		cfg = newConfig(t, "what is a monad")
+		cfg.Format, cfg.FormatAs = true, "json"
+		cfg.FormatText = config.FormatText{"json": "reply in json"}
+		require.Nil(t, findDuplicate(cfg, store, time.Now()), "format")
+	})
+
+	t.Run("ignores conversations outside the window", func(t *testing.T) {
+		store, _ := newTestConversationStore(t)
+		save(t, store, idOne, []proto.Message{
+			{Role: proto.RoleSystem, Content: "be brief"},
+			{Role: proto.RoleUser, Content: "what is a monad"},
+			{Role: proto.RoleAssistant, Content: "answer"},
+		})
+
+		require.Nil(t, findDuplicate(newConfig(t, "what is a monad"), store, time.Now().Add(2*time.Hour)))
+	})
+
+	t.Run("ignores multi-turn conversations", func(t *testing.T) {
+		store, _ := newTestConversationStore(t)
+		save(t, store, idMany, []proto.Message{
+			{Role: proto.RoleSystem, Content: "be brief"},
+			{Role: proto.RoleUser, Content: "what is a monad"},
+			{Role: proto.RoleAssistant, Content: "answer"},
+			{Role: proto.RoleUser, Content: "shorter please"},
+			{Role: proto.RoleAssistant, Content: "short answer"},
+		})
+
+		require.Nil(t, findDuplicate(newConfig(t, "what is a monad"), store, time.Now()))
+	})
+}
+
+func TestSimilarPrompts(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want bool
+	}{
+		{"What is a monad?", "what is a monad", true},
+		{"explain the difference between tcp and udp in detail please", "explain the difference between TCP and UDP in detail, thanks", true},
+		{"explain the difference between tcp and udp in detail please", "please explain the difference between TCP and UDP in detail", false},
+		{"is x bigger than y", "is y bigger than x", false},
+		{"what is a monad", "what is a functor", false},
+		{"", "", false},
+	} {
+		t.Run(tc.a+" / "+tc.b, func(t *testing.T) {
+			require.Equal(t, tc.want, similarPrompts(promptWords(tc.a), promptWords(tc.b)))
+		})
+	}
+}
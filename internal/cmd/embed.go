@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/spf13/cobra"
+)
+
+// embedBatchSize caps the number of inputs sent in a single embeddings
+// request; providers reject larger batches.
+const embedBatchSize = 100
+
+// embedOutput is the JSON document written for a single embedded input.
+type embedOutput struct {
+	Model     string    `json:"model"`
+	Embedding []float64 `json:"embedding"`
+}
+
+// embedLineOutput is one JSONL line written with --lines.
+type embedLineOutput struct {
+	Index     int       `json:"index"`
+	Text      string    `json:"text"`
+	Model     string    `json:"model"`
+	Embedding []float64 `json:"embedding"`
+}
+
+func newEmbedCmd(rt *runtime) *cobra.Command {
+	var lines bool
+	cmd := &cobra.Command{
+		Use:   "embed [text]",
+		Short: "Compute embeddings for text from arguments or stdin",
+		Long: "Compute an embedding vector for text from the arguments and stdin and print it as JSON.\n" +
+			"With --lines, every non-empty stdin line is embedded separately and printed as JSONL.",
+		Example: `  echo "hello world" | yai embed
+  cat docs.txt | yai embed --lines --api ollama > vectors.jsonl`,
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			inputs, err := readEmbedInputs(args, os.Stdin, !present.IsInputTTY(), lines)
+			if err != nil {
+				return err
+			}
+			return rt.runEmbed(ctx, os.Stdout, inputs, lines)
+		},
+	}
+
+	flags := cmd.Flags()
+	s := present.StdoutStyles().FlagDesc
+	flags.StringVarP(&rt.cfg.EmbedModel, "model", "m", rt.cfg.EmbedModel, s.Render(helpText["embed-model"]))
+	flags.StringVarP(&rt.cfg.API, "api", "a", rt.cfg.API, s.Render(helpText["api"]))
+	flags.BoolVarP(&lines, "lines", "l", false, s.Render(helpText["embed-lines"]))
+	flags.IntVar(&rt.cfg.MaxRetries, "max-retries", rt.cfg.MaxRetries, s.Render(helpText["max-retries"]))
+	flags.Var(newDurationFlag(rt.cfg.RequestTimeout, &rt.cfg.RequestTimeout), "request-timeout", s.Render(helpText["request-timeout"]))
+	flags.Var(newDurationFlag(rt.cfg.ConnectTimeout, &rt.cfg.ConnectTimeout), "connect-timeout", s.Render(helpText["connect-timeout"]))
+	flags.SortFlags = false
+	_ = cmd.RegisterFlagCompletionFunc("api", completeAPIs(&rt.cfg))
+
+	return cmd
+}
+
+func (rt *runtime) runEmbed(ctx context.Context, w io.Writer, inputs []string, lines bool) error {
+	svc := agent.New(&rt.cfg, nil, nil)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	for start := 0; start < len(inputs); start += embedBatchSize {
+		batch := inputs[start:min(start+embedBatchSize, len(inputs))]
+		res, err := svc.Embed(ctx, batch)
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+		for i, vec := range res.Vectors {
+			var out any = embedOutput{Model: res.Model, Embedding: vec}
+			if lines {
+				out = embedLineOutput{Index: start + i, Text: batch[i], Model: res.Model, Embedding: vec}
+			}
+			if err := enc.Encode(out); err != nil {
+				return errs.Wrap(err, "Could not write embeddings.")
+			}
+		}
+	}
+	return nil
+}
+
+// readEmbedInputs collects the text to embed. Arguments and stdin are joined
+// into a single input; with lines, each non-empty stdin line (and the
+// arguments, if any) becomes its own input.
+func readEmbedInputs(args []string, stdin io.Reader, hasStdin, lines bool) ([]string, error) {
+	var inputs []string
+	if arg := strings.TrimSpace(strings.Join(args, " ")); arg != "" {
+		inputs = append(inputs, arg)
+	}
+
+	if hasStdin {
+		if lines {
+			scanner := bufio.NewScanner(stdin)
+			scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+			for scanner.Scan() {
+				if line := strings.TrimSpace(scanner.Text()); line != "" {
+					inputs = append(inputs, line)
+				}
+			}
+			if err := scanner.Err(); err != nil {
+				return nil, errs.Wrap(err, "Could not read stdin.")
+			}
+		} else {
+			data, err := io.ReadAll(stdin)
+			if err != nil {
+				return nil, errs.Wrap(err, "Could not read stdin.")
+			}
+			text := strings.TrimSpace(string(data))
+			switch {
+			case text == "":
+			case len(inputs) == 0:
+				inputs = append(inputs, text)
+			default:
+				inputs[0] += "\n\n" + text
+			}
+		}
+	}
+
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("%w", errs.UserErrorf("Nothing to embed; pass text as arguments or pipe it on stdin."))
+	}
+	return inputs, nil
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadEmbedInputs(t *testing.T) {
+	t.Run("joins args and stdin", func(t *testing.T) {
+		inputs, err := readEmbedInputs([]string{"title:"}, strings.NewReader("body\n"), true, false)
+		require.NoError(t, err)
+		require.Equal(t, []string{"title:\n\nbody"}, inputs)
+	})
+
+	t.Run("splits lines", func(t *testing.T) {
+		inputs, err := readEmbedInputs(nil, strings.NewReader("one\n\n two \n"), true, true)
+		require.NoError(t, err)
+		require.Equal(t, []string{"one", "two"}, inputs)
+	})
+
+	t.Run("ignores stdin when it is a TTY", func(t *testing.T) {
+		inputs, err := readEmbedInputs([]string{"hi"}, strings.NewReader("ignored"), false, false)
+		require.NoError(t, err)
+		require.Equal(t, []string{"hi"}, inputs)
+	})
+
+	t.Run("requires input", func(t *testing.T) {
+		_, err := readEmbedInputs(nil, strings.NewReader("  \n"), true, false)
+		require.ErrorContains(t, err, "Nothing to embed")
+	})
+}
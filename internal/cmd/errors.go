@@ -19,6 +19,11 @@ func handleError(err error) {
 		_, _ = io.Copy(io.Discard, os.Stdin)
 	}
 
+	fmt.Fprint(os.Stderr, formatError(present.StderrStyles(), err))
+}
+
+// formatError renders err the way handleError prints it.
+func formatError(s present.Styles, err error) string {
 	format := "\n%s\n\n"
 
 	var ferr flagParseError
@@ -26,28 +31,26 @@ func handleError(err error) {
 		args := []any{
 			fmt.Sprintf(
 				"Check out %s %s",
-				present.StderrStyles().InlineCode.Render("yai -h"),
-				present.StderrStyles().Comment.Render("for help."),
+				s.InlineCode.Render("yai -h"),
+				s.Comment.Render("for help."),
 			),
 			fmt.Sprintf(
 				ferr.ReasonFormat(),
-				present.StderrStyles().InlineCode.Render(ferr.Flag()),
+				s.InlineCode.Render(ferr.Flag()),
 			),
 		}
-		fmt.Fprintf(os.Stderr, format+"%s\n\n", args...)
-		return
+		return fmt.Sprintf(format+"%s\n\n", args...)
 	}
 
 	var merr errs.Error
 	if errors.As(err, &merr) {
-		formatArgs := []any{present.StderrStyles().ErrPadding.Render(present.StderrStyles().ErrorHeader.String(), merr.Reason)}
+		formatArgs := []any{s.ErrPadding.Render(s.ErrorHeader.String(), merr.Reason)}
 		if merr.Err != huh.ErrUserAborted {
 			format += "%s\n\n"
-			formatArgs = append(formatArgs, present.StderrStyles().ErrPadding.Render(present.StderrStyles().ErrorDetails.Render(err.Error())))
+			formatArgs = append(formatArgs, s.ErrPadding.Render(s.ErrorDetails.Render(err.Error())))
 		}
-		fmt.Fprintf(os.Stderr, format, formatArgs...)
-		return
+		return fmt.Sprintf(format, formatArgs...)
 	}
 
-	fmt.Fprintf(os.Stderr, format, present.StderrStyles().ErrPadding.Render(present.StderrStyles().ErrorDetails.Render(err.Error())))
+	return fmt.Sprintf(format, s.ErrPadding.Render(s.ErrorDetails.Render(err.Error())))
 }
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/charmbracelet/huh"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present/presenttest"
+)
+
+func TestFormatError(t *testing.T) {
+	s := presenttest.Styles()
+
+	t.Run("flag", func(t *testing.T) {
+		err := newFlagParseError(errors.New("unknown flag: --wat"))
+		presenttest.RequireEqual(t, formatError(s, err))
+	})
+
+	t.Run("reason", func(t *testing.T) {
+		err := errs.Wrap(errors.New("dial tcp: connection refused"), "Could not reach the openai API.")
+		presenttest.RequireEqual(t, formatError(s, err))
+	})
+
+	t.Run("aborted", func(t *testing.T) {
+		err := errs.Wrap(huh.ErrUserAborted, "Cancelled.")
+		presenttest.RequireEqual(t, formatError(s, err))
+	})
+
+	t.Run("plain", func(t *testing.T) {
+		presenttest.RequireEqual(t, formatError(s, errors.New("boom")))
+	})
+}
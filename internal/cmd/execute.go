@@ -1,18 +1,29 @@
 package cmd
 
 import (
+	"context"
 	"os"
 
+	"github.com/dotcommander/yai/internal/agent"
 	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/present"
 )
 
 // Execute wires commands and runs Cobra.
 func Execute(build BuildInfo, cfg config.Config, cfgErr error) {
 	defer maybeWriteMemProfile()
+	defer newCrashHandler(build, cfg.StateDir()).recover()
+	restore := present.EnableVirtualTerminal()
+	defer restore()
 
+	if cfgErr != nil {
+		cfgErr = configError{cfgErr}
+	}
 	root := NewRootCmd(build, cfg, cfgErr)
-	if err := root.Execute(); err != nil {
+	ctx := agent.WithSpend(context.Background(), agent.Spend{Check: checkBudget, Record: recordAnswer})
+	if err := root.ExecuteContext(ctx); err != nil {
 		handleError(err)
-		os.Exit(1)
+		restore()
+		os.Exit(exitCode(err))
 	}
 }
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/huh"
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/requestbuilder"
+)
+
+// Exit codes, so scripts can tell failures apart. They are listed in
+// `yai --help`; keep exitCodes in sync.
+const (
+	exitError           = 1
+	exitConfig          = 2
+	exitAuth            = 3
+	exitRateLimit       = 4
+	exitContextOverflow = 5
+	exitProvider        = 6
+	exitCanceled        = 130
+)
+
+var exitCodes = []struct {
+	code int
+	desc string
+}{
+	{exitError, "other errors"},
+	{exitConfig, "invalid settings or flags"},
+	{exitAuth, "missing or rejected API key"},
+	{exitRateLimit, "rate limited by the provider, after retries"},
+	{exitContextOverflow, "prompt too long for the model's context window"},
+	{exitProvider, "other provider errors: error responses, dropped connections, timeouts"},
+	{exitCanceled, "canceled: Ctrl+C with --fail-on-interrupt, or a dismissed prompt"},
+}
+
+// configError marks an error in the settings or flags: loading the settings
+// file, a flag the model does not take, or a key or value config get and
+// config set refuse.
+type configError struct {
+	err error
+}
+
+func (e configError) Error() string { return e.err.Error() }
+func (e configError) Unwrap() error { return e.err }
+
+// exitCode returns the exit code yai ends with after err.
+func exitCode(err error) int {
+	var (
+		cfgErr      configError
+		flagErr     flagParseError
+		providerErr *fantasy.ProviderError
+	)
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, huh.ErrUserAborted):
+		return exitCanceled
+	case errors.As(err, &cfgErr), errors.As(err, &flagErr):
+		return exitConfig
+	case errors.Is(err, requestbuilder.ErrMissingKey):
+		return exitAuth
+	case agent.IsContextOverflow(err):
+		return exitContextOverflow
+	case errors.As(err, &providerErr) &&
+		(providerErr.StatusCode == http.StatusUnauthorized || providerErr.StatusCode == http.StatusForbidden):
+		return exitAuth
+	case errors.As(err, &providerErr) && providerErr.StatusCode == http.StatusTooManyRequests:
+		return exitRateLimit
+	case agent.IsProviderFailure(err):
+		return exitProvider
+	default:
+		return exitError
+	}
+}
+
+// exitCodesHelp renders the exit code table for `yai --help`.
+func exitCodesHelp(s present.Styles) string {
+	var sb strings.Builder
+	sb.WriteString("\nExit codes:\n")
+	for _, c := range exitCodes {
+		fmt.Fprintf(&sb, "  %s %s\n", s.Flag.Render(fmt.Sprintf("%-4d", c.code)), s.FlagDesc.Render(c.desc))
+	}
+	return sb.String()
+}
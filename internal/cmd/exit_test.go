@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"syscall"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/huh"
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present/presenttest"
+	"github.com/dotcommander/yai/internal/requestbuilder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitCode(t *testing.T) {
+	providerErr := func(status int, message string) error {
+		return errs.Wrap(&fantasy.ProviderError{StatusCode: status, Message: message}, "API error.")
+	}
+	for name, tc := range map[string]struct {
+		err  error
+		code int
+	}{
+		"other":            {errors.New("boom"), exitError},
+		"settings":         {configError{errs.Wrap(errors.New("yaml: bad"), "Could not parse settings file.")}, exitConfig},
+		"flag":             {newFlagParseError(errors.New("unknown flag: --wat")), exitConfig},
+		"missing key":      {fmt.Errorf("prepare: %w", requestbuilder.ErrMissingKey), exitAuth},
+		"unauthorized":     {providerErr(http.StatusUnauthorized, "bad key"), exitAuth},
+		"rate limit":       {providerErr(http.StatusTooManyRequests, "slow down"), exitRateLimit},
+		"context overflow": {providerErr(http.StatusBadRequest, "context_length_exceeded"), exitContextOverflow},
+		"bad request":      {providerErr(http.StatusBadRequest, "invalid"), exitProvider},
+		"server error":     {providerErr(http.StatusInternalServerError, "oops"), exitProvider},
+		"connection":       {fmt.Errorf("stream: %w", syscall.ECONNRESET), exitProvider},
+		"stalled stream":   {&agent.StreamTimeoutError{FirstToken: true}, exitProvider},
+		"interrupted":      {errs.Wrap(context.Canceled, "Interrupted."), exitCanceled},
+		"aborted form":     {errs.Wrap(huh.ErrUserAborted, "Cancelled."), exitCanceled},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.code, exitCode(tc.err))
+		})
+	}
+}
+
+func TestExitCodesHelp(t *testing.T) {
+	presenttest.RequireEqual(t, exitCodesHelp(presenttest.Styles()))
+}
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/requestbuilder"
+	"github.com/dotcommander/yai/internal/storage"
+)
+
+const (
+	// fastestWindow is how far back --fastest looks in the usage ledger.
+	fastestWindow = 7 * 24 * time.Hour
+	// fastestSamples is how many recent answers of a route --fastest
+	// compares.
+	fastestSamples = 20
+	// failingFor is how long a route whose last request failed is left
+	// out.
+	failingFor = 30 * time.Minute
+)
+
+// route is a configured API serving a model.
+type route struct {
+	API   string
+	Model string
+}
+
+// routeStat is what the usage ledger says about a route.
+type routeStat struct {
+	route
+	// FirstToken is the median time to the first token of the recent
+	// answers, or 0 when there are none.
+	FirstToken time.Duration
+	// Failing is set when the last request failed, less than failingFor
+	// ago.
+	Failing bool
+	// Requests and Failures count the requests and failed requests in the
+	// entries, and LastFailure is the latest of them.
+	Requests    int
+	Failures    int
+	LastFailure storage.LedgerEntry
+}
+
+// modelRoutes lists the APIs that have the model, by name or alias, in
+// settings order.
+func modelRoutes(cfg *config.Config, model string) []route {
+	var routes []route
+	for _, api := range cfg.APIs {
+		for name, mod := range api.Models {
+			if name == model || slices.Contains(mod.Aliases, model) {
+				routes = append(routes, route{API: api.Name, Model: name})
+				break
+			}
+		}
+	}
+	return routes
+}
+
+// routeStats summarizes the ledger entries of each route, oldest first, as
+// of now.
+func routeStats(routes []route, entries []storage.LedgerEntry, now time.Time) []routeStat {
+	stats := make([]routeStat, 0, len(routes))
+	for _, r := range routes {
+		stat := routeStat{route: r}
+		var samples []int64
+		seen := false
+		for i := len(entries) - 1; i >= 0; i-- {
+			e := entries[i]
+			if e.API != r.API || e.Model != r.Model {
+				continue
+			}
+			stat.Requests++
+			if !seen {
+				seen = true
+				stat.Failing = e.Failed && now.Sub(e.Time) < failingFor
+			}
+			if e.Failed {
+				if stat.Failures == 0 {
+					stat.LastFailure = e
+				}
+				stat.Failures++
+				continue
+			}
+			if e.FirstTokenMS > 0 && len(samples) < fastestSamples {
+				samples = append(samples, e.FirstTokenMS)
+			}
+		}
+		if len(samples) > 0 {
+			slices.Sort(samples)
+			stat.FirstToken = time.Duration(samples[len(samples)/2]) * time.Millisecond
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+// fastestRoute picks the healthy route with the quickest median first
+// token. Routes with no recorded answers are only picked when no healthy
+// route has any, and then in settings order.
+func fastestRoute(stats []routeStat) (routeStat, bool) {
+	healthy := slices.DeleteFunc(slices.Clone(stats), func(s routeStat) bool { return s.Failing })
+	if len(healthy) == 0 {
+		return routeStat{}, false
+	}
+	slices.SortStableFunc(healthy, func(a, b routeStat) int {
+		switch {
+		case a.FirstToken == 0 && b.FirstToken != 0:
+			return 1
+		case b.FirstToken == 0 && a.FirstToken != 0:
+			return -1
+		}
+		return cmp.Compare(a.FirstToken, b.FirstToken)
+	})
+	return healthy[0], true
+}
+
+// useFastestRoute switches cfg to the quickest healthy API serving its model,
+// as --fastest asks. Without another route, or any route known to work, the
+// API is left as it is.
+func useFastestRoute(cfg *config.Config) {
+	routes := modelRoutes(cfg, cfg.Model)
+	if len(routes) < 2 {
+		return
+	}
+	entries, err := storage.OpenLedger(cfg.StateDir()).Since(time.Now().Add(-fastestWindow))
+	if err != nil {
+		if !cfg.Quiet {
+			fmt.Fprintln(os.Stderr, present.StderrStyles().Comment.Render("Warning: --fastest could not read the usage ledger: "+err.Error()))
+		}
+		return
+	}
+	best, ok := fastestRoute(routeStats(routes, entries, time.Now()))
+	if !ok {
+		return
+	}
+	cfg.API = best.API
+	cfg.Model = best.Model
+	if !cfg.Quiet && best.FirstToken > 0 {
+		fmt.Fprintln(os.Stderr, present.StderrStyles().Comment.Render(fmt.Sprintf(
+			"Using %s/%s, the fastest route (first token in %s)", best.API, best.Model, best.FirstToken,
+		)))
+	}
+}
+
+// recordAnswer adds the last answer of msgs, the one the model just wrote,
+// to the usage ledger, with the tokens of the turn that led to it. The
+// ledger guides --fastest and tracks spend, so failing to write it is not
+// worth stopping for.
+func recordAnswer(cfg *config.Config, msgs []proto.Message) {
+	if cfg.NoCache || len(msgs) == 0 {
+		return
+	}
+	last := msgs[len(msgs)-1]
+	if last.Role != proto.RoleAssistant || last.Latency == 0 || last.Partial {
+		return
+	}
+	entry := storage.LedgerEntry{
+		Time:         last.Time,
+		FirstTokenMS: last.FirstToken.Milliseconds(),
+		LatencyMS:    last.Latency.Milliseconds(),
+	}
+	entry.InputTokens, entry.OutputTokens, entry.Estimated = turnTokens(msgs)
+	appendLedger(cfg, entry)
+}
+
+// recordFailure adds a request the provider did not answer to the usage
+// ledger, when err says the provider is unhealthy: it timed out, failed,
+// or could not be reached. Requests it rejected are not held against it.
+func recordFailure(cfg *config.Config, err error) {
+	kind := agent.FailureKind(err)
+	if cfg.NoCache || kind == "" {
+		return
+	}
+	appendLedger(cfg, storage.LedgerEntry{Time: time.Now(), Failed: true, Failure: kind})
+}
+
+func appendLedger(cfg *config.Config, entry storage.LedgerEntry) {
+	resolved := *cfg
+	_, mod, err := requestbuilder.ResolveModel(&resolved)
+	if err != nil {
+		return
+	}
+	entry.API, entry.Model = mod.API, mod.Name
+	entry.Cost = requestCost(mod, entry.InputTokens, entry.OutputTokens)
+	_ = storage.OpenLedger(cfg.StateDir()).Append(entry)
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func fastestTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.CachePath = t.TempDir()
+	cfg.Quiet = true
+	cfg.Model = "4o"
+	cfg.APIs = config.APIs{
+		{Name: "openai", Models: map[string]config.Model{"gpt-4o": {Aliases: []string{"4o"}}}},
+		{Name: "azure", Models: map[string]config.Model{"gpt-4o": {Aliases: []string{"4o"}}}},
+		{Name: "anthropic", Models: map[string]config.Model{"claude-sonnet-4": {}}},
+	}
+	return cfg
+}
+
+func TestFastestRoute(t *testing.T) {
+	now := time.Now()
+	openai := route{API: "openai", Model: "gpt-4o"}
+	azure := route{API: "azure", Model: "gpt-4o"}
+	entry := func(r route, ago time.Duration, firstTokenMS int64, failed bool) storage.LedgerEntry {
+		return storage.LedgerEntry{Time: now.Add(-ago), API: r.API, Model: r.Model, FirstTokenMS: firstTokenMS, Failed: failed}
+	}
+
+	t.Run("median first token", func(t *testing.T) {
+		stats := routeStats([]route{openai, azure}, []storage.LedgerEntry{
+			entry(openai, 3*time.Hour, 200, false),
+			entry(openai, 2*time.Hour, 900, false),
+			entry(openai, time.Hour, 800, false),
+			entry(azure, time.Hour, 500, false),
+		}, now)
+		require.Equal(t, 800*time.Millisecond, stats[0].FirstToken)
+		best, ok := fastestRoute(stats)
+		require.True(t, ok)
+		require.Equal(t, azure, best.route)
+	})
+
+	t.Run("recent failure", func(t *testing.T) {
+		stats := routeStats([]route{openai, azure}, []storage.LedgerEntry{
+			entry(openai, time.Hour, 900, false),
+			entry(azure, time.Hour, 300, false),
+			entry(azure, time.Minute, 0, true),
+		}, now)
+		require.True(t, stats[1].Failing)
+		best, ok := fastestRoute(stats)
+		require.True(t, ok)
+		require.Equal(t, openai, best.route)
+	})
+
+	t.Run("old failure", func(t *testing.T) {
+		stats := routeStats([]route{azure}, []storage.LedgerEntry{
+			entry(azure, time.Hour, 300, false),
+			entry(azure, failingFor+time.Minute, 0, true),
+		}, now)
+		require.False(t, stats[0].Failing)
+	})
+
+	t.Run("untried routes come last", func(t *testing.T) {
+		stats := routeStats([]route{openai, azure}, []storage.LedgerEntry{
+			entry(azure, time.Hour, 2000, false),
+		}, now)
+		best, ok := fastestRoute(stats)
+		require.True(t, ok)
+		require.Equal(t, azure, best.route)
+	})
+
+	t.Run("all failing", func(t *testing.T) {
+		_, ok := fastestRoute([]routeStat{{route: openai, Failing: true}})
+		require.False(t, ok)
+	})
+}
+
+func TestUseFastestRoute(t *testing.T) {
+	cfg := fastestTestConfig(t)
+	require.Equal(t, []route{{API: "openai", Model: "gpt-4o"}, {API: "azure", Model: "gpt-4o"}}, modelRoutes(cfg, "4o"))
+
+	// Answers recorded through the default route and through azure.
+	recordAnswer(cfg, []proto.Message{{Role: proto.RoleAssistant, Content: "a", Time: time.Now(), Latency: 3 * time.Second, FirstToken: time.Second}})
+	cfg.API = "azure"
+	recordAnswer(cfg, []proto.Message{{Role: proto.RoleAssistant, Content: "b", Time: time.Now(), Latency: time.Second, FirstToken: 200 * time.Millisecond}})
+	recordAnswer(cfg, []proto.Message{{Role: proto.RoleAssistant, Content: "cut", Time: time.Now(), Latency: time.Second, Partial: true}})
+
+	entries, err := storage.OpenLedger(cfg.CachePath).Since(time.Time{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2, "partial answers are not recorded")
+	require.Equal(t, "openai", entries[0].API)
+	require.Equal(t, "gpt-4o", entries[0].Model, "aliases are recorded as the model name")
+
+	cfg.API = ""
+	cfg.Model = "4o"
+	useFastestRoute(cfg)
+	require.Equal(t, "azure", cfg.API)
+	require.Equal(t, "gpt-4o", cfg.Model)
+
+	recordFailure(cfg, &fantasy.ProviderError{StatusCode: http.StatusBadRequest})
+	cfg.API = ""
+	useFastestRoute(cfg)
+	require.Equal(t, "azure", cfg.API, "a rejected request says nothing about the route's health")
+
+	recordFailure(cfg, &fantasy.ProviderError{StatusCode: http.StatusServiceUnavailable})
+	cfg.API = ""
+	useFastestRoute(cfg)
+	require.Equal(t, "openai", cfg.API, "a failing route is skipped")
+
+	cfg.Model = "claude-sonnet-4"
+	cfg.API = ""
+	useFastestRoute(cfg)
+	require.Empty(t, cfg.API, "a model with one route is left alone")
+}
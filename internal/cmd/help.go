@@ -7,6 +7,11 @@ import (
 
 var helpText = map[string]string{
 	"api":                   "OpenAI compatible REST API (openai, localai, anthropic, ...)",
+	"yes":                   "Send without asking, even above confirm-tokens or confirm-cost, or to a model in confirm-models",
+	"ignore-budget":         "Send even when this month's spend reached monthly-budget",
+	"notify":                "Signal when a long request finishes: bell, desktop, or both (the default)",
+	"notify-after":          "Only --notify for requests that took at least this long; negative for every one",
+	"fastest":               "Use the API with the quickest recent answers among those serving the model",
 	"apis":                  "Aliases and endpoints for OpenAI compatible REST API",
 	"http-proxy":            "HTTP proxy to use for API requests",
 	"model":                 "Default model (gpt-3.5-turbo, gpt-4, ggml-gpt4all-j...)",
@@ -15,6 +20,7 @@ var helpText = map[string]string{
 	"format":                "Ask for the response to be formatted as markdown unless otherwise set",
 	"format-text":           "Text to append when using the -f flag",
 	"format-as":             "Format to use when formatting is enabled",
+	"reply-language":        "Language to answer in: auto for the language of the prompt, or a name such as Spanish",
 	"role":                  "System role to use",
 	"roles":                 "List of predefined system messages that can be used as roles",
 	"list-roles":            "List the roles defined in your configuration file",
@@ -26,6 +32,9 @@ var helpText = map[string]string{
 	"version":               "Show version and exit",
 	"max-retries":           "Maximum number of times to retry API calls",
 	"request-timeout":       "Maximum wall time for a single provider request/stream (0 uses default; negative disables)",
+	"connect-timeout":       "Maximum time to connect to the provider (0 uses default; negative disables)",
+	"first-token-timeout":   "Maximum wait for the first chunk of a response (0 uses default; negative disables)",
+	"idle-timeout":          "Maximum gap between chunks of a response (0 uses default; negative disables)",
 	"no-limit":              "Turn off the client-side limit on the size of the input into the model",
 	"word-wrap":             "Wrap formatted output at specific width (default is 80)",
 	"max-tokens":            "Maximum number of tokens in response",
@@ -34,28 +43,76 @@ var helpText = map[string]string{
 	"stop":                  "Stop sequences (currently not forwarded by Fantasy v0.8.1 in yai bridge)",
 	"topp":                  "TopP, an alternative to temperature that narrows response, from 0.0 to 1.0, -1.0 to disable",
 	"topk":                  "TopK, only sample from the top K options for each subsequent token, -1 to disable",
+	"presence-penalty":      "Penalize tokens that already appeared, from -2.0 to 2.0, 0 to disable",
+	"frequency-penalty":     "Penalize tokens by how often they appeared, from -2.0 to 2.0, 0 to disable",
+	"seed":                  "Seed for sampling, for repeatable answers where the provider supports it, 0 to disable",
+	"thinking-budget":       "Tokens Claude and Gemini models may think for before answering; 0 uses the model's, -1 sends none",
 	"fanciness":             "Your desired level of fanciness",
-	"status-text":           "Text to show while generating",
+	"status-text":           "Text to show while generating; {{.model}} and {{.elapsed}} are filled in",
+	"spinner":               "Animation to show while generating: cycling, dots, pulse, braille, or none",
 	"settings":              "Open settings in your $EDITOR",
 	"dirs":                  "Print the directories in which yai stores its data",
 	"reset-settings":        "Backup your old settings file and reset everything to the defaults",
 	"continue":              "Continue from the last response or a given save title",
 	"continue-last":         "Continue from the last response",
+	"context-strategy":      "What to do with a conversation too long for the model: drop-oldest, summarize, or error",
+	"fail-on-interrupt":     "Exit with an error when the response is stopped with Ctrl+C, after writing out what was received",
+	"prompt-file":           "Read the prompt from a file, filling in {{.name}} template variables; prompt arguments are added after it",
+	"var":                   "Set a template variable for --prompt-file, as name=value (repeatable)",
+	"snippet":               "Put a saved snippet from the snippets directory before the (first) prompt (repeatable)",
+	"resume":                "Finish the last response that was cut off, or the one given with --continue",
+	"dry-run":               "Check the request without sending it: the model, its API key, MCP tools, and the estimated tokens and cost",
+	"output-format":         "Write the response as text, or as jsonl: one JSON event per line (chunk, tool_call, tool_result, warning, retry, logprobs, usage, error, done)",
+	"logprobs":              "With --output-format jsonl, report the log probability of each token and of its N likeliest alternatives",
+	"postprocess":           "Rewrite the answer with a filter before it is printed and saved: strip-preamble, extract-code, trim, or a command; repeat to chain",
 	"no-cache":              "Disables caching of the prompt/response",
+	"no-redact":             "Send the prompt and piped input without replacing secrets that match redact-builtin or redact-patterns",
 	"title":                 "Saves the current conversation with the given title",
 	"list":                  "Lists saved conversations",
 	"delete":                "Deletes one or more saved conversations with the given titles or IDs",
 	"delete-older-than":     "Deletes all saved conversations older than the specified duration; valid values are " + xstrings.EnglishJoin(duration.ValidUnits(), true),
 	"show":                  "Show a saved conversation with the given title or ID",
-	"theme":                 "Theme to use in the forms; valid choices are charm, catppuccin, dracula, and base16",
+	"config":                "Settings file to use instead of yai.yml in the config directory (or set YAI_CONFIG_PATH)",
+	"workspace":             "Workspace to use, with its own conversations and settings (or set YAI_WORKSPACE)",
+	"color":                 "When to color the output: auto (on terminals, unless NO_COLOR is set), always, or never",
+	"glamour-style":         "Markdown style of answers: dark, light, dracula, tokyo-night, pink, ascii, notty, or a JSON style file",
+	"theme":                 "Theme of the output and forms: charm, dracula, catppuccin, nord, base16, plain, or a file in themes/ next to the settings",
 	"show-last":             "Show the last saved conversation",
 	"editor":                "Edit the prompt in your $EDITOR; only taken into account if no other args and if STDIN is a TTY",
 	"mcp-servers":           "MCP Servers configurations",
 	"mcp-disable":           "Disable specific MCP servers",
+	"mcp-only":              "Only start these MCP servers, instead of the role's mcp-servers or all of them",
+	"mcp-root":              "Directory MCP servers may work in, instead of the current one (repeatable)",
 	"mcp-list":              "List all available MCP servers",
 	"mcp-list-tools":        "List all available tools from enabled MCP servers",
 	"mcp-timeout":           "Timeout for MCP server calls, defaults to 15 seconds",
 	"mcp-allow-non-tty":     "Allow MCP tool exposure/execution when STDIN is not a TTY (disabled by default)",
 	"mcp-no-inherit-env":    "Do not inherit the full process environment for stdio MCP servers",
+	"mcp-read-only":         "Only offer MCP tools that read, leaving out those that write, delete, or run things",
+	"mcp-sampling":          "Let MCP servers ask the model for completions: off, ask, or allow",
 	"patch":                 "Output a unified diff instead of prose (implies --raw, uses built-in diff role)",
+	"transcribe":            "Transcribe an audio file (or - for stdin) and use the text as the prompt",
+	"batch-input-file":      "JSONL file with one {\"id\": ..., \"prompt\": ...} object per line",
+	"batch-output-file":     "Append JSONL results to this file and skip prompts already answered in it (default is stdout)",
+	"batch-concurrency":     "Number of prompts to run at the same time",
+	"batch-rate":            "Maximum number of requests started per second (0 disables rate limiting)",
+	"embed-model":           "Embedding model to use (defaults to embed-model in settings, then the API's default)",
+	"embed-lines":           "Embed each non-empty input line separately and output JSONL",
+	"knowledge":             "File or directory to use as knowledge; can be repeated",
+	"knowledge-history":     "Also use saved conversations as knowledge",
+	"knowledge-sources":     "Number of knowledge chunks to include with the prompt",
+	"translate-to":          "Language to translate into, such as de or Japanese",
+	"summary-length":        "Length of the summary: short, bullets, or tldr",
+	"summary-chunked":       "Summarize input too long for the model in parts, then combine them",
+	"chunk-size":            "Answer piped input longer than this many bytes in parts; 0 cuts it off at max-input-chars",
+	"chunk-strategy":        "How parts are answered: map-reduce answers each and combines them, refine improves one answer part by part",
+	"bench-model":           "Model to benchmark; can be repeated (defaults to the default model)",
+	"bench-prompt":          "File with the prompt to benchmark with",
+	"bench-runs":            "Number of runs per model",
+	"bench-json":            "Output the benchmark as JSON",
+	"sink":                  "Also write the response as FORMAT (raw, markdown, or jsonl) to TARGET: a file, unix:SOCKET, tcp:HOST:PORT, or - for stdout; can be repeated",
+	"serve-addr":            "Address to listen on",
+	"serve-mcp-tools":       "Offer MCP tools to the model; anything that can reach the address can then run them",
+	"daemon-socket":         "Unix socket to listen on (defaults to daemon-socket in settings)",
+	"no-daemon":             "Run the request in this process even when yai daemon is running",
 }
@@ -3,7 +3,10 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/atotto/clipboard"
@@ -11,7 +14,9 @@ import (
 	"github.com/charmbracelet/huh"
 	"github.com/dotcommander/yai/internal/errs"
 	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/proto"
 	"github.com/dotcommander/yai/internal/storage"
+	"github.com/dotcommander/yai/internal/storage/remote"
 	"github.com/muesli/termenv"
 
 	"github.com/spf13/cobra"
@@ -26,13 +31,24 @@ func newHistoryCmd(rt *runtime) *cobra.Command {
 	historyCmd.AddCommand(newHistoryListCmd(rt))
 	historyCmd.AddCommand(newHistoryShowCmd(rt))
 	historyCmd.AddCommand(newHistoryDeleteCmd(rt))
+	historyCmd.AddCommand(newHistoryRestoreCmd(rt))
+	historyCmd.AddCommand(newHistoryTrashCmd(rt))
+	historyCmd.AddCommand(newHistoryPinCmd(rt, true))
+	historyCmd.AddCommand(newHistoryPinCmd(rt, false))
+	historyCmd.AddCommand(newHistoryNoteCmd(rt))
 	historyCmd.AddCommand(newHistoryPruneCmd(rt))
+	historyCmd.AddCommand(newHistoryCompactCmd(rt))
+	historyCmd.AddCommand(newHistoryAppendCmd(rt))
+	historyCmd.AddCommand(newHistorySyncCmd(rt))
+	historyCmd.AddCommand(newHistoryStatsCmd(rt))
+	historyCmd.AddCommand(newHistoryShareCmd(rt))
 
 	return historyCmd
 }
 
 func newHistoryListCmd(rt *runtime) *cobra.Command {
-	return &cobra.Command{
+	var verbose bool
+	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List saved conversations",
 		Args:  cobra.NoArgs,
@@ -40,13 +56,15 @@ func newHistoryListCmd(rt *runtime) *cobra.Command {
 			if rt.cfgErr != nil {
 				return rt.cfgErr
 			}
-			return listConversations(&rt.cfg, rt.cfg.Raw)
+			return listConversations(&rt.cfg, rt.cfg.Raw, verbose)
 		},
 	}
+	listCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Also print each conversation's model and note")
+	return listCmd
 }
 
 func newHistoryShowCmd(rt *runtime) *cobra.Command {
-	var last bool
+	var last, asJSON, verbose bool
 	showCmd := &cobra.Command{
 		Use:   "show [id-or-title]",
 		Short: "Show a saved conversation",
@@ -59,6 +77,8 @@ func newHistoryShowCmd(rt *runtime) *cobra.Command {
 			cfg := rt.cfg
 			cfg.Show = ""
 			cfg.ShowLast = last
+			cfg.ShowJSON = asJSON
+			cfg.ShowVerbose = verbose
 			if len(args) == 1 {
 				cfg.Show = args[0]
 			}
@@ -66,23 +86,142 @@ func newHistoryShowCmd(rt *runtime) *cobra.Command {
 		},
 	}
 	showCmd.Flags().BoolVarP(&last, "last", "S", false, "Show the last saved conversation")
+	showCmd.Flags().BoolVar(&asJSON, "json", false, "Print the conversation and its metadata as JSON")
+	showCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Also print when each message was written and how long each answer took")
 	return showCmd
 }
 
 func newHistoryDeleteCmd(rt *runtime) *cobra.Command {
+	var match, api, model string
+	deleteCmd := &cobra.Command{
+		Use:   "delete [id-or-title...]",
+		Short: "Move saved conversations to the trash",
+		Example: `  yai history delete my-title
+  yai history delete --match '^pipeline-' --model gpt-5-mini`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			filter := conversationFilter{api: api, model: model}
+			if match != "" {
+				re, err := regexp.Compile(match)
+				if err != nil {
+					return errs.Wrap(errs.UserErrorf("invalid --match: %s", err), "Could not delete conversations.")
+				}
+				filter.match = re
+			}
+			switch {
+			case filter.empty() && len(args) == 0:
+				return errs.Wrap(errs.UserErrorf("pass conversation IDs or titles, or select them with --match, --api, or --model"), "Could not delete conversations.")
+			case !filter.empty() && len(args) > 0:
+				return errs.Wrap(errs.UserErrorf("pass either conversation IDs or titles, or --match, --api, and --model, not both"), "Could not delete conversations.")
+			case len(args) > 0:
+				return deleteConversations(&rt.cfg, args)
+			default:
+				return deleteMatchingConversations(&rt.cfg, filter)
+			}
+		},
+	}
+	deleteCmd.Flags().StringVar(&match, "match", "", "Delete conversations whose title matches this regular expression")
+	deleteCmd.Flags().StringVar(&api, "api", "", "Delete conversations saved with this API")
+	deleteCmd.Flags().StringVar(&model, "model", "", "Delete conversations saved with this model")
+	return deleteCmd
+}
+
+func newHistoryRestoreCmd(rt *runtime) *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore <id-or-title> [more...]",
+		Short: "Restore deleted conversations from the trash",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			return restoreConversations(&rt.cfg, args)
+		},
+	}
+}
+
+func newHistoryTrashCmd(rt *runtime) *cobra.Command {
+	trashCmd := &cobra.Command{
+		Use:   "trash",
+		Short: "List deleted conversations that can be restored",
+		Long: "List conversations in the trash. Deleted conversations stay there for trash-retention\n" +
+			"(30 days by default) and can be brought back with `yai history restore`.",
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			return listTrash(&rt.cfg)
+		},
+	}
+
+	var olderThan time.Duration
+	emptyCmd := &cobra.Command{
+		Use:   "empty",
+		Short: "Permanently delete the conversations in the trash",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			return emptyTrash(&rt.cfg, olderThan)
+		},
+	}
+	emptyCmd.Flags().Var(newDurationFlag(olderThan, &olderThan), "older-than", "Only delete conversations trashed longer ago than this; e.g. 24h, 7d")
+	trashCmd.AddCommand(emptyCmd)
+	return trashCmd
+}
+
+func newHistoryPinCmd(rt *runtime, pin bool) *cobra.Command {
+	use, short := "pin", "Pin conversations so they are listed first and never pruned"
+	if !pin {
+		use, short = "unpin", "Unpin conversations"
+	}
 	return &cobra.Command{
-		Use:   "delete <id-or-title> [more...]",
-		Short: "Delete saved conversations",
+		Use:   use + " <id-or-title> [more...]",
+		Short: short,
 		Args:  cobra.MinimumNArgs(1),
 		RunE: func(_ *cobra.Command, args []string) error {
 			if rt.cfgErr != nil {
 				return rt.cfgErr
 			}
-			return deleteConversations(&rt.cfg, args)
+			return pinConversations(&rt.cfg, args, pin)
 		},
 	}
 }
 
+func newHistoryNoteCmd(rt *runtime) *cobra.Command {
+	var clearNote bool
+	noteCmd := &cobra.Command{
+		Use:   "note <id-or-title> [note...]",
+		Short: "Attach a note to a conversation, or print its note",
+		Example: `  yai history note my-title "context for this thread"
+  yai history note my-title
+  yai history note my-title --clear`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			var note *string
+			switch {
+			case clearNote && len(args) > 1:
+				return errs.Wrap(errs.UserErrorf("pass either a note or --clear, not both"), "Could not save the note.")
+			case clearNote:
+				note = new(string)
+			case len(args) > 1:
+				text := strings.TrimSpace(strings.Join(args[1:], " "))
+				note = &text
+			}
+			return noteConversation(&rt.cfg, args[0], note)
+		},
+	}
+	noteCmd.Flags().BoolVar(&clearNote, "clear", false, "Remove the note")
+	return noteCmd
+}
+
 func newHistoryPruneCmd(rt *runtime) *cobra.Command {
 	var olderThan time.Duration
 	pruneCmd := &cobra.Command{
@@ -104,10 +243,111 @@ func newHistoryPruneCmd(rt *runtime) *cobra.Command {
 	return pruneCmd
 }
 
+func newHistoryCompactCmd(rt *runtime) *cobra.Command {
+	return &cobra.Command{
+		Use:   "compact",
+		Short: "Compact the conversation index and remove leftover files",
+		Long: "Rewrite the conversation index with one record per conversation, remove lock files of\n" +
+			"conversations that no longer exist, and remove empty directories. yai also compacts the\n" +
+			"index on its own as it grows; use this to reclaim space right away, e.g. after a large delete.",
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			return compactConversationStore(&rt.cfg)
+		},
+	}
+}
+
+func newHistoryAppendCmd(rt *runtime) *cobra.Command {
+	role := proto.RoleAssistant
+	appendCmd := &cobra.Command{
+		Use:   "append <id-or-title> [text | -]",
+		Short: "Append a message to a saved conversation without calling a model",
+		Example: `  pbpaste | yai history append my-title --role assistant -
+  yai history append my-title --role user "what about edge cases?"`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			content, err := appendContent(args[1:], os.Stdin)
+			if err != nil {
+				return err
+			}
+			return appendToConversation(&rt.cfg, args[0], role, content)
+		},
+	}
+	appendCmd.Flags().StringVar(&role, "role", role, "Role of the appended message (user or assistant)")
+	_ = appendCmd.RegisterFlagCompletionFunc("role", cobra.FixedCompletions(
+		[]string{proto.RoleUser, proto.RoleAssistant}, cobra.ShellCompDirectiveNoFileComp,
+	))
+	return appendCmd
+}
+
+func newHistoryStatsCmd(rt *runtime) *cobra.Command {
+	var asJSON bool
+	statsCmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Summarize saved conversations: counts, models, tokens, and storage",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			return historyStatistics(&rt.cfg, asJSON)
+		},
+	}
+	statsCmd.Flags().BoolVar(&asJSON, "json", false, "Print the statistics as JSON")
+	return statsCmd
+}
+
+func newHistorySyncCmd(rt *runtime) *cobra.Command {
+	var dryRun bool
+	syncCmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Sync saved conversations with a git repository, S3, or an rsync target",
+		Long: "Pull the shared copy of your conversations, merge it with the local ones, and push the result back.\n" +
+			"When a conversation changed on both sides, the most recently updated copy wins.\n" +
+			"Set sync.backend and sync.remote in settings, or pass --backend and --remote.",
+		Example: `  yai history sync --backend git --remote git@github.com:me/yai-history.git
+  yai history sync --dry-run`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			return syncHistory(cmd.Context(), &rt.cfg, dryRun)
+		},
+	}
+	syncCmd.Flags().StringVar(&rt.cfg.Sync.Backend, "backend", rt.cfg.Sync.Backend, "Sync backend: "+strings.Join(remote.Backends, ", "))
+	syncCmd.Flags().StringVar(&rt.cfg.Sync.Remote, "remote", rt.cfg.Sync.Remote, "Git URL, s3://bucket/prefix, or rsync target to sync with")
+	syncCmd.Flags().BoolVar(&dryRun, "dry-run", false, "List the changes without applying them")
+	_ = syncCmd.RegisterFlagCompletionFunc("backend", cobra.FixedCompletions(remote.Backends, cobra.ShellCompDirectiveNoFileComp))
+	return syncCmd
+}
+
+// appendContent returns the message text for `history append`: the joined
+// arguments, or stdin when there are none or the only argument is "-".
+func appendContent(args []string, stdin io.Reader) (string, error) {
+	if len(args) > 0 && !(len(args) == 1 && args[0] == "-") {
+		return strings.Join(args, " "), nil
+	}
+	if len(args) == 0 && present.IsInputTTY() {
+		return "", errs.Wrap(errs.UserErrorf("pass the message as an argument or pipe it with -"), "Nothing to append.")
+	}
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return "", errs.Wrap(err, "Could not read stdin.")
+	}
+	return strings.TrimRight(string(data), "\n"), nil
+}
+
 func makeOptions(conversations []storage.Conversation) []huh.Option[string] {
 	opts := make([]huh.Option[string], 0, len(conversations))
 	for _, c := range conversations {
-		timea := present.StdoutStyles().Timeago.Render(timeago.Of(c.UpdatedAt))
+		timea := present.StdoutStyles().Timeago.Render(timeago.Of(c.UpdatedAt) + pinnedSuffix(c))
 		left := present.StdoutStyles().SHA1.Render(c.ID[:storage.SHA1Short])
 		right := present.StdoutStyles().ConversationList.Render(c.Title, timea)
 		if c.Model != nil {
@@ -116,6 +356,7 @@ func makeOptions(conversations []storage.Conversation) []huh.Option[string] {
 		if c.API != nil {
 			right += present.StdoutStyles().Comment.Render(" (" + *c.API + ")")
 		}
+		right += present.StdoutStyles().Comment.Render(noteSummary(c))
 		opts = append(opts, huh.NewOption(left+" "+right, c.ID))
 	}
 	return opts
@@ -153,20 +394,74 @@ func selectFromList(conversations []storage.Conversation) {
 	}
 }
 
+// copySelectedConversationID copies selected to the system clipboard, or,
+// when there is none to reach, such as over SSH, asks the terminal to with
+// OSC 52. The sequence is not sent otherwise, as older Windows consoles
+// print it.
 func copySelectedConversationID(selected string) error {
 	err := clipboard.WriteAll(selected)
-	termenv.Copy(selected)
+	if err != nil {
+		termenv.Copy(selected)
+	}
 	return err
 }
 
-func printList(conversations []storage.Conversation) {
+// printList prints one conversation per line. verbose adds the model and
+// API, and the note on the lines below.
+func printList(conversations []storage.Conversation, verbose bool) {
 	for _, conversation := range conversations {
 		_, _ = fmt.Fprintf(
 			os.Stdout,
-			"%s\t%s\t%s\n",
+			"%s\t%s\t%s",
 			present.StdoutStyles().SHA1.Render(conversation.ID[:storage.SHA1Short]),
 			conversation.Title,
-			present.StdoutStyles().Timeago.Render(timeago.Of(conversation.UpdatedAt)),
+			present.StdoutStyles().Timeago.Render(timeago.Of(conversation.UpdatedAt)+pinnedSuffix(conversation)),
 		)
+		if !verbose {
+			fmt.Println()
+			continue
+		}
+		fmt.Printf("\t%s\n", present.StdoutStyles().Comment.Render(modelLabel(conversation)))
+		if conversation.Note != "" {
+			for line := range strings.SplitSeq(conversation.Note, "\n") {
+				fmt.Printf("\t%s\n", line)
+			}
+		}
+	}
+}
+
+// modelLabel returns "model (api)", or whichever of the two is known, with
+// the model version and request ID the provider reported, if any.
+func modelLabel(c storage.Conversation) string {
+	var model, api string
+	if c.Model != nil {
+		model = *c.Model
+	}
+	if c.ModelVersion != "" && c.ModelVersion != model {
+		model += " [" + c.ModelVersion + "]"
+	}
+	if c.API != nil && *c.API != "" {
+		api = "(" + *c.API + ")"
+	}
+	label := strings.TrimSpace(model + " " + api)
+	if c.RequestID != "" {
+		label += " · request " + c.RequestID
+	}
+	return label
+}
+
+// noteSummary returns the first line of the conversation's note for the
+// picker, or "" when it has none.
+func noteSummary(c storage.Conversation) string {
+	if c.Note == "" {
+		return ""
+	}
+	return " · " + firstLine(c.Note)
+}
+
+func pinnedSuffix(c storage.Conversation) string {
+	if c.Pinned {
+		return " (pinned)"
 	}
+	return ""
 }
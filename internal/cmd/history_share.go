@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/spf13/cobra"
+)
+
+// gistsURL is the GitHub API endpoint gists are created at.
+var gistsURL = "https://api.github.com/gists"
+
+type shareOptions struct {
+	gist   bool
+	public bool
+	output string
+}
+
+func newHistoryShareCmd(rt *runtime) *cobra.Command {
+	var opts shareOptions
+	shareCmd := &cobra.Command{
+		Use:   "share [id-or-title]",
+		Short: "Export a saved conversation as an HTML page or a GitHub gist",
+		Long: "Export a saved conversation, the last one by default, as a self-contained HTML page in the\n" +
+			"working directory, or with --gist as a secret GitHub gist, and print its path or URL.\n" +
+			"Matches of redact-patterns are replaced with [REDACTED] first. Creating a gist needs a\n" +
+			"GitHub token in GITHUB_TOKEN or GH_TOKEN.",
+		Example: `  yai history share my-title
+  yai history share --gist my-title
+  yai history share -o review.html`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			if opts.public && !opts.gist {
+				return errs.Wrap(errs.UserErrorf("--public applies to --gist"), "Could not share the conversation.")
+			}
+			if opts.output != "" && opts.gist {
+				return errs.Wrap(errs.UserErrorf("pass either --gist or --output, not both"), "Could not share the conversation.")
+			}
+			cfg := rt.cfg
+			cfg.Show = ""
+			if len(args) == 1 {
+				cfg.Show = args[0]
+			}
+			return shareConversation(cmd.Context(), &cfg, opts)
+		},
+	}
+	shareCmd.Flags().BoolVar(&opts.gist, "gist", false, "Create a GitHub gist instead of an HTML file")
+	shareCmd.Flags().BoolVar(&opts.public, "public", false, "Make the gist public instead of secret")
+	shareCmd.Flags().StringVarP(&opts.output, "output", "o", "", "Write the HTML page to this file")
+	return shareCmd
+}
+
+func shareConversation(ctx context.Context, cfg *config.Config, opts shareOptions) error {
+	redactor, err := newRedactor(cfg)
+	if err != nil {
+		return err
+	}
+
+	store, err := openConversationStore(cfg.CachePath)
+	if err != nil {
+		return errs.Wrap(err, "Could not open conversation store.")
+	}
+	defer store.Close() //nolint:errcheck
+
+	found, err := findReadConversation(cfg, store.DB, cfg.Show)
+	if err != nil {
+		return errs.Wrap(err, "There was an error loading the conversation.")
+	}
+	var messages []proto.Message
+	if err := store.Cache.Read(found.ID, &messages); err != nil {
+		return errs.Wrap(err, "There was an error loading the conversation.")
+	}
+
+	title, inTitle := redactor.Redact(found.Title)
+	transcript, inTranscript := redactor.Redact(proto.Conversation(messages).String())
+	if redacted := inTitle.Add(inTranscript); redacted.Total() > 0 && !cfg.Quiet {
+		fmt.Fprintln(os.Stderr, present.StderrStyles().Comment.Render("Redacted "+redacted.String()+"."))
+	}
+
+	if opts.gist {
+		client, err := config.NewHTTPClient(cfg.HTTPProxy, cfg.ConnectTimeout, 0)
+		if err != nil {
+			return errs.Wrap(err, "Could not configure HTTP transport.")
+		}
+		name := exportFileName(title, found.UpdatedAt) + ".md"
+		url, err := createGist(ctx, client, name, title, "# "+title+"\n\n"+transcript, opts.public)
+		if err != nil {
+			return err
+		}
+		fmt.Println(url)
+		return nil
+	}
+
+	var page bytes.Buffer
+	if err := present.RenderHTML(&page, title, transcript); err != nil {
+		return errs.Wrap(err, "Could not render the conversation.")
+	}
+	name := opts.output
+	if name == "" {
+		name, err = createExportFile(exportFileName(title, found.UpdatedAt), "html", page.Bytes())
+	} else {
+		err = os.WriteFile(name, page.Bytes(), 0o644) //nolint:gosec
+	}
+	if err != nil {
+		return errs.Wrap(err, "Could not write the conversation.")
+	}
+	fmt.Println(name)
+	return nil
+}
+
+// createGist creates a gist with content as its one file, and returns the
+// gist's URL.
+func createGist(ctx context.Context, client *http.Client, name, description, content string, public bool) (string, error) {
+	token := cmp.Or(os.Getenv("GITHUB_TOKEN"), os.Getenv("GH_TOKEN"))
+	if token == "" {
+		return "", errs.Wrap(
+			errs.UserErrorf("set GITHUB_TOKEN or GH_TOKEN to a GitHub token that may create gists"),
+			"Could not create the gist.",
+		)
+	}
+	body, err := json.Marshal(map[string]any{
+		"description": description,
+		"public":      public,
+		"files":       map[string]any{name: map[string]string{"content": content}},
+	})
+	if err != nil {
+		return "", errs.Wrap(err, "Could not create the gist.")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, gistsURL, bytes.NewReader(body))
+	if err != nil {
+		return "", errs.Wrap(err, "Could not create the gist.")
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", errs.Wrap(err, "Could not create the gist.")
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusCreated {
+		return "", errs.Wrap(
+			fmt.Errorf("github: %s: %s", resp.Status, strings.TrimSpace(string(data))),
+			"Could not create the gist.",
+		)
+	}
+	var gist struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.Unmarshal(data, &gist); err != nil || gist.HTMLURL == "" {
+		return "", errs.Wrap(fmt.Errorf("github: unexpected response: %s", data), "Could not create the gist.")
+	}
+	return gist.HTMLURL, nil
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/proto"
+)
+
+// busiestDays is how many days `history stats` lists.
+const busiestDays = 5
+
+// historyStats is the JSON representation printed by `history stats --json`.
+type historyStats struct {
+	Conversations   int         `json:"conversations"`
+	Messages        int         `json:"messages"`
+	EstimatedTokens int64       `json:"estimated_tokens"`
+	StorageBytes    int64       `json:"storage_bytes"`
+	Models          []modelStat `json:"models"`
+	BusiestDays     []dayStat   `json:"busiest_days"`
+	// Unreadable counts conversations listed in the index whose messages
+	// could not be read.
+	Unreadable int `json:"unreadable,omitempty"`
+}
+
+type modelStat struct {
+	API             string `json:"api"`
+	Model           string `json:"model"`
+	Conversations   int    `json:"conversations"`
+	Messages        int    `json:"messages"`
+	EstimatedTokens int64  `json:"estimated_tokens"`
+}
+
+// dayStat counts the conversations last updated on a day.
+type dayStat struct {
+	Date          string `json:"date"`
+	Conversations int    `json:"conversations"`
+}
+
+func historyStatistics(cfg *config.Config, asJSON bool) error {
+	store, err := openConversationStore(cfg.CachePath)
+	if err != nil {
+		return errs.Wrap(err, "Could not open conversation store.")
+	}
+	defer store.Close() //nolint:errcheck
+
+	stats, err := collectHistoryStats(store, filepath.Join(cfg.CachePath, "conversations"))
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(stats); err != nil {
+			return errs.Wrap(err, "Could not write statistics.")
+		}
+		return nil
+	}
+	printHistoryStats(os.Stdout, present.StdoutStyles(), stats)
+	return nil
+}
+
+// collectHistoryStats reads every saved conversation. Dates are bucketed in
+// the local time zone.
+func collectHistoryStats(store *conversationStore, dir string) (historyStats, error) {
+	stats := historyStats{Models: []modelStat{}, BusiestDays: []dayStat{}}
+	models := map[[2]string]*modelStat{}
+	days := map[string]int{}
+
+	for _, convo := range store.DB.List() {
+		stats.Conversations++
+		days[convo.UpdatedAt.Local().Format(time.DateOnly)]++
+
+		key := [2]string{deref(convo.API), deref(convo.Model)}
+		model, ok := models[key]
+		if !ok {
+			model = &modelStat{API: key[0], Model: key[1]}
+			models[key] = model
+		}
+		model.Conversations++
+
+		var messages []proto.Message
+		if err := store.Cache.Read(convo.ID, &messages); err != nil {
+			stats.Unreadable++
+			continue
+		}
+		tokens := proto.EstimateTokens(messages)
+		stats.Messages += len(messages)
+		stats.EstimatedTokens += tokens
+		model.Messages += len(messages)
+		model.EstimatedTokens += tokens
+	}
+
+	for _, model := range models {
+		stats.Models = append(stats.Models, *model)
+	}
+	slices.SortFunc(stats.Models, func(a, b modelStat) int {
+		return cmp.Or(
+			cmp.Compare(b.Messages, a.Messages),
+			cmp.Compare(a.API, b.API),
+			cmp.Compare(a.Model, b.Model),
+		)
+	})
+
+	for date, n := range days {
+		stats.BusiestDays = append(stats.BusiestDays, dayStat{Date: date, Conversations: n})
+	}
+	slices.SortFunc(stats.BusiestDays, func(a, b dayStat) int {
+		return cmp.Or(cmp.Compare(b.Conversations, a.Conversations), cmp.Compare(b.Date, a.Date))
+	})
+	stats.BusiestDays = stats.BusiestDays[:min(len(stats.BusiestDays), busiestDays)]
+
+	size, err := dirSize(dir)
+	if err != nil {
+		return stats, errs.Wrap(err, "Could not measure conversation storage.")
+	}
+	stats.StorageBytes = size
+	return stats, nil
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil //nolint:nilerr // the file went away while walking
+		}
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("walk %s: %w", dir, err)
+	}
+	return size, nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func printHistoryStats(w io.Writer, s present.Styles, stats historyStats) {
+	row := func(label, value string) {
+		fmt.Fprintf(w, "%s %s\n", s.Comment.Render(fmt.Sprintf("%-16s", label)), value)
+	}
+	row("Conversations", fmt.Sprint(stats.Conversations))
+	row("Messages", fmt.Sprint(stats.Messages))
+	row("Tokens (est.)", "~"+formatCount(stats.EstimatedTokens))
+	row("Storage", formatBytes(stats.StorageBytes))
+	if stats.Unreadable > 0 {
+		row("Unreadable", fmt.Sprint(stats.Unreadable))
+	}
+
+	if len(stats.Models) > 0 {
+		fmt.Fprintln(w, "\n"+s.AppName.Render("By model"))
+		for _, m := range stats.Models {
+			name := m.Model
+			if m.API != "" {
+				name = m.API + "/" + m.Model
+			}
+			if name == "" {
+				name = "unknown"
+			}
+			fmt.Fprintf(w, "  %-32s %5d conversations %6d messages  ~%s tokens\n",
+				name, m.Conversations, m.Messages, formatCount(m.EstimatedTokens))
+		}
+	}
+
+	if len(stats.BusiestDays) > 0 {
+		fmt.Fprintln(w, "\n"+s.AppName.Render("Busiest days"))
+		for _, d := range stats.BusiestDays {
+			fmt.Fprintf(w, "  %s %5d conversations\n", d.Date, d.Conversations)
+		}
+	}
+}
+
+// formatCount abbreviates large counts: 950, 12.3k, 4.1M.
+func formatCount(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	default:
+		return fmt.Sprint(n)
+	}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dotcommander/yai/internal/present/presenttest"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryStats(t *testing.T) {
+	store, tmpDir := newTestConversationStore(t)
+	day := time.Date(2026, 5, 1, 12, 0, 0, 0, time.Local)
+	save := func(id, api, model string, when time.Time, messages ...proto.Message) {
+		t.Helper()
+		require.NoError(t, store.Cache.Write(id, &messages))
+		require.NoError(t, store.DB.Put(storage.Conversation{ID: id, Title: id, UpdatedAt: when, API: &api, Model: &model}))
+	}
+	save("aaaa", "openai", "gpt-5", day,
+		proto.Message{Role: proto.RoleUser, Content: "12345678"},
+		proto.Message{Role: proto.RoleAssistant, Content: "1234"},
+	)
+	save("bbbb", "openai", "gpt-5", day.Add(time.Hour),
+		proto.Message{Role: proto.RoleUser, Content: "1234"},
+	)
+	save("cccc", "anthropic", "claude", day.AddDate(0, 0, 1),
+		proto.Message{Role: proto.RoleUser, Content: "1"},
+	)
+	// Listed in the index, but the messages are gone.
+	require.NoError(t, store.DB.Save("dddd", "lost", "openai", "gpt-5"))
+
+	stats, err := collectHistoryStats(store, filepath.Join(tmpDir, "conversations"))
+	require.NoError(t, err)
+	require.Equal(t, 4, stats.Conversations)
+	require.Equal(t, 4, stats.Messages)
+	require.Equal(t, int64(5), stats.EstimatedTokens)
+	require.Equal(t, 1, stats.Unreadable)
+	require.Positive(t, stats.StorageBytes)
+	require.Equal(t, []modelStat{
+		{API: "openai", Model: "gpt-5", Conversations: 3, Messages: 3, EstimatedTokens: 4},
+		{API: "anthropic", Model: "claude", Conversations: 1, Messages: 1, EstimatedTokens: 1},
+	}, stats.Models)
+	require.Equal(t, dayStat{Date: "2026-05-01", Conversations: 2}, stats.BusiestDays[0])
+
+	t.Run("text", func(t *testing.T) {
+		stats.StorageBytes = 123456
+		stats.BusiestDays = stats.BusiestDays[:1]
+		var buf bytes.Buffer
+		printHistoryStats(&buf, presenttest.Styles(), stats)
+		presenttest.RequireEqual(t, buf.String())
+	})
+}
+
+func TestFormatBytes(t *testing.T) {
+	require.Equal(t, "512 B", formatBytes(512))
+	require.Equal(t, "1.5 KiB", formatBytes(1536))
+	require.Equal(t, "2.0 MiB", formatBytes(2<<20))
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/storage"
+	"github.com/dotcommander/yai/internal/storage/remote"
+)
+
+// syncHistory merges the local conversations with the configured remote.
+func syncHistory(ctx context.Context, cfg *config.Config, dryRun bool) error {
+	if cfg.Sync.Backend == "" || cfg.Sync.Remote == "" {
+		return errs.Wrap(
+			errs.UserErrorf("set sync.backend and sync.remote in settings, or pass --backend and --remote"),
+			"Nowhere to sync to.",
+		)
+	}
+	backend, err := remote.New(cfg.Sync.Backend, cfg.Sync.Remote)
+	if err != nil {
+		return errs.Wrap(errs.UserErrorf("%s (want one of %s)", err, strings.Join(remote.Backends, ", ")), "Invalid sync settings.")
+	}
+
+	store, err := openConversationStore(cfg.CachePath)
+	if err != nil {
+		return errs.Wrap(err, "Could not open conversation store.")
+	}
+	defer store.Close() //nolint:errcheck
+
+	syncer := &remote.Syncer{
+		Backend: backend,
+		Dir:     filepath.Join(cfg.CachePath, "sync", cfg.Sync.Backend),
+		Local:   remote.Store{DB: store.DB, Cache: store.Cache},
+		DryRun:  dryRun,
+	}
+	summary, err := syncer.Run(ctx)
+	if errors.Is(err, remote.ErrBusy) {
+		return errs.Wrap(errs.UserErrorf("another yai history sync is running"), "Could not sync conversations.")
+	}
+	if err != nil {
+		return errs.Wrap(err, "Could not sync conversations.")
+	}
+
+	if dryRun {
+		printSyncPlan(store.DB, summary.Changes)
+		return nil
+	}
+	if !cfg.Quiet {
+		for _, id := range summary.Skipped {
+			fmt.Fprintln(os.Stderr, present.StderrStyles().Comment.Render(
+				"Skipped "+id[:min(len(id), storage.SHA1Short)]+": in use by another yai process; it syncs next time.",
+			))
+		}
+	}
+	present.PrintConfirmation("SYNCED", fmt.Sprintf(
+		"%d pulled, %d pushed, %d deleted here, %d deleted on the remote",
+		summary.Count(remote.Pull),
+		summary.Count(remote.Push),
+		summary.Count(remote.DeleteLocal),
+		summary.Count(remote.DeleteRemote),
+	))
+	return nil
+}
+
+func printSyncPlan(db *storage.DB, changes []remote.Change) {
+	if len(changes) == 0 {
+		fmt.Println("Already in sync.")
+		return
+	}
+	for _, change := range changes {
+		title := ""
+		if convo, err := db.Find(change.ID); err == nil {
+			title = convo.Title
+		}
+		fmt.Printf(
+			"%-13s\t%s\t%s\n",
+			change.Action,
+			present.StdoutStyles().SHA1.Render(change.ID[:min(len(change.ID), storage.SHA1Short)]),
+			title,
+		)
+	}
+}
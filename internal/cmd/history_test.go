@@ -1,9 +1,14 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/dotcommander/yai/internal/config"
 	"github.com/dotcommander/yai/internal/proto"
@@ -37,7 +42,7 @@ func TestListConversations(t *testing.T) {
 			Settings: config.Settings{CachePath: tmpDir},
 		}
 
-		err := listConversations(cfg, true)
+		err := listConversations(cfg, true, false)
 		require.NoError(t, err)
 	})
 
@@ -49,7 +54,7 @@ func TestListConversations(t *testing.T) {
 			Settings: config.Settings{CachePath: tmpDir},
 		}
 
-		err := listConversations(cfg, true)
+		err := listConversations(cfg, true, false)
 		require.NoError(t, err)
 	})
 }
@@ -102,6 +107,198 @@ func TestDeleteConversations(t *testing.T) {
 	})
 }
 
+func TestDeleteMatchingConversations(t *testing.T) {
+	store, tmpDir := newTestConversationStore(t)
+	for _, c := range []struct{ id, title, api, model string }{
+		{"aaaa1111", "pipeline-1", "openai", "gpt-5-mini"},
+		{"bbbb2222", "pipeline-2", "openai", "gpt-5"},
+		{"cccc3333", "pipeline-3", "anthropic", "gpt-5-mini"},
+		{"dddd4444", "notes on pipelines", "openai", "gpt-5-mini"},
+	} {
+		require.NoError(t, store.DB.Save(c.id, c.title, c.api, c.model))
+		messages := []proto.Message{}
+		require.NoError(t, store.Cache.Write(c.id, &messages))
+	}
+	require.NoError(t, store.Close())
+
+	cfg := &config.Config{Settings: config.Settings{CachePath: tmpDir, Quiet: true}}
+	filter := conversationFilter{match: regexp.MustCompile(`^pipeline-`), api: "openai", model: "gpt-5-mini"}
+	require.NoError(t, deleteMatchingConversations(cfg, filter))
+
+	db, err := storage.Open(filepath.Join(tmpDir, "conversations"))
+	require.NoError(t, err)
+	defer db.Close() //nolint:errcheck
+	var left []string
+	for _, c := range db.List() {
+		left = append(left, c.Title)
+	}
+	require.ElementsMatch(t, []string{"pipeline-2", "pipeline-3", "notes on pipelines"}, left)
+}
+
+func TestTrash(t *testing.T) {
+	store, tmpDir := newTestConversationStore(t)
+	messages := []proto.Message{{Role: proto.RoleUser, Content: "hi"}}
+	for _, id := range []string{"abc123def456", "def456abc123"} {
+		require.NoError(t, store.DB.Save(id, "convo "+id[:3], "openai", "test-model"))
+		require.NoError(t, store.Cache.Write(id, &messages))
+	}
+	require.NoError(t, store.Close())
+	cfg := &config.Config{Settings: config.Settings{CachePath: tmpDir, Quiet: true, TrashRetention: time.Hour}}
+
+	open := func() *conversationStore {
+		t.Helper()
+		store, err := openConversationStore(tmpDir)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = store.Close() })
+		return store
+	}
+
+	require.NoError(t, deleteConversations(cfg, []string{"abc123def456", "def456abc123"}))
+	require.Len(t, open().DB.Trash(), 2)
+
+	require.NoError(t, restoreConversations(cfg, []string{"convo abc"}))
+	store = open()
+	convo, err := store.DB.Find("abc123")
+	require.NoError(t, err)
+	var restored []proto.Message
+	require.NoError(t, store.Cache.Read(convo.ID, &restored))
+	require.Equal(t, messages, restored)
+	require.Error(t, restoreConversations(cfg, []string{"abc123"}))
+
+	require.NoError(t, emptyTrash(cfg, time.Hour))
+	require.Len(t, open().DB.Trash(), 1)
+	require.NoError(t, emptyTrash(cfg, 0))
+	require.Empty(t, open().DB.Trash())
+	_, err = os.Stat(filepath.Join(tmpDir, "trash", "def456abc123.json"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	t.Run("negative retention deletes permanently", func(t *testing.T) {
+		cfg := &config.Config{Settings: config.Settings{CachePath: tmpDir, Quiet: true, TrashRetention: -1}}
+		require.NoError(t, deleteConversations(cfg, []string{"abc123def456"}))
+		store := open()
+		require.Empty(t, store.DB.List())
+		require.Empty(t, store.DB.Trash())
+	})
+
+	t.Run("a payload that cannot be trashed keeps the conversation", func(t *testing.T) {
+		store, tmpDir := newTestConversationStore(t)
+		require.NoError(t, store.DB.Save("abc123def456", "keeper", "openai", "test-model"))
+		require.NoError(t, store.Cache.Write("abc123def456", &messages))
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "trash"), nil, 0o600))
+
+		require.ErrorContains(t, trashConversation(cfg, store, "abc123def456"), "move conversation payload to trash")
+		require.Len(t, store.DB.List(), 1)
+		require.Empty(t, store.DB.Trash())
+		var kept []proto.Message
+		require.NoError(t, store.Cache.Read("abc123def456", &kept))
+		require.NoError(t, store.Close())
+	})
+
+	t.Run("a conversation without a payload can be restored", func(t *testing.T) {
+		store, _ := newTestConversationStore(t)
+		require.NoError(t, store.DB.Save("abc123def456", "no payload", "openai", "test-model"))
+
+		require.NoError(t, trashConversation(cfg, store, "abc123def456"))
+		require.Len(t, store.DB.Trash(), 1)
+		require.NoError(t, restoreConversation(store, "abc123def456"))
+		require.Len(t, store.DB.List(), 1)
+		require.Empty(t, store.DB.Trash())
+		require.NoError(t, store.Close())
+	})
+}
+
+func TestPinConversations(t *testing.T) {
+	store, tmpDir := newTestConversationStore(t)
+	require.NoError(t, store.DB.Save("abc123def456", "keeper", "openai", "test-model"))
+	require.NoError(t, store.Close())
+	cfg := &config.Config{Settings: config.Settings{CachePath: tmpDir, Quiet: true}}
+
+	pinned := func() bool {
+		t.Helper()
+		db, err := storage.Open(filepath.Join(tmpDir, "conversations"))
+		require.NoError(t, err)
+		defer db.Close() //nolint:errcheck
+		convo, err := db.Find("keeper")
+		require.NoError(t, err)
+		return convo.Pinned
+	}
+
+	require.NoError(t, pinConversations(cfg, []string{"keeper"}, true))
+	require.True(t, pinned())
+	require.NoError(t, pinConversations(cfg, []string{"abc123"}, false))
+	require.False(t, pinned())
+	require.Error(t, pinConversations(cfg, []string{"missing"}, true))
+}
+
+func TestNoteConversation(t *testing.T) {
+	store, tmpDir := newTestConversationStore(t)
+	require.NoError(t, store.DB.Save("abc123def456", "keeper", "openai", "test-model"))
+	require.NoError(t, store.Close())
+	cfg := &config.Config{Settings: config.Settings{CachePath: tmpDir, Quiet: true}}
+
+	note := func() string {
+		t.Helper()
+		db, err := storage.Open(filepath.Join(tmpDir, "conversations"))
+		require.NoError(t, err)
+		defer db.Close() //nolint:errcheck
+		convo, err := db.Find("keeper")
+		require.NoError(t, err)
+		return convo.Note
+	}
+
+	text := "context for this thread"
+	require.NoError(t, noteConversation(cfg, "keeper", &text))
+	require.Equal(t, text, note())
+	require.NoError(t, noteConversation(cfg, "abc123", nil))
+	require.Equal(t, text, note())
+	require.NoError(t, noteConversation(cfg, "abc123", new(string)))
+	require.Empty(t, note())
+	require.Error(t, noteConversation(cfg, "missing", &text))
+}
+
+func TestCompactConversationStore(t *testing.T) {
+	store, tmpDir := newTestConversationStore(t)
+	for i := range 20 {
+		require.NoError(t, store.DB.Save("abc123def456", fmt.Sprintf("title %d", i), "openai", "test-model"))
+	}
+	messages := []proto.Message{{Role: proto.RoleUser, Content: "hi"}}
+	require.NoError(t, store.Cache.Write("abc123def456", &messages))
+	require.NoError(t, store.Cache.Write("ffff00001111", &messages))
+	require.NoError(t, store.Cache.Delete("ffff00001111"))
+	before, err := store.DB.IndexSize()
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	cfg := &config.Config{Settings: config.Settings{CachePath: tmpDir, Quiet: true}}
+	require.NoError(t, compactConversationStore(cfg))
+
+	store, err = openConversationStore(tmpDir)
+	require.NoError(t, err)
+	defer store.Close() //nolint:errcheck
+	after, err := store.DB.IndexSize()
+	require.NoError(t, err)
+	require.Less(t, after, before)
+
+	convo, err := store.DB.Find("abc123")
+	require.NoError(t, err)
+	require.Equal(t, "title 19", convo.Title)
+	_, err = os.Stat(filepath.Join(tmpDir, "conversations", "ff"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestConversationFilter(t *testing.T) {
+	api, model := "openai", "gpt-5"
+	convo := storage.Conversation{Title: "pipeline-7", API: &api, Model: &model}
+
+	require.True(t, conversationFilter{}.empty())
+	require.True(t, conversationFilter{}.matches(convo))
+	require.True(t, conversationFilter{match: regexp.MustCompile(`\d$`)}.matches(convo))
+	require.False(t, conversationFilter{match: regexp.MustCompile(`^notes`)}.matches(convo))
+	require.True(t, conversationFilter{api: "openai", model: "gpt-5"}.matches(convo))
+	require.False(t, conversationFilter{model: "gpt-5-mini"}.matches(convo))
+	require.False(t, conversationFilter{api: "openai"}.matches(storage.Conversation{Title: "no metadata"}))
+}
+
 func TestDeleteConversationByID(t *testing.T) {
 	t.Run("deletes conversation from both index and cache", func(t *testing.T) {
 		store, _ := newTestConversationStore(t)
@@ -120,3 +317,66 @@ func TestDeleteConversationByID(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+func TestAppendToConversation(t *testing.T) {
+	store, tmpDir := newTestConversationStore(t)
+	id := storage.NewConversationID()
+	messages := []proto.Message{{Role: proto.RoleUser, Content: "question"}}
+	require.NoError(t, store.Cache.Write(id, &messages))
+	require.NoError(t, store.DB.Save(id, "my-title", "openai", "gpt-4"))
+
+	cfg := &config.Config{Settings: config.Settings{CachePath: tmpDir, Quiet: true}}
+
+	t.Run("appends message and keeps metadata", func(t *testing.T) {
+		require.NoError(t, appendToConversation(cfg, "my-title", proto.RoleAssistant, "pasted answer"))
+
+		var got []proto.Message
+		require.NoError(t, store.Cache.Read(id, &got))
+		require.Equal(t, []proto.Message{
+			{Role: proto.RoleUser, Content: "question"},
+			{Role: proto.RoleAssistant, Content: "pasted answer"},
+		}, got)
+
+		db, err := storage.Open(filepath.Join(tmpDir, "conversations"))
+		require.NoError(t, err)
+		defer db.Close() //nolint:errcheck
+		convo, err := db.Find(id)
+		require.NoError(t, err)
+		require.Equal(t, "my-title", convo.Title)
+		require.Equal(t, "gpt-4", *convo.Model)
+	})
+
+	t.Run("rejects other roles", func(t *testing.T) {
+		require.ErrorContains(t, appendToConversation(cfg, id, proto.RoleSystem, "x"), "--role must be")
+	})
+
+	t.Run("rejects empty messages", func(t *testing.T) {
+		require.ErrorContains(t, appendToConversation(cfg, id, proto.RoleUser, " \n"), "message is empty")
+	})
+}
+
+func TestAppendContent(t *testing.T) {
+	got, err := appendContent([]string{"hello", "world"}, strings.NewReader("ignored"))
+	require.NoError(t, err)
+	require.Equal(t, "hello world", got)
+
+	got, err = appendContent([]string{"-"}, strings.NewReader("from stdin\n"))
+	require.NoError(t, err)
+	require.Equal(t, "from stdin", got)
+}
+
+func TestSyncHistory(t *testing.T) {
+	_, tmpDir := newTestConversationStore(t)
+
+	t.Run("requires a remote", func(t *testing.T) {
+		cfg := &config.Config{Settings: config.Settings{CachePath: tmpDir}}
+		cfg.Sync.Backend = "git"
+		require.ErrorContains(t, syncHistory(context.Background(), cfg, true), "sync.remote")
+	})
+
+	t.Run("rejects unknown backends", func(t *testing.T) {
+		cfg := &config.Config{Settings: config.Settings{CachePath: tmpDir}}
+		cfg.Sync = config.SyncSettings{Backend: "ftp", Remote: "example.com"}
+		require.ErrorContains(t, syncHistory(context.Background(), cfg, true), "unknown sync backend")
+	})
+}
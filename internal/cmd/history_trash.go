@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	timeago "github.com/caarlos0/timea.go"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/storage"
+)
+
+// trashConversation moves a conversation to the trash, or deletes it for
+// good when trash-retention is negative.
+func trashConversation(cfg *config.Config, store *conversationStore, id string) error {
+	if cfg.TrashRetention < 0 {
+		return purgeConversation(store, id)
+	}
+	// The payload goes first: a failure leaves the conversation where it
+	// was, rather than listed nowhere with its messages out of the trash.
+	trashErr := store.Cache.Trash(id)
+	if trashErr != nil && !errors.Is(trashErr, os.ErrNotExist) {
+		return fmt.Errorf("move conversation payload to trash: %w", trashErr)
+	}
+	if err := store.DB.Delete(id); err != nil {
+		if trashErr == nil {
+			_ = store.Cache.Restore(id)
+		}
+		return fmt.Errorf("delete conversation index: %w", err)
+	}
+	return nil
+}
+
+// restoreConversation brings a conversation back from the trash. Like
+// trashConversation, it accepts a conversation whose payload is missing.
+func restoreConversation(store *conversationStore, id string) error {
+	restoreErr := store.Cache.Restore(id)
+	if restoreErr != nil && !errors.Is(restoreErr, os.ErrNotExist) {
+		return fmt.Errorf("move conversation payload out of the trash: %w", restoreErr)
+	}
+	if err := store.DB.Restore(id); err != nil {
+		if restoreErr == nil {
+			_ = store.Cache.Trash(id)
+		}
+		return fmt.Errorf("restore conversation index: %w", err)
+	}
+	return nil
+}
+
+// purgeConversation deletes a conversation, in the trash or not, for good.
+func purgeConversation(store *conversationStore, id string) error {
+	if err := store.DB.Purge(id); err != nil {
+		return fmt.Errorf("delete conversation index: %w", err)
+	}
+	err := store.Cache.Purge(id)
+	if errors.Is(err, os.ErrNotExist) {
+		err = store.Cache.Delete(id)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("delete conversation payload: %w", err)
+	}
+	return nil
+}
+
+// expiredTrash returns the trashed conversations deleted more than olderThan
+// ago; zero or negative returns the whole trash.
+func expiredTrash(store *conversationStore, olderThan time.Duration) []storage.Trashed {
+	trash := store.DB.Trash()
+	if olderThan <= 0 {
+		return trash
+	}
+	cutoff := time.Now().Add(-olderThan)
+	var expired []storage.Trashed
+	for _, t := range trash {
+		if t.DeletedAt.Before(cutoff) {
+			expired = append(expired, t)
+		}
+	}
+	return expired
+}
+
+// purgeExpiredTrash removes conversations that outlived trash-retention.
+func purgeExpiredTrash(cfg *config.Config, store *conversationStore) error {
+	if cfg.TrashRetention <= 0 {
+		return nil
+	}
+	for _, t := range expiredTrash(store, cfg.TrashRetention) {
+		if err := purgeConversation(store, t.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func restoreConversations(cfg *config.Config, targets []string) error {
+	store, err := openConversationStore(cfg.CachePath)
+	if err != nil {
+		return errs.Wrap(err, "Could not open conversation store.")
+	}
+	defer store.Close() //nolint:errcheck
+
+	for _, target := range targets {
+		trashed, err := store.DB.FindTrashed(target)
+		if err != nil {
+			return errs.Wrap(err, "Couldn't find conversation in the trash.")
+		}
+		if err := restoreConversation(store, trashed.ID); err != nil {
+			return errs.Wrap(err, "Couldn't restore conversation.")
+		}
+		if !cfg.Quiet {
+			fmt.Fprintln(os.Stderr, "Conversation restored:", trashed.ID[:storage.SHA1MinLen])
+		}
+	}
+	return nil
+}
+
+func listTrash(cfg *config.Config) error {
+	store, err := openConversationStore(cfg.CachePath)
+	if err != nil {
+		return errs.Wrap(err, "Could not open conversation store.")
+	}
+	defer store.Close() //nolint:errcheck
+
+	trash := store.DB.Trash()
+	if len(trash) == 0 {
+		fmt.Fprintln(os.Stderr, "The trash is empty.")
+		return nil
+	}
+	for _, t := range trash {
+		_, _ = fmt.Fprintf(
+			os.Stdout,
+			"%s\t%s\t%s\n",
+			present.StdoutStyles().SHA1.Render(t.ID[:storage.SHA1Short]),
+			t.Title,
+			present.StdoutStyles().Timeago.Render("deleted "+timeago.Of(t.DeletedAt)),
+		)
+	}
+	return nil
+}
+
+// emptyTrash deletes the conversations in the trash for good, or only those
+// deleted more than olderThan ago.
+func emptyTrash(cfg *config.Config, olderThan time.Duration) error {
+	store, err := openConversationStore(cfg.CachePath)
+	if err != nil {
+		return errs.Wrap(err, "Could not open conversation store.")
+	}
+	defer store.Close() //nolint:errcheck
+
+	trash := expiredTrash(store, olderThan)
+	if len(trash) == 0 {
+		if !cfg.Quiet {
+			fmt.Fprintln(os.Stderr, "No conversations found.")
+		}
+		return nil
+	}
+
+	conversations := make([]storage.Conversation, 0, len(trash))
+	for _, t := range trash {
+		conversations = append(conversations, t.Conversation)
+	}
+	if err := confirmBulkDelete(cfg, conversations, "Permanently delete these conversations?"); err != nil {
+		return err
+	}
+	for _, t := range trash {
+		if err := purgeConversation(store, t.ID); err != nil {
+			return errs.Wrap(err, "Couldn't empty the trash.")
+		}
+	}
+	if !cfg.Quiet {
+		fmt.Fprintf(os.Stderr, "Permanently deleted %d conversations.\n", len(trash))
+	}
+	return nil
+}
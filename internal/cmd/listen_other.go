@@ -0,0 +1,17 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenPrivate listens on the Unix socket path, which only this user can
+// connect to: the umask keeps the other bits off from the moment the socket
+// is created.
+func listenPrivate(path string) (net.Listener, error) {
+	old := syscall.Umask(0o077)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path) //nolint:wrapcheck
+}
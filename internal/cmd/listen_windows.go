@@ -0,0 +1,9 @@
+package cmd
+
+import "net"
+
+// listenPrivate listens on the Unix socket path. Windows keeps others out of
+// the socket by the ACL of its directory; file modes do not apply.
+func listenPrivate(path string) (net.Listener, error) {
+	return net.Listen("unix", path) //nolint:wrapcheck
+}
@@ -48,6 +48,20 @@ func newMCPCmd(rt *runtime) *cobra.Command {
 		},
 	})
 
+	mcpCmd.AddCommand(&cobra.Command{
+		Use:   "refresh",
+		Short: "List the tools of enabled MCP servers again, replacing the cached lists",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			ctx, cancel := context.WithTimeout(cmd.Context(), rt.cfg.MCPTimeout)
+			defer cancel()
+			return mcpRefresh(ctx, &rt.cfg)
+		},
+	})
+
 	return mcpCmd
 }
 
@@ -64,6 +78,22 @@ func mcpList(cfg *config.Config) {
 	}
 }
 
+func mcpRefresh(ctx context.Context, cfg *config.Config) error {
+	svc := imcp.New(cfg)
+	defer svc.Close()
+	servers, err := svc.Refresh(ctx)
+	if err != nil {
+		return err
+	}
+
+	names := slices.Collect(maps.Keys(servers))
+	slices.Sort(names)
+	for _, sname := range names {
+		_, _ = fmt.Fprintf(os.Stdout, "%s%d tools\n", present.StdoutStyles().Timeago.Render(sname+" > "), len(servers[sname]))
+	}
+	return nil
+}
+
 func mcpListTools(ctx context.Context, cfg *config.Config) error {
 	svc := imcp.New(cfg)
 	defer svc.Close()
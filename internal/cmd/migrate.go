@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aymanbagabas/go-udiff"
+	"github.com/charmbracelet/huh"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+)
+
+// migrationDeclinedFile, in the state directory, holds the digest of the
+// settings file whose migration was last declined.
+const migrationDeclinedFile = "settings-migration-declined"
+
+// errMigrationDeclined is returned by migrateSettings when the user chose
+// not to update the settings file.
+var errMigrationDeclined = errors.New("settings migration declined")
+
+// maybeMigrateSettings warns about the keys of the settings file yai does
+// not know, and offers to rewrite its deprecated keys, showing the change
+// first. Without a terminal to ask in, it only warns, and with --quiet it
+// does neither. Once declined, it does not ask again until the file
+// changes.
+func (rt *runtime) maybeMigrateSettings() error {
+	if rt.cfg.Quiet {
+		return nil
+	}
+	if unknown := rt.cfg.UnknownKeys; len(unknown) > 0 {
+		keys := make([]string, 0, len(unknown))
+		for _, key := range unknown {
+			keys = append(keys, fmt.Sprintf("%s (line %d)", key.Path, key.Line))
+		}
+		fmt.Fprintln(os.Stderr, present.StderrStyles().Comment.Render(
+			"Warning: your settings have keys yai does not know, which are ignored: "+strings.Join(keys, ", ")+".",
+		))
+	}
+	keys := rt.cfg.DeprecatedKeys
+	if len(keys) == 0 {
+		return nil
+	}
+	content, err := os.ReadFile(rt.cfg.SettingsPath)
+	if err != nil || migrationDeclined(&rt.cfg, content) {
+		return nil //nolint:nilerr
+	}
+	if !present.IsInputTTY() || !present.IsErrorTTY() {
+		paths := make([]string, 0, len(keys))
+		for _, key := range keys {
+			paths = append(paths, key.Path)
+		}
+		fmt.Fprintln(os.Stderr, present.StderrStyles().Comment.Render(
+			"Warning: your settings use deprecated keys ("+strings.Join(paths, ", ")+"); run `yai config migrate` to update them.",
+		))
+		return nil
+	}
+	err = migrateSettings(&rt.cfg, os.Stderr, false)
+	if errors.Is(err, huh.ErrUserAborted) {
+		return errs.Wrap(err, "User canceled.")
+	}
+	if errors.Is(err, errMigrationDeclined) {
+		declineMigration(&rt.cfg, content)
+		return nil
+	}
+	if err != nil {
+		// The settings loaded; a failed migration should not stop the run.
+		fmt.Fprintln(os.Stderr, present.StderrStyles().Comment.Render("Warning: could not update your settings: "+err.Error()))
+	}
+	return nil
+}
+
+// migrateSettings shows the changes and the diff of migrating the settings
+// file to w and writes it once confirmed, or right away with yes, keeping
+// the previous file as a backup next to it.
+func migrateSettings(cfg *config.Config, w io.Writer, yes bool) error {
+	content, err := os.ReadFile(cfg.SettingsPath)
+	if err != nil {
+		return fmt.Errorf("read settings: %w", err)
+	}
+	migrated, keys, err := config.MigrateSettings(content)
+	if err != nil {
+		return fmt.Errorf("migrate settings: %w", err)
+	}
+	if len(keys) == 0 {
+		fmt.Fprintln(w, "Your settings are up to date.")
+		return nil
+	}
+
+	fmt.Fprintln(w, "Changes to your settings:")
+	for _, key := range keys {
+		fmt.Fprintf(w, "  • %s (line %d)\n", key.Describe(), key.Line)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprint(w, udiff.Unified(cfg.SettingsPath, cfg.SettingsPath, string(content), string(migrated)))
+	fmt.Fprintln(w)
+
+	if !yes {
+		confirm := true
+		if err := huh.Run(
+			huh.NewConfirm().
+				Title("Update your settings file?").
+				Description("Comments and everything else are kept as they are.").
+				Affirmative("Update").
+				Negative("Not now").
+				Value(&confirm),
+		); err != nil {
+			return fmt.Errorf("prompt: %w", err)
+		}
+		if !confirm {
+			fmt.Fprintln(w, present.StderrStyles().Comment.Render("Run `yai config migrate` to update them later."))
+			return errMigrationDeclined
+		}
+	}
+
+	info, err := os.Stat(cfg.SettingsPath)
+	if err != nil {
+		return fmt.Errorf("read settings: %w", err)
+	}
+	backup := cfg.SettingsPath + ".bak"
+	if err := os.WriteFile(backup, content, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("back up settings: %w", err)
+	}
+	if err := os.WriteFile(cfg.SettingsPath, migrated, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("write settings: %w", err)
+	}
+	cfg.DeprecatedKeys = nil
+	fmt.Fprintln(w, "Updated", cfg.SettingsPath)
+	fmt.Fprintln(w, present.StderrStyles().Comment.Render("Your old settings have been saved to: "+backup))
+	return nil
+}
+
+// migrationDeclined reports whether the migration of the settings file
+// content was declined.
+func migrationDeclined(cfg *config.Config, content []byte) bool {
+	stamp, err := os.ReadFile(filepath.Join(cfg.StateDir(), migrationDeclinedFile))
+	return err == nil && strings.TrimSpace(string(stamp)) == settingsDigest(content)
+}
+
+// declineMigration remembers that the migration of the settings file
+// content was declined. Failing to is not worth stopping for: yai asks
+// again next time.
+func declineMigration(cfg *config.Config, content []byte) {
+	dir := cfg.StateDir()
+	if os.MkdirAll(dir, 0o700) != nil {
+		return
+	}
+	_ = os.WriteFile(filepath.Join(dir, migrationDeclinedFile), []byte(settingsDigest(content)+"\n"), 0o600)
+}
+
+func settingsDigest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateSettings(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.SettingsPath = filepath.Join(t.TempDir(), "yai.yml")
+	old := "default-api: openai\nsystem: be brief\npalette: pink\n"
+	require.NoError(t, os.WriteFile(cfg.SettingsPath, []byte(old), 0o600))
+	cfg.DeprecatedKeys = []config.DeprecatedKey{{Path: "system"}}
+
+	var out bytes.Buffer
+	require.NoError(t, migrateSettings(cfg, &out, true))
+	require.Contains(t, out.String(), "-system: be brief")
+	require.NotContains(t, out.String(), "• palette", "unknown keys are left alone")
+	require.Empty(t, cfg.DeprecatedKeys)
+
+	content, err := os.ReadFile(cfg.SettingsPath)
+	require.NoError(t, err)
+	require.Equal(t, "default-api: openai\npalette: pink\n", string(content))
+	backup, err := os.ReadFile(cfg.SettingsPath + ".bak")
+	require.NoError(t, err)
+	require.Equal(t, old, string(backup))
+
+	out.Reset()
+	require.NoError(t, migrateSettings(cfg, &out, true))
+	require.Contains(t, out.String(), "up to date")
+}
+
+func TestMigrationDeclined(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.StatePath = t.TempDir()
+	content := []byte("model: gpt-4o\n")
+	require.False(t, migrationDeclined(cfg, content))
+
+	declineMigration(cfg, content)
+	require.True(t, migrationDeclined(cfg, content))
+	require.False(t, migrationDeclined(cfg, []byte("model: gpt-5\n")), "asked again once the file changes")
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// completeModels completes --model with the configured models, their
+// aliases, and the virtual models. With --api, only that API's models are
+// offered.
+func completeModels(cfg *config.Config) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		onlyAPI := ""
+		if f := cmd.Flags().Lookup("api"); f != nil && f.Changed {
+			onlyAPI = cfg.API
+		}
+		return modelCompletions(cfg, onlyAPI, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// modelCompletions returns the model names and aliases starting with
+// prefix, each described by what it is, on api when it is not empty.
+func modelCompletions(cfg *config.Config, api, prefix string) []string {
+	var out []string
+	add := func(name, desc string) {
+		if strings.HasPrefix(name, prefix) {
+			out = append(out, cobra.CompletionWithDesc(name, desc))
+		}
+	}
+	for _, a := range cfg.APIs {
+		if api != "" && a.Name != api {
+			continue
+		}
+		for _, name := range slices.Sorted(maps.Keys(a.Models)) {
+			add(name, a.Name)
+			for _, alias := range a.Models[name].Aliases {
+				add(alias, name+" on "+a.Name)
+			}
+		}
+	}
+	if api == "" {
+		for _, name := range slices.Sorted(maps.Keys(cfg.VirtualModels)) {
+			desc := "virtual model"
+			if policy := cfg.VirtualModels[name].Policy; policy != "" {
+				desc += ", " + policy
+			}
+			add(name, desc)
+		}
+	}
+	return out
+}
+
+// completeAPIs completes --api with the configured APIs.
+func completeAPIs(cfg *config.Config) cobra.CompletionFunc {
+	return func(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var out []string
+		for _, a := range cfg.APIs {
+			if strings.HasPrefix(a.Name, toComplete) {
+				out = append(out, a.Name)
+			}
+		}
+		return out, cobra.ShellCompDirectiveNoFileComp
+	}
+}
@@ -4,6 +4,7 @@ import "github.com/dotcommander/yai/internal/config"
 
 func isNoArgs(cfg *config.Config) bool {
 	return cfg.Prefix == "" &&
+		cfg.Transcribe == "" &&
 		cfg.Show == "" &&
 		!cfg.ShowLast &&
 		len(cfg.Delete) == 0 &&
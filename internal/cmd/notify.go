@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	goruntime "runtime"
+	"time"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+)
+
+// Values of --notify.
+const (
+	notifyBell    = "bell"
+	notifyDesktop = "desktop"
+	notifyBoth    = "both"
+)
+
+// checkNotify rejects an unknown --notify value before the request is made.
+func checkNotify(cfg *config.Config) error {
+	switch cfg.Notify {
+	case "", notifyBell, notifyDesktop, notifyBoth:
+		return nil
+	}
+	return fmt.Errorf("%w", errs.UserErrorf(
+		"--notify must be %q, %q, or %q, got %q", notifyBell, notifyDesktop, notifyBoth, cfg.Notify,
+	))
+}
+
+// notifyDone tells the user a request that took elapsed has finished, as
+// --notify asks, when it took at least notify-after. A notification that
+// cannot be shown is not worth an error.
+func notifyDone(cfg *config.Config, elapsed time.Duration, failed bool) {
+	if cfg.Notify == "" || (cfg.NotifyAfter > 0 && elapsed < cfg.NotifyAfter) {
+		return
+	}
+	if cfg.Notify == notifyBell || cfg.Notify == notifyBoth {
+		if present.IsErrorTTY() {
+			fmt.Fprint(os.Stderr, "\a")
+		}
+	}
+	if cfg.Notify == notifyDesktop || cfg.Notify == notifyBoth {
+		cmd := desktopNotification(goruntime.GOOS, "yai", notificationBody(cfg, elapsed, failed))
+		if cmd != nil && cmd.Start() == nil {
+			go cmd.Wait() //nolint:errcheck
+		}
+	}
+}
+
+func notificationBody(cfg *config.Config, elapsed time.Duration, failed bool) string {
+	elapsed = elapsed.Round(time.Second)
+	if failed {
+		return fmt.Sprintf("The request to %s failed after %s", cfg.Model, elapsed)
+	}
+	return fmt.Sprintf("%s answered in %s", cfg.Model, elapsed)
+}
+
+// desktopNotification returns the command that shows a desktop
+// notification on goos, or nil where there is none. The texts are passed as
+// arguments or in the environment, never in a script, so they need no
+// quoting.
+func desktopNotification(goos, title, body string) *exec.Cmd {
+	switch goos {
+	case "darwin":
+		return exec.Command("osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title, body,
+		)
+	case "windows":
+		cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", windowsToast)
+		cmd.Env = append(os.Environ(), "YAI_NOTIFY_TITLE="+title, "YAI_NOTIFY_BODY="+body)
+		return cmd
+	case "linux", "freebsd", "openbsd", "netbsd", "dragonfly":
+		return exec.Command("notify-send", "--app-name=yai", title, body)
+	}
+	return nil
+}
+
+// windowsToast shows a toast notification with the title and body set in
+// the environment.
+const windowsToast = `$title = $env:YAI_NOTIFY_TITLE
+$body = $env:YAI_NOTIFY_BODY
+$manager = [Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime]
+$xml = $manager::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $xml.GetElementsByTagName('text')
+$text.Item(0).AppendChild($xml.CreateTextNode($title)) > $null
+$text.Item(1).AppendChild($xml.CreateTextNode($body)) > $null
+$manager::CreateToastNotifier('yai').Show([Windows.UI.Notifications.ToastNotification]::new($xml))`
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckNotify(t *testing.T) {
+	for _, v := range []string{"", notifyBell, notifyDesktop, notifyBoth} {
+		require.NoError(t, checkNotify(&config.Config{Settings: config.Settings{Notify: v}}), v)
+	}
+	require.ErrorContains(t, checkNotify(&config.Config{Settings: config.Settings{Notify: "sms"}}), `got "sms"`)
+}
+
+func TestNotificationBody(t *testing.T) {
+	cfg := &config.Config{Settings: config.Settings{Model: "gpt-5"}}
+	require.Equal(t, "gpt-5 answered in 42s", notificationBody(cfg, 41700*time.Millisecond, false))
+	require.Equal(t, "The request to gpt-5 failed after 1m3s", notificationBody(cfg, 63*time.Second, true))
+}
+
+func TestDesktopNotification(t *testing.T) {
+	body := `it's "done"; $(rm -rf)`
+
+	cmd := desktopNotification("darwin", "yai", body)
+	require.Equal(t, "osascript", cmd.Args[0])
+	require.Equal(t, []string{"yai", body}, cmd.Args[len(cmd.Args)-2:])
+
+	cmd = desktopNotification("linux", "yai", body)
+	require.Equal(t, []string{"notify-send", "--app-name=yai", "yai", body}, cmd.Args)
+
+	cmd = desktopNotification("windows", "yai", body)
+	require.Equal(t, "powershell", cmd.Args[0])
+	require.NotContains(t, cmd.Args[len(cmd.Args)-1], body)
+	require.Contains(t, cmd.Env, "YAI_NOTIFY_BODY="+body)
+
+	require.Nil(t, desktopNotification("plan9", "yai", body))
+}
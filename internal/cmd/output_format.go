@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/dotcommander/yai/internal/errs"
+)
+
+// Values of --output-format.
+const (
+	outputFormatText  = "text"
+	outputFormatJSONL = "jsonl"
+)
+
+// maxLogprobs is the most alternatives --logprobs can ask for, as the
+// OpenAI API allows.
+const maxLogprobs = 20
+
+// applyOutputFormat checks --output-format and --logprobs. jsonl replaces the rendered
+// response with JSON events on stdout, so it runs like --raw and cannot echo
+// the prompt.
+func (rt *runtime) applyOutputFormat() error {
+	if rt.cfg.Logprobs < 0 || rt.cfg.Logprobs > maxLogprobs {
+		return fmt.Errorf("%w", errs.UserErrorf("--logprobs must be between 1 and %d, got %d", maxLogprobs, rt.cfg.Logprobs))
+	}
+	switch rt.cfg.OutputFormat {
+	case "", outputFormatText:
+		if rt.cfg.Logprobs > 0 {
+			return fmt.Errorf("%w", errs.UserErrorf("--logprobs needs --output-format jsonl, which writes them as logprobs events"))
+		}
+		return nil
+	case outputFormatJSONL:
+	default:
+		return fmt.Errorf("%w", errs.UserErrorf("--output-format must be %q or %q, got %q", outputFormatText, outputFormatJSONL, rt.cfg.OutputFormat))
+	}
+	if rt.cfg.IncludePrompt != 0 || rt.cfg.IncludePromptArgs {
+		return fmt.Errorf("%w", errs.UserErrorf("--output-format jsonl cannot be used with --prompt or --prompt-args"))
+	}
+	rt.cfg.Raw = true
+	return nil
+}
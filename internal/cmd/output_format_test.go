@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyOutputFormat(t *testing.T) {
+	t.Run("text", func(t *testing.T) {
+		rt := &runtime{cfg: config.Config{}}
+		rt.cfg.OutputFormat = outputFormatText
+		require.NoError(t, rt.applyOutputFormat())
+		require.False(t, rt.cfg.Raw)
+	})
+
+	t.Run("jsonl runs raw", func(t *testing.T) {
+		rt := &runtime{cfg: config.Config{}}
+		rt.cfg.OutputFormat = outputFormatJSONL
+		require.NoError(t, rt.applyOutputFormat())
+		require.True(t, rt.cfg.Raw)
+	})
+
+	t.Run("jsonl does not echo the prompt", func(t *testing.T) {
+		rt := &runtime{cfg: config.Config{}}
+		rt.cfg.OutputFormat = outputFormatJSONL
+		rt.cfg.IncludePromptArgs = true
+		require.Error(t, rt.applyOutputFormat())
+	})
+
+	t.Run("logprobs", func(t *testing.T) {
+		rt := &runtime{cfg: config.Config{}}
+		rt.cfg.Logprobs = 3
+		require.ErrorContains(t, rt.applyOutputFormat(), "--output-format jsonl")
+		rt.cfg.OutputFormat = outputFormatJSONL
+		require.NoError(t, rt.applyOutputFormat())
+		rt.cfg.Logprobs = 21
+		require.Error(t, rt.applyOutputFormat())
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		rt := &runtime{cfg: config.Config{}}
+		rt.cfg.OutputFormat = "yaml"
+		require.Error(t, rt.applyOutputFormat())
+	})
+}
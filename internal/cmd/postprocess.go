@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/caarlos0/go-shellwords"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+)
+
+// postprocessFilter rewrites an answer.
+type postprocessFilter func(context.Context, string) (string, error)
+
+// newPostprocessor returns the filters of postprocess, then those that
+// role-postprocess gives the role, chained; or nil when there are none.
+// A filter that is not built in is a command that reads the answer on
+// stdin and prints the new one.
+func newPostprocessor(cfg *config.Config) (postprocessFilter, error) {
+	names := append(append([]string(nil), cfg.Postprocess...), cfg.RolePostprocess[cfg.Role]...)
+	if len(names) == 0 {
+		return nil, nil
+	}
+	filters := make([]postprocessFilter, 0, len(names))
+	for _, name := range names {
+		if fn, ok := present.Postprocessors[name]; ok {
+			filters = append(filters, func(_ context.Context, s string) (string, error) {
+				return fn(s), nil
+			})
+			continue
+		}
+		args, err := shellwords.Parse(name)
+		if err != nil || len(args) == 0 {
+			return nil, errs.Wrap(
+				errs.UserErrorf("%q is neither a built-in filter (%s) nor a command", name, strings.Join(present.PostprocessorNames(), ", ")),
+				"Invalid postprocess settings.",
+			)
+		}
+		filters = append(filters, func(ctx context.Context, s string) (string, error) {
+			return runPostprocessCommand(ctx, name, args, s)
+		})
+	}
+	return func(ctx context.Context, s string) (string, error) {
+		for _, filter := range filters {
+			var err error
+			if s, err = filter(ctx, s); err != nil {
+				return "", err
+			}
+		}
+		return s, nil
+	}, nil
+}
+
+func runPostprocessCommand(ctx context.Context, name string, args []string, in string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...) //nolint:gosec // the user's configured filter
+	cmd.Stdin = strings.NewReader(in)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if msg := strings.TrimSpace(stderr.String()); errors.As(err, &exitErr) && msg != "" {
+			err = errors.New(msg)
+		}
+		return "", fmt.Errorf("postprocess filter %q: %w", name, err)
+	}
+	return stdout.String(), nil
+}
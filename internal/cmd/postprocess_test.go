@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPostprocessor(t *testing.T) {
+	t.Run("none", func(t *testing.T) {
+		fn, err := newPostprocessor(&config.Config{})
+		require.NoError(t, err)
+		require.Nil(t, fn)
+	})
+
+	t.Run("built-ins then role filters", func(t *testing.T) {
+		cfg := &config.Config{Settings: config.Settings{
+			Role:            "shell",
+			Postprocess:     []string{"strip-preamble"},
+			RolePostprocess: map[string][]string{"shell": {"extract-code", "trim"}, "other": {"/bin/false"}},
+		}}
+		fn, err := newPostprocessor(cfg)
+		require.NoError(t, err)
+		out, err := fn(context.Background(), "Here is the command:\n\n```sh\n  ls -l\n```\n")
+		require.NoError(t, err)
+		require.Equal(t, "ls -l", out)
+	})
+
+	t.Run("commands", func(t *testing.T) {
+		fn, err := newPostprocessor(&config.Config{Settings: config.Settings{Postprocess: []string{"/nonexistent/yai-filter --strict"}}})
+		require.NoError(t, err)
+		_, err = fn(context.Background(), "answer")
+		require.ErrorContains(t, err, `postprocess filter "/nonexistent/yai-filter --strict"`)
+
+		_, err = newPostprocessor(&config.Config{Settings: config.Settings{Postprocess: []string{`"unterminated`}}})
+		require.ErrorContains(t, err, "neither a built-in filter (extract-code, strip-preamble, trim) nor a command")
+	})
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+)
+
+// maybeLoadPromptFile puts the --prompt-file prompt, with its template
+// variables filled in, before the prompt given as arguments.
+func (rt *runtime) maybeLoadPromptFile() error {
+	if rt.cfg.PromptFile == "" {
+		if len(rt.cfg.PromptVars) > 0 {
+			return errs.Wrap(
+				errs.UserErrorf("Template variables are only used with %s.", present.StdoutStyles().InlineCode.Render("--prompt-file")),
+				"Nothing to fill in with --var.",
+			)
+		}
+		return nil
+	}
+
+	vars, err := parsePromptVars(rt.cfg.PromptVars)
+	if err != nil {
+		return err
+	}
+	prompt, err := loadPromptFile(rt.cfg.PromptFile, vars)
+	if err != nil {
+		return err
+	}
+	prompt = strings.TrimSpace(prompt)
+	if rt.cfg.Prefix != "" {
+		prompt += "\n\n" + rt.cfg.Prefix
+	}
+	rt.cfg.Prefix = prompt
+	return nil
+}
+
+// loadPromptFile reads the prompt template at path and executes it with
+// vars. Variables the template uses but vars lacks are an error rather than
+// an empty string, so a typo does not go unnoticed.
+func loadPromptFile(path string, vars map[string]string) (string, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // G304: user-selected prompt file
+	if err != nil {
+		return "", errs.Wrap(err, "Could not read the prompt file.")
+	}
+	tmpl, err := template.New(path).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return "", errs.Wrap(err, "Could not parse the prompt file.")
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, vars); err != nil {
+		return "", errs.Wrap(err, "Could not fill in the prompt file; set its variables with --var name=value.")
+	}
+	return sb.String(), nil
+}
+
+// parsePromptVars parses --var name=value pairs.
+func parsePromptVars(pairs []string) (map[string]string, error) {
+	vars := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, errs.Wrap(
+				errs.UserErrorf("Write it as %s.", present.StdoutStyles().InlineCode.Render("--var name=value")),
+				"Invalid template variable "+pair+".",
+			)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// expandFileArgs replaces each @path argument with the contents of the file
+// at path. Arguments naming no file, like "@alice", are kept as they are;
+// "@@" escapes a leading @.
+func expandFileArgs(args []string) ([]string, error) {
+	out := make([]string, 0, len(args))
+	for _, arg := range args {
+		path, ok := strings.CutPrefix(arg, "@")
+		switch {
+		case !ok || path == "":
+			out = append(out, arg)
+		case strings.HasPrefix(path, "@"):
+			out = append(out, path)
+		default:
+			data, err := os.ReadFile(path) //nolint:gosec // G304: user-selected prompt file
+			if errors.Is(err, fs.ErrNotExist) {
+				out = append(out, arg)
+				continue
+			}
+			if err != nil {
+				return nil, errs.Wrap(err, "Could not read "+path+".")
+			}
+			out = append(out, string(data))
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaybeLoadPromptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "review.md")
+	require.NoError(t, os.WriteFile(path, []byte("Review this {{.lang}} code.\n"), 0o600))
+
+	t.Run("fills in variables", func(t *testing.T) {
+		rt := &runtime{cfg: config.Config{}}
+		rt.cfg.PromptFile = path
+		rt.cfg.PromptVars = []string{"lang=Go"}
+		rt.cfg.Prefix = "Focus on errors."
+		require.NoError(t, rt.maybeLoadPromptFile())
+		require.Equal(t, "Review this Go code.\n\nFocus on errors.", rt.cfg.Prefix)
+	})
+
+	t.Run("missing variable", func(t *testing.T) {
+		rt := &runtime{cfg: config.Config{}}
+		rt.cfg.PromptFile = path
+		require.ErrorContains(t, rt.maybeLoadPromptFile(), "lang")
+	})
+
+	t.Run("invalid variable", func(t *testing.T) {
+		rt := &runtime{cfg: config.Config{}}
+		rt.cfg.PromptFile = path
+		rt.cfg.PromptVars = []string{"lang"}
+		require.Error(t, rt.maybeLoadPromptFile())
+	})
+
+	t.Run("variables without a file", func(t *testing.T) {
+		rt := &runtime{cfg: config.Config{}}
+		rt.cfg.PromptVars = []string{"lang=Go"}
+		require.Error(t, rt.maybeLoadPromptFile())
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		rt := &runtime{cfg: config.Config{}}
+		rt.cfg.PromptFile = filepath.Join(t.TempDir(), "nope.md")
+		require.Error(t, rt.maybeLoadPromptFile())
+	})
+}
+
+func TestExpandFileArgs(t *testing.T) {
+	t.Chdir(t.TempDir())
+	require.NoError(t, os.WriteFile("notes.txt", []byte("{{ kept as is }}"), 0o600))
+
+	args, err := expandFileArgs([]string{"summarize", "@notes.txt", "@alice", "@@notes.txt", "@"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"summarize", "{{ kept as is }}", "@alice", "@notes.txt", "@"}, args)
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	timeago "github.com/caarlos0/timea.go"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+// routeStatus is the JSON representation printed by `providers status
+// --json`.
+type routeStatus struct {
+	API          string     `json:"api"`
+	Model        string     `json:"model"`
+	Healthy      bool       `json:"healthy"`
+	Requests     int        `json:"requests"`
+	Failures     int        `json:"failures"`
+	LastFailure  *time.Time `json:"last_failure,omitempty"`
+	Failure      string     `json:"failure,omitempty"`
+	FirstTokenMS int64      `json:"first_token_ms,omitempty"`
+}
+
+func newProvidersCmd(rt *runtime) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "providers",
+		Short: "Inspect the configured providers",
+		Args:  cobra.NoArgs,
+	}
+	var asJSON bool
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the recent requests, failures, and health of each API and model",
+		Long: "Show the requests and failures of each API and model over the last week, from the usage ledger. " +
+			"A route whose last request timed out or failed less than 30 minutes ago is unhealthy; " +
+			"models served by several APIs are sent to a healthy one.",
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			now := time.Now()
+			entries, err := storage.OpenLedger(rt.cfg.StateDir()).Since(now.Add(-fastestWindow))
+			if err != nil {
+				return errs.Wrap(err, "Could not read the usage ledger.")
+			}
+			statuses := providerStatuses(&rt.cfg, entries, now)
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(statuses); err != nil {
+					return errs.Wrap(err, "Could not write provider status.")
+				}
+				return nil
+			}
+			printProviderStatuses(os.Stdout, present.StdoutStyles(), statuses)
+			return nil
+		},
+	}
+	statusCmd.Flags().BoolVar(&asJSON, "json", false, "Print the status as JSON")
+	cmd.AddCommand(statusCmd)
+	return cmd
+}
+
+// providerStatuses summarizes the ledger for every configured route that
+// was used, in settings order.
+func providerStatuses(cfg *config.Config, entries []storage.LedgerEntry, now time.Time) []routeStatus {
+	var routes []route
+	for _, api := range cfg.APIs {
+		for _, name := range slices.Sorted(maps.Keys(api.Models)) {
+			routes = append(routes, route{API: api.Name, Model: name})
+		}
+	}
+	statuses := []routeStatus{}
+	for _, stat := range routeStats(routes, entries, now) {
+		if stat.Requests == 0 {
+			continue
+		}
+		status := routeStatus{
+			API:          stat.API,
+			Model:        stat.Model,
+			Healthy:      !stat.Failing,
+			Requests:     stat.Requests,
+			Failures:     stat.Failures,
+			FirstTokenMS: stat.FirstToken.Milliseconds(),
+		}
+		if stat.Failures > 0 {
+			status.LastFailure = &stat.LastFailure.Time
+			status.Failure = stat.LastFailure.Failure
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+func printProviderStatuses(w io.Writer, s present.Styles, statuses []routeStatus) {
+	if len(statuses) == 0 {
+		fmt.Fprintln(w, s.Comment.Render("No requests recorded in the last week."))
+		return
+	}
+	for _, st := range statuses {
+		health := s.Flag.Render("healthy")
+		if !st.Healthy {
+			health = s.Warning.Render("failing")
+		}
+		line := fmt.Sprintf("  %-32s %s %5d requests %4d failed", st.API+"/"+st.Model, health, st.Requests, st.Failures)
+		if st.FirstTokenMS > 0 {
+			line += fmt.Sprintf("  first token %s", time.Duration(st.FirstTokenMS)*time.Millisecond)
+		}
+		if st.LastFailure != nil {
+			line += s.Comment.Render(fmt.Sprintf("  last failure %s (%s)",
+				timeago.Of(*st.LastFailure), cmp.Or(st.Failure, "unknown")))
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// preferHealthyRoute moves a request off an API that is failing when
+// another API serves the same model and is not. It only applies when no API
+// was chosen, so the model is looked up by name or alias across all of them.
+func preferHealthyRoute(cfg *config.Config) {
+	routes := modelRoutes(cfg, cfg.Model)
+	if len(routes) < 2 {
+		return
+	}
+	entries, err := storage.OpenLedger(cfg.StateDir()).Since(time.Now().Add(-failingFor))
+	if err != nil {
+		return
+	}
+	stats := routeStats(routes, entries, time.Now())
+	if !stats[0].Failing {
+		return
+	}
+	for _, stat := range stats[1:] {
+		if stat.Failing {
+			continue
+		}
+		cfg.API = stat.API
+		cfg.Model = stat.Model
+		if !cfg.Quiet {
+			fmt.Fprintln(os.Stderr, present.StderrStyles().Comment.Render(fmt.Sprintf(
+				"Using %s/%s: %s failed recently (%s)",
+				stat.API, stat.Model, stats[0].API, cmp.Or(stats[0].LastFailure.Failure, "unknown"),
+			)))
+		}
+		return
+	}
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/dotcommander/yai/internal/present/presenttest"
+	"github.com/dotcommander/yai/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderStatuses(t *testing.T) {
+	cfg := fastestTestConfig(t)
+	now := time.Now()
+	entries := []storage.LedgerEntry{
+		{Time: now.Add(-2 * time.Hour), API: "azure", Model: "gpt-4o", FirstTokenMS: 300},
+		{Time: now.Add(-time.Hour), API: "openai", Model: "gpt-4o", FirstTokenMS: 500},
+		{Time: now.Add(-time.Minute), API: "openai", Model: "gpt-4o", Failed: true, Failure: "server"},
+	}
+
+	statuses := providerStatuses(cfg, entries, now)
+	require.Len(t, statuses, 2, "routes without requests are left out")
+	require.Equal(t, "openai", statuses[0].API, "routes are in settings order")
+	require.False(t, statuses[0].Healthy)
+	require.Equal(t, 2, statuses[0].Requests)
+	require.Equal(t, 1, statuses[0].Failures)
+	require.Equal(t, "server", statuses[0].Failure)
+	require.NotNil(t, statuses[0].LastFailure)
+	require.True(t, statuses[1].Healthy)
+	require.Nil(t, statuses[1].LastFailure)
+	require.Equal(t, int64(300), statuses[1].FirstTokenMS)
+
+	var b strings.Builder
+	printProviderStatuses(&b, presenttest.Styles(), statuses)
+	out := ansi.Strip(b.String())
+	require.Contains(t, out, "openai/gpt-4o")
+	require.Contains(t, out, "failing")
+	require.Contains(t, out, "(server)")
+
+	b.Reset()
+	printProviderStatuses(&b, presenttest.Styles(), providerStatuses(cfg, nil, now))
+	require.Contains(t, b.String(), "No requests recorded")
+}
+
+func TestPreferHealthyRoute(t *testing.T) {
+	cfg := fastestTestConfig(t)
+	ledger := storage.OpenLedger(cfg.CachePath)
+
+	preferHealthyRoute(cfg)
+	require.Empty(t, cfg.API, "nothing is changed while the first route is healthy")
+
+	require.NoError(t, ledger.Append(storage.LedgerEntry{Time: time.Now(), API: "openai", Model: "gpt-4o", Failed: true, Failure: "timeout"}))
+	preferHealthyRoute(cfg)
+	require.Equal(t, "azure", cfg.API)
+	require.Equal(t, "gpt-4o", cfg.Model)
+
+	cfg.API = ""
+	cfg.Model = "4o"
+	require.NoError(t, ledger.Append(storage.LedgerEntry{Time: time.Now(), API: "azure", Model: "gpt-4o", Failed: true, Failure: "server"}))
+	preferHealthyRoute(cfg)
+	require.Empty(t, cfg.API, "with every route failing, the first is kept")
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/redact"
+	"github.com/dotcommander/yai/internal/stream"
+)
+
+// newRedactor returns the redactor for the redact-builtin rules and the
+// redact-patterns.
+func newRedactor(cfg *config.Config) (*redact.Redactor, error) {
+	r, err := redact.New(cfg.RedactBuiltin, cfg.RedactPatterns)
+	if err != nil {
+		return nil, errs.Wrap(errs.UserErrorf("%s", err), "Invalid redaction settings.")
+	}
+	return r, nil
+}
+
+// redactPrompt replaces secrets in the prompt and the piped input before
+// they are sent, and warns about what it replaced. Piped input is read here;
+// the program reads it from rt.stdin afterwards.
+func (rt *runtime) redactPrompt() error {
+	if rt.cfg.NoRedact {
+		return nil
+	}
+	redactor, err := newRedactor(&rt.cfg)
+	if err != nil {
+		return err
+	}
+	input, err := rt.readPipedInput()
+	if err != nil {
+		return err
+	}
+	prefix, found := redactor.Redact(rt.cfg.Prefix)
+	rt.cfg.Prefix = prefix
+	if rt.stdin != nil {
+		redacted, more := redactor.Redact(input)
+		found = found.Add(more)
+		rt.stdin = strings.NewReader(redacted)
+	}
+	if found.Total() > 0 && !rt.cfg.Quiet {
+		fmt.Fprintln(os.Stderr, present.StderrStyles().Comment.Render(redactedNote(found, "prompt")))
+	}
+	return nil
+}
+
+// redactedNote tells what was redacted from what.
+func redactedNote(found redact.Matches, from string) string {
+	return fmt.Sprintf("Redacted %s from the %s; pass --no-redact to send them as they are.", found, from)
+}
+
+// redactedStream adds a note on what was redacted from a chat message to
+// the warnings of the stream, which the chat shows.
+type redactedStream struct {
+	stream.Stream
+
+	note string
+}
+
+func (s *redactedStream) DrainWarnings() []string {
+	warnings := s.Stream.DrainWarnings()
+	if s.note != "" {
+		warnings = append(warnings, s.note)
+		s.note = ""
+	}
+	return warnings
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/tui"
+)
+
+// applyResume turns --resume into a request asking the model to finish the
+// cut-off answer of the last conversation, or of the one given with
+// --continue.
+func (rt *runtime) applyResume() error {
+	if !rt.cfg.Resume {
+		return nil
+	}
+	if rt.cfg.Prefix != "" {
+		return errs.Wrap(
+			errs.UserErrorf("Run %s without a prompt.", present.StdoutStyles().InlineCode.Render("yai --resume")),
+			"Resuming takes no prompt.",
+		)
+	}
+	if rt.cfg.Continue == "" {
+		rt.cfg.ContinueLast = true
+	}
+	rt.cfg.Prefix = agent.ResumePrompt
+	return nil
+}
+
+// checkResumable makes sure --resume continues a conversation whose last
+// answer was cut off.
+func (rt *runtime) checkResumable(store *conversationStore) error {
+	if !rt.cfg.Resume {
+		return nil
+	}
+	var messages []proto.Message
+	if rt.cfg.CacheReadFromID != "" {
+		if err := store.Cache.Read(rt.cfg.CacheReadFromID, &messages); err != nil {
+			return errs.Wrap(err, "Could not read the conversation.")
+		}
+	}
+	if !agent.CanResume(messages) {
+		return errs.Wrap(
+			errs.UserErrorf("Only answers cut off by Ctrl+C or a dropped connection can be resumed."),
+			"There is nothing to resume.",
+		)
+	}
+	return nil
+}
+
+// saveInterrupted saves a conversation whose request failed mid-response,
+// so that the answer can be resumed. The request's own error is what gets
+// reported, so a failure to save is not.
+func (rt *runtime) saveInterrupted(store *conversationStore, yai *tui.Yai) {
+	if yai == nil || !yai.Interrupted() || rt.cfg.NoCache {
+		return
+	}
+	if err := saveConversationWithFeedback(&rt.cfg, store, agent.MergeResumed(yai.Messages()), false); err != nil {
+		return
+	}
+	printResumeHint(&rt.cfg)
+}
+
+func printResumeHint(cfg *config.Config) {
+	if cfg.Quiet || cfg.NoCache {
+		return
+	}
+	s := present.StderrStyles()
+	fmt.Fprintln(
+		os.Stderr,
+		s.Comment.Render("The response was cut off. Run")+" "+s.InlineCode.Render("yai --resume")+" "+s.Comment.Render("to continue it."),
+	)
+}
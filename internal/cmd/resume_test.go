@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyResume(t *testing.T) {
+	t.Run("continues the last conversation", func(t *testing.T) {
+		rt := &runtime{cfg: config.Config{}}
+		rt.cfg.Resume = true
+		require.NoError(t, rt.applyResume())
+		require.True(t, rt.cfg.ContinueLast)
+		require.Equal(t, agent.ResumePrompt, rt.cfg.Prefix)
+	})
+
+	t.Run("continues the given conversation", func(t *testing.T) {
+		rt := &runtime{cfg: config.Config{}}
+		rt.cfg.Resume = true
+		rt.cfg.Continue = "notes"
+		require.NoError(t, rt.applyResume())
+		require.False(t, rt.cfg.ContinueLast)
+	})
+
+	t.Run("takes no prompt", func(t *testing.T) {
+		rt := &runtime{cfg: config.Config{}}
+		rt.cfg.Resume = true
+		rt.cfg.Prefix = "go on"
+		require.Error(t, rt.applyResume())
+	})
+}
+
+func TestCheckResumable(t *testing.T) {
+	store, _ := newTestConversationStore(t)
+	const id = "abc123def456"
+	rt := &runtime{cfg: config.Config{}}
+	rt.cfg.Resume = true
+	rt.cfg.CacheReadFromID = id
+
+	finished := []proto.Message{
+		{Role: proto.RoleUser, Content: "count to ten"},
+		{Role: proto.RoleAssistant, Content: "one to ten"},
+	}
+	require.NoError(t, store.Cache.Write(id, &finished))
+	require.Error(t, rt.checkResumable(store))
+
+	partial := agent.WithPartial(finished[:1], "one, two, ")
+	require.NoError(t, store.Cache.Write(id, &partial))
+	require.NoError(t, rt.checkResumable(store))
+
+	rt.cfg.CacheReadFromID = ""
+	require.Error(t, rt.checkResumable(store))
+}
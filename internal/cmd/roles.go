@@ -7,6 +7,7 @@ import (
 
 	"github.com/dotcommander/yai/internal/config"
 	"github.com/dotcommander/yai/internal/present"
+	"github.com/spf13/cobra"
 )
 
 func roleNames(cfg *config.Config, prefix string) []string {
@@ -21,6 +22,34 @@ func roleNames(cfg *config.Config, prefix string) []string {
 	return roles
 }
 
+// completeRoles completes --role with the role names, a directory of
+// nested roles at a time: code/ stands for code/review and code/tests until
+// what was typed reaches into it.
+func completeRoles(cfg *config.Config) cobra.CompletionFunc {
+	return func(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		out := roleCompletions(cfg, toComplete)
+		if len(out) == 1 && strings.HasSuffix(out[0], "/") {
+			return out, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+		}
+		return out, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// roleCompletions returns the roles starting with prefix, with the nested
+// roles below the next / collapsed into their directory.
+func roleCompletions(cfg *config.Config, prefix string) []string {
+	var out []string
+	for _, role := range roleNames(cfg, prefix) {
+		if i := strings.Index(role[len(prefix):], "/"); i >= 0 {
+			role = role[:len(prefix)+i+1]
+		}
+		if !slices.Contains(out, role) {
+			out = append(out, role)
+		}
+	}
+	return out
+}
+
 func listRoles(cfg *config.Config) {
 	for _, role := range roleNames(cfg, "") {
 		s := role
@@ -3,11 +3,13 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"slices"
 	"strings"
 	"syscall"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	glamour "github.com/charmbracelet/glamour/styles"
@@ -18,14 +20,26 @@ import (
 	"github.com/dotcommander/yai/internal/errs"
 	"github.com/dotcommander/yai/internal/present"
 	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/storage/robustio"
 	"github.com/dotcommander/yai/internal/tui"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 type runtime struct {
 	build  BuildInfo
 	cfg    config.Config
 	cfgErr error
+
+	// promptHook, when set, rewrites the prompt right before each request is
+	// started (e.g. to add retrieved knowledge).
+	promptHook func(ctx context.Context, prompt string) (string, error)
+	// stdin, when set, is the piped input, already read (e.g. to measure
+	// the prompt before confirming it).
+	stdin io.Reader
+	// flags are the flags of the command that runs, which the daemon
+	// applies to its settings.
+	flags *pflag.FlagSet
 }
 
 // NewRootCmd constructs the Cobra root command.
@@ -35,7 +49,13 @@ func NewRootCmd(build BuildInfo, cfg config.Config, cfgErr error) *cobra.Command
 	glamour.LightStyleConfig.CodeBlock.Chroma.Error.BackgroundColor = new(string)
 
 	rt := &runtime{build: normalizeBuildInfo(build), cfg: cfg, cfgErr: cfgErr}
+	rt.cfg.ClientVersion = rt.build.Version
+	if err := useColor(&rt.cfg); err != nil && !rt.cfg.Quiet {
+		fmt.Fprintln(os.Stderr, present.StderrStyles().Comment.Render("Warning: "+err.Error()))
+	}
+	useTheme(&rt.cfg)
 
+	chatCmd := newChatCmd(rt)
 	rootCmd := &cobra.Command{
 		Use:                "yai",
 		Short:              "GPT on the command line. Built for pipelines.",
@@ -45,6 +65,19 @@ func NewRootCmd(build BuildInfo, cfg config.Config, cfgErr error) *cobra.Command
 		Args:               cobra.ArbitraryArgs,
 		DisableFlagParsing: false,
 		TraverseChildren:   true,
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			flags := cmd.Flags()
+			rt.flags = flags
+			if flags.Changed("color") {
+				if err := useColor(&rt.cfg); err != nil {
+					return err
+				}
+			}
+			if flags.Changed("theme") || flags.Changed("glamour-style") {
+				useTheme(&rt.cfg)
+			}
+			return nil
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if rt.cfgErr != nil {
 				return rt.cfgErr
@@ -52,6 +85,16 @@ func NewRootCmd(build BuildInfo, cfg config.Config, cfgErr error) *cobra.Command
 			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
 			defer stop()
 			cmd.SetContext(ctx)
+			if err := rt.maybeMigrateSettings(); err != nil {
+				return err
+			}
+			chat, err := rt.defaultsToChat(cmd.Flags(), chatCmd.Flags())
+			if err != nil {
+				return err
+			}
+			if chat {
+				return rt.runChat(ctx, cmd.Flags(), args)
+			}
 			return rt.runGenerate(cmd, args)
 		},
 	}
@@ -75,7 +118,19 @@ func NewRootCmd(build BuildInfo, cfg config.Config, cfgErr error) *cobra.Command
 	rootCmd.AddCommand(newMCPCmd(rt))
 	rootCmd.AddCommand(newManCmd(rootCmd))
 	rootCmd.AddCommand(newUpgradeCmd(rt))
-	rootCmd.AddCommand(newChatCmd(rt))
+	rootCmd.AddCommand(chatCmd)
+	rootCmd.AddCommand(newBatchCmd(rt))
+	rootCmd.AddCommand(newEmbedCmd(rt))
+	rootCmd.AddCommand(newAskCmd(rt))
+	rootCmd.AddCommand(newServeCmd(rt))
+	rootCmd.AddCommand(newDaemonCmd(rt))
+	rootCmd.AddCommand(newUsageCmd(rt))
+	rootCmd.AddCommand(newProvidersCmd(rt))
+	rootCmd.AddCommand(newDoctorCmd(rt))
+	rootCmd.AddCommand(newBenchCmd(rt))
+	rootCmd.AddCommand(newWorkspaceCmd(rt))
+	rootCmd.AddCommand(newTranslateCmd(rt))
+	rootCmd.AddCommand(newSummarizeCmd(rt))
 
 	// Enable completion now that we have subcommands.
 	rootCmd.InitDefaultCompletionCmd()
@@ -84,11 +139,30 @@ func NewRootCmd(build BuildInfo, cfg config.Config, cfgErr error) *cobra.Command
 }
 
 func (rt *runtime) runGenerate(cmd *cobra.Command, args []string) error {
+	args, err := expandFileArgs(args)
+	if err != nil {
+		return err
+	}
 	rt.cfg.Prefix = present.RemoveWhitespace(strings.Join(args, " "))
 
+	if err := rt.maybeLoadPromptFile(); err != nil {
+		return err
+	}
+	if err := rt.applySnippets(); err != nil {
+		return err
+	}
+	if err := rt.applyResume(); err != nil {
+		return err
+	}
 	if err := rt.applyPatchMode(cmd); err != nil {
 		return err
 	}
+	if err := rt.applySinks(); err != nil {
+		return err
+	}
+	if err := rt.applyOutputFormat(); err != nil {
+		return err
+	}
 	if err := rt.maybeLoadPromptFromEditor(); err != nil {
 		return err
 	}
@@ -105,15 +179,72 @@ func (rt *runtime) runGenerate(cmd *cobra.Command, args []string) error {
 	}
 	defer store.Close() //nolint:errcheck
 
+	if !rt.cfg.DryRun {
+		// --dry-run reports these with its other checks.
+		if err := checkSettings(cmd.Flags(), &rt.cfg); err != nil {
+			return err
+		}
+		if err := checkBudget(&rt.cfg); err != nil {
+			return err
+		}
+	}
+	// Transcribing is a paid request of its own: it waits for the checks.
+	if err := rt.maybeTranscribe(cmd.Context()); err != nil {
+		return err
+	}
+	if err := rt.checkResumable(store); err != nil {
+		return err
+	}
+	if shown, err := rt.maybeShowDuplicate(store); shown || err != nil {
+		return err
+	}
+	if rt.cfg.ChunkSize > 0 {
+		if err := rt.prepareChunks(); err != nil {
+			return err
+		}
+	}
+	if err := rt.redactPrompt(); err != nil {
+		return err
+	}
+	if rt.cfg.DryRun {
+		return rt.dryRun(cmd.Context(), cmd.Flags(), store)
+	}
+	if err := rt.confirmSend(store); err != nil {
+		return err
+	}
+	if rt.cfg.ChunkSize > 0 {
+		if err := rt.chunkInput(cmd.Context(), chunkSize(&rt.cfg), "", "Answering"); err != nil {
+			return err
+		}
+	}
+
 	yai, err := rt.runGenerateProgram(cmd.Context(), rt.programOptions(), store)
 	if err != nil {
+		rt.saveInterrupted(store, yai)
 		return err
 	}
 	if err := rt.ensurePromptInput(yai); err != nil {
 		return err
 	}
 	rt.printGenerateOutput(yai)
-	return saveConversation(&rt.cfg, store, yai.Messages())
+	printFallbackNote(&rt.cfg)
+	messages := agent.MergeResumed(yai.Messages())
+	if err := saveConversation(&rt.cfg, store, messages); err != nil {
+		return err
+	}
+	if yai.Interrupted() {
+		printResumeHint(&rt.cfg)
+	}
+	if yai.Canceled {
+		return rt.interruptError()
+	}
+	added := 1
+	if rt.cfg.Resume {
+		// A resumed answer continues the last turn rather than adding one.
+		added = 0
+	}
+	refreshTitle(cmd.Context(), &rt.cfg, store, messages, added)
+	return nil
 }
 
 func (rt *runtime) applyPatchMode(cmd *cobra.Command) error {
@@ -137,7 +268,7 @@ func (rt *runtime) programOptions() []tea.ProgramOption {
 		rt.cfg.Quiet = true
 	}
 
-	opts := []tea.ProgramOption{}
+	opts := []tea.ProgramOption{tea.WithFilter(tui.InterruptFilter)}
 	if !present.IsInputTTY() || rt.cfg.Raw {
 		opts = append(opts, tea.WithInput(nil))
 	}
@@ -196,7 +327,7 @@ func (rt *runtime) runHeadlessMode(cmd *cobra.Command, args []string) (bool, err
 		return true, mcpListTools(ctx, &rt.cfg)
 	case rt.cfg.List:
 		drainStdin()
-		return true, listConversations(&rt.cfg, rt.cfg.Raw)
+		return true, listConversations(&rt.cfg, rt.cfg.Raw, false)
 	case len(rt.cfg.Delete) > 0:
 		drainStdin()
 		return true, deleteConversations(&rt.cfg, rt.cfg.Delete)
@@ -236,6 +367,26 @@ func (rt *runtime) openAndPlanStore() (*conversationStore, error) {
 	rt.cfg.CacheReadFromID = pl.ReadID
 	rt.cfg.API = pl.API
 	rt.cfg.Model = pl.Model
+	if err := useVirtualModel(&rt.cfg); err != nil {
+		store.Close() //nolint:errcheck
+		return nil, err
+	}
+	switch {
+	case rt.cfg.Fastest:
+		useFastestRoute(&rt.cfg)
+	case rt.cfg.API == "":
+		preferHealthyRoute(&rt.cfg)
+	}
+	if store.tempDir != "" {
+		// Nothing is kept in a temporary store; do not claim it was saved.
+		rt.cfg.NoCache = true
+	}
+	if !rt.cfg.NoCache {
+		if err := store.lock(pl.WriteID); err != nil {
+			store.Close() //nolint:errcheck
+			return nil, err
+		}
+	}
 	return store, nil
 }
 
@@ -244,19 +395,52 @@ func (rt *runtime) runGenerateProgram(
 	opts []tea.ProgramOption,
 	store *conversationStore,
 ) (*tui.Yai, error) {
+	postprocess, err := newPostprocessor(&rt.cfg)
+	if err != nil {
+		return nil, err
+	}
+	sinks, err := openSinks(rt.cfg.Sinks)
+	if err != nil {
+		return nil, err
+	}
 	agentSvc := agent.New(&rt.cfg, store.Cache, nil)
 	startStreamFn := agentSvc.Stream
+	if daemon := rt.daemon(); daemon != nil {
+		startStreamFn = func(ctx context.Context, prompt string) (agent.StreamStart, error) {
+			return daemon.Stream(ctx, &rt.cfg, store.Cache, prompt)
+		}
+	}
+	if hook := rt.promptHook; hook != nil {
+		start := startStreamFn
+		startStreamFn = func(ctx context.Context, prompt string) (agent.StreamStart, error) {
+			prompt, err := hook(ctx, prompt)
+			if err != nil {
+				return agent.StreamStart{}, err
+			}
+			return start(ctx, prompt)
+		}
+	}
 	yai := tui.NewYai(ctx, present.StderrRenderer(), &rt.cfg, agentSvc, startStreamFn)
-	p := tea.NewProgram(yai, opts...)
-	m, err := p.Run()
+	yai.JSONEvents = rt.cfg.OutputFormat == outputFormatJSONL
+	yai.Stdin = rt.stdin
+	yai.Postprocess = postprocess
+	yai.Sinks = sinks
+	start := time.Now()
+	m, err := runGuarded(yai, opts...)
+	warnSinks(closeSinks(sinks))
 	if err != nil {
 		return nil, errs.Wrap(err, "Couldn't start Bubble Tea program.")
 	}
 
 	yai = m.(*tui.Yai)
+	if !yai.Canceled {
+		notifyDone(&rt.cfg, time.Since(start), yai.Error != nil)
+	}
 	if yai.Error != nil {
-		return nil, *yai.Error
+		recordFailure(&rt.cfg, *yai.Error)
+		return yai, *yai.Error
 	}
+	recordAnswer(&rt.cfg, yai.Messages())
 	return yai, nil
 }
 
@@ -273,6 +457,15 @@ func (rt *runtime) ensurePromptInput(yai *tui.Yai) error {
 	)
 }
 
+// interruptError is what a run stopped with Ctrl+C returns: nothing, unless
+// fail-on-interrupt is set.
+func (rt *runtime) interruptError() error {
+	if !rt.cfg.FailOnInterrupt {
+		return nil
+	}
+	return errs.Wrap(context.Canceled, "Interrupted.")
+}
+
 func (rt *runtime) printGenerateOutput(yai *tui.Yai) {
 	if !present.IsOutputTTY() || rt.cfg.Raw {
 		return
@@ -306,7 +499,11 @@ func showConversation(cfg *config.Config) error {
 		return errs.Wrap(err, "There was an error loading the conversation.")
 	}
 
-	out := proto.Conversation(messages).String()
+	if cfg.ShowJSON {
+		return writeConversationJSON(os.Stdout, found, messages)
+	}
+
+	out := proto.Conversation(messages).Transcript(cfg.ShowVerbose)
 	if present.IsOutputTTY() && !cfg.Raw {
 		formatted, err := present.RenderMarkdownForTTY(out, cfg.WordWrap)
 		if err == nil {
@@ -323,7 +520,7 @@ func prefixFromEditor(appName string) (string, error) {
 		return "", fmt.Errorf("could not create temporary file: %w", err)
 	}
 	_ = f.Close()
-	defer func() { _ = os.Remove(f.Name()) }() //nolint:gosec // G703: path from os.CreateTemp, not user input
+	defer func() { _ = robustio.Remove(f.Name()) }() //nolint:gosec // G703: path from os.CreateTemp, not user input
 
 	c, err := editor.Cmd(
 		appName,
@@ -395,22 +592,9 @@ func promptForAPIAndModel(cfg *config.Config) error {
 			return cfg.Prefix != ""
 		}),
 	).
-		WithTheme(themeFrom(cfg.Theme)).
+		WithTheme(formTheme()).
 		Run(); err != nil {
 		return fmt.Errorf("prompt form: %w", err)
 	}
 	return nil
 }
-
-func themeFrom(theme string) *huh.Theme {
-	switch theme {
-	case "dracula":
-		return huh.ThemeDracula()
-	case "catppuccin":
-		return huh.ThemeCatppuccin()
-	case "base16":
-		return huh.ThemeBase16()
-	default:
-		return huh.ThemeCharm()
-	}
-}
@@ -1,6 +1,9 @@
 package cmd
 
 import (
+	"cmp"
+
+	"github.com/dotcommander/yai/internal/agent"
 	"github.com/dotcommander/yai/internal/config"
 	"github.com/dotcommander/yai/internal/present"
 	"github.com/spf13/cobra"
@@ -12,15 +15,32 @@ func initRootFlags(cmd *cobra.Command, cfg *config.Config) {
 	flags := cmd.Flags()
 	s := present.StdoutStyles().FlagDesc
 
+	// Flags of every command. --config and --workspace were read before
+	// the settings loaded; they are declared here so the flag parser
+	// accepts them.
+	var settingsPath, workspace string
+	cmd.PersistentFlags().StringVar(&settingsPath, "config", "", s.Render(helpText["config"]))
+	_ = cmd.MarkPersistentFlagFilename("config", "yml", "yaml")
+	cmd.PersistentFlags().StringVar(&workspace, "workspace", "", s.Render(helpText["workspace"]))
+	_ = cmd.RegisterFlagCompletionFunc("workspace", completeWorkspaces(cfg))
+	cmd.PersistentFlags().StringVar(&cfg.Color, "color", cmp.Or(cfg.Color, present.ColorAuto), s.Render(helpText["color"]))
+	_ = cmd.RegisterFlagCompletionFunc("color", cobra.FixedCompletions(
+		[]string{present.ColorAuto, present.ColorAlways, present.ColorNever},
+		cobra.ShellCompDirectiveNoFileComp,
+	))
+
 	// Root-only flags.
 	flags.BoolVarP(&cfg.AskModel, "ask-model", "M", cfg.AskModel, s.Render(helpText["ask-model"]))
 	flags.IntVarP(&cfg.IncludePrompt, "prompt", "P", cfg.IncludePrompt, s.Render(helpText["prompt"]))
 	flags.BoolVarP(&cfg.IncludePromptArgs, "prompt-args", "p", cfg.IncludePromptArgs, s.Render(helpText["prompt-args"]))
+	flags.StringVar(&cfg.PromptFile, "prompt-file", "", s.Render(helpText["prompt-file"]))
+	flags.StringArrayVar(&cfg.PromptVars, "var", nil, s.Render(helpText["var"]))
 	flags.BoolVarP(&cfg.List, "list", "l", cfg.List, s.Render(helpText["list"]))
 	flags.StringArrayVarP(&cfg.Delete, "delete", "d", cfg.Delete, s.Render(helpText["delete"]))
 	flags.Var(newDurationFlag(cfg.DeleteOlderThan, &cfg.DeleteOlderThan), "delete-older-than", s.Render(helpText["delete-older-than"]))
 	flags.StringVarP(&cfg.Show, "show", "s", cfg.Show, s.Render(helpText["show"]))
 	flags.BoolVarP(&cfg.ShowLast, "show-last", "S", false, s.Render(helpText["show-last"]))
+	flags.BoolVar(&cfg.Resume, "resume", false, s.Render(helpText["resume"]))
 	flags.BoolVarP(&cfg.ShowHelp, "help", "h", false, s.Render(helpText["help"]))
 	flags.BoolVarP(&cfg.Version, "version", "v", false, s.Render(helpText["version"]))
 	flags.BoolVar(&cfg.ResetSettings, "reset-settings", cfg.ResetSettings, s.Render(helpText["reset-settings"]))
@@ -28,10 +48,20 @@ func initRootFlags(cmd *cobra.Command, cfg *config.Config) {
 	flags.BoolVar(&cfg.Dirs, "dirs", false, s.Render(helpText["dirs"]))
 	flags.BoolVar(&cfg.ListRoles, "list-roles", cfg.ListRoles, s.Render(helpText["list-roles"]))
 	flags.BoolVar(&cfg.Patch, "patch", false, s.Render(helpText["patch"]))
+	flags.StringVar(&cfg.Transcribe, "transcribe", "", s.Render(helpText["transcribe"]))
 	flags.BoolVarP(&cfg.OpenEditor, "editor", "e", false, s.Render(helpText["editor"]))
 	flags.BoolVar(&cfg.MCPList, "mcp-list", false, s.Render(helpText["mcp-list"]))
 	flags.BoolVar(&cfg.MCPListTools, "mcp-list-tools", false, s.Render(helpText["mcp-list-tools"]))
 	flags.BoolVar(&cfg.MCPAllowNonTTY, "mcp-allow-non-tty", cfg.MCPAllowNonTTY, s.Render(helpText["mcp-allow-non-tty"]))
+	flags.BoolVar(&cfg.NoDaemon, "no-daemon", false, s.Render(helpText["no-daemon"]))
+	flags.BoolVar(&cfg.FailOnInterrupt, "fail-on-interrupt", cfg.FailOnInterrupt, s.Render(helpText["fail-on-interrupt"]))
+	flags.BoolVar(&cfg.DryRun, "dry-run", false, s.Render(helpText["dry-run"]))
+	flags.StringVar(&cfg.OutputFormat, "output-format", outputFormatText, s.Render(helpText["output-format"]))
+	flags.StringArrayVar(&cfg.Sinks, "sink", nil, s.Render(helpText["sink"]))
+	flags.Int64Var(&cfg.Logprobs, "logprobs", 0, s.Render(helpText["logprobs"]))
+	flags.StringArrayVar(&cfg.Postprocess, "postprocess", cfg.Postprocess, s.Render(helpText["postprocess"]))
+	flags.IntVar(&cfg.ChunkSize, "chunk-size", cfg.ChunkSize, s.Render(helpText["chunk-size"]))
+	flags.StringVar(&cfg.ChunkStrategy, "chunk-strategy", cfg.ChunkStrategy, s.Render(helpText["chunk-strategy"]))
 	flags.Lookup("prompt").NoOptDefVal = "-1"
 	flags.SortFlags = false
 
@@ -40,6 +70,18 @@ func initRootFlags(cmd *cobra.Command, cfg *config.Config) {
 
 	// Shell completions for show/delete IDs (continue + role already registered by registerSharedFlags).
 	registerConversationCompletion(cmd, cfg, "show", "delete")
+	_ = cmd.RegisterFlagCompletionFunc("output-format", cobra.FixedCompletions(
+		[]string{outputFormatText, outputFormatJSONL},
+		cobra.ShellCompDirectiveNoFileComp,
+	))
+	_ = cmd.RegisterFlagCompletionFunc("postprocess", cobra.FixedCompletions(
+		present.PostprocessorNames(),
+		cobra.ShellCompDirectiveNoFileComp,
+	))
+	_ = cmd.RegisterFlagCompletionFunc("chunk-strategy", cobra.FixedCompletions(
+		agent.ChunkStrategies,
+		cobra.ShellCompDirectiveNoFileComp,
+	))
 
 	cmd.MarkFlagsMutuallyExclusive(
 		"settings",
@@ -0,0 +1,367 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	imcp "github.com/dotcommander/yai/internal/mcp"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultServeAddr = "localhost:8080"
+
+	// maxServeRequestBytes caps the size of a completion request body.
+	maxServeRequestBytes = 16 << 20
+)
+
+// serveRequest is the body of POST /v1/completions.
+type serveRequest struct {
+	Prompt   string        `json:"prompt"`
+	Messages []messageJSON `json:"messages,omitempty"`
+	API      string        `json:"api,omitempty"`
+	Model    string        `json:"model,omitempty"`
+	Role     string        `json:"role,omitempty"`
+	Stream   bool          `json:"stream,omitempty"`
+
+	// Flags are command-line flags, as --name=value, applied to the
+	// server's settings for this request. Only the daemon accepts them,
+	// since they come from a client running as the same user.
+	Flags []string `json:"flags,omitempty"`
+}
+
+// serveResponse is the non-streaming response, and the data of the final
+// "done" event when streaming.
+type serveResponse struct {
+	Response     string          `json:"response"`
+	API          string          `json:"api"`
+	Model        string          `json:"model"`
+	Usage        proto.UsageJSON `json:"usage"`
+	Messages     []messageJSON   `json:"messages"`
+	Retries      int             `json:"retries,omitempty"`
+	Warnings     []string        `json:"warnings,omitempty"`
+	FallbackFrom string          `json:"fallback_from,omitempty"`
+	RequestID    string          `json:"request_id,omitempty"`
+	ModelVersion string          `json:"model_version,omitempty"`
+}
+
+type serveError struct {
+	Error string `json:"error"`
+}
+
+type serveChunk struct {
+	Content string `json:"content"`
+}
+
+type serveToolCall struct {
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"`
+}
+
+// completeFunc runs a completion with cfg; it is replaced in tests.
+type completeFunc func(ctx context.Context, cfg *config.Config, history []proto.Message, prompt string, onEvent func(agent.Event)) (agent.Completion, error)
+
+type server struct {
+	cfg      config.Config
+	complete completeFunc
+	// trusted allows requests to carry flags.
+	trusted bool
+}
+
+func newServeCmd(rt *runtime) *cobra.Command {
+	addr := defaultServeAddr
+	var mcpTools bool
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve completions over HTTP",
+		Long: "Serve completions over HTTP for web UIs and other integrations.\n" +
+			"POST a JSON object with a \"prompt\" to /v1/completions. Requests that set \"stream\" or\n" +
+			"accept text/event-stream receive Server-Sent Events as the response is generated.\n" +
+			"MCP tools are off unless --mcp-tools is given, since anything that can reach the address can use them.",
+		Example: `  yai serve --addr localhost:8080
+  curl -N localhost:8080/v1/completions -H 'Content-Type: application/json' -d '{"prompt": "hello", "stream": true}'`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			if !mcpTools {
+				rt.cfg.MCPDisable = []string{"*"}
+			}
+			return rt.runServe(ctx, addr)
+		},
+	}
+
+	flags := cmd.Flags()
+	s := present.StdoutStyles().FlagDesc
+	flags.StringVar(&addr, "addr", addr, s.Render(helpText["serve-addr"]))
+	flags.BoolVar(&mcpTools, "mcp-tools", false, s.Render(helpText["serve-mcp-tools"]))
+	flags.StringVarP(&rt.cfg.Model, "model", "m", rt.cfg.Model, s.Render(helpText["model"]))
+	flags.StringVarP(&rt.cfg.API, "api", "a", rt.cfg.API, s.Render(helpText["api"]))
+	flags.StringVarP(&rt.cfg.Role, "role", "R", rt.cfg.Role, s.Render(helpText["role"]))
+	flags.IntVar(&rt.cfg.MaxRetries, "max-retries", rt.cfg.MaxRetries, s.Render(helpText["max-retries"]))
+	flags.Var(newDurationFlag(rt.cfg.RequestTimeout, &rt.cfg.RequestTimeout), "request-timeout", s.Render(helpText["request-timeout"]))
+	flags.Var(newDurationFlag(rt.cfg.ConnectTimeout, &rt.cfg.ConnectTimeout), "connect-timeout", s.Render(helpText["connect-timeout"]))
+	flags.Var(newDurationFlag(rt.cfg.FirstTokenTimeout, &rt.cfg.FirstTokenTimeout), "first-token-timeout", s.Render(helpText["first-token-timeout"]))
+	flags.Var(newDurationFlag(rt.cfg.IdleTimeout, &rt.cfg.IdleTimeout), "idle-timeout", s.Render(helpText["idle-timeout"]))
+	flags.SortFlags = false
+	_ = cmd.RegisterFlagCompletionFunc("model", completeModels(&rt.cfg))
+	_ = cmd.RegisterFlagCompletionFunc("api", completeAPIs(&rt.cfg))
+	_ = cmd.RegisterFlagCompletionFunc("role", completeRoles(&rt.cfg))
+
+	return cmd
+}
+
+func (rt *runtime) runServe(ctx context.Context, addr string) error {
+	mcpSvc := imcp.New(&rt.cfg)
+	defer mcpSvc.Close()
+
+	srv := &server{
+		cfg: rt.cfg,
+		complete: func(ctx context.Context, cfg *config.Config, history []proto.Message, prompt string, onEvent func(agent.Event)) (agent.Completion, error) {
+			return agent.New(cfg, nil, mcpSvc).CompleteStream(ctx, history, prompt, onEvent)
+		},
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errs.Wrap(err, "Could not start the server.")
+	}
+	fmt.Fprintf(os.Stderr, "Serving completions on http://%s/v1/completions\n", ln.Addr())
+	return serveHTTP(ctx, ln, srv.handler())
+}
+
+// serveHTTP serves handler on ln until ctx is canceled, then shuts down
+// gracefully.
+func serveHTTP(ctx context.Context, ln net.Listener, handler http.Handler) error {
+	httpSrv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- httpSrv.Serve(ln) }()
+
+	select {
+	case err := <-errc:
+		return errs.Wrap(err, "The server stopped.")
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpSrv.Shutdown(shutdownCtx)
+		return nil
+	}
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/completions", s.handleCompletion)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+func (s *server) handleCompletion(w http.ResponseWriter, r *http.Request) {
+	// Browsers send text/plain and form posts to any origin without asking;
+	// JSON from another origin needs a preflight, which is never answered.
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeServeJSON(w, http.StatusUnsupportedMediaType, serveError{Error: "Content-Type must be application/json"})
+		return
+	}
+	var req serveRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxServeRequestBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeServeJSON(w, http.StatusBadRequest, serveError{Error: "invalid request: " + err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.Prompt) == "" {
+		writeServeJSON(w, http.StatusBadRequest, serveError{Error: "missing prompt"})
+		return
+	}
+
+	// Each request gets its own config copy: model resolution and fallback
+	// retries mutate the config, and requests run concurrently.
+	cfg := s.cfg
+	if len(req.Flags) > 0 {
+		if !s.trusted {
+			writeServeJSON(w, http.StatusBadRequest, serveError{Error: "flags are only accepted by yai daemon"})
+			return
+		}
+		if err := parseDaemonFlags(&cfg, req.Flags); err != nil {
+			writeServeJSON(w, http.StatusBadRequest, serveError{Error: "invalid flags: " + err.Error()})
+			return
+		}
+	}
+	cfg.NoCache = true
+	if req.API != "" {
+		cfg.API = req.API
+	}
+	if req.Model != "" {
+		cfg.Model = req.Model
+	}
+	if req.Role != "" {
+		cfg.Role = req.Role
+	}
+	history := protoMessages(req.Messages)
+	// yai daemon's client checks the budget and records the answer itself.
+	if !s.trusted {
+		if err := checkBudget(&cfg); err != nil {
+			writeServeJSON(w, http.StatusTooManyRequests, serveError{Error: batchErrorText(err)})
+			return
+		}
+	}
+	ctx := agent.WithSpend(r.Context(), s.spend())
+
+	if req.Stream || strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		s.streamCompletion(ctx, w, &cfg, history, req.Prompt)
+		return
+	}
+
+	res, err := s.run(ctx, &cfg, history, req.Prompt, nil)
+	if err != nil {
+		writeServeJSON(w, http.StatusBadGateway, serveError{Error: batchErrorText(err)})
+		return
+	}
+	writeServeJSON(w, http.StatusOK, newServeResponse(&cfg, res))
+}
+
+// run completes prompt and records the outcome in the usage ledger, unless
+// the request came from yai daemon's client.
+func (s *server) run(ctx context.Context, cfg *config.Config, history []proto.Message, prompt string, onEvent func(agent.Event)) (agent.Completion, error) {
+	res, err := s.complete(ctx, cfg, history, prompt, onEvent)
+	if s.trusted {
+		return res, err
+	}
+	ledger := s.ledgerConfig(cfg)
+	if err != nil {
+		recordFailure(ledger, err)
+	} else {
+		recordAnswer(ledger, res.Messages)
+	}
+	return res, err
+}
+
+// spend checks the budget for the requests a completion makes on its own
+// and records them in the usage ledger.
+func (s *server) spend() agent.Spend {
+	return agent.Spend{
+		Check: checkBudget,
+		Record: func(cfg *config.Config, messages []proto.Message) {
+			recordAnswer(s.ledgerConfig(cfg), messages)
+		},
+	}
+}
+
+// ledgerConfig is cfg as the usage ledger should see it: requests never
+// use the conversation cache, but are recorded unless the server was
+// started with --no-cache.
+func (s *server) ledgerConfig(cfg *config.Config) *config.Config {
+	ledger := *cfg
+	ledger.NoCache = s.cfg.NoCache
+	return &ledger
+}
+
+// streamCompletion answers with Server-Sent Events mirroring the completion
+// stream: "chunk" for content, "tool_call" for each tool run between steps,
+// "retry" when partial output should be discarded, and a final "done" or
+// "error".
+func (s *server) streamCompletion(ctx context.Context, w http.ResponseWriter, cfg *config.Config, history []proto.Message, prompt string) {
+	sse := newSSEWriter(w)
+	res, err := s.run(ctx, cfg, history, prompt, func(ev agent.Event) {
+		switch ev.Type {
+		case agent.EventChunk:
+			sse.send("chunk", serveChunk{Content: ev.Content})
+		case agent.EventToolCall:
+			call := serveToolCall{Name: ev.Tool.Name}
+			if ev.Tool.Err != nil {
+				call.Error = ev.Tool.Err.Error()
+			}
+			sse.send("tool_call", call)
+		case agent.EventRetry:
+			sse.send("retry", serveError{Error: batchErrorText(ev.Err)})
+		}
+	})
+	if err != nil {
+		sse.send("error", serveError{Error: batchErrorText(err)})
+		return
+	}
+	sse.send("done", newServeResponse(cfg, res))
+}
+
+func newServeResponse(cfg *config.Config, res agent.Completion) serveResponse {
+	return serveResponse{
+		Response:     res.Response,
+		API:          res.Model.API,
+		Model:        res.Model.Name,
+		Usage:        proto.NewUsageJSON(res.Usage),
+		Messages:     newMessagesJSON(res.Messages),
+		Retries:      res.Retries,
+		Warnings:     res.Warnings,
+		FallbackFrom: cfg.FallbackFrom,
+		RequestID:    res.Meta.RequestID,
+		ModelVersion: res.Meta.ModelVersion,
+	}
+}
+
+func writeServeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+}
+
+// sseWriter writes Server-Sent Events, flushing after each one. The headers
+// are written with the first event.
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	started bool
+}
+
+func newSSEWriter(w http.ResponseWriter) *sseWriter {
+	flusher, _ := w.(http.Flusher)
+	return &sseWriter{w: w, flusher: flusher}
+}
+
+func (s *sseWriter) send(event string, data any) {
+	if !s.started {
+		h := s.w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("X-Accel-Buffering", "no")
+		s.w.WriteHeader(http.StatusOK)
+		s.started = true
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		payload, _ = json.Marshal(serveError{Error: err.Error()})
+		event = "error"
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		// The client went away; the request context cancels the completion.
+		return
+	}
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+}
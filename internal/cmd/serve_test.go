@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeCompletions(t *testing.T) {
+	fake := func(_ context.Context, cfg *config.Config, history []proto.Message, prompt string, onEvent func(agent.Event)) (agent.Completion, error) {
+		if prompt == "fail" {
+			return agent.Completion{}, errors.New("boom")
+		}
+		if onEvent != nil {
+			onEvent(agent.Event{Type: agent.EventChunk, Content: "hel"})
+			onEvent(agent.Event{Type: agent.EventToolCall, Tool: proto.ToolCallStatus{Name: "fs_read", Err: errors.New("denied")}})
+			onEvent(agent.Event{Type: agent.EventChunk, Content: "lo"})
+		}
+		return agent.Completion{
+			Response: "hello",
+			Model:    config.Model{Name: cfg.Model, API: cfg.API},
+			Usage:    proto.Usage{TotalTokens: int64(len(history))},
+		}, nil
+	}
+	srv := httptest.NewServer((&server{
+		cfg:      config.Config{Settings: config.Settings{API: "openai", Model: "gpt-4.1"}},
+		complete: fake,
+	}).handler())
+	t.Cleanup(srv.Close)
+
+	post := func(t *testing.T, body string, header http.Header) (*http.Response, string) {
+		t.Helper()
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, srv.URL+"/v1/completions", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := srv.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint:errcheck
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(data)
+	}
+
+	t.Run("returns JSON by default", func(t *testing.T) {
+		resp, body := post(t, `{"prompt": "hi", "model": "o3", "messages": [{"role": "user", "content": "earlier"}]}`, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		require.JSONEq(t, `{
+			"response": "hello",
+			"api": "openai",
+			"model": "o3",
+			"usage": {"input_tokens": 0, "output_tokens": 0, "total_tokens": 1},
+			"messages": []
+		}`, body)
+	})
+
+	t.Run("streams server-sent events", func(t *testing.T) {
+		resp, body := post(t, `{"prompt": "hi", "stream": true}`, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		require.Equal(t, "event: chunk\ndata: {\"content\":\"hel\"}\n\n"+
+			"event: tool_call\ndata: {\"name\":\"fs_read\",\"error\":\"denied\"}\n\n"+
+			"event: chunk\ndata: {\"content\":\"lo\"}\n\n"+
+			"event: done\ndata: {\"response\":\"hello\",\"api\":\"openai\",\"model\":\"gpt-4.1\","+
+			"\"usage\":{\"input_tokens\":0,\"output_tokens\":0,\"total_tokens\":0},\"messages\":[]}\n\n", body)
+	})
+
+	t.Run("streams when the client accepts event streams", func(t *testing.T) {
+		resp, body := post(t, `{"prompt": "fail"}`, http.Header{"Accept": {"text/event-stream"}})
+		require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		require.Equal(t, "event: error\ndata: {\"error\":\"boom\"}\n\n", body)
+	})
+
+	t.Run("rejects other content types", func(t *testing.T) {
+		// What a web page can send to any origin without a preflight.
+		resp, body := post(t, `{"prompt": "hi"}`, http.Header{"Content-Type": {"text/plain"}})
+		require.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+		require.JSONEq(t, `{"error": "Content-Type must be application/json"}`, body)
+
+		resp, _ = post(t, `{"prompt": "hi"}`, http.Header{"Content-Type": {"application/json; charset=utf-8"}})
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		resp, body := post(t, `{"prompt": "  "}`, nil)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		require.JSONEq(t, `{"error": "missing prompt"}`, body)
+
+		resp, _ = post(t, `{"prompt": "hi", "temperature": 2}`, nil)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, body = post(t, `{"prompt": "hi", "flags": ["--model=o3"]}`, nil)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		require.JSONEq(t, `{"error": "flags are only accepted by yai daemon"}`, body)
+	})
+
+	t.Run("reports completion errors", func(t *testing.T) {
+		resp, body := post(t, `{"prompt": "fail"}`, nil)
+		require.Equal(t, http.StatusBadGateway, resp.StatusCode)
+		require.JSONEq(t, `{"error": "boom"}`, body)
+	})
+}
+
+func TestServeBudget(t *testing.T) {
+	cfg := fastestTestConfig(t)
+	cfg.MonthlyBudget = 1
+	calls := 0
+	srv := httptest.NewServer((&server{
+		cfg: *cfg,
+		complete: func(_ context.Context, cfg *config.Config, _ []proto.Message, prompt string, _ func(agent.Event)) (agent.Completion, error) {
+			calls++
+			return agent.Completion{Response: "hello", Messages: []proto.Message{
+				{Role: proto.RoleUser, Content: prompt},
+				{Role: proto.RoleAssistant, Content: "hello", Time: time.Now(), Latency: time.Second},
+			}}, nil
+		},
+	}).handler())
+	t.Cleanup(srv.Close)
+	post := func() int {
+		resp, err := srv.Client().Post(srv.URL+"/v1/completions", "application/json", strings.NewReader(`{"prompt": "hi"}`))
+		require.NoError(t, err)
+		resp.Body.Close() //nolint:errcheck
+		return resp.StatusCode
+	}
+
+	require.Equal(t, http.StatusOK, post())
+	entries, err := storage.OpenLedger(cfg.StateDir()).Since(time.Time{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "the answer is recorded")
+	require.Equal(t, "gpt-4o", entries[0].Model)
+
+	require.NoError(t, storage.OpenLedger(cfg.StateDir()).Append(storage.LedgerEntry{Time: time.Now(), API: "openai", Cost: 1.25}))
+	require.Equal(t, http.StatusTooManyRequests, post())
+	require.Equal(t, 1, calls, "nothing is sent over the budget")
+}
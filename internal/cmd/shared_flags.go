@@ -1,11 +1,17 @@
 package cmd
 
 import (
+	"maps"
 	"path/filepath"
+	"slices"
 
+	"github.com/dotcommander/yai/internal/agent"
 	"github.com/dotcommander/yai/internal/config"
+	imcp "github.com/dotcommander/yai/internal/mcp"
 	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/requestbuilder"
 	"github.com/dotcommander/yai/internal/storage"
+	"github.com/dotcommander/yai/internal/tui"
 	"github.com/spf13/cobra"
 )
 
@@ -17,36 +23,81 @@ func registerSharedFlags(cmd *cobra.Command, cfg *config.Config) {
 
 	flags.StringVarP(&cfg.Model, "model", "m", cfg.Model, s.Render(helpText["model"]))
 	flags.StringVarP(&cfg.API, "api", "a", cfg.API, s.Render(helpText["api"]))
+	flags.BoolVar(&cfg.Fastest, "fastest", cfg.Fastest, s.Render(helpText["fastest"]))
 	flags.StringVarP(&cfg.HTTPProxy, "http-proxy", "x", cfg.HTTPProxy, s.Render(helpText["http-proxy"]))
 	flags.BoolVarP(&cfg.Format, "format", "f", cfg.Format, s.Render(helpText["format"]))
 	flags.StringVar(&cfg.FormatAs, "format-as", cfg.FormatAs, s.Render(helpText["format-as"]))
+	flags.StringVar(&cfg.ReplyLanguage, "reply-language", cfg.ReplyLanguage, s.Render(helpText["reply-language"]))
 	flags.BoolVarP(&cfg.Raw, "raw", "r", cfg.Raw, s.Render(helpText["raw"]))
 	flags.BoolVarP(&cfg.Quiet, "quiet", "q", cfg.Quiet, s.Render(helpText["quiet"]))
 	flags.StringVarP(&cfg.Continue, "continue", "c", "", s.Render(helpText["continue"]))
 	flags.BoolVarP(&cfg.ContinueLast, "continue-last", "C", false, s.Render(helpText["continue-last"]))
+	flags.StringVar(&cfg.ContextStrategy, "context-strategy", cfg.ContextStrategy, s.Render(helpText["context-strategy"]))
 	flags.StringVarP(&cfg.Title, "title", "t", cfg.Title, s.Render(helpText["title"]))
 	flags.StringVarP(&cfg.Role, "role", "R", cfg.Role, s.Render(helpText["role"]))
+	flags.StringArrayVar(&cfg.Snippets, "snippet", nil, s.Render(helpText["snippet"]))
 	flags.BoolVar(&cfg.NoCache, "no-cache", cfg.NoCache, s.Render(helpText["no-cache"]))
 	flags.Int64Var(&cfg.MaxTokens, "max-tokens", cfg.MaxTokens, s.Render(helpText["max-tokens"]))
 	flags.Int64Var(&cfg.MaxCompletionTokens, "max-completion-tokens", cfg.MaxCompletionTokens, s.Render(helpText["max-completion-tokens"]))
 	flags.Float64Var(&cfg.Temperature, "temp", cfg.Temperature, s.Render(helpText["temp"]))
 	flags.Float64Var(&cfg.TopP, "topp", cfg.TopP, s.Render(helpText["topp"]))
 	flags.Int64Var(&cfg.TopK, "topk", cfg.TopK, s.Render(helpText["topk"]))
+	flags.Float64Var(&cfg.PresencePenalty, "presence-penalty", cfg.PresencePenalty, s.Render(helpText["presence-penalty"]))
+	flags.Float64Var(&cfg.FrequencyPenalty, "frequency-penalty", cfg.FrequencyPenalty, s.Render(helpText["frequency-penalty"]))
+	flags.Int64Var(&cfg.Seed, "seed", cfg.Seed, s.Render(helpText["seed"]))
+	flags.IntVar(&cfg.ThinkingBudget, "thinking-budget", cfg.ThinkingBudget, s.Render(helpText["thinking-budget"]))
 	flags.IntVar(&cfg.MaxRetries, "max-retries", cfg.MaxRetries, s.Render(helpText["max-retries"]))
 	flags.Var(newDurationFlag(cfg.RequestTimeout, &cfg.RequestTimeout), "request-timeout", s.Render(helpText["request-timeout"]))
+	flags.Var(newDurationFlag(cfg.ConnectTimeout, &cfg.ConnectTimeout), "connect-timeout", s.Render(helpText["connect-timeout"]))
+	flags.Var(newDurationFlag(cfg.FirstTokenTimeout, &cfg.FirstTokenTimeout), "first-token-timeout", s.Render(helpText["first-token-timeout"]))
+	flags.Var(newDurationFlag(cfg.IdleTimeout, &cfg.IdleTimeout), "idle-timeout", s.Render(helpText["idle-timeout"]))
 	flags.IntVar(&cfg.WordWrap, "word-wrap", cfg.WordWrap, s.Render(helpText["word-wrap"]))
 	flags.BoolVar(&cfg.NoLimit, "no-limit", cfg.NoLimit, s.Render(helpText["no-limit"]))
 	flags.StringArrayVar(&cfg.Stop, "stop", cfg.Stop, s.Render(helpText["stop"]))
 	flags.UintVar(&cfg.Fanciness, "fanciness", cfg.Fanciness, s.Render(helpText["fanciness"]))
 	flags.StringVar(&cfg.StatusText, "status-text", cfg.StatusText, s.Render(helpText["status-text"]))
+	flags.StringVar(&cfg.Spinner, "spinner", cfg.Spinner, s.Render(helpText["spinner"]))
 	flags.StringVar(&cfg.Theme, "theme", cfg.Theme, s.Render(helpText["theme"]))
+	flags.StringVar(&cfg.GlamourStyle, "glamour-style", cfg.GlamourStyle, s.Render(helpText["glamour-style"]))
+	flags.BoolVar(&cfg.Yes, "yes", cfg.Yes, s.Render(helpText["yes"]))
+	flags.BoolVar(&cfg.NoRedact, "no-redact", cfg.NoRedact, s.Render(helpText["no-redact"]))
+	flags.BoolVar(&cfg.IgnoreBudget, "ignore-budget", cfg.IgnoreBudget, s.Render(helpText["ignore-budget"]))
+	flags.StringVar(&cfg.Notify, "notify", cfg.Notify, s.Render(helpText["notify"]))
+	flags.Lookup("notify").NoOptDefVal = notifyBoth
+	flags.Var(newDurationFlag(cfg.NotifyAfter, &cfg.NotifyAfter), "notify-after", s.Render(helpText["notify-after"]))
 	flags.StringArrayVar(&cfg.MCPDisable, "mcp-disable", nil, s.Render(helpText["mcp-disable"]))
+	flags.StringArrayVar(&cfg.MCPOnly, "mcp-only", cfg.MCPOnly, s.Render(helpText["mcp-only"]))
+	flags.StringArrayVar(&cfg.MCPRoots, "mcp-root", cfg.MCPRoots, s.Render(helpText["mcp-root"]))
 	flags.BoolVar(&cfg.MCPNoInheritEnv, "mcp-no-inherit-env", cfg.MCPNoInheritEnv, s.Render(helpText["mcp-no-inherit-env"]))
+	flags.BoolVar(&cfg.MCPReadOnly, "mcp-read-only", cfg.MCPReadOnly, s.Render(helpText["mcp-read-only"]))
+	flags.StringVar(&cfg.MCPSampling, "mcp-sampling", cfg.MCPSampling, s.Render(helpText["mcp-sampling"]))
 
 	registerConversationCompletion(cmd, cfg, "continue")
-	_ = cmd.RegisterFlagCompletionFunc("role", func(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return roleNames(cfg, toComplete), cobra.ShellCompDirectiveDefault
+	_ = cmd.RegisterFlagCompletionFunc("model", completeModels(cfg))
+	_ = cmd.RegisterFlagCompletionFunc("api", completeAPIs(cfg))
+	_ = cmd.RegisterFlagCompletionFunc("role", completeRoles(cfg))
+	_ = cmd.RegisterFlagCompletionFunc("snippet", completeSnippets(cfg))
+	_ = cmd.RegisterFlagCompletionFunc("context-strategy", cobra.FixedCompletions(agent.ContextStrategies, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("mcp-only", func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		names := slices.Collect(maps.Keys(cfg.MCPServers))
+		slices.Sort(names)
+		return names, cobra.ShellCompDirectiveNoFileComp
 	})
+	_ = cmd.RegisterFlagCompletionFunc("mcp-root", func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveFilterDirs
+	})
+	_ = cmd.RegisterFlagCompletionFunc("glamour-style", func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return present.MarkdownStyleNames(), cobra.ShellCompDirectiveDefault
+	})
+	_ = cmd.RegisterFlagCompletionFunc("mcp-sampling", cobra.FixedCompletions(imcp.SamplingModes, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("reply-language", cobra.FixedCompletions(
+		append([]string{requestbuilder.ReplyLanguageAuto}, requestbuilder.Languages...), cobra.ShellCompDirectiveNoFileComp,
+	))
+	_ = cmd.RegisterFlagCompletionFunc("spinner", cobra.FixedCompletions(tui.SpinnerStyles, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("notify", cobra.FixedCompletions(
+		[]string{notifyBell, notifyDesktop, notifyBoth}, cobra.ShellCompDirectiveNoFileComp,
+	))
+	cmd.MarkFlagsMutuallyExclusive("api", "fastest")
 }
 
 // registerConversationCompletion registers shell-completion for flags that
@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"encoding/json"
 	"io"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/dotcommander/yai/internal/config"
 	"github.com/dotcommander/yai/internal/proto"
@@ -79,4 +81,70 @@ func TestShowConversation_Headless(t *testing.T) {
 		})
 		require.Equal(t, proto.Conversation(msgs2).String(), out)
 	})
+	t.Run("show as json", func(t *testing.T) {
+		c := cfg
+		c.Show = id1[:8]
+		c.ShowJSON = true
+		out := captureStdout(t, func() {
+			require.NoError(t, showConversation(&c))
+		})
+
+		var got map[string]any
+		require.NoError(t, json.Unmarshal([]byte(out), &got))
+		require.Equal(t, id1, got["id"])
+		require.Equal(t, "title-1", got["title"])
+		require.Equal(t, "gpt-4", got["model"])
+		require.Equal(t, []any{
+			map[string]any{"role": "user", "content": "first"},
+			map[string]any{"role": "assistant", "content": "one"},
+		}, got["messages"])
+	})
+}
+
+func TestShowConversation_Verbose(t *testing.T) {
+	store, tmpDir := newTestConversationStore(t)
+
+	cfg := config.Config{}
+	cfg.CachePath = tmpDir
+
+	at := time.Date(2026, 10, 16, 14, 3, 5, 0, time.UTC)
+	msgs := []proto.Message{
+		{Role: proto.RoleUser, Content: "first", Time: at},
+		{Role: proto.RoleAssistant, Content: "one", Time: at.Add(time.Second), Latency: 1500 * time.Millisecond, FirstToken: 300 * time.Millisecond},
+	}
+	id := storage.NewConversationID()
+	require.NoError(t, store.Cache.Write(id, &msgs))
+	require.NoError(t, store.DB.Save(id, "timed", "openai", "gpt-4"))
+
+	t.Run("text", func(t *testing.T) {
+		c := cfg
+		c.Show = id[:8]
+		c.ShowVerbose = true
+		out := captureStdout(t, func() {
+			require.NoError(t, showConversation(&c))
+		})
+		require.Equal(t, proto.Conversation(msgs).Transcript(true), out)
+		require.Contains(t, out, "1.5s · first token 300ms")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		c := cfg
+		c.Show = id[:8]
+		c.ShowJSON = true
+		out := captureStdout(t, func() {
+			require.NoError(t, showConversation(&c))
+		})
+
+		var got conversationJSON
+		require.NoError(t, json.Unmarshal([]byte(out), &got))
+		require.Equal(t, int64(1500), got.Messages[1].LatencyMS)
+		require.Equal(t, int64(300), got.Messages[1].FirstTokenMS)
+		require.Equal(t, msgs, protoMessages(got.Messages))
+	})
+}
+
+func TestToolArgumentsJSON(t *testing.T) {
+	require.Nil(t, toolArgumentsJSON(nil))
+	require.JSONEq(t, `{"path": "a"}`, string(toolArgumentsJSON([]byte(`{"path": "a"}`))))
+	require.JSONEq(t, `"not json"`, string(toolArgumentsJSON([]byte("not json"))))
 }
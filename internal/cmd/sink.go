@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/tui"
+)
+
+// sinkDialTimeout bounds how long connecting to a socket sink takes.
+const sinkDialTimeout = 5 * time.Second
+
+// sinkStdout is the target of a sink that writes to stdout.
+const sinkStdout = "-"
+
+// sinkSpec is a parsed --sink FORMAT:TARGET.
+type sinkSpec struct {
+	format string
+	target string
+}
+
+func parseSink(spec string) (sinkSpec, error) {
+	format, target, ok := strings.Cut(spec, ":")
+	if !ok || target == "" {
+		return sinkSpec{}, errs.UserErrorf("--sink must be FORMAT:TARGET, such as markdown:answer.md, got %q", spec)
+	}
+	if !slices.Contains(tui.SinkFormats, format) {
+		return sinkSpec{}, errs.UserErrorf("the format of --sink %s must be one of %s", spec, strings.Join(tui.SinkFormats, ", "))
+	}
+	return sinkSpec{format: format, target: target}, nil
+}
+
+// applySinks checks the --sink flags. A sink to stdout chooses what is
+// written there instead of being a copy: raw is --raw, jsonl is
+// --output-format jsonl, and markdown the rendered answer.
+func (rt *runtime) applySinks() error {
+	toStdout := ""
+	for _, spec := range rt.cfg.Sinks {
+		sink, err := parseSink(spec)
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+		if sink.target != sinkStdout {
+			continue
+		}
+		if toStdout != "" {
+			return fmt.Errorf("%w", errs.UserErrorf("only one --sink can write to stdout, got %s and %s", toStdout, spec))
+		}
+		toStdout = spec
+		switch sink.format {
+		case tui.SinkRaw:
+			rt.cfg.Raw = true
+		case tui.SinkMarkdown:
+		case tui.SinkJSONL:
+			rt.cfg.OutputFormat = outputFormatJSONL
+			continue
+		}
+		if rt.cfg.OutputFormat == outputFormatJSONL {
+			return fmt.Errorf("%w", errs.UserErrorf("--sink %s cannot be used with --output-format jsonl, which writes events to stdout", spec))
+		}
+	}
+	return nil
+}
+
+// openSinks opens the --sink targets other than stdout: a file, which is
+// replaced, or a socket given as unix:PATH or tcp:HOST:PORT.
+func openSinks(specs []string) ([]*tui.Sink, error) {
+	var sinks []*tui.Sink
+	for _, spec := range specs {
+		sink, err := parseSink(spec)
+		if err != nil {
+			closeSinks(sinks) //nolint:errcheck
+			return nil, fmt.Errorf("%w", err)
+		}
+		if sink.target == sinkStdout {
+			continue
+		}
+		w, err := openSinkTarget(sink.target)
+		if err != nil {
+			closeSinks(sinks) //nolint:errcheck
+			return nil, errs.Wrap(err, "Could not open --sink "+spec+".")
+		}
+		s, err := tui.NewSink(sink.format, w)
+		if err != nil {
+			w.Close()         //nolint:errcheck,gosec
+			closeSinks(sinks) //nolint:errcheck
+			return nil, errs.Wrap(err, "Could not open --sink "+spec+".")
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+func openSinkTarget(target string) (io.WriteCloser, error) {
+	for _, network := range []string{"unix", "tcp"} {
+		if addr, ok := strings.CutPrefix(target, network+":"); ok {
+			conn, err := net.DialTimeout(network, addr, sinkDialTimeout)
+			if err != nil {
+				return nil, fmt.Errorf("connect: %w", err)
+			}
+			return conn, nil
+		}
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644) //nolint:gosec // G302,G304: user-selected output file
+	if err != nil {
+		return nil, fmt.Errorf("create: %w", err)
+	}
+	return f, nil
+}
+
+// closeSinks writes out and closes the sinks, returning their errors.
+func closeSinks(sinks []*tui.Sink) error {
+	var errList []error
+	for _, s := range sinks {
+		errList = append(errList, s.Close())
+	}
+	return errors.Join(errList...)
+}
+
+// warnSinks reports sinks that could not be written to. The answer itself
+// was fine, so the run does not fail.
+func warnSinks(err error) {
+	if err == nil {
+		return
+	}
+	fmt.Fprintln(os.Stderr, present.StderrStyles().Comment.Render("Warning: could not write to every --sink: "+err.Error()))
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplySinks(t *testing.T) {
+	run := func(format string, specs ...string) (*runtime, error) {
+		rt := &runtime{cfg: config.Config{}}
+		rt.cfg.OutputFormat = format
+		rt.cfg.Sinks = specs
+		return rt, rt.applySinks()
+	}
+
+	rt, err := run(outputFormatText, "raw:-", "markdown:answer.md")
+	require.NoError(t, err)
+	require.True(t, rt.cfg.Raw)
+
+	rt, err = run(outputFormatText, "jsonl:-")
+	require.NoError(t, err)
+	require.Equal(t, outputFormatJSONL, rt.cfg.OutputFormat)
+
+	_, err = run(outputFormatText, "raw:-", "jsonl:-")
+	require.ErrorContains(t, err, "only one --sink can write to stdout")
+	_, err = run(outputFormatJSONL, "markdown:-")
+	require.ErrorContains(t, err, "cannot be used with --output-format jsonl")
+	_, err = run(outputFormatText, "answer.md")
+	require.ErrorContains(t, err, "--sink must be FORMAT:TARGET")
+	_, err = run(outputFormatText, "html:answer.html")
+	require.ErrorContains(t, err, "must be one of raw, markdown, jsonl")
+}
+
+func TestOpenSinks(t *testing.T) {
+	dir := t.TempDir()
+	socket := filepath.Join(dir, "events.sock")
+	ln, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() }) //nolint:errcheck,gosec
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		data, _ := io.ReadAll(conn)
+		received <- string(data)
+	}()
+
+	file := filepath.Join(dir, "answer.md")
+	sinks, err := openSinks([]string{"raw:-", "markdown:" + file, "jsonl:unix:" + socket})
+	require.NoError(t, err)
+	require.Len(t, sinks, 2, "stdout is not a sink of its own")
+	require.NoError(t, closeSinks(sinks))
+	require.Empty(t, <-received)
+	require.FileExists(t, file)
+
+	_, err = openSinks([]string{"jsonl:unix:" + filepath.Join(dir, "missing.sock")})
+	var reason errs.Error
+	require.ErrorAs(t, err, &reason)
+	require.Contains(t, reason.Reason, "Could not open --sink jsonl:unix:")
+}
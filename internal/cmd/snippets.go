@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/spf13/cobra"
+)
+
+// applySnippets puts the --snippet texts, in order, before the prompt.
+func (rt *runtime) applySnippets() error {
+	parts, err := readSnippets(&rt.cfg)
+	if err != nil || len(parts) == 0 {
+		return err
+	}
+	if rt.cfg.Prefix != "" {
+		parts = append(parts, rt.cfg.Prefix)
+	}
+	rt.cfg.Prefix = strings.Join(parts, "\n\n")
+	return nil
+}
+
+// readSnippets returns the texts of the --snippet names, in order.
+func readSnippets(cfg *config.Config) ([]string, error) {
+	texts := make([]string, 0, len(cfg.Snippets))
+	for _, name := range cfg.Snippets {
+		text, err := config.ReadSnippet(cfg, name)
+		if errors.Is(err, config.ErrUnknownSnippet) {
+			return nil, errs.Wrap(
+				errs.UserErrorf("Save it as %s.md or %s.txt in %s.", name, name, config.SnippetsDir(cfg)),
+				"Snippet "+name+" not found.",
+			)
+		}
+		if err != nil {
+			return nil, errs.Wrap(err, "Could not read snippet "+name+".")
+		}
+		texts = append(texts, text)
+	}
+	return texts, nil
+}
+
+// snippetNames returns the saved snippets starting with prefix, for shell
+// completion.
+func snippetNames(cfg *config.Config, prefix string) []string {
+	names, _ := config.ListSnippets(cfg)
+	out := names[:0]
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+func completeSnippets(cfg *config.Config) cobra.CompletionFunc {
+	return func(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return snippetNames(cfg, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplySnippets(t *testing.T) {
+	settings := filepath.Join(t.TempDir(), "yai.yml")
+	dir := filepath.Join(filepath.Dir(settings), "snippets")
+	require.NoError(t, os.MkdirAll(dir, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bug.md"), []byte("Steps to reproduce:\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "rubric.txt"), []byte("Rate 1-5."), 0o600))
+
+	t.Run("before the prompt", func(t *testing.T) {
+		rt := &runtime{cfg: config.Config{}}
+		rt.cfg.SettingsPath = settings
+		rt.cfg.Snippets = []string{"bug", "rubric"}
+		rt.cfg.Prefix = "the login page hangs"
+		require.NoError(t, rt.applySnippets())
+		require.Equal(t, "Steps to reproduce:\n\nRate 1-5.\n\nthe login page hangs", rt.cfg.Prefix)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		rt := &runtime{cfg: config.Config{}}
+		rt.cfg.SettingsPath = settings
+		rt.cfg.Snippets = []string{"nope"}
+		require.ErrorContains(t, rt.applySnippets(), "nope")
+	})
+}
+
+func TestFlagCompletion(t *testing.T) {
+	cfg := config.Config{}
+	cfg.SettingsPath = filepath.Join(t.TempDir(), "yai.yml")
+	cfg.Roles = map[string][]string{"shell": nil, "review": nil, "code/review": nil, "code/tests": nil, "code/go/lint": nil}
+	cfg.APIs = config.APIs{
+		{Name: "openai", Models: map[string]config.Model{"gpt-4o": {Aliases: []string{"4o"}}, "gpt-4o-mini": {}}},
+		{Name: "ollama", Models: map[string]config.Model{"llama3": {}}},
+	}
+	cfg.VirtualModels = map[string]config.VirtualModel{"smart": {Policy: config.PolicyFirstHealthy}}
+	dir := filepath.Join(filepath.Dir(cfg.SettingsPath), "snippets")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "review"), 0o700))
+	for _, name := range []string{"bug.md", "review/rubric.md"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("text"), 0o600))
+	}
+
+	root := NewRootCmd(BuildInfo{}, cfg, nil)
+	chat, _, err := root.Find([]string{"chat"})
+	require.NoError(t, err)
+
+	for _, cmd := range []*cobra.Command{root, chat} {
+		t.Run(cmd.Name(), func(t *testing.T) {
+			complete, ok := cmd.GetFlagCompletionFunc("snippet")
+			require.True(t, ok)
+			names, directive := complete(cmd, nil, "rev")
+			require.Equal(t, []string{"review/rubric"}, names)
+			require.Equal(t, cobra.ShellCompDirectiveNoFileComp, directive)
+
+			complete, ok = cmd.GetFlagCompletionFunc("role")
+			require.True(t, ok)
+			names, directive = complete(cmd, nil, "")
+			require.Equal(t, []string{"code/", "review", "shell"}, names)
+			require.Equal(t, cobra.ShellCompDirectiveNoFileComp, directive)
+			names, directive = complete(cmd, nil, "co")
+			require.Equal(t, []string{"code/"}, names)
+			require.Equal(t, cobra.ShellCompDirectiveNoFileComp|cobra.ShellCompDirectiveNoSpace, directive)
+			names, _ = complete(cmd, nil, "code/")
+			require.Equal(t, []string{"code/go/", "code/review", "code/tests"}, names)
+
+			complete, ok = cmd.GetFlagCompletionFunc("model")
+			require.True(t, ok)
+			names, _ = complete(cmd, nil, "")
+			require.Equal(t, []string{
+				"gpt-4o\topenai", "4o\tgpt-4o on openai", "gpt-4o-mini\topenai",
+				"llama3\tollama",
+				"smart\tvirtual model, first-healthy",
+			}, names)
+			names, _ = complete(cmd, nil, "gpt-4o-")
+			require.Equal(t, []string{"gpt-4o-mini\topenai"}, names)
+
+			complete, ok = cmd.GetFlagCompletionFunc("api")
+			require.True(t, ok)
+			names, _ = complete(cmd, nil, "o")
+			require.Equal(t, []string{"openai", "ollama"}, names)
+		})
+	}
+
+	t.Run("models of --api", func(t *testing.T) {
+		require.NoError(t, chat.Flags().Set("api", "ollama"))
+		complete, _ := chat.GetFlagCompletionFunc("model")
+		names, _ := complete(chat, nil, "")
+		require.Equal(t, []string{"llama3\tollama"}, names)
+	})
+}
@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+// usageMonths is how many months `yai usage --monthly` covers.
+const usageMonths = 12
+
+// usageReport is the JSON representation printed by `yai usage --json`.
+type usageReport struct {
+	// Since is the first day covered.
+	Since string  `json:"since"`
+	Spend float64 `json:"spend"`
+	// MonthSpend is the spend of the current month, which MonthlyBudget
+	// limits.
+	MonthSpend    float64       `json:"month_spend"`
+	MonthlyBudget float64       `json:"monthly_budget,omitempty"`
+	APIs          []apiSpend    `json:"apis"`
+	Periods       []periodSpend `json:"periods"`
+	// Estimated is set when the tokens of some requests were estimated
+	// from their text, as the provider reported none.
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+type apiSpend struct {
+	API          string  `json:"api"`
+	Requests     int     `json:"requests"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+}
+
+// periodSpend is the spend of a day, as 2026-10-16, or of a month, as
+// 2026-10, by API.
+type periodSpend struct {
+	Period string             `json:"period"`
+	Cost   float64            `json:"cost"`
+	APIs   map[string]float64 `json:"apis"`
+}
+
+func newUsageCmd(rt *runtime) *cobra.Command {
+	var monthly, asJSON bool
+	cmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Show the estimated spend per API, by day this month or by month",
+		Long: "Show the estimated spend per API from the usage ledger: by day this month, " +
+			"or by month over the last year with --monthly. Costs use the input-cost and " +
+			"output-cost of each model.",
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			now := time.Now()
+			since := monthStart(now)
+			if monthly {
+				since = since.AddDate(0, 1-usageMonths, 0)
+			}
+			entries, err := storage.OpenLedger(rt.cfg.StateDir()).Since(since)
+			if err != nil {
+				return errs.Wrap(err, "Could not read the usage ledger.")
+			}
+			report := collectUsage(entries, since, now, monthly)
+			report.MonthlyBudget = rt.cfg.MonthlyBudget
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(report); err != nil {
+					return errs.Wrap(err, "Could not write usage.")
+				}
+				return nil
+			}
+			printUsage(os.Stdout, present.StdoutStyles(), report, monthly)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&monthly, "monthly", false, "Show the spend by month over the last year")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the usage as JSON")
+	return cmd
+}
+
+// monthStart is the start of the local month of t.
+func monthStart(t time.Time) time.Time {
+	t = t.Local()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.Local)
+}
+
+// collectUsage sums the answered requests in entries, by API and by day or
+// month, in the local time zone.
+func collectUsage(entries []storage.LedgerEntry, since, now time.Time, monthly bool) usageReport {
+	report := usageReport{Since: since.Format(time.DateOnly), APIs: []apiSpend{}, Periods: []periodSpend{}}
+	apis := map[string]*apiSpend{}
+	periods := map[string]*periodSpend{}
+	thisMonth := monthStart(now)
+	layout := time.DateOnly
+	if monthly {
+		layout = "2006-01"
+	}
+	for _, e := range entries {
+		if e.Failed || e.Time.Before(since) {
+			continue
+		}
+		report.Spend += e.Cost
+		report.Estimated = report.Estimated || e.Estimated
+		if !e.Time.Before(thisMonth) {
+			report.MonthSpend += e.Cost
+		}
+
+		api, ok := apis[e.API]
+		if !ok {
+			api = &apiSpend{API: e.API}
+			apis[e.API] = api
+		}
+		api.Requests++
+		api.InputTokens += e.InputTokens
+		api.OutputTokens += e.OutputTokens
+		api.Cost += e.Cost
+
+		key := e.Time.Local().Format(layout)
+		period, ok := periods[key]
+		if !ok {
+			period = &periodSpend{Period: key, APIs: map[string]float64{}}
+			periods[key] = period
+		}
+		period.Cost += e.Cost
+		period.APIs[e.API] += e.Cost
+	}
+	for _, api := range apis {
+		report.APIs = append(report.APIs, *api)
+	}
+	slices.SortFunc(report.APIs, func(a, b apiSpend) int {
+		return cmp.Or(cmp.Compare(b.Cost, a.Cost), cmp.Compare(a.API, b.API))
+	})
+	for _, period := range periods {
+		report.Periods = append(report.Periods, *period)
+	}
+	slices.SortFunc(report.Periods, func(a, b periodSpend) int { return cmp.Compare(a.Period, b.Period) })
+	return report
+}
+
+func printUsage(w io.Writer, s present.Styles, report usageReport, monthly bool) {
+	row := func(label, value string) {
+		fmt.Fprintf(w, "%s %s\n", s.Comment.Render(fmt.Sprintf("%-16s", label)), value)
+	}
+	row("Since", report.Since)
+	row("Spend", formatDollars(report.Spend))
+	if report.MonthlyBudget > 0 {
+		row("Monthly budget", fmt.Sprintf("%s of %s (%d%%)",
+			formatDollars(report.MonthSpend), formatDollars(report.MonthlyBudget),
+			int(report.MonthSpend/report.MonthlyBudget*100)))
+	}
+	if len(report.APIs) == 0 {
+		fmt.Fprintln(w, "\n"+s.Comment.Render("No requests recorded yet."))
+		return
+	}
+
+	fmt.Fprintln(w, "\n"+s.AppName.Render("By API"))
+	for _, api := range report.APIs {
+		fmt.Fprintf(w, "  %-24s %10s %6d requests  %s in  %s out\n",
+			cmp.Or(api.API, "unknown"), formatDollars(api.Cost), api.Requests,
+			formatCount(api.InputTokens), formatCount(api.OutputTokens))
+	}
+
+	title := "By day"
+	if monthly {
+		title = "By month"
+	}
+	fmt.Fprintln(w, "\n"+s.AppName.Render(title))
+	for _, p := range report.Periods {
+		parts := make([]string, 0, len(p.APIs))
+		for _, api := range slices.Sorted(maps.Keys(p.APIs)) {
+			parts = append(parts, cmp.Or(api, "unknown")+" "+formatDollars(p.APIs[api]))
+		}
+		fmt.Fprintf(w, "  %-10s %10s  %s\n", p.Period, formatDollars(p.Cost), s.Comment.Render(strings.Join(parts, ", ")))
+	}
+
+	if report.Estimated {
+		fmt.Fprintln(w, "\n"+s.Comment.Render("Some providers reported no tokens; those requests are estimated from their text."))
+	}
+}
+
+func formatDollars(v float64) string {
+	if v > 0 && v < 0.01 {
+		return fmt.Sprintf("$%.4f", v)
+	}
+	return fmt.Sprintf("$%.2f", v)
+}
+
+// turnTokens returns the tokens of the last turn of msgs: the ones the
+// provider reported for the answers after the last prompt, or estimates from
+// the text when it reported none.
+func turnTokens(msgs []proto.Message) (input, output int64, estimated bool) {
+	start := len(msgs)
+	for start > 0 && msgs[start-1].Role != proto.RoleUser {
+		start--
+	}
+	var usage proto.Usage
+	for _, m := range msgs[start:] {
+		usage = usage.Add(m.Usage)
+	}
+	if usage.InputTokens > 0 || usage.OutputTokens > 0 {
+		return usage.InputTokens, usage.OutputTokens, false
+	}
+	return proto.EstimateTokens(msgs[:start]), proto.EstimateTokens(msgs[start:]), true
+}
+
+// requestCost is the price of a request to mod, in dollars.
+func requestCost(mod config.Model, input, output int64) float64 {
+	return (float64(input)*mod.InputCost + float64(output)*mod.OutputCost) / 1_000_000
+}
+
+// checkBudget refuses a request once this month's spend reached
+// monthly-budget, unless --ignore-budget was given. A ledger that cannot be
+// read does not block anything.
+func checkBudget(cfg *config.Config) error {
+	if cfg.MonthlyBudget <= 0 || cfg.IgnoreBudget {
+		return nil
+	}
+	now := time.Now()
+	entries, err := storage.OpenLedger(cfg.StateDir()).Since(monthStart(now))
+	if err != nil {
+		if !cfg.Quiet {
+			fmt.Fprintln(os.Stderr, present.StderrStyles().Comment.Render("Warning: could not read the usage ledger to check the budget: "+err.Error()))
+		}
+		return nil
+	}
+	spend := collectUsage(entries, monthStart(now), now, false).MonthSpend
+	if spend < cfg.MonthlyBudget {
+		return nil
+	}
+	return fmt.Errorf("%w", errs.UserErrorf(
+		"This month's spend, %s, reached the monthly budget of %s; pass --ignore-budget to send anyway",
+		formatDollars(spend), formatDollars(cfg.MonthlyBudget),
+	))
+}
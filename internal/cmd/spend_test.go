@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/present/presenttest"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTurnTokens(t *testing.T) {
+	msgs := []proto.Message{
+		{Role: proto.RoleUser, Content: "first"},
+		{Role: proto.RoleAssistant, Content: "old answer", Usage: proto.Usage{InputTokens: 1000, OutputTokens: 1000}},
+		{Role: proto.RoleUser, Content: "second"},
+		{Role: proto.RoleAssistant, Usage: proto.Usage{InputTokens: 100, OutputTokens: 10}},
+		{Role: proto.RoleTool, Content: "result"},
+		{Role: proto.RoleAssistant, Content: "done", Usage: proto.Usage{InputTokens: 150, OutputTokens: 20}},
+	}
+	in, out, estimated := turnTokens(msgs)
+	require.Equal(t, int64(250), in)
+	require.Equal(t, int64(30), out)
+	require.False(t, estimated)
+
+	in, out, estimated = turnTokens([]proto.Message{
+		{Role: proto.RoleUser, Content: "12345678"},
+		{Role: proto.RoleAssistant, Content: "1234"},
+	})
+	require.Equal(t, int64(2), in)
+	require.Equal(t, int64(1), out)
+	require.True(t, estimated)
+}
+
+func TestRecordAnswerCost(t *testing.T) {
+	cfg := fastestTestConfig(t)
+	cfg.APIs[0].Models["gpt-4o"] = config.Model{Aliases: []string{"4o"}, InputCost: 2.5, OutputCost: 10}
+	recordAnswer(cfg, []proto.Message{
+		{Role: proto.RoleUser, Content: "hi"},
+		{Role: proto.RoleAssistant, Content: "a", Time: time.Now(), Latency: time.Second, Usage: proto.Usage{InputTokens: 400_000, OutputTokens: 100_000}},
+	})
+	entries, err := storage.OpenLedger(cfg.CachePath).Since(time.Time{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, int64(400_000), entries[0].InputTokens)
+	require.InDelta(t, 2.0, entries[0].Cost, 1e-9)
+}
+
+func TestCollectUsage(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	entries := []storage.LedgerEntry{
+		{Time: now.AddDate(0, -1, 0), API: "openai", Cost: 5},
+		{Time: now.AddDate(0, 0, -1), API: "openai", Cost: 1.5, InputTokens: 100, OutputTokens: 10},
+		{Time: now, API: "anthropic", Cost: 2, Estimated: true},
+		{Time: now, API: "openai", Cost: 0.5, InputTokens: 50},
+		{Time: now, API: "openai", Failed: true},
+	}
+
+	daily := collectUsage(entries, monthStart(now), now, false)
+	require.Equal(t, "2026-10-01", daily.Since)
+	require.InDelta(t, 4.0, daily.Spend, 1e-9)
+	require.InDelta(t, 4.0, daily.MonthSpend, 1e-9)
+	require.True(t, daily.Estimated)
+	require.Equal(t, []apiSpend{
+		{API: "anthropic", Requests: 1, Cost: 2},
+		{API: "openai", Requests: 2, InputTokens: 150, OutputTokens: 10, Cost: 2},
+	}, daily.APIs, "ties by name")
+	require.Equal(t, []periodSpend{
+		{Period: "2026-10-15", Cost: 1.5, APIs: map[string]float64{"openai": 1.5}},
+		{Period: "2026-10-16", Cost: 2.5, APIs: map[string]float64{"anthropic": 2, "openai": 0.5}},
+	}, daily.Periods)
+
+	monthly := collectUsage(entries, monthStart(now).AddDate(0, -11, 0), now, true)
+	require.InDelta(t, 9.0, monthly.Spend, 1e-9)
+	require.InDelta(t, 4.0, monthly.MonthSpend, 1e-9)
+	require.Len(t, monthly.Periods, 2)
+	require.Equal(t, "2026-09", monthly.Periods[0].Period)
+
+	var buf bytes.Buffer
+	daily.MonthlyBudget = 10
+	printUsage(&buf, presenttest.Styles(), daily, false)
+	require.Contains(t, buf.String(), "$4.00 of $10.00 (40%)")
+	require.Contains(t, buf.String(), "anthropic $2.00, openai $0.50")
+}
+
+func TestCheckBudget(t *testing.T) {
+	cfg := fastestTestConfig(t)
+	require.NoError(t, checkBudget(cfg), "no budget")
+
+	cfg.MonthlyBudget = 1
+	require.NoError(t, checkBudget(cfg))
+	require.NoError(t, storage.OpenLedger(cfg.CachePath).Append(storage.LedgerEntry{Time: time.Now(), API: "openai", Cost: 1.25}))
+	require.ErrorContains(t, checkBudget(cfg), "$1.25, reached the monthly budget of $1.00")
+
+	cfg.IgnoreBudget = true
+	require.NoError(t, checkBudget(cfg))
+}
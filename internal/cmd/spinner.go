@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/tui"
+)
+
+// checkSpinner rejects an unknown --spinner or a --status-text that is not
+// a valid template before the request is made.
+func checkSpinner(cfg *config.Config) error {
+	if err := tui.CheckSpinner(cfg.Spinner, cfg.StatusText); err != nil {
+		return fmt.Errorf("%w", errs.UserErrorf("%s", err))
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Summary lengths of yai summarize.
+const (
+	summaryShort   = "short"
+	summaryBullets = "bullets"
+	summaryTLDR    = "tldr"
+)
+
+var summaryLengths = []string{summaryShort, summaryBullets, summaryTLDR}
+
+// summaryInstructions ask for each summary length.
+var summaryInstructions = map[string]string{
+	summaryShort:   "Summarize the following text in one short paragraph.",
+	summaryBullets: "Summarize the following text as a list of at most seven bullet points, the most important first.",
+	summaryTLDR:    "Summarize the following text in a single sentence.",
+}
+
+// summaryPartInstruction asks for the summary of one part of a text too
+// long to send at once.
+const summaryPartInstruction = "Summarize this part in detail, keeping names, numbers, decisions, and errors, " +
+	"so the summaries of all parts can be combined into one."
+
+type summarizeOptions struct {
+	length  string
+	chunked bool
+}
+
+func newSummarizeCmd(rt *runtime) *cobra.Command {
+	opts := summarizeOptions{length: summaryShort}
+	cmd := &cobra.Command{
+		Use:   "summarize [focus]",
+		Short: "Summarize text from stdin, in parts when it is too long for the model",
+		Long: "Summarize the text piped on stdin. The arguments, if any, say what to focus on. With --chunked, text\n" +
+			"longer than the model's max-input-chars is split into parts that are summarized one by one, and the\n" +
+			"summaries of the parts combined into one, instead of cutting the text off. --chunk-size, which\n" +
+			"implies --chunked, and --chunk-strategy choose the size of the parts and how they are combined.",
+		Example: `  git log -100 | yai summarize --length bullets
+  cat build.log | yai summarize --chunked "why did the build fail?"`,
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return rt.runSummarize(ctx, cmd.Flags(), args, opts)
+		},
+	}
+
+	registerSharedFlags(cmd, &rt.cfg)
+	flags := cmd.Flags()
+	s := present.StdoutStyles().FlagDesc
+	flags.StringVar(&opts.length, "length", opts.length, s.Render(helpText["summary-length"]))
+	flags.BoolVar(&opts.chunked, "chunked", false, s.Render(helpText["summary-chunked"]))
+	flags.IntVar(&rt.cfg.ChunkSize, "chunk-size", rt.cfg.ChunkSize, s.Render(helpText["chunk-size"]))
+	flags.StringVar(&rt.cfg.ChunkStrategy, "chunk-strategy", rt.cfg.ChunkStrategy, s.Render(helpText["chunk-strategy"]))
+	flags.SortFlags = false
+	_ = cmd.RegisterFlagCompletionFunc("length", cobra.FixedCompletions(summaryLengths, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("chunk-strategy", cobra.FixedCompletions(agent.ChunkStrategies, cobra.ShellCompDirectiveNoFileComp))
+	cmd.MarkFlagsMutuallyExclusive("continue", "continue-last")
+
+	return cmd
+}
+
+// summaryInstruction returns the instruction for length, with focus added.
+// "tl;dr" is accepted for tldr.
+func summaryInstruction(length, focus string) (string, error) {
+	length = strings.ReplaceAll(strings.ToLower(length), ";", "")
+	if !slices.Contains(summaryLengths, length) {
+		return "", errs.Wrap(
+			errs.UserErrorf("--length must be one of %s, got %q", strings.Join(summaryLengths, ", "), length),
+			"Unknown summary length.",
+		)
+	}
+	instruction := summaryInstructions[length]
+	if focus != "" {
+		instruction += " Focus on: " + focus
+	}
+	return instruction, nil
+}
+
+func (rt *runtime) runSummarize(ctx context.Context, flags *pflag.FlagSet, args []string, opts summarizeOptions) error {
+	instruction, err := summaryInstruction(opts.length, present.RemoveWhitespace(strings.Join(args, " ")))
+	if err != nil {
+		return err
+	}
+	if present.IsInputTTY() {
+		return errNothingToSummarize()
+	}
+	chunked := opts.chunked || rt.cfg.ChunkSize > 0
+	if chunked {
+		if err := rt.prepareChunks(); err != nil {
+			return err
+		}
+	}
+	rt.cfg.Prefix = instruction
+
+	store, err := rt.openAndPlanStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close() //nolint:errcheck
+
+	if err := validateCapabilities(flags, &rt.cfg); err != nil {
+		return err
+	}
+	if err := checkNotify(&rt.cfg); err != nil {
+		return err
+	}
+	if err := checkSpinner(&rt.cfg); err != nil {
+		return err
+	}
+	if err := checkBudget(&rt.cfg); err != nil {
+		return err
+	}
+	if err := rt.redactPrompt(); err != nil {
+		return err
+	}
+	if err := rt.confirmSend(store); err != nil {
+		return err
+	}
+	if chunked {
+		if err := rt.chunkInput(ctx, chunkSize(&rt.cfg), summaryPartInstruction, "Summarizing"); err != nil {
+			return err
+		}
+	}
+
+	yai, err := rt.runGenerateProgram(ctx, rt.programOptions(), store)
+	if err != nil {
+		return err
+	}
+	if yai.Input == "" {
+		return errNothingToSummarize()
+	}
+	rt.printGenerateOutput(yai)
+	printFallbackNote(&rt.cfg)
+	if err := saveConversation(&rt.cfg, store, yai.Messages()); err != nil {
+		return err
+	}
+	if yai.Canceled {
+		return rt.interruptError()
+	}
+	refreshTitle(ctx, &rt.cfg, store, yai.Messages(), 1)
+	return nil
+}
+
+func errNothingToSummarize() error {
+	return errs.Wrap(
+		errs.UserErrorf("Pipe the text on stdin.\nExample: %s",
+			present.StdoutStyles().InlineCode.Render("cat notes.md | yai summarize")),
+		"Nothing to summarize.",
+	)
+}
@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummaryInstruction(t *testing.T) {
+	got, err := summaryInstruction("tl;dr", "")
+	require.NoError(t, err)
+	require.Equal(t, summaryInstructions[summaryTLDR], got)
+
+	got, err = summaryInstruction("bullets", "the errors")
+	require.NoError(t, err)
+	require.Equal(t, summaryInstructions[summaryBullets]+" Focus on: the errors", got)
+
+	_, err = summaryInstruction("long", "")
+	require.ErrorContains(t, err, "--length must be one of short, bullets, tldr")
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/huh"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/muesli/termenv"
+)
+
+// themesDir is where theme files are kept, next to the settings file.
+func themesDir(cfg *config.Config) string {
+	if cfg.SettingsPath == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(cfg.SettingsPath), "themes")
+}
+
+// useTheme styles the output with the theme and markdown style of cfg. A
+// theme that cannot be loaded is reported, and the default one is used
+// instead; a markdown style that cannot be is reported and left out.
+func useTheme(cfg *config.Config) {
+	var warnings []string
+	t, err := present.LoadTheme(cfg.Theme, themesDir(cfg))
+	if err != nil {
+		warnings = append(warnings, err.Error())
+		t = present.Themes[present.DefaultTheme]
+	}
+	if err := present.CheckMarkdownStyle(t.Markdown); err != nil {
+		warnings = append(warnings, err.Error())
+		t.Markdown = ""
+	}
+	style := cfg.GlamourStyle
+	if err := present.CheckMarkdownStyle(style); err != nil {
+		warnings = append(warnings, err.Error())
+		style = ""
+	}
+	present.UseTheme(t)
+	present.UseMarkdownStyle(style)
+	if cfg.Quiet {
+		return
+	}
+	for _, w := range warnings {
+		fmt.Fprintln(os.Stderr, present.StderrStyles().Comment.Render("Warning: "+w))
+	}
+}
+
+// useColor applies the color mode of cfg, or auto when it is not valid.
+func useColor(cfg *config.Config) error {
+	if err := present.UseColor(cfg.Color); err != nil {
+		_ = present.UseColor(present.ColorAuto)
+		return fmt.Errorf("%w", errs.UserErrorf("%s", err))
+	}
+	return nil
+}
+
+// formTheme returns the form theme of the output theme, or the plain base
+// theme when colors are off (NO_COLOR or no terminal) so forms keep their
+// layout without color codes.
+func formTheme() *huh.Theme {
+	if present.StdoutRenderer().ColorProfile() == termenv.Ascii {
+		return huh.ThemeBase()
+	}
+	switch present.CurrentTheme().Form {
+	case "dracula":
+		return huh.ThemeDracula()
+	case "catppuccin":
+		return huh.ThemeCatppuccin()
+	case "base16":
+		return huh.ThemeBase16()
+	case "base":
+		return huh.ThemeBase()
+	default:
+		return huh.ThemeCharm()
+	}
+}
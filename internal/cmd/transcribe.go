@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/provider"
+	"github.com/dotcommander/yai/internal/storage"
+)
+
+// maxAudioBytes matches the upload limit of the OpenAI transcription API.
+const maxAudioBytes = 25 << 20
+
+// maybeTranscribe turns --transcribe audio into prompt text. The transcript
+// follows any prompt given as arguments, the same way stdin does. A dry run
+// reads the audio but does not send it.
+func (rt *runtime) maybeTranscribe(ctx context.Context) error {
+	if rt.cfg.Transcribe == "" {
+		return nil
+	}
+
+	audio, err := readAudio(rt.cfg.Transcribe, os.Stdin, !present.IsInputTTY())
+	if err != nil {
+		return err
+	}
+	if rt.cfg.DryRun {
+		return nil
+	}
+	if !rt.cfg.Quiet {
+		fmt.Fprintln(os.Stderr, present.StderrStyles().Comment.Render("Transcribing audio..."))
+	}
+	start := time.Now()
+	text, err := agent.New(&rt.cfg, nil, nil).Transcribe(ctx, audio)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	recordTranscription(&rt.cfg, start, text)
+	if strings.TrimSpace(text) == "" {
+		return errs.Wrap(errs.UserErrorf("The transcript is empty."), "Could not transcribe audio.")
+	}
+
+	if rt.cfg.Prefix == "" {
+		rt.cfg.Prefix = text
+	} else {
+		rt.cfg.Prefix += "\n\n" + text
+	}
+	return nil
+}
+
+// recordTranscription adds a transcription to the usage ledger, so it counts
+// toward monthly-budget. Settings have no price for audio: the transcript is
+// priced at the output-cost of the transcription model, when the API lists
+// it, from its estimated tokens.
+func recordTranscription(cfg *config.Config, start time.Time, text string) {
+	if cfg.NoCache {
+		return
+	}
+	model := cfg.TranscribeModel
+	if model == "" {
+		model = provider.DefaultTranscribeModel(cfg.API)
+	}
+	entry := storage.LedgerEntry{
+		Time:         start,
+		API:          cfg.API,
+		Model:        model,
+		LatencyMS:    time.Since(start).Milliseconds(),
+		OutputTokens: proto.EstimateTokens([]proto.Message{{Role: proto.RoleUser, Content: text}}),
+		Estimated:    true,
+	}
+	for _, api := range cfg.APIs {
+		if mod, ok := api.Models[model]; ok && api.Name == cfg.API {
+			entry.Cost = requestCost(mod, 0, entry.OutputTokens)
+		}
+	}
+	_ = storage.OpenLedger(cfg.StateDir()).Append(entry)
+}
+
+// readAudio reads audio from path, or from stdin when path is "-".
+func readAudio(path string, stdin io.Reader, hasStdin bool) (provider.Audio, error) {
+	var r io.Reader
+	name := path
+	if path == "-" {
+		if !hasStdin {
+			return provider.Audio{}, errs.Wrap(errs.UserErrorf("pipe audio into yai when using --transcribe -"), "No audio on stdin.")
+		}
+		r, name = stdin, ""
+	} else {
+		f, err := os.Open(path) //nolint:gosec // G304: user-selected audio file
+		if err != nil {
+			return provider.Audio{}, errs.Wrap(err, "Could not open audio file.")
+		}
+		defer f.Close() //nolint:errcheck
+		r = f
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxAudioBytes+1))
+	if err != nil {
+		return provider.Audio{}, errs.Wrap(err, "Could not read audio.")
+	}
+	if len(data) > maxAudioBytes {
+		return provider.Audio{}, errs.Wrap(errs.UserErrorf("audio must be at most %d MiB", maxAudioBytes>>20), "Audio is too large.")
+	}
+	if len(data) == 0 {
+		return provider.Audio{}, errs.Wrap(errs.UserErrorf("no audio data"), "Audio is empty.")
+	}
+	return provider.Audio{Data: data, Filename: name}, nil
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadAudio(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "clip.wav")
+		require.NoError(t, os.WriteFile(path, []byte("audio"), 0o600))
+
+		audio, err := readAudio(path, nil, false)
+		require.NoError(t, err)
+		require.Equal(t, "audio", string(audio.Data))
+		require.Equal(t, path, audio.Filename)
+	})
+
+	t.Run("stdin", func(t *testing.T) {
+		audio, err := readAudio("-", strings.NewReader("piped"), true)
+		require.NoError(t, err)
+		require.Equal(t, "piped", string(audio.Data))
+		require.Empty(t, audio.Filename)
+	})
+
+	t.Run("stdin is a TTY", func(t *testing.T) {
+		_, err := readAudio("-", strings.NewReader(""), false)
+		requireReason(t, err, "No audio on stdin.")
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		_, err := readAudio("-", strings.NewReader(""), true)
+		requireReason(t, err, "Audio is empty.")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := readAudio(filepath.Join(t.TempDir(), "nope.wav"), nil, false)
+		requireReason(t, err, "Could not open audio file.")
+	})
+}
+
+func requireReason(t *testing.T, err error, reason string) {
+	t.Helper()
+	var e errs.Error
+	require.True(t, errors.As(err, &e), "expected an errs.Error, got %v", err)
+	require.Equal(t, reason, e.ReasonText())
+}
+
+func TestRecordTranscription(t *testing.T) {
+	cfg := fastestTestConfig(t)
+	cfg.API = "openai"
+	cfg.APIs[0].Models["whisper-1"] = config.Model{OutputCost: 10}
+
+	recordTranscription(cfg, time.Now(), strings.Repeat("word ", 100))
+	entries, err := storage.OpenLedger(cfg.StateDir()).Since(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "openai", entries[0].API)
+	require.Equal(t, "whisper-1", entries[0].Model)
+	require.True(t, entries[0].Estimated)
+	require.Positive(t, entries[0].OutputTokens)
+	require.InDelta(t, requestCost(cfg.APIs[0].Models["whisper-1"], 0, entries[0].OutputTokens), entries[0].Cost, 1e-9)
+	require.Positive(t, entries[0].Cost)
+}
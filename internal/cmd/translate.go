@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/requestbuilder"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func newTranslateCmd(rt *runtime) *cobra.Command {
+	var to string
+	cmd := &cobra.Command{
+		Use:   "translate [file]",
+		Short: "Translate text from stdin or a file, keeping code and markdown as they are",
+		Long: "Translate the text piped on stdin, or the file given, into the language of --to with the built-in\n" +
+			"translate role. Code blocks, inline code, URLs, and the markdown structure are kept; only the\n" +
+			"translation is written. Define a role named translate in yai.yml to replace the instructions.",
+		Example: `  cat README.md | yai translate --to de > README.de.md
+  yai translate --to Japanese docs/setup.md`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return rt.runTranslate(ctx, cmd.Flags(), args, to)
+		},
+	}
+
+	registerSharedFlags(cmd, &rt.cfg)
+	flags := cmd.Flags()
+	flags.StringVar(&to, "to", "", present.StdoutStyles().FlagDesc.Render(helpText["translate-to"]))
+	flags.SortFlags = false
+	_ = cmd.MarkFlagRequired("to")
+	_ = cmd.RegisterFlagCompletionFunc("to", cobra.FixedCompletions(requestbuilder.Languages, cobra.ShellCompDirectiveNoFileComp))
+	cmd.MarkFlagsMutuallyExclusive("continue", "continue-last")
+
+	return cmd
+}
+
+// applyTranslate sets up the translate role, asking for answers in to.
+func (rt *runtime) applyTranslate(flags *pflag.FlagSet, to string) error {
+	to = strings.TrimSpace(to)
+	if to == "" || strings.EqualFold(to, requestbuilder.ReplyLanguageAuto) {
+		return errs.Wrap(
+			errs.UserErrorf("Name the language to translate into, such as %s.", present.StdoutStyles().InlineCode.Render("--to de")),
+			"No language to translate into.",
+		)
+	}
+	for _, name := range []string{"role", "format", "reply-language"} {
+		if flags.Changed(name) {
+			return errs.Wrap(errs.UserErrorf("--%s cannot be used with yai translate", name), "Conflicting flags.")
+		}
+	}
+	rt.cfg.Role = config.TranslateRole
+	rt.cfg.Format = false
+	rt.cfg.ReplyLanguage = to
+	return nil
+}
+
+// translateInput reads the file to translate, when one is given, in place
+// of stdin.
+func (rt *runtime) translateInput(args []string) error {
+	if len(args) == 0 {
+		if present.IsInputTTY() {
+			return errNothingToTranslate()
+		}
+		return nil
+	}
+	if !present.IsInputTTY() {
+		return errs.Wrap(errs.UserErrorf("Pipe the text on stdin or name a file, not both."), "Too much to translate.")
+	}
+	data, err := os.ReadFile(args[0]) //nolint:gosec // G304: user-selected input file
+	if err != nil {
+		return errs.Wrap(err, "Could not read the file to translate.")
+	}
+	rt.stdin = bytes.NewReader(data)
+	return nil
+}
+
+func (rt *runtime) runTranslate(ctx context.Context, flags *pflag.FlagSet, args []string, to string) error {
+	if err := rt.applyTranslate(flags, to); err != nil {
+		return err
+	}
+	if err := rt.translateInput(args); err != nil {
+		return err
+	}
+
+	store, err := rt.openAndPlanStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close() //nolint:errcheck
+
+	if err := validateCapabilities(flags, &rt.cfg); err != nil {
+		return err
+	}
+	if err := checkNotify(&rt.cfg); err != nil {
+		return err
+	}
+	if err := checkSpinner(&rt.cfg); err != nil {
+		return err
+	}
+	if err := checkBudget(&rt.cfg); err != nil {
+		return err
+	}
+	if err := rt.redactPrompt(); err != nil {
+		return err
+	}
+	if err := rt.confirmSend(store); err != nil {
+		return err
+	}
+
+	yai, err := rt.runGenerateProgram(ctx, rt.programOptions(), store)
+	if err != nil {
+		return err
+	}
+	if yai.Input == "" {
+		return errNothingToTranslate()
+	}
+	rt.printGenerateOutput(yai)
+	printFallbackNote(&rt.cfg)
+	if err := saveConversation(&rt.cfg, store, yai.Messages()); err != nil {
+		return err
+	}
+	if yai.Canceled {
+		return rt.interruptError()
+	}
+	refreshTitle(ctx, &rt.cfg, store, yai.Messages(), 1)
+	return nil
+}
+
+func errNothingToTranslate() error {
+	return errs.Wrap(
+		errs.UserErrorf("Pipe the text on stdin or name a file.\nExample: %s",
+			present.StdoutStyles().InlineCode.Render("cat README.md | yai translate --to de")),
+		"Nothing to translate.",
+	)
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyTranslate(t *testing.T) {
+	apply := func(args ...string) (*runtime, error) {
+		rt := &runtime{cfg: config.Default()}
+		rt.cfg.Format = true
+		rt.cfg.ReplyLanguage = "auto"
+		cmd := newTranslateCmd(rt)
+		require.NoError(t, cmd.ParseFlags(args))
+		to, err := cmd.Flags().GetString("to")
+		require.NoError(t, err)
+		return rt, rt.applyTranslate(cmd.Flags(), to)
+	}
+
+	rt, err := apply("--to", "de")
+	require.NoError(t, err)
+	require.Equal(t, config.TranslateRole, rt.cfg.Role)
+	require.Equal(t, "de", rt.cfg.ReplyLanguage, "the target wins over reply-language: auto")
+	require.False(t, rt.cfg.Format)
+
+	for _, args := range [][]string{{"--to", "auto"}, {"--to", " "}} {
+		_, err = apply(args...)
+		var reason errs.Error
+		require.ErrorAs(t, err, &reason)
+		require.Equal(t, "No language to translate into.", reason.Reason)
+	}
+
+	_, err = apply("--to", "de", "--role", "tldr")
+	require.ErrorContains(t, err, "--role cannot be used with yai translate")
+	_, err = apply("--to", "de", "--reply-language", "fr")
+	require.ErrorContains(t, err, "--reply-language cannot be used with yai translate")
+}
@@ -58,6 +58,9 @@ func usageFunc(cmd *cobra.Command) error {
 			cheapHighlighting(present.StdoutStyles(), examples[cmd.Example]),
 		)
 	}
+	if !cmd.HasParent() {
+		fmt.Print(exitCodesHelp(present.StdoutStyles()))
+	}
 
 	return nil
 }
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/storage"
+)
+
+// useVirtualModel switches cfg from a virtual model to the candidate its
+// policy picks, using what the usage ledger says about each. It does nothing
+// when the model is not virtual or an API was chosen.
+func useVirtualModel(cfg *config.Config) error {
+	vm, ok := cfg.VirtualModel(cfg.Model)
+	if !ok || cfg.API != "" {
+		return nil
+	}
+	policy := cmp.Or(vm.Policy, config.PolicyFirstHealthy)
+	switch policy {
+	case config.PolicyFirstHealthy, config.PolicyCheapest, config.PolicyFastest:
+	default:
+		return fmt.Errorf("%w", errs.UserErrorf(
+			"The policy of virtual model %s must be %q, %q, or %q, got %q",
+			cfg.Model, config.PolicyFirstHealthy, config.PolicyCheapest, config.PolicyFastest, vm.Policy,
+		))
+	}
+	routes := candidateRoutes(cfg, vm.Models)
+	if len(routes) == 0 {
+		return errs.Wrap(
+			errs.UserErrorf("Add models to it, or configure the ones it lists: yai --settings"),
+			fmt.Sprintf("Virtual model %s has no configured models.", cfg.Model),
+		)
+	}
+	now := time.Now()
+	// Without the ledger, every candidate counts as healthy and untried.
+	entries, _ := storage.OpenLedger(cfg.StateDir()).Since(now.Add(-fastestWindow))
+	best := pickRoute(cfg, policy, routeStats(routes, entries, now))
+
+	name := cfg.Model
+	cfg.API = best.API
+	cfg.Model = best.Model
+	if !cfg.Quiet && len(routes) > 1 {
+		fmt.Fprintln(os.Stderr, present.StderrStyles().Comment.Render(fmt.Sprintf(
+			"Using %s/%s for %s (%s)", best.API, best.Model, name, policy,
+		)))
+	}
+	return nil
+}
+
+// candidateRoutes lists the configured routes of the candidates, in order
+// and without repeats. A candidate without an API has a route on each API
+// that has the model.
+func candidateRoutes(cfg *config.Config, candidates []config.Candidate) []route {
+	var routes []route
+	for _, c := range candidates {
+		for _, r := range modelRoutes(cfg, c.Model) {
+			if (c.API == "" || r.API == c.API) && !slices.Contains(routes, r) {
+				routes = append(routes, r)
+			}
+		}
+	}
+	return routes
+}
+
+// pickRoute picks a route as policy asks, among those that are not failing.
+// When all are, the first is used.
+func pickRoute(cfg *config.Config, policy string, stats []routeStat) route {
+	healthy := slices.DeleteFunc(slices.Clone(stats), func(s routeStat) bool { return s.Failing })
+	if len(healthy) == 0 {
+		return stats[0].route
+	}
+	switch policy {
+	case config.PolicyFastest:
+		best, _ := fastestRoute(healthy)
+		return best.route
+	case config.PolicyCheapest:
+		slices.SortStableFunc(healthy, func(a, b routeStat) int {
+			return cmp.Compare(routePrice(cfg, a.route), routePrice(cfg, b.route))
+		})
+	}
+	return healthy[0].route
+}
+
+// routePrice is the input-cost plus output-cost of the route's model. A
+// model without prices counts as free.
+func routePrice(cfg *config.Config, r route) float64 {
+	for _, api := range cfg.APIs {
+		if api.Name == r.API {
+			mod := api.Models[r.Model]
+			return mod.InputCost + mod.OutputCost
+		}
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func virtualTestConfig(t *testing.T, policy string) *config.Config {
+	t.Helper()
+	cfg := fastestTestConfig(t)
+	cfg.APIs[1].Models["gpt-4o"] = config.Model{Aliases: []string{"4o"}, InputCost: 2, OutputCost: 8}
+	cfg.APIs[2].Models["claude-sonnet-4"] = config.Model{InputCost: 3, OutputCost: 15}
+	cfg.APIs[0].Models["gpt-4o"] = config.Model{Aliases: []string{"4o"}, InputCost: 2.5, OutputCost: 10}
+	cfg.VirtualModels = map[string]config.VirtualModel{
+		"smart": {Policy: policy, Models: []config.Candidate{
+			{API: "anthropic", Model: "claude-sonnet-4"},
+			{Model: "4o"},
+		}},
+	}
+	cfg.Model = "smart"
+	return cfg
+}
+
+func TestCandidateRoutes(t *testing.T) {
+	cfg := virtualTestConfig(t, "")
+	require.Equal(t, []route{
+		{API: "anthropic", Model: "claude-sonnet-4"},
+		{API: "openai", Model: "gpt-4o"},
+		{API: "azure", Model: "gpt-4o"},
+	}, candidateRoutes(cfg, append(cfg.VirtualModels["smart"].Models, config.Candidate{API: "azure", Model: "gpt-4o"})))
+	require.Empty(t, candidateRoutes(cfg, []config.Candidate{{API: "azure", Model: "claude-sonnet-4"}}))
+}
+
+func TestUseVirtualModel(t *testing.T) {
+	t.Run("first healthy", func(t *testing.T) {
+		cfg := virtualTestConfig(t, "")
+		require.NoError(t, useVirtualModel(cfg))
+		require.Equal(t, "anthropic", cfg.API)
+		require.Equal(t, "claude-sonnet-4", cfg.Model)
+
+		require.NoError(t, storage.OpenLedger(cfg.CachePath).Append(storage.LedgerEntry{
+			Time: time.Now(), API: "anthropic", Model: "claude-sonnet-4", Failed: true, Failure: "server",
+		}))
+		cfg.API, cfg.Model = "", "smart"
+		require.NoError(t, useVirtualModel(cfg))
+		require.Equal(t, "openai", cfg.API, "a failing candidate is passed over")
+	})
+
+	t.Run("cheapest", func(t *testing.T) {
+		cfg := virtualTestConfig(t, config.PolicyCheapest)
+		require.NoError(t, useVirtualModel(cfg))
+		require.Equal(t, "azure", cfg.API)
+		require.Equal(t, "gpt-4o", cfg.Model)
+	})
+
+	t.Run("fastest", func(t *testing.T) {
+		cfg := virtualTestConfig(t, config.PolicyFastest)
+		require.NoError(t, storage.OpenLedger(cfg.CachePath).Append(storage.LedgerEntry{
+			Time: time.Now(), API: "openai", Model: "gpt-4o", FirstTokenMS: 250, LatencyMS: 900,
+		}))
+		require.NoError(t, useVirtualModel(cfg))
+		require.Equal(t, "openai", cfg.API)
+	})
+
+	t.Run("an API was chosen", func(t *testing.T) {
+		cfg := virtualTestConfig(t, "")
+		cfg.API = "azure"
+		require.NoError(t, useVirtualModel(cfg))
+		require.Equal(t, "smart", cfg.Model)
+	})
+
+	t.Run("unknown policy", func(t *testing.T) {
+		cfg := virtualTestConfig(t, "random")
+		require.ErrorContains(t, useVirtualModel(cfg), `got "random"`)
+	})
+
+	t.Run("nothing configured", func(t *testing.T) {
+		cfg := virtualTestConfig(t, "")
+		cfg.VirtualModels["smart"] = config.VirtualModel{Models: []config.Candidate{{Model: "gpt-9"}}}
+		require.Error(t, useVirtualModel(cfg))
+	})
+}
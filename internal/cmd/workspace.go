@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/spf13/cobra"
+)
+
+func newWorkspaceCmd(rt *runtime) *cobra.Command {
+	workspaceCmd := &cobra.Command{
+		Use:   "workspace",
+		Short: "Keep conversations and settings apart, such as work and personal",
+		Long: "A workspace has its own conversations, knowledge indexes, and caches, in a directory of cache-path, " +
+			"and its own settings file in the workspaces directory next to yai.yml, whose keys override those of " +
+			"yai.yml. Choose one with --workspace or YAI_WORKSPACE for a command, or with `yai workspace use` for " +
+			"every command after. The usage ledger is shared, so monthly-budget covers them all.",
+		Args: cobra.NoArgs,
+	}
+	workspaceCmd.AddCommand(&cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the workspaces, marking the one in use",
+		Args:    cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			return listWorkspaces(os.Stdout, present.StdoutStyles(), &rt.cfg)
+		},
+	})
+	workspaceCmd.AddCommand(&cobra.Command{
+		Use:   "create NAME",
+		Short: "Create a workspace and its settings file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			return createWorkspace(os.Stdout, &rt.cfg, args[0])
+		},
+	})
+	workspaceCmd.AddCommand(&cobra.Command{
+		Use:               "use NAME",
+		Short:             "Use a workspace from now on; \"default\" goes back to none",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeWorkspaces(&rt.cfg),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if rt.cfgErr != nil {
+				return rt.cfgErr
+			}
+			return useWorkspace(os.Stdout, &rt.cfg, args[0])
+		},
+	})
+	return workspaceCmd
+}
+
+func listWorkspaces(w io.Writer, s present.Styles, cfg *config.Config) error {
+	names, err := config.ListWorkspaces(cfg)
+	if err != nil {
+		return errs.Wrap(err, "Could not list the workspaces.")
+	}
+	current := cmp.Or(cfg.Workspace, config.DefaultWorkspace)
+	for _, name := range names {
+		if name == current {
+			fmt.Fprintln(w, s.Flag.Render("* "+name))
+			continue
+		}
+		fmt.Fprintln(w, "  "+name)
+	}
+	return nil
+}
+
+func createWorkspace(w io.Writer, cfg *config.Config, name string) error {
+	created, err := config.CreateWorkspace(cfg, name)
+	if err != nil {
+		return errs.Wrap(err, "Could not create workspace "+name+".")
+	}
+	if !created {
+		fmt.Fprintf(w, "Workspace %s already exists.\n", name)
+		return nil
+	}
+	fmt.Fprintf(w, "Created workspace %s. Its settings are in %s.\n", name, config.WorkspaceSettingsPath(cfg, name))
+	return nil
+}
+
+// useWorkspace makes name the workspace of the commands that follow,
+// creating it when needed.
+func useWorkspace(w io.Writer, cfg *config.Config, name string) error {
+	if _, err := config.CreateWorkspace(cfg, name); err != nil {
+		return errs.Wrap(err, "Could not use workspace "+name+".")
+	}
+	if err := config.UseWorkspace(cfg, name); err != nil {
+		return errs.Wrap(err, "Could not use workspace "+name+".")
+	}
+	fmt.Fprintf(w, "Using workspace %s.\n", name)
+	if env := os.Getenv(config.WorkspaceEnv); env != "" && env != name {
+		fmt.Fprintln(os.Stderr, present.StderrStyles().Comment.Render(
+			fmt.Sprintf("Warning: %s=%s still chooses %s in this shell.", config.WorkspaceEnv, env, env),
+		))
+	}
+	return nil
+}
+
+func completeWorkspaces(cfg *config.Config) cobra.CompletionFunc {
+	return func(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		names, _ := config.ListWorkspaces(cfg)
+		out := names[:0]
+		for _, name := range names {
+			if strings.HasPrefix(name, toComplete) {
+				out = append(out, name)
+			}
+		}
+		return out, cobra.ShellCompDirectiveNoFileComp
+	}
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkspaceCommands(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(config.WorkspaceEnv, "")
+	cfg := &config.Config{Settings: config.Settings{CachePath: filepath.Join(dir, "cache")}}
+	cfg.SettingsPath = filepath.Join(dir, "yai.yml")
+
+	var buf bytes.Buffer
+	require.NoError(t, createWorkspace(&buf, cfg, "work"))
+	require.Contains(t, buf.String(), "Created workspace work.")
+	buf.Reset()
+	require.NoError(t, createWorkspace(&buf, cfg, "work"))
+	require.Equal(t, "Workspace work already exists.\n", buf.String())
+	require.Error(t, createWorkspace(&buf, cfg, "no/slashes"))
+
+	buf.Reset()
+	require.NoError(t, useWorkspace(&buf, cfg, "personal"))
+	require.Equal(t, "Using workspace personal.\n", buf.String())
+
+	cfg.Workspace = "personal"
+	buf.Reset()
+	require.NoError(t, listWorkspaces(&buf, present.StdoutStyles(), cfg))
+	require.Equal(t, "  default\n* personal\n  work\n", buf.String())
+}
@@ -1,13 +1,15 @@
 package config
 
 import (
-	"bytes"
+	"cmp"
 	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	stdstrings "strings"
+	"syscall"
 	"text/template"
 	"time"
 
@@ -28,6 +30,9 @@ var tldrRole string
 //go:embed patch_role.md
 var patchRole string
 
+//go:embed translate_role.md
+var translateRole string
+
 const (
 	defaultMarkdownFormatText = "Format the response as markdown without enclosing backticks."
 	defaultJSONFormatText     = "Format the response as json without enclosing backticks."
@@ -41,6 +46,14 @@ type Model struct {
 	Aliases        []string `yaml:"aliases"`
 	Fallback       string   `yaml:"fallback"`
 	ThinkingBudget int      `yaml:"thinking-budget,omitempty"`
+	// ContextWindow is how many tokens the model takes in a request,
+	// answer included. Requests are fitted into it by estimate.
+	ContextWindow int64 `yaml:"context-window,omitempty"`
+	// InputCost and OutputCost are the prices of a million input and
+	// output tokens, used to estimate what a request costs before it is
+	// sent and to track spend.
+	InputCost  float64 `yaml:"input-cost,omitempty"`
+	OutputCost float64 `yaml:"output-cost,omitempty"`
 }
 
 // API represents an API endpoint and its models.
@@ -49,10 +62,13 @@ type API struct {
 	APIKey    string           `yaml:"api-key"` //nolint:gosec // G117: config struct field required for YAML unmarshalling, not a hardcoded credential
 	APIKeyEnv string           `yaml:"api-key-env"`
 	APIKeyCmd string           `yaml:"api-key-cmd"`
-	Version   string           `yaml:"version"` // not used
+	Version   string           `yaml:"version"` // deprecated: not used
 	BaseURL   string           `yaml:"base-url"`
 	Models    map[string]Model `yaml:"models"`
 	User      string           `yaml:"user"`
+	// Plugin is the command line of a provider plugin that answers for
+	// this API; see package plugin.
+	Plugin string `yaml:"plugin"`
 }
 
 // APIs is a type alias to allow custom YAML decoding.
@@ -92,65 +108,202 @@ func (ft *FormatText) UnmarshalYAML(unmarshal func(any) error) error {
 	return nil
 }
 
+// Policies of a virtual model.
+const (
+	// PolicyFirstHealthy picks the first candidate whose last request did
+	// not fail recently.
+	PolicyFirstHealthy = "first-healthy"
+	// PolicyCheapest picks the healthy candidate with the lowest
+	// input-cost plus output-cost.
+	PolicyCheapest = "cheapest"
+	// PolicyFastest picks the healthy candidate with the quickest recent
+	// first token, as --fastest does.
+	PolicyFastest = "fastest"
+)
+
+// VirtualModel is a model name, such as smart or cheap, that stands for an
+// ordered list of configured models, one of which Policy picks for each
+// request.
+type VirtualModel struct {
+	Policy string      `yaml:"policy"`
+	Models []Candidate `yaml:"models"`
+}
+
+// Candidate is a model a virtual model may use: a model name or alias, on
+// the given API or, without one, on any API that has it.
+type Candidate struct {
+	API   string `yaml:"api"`
+	Model string `yaml:"model"`
+}
+
+// UnmarshalYAML also accepts a bare model name.
+func (c *Candidate) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		c.Model = node.Value
+		return nil
+	}
+	type plain Candidate
+	return node.Decode((*plain)(c))
+}
+
 // Settings holds persisted configuration loaded from the YAML settings file
 // and environment variables.
 type Settings struct {
-	API                 string              `yaml:"default-api" env:"API"`
-	Model               string              `yaml:"default-model" env:"MODEL"`
-	Format              bool                `yaml:"format" env:"FORMAT"`
-	FormatText          FormatText          `yaml:"format-text"`
-	FormatAs            string              `yaml:"format-as" env:"FORMAT_AS"`
-	Raw                 bool                `yaml:"raw" env:"RAW"`
-	Quiet               bool                `yaml:"quiet" env:"QUIET"`
-	MaxTokens           int64               `yaml:"max-tokens" env:"MAX_TOKENS"`
-	MaxCompletionTokens int64               `yaml:"max-completion-tokens" env:"MAX_COMPLETION_TOKENS"`
-	MaxInputChars       int64               `yaml:"max-input-chars" env:"MAX_INPUT_CHARS"`
-	MaxOutputBytes      int64               `yaml:"max-output-bytes" env:"MAX_OUTPUT_BYTES"`
-	Temperature         float64             `yaml:"temp" env:"TEMP"`
-	Stop                []string            `yaml:"stop" env:"STOP"`
-	TopP                float64             `yaml:"topp" env:"TOPP"`
-	TopK                int64               `yaml:"topk" env:"TOPK"`
-	NoLimit             bool                `yaml:"no-limit" env:"NO_LIMIT"`
-	CachePath           string              `yaml:"cache-path" env:"CACHE_PATH"`
-	NoCache             bool                `yaml:"no-cache" env:"NO_CACHE"`
-	IncludePromptArgs   bool                `yaml:"include-prompt-args" env:"INCLUDE_PROMPT_ARGS"`
-	IncludePrompt       int                 `yaml:"include-prompt" env:"INCLUDE_PROMPT"`
-	MaxRetries          int                 `yaml:"max-retries" env:"MAX_RETRIES"`
-	WordWrap            int                 `yaml:"word-wrap" env:"WORD_WRAP"`
-	Fanciness           uint                `yaml:"fanciness" env:"FANCINESS"`
-	StatusText          string              `yaml:"status-text" env:"STATUS_TEXT"`
-	HTTPProxy           string              `yaml:"http-proxy" env:"HTTP_PROXY"`
-	APIs                APIs                `yaml:"apis"`
-	System              string              `yaml:"system"`
-	Role                string              `yaml:"role" env:"ROLE"`
-	Theme               string              `yaml:"theme" env:"THEME"`
-	User                string              `yaml:"user" env:"USER"`
-	Roles               map[string][]string `yaml:"roles"`
-
-	MCPServers      map[string]MCPServerConfig `yaml:"mcp-servers"`
-	MCPDisable      []string                   `yaml:"mcp-disable" env:"MCP_DISABLE"`
-	MCPTimeout      time.Duration              `yaml:"mcp-timeout" env:"MCP_TIMEOUT"`
-	MCPAllowNonTTY  bool                       `yaml:"mcp-allow-non-tty" env:"MCP_ALLOW_NON_TTY"`
-	MCPNoInheritEnv bool                       `yaml:"mcp-no-inherit-env" env:"MCP_NO_INHERIT_ENV"`
-	RequestTimeout  time.Duration              `yaml:"request-timeout" env:"REQUEST_TIMEOUT"`
+	API                 string                  `yaml:"default-api" env:"API"`
+	Model               string                  `yaml:"default-model" env:"MODEL"`
+	Format              bool                    `yaml:"format" env:"FORMAT"`
+	FormatText          FormatText              `yaml:"format-text"`
+	FormatAs            string                  `yaml:"format-as" env:"FORMAT_AS"`
+	NoJSONMode          bool                    `yaml:"no-json-mode" env:"NO_JSON_MODE"`
+	ReplyLanguage       string                  `yaml:"reply-language" env:"REPLY_LANGUAGE"`
+	Raw                 bool                    `yaml:"raw" env:"RAW"`
+	Quiet               bool                    `yaml:"quiet" env:"QUIET"`
+	FailOnInterrupt     bool                    `yaml:"fail-on-interrupt" env:"FAIL_ON_INTERRUPT"`
+	MaxTokens           int64                   `yaml:"max-tokens" env:"MAX_TOKENS"`
+	MaxCompletionTokens int64                   `yaml:"max-completion-tokens" env:"MAX_COMPLETION_TOKENS"`
+	MaxInputChars       int64                   `yaml:"max-input-chars" env:"MAX_INPUT_CHARS"`
+	TruncateHead        float64                 `yaml:"truncate-head" env:"TRUNCATE_HEAD"`
+	ContextStrategy     string                  `yaml:"context-strategy" env:"CONTEXT_STRATEGY"`
+	MaxOutputBytes      int64                   `yaml:"max-output-bytes" env:"MAX_OUTPUT_BYTES"`
+	ChunkSize           int                     `yaml:"chunk-size" env:"CHUNK_SIZE"`
+	ChunkStrategy       string                  `yaml:"chunk-strategy" env:"CHUNK_STRATEGY"`
+	Temperature         float64                 `yaml:"temp" env:"TEMP"`
+	Stop                []string                `yaml:"stop" env:"STOP"`
+	TopP                float64                 `yaml:"topp" env:"TOPP"`
+	TopK                int64                   `yaml:"topk" env:"TOPK"`
+	ThinkingBudget      int                     `yaml:"thinking-budget" env:"THINKING_BUDGET"`
+	PresencePenalty     float64                 `yaml:"presence-penalty" env:"PRESENCE_PENALTY"`
+	FrequencyPenalty    float64                 `yaml:"frequency-penalty" env:"FREQUENCY_PENALTY"`
+	Seed                int64                   `yaml:"seed" env:"SEED"`
+	NoLimit             bool                    `yaml:"no-limit" env:"NO_LIMIT"`
+	CachePath           string                  `yaml:"cache-path" env:"CACHE_PATH"`
+	NoCache             bool                    `yaml:"no-cache" env:"NO_CACHE"`
+	IncludePromptArgs   bool                    `yaml:"include-prompt-args" env:"INCLUDE_PROMPT_ARGS"`
+	IncludePrompt       int                     `yaml:"include-prompt" env:"INCLUDE_PROMPT"`
+	MaxRetries          int                     `yaml:"max-retries" env:"MAX_RETRIES"`
+	WordWrap            int                     `yaml:"word-wrap" env:"WORD_WRAP"`
+	Fanciness           uint                    `yaml:"fanciness" env:"FANCINESS"`
+	StatusText          string                  `yaml:"status-text" env:"STATUS_TEXT"`
+	Spinner             string                  `yaml:"spinner" env:"SPINNER"`
+	HTTPProxy           string                  `yaml:"http-proxy" env:"HTTP_PROXY"`
+	APIs                APIs                    `yaml:"apis"`
+	VirtualModels       map[string]VirtualModel `yaml:"virtual-models"`
+	System              string                  `yaml:"system"` // deprecated: not used
+	Role                string                  `yaml:"role" env:"ROLE"`
+	Theme               string                  `yaml:"theme" env:"THEME"`
+	GlamourStyle        string                  `yaml:"glamour-style" env:"GLAMOUR_STYLE"`
+	Color               string                  `yaml:"color" env:"COLOR"`
+	Notify              string                  `yaml:"notify" env:"NOTIFY"`
+	NotifyAfter         time.Duration           `yaml:"notify-after" env:"NOTIFY_AFTER"`
+	User                string                  `yaml:"user" env:"USER"`
+	Roles               map[string][]string     `yaml:"roles"`
+	EmbedModel          string                  `yaml:"embed-model" env:"EMBED_MODEL"`
+	TranscribeModel     string                  `yaml:"transcribe-model" env:"TRANSCRIBE_MODEL"`
+	RoleCacheThreshold  int                     `yaml:"role-cache-threshold" env:"ROLE_CACHE_THRESHOLD"`
+	TitleRefreshTurns   int                     `yaml:"title-refresh-turns" env:"TITLE_REFRESH_TURNS"`
+	TitleRedaction      bool                    `yaml:"title-redaction" env:"TITLE_REDACTION"`
+	UtilityModel        string                  `yaml:"utility-model" env:"UTILITY_MODEL"`
+	DuplicateWindow     time.Duration           `yaml:"duplicate-window" env:"DUPLICATE_WINDOW"`
+	TrashRetention      time.Duration           `yaml:"trash-retention" env:"TRASH_RETENTION"`
+	ConfirmTokens       int64                   `yaml:"confirm-tokens" env:"CONFIRM_TOKENS"`
+	ConfirmCost         float64                 `yaml:"confirm-cost" env:"CONFIRM_COST"`
+	ConfirmModels       []string                `yaml:"confirm-models" env:"CONFIRM_MODELS"`
+	MonthlyBudget       float64                 `yaml:"monthly-budget" env:"MONTHLY_BUDGET"`
+	DaemonSocket        string                  `yaml:"daemon-socket" env:"DAEMON_SOCKET"`
+	DefaultCommand      string                  `yaml:"default-command" env:"DEFAULT_COMMAND"`
+	ChatTimings         bool                    `yaml:"chat-timings" env:"CHAT_TIMINGS"`
+	NoRedact            bool                    `yaml:"no-redact" env:"NO_REDACT"`
+	RedactBuiltin       []string                `yaml:"redact-builtin" env:"REDACT_BUILTIN"`
+	RedactPatterns      []string                `yaml:"redact-patterns"`
+	Postprocess         []string                `yaml:"postprocess" env:"POSTPROCESS"`
+	RolePostprocess     map[string][]string     `yaml:"role-postprocess"`
+
+	MCPServers            map[string]MCPServerConfig `yaml:"mcp-servers"`
+	MCPDisable            []string                   `yaml:"mcp-disable" env:"MCP_DISABLE"`
+	MCPOnly               []string                   `yaml:"mcp-only" env:"MCP_ONLY"`
+	MCPRoots              []string                   `yaml:"mcp-roots" env:"MCP_ROOTS"`
+	MCPTimeout            time.Duration              `yaml:"mcp-timeout" env:"MCP_TIMEOUT"`
+	MCPAllowNonTTY        bool                       `yaml:"mcp-allow-non-tty" env:"MCP_ALLOW_NON_TTY"`
+	MCPNoInheritEnv       bool                       `yaml:"mcp-no-inherit-env" env:"MCP_NO_INHERIT_ENV"`
+	MCPReadOnly           bool                       `yaml:"mcp-read-only" env:"MCP_READ_ONLY"`
+	MCPToolsCacheTTL      time.Duration              `yaml:"mcp-tools-cache-ttl" env:"MCP_TOOLS_CACHE_TTL"`
+	MCPSampling           string                     `yaml:"mcp-sampling" env:"MCP_SAMPLING"`
+	MCPSamplingMaxTokens  int64                      `yaml:"mcp-sampling-max-tokens" env:"MCP_SAMPLING_MAX_TOKENS"`
+	MCPSamplingLimit      int                        `yaml:"mcp-sampling-limit" env:"MCP_SAMPLING_LIMIT"`
+	ToolInjection         string                     `yaml:"tool-injection" env:"TOOL_INJECTION"`
+	ToolInjectionPatterns []string                   `yaml:"tool-injection-patterns"`
+	ToolInjectionModel    bool                       `yaml:"tool-injection-model" env:"TOOL_INJECTION_MODEL"`
+	RequestTimeout        time.Duration              `yaml:"request-timeout" env:"REQUEST_TIMEOUT"`
+	ConnectTimeout        time.Duration              `yaml:"connect-timeout" env:"CONNECT_TIMEOUT"`
+	FirstTokenTimeout     time.Duration              `yaml:"first-token-timeout" env:"FIRST_TOKEN_TIMEOUT"`
+	IdleTimeout           time.Duration              `yaml:"idle-timeout" env:"IDLE_TIMEOUT"`
+	Retry                 RetrySettings              `yaml:"retry" envPrefix:"RETRY_"`
+	Sync                  SyncSettings               `yaml:"sync" envPrefix:"SYNC_"`
+	Hooks                 HookSettings               `yaml:"hooks" envPrefix:"HOOKS_"`
+}
+
+// RetrySettings holds how many times each class of transient error is
+// retried, and the backoff between attempts. max-retries still caps the
+// total number of retries of a request.
+type RetrySettings struct {
+	RateLimit      int           `yaml:"rate-limit" env:"RATE_LIMIT"`
+	ServerError    int           `yaml:"server-error" env:"SERVER_ERROR"`
+	Network        int           `yaml:"network" env:"NETWORK"`
+	InitialBackoff time.Duration `yaml:"initial-backoff" env:"INITIAL_BACKOFF"`
+	MaxBackoff     time.Duration `yaml:"max-backoff" env:"MAX_BACKOFF"`
+	// Jitter is the largest random deviation from the backoff, as a
+	// fraction of it.
+	Jitter float64 `yaml:"jitter" env:"JITTER"`
+}
+
+// SyncSettings selects where `yai history sync` keeps the shared copy of
+// the conversation history.
+type SyncSettings struct {
+	// Backend is one of git, s3, or rsync.
+	Backend string `yaml:"backend" env:"BACKEND"`
+	// Remote is a git URL, an s3://bucket/prefix URL, or an rsync target
+	// such as host:path.
+	Remote string `yaml:"remote" env:"REMOTE"`
+}
+
+// HookSettings holds the commands yai runs around each request to the
+// model. Each reads the conversation as JSON on stdin.
+type HookSettings struct {
+	// PreRequest runs before a request is sent, and may change or reject it.
+	PreRequest string `yaml:"pre-request" env:"PRE_REQUEST"`
+	// PostResponse runs once the model has answered, and may change what
+	// is saved of the answer or reject it.
+	PostResponse string `yaml:"post-response" env:"POST_RESPONSE"`
 }
 
 // Runtime holds CLI/runtime-only options that should not be loaded from the
 // settings file.
 type Runtime struct {
-	AskModel        bool
-	ShowHelp        bool
-	ResetSettings   bool
-	Prefix          string
-	Version         bool
-	EditSettings    bool
-	Dirs            bool
-	SettingsPath    string
+	AskModel      bool
+	ShowHelp      bool
+	ResetSettings bool
+	Prefix        string
+	PromptFile    string
+	PromptVars    []string
+	Snippets      []string
+	Fastest       bool
+	Version       bool
+	EditSettings  bool
+	Dirs          bool
+	SettingsPath  string
+	// Workspace is the workspace in use, or "" for the default one.
+	Workspace string
+	// StatePath is the directory of the usage ledger, logs, and crash
+	// reports; see StateDir.
+	StatePath       string
 	ContinueLast    bool
 	Continue        string
+	Resume          bool
 	Title           string
 	ShowLast        bool
 	Show            string
+	ShowJSON        bool
+	ShowVerbose     bool
 	List            bool
 	ListRoles       bool
 	Delete          []string
@@ -159,6 +312,37 @@ type Runtime struct {
 	MCPListTools    bool
 	OpenEditor      bool
 	Patch           bool
+	Transcribe      string
+	NoDaemon        bool
+	// Logprobs asks for the log probabilities of the answer's tokens, with
+	// this many alternatives each, in the jsonl output.
+	Logprobs int64
+	// IgnoreBudget sends even when this month's spend reached
+	// monthly-budget.
+	IgnoreBudget bool
+	// Yes sends without asking, even when confirm-tokens, confirm-cost, or
+	// confirm-models would.
+	Yes bool
+	// DryRun checks the request without sending it; see agent.Service.Validate.
+	DryRun bool
+	// OutputFormat is "text" or "jsonl"; see tui.Yai for the jsonl events.
+	OutputFormat string
+	// Sinks are the --sink FORMAT:TARGET flags, which copy the response
+	// elsewhere; see tui.Sink.
+	Sinks []string
+	// ClientVersion is the yai build version reported to MCP servers.
+	ClientVersion string
+	// FallbackFrom is the model that was asked for when its fallback model
+	// answered instead.
+	FallbackFrom string
+	// RequestID and ModelVersion are what the provider reported for the
+	// last answer, saved with the conversation.
+	RequestID    string
+	ModelVersion string
+	// DeprecatedKeys and UnknownKeys are the deprecated keys, and those yai
+	// does not know, found in the settings file.
+	DeprecatedKeys []DeprecatedKey
+	UnknownKeys    []UnknownKey
 
 	CacheReadFromID                   string
 	CacheWriteToID, CacheWriteToTitle string
@@ -181,19 +365,24 @@ type MCPServerConfig struct {
 	Args    []string          `yaml:"args"`
 	URL     string            `yaml:"url"`
 	Headers map[string]string `yaml:"headers"`
+	// ProtocolVersion pins the MCP protocol version requested during
+	// initialization. Empty means the latest version supported by yai.
+	ProtocolVersion string `yaml:"protocol-version"`
 }
 
 // Ensure loads settings from disk and environment and applies defaults.
+// settingsPath is the settings file given with --config, if any; see
+// ResolvePaths. workspace is the one given with --workspace, if any.
 //
 // It also creates the default settings file if it does not exist.
-func Ensure() (Config, error) {
+func Ensure(settingsPath, workspace string) (Config, error) {
 	var c Config
-	home, err := os.UserHomeDir()
+	paths, err := ResolvePaths(settingsPath)
 	if err != nil {
-		return c, errs.Wrap(err, "Could not determine home directory.")
+		return c, err
 	}
 
-	sp := filepath.Join(home, ".config", "yai", "yai.yml")
+	sp := paths.Settings
 	c.SettingsPath = sp
 
 	dir := filepath.Dir(sp)
@@ -201,31 +390,66 @@ func Ensure() (Config, error) {
 		return c, errs.Wrap(dirErr, "Could not create cache directory.")
 	}
 
+	if c.Workspace, err = chooseWorkspace(sp, workspace); err != nil {
+		return c, err
+	}
 	if err := loadAndParse(sp, &c); err != nil {
 		return c, err
 	}
 
-	applyDefaults(&c, home)
+	applyDefaults(&c, paths)
+	useWorkspaceCache(&c)
 
-	// request-timeout:
+	// request-timeout, connect-timeout, first-token-timeout, idle-timeout:
 	// - 0 means use default
 	// - negative means disable (handled by callers by only applying when > 0)
 	if c.RequestTimeout < 0 && !c.Quiet {
 		fmt.Fprintln(os.Stderr, "Note: request-timeout is negative; request timeout is disabled.")
 	}
 
+	// An unwritable cache is not fatal: the conversation store falls back to
+	// a temporary one when it is opened.
 	if err := os.MkdirAll(
 		filepath.Join(c.CachePath, "conversations"),
 		0o700,
-	); err != nil {
+	); err != nil && !IsUnwritable(err) {
 		return c, errs.Wrap(err, "Could not create cache directory.")
 	}
 
 	return c, nil
 }
 
-// loadAndParse reads the config file, decodes YAML, applies environment
-// overrides, and merges role definitions.
+// StateDir is the directory of the usage ledger, logs, and crash reports:
+// StatePath, or cache-path when it is not set.
+func (c *Config) StateDir() string {
+	return cmp.Or(c.StatePath, c.CachePath)
+}
+
+// ConfirmsModel reports whether requests to m are listed in confirm-models,
+// by name or alias.
+func (c *Config) ConfirmsModel(m Model) bool {
+	for _, name := range c.ConfirmModels {
+		if name == m.Name || slices.Contains(m.Aliases, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// VirtualModel returns the virtual model named name, if there is one.
+func (c *Config) VirtualModel(name string) (VirtualModel, bool) {
+	vm, ok := c.VirtualModels[name]
+	return vm, ok
+}
+
+// IsUnwritable reports whether err comes from writing to a read-only file
+// system or a path without write permission.
+func IsUnwritable(err error) bool {
+	return errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.EROFS)
+}
+
+// loadAndParse reads the config file, decodes YAML, applies the settings of
+// the workspace and environment overrides, and merges role definitions.
 func loadAndParse(sp string, c *Config) error {
 	if err := WriteConfigFile(sp); err != nil {
 		return err
@@ -234,11 +458,19 @@ func loadAndParse(sp string, c *Config) error {
 	if err != nil {
 		return errs.Wrap(err, "Could not read settings file.")
 	}
-	dec := yaml.NewDecoder(bytes.NewReader(content))
-	dec.KnownFields(true)
-	if err := dec.Decode(c); err != nil {
+	if c.UnknownKeys, err = decodeSettings(content, c); err != nil {
+		return errs.Wrap(err, "Could not parse settings file.")
+	}
+	// The file parsed, so finding its deprecated keys cannot fail.
+	c.DeprecatedKeys, _ = FindDeprecated(content)
+	if err := applyRenames(c, c.DeprecatedKeys); err != nil {
 		return errs.Wrap(err, "Could not parse settings file.")
 	}
+	if c.Workspace != "" {
+		if err := applyWorkspace(c); err != nil {
+			return err
+		}
+	}
 
 	if err := env.ParseWithOptions(c, env.Options{Prefix: "YAI_"}); err != nil {
 		return errs.Wrap(err, "Could not parse environment into settings file.")
@@ -253,13 +485,24 @@ func loadAndParse(sp string, c *Config) error {
 }
 
 // applyDefaults fills zero-value fields with sensible defaults.
-func applyDefaults(c *Config, home string) {
+func applyDefaults(c *Config, paths Paths) {
+	// A cache-path from the settings keeps the state with the data, as it
+	// was before the two were apart.
 	if c.CachePath == "" {
-		c.CachePath = filepath.Join(home, ".config", "yai", "history")
+		c.CachePath, c.StatePath = paths.Data, paths.State
+	}
+	if c.DaemonSocket == "" {
+		c.DaemonSocket = filepath.Join(c.StateDir(), "daemon.sock")
 	}
 	if c.MaxOutputBytes == 0 {
 		c.MaxOutputBytes = 2 * 1024 * 1024
 	}
+	if c.TruncateHead == 0 {
+		c.TruncateHead = Default().TruncateHead
+	}
+	if c.ContextStrategy == "" {
+		c.ContextStrategy = Default().ContextStrategy
+	}
 	if c.WordWrap == 0 {
 		c.WordWrap = 80
 	}
@@ -269,12 +512,79 @@ func applyDefaults(c *Config, home string) {
 	if c.FormatAs == "" {
 		c.FormatAs = "markdown"
 	}
+	if c.RedactBuiltin == nil {
+		c.RedactBuiltin = Default().RedactBuiltin
+	}
 	if c.MCPTimeout == 0 {
 		c.MCPTimeout = Default().MCPTimeout
 	}
+	if c.MCPToolsCacheTTL == 0 {
+		c.MCPToolsCacheTTL = Default().MCPToolsCacheTTL
+	}
+	if c.MCPSampling == "" {
+		c.MCPSampling = Default().MCPSampling
+	}
+	if c.MCPSamplingMaxTokens == 0 {
+		c.MCPSamplingMaxTokens = Default().MCPSamplingMaxTokens
+	}
+	if c.MCPSamplingLimit == 0 {
+		c.MCPSamplingLimit = Default().MCPSamplingLimit
+	}
+	if c.ToolInjection == "" {
+		c.ToolInjection = Default().ToolInjection
+	}
 	if c.RequestTimeout == 0 {
 		c.RequestTimeout = Default().RequestTimeout
 	}
+	if c.ConnectTimeout == 0 {
+		c.ConnectTimeout = Default().ConnectTimeout
+	}
+	if c.FirstTokenTimeout == 0 {
+		c.FirstTokenTimeout = Default().FirstTokenTimeout
+	}
+	if c.IdleTimeout == 0 {
+		c.IdleTimeout = Default().IdleTimeout
+	}
+	if c.RoleCacheThreshold == 0 {
+		c.RoleCacheThreshold = Default().RoleCacheThreshold
+	}
+	if c.TitleRefreshTurns == 0 {
+		c.TitleRefreshTurns = Default().TitleRefreshTurns
+	}
+	if c.DuplicateWindow == 0 {
+		c.DuplicateWindow = Default().DuplicateWindow
+	}
+	if c.TrashRetention == 0 {
+		c.TrashRetention = Default().TrashRetention
+	}
+	if c.NotifyAfter == 0 {
+		c.NotifyAfter = Default().NotifyAfter
+	}
+	c.Retry = c.Retry.WithDefaults()
+}
+
+// WithDefaults returns r with zero fields set to their defaults.
+func (r RetrySettings) WithDefaults() RetrySettings {
+	d := Default().Retry
+	if r.RateLimit == 0 {
+		r.RateLimit = d.RateLimit
+	}
+	if r.ServerError == 0 {
+		r.ServerError = d.ServerError
+	}
+	if r.Network == 0 {
+		r.Network = d.Network
+	}
+	if r.InitialBackoff == 0 {
+		r.InitialBackoff = d.InitialBackoff
+	}
+	if r.MaxBackoff == 0 {
+		r.MaxBackoff = d.MaxBackoff
+	}
+	if r.Jitter == 0 {
+		r.Jitter = d.Jitter
+	}
+	return r
 }
 
 // MergeRolesFromDir merges role definitions from ~/.config/yai/roles into cfg.
@@ -393,6 +703,9 @@ type starterRole struct {
 	content  string
 }
 
+// TranslateRole is the built-in role of yai translate.
+const TranslateRole = "translate"
+
 var starterRoles = []starterRole{
 	{"tldr.md", tldrRole},
 	{"diff.md", patchRole},
@@ -416,8 +729,8 @@ func installStarterRoles(configDir string) {
 	}
 }
 
-// RegisterBuiltinRoles ensures built-in roles (diff, tldr) are available
-// in-memory even if the on-disk role files are missing.
+// RegisterBuiltinRoles ensures built-in roles (diff, tldr, translate) are
+// available in-memory even if the on-disk role files are missing.
 func RegisterBuiltinRoles(cfg *Config) {
 	if cfg.Roles == nil {
 		cfg.Roles = map[string][]string{}
@@ -428,6 +741,9 @@ func RegisterBuiltinRoles(cfg *Config) {
 	if _, exists := cfg.Roles["diff"]; !exists {
 		cfg.Roles["diff"] = []string{patchRole}
 	}
+	if _, exists := cfg.Roles[TranslateRole]; !exists {
+		cfg.Roles[TranslateRole] = []string{translateRole}
+	}
 }
 
 // Default returns the default configuration values.
@@ -439,8 +755,32 @@ func Default() Config {
 				"markdown": defaultMarkdownFormatText,
 				"json":     defaultJSONFormatText,
 			},
-			MCPTimeout:     15 * time.Second,
-			RequestTimeout: 5 * time.Minute,
+			RedactBuiltin:        []string{"aws-access-key", "email", "github-token", "private-key"},
+			MCPTimeout:           15 * time.Second,
+			MCPToolsCacheTTL:     24 * time.Hour,
+			MCPSampling:          "off",
+			MCPSamplingMaxTokens: 1000,
+			MCPSamplingLimit:     10,
+			ToolInjection:        "warn",
+			RequestTimeout:       30 * time.Minute,
+			ConnectTimeout:       30 * time.Second,
+			FirstTokenTimeout:    5 * time.Minute,
+			IdleTimeout:          2 * time.Minute,
+			TruncateHead:         0.5,
+			ContextStrategy:      "drop-oldest",
+			RoleCacheThreshold:   4096,
+			TitleRefreshTurns:    10,
+			DuplicateWindow:      24 * time.Hour,
+			TrashRetention:       30 * 24 * time.Hour,
+			NotifyAfter:          10 * time.Second,
+			Retry: RetrySettings{
+				RateLimit:      5,
+				ServerError:    3,
+				Network:        2,
+				InitialBackoff: 500 * time.Millisecond,
+				MaxBackoff:     30 * time.Second,
+				Jitter:         0.125,
+			},
 		},
 	}
 }
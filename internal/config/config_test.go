@@ -1,8 +1,11 @@
 package config
 
 import (
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"syscall"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -23,6 +26,23 @@ func TestFormatText(t *testing.T) {
 	})
 }
 
+func TestVirtualModels(t *testing.T) {
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte(`virtual-models:
+  smart:
+    policy: cheapest
+    models:
+      - gpt-4o
+      - {api: azure, model: gpt-4o-mini}
+`), &cfg))
+	vm, ok := cfg.VirtualModel("smart")
+	require.True(t, ok)
+	require.Equal(t, PolicyCheapest, vm.Policy)
+	require.Equal(t, []Candidate{{Model: "gpt-4o"}, {API: "azure", Model: "gpt-4o-mini"}}, vm.Models)
+	_, ok = cfg.VirtualModel("gpt-4o")
+	require.False(t, ok)
+}
+
 func TestMergeRolesFromDir(t *testing.T) {
 	t.Run("loads text role files as file references", func(t *testing.T) {
 		root := t.TempDir()
@@ -179,6 +199,7 @@ func TestRegisterBuiltinRoles(t *testing.T) {
 		require.Contains(t, cfg.Roles, "tldr")
 		require.Contains(t, cfg.Roles["diff"][0], "unified diff")
 		require.Contains(t, cfg.Roles["tldr"][0], "concise")
+		require.Contains(t, cfg.Roles[TranslateRole][0], "translator")
 	})
 
 	t.Run("does not overwrite user-defined roles", func(t *testing.T) {
@@ -219,3 +240,9 @@ func TestCreateConfigFileInstallsStarterRoles(t *testing.T) {
 		require.NotEmpty(t, content)
 	})
 }
+
+func TestIsUnwritable(t *testing.T) {
+	require.True(t, IsUnwritable(&fs.PathError{Op: "mkdir", Path: "/x", Err: syscall.EROFS}))
+	require.True(t, IsUnwritable(fmt.Errorf("open store: %w", &fs.PathError{Op: "open", Path: "/x", Err: fs.ErrPermission})))
+	require.False(t, IsUnwritable(&fs.PathError{Op: "mkdir", Path: "/x", Err: syscall.ENOTDIR}))
+}
@@ -0,0 +1,308 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	stdstrings "strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Deprecation describes a settings key that is no longer used or was
+// renamed.
+type Deprecation struct {
+	// Key is the dotted path of the key, with * standing for any name, as
+	// in "apis.*.version".
+	Key string
+	// RenamedTo is the new name of the key, in the same mapping. Empty
+	// means the key is dropped.
+	RenamedTo string
+	// Reason explains the change to the user.
+	Reason string
+}
+
+// deprecations lists the settings keys that are still parsed, so old
+// settings files load, but that migrations rewrite. Renamed keys are at the
+// top of the file; see applyRenames.
+var deprecations = []Deprecation{
+	{Key: "system", Reason: "it was never sent to the model; put system prompts in a role"},
+	{Key: "apis.*.version", Reason: "it was never sent to the API"},
+	{Key: "model", RenamedTo: "default-model", Reason: "the setting behind --model and YAI_MODEL"},
+	{Key: "api", RenamedTo: "default-api", Reason: "the setting behind --api and YAI_API"},
+	{Key: "temperature", RenamedTo: "temp", Reason: "named like --temp"},
+	{Key: "top-p", RenamedTo: "topp", Reason: "named like --topp"},
+	{Key: "top-k", RenamedTo: "topk", Reason: "named like --topk"},
+}
+
+// DeprecatedKey is a deprecated key found in a settings file.
+type DeprecatedKey struct {
+	Deprecation
+	// Path is the dotted path of the key in the file, as in
+	// "apis.openai.version".
+	Path string
+	// Line is the 1-based line of the key.
+	Line int
+
+	key    *yaml.Node
+	end    int // last line of the value
+	parent *yaml.Node
+}
+
+// Describe explains what migrating the key does.
+func (d DeprecatedKey) Describe() string {
+	if d.RenamedTo != "" {
+		return fmt.Sprintf("%s is now %s", d.Path, d.RenamedTo)
+	}
+	return fmt.Sprintf("%s is no longer used: %s", d.Path, d.Reason)
+}
+
+// UnknownKey is a key of a settings file that yai does not know, such as a
+// misspelled one or one from another version. It is left out when the
+// settings load.
+type UnknownKey struct {
+	// Path is the dotted path of the key, as in "apis.openai.retries".
+	Path string
+	// Line is the 1-based line of the key.
+	Line int
+}
+
+// FindDeprecated returns the deprecated keys of the settings file content,
+// in file order.
+func FindDeprecated(content []byte) ([]DeprecatedKey, error) {
+	return findDeprecated(content, deprecations)
+}
+
+func findDeprecated(content []byte, rules []Deprecation) ([]DeprecatedKey, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("parse settings: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	lines := bytes.Count(content, []byte("\n")) + 1
+	var found []DeprecatedKey
+	walkMapping(doc.Content[0], nil, lines, func(path []string, key, parent *yaml.Node, end int) {
+		for _, rule := range rules {
+			if matchKey(rule.Key, path) {
+				found = append(found, DeprecatedKey{
+					Deprecation: rule,
+					Path:        stdstrings.Join(path, "."),
+					Line:        key.Line,
+					key:         key,
+					end:         end,
+					parent:      parent,
+				})
+				return
+			}
+		}
+	})
+	return found, nil
+}
+
+// decodeSettings decodes the settings file content into c and returns the
+// keys yai does not know. They are left out rather than failing the decode,
+// so that settings written for another version still load; any other error
+// fails it.
+func decodeSettings(content []byte, c *Config) ([]UnknownKey, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	if err := doc.Decode(c); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	var unknown []UnknownKey
+	findUnknown(doc.Content[0], reflect.TypeFor[Config](), nil, &unknown)
+	return unknown, nil
+}
+
+// findUnknown adds the keys of node, and of the mappings in it, that the
+// type t it decodes into has no field for to unknown, in file order.
+func findUnknown(node *yaml.Node, t reflect.Type, path []string, unknown *[]UnknownKey) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// APIs decodes a mapping of names to APIs.
+	if t == reflect.TypeFor[APIs]() {
+		t = reflect.TypeFor[map[string]API]()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			keyPath := append(slices.Clip(path), key.Value)
+			field, ok := fields[key.Value]
+			if !ok {
+				*unknown = append(*unknown, UnknownKey{Path: stdstrings.Join(keyPath, "."), Line: key.Line})
+				continue
+			}
+			findUnknown(value, field, keyPath, unknown)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			findUnknown(node.Content[i+1], t.Elem(), append(slices.Clip(path), node.Content[i].Value), unknown)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, item := range node.Content {
+			findUnknown(item, t.Elem(), append(slices.Clip(path), strconv.Itoa(i)), unknown)
+		}
+	}
+}
+
+// yamlFields returns the types of the fields of struct t by the keys they
+// decode from, with those of inlined structs.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for field := range t.Fields() {
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := stdstrings.Cut(field.Tag.Get("yaml"), ",")
+		switch {
+		case name == "-":
+			continue
+		case opts == "inline":
+			maps.Copy(fields, yamlFields(field.Type))
+			continue
+		case name == "":
+			name = stdstrings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// walkMapping calls fn for each key of the block mapping node and the
+// mappings in it, with the last line of its value. Mappings end where the
+// next key starts.
+func walkMapping(node *yaml.Node, path []string, end int, fn func(path []string, key, parent *yaml.Node, end int)) {
+	if node.Kind != yaml.MappingNode || node.Style&yaml.FlowStyle != 0 {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		valueEnd := end
+		if i+2 < len(node.Content) {
+			valueEnd = node.Content[i+2].Line - 1
+		}
+		keyPath := append(slices.Clip(path), key.Value)
+		fn(keyPath, key, node, valueEnd)
+		walkMapping(value, keyPath, valueEnd, fn)
+	}
+}
+
+func matchKey(pattern string, path []string) bool {
+	parts := stdstrings.Split(pattern, ".")
+	if len(parts) != len(path) {
+		return false
+	}
+	for i, part := range parts {
+		if part != "*" && part != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// MigrateSettings rewrites the deprecated keys of the settings file content:
+// renamed keys get their new name, and unused ones are removed with their
+// comment. Everything else, comments and unknown keys included, is kept as
+// it is. A renamed key whose new name is already set is
+// removed.
+func MigrateSettings(content []byte) ([]byte, []DeprecatedKey, error) {
+	return migrateSettings(content, deprecations)
+}
+
+func migrateSettings(content []byte, rules []Deprecation) ([]byte, []DeprecatedKey, error) {
+	found, err := findDeprecated(content, rules)
+	if err != nil || len(found) == 0 {
+		return content, nil, err
+	}
+	lines := stdstrings.SplitAfter(string(content), "\n")
+	drop := make([]bool, len(lines))
+	for _, d := range found {
+		i := d.Line - 1
+		if d.RenamedTo != "" && !hasKey(d.parent, d.RenamedTo) {
+			col := d.key.Column - 1
+			if !stdstrings.HasPrefix(lines[i][col:], d.key.Value) {
+				return nil, nil, fmt.Errorf("rewrite %s on line %d: quoted keys are not supported", d.Path, d.Line)
+			}
+			lines[i] = lines[i][:col] + d.RenamedTo + lines[i][col+len(d.key.Value):]
+			continue
+		}
+		// Keep trailing blank lines and comments, which belong to the next
+		// key; take the comment above the key along.
+		end := d.end - 1
+		for end > i && isBlankOrComment(lines[end]) {
+			end--
+		}
+		start := i
+		if d.key.HeadComment != "" {
+			for start > 0 && stdstrings.HasPrefix(stdstrings.TrimSpace(lines[start-1]), "#") {
+				start--
+			}
+		}
+		for j := start; j <= end && j < len(drop); j++ {
+			drop[j] = true
+		}
+	}
+	var out stdstrings.Builder
+	for i, line := range lines {
+		if !drop[i] {
+			out.WriteString(line)
+		}
+	}
+	return []byte(out.String()), found, nil
+}
+
+// applyRenames decodes the values of the renamed keys among keys into c
+// under their new names, unless those are set too, so settings that use
+// the old names still load.
+func applyRenames(c *Config, keys []DeprecatedKey) error {
+	for _, d := range keys {
+		if d.RenamedTo == "" || stdstrings.Contains(d.Path, ".") || hasKey(d.parent, d.RenamedTo) {
+			continue
+		}
+		value := d.parent.Content[slices.Index(d.parent.Content, d.key)+1]
+		renamed := yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: d.RenamedTo},
+			value,
+		}}
+		if err := renamed.Decode(c); err != nil {
+			return fmt.Errorf("line %d: %s: %w", d.Line, d.Path, err)
+		}
+	}
+	return nil
+}
+
+func hasKey(mapping *yaml.Node, name string) bool {
+	for i := 0; i < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isBlankOrComment(line string) bool {
+	line = stdstrings.TrimSpace(line)
+	return line == "" || stdstrings.HasPrefix(line, "#")
+}
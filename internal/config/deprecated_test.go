@@ -0,0 +1,155 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const deprecatedSettings = `# yai settings
+default-api: openai
+# A system prompt.
+system: be brief
+old-name: 5 # keep this comment
+
+apis:
+  openai:
+    base-url: https://api.openai.com/v1
+    version: "2024-01-01"
+    # The models.
+    models:
+      gpt-4o:
+        aliases: ["4o"]
+  azure:
+    version: "2023-05-15"
+    # Trailing comment.
+
+roles:
+  default: []
+`
+
+func TestFindDeprecated(t *testing.T) {
+	found, err := FindDeprecated([]byte(deprecatedSettings))
+	require.NoError(t, err)
+	paths := make([]string, 0, len(found))
+	for _, key := range found {
+		paths = append(paths, key.Path)
+	}
+	require.Equal(t, []string{"system", "apis.openai.version", "apis.azure.version"}, paths)
+	require.Equal(t, 4, found[0].Line)
+	require.Contains(t, found[1].Describe(), "apis.openai.version is no longer used")
+
+	t.Run("template", func(t *testing.T) {
+		found, err := FindDeprecated([]byte(configTemplate))
+		require.NoError(t, err)
+		require.Empty(t, found)
+	})
+}
+
+func TestMigrateSettings(t *testing.T) {
+	rules := append([]Deprecation{{Key: "old-name", RenamedTo: "new-name"}}, deprecations...)
+	migrated, found, err := migrateSettings([]byte(deprecatedSettings), rules)
+	require.NoError(t, err)
+	require.Len(t, found, 4)
+	require.Equal(t, `# yai settings
+default-api: openai
+new-name: 5 # keep this comment
+
+apis:
+  openai:
+    base-url: https://api.openai.com/v1
+    # The models.
+    models:
+      gpt-4o:
+        aliases: ["4o"]
+  azure:
+    # Trailing comment.
+
+roles:
+  default: []
+`, string(migrated))
+
+	t.Run("renamed key already set", func(t *testing.T) {
+		rules := []Deprecation{{Key: "old-name", RenamedTo: "word-wrap"}}
+		migrated, _, err := migrateSettings([]byte("old-name: 1\nword-wrap: 2\n"), rules)
+		require.NoError(t, err)
+		require.Equal(t, "word-wrap: 2\n", string(migrated))
+	})
+
+	t.Run("unknown keys", func(t *testing.T) {
+		content := "default-api: openai\npalette:\n  accent: pink\napis:\n  openai:\n    retries: 3 # try harder\n"
+		migrated, found, err := MigrateSettings([]byte(content))
+		require.NoError(t, err)
+		require.Empty(t, found)
+		require.Equal(t, content, string(migrated), "unknown keys are left alone")
+	})
+
+	t.Run("up to date", func(t *testing.T) {
+		migrated, found, err := MigrateSettings([]byte(configTemplate))
+		require.NoError(t, err)
+		require.Empty(t, found)
+		require.Equal(t, configTemplate, string(migrated))
+	})
+}
+
+func TestLoadUnknownKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "yai.yml")
+	require.NoError(t, os.WriteFile(path, []byte("palette: pink\nword-wrap: 100\napis:\n  openai:\n    retries: 3\n    base-url: http://localhost\n"), 0o600))
+	var c Config
+	require.NoError(t, loadAndParse(path, &c), "unknown keys do not break parsing")
+	require.Equal(t, 100, c.WordWrap)
+	require.Equal(t, "http://localhost", c.APIs[0].BaseURL)
+	require.Empty(t, c.DeprecatedKeys)
+	require.Equal(t, []UnknownKey{{Path: "palette", Line: 1}, {Path: "apis.openai.retries", Line: 5}}, c.UnknownKeys)
+
+	require.NoError(t, os.WriteFile(path, []byte("word-wrap: lots\n"), 0o600))
+	require.ErrorContains(t, loadAndParse(path, &Config{}), "cannot unmarshal")
+}
+
+func TestLoadRenamedKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "yai.yml")
+	require.NoError(t, os.WriteFile(path, []byte("model: gpt-4o\ntemperature: 0.2\ntop-p: 0.9\ntopp: 0.5\n"), 0o600))
+	var c Config
+	require.NoError(t, loadAndParse(path, &c))
+	require.Equal(t, "gpt-4o", c.Model)
+	require.InDelta(t, 0.2, c.Temperature, 1e-9)
+	require.InDelta(t, 0.5, c.TopP, 1e-9, "the new name wins")
+	require.Len(t, c.DeprecatedKeys, 3)
+	require.Equal(t, "model is now default-model", c.DeprecatedKeys[0].Describe())
+
+	migrated, _, err := MigrateSettings([]byte("model: gpt-4o\ntemperature: 0.2\ntop-p: 0.9\ntopp: 0.5\n"))
+	require.NoError(t, err)
+	require.Equal(t, "default-model: gpt-4o\ntemp: 0.2\ntopp: 0.5\n", string(migrated))
+}
+
+func TestDecodeSettingsUnknownKeys(t *testing.T) {
+	var c Config
+	unknown, err := decodeSettings([]byte(`default-model: gpt-4o
+format-text: be brief
+apis:
+  openai:
+    models:
+      gpt-4o:
+        aliases: ["4o"]
+        max-chars: 1000
+virtual-models:
+  smart:
+    models: [gpt-4o, {api: openai, model: o3, weight: 2}]
+mcp-servers:
+  fs:
+    command: fs-server
+    cwd: /tmp
+hooks:
+  pre-request: check
+`), &c)
+	require.NoError(t, err)
+	require.Equal(t, []UnknownKey{
+		{Path: "apis.openai.models.gpt-4o.max-chars", Line: 8},
+		{Path: "virtual-models.smart.models.1.weight", Line: 11},
+		{Path: "mcp-servers.fs.cwd", Line: 15},
+	}, unknown)
+	require.Equal(t, "gpt-4o", c.Model)
+	require.Equal(t, []string{"4o"}, c.APIs[0].Models["gpt-4o"].Aliases)
+}
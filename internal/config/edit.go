@@ -0,0 +1,258 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	stdstrings "strings"
+
+	"github.com/dotcommander/yai/internal/storage/robustio"
+	"gopkg.in/yaml.v3"
+)
+
+// GetSetting returns the value of the key at the dotted path key, as in
+// "apis.openai.base-url", in the settings file content: scalars as they
+// are, mappings and lists as YAML. Names with dots, such as gpt-4.1, are
+// matched as they are.
+func GetSetting(content []byte, key string) (string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return "", fmt.Errorf("parse settings: %w", err)
+	}
+	segs := stdstrings.Split(key, ".")
+	if len(doc.Content) == 0 {
+		return "", fmt.Errorf("%s is not set", key)
+	}
+	node := doc.Content[0]
+	for len(segs) > 0 {
+		i, n := findSetting(node, segs)
+		if i < 0 {
+			return "", fmt.Errorf("%s is not set", key)
+		}
+		node, segs = node.Content[i+1], segs[n:]
+	}
+	switch {
+	case node.Kind == yaml.ScalarNode && node.Tag == "!!null":
+		return "", nil
+	case node.Kind == yaml.ScalarNode:
+		return node.Value, nil
+	}
+	out, err := yaml.Marshal(node)
+	if err != nil {
+		return "", fmt.Errorf("encode %s: %w", key, err)
+	}
+	return stdstrings.TrimSuffix(string(out), "\n"), nil
+}
+
+// SetSetting sets the key at the dotted path key to value, a YAML value
+// such as gpt-4o, 100, or [a, b], in the settings file content. Only the
+// lines of the key change; comments and everything else are kept. A missing
+// key, and the mappings it is in, are added at the end of their mapping.
+// The result must still be valid settings, with no keys unknown to yai but
+// those already in content.
+func SetSetting(content []byte, key, value string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("parse settings: %w", err)
+	}
+	var c Config
+	unknown, _ := decodeSettings(content, &c)
+	rendered := renderSettingValue(value)
+	lines := stdstrings.SplitAfter(string(content), "\n")
+	if n := len(lines); n > 0 && lines[n-1] == "" {
+		lines = lines[:n-1]
+	}
+	if n := len(lines); n > 0 && !stdstrings.HasSuffix(lines[n-1], "\n") {
+		lines[n-1] += "\n"
+	}
+
+	segs := stdstrings.Split(key, ".")
+	if len(doc.Content) == 0 {
+		lines = append(lines, newSettingLines(segs, rendered, 0)...)
+		return validSettings(lines, unknown)
+	}
+	node, end := doc.Content[0], len(lines)
+	for {
+		if node.Kind != yaml.MappingNode || node.Style&yaml.FlowStyle != 0 && len(node.Content) > 0 {
+			return nil, fmt.Errorf("set %s: it is inside a value that is not a mapping", key)
+		}
+		i, n := findSetting(node, segs)
+		if i < 0 {
+			indent := 0
+			if len(node.Content) > 0 {
+				indent = node.Content[0].Column - 1
+			}
+			at := lastValueLine(lines, end)
+			lines = insertLines(lines, at, newSettingLines(segs, rendered, indent))
+			return validSettings(lines, unknown)
+		}
+		k, v := node.Content[i], node.Content[i+1]
+		valueEnd := end
+		if i+2 < len(node.Content) {
+			valueEnd = node.Content[i+2].Line - 1
+		}
+		row := k.Line - 1
+		prefix, err := keyPrefix(lines[row], k)
+		if err != nil {
+			return nil, err
+		}
+		comment := ""
+		if v.LineComment != "" {
+			comment = " " + v.LineComment
+		} else if k.LineComment != "" {
+			comment = " " + k.LineComment
+		}
+		last := lastValueLine(lines, valueEnd) - 1
+		if n == len(segs) {
+			// Replace the value, with the lines it took.
+			lines[row] = prefix + " " + rendered + comment + "\n"
+			lines = append(lines[:row+1], lines[max(last, row)+1:]...)
+			return validSettings(lines, unknown)
+		}
+		segs = segs[n:]
+		if v.Kind == yaml.MappingNode && v.Style&yaml.FlowStyle == 0 {
+			node, end = v, valueEnd
+			continue
+		}
+		if v.Kind == yaml.ScalarNode && v.Tag == "!!null" || v.Kind == yaml.MappingNode && len(v.Content) == 0 {
+			// An empty value becomes a mapping with the key in it.
+			lines[row] = prefix + comment + "\n"
+			lines = insertLines(lines, row+1, newSettingLines(segs, rendered, k.Column+1))
+			return validSettings(lines, unknown)
+		}
+		return nil, fmt.Errorf("set %s: %s is not a mapping", key, k.Value)
+	}
+}
+
+// findSetting returns the index in mapping of the key segs starts with, the
+// longest one when names contain dots, and how many of segs it takes; -1
+// when there is none.
+func findSetting(mapping *yaml.Node, segs []string) (index, n int) {
+	index = -1
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		name := mapping.Content[i].Value
+		for j := len(segs); j > n; j-- {
+			if stdstrings.Join(segs[:j], ".") == name {
+				index, n = i, j
+				break
+			}
+		}
+	}
+	return index, n
+}
+
+// keyPrefix returns line up to the colon after key k.
+func keyPrefix(line string, k *yaml.Node) (string, error) {
+	col := k.Column - 1
+	if col > len(line) || !stdstrings.HasPrefix(line[col:], k.Value) {
+		return "", fmt.Errorf("set %s on line %d: quoted keys are not supported", k.Value, k.Line)
+	}
+	i := stdstrings.Index(line[col+len(k.Value):], ":")
+	if i < 0 {
+		return "", fmt.Errorf("set %s on line %d: complex keys are not supported", k.Value, k.Line)
+	}
+	return line[:col+len(k.Value)+i+1], nil
+}
+
+// lastValueLine returns the number of lines of a value ending at line end,
+// leaving out the blank lines and comments after it, which belong to the
+// next key.
+func lastValueLine(lines []string, end int) int {
+	for end > 0 && isBlankOrComment(lines[end-1]) {
+		end--
+	}
+	return end
+}
+
+// newSettingLines returns the lines setting the key at path segs to value,
+// indented by indent.
+func newSettingLines(segs []string, value string, indent int) []string {
+	out := make([]string, 0, len(segs))
+	for i, seg := range segs {
+		line := stdstrings.Repeat(" ", indent+2*i) + seg + ":"
+		if i == len(segs)-1 {
+			line += " " + value
+		}
+		out = append(out, line+"\n")
+	}
+	return out
+}
+
+func insertLines(lines []string, at int, add []string) []string {
+	out := make([]string, 0, len(lines)+len(add))
+	out = append(out, lines[:at]...)
+	out = append(out, add...)
+	return append(out, lines[at:]...)
+}
+
+// renderSettingValue returns value as it is when YAML reads it back as the
+// same text or as a list or mapping, such as [a, b], and quoted otherwise.
+func renderSettingValue(value string) string {
+	var doc yaml.Node
+	if !stdstrings.Contains(value, "\n") && yaml.Unmarshal([]byte(value), &doc) == nil && len(doc.Content) == 1 {
+		v := doc.Content[0]
+		if v.Kind == yaml.ScalarNode && v.Style == 0 && v.Value == value && !stdstrings.Contains(value, "#") ||
+			v.Style&yaml.FlowStyle != 0 {
+			return value
+		}
+	}
+	out, _ := yaml.Marshal(&yaml.Node{Kind: yaml.ScalarNode, Value: value, Style: yaml.DoubleQuotedStyle})
+	return stdstrings.TrimSuffix(string(out), "\n")
+}
+
+// validSettings joins lines, checking that they are still valid settings
+// and that the only unknown keys in them are those of before.
+func validSettings(lines []string, before []UnknownKey) ([]byte, error) {
+	content := []byte(stdstrings.Join(lines, ""))
+	var c Config
+	after, err := decodeSettings(content, &c)
+	if err != nil {
+		return nil, fmt.Errorf("the settings would not be valid: %w", err)
+	}
+	known := make(map[string]int, len(before))
+	for _, key := range before {
+		known[key.Path]++
+	}
+	for _, key := range after {
+		if known[key.Path] == 0 {
+			return nil, fmt.Errorf("the settings would not be valid: line %d: %s is not a setting yai knows", key.Line, key.Path)
+		}
+		known[key.Path]--
+	}
+	return content, nil
+}
+
+// ReplaceSettingsFile replaces the settings file at path with content,
+// keeping its permissions. The content is written to a temporary file
+// first, so an interrupted write leaves the old settings in place.
+func ReplaceSettingsFile(path string, content []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("replace settings: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "yai.yml.*")
+	if err != nil {
+		return fmt.Errorf("replace settings: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+	}()
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		return fmt.Errorf("replace settings: %w", err)
+	}
+	if _, err := tmp.Write(content); err != nil {
+		return fmt.Errorf("replace settings: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("replace settings: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("replace settings: %w", err)
+	}
+	if err := robustio.Rename(tmpName, path); err != nil { //nolint:gosec // G703: paths are from os.CreateTemp and filepath.Dir, not user input
+		return fmt.Errorf("replace settings: %w", err)
+	}
+	return nil
+}
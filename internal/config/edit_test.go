@@ -0,0 +1,164 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aymanbagabas/go-udiff"
+	"github.com/stretchr/testify/require"
+)
+
+const editSettings = `# Default model (gpt-3.5-turbo, gpt-4, ggml-gpt4all-j...).
+default-model: gpt-4o # the everyday one
+# Text to append when using the -f flag.
+format-text:
+  markdown: Format the response as markdown.
+max-tokens: 100
+mcp-servers: {}
+apis:
+  openai:
+    base-url: https://api.openai.com/v1
+    models:
+      gpt-4.1:
+        max-input-chars: 392000
+
+  ollama:
+    base-url: http://localhost:11434
+`
+
+func TestGetSetting(t *testing.T) {
+	for key, want := range map[string]string{
+		"default-model":        "gpt-4o",
+		"max-tokens":           "100",
+		"apis.openai.base-url": "https://api.openai.com/v1",
+		"apis.openai.models.gpt-4.1.max-input-chars": "392000",
+		"format-text": "markdown: Format the response as markdown.",
+	} {
+		got, err := GetSetting([]byte(editSettings), key)
+		require.NoError(t, err, key)
+		require.Equal(t, want, got, key)
+	}
+	_, err := GetSetting([]byte(editSettings), "apis.anthropic.base-url")
+	require.EqualError(t, err, "apis.anthropic.base-url is not set")
+}
+
+func TestSetSetting(t *testing.T) {
+	set := func(t *testing.T, key, value string) string {
+		t.Helper()
+		out, err := SetSetting([]byte(editSettings), key, value)
+		require.NoError(t, err)
+		got, err := GetSetting(out, key)
+		require.NoError(t, err)
+		require.Equal(t, value, got)
+		return string(out)
+	}
+
+	t.Run("replaces a value, keeping its comment", func(t *testing.T) {
+		out := set(t, "default-model", "claude-sonnet-4")
+		require.Contains(t, out, "# Default model (gpt-3.5-turbo, gpt-4, ggml-gpt4all-j...).\ndefault-model: claude-sonnet-4 # the everyday one\n")
+		require.Len(t, out, len(editSettings)+len("claude-sonnet-4")-len("gpt-4o"))
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		out := set(t, "apis.ollama.base-url", "http://gpu-box:11434")
+		require.Contains(t, out, "  ollama:\n    base-url: http://gpu-box:11434\n")
+		set(t, "apis.openai.models.gpt-4.1.max-input-chars", "1000000")
+	})
+
+	t.Run("replaces a mapping", func(t *testing.T) {
+		out := set(t, "format-text", "Answer in markdown.")
+		require.Contains(t, out, "format-text: Answer in markdown.\nmax-tokens: 100\n")
+	})
+
+	t.Run("adds missing keys at the end of their mapping", func(t *testing.T) {
+		out := set(t, "apis.openai.api-key-env", "OPENAI_KEY")
+		require.Contains(t, out, "        max-input-chars: 392000\n    api-key-env: OPENAI_KEY\n\n  ollama:")
+		out = set(t, "apis.groq.base-url", "https://api.groq.com/openai/v1")
+		require.Contains(t, out, "    base-url: http://localhost:11434\n  groq:\n    base-url: https://api.groq.com/openai/v1\n")
+		out = set(t, "word-wrap", "100")
+		require.Contains(t, out, "http://localhost:11434\nword-wrap: 100\n")
+	})
+
+	t.Run("fills an empty mapping", func(t *testing.T) {
+		out := set(t, "mcp-servers.github.command", "github-mcp-server")
+		require.Contains(t, out, "mcp-servers:\n  github:\n    command: github-mcp-server\napis:")
+	})
+
+	t.Run("quotes values YAML would read differently", func(t *testing.T) {
+		out := set(t, "default-model", "a: b # c")
+		require.Contains(t, out, `default-model: "a: b # c" # the everyday one`)
+	})
+
+	t.Run("lists", func(t *testing.T) {
+		out, err := SetSetting([]byte(editSettings), "stop", "[END, STOP]")
+		require.NoError(t, err)
+		require.Contains(t, string(out), "stop: [END, STOP]\n")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := SetSetting([]byte(editSettings), "default-modle", "gpt-4o")
+		require.EqualError(t, err, "the settings would not be valid: line 17: default-modle is not a setting yai knows")
+		_, err = SetSetting([]byte(editSettings), "apis.openai.retries", "3")
+		require.ErrorContains(t, err, "retries is not a setting yai knows")
+		_, err = SetSetting([]byte(editSettings), "max-tokens", "many")
+		require.ErrorContains(t, err, "the settings would not be valid")
+		_, err = SetSetting([]byte(editSettings), "default-model.name", "x")
+		require.EqualError(t, err, "set default-model.name: default-model is not a mapping")
+	})
+
+	t.Run("keeps unknown keys already there", func(t *testing.T) {
+		out, err := SetSetting([]byte("palette: pink\nword-wrap: 80\n"), "word-wrap", "100")
+		require.NoError(t, err)
+		require.Equal(t, "palette: pink\nword-wrap: 100\n", string(out))
+	})
+
+	t.Run("the settings template", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "yai.yml")
+		require.NoError(t, WriteConfigFile(path))
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		for key, value := range map[string]string{
+			"default-model":                        "gpt-4o-mini",
+			"apis.openai.base-url":                 "http://localhost:8080/v1",
+			"mcp-sampling":                         "ask",
+			"apis.ollama.models.x.max-input-chars": "4000",
+		} {
+			out, err := SetSetting(content, key, value)
+			require.NoError(t, err, key)
+			// At most the line of the key is replaced; the rest is kept.
+			removed := 0
+			for _, line := range strings.Split(udiff.Unified("a", "b", string(content), string(out)), "\n") {
+				if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---") {
+					removed++
+				}
+			}
+			require.LessOrEqual(t, removed, 1, key)
+		}
+	})
+
+	t.Run("empty file", func(t *testing.T) {
+		out, err := SetSetting(nil, "apis.openai.base-url", "http://localhost")
+		require.NoError(t, err)
+		require.Equal(t, "apis:\n  openai:\n    base-url: http://localhost\n", string(out))
+	})
+}
+
+func TestReplaceSettingsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "yai.yml")
+	require.NoError(t, os.WriteFile(path, []byte("default-model: gpt-4o\n"), 0o640))
+
+	require.NoError(t, ReplaceSettingsFile(path, []byte("default-model: o3\n")))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "default-model: o3\n", string(data))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "no temporary file is left")
+
+	require.ErrorIs(t, ReplaceSettingsFile(filepath.Join(t.TempDir(), "missing.yml"), nil), os.ErrNotExist)
+}
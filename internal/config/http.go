@@ -8,10 +8,17 @@ import (
 	"time"
 )
 
+// defaultConnectTimeout bounds dialing when no connect timeout is configured.
+const defaultConnectTimeout = 30 * time.Second
+
+// defaultResponseTimeout bounds the wait for response headers when no
+// response timeout is configured.
+const defaultResponseTimeout = 30 * time.Second
+
 // NewHTTPClient returns an HTTP client with the project's standard transport
 // timeouts and optional proxy configuration.
-func NewHTTPClient(httpProxy string) (*http.Client, error) {
-	tr, err := NewHTTPTransport(httpProxy)
+func NewHTTPClient(httpProxy string, connectTimeout, responseTimeout time.Duration) (*http.Client, error) {
+	tr, err := NewHTTPTransport(httpProxy, connectTimeout, responseTimeout)
 	if err != nil {
 		return nil, err
 	}
@@ -19,17 +26,32 @@ func NewHTTPClient(httpProxy string) (*http.Client, error) {
 }
 
 // NewHTTPTransport clones http.DefaultTransport and applies the transport
-// defaults used for provider and remote role loading.
-func NewHTTPTransport(httpProxy string) (*http.Transport, error) {
+// defaults used for provider and remote role loading. connectTimeout bounds
+// dialing, and responseTimeout the wait for the response headers once the
+// request is sent; for each, 0 uses the default and a negative value
+// disables it.
+func NewHTTPTransport(httpProxy string, connectTimeout, responseTimeout time.Duration) (*http.Transport, error) {
 	base, ok := http.DefaultTransport.(*http.Transport)
 	if !ok {
 		return nil, fmt.Errorf("default transport is not *http.Transport")
 	}
 
 	tr := base.Clone()
-	tr.DialContext = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	switch {
+	case connectTimeout == 0:
+		connectTimeout = defaultConnectTimeout
+	case connectTimeout < 0:
+		connectTimeout = 0
+	}
+	switch {
+	case responseTimeout == 0:
+		responseTimeout = defaultResponseTimeout
+	case responseTimeout < 0:
+		responseTimeout = 0
+	}
+	tr.DialContext = (&net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}).DialContext
 	tr.TLSHandshakeTimeout = 10 * time.Second
-	tr.ResponseHeaderTimeout = 30 * time.Second
+	tr.ResponseHeaderTimeout = responseTimeout
 	tr.IdleConnTimeout = 90 * time.Second
 	tr.ExpectContinueTimeout = 1 * time.Second
 
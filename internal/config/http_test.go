@@ -2,12 +2,25 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
 
 func TestNewHTTPTransportRejectsBadProxy(t *testing.T) {
-	_, err := NewHTTPTransport("://bad-proxy")
+	_, err := NewHTTPTransport("://bad-proxy", 0, 0)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "parse proxy")
 }
+
+func TestNewHTTPTransportResponseTimeout(t *testing.T) {
+	for timeout, want := range map[time.Duration]time.Duration{
+		0:               defaultResponseTimeout,
+		5 * time.Minute: 5 * time.Minute,
+		-1:              0,
+	} {
+		tr, err := NewHTTPTransport("", 0, timeout)
+		require.NoError(t, err)
+		require.Equal(t, want, tr.ResponseHeaderTimeout, timeout)
+	}
+}
@@ -42,7 +42,7 @@ func fetchRemoteMsg(rawURL string, httpProxy string) (string, error) {
 		return "", fmt.Errorf("fetch role message: %w", err)
 	}
 
-	httpClient, err := NewHTTPClient(httpProxy)
+	httpClient, err := NewHTTPClient(httpProxy, 0, 0)
 	if err != nil {
 		return "", fmt.Errorf("fetch role message: %w", err)
 	}
@@ -82,8 +82,48 @@ func loadFileMsg(path string) (string, error) {
 	return body, nil
 }
 
+// RoleMCPServers returns the mcp-servers list in the frontmatter of the
+// markdown files among the messages of a role. It is nil when none has one,
+// and empty when the list is.
+func RoleMCPServers(setup []string) ([]string, error) {
+	var servers []string
+	for _, msg := range setup {
+		path, ok := strings.CutPrefix(msg, "file://")
+		if !ok || !strings.EqualFold(filepath.Ext(path), ".md") {
+			continue
+		}
+		bts, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read role file: %w", err)
+		}
+		var front struct {
+			MCPServers []string `yaml:"mcp-servers"`
+		}
+		if err := parseYAMLFrontmatter(string(bts), &front); err != nil {
+			return nil, err
+		}
+		if front.MCPServers != nil {
+			servers = append(append([]string{}, servers...), front.MCPServers...)
+		}
+	}
+	return servers, nil
+}
+
 // StripYAMLFrontmatter removes YAML frontmatter from markdown content.
 func StripYAMLFrontmatter(content string) (string, error) {
+	// Permissive unmarshal: frontmatter keys are discarded, only structure is validated.
+	var parsed map[string]any
+	return splitYAMLFrontmatter(content, &parsed)
+}
+
+// parseYAMLFrontmatter decodes the YAML frontmatter of markdown content
+// into v, leaving it unchanged when there is none.
+func parseYAMLFrontmatter(content string, v any) error {
+	_, err := splitYAMLFrontmatter(content, v)
+	return err
+}
+
+func splitYAMLFrontmatter(content string, v any) (string, error) {
 	lines := strings.Split(content, "\n")
 	if len(lines) == 0 {
 		return content, nil
@@ -104,9 +144,7 @@ func StripYAMLFrontmatter(content string) (string, error) {
 	}
 
 	frontmatter := strings.Join(lines[1:end], "\n")
-	// Permissive unmarshal: frontmatter keys are discarded, only structure is validated.
-	var parsed map[string]any
-	if err := yaml.Unmarshal([]byte(frontmatter), &parsed); err != nil {
+	if err := yaml.Unmarshal([]byte(frontmatter), v); err != nil {
 		return "", fmt.Errorf("invalid markdown frontmatter: %w", err)
 	}
 
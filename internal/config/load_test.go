@@ -46,3 +46,32 @@ func TestLoadMsg(t *testing.T) {
 		require.Contains(t, err.Error(), "invalid markdown frontmatter")
 	})
 }
+
+func TestRoleMCPServers(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return "file://" + path
+	}
+	coder := write("coder.md", "---\nmcp-servers: [github, jira]\n---\nYou write code.\n")
+	offline := write("offline.md", "---\nmcp-servers: []\n---\nNo tools.\n")
+	plain := write("plain.md", "---\nname: plain\n---\nHello.\n")
+	broken := write("broken.md", "---\nmcp-servers: [github\n---\n")
+
+	servers, err := RoleMCPServers([]string{"be brief", coder})
+	require.NoError(t, err)
+	require.Equal(t, []string{"github", "jira"}, servers)
+
+	servers, err = RoleMCPServers([]string{offline})
+	require.NoError(t, err)
+	require.NotNil(t, servers)
+	require.Empty(t, servers)
+
+	servers, err = RoleMCPServers([]string{plain, "file:///nonexistent.txt"})
+	require.NoError(t, err)
+	require.Nil(t, servers)
+
+	_, err = RoleMCPServers([]string{broken})
+	require.ErrorContains(t, err, "invalid markdown frontmatter")
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"cmp"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dotcommander/yai/internal/errs"
+)
+
+// ConfigPathEnv names the environment variable with the path of the
+// settings file.
+const ConfigPathEnv = "YAI_CONFIG_PATH"
+
+// Paths are where yai keeps its files.
+type Paths struct {
+	// Settings is the settings file. Roles, snippets, and themes live in
+	// directories next to it.
+	Settings string
+	// Data holds the conversations, knowledge indexes, and caches; it is
+	// the default cache-path.
+	Data string
+	// State holds the usage ledger, logs, crash reports, and the daemon
+	// socket.
+	State string
+}
+
+// ResolvePaths finds where yai keeps its files. The settings file is
+// settings when given (from --config), else $YAI_CONFIG_PATH, else yai.yml
+// under $XDG_CONFIG_HOME/yai, else ~/.config/yai/yai.yml. Data and state go
+// under $XDG_DATA_HOME/yai and $XDG_STATE_HOME/yai, ~/.local/share/yai and
+// ~/.local/state/yai by default, unless a history directory from before
+// they were used is next to the settings file: then it keeps both.
+func ResolvePaths(settings string) (Paths, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return Paths{}, errs.Wrap(err, "Could not determine home directory.")
+	}
+	settings = cmp.Or(settings, os.Getenv(ConfigPathEnv))
+	if settings == "" {
+		settings = filepath.Join(xdgDir("XDG_CONFIG_HOME", home, ".config"), "yai", "yai.yml")
+	}
+	settings, err = filepath.Abs(expandHome(settings, home))
+	if err != nil {
+		return Paths{}, errs.Wrap(err, "Could not resolve the settings file path.")
+	}
+
+	paths := Paths{
+		Settings: settings,
+		Data:     filepath.Join(xdgDir("XDG_DATA_HOME", home, ".local", "share"), "yai"),
+		State:    filepath.Join(xdgDir("XDG_STATE_HOME", home, ".local", "state"), "yai"),
+	}
+	legacy := filepath.Join(filepath.Dir(settings), "history")
+	if info, err := os.Stat(legacy); err == nil && info.IsDir() {
+		paths.Data, paths.State = legacy, legacy
+	}
+	return paths, nil
+}
+
+// FlagArg returns the value of the flag --name in the command line args,
+// for flags such as --config that are needed before the flags are parsed,
+// or "" when it is not there.
+func FlagArg(args []string, name string) string {
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return ""
+		case arg == "--"+name && i+1 < len(args):
+			return args[i+1]
+		case strings.HasPrefix(arg, "--"+name+"="):
+			return strings.TrimPrefix(arg, "--"+name+"=")
+		}
+	}
+	return ""
+}
+
+// xdgDir is the directory in the XDG variable name, or the one under home
+// the XDG base directory spec defaults it to. Relative paths are invalid in
+// XDG variables and ignored.
+func xdgDir(name, home string, def ...string) string {
+	if dir := os.Getenv(name); filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(append([]string{home}, def...)...)
+}
+
+func expandHome(path, home string) string {
+	if path == "~" {
+		return home
+	}
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		return filepath.Join(home, rest)
+	}
+	return path
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolvePaths(t *testing.T) {
+	setup := func(t *testing.T) string {
+		t.Helper()
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		t.Setenv("USERPROFILE", home)
+		for _, name := range []string{ConfigPathEnv, "XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_STATE_HOME"} {
+			t.Setenv(name, "")
+		}
+		return home
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		home := setup(t)
+		paths, err := ResolvePaths("")
+		require.NoError(t, err)
+		require.Equal(t, Paths{
+			Settings: filepath.Join(home, ".config", "yai", "yai.yml"),
+			Data:     filepath.Join(home, ".local", "share", "yai"),
+			State:    filepath.Join(home, ".local", "state", "yai"),
+		}, paths)
+	})
+
+	t.Run("xdg", func(t *testing.T) {
+		home := setup(t)
+		t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "cfg"))
+		t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+		t.Setenv("XDG_STATE_HOME", filepath.Join(home, "state"))
+		paths, err := ResolvePaths("")
+		require.NoError(t, err)
+		require.Equal(t, Paths{
+			Settings: filepath.Join(home, "cfg", "yai", "yai.yml"),
+			Data:     filepath.Join(home, "data", "yai"),
+			State:    filepath.Join(home, "state", "yai"),
+		}, paths)
+	})
+
+	t.Run("relative xdg is ignored", func(t *testing.T) {
+		home := setup(t)
+		t.Setenv("XDG_DATA_HOME", "data")
+		paths, err := ResolvePaths("")
+		require.NoError(t, err)
+		require.Equal(t, filepath.Join(home, ".local", "share", "yai"), paths.Data)
+	})
+
+	t.Run("config path", func(t *testing.T) {
+		home := setup(t)
+		t.Setenv(ConfigPathEnv, filepath.Join(home, "env.yml"))
+		paths, err := ResolvePaths("")
+		require.NoError(t, err)
+		require.Equal(t, filepath.Join(home, "env.yml"), paths.Settings)
+
+		paths, err = ResolvePaths("~/flag.yml")
+		require.NoError(t, err)
+		require.Equal(t, filepath.Join(home, "flag.yml"), paths.Settings)
+	})
+
+	t.Run("keeps history next to the settings", func(t *testing.T) {
+		home := setup(t)
+		legacy := filepath.Join(home, ".config", "yai", "history")
+		require.NoError(t, os.MkdirAll(legacy, 0o700))
+		t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+		paths, err := ResolvePaths("")
+		require.NoError(t, err)
+		require.Equal(t, legacy, paths.Data)
+		require.Equal(t, legacy, paths.State)
+	})
+}
+
+func TestFlagArg(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"hello", "--config"}, ""},
+		{[]string{"-m", "gpt-4o", "--config", "a.yml", "hi"}, "a.yml"},
+		{[]string{"config", "get", "--config=b.yml", "theme"}, "b.yml"},
+		{[]string{"--", "--config", "c.yml"}, ""},
+	} {
+		require.Equal(t, tc.want, FlagArg(tc.args, "config"), tc.args)
+	}
+}
+
+func TestStateDir(t *testing.T) {
+	c := Config{Settings: Settings{CachePath: "/cache"}}
+	require.Equal(t, "/cache", c.StateDir())
+
+	c = Config{Settings: Settings{CachePath: "/cache"}}
+	c.StatePath = "/state"
+	require.Equal(t, "/state", c.StateDir())
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	stdstrings "strings"
+)
+
+// snippetExts are the file extensions read as snippets, by preference.
+var snippetExts = []string{".md", ".txt"}
+
+// ErrUnknownSnippet is returned when no snippet file has the given name.
+var ErrUnknownSnippet = errors.New("unknown snippet")
+
+// SnippetsDir returns ~/.config/yai/snippets, next to the settings file.
+func SnippetsDir(cfg *Config) string {
+	return filepath.Join(filepath.Dir(cfg.SettingsPath), "snippets")
+}
+
+// ReadSnippet returns the text of the snippet name: the .md or .txt file of
+// that name in the snippets directory, which may be in a subdirectory such
+// as "review/rubric".
+func ReadSnippet(cfg *Config, name string) (string, error) {
+	if name == "" || !filepath.IsLocal(name) {
+		return "", fmt.Errorf("%w %q", ErrUnknownSnippet, name)
+	}
+	for _, ext := range snippetExts {
+		data, err := os.ReadFile(filepath.Join(SnippetsDir(cfg), filepath.FromSlash(name)+ext))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("read snippet %q: %w", name, err)
+		}
+		return stdstrings.TrimSpace(string(data)), nil
+	}
+	return "", fmt.Errorf("%w %q", ErrUnknownSnippet, name)
+}
+
+// ListSnippets returns the names of the snippets, sorted.
+func ListSnippets(cfg *Config) ([]string, error) {
+	dir := SnippetsDir(cfg)
+	var names []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		ext := filepath.Ext(path)
+		if d.IsDir() || !slices.Contains(snippetExts, ext) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return fmt.Errorf("resolve snippet path %q: %w", path, err)
+		}
+		names = append(names, stdstrings.TrimSuffix(filepath.ToSlash(rel), filepath.Ext(rel)))
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read snippets directory %q: %w", dir, err)
+	}
+	slices.Sort(names)
+	return slices.Compact(names), nil
+}
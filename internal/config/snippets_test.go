@@ -0,0 +1,54 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnippets(t *testing.T) {
+	cfg := &Config{}
+	cfg.SettingsPath = filepath.Join(t.TempDir(), "yai.yml")
+
+	t.Run("no directory", func(t *testing.T) {
+		names, err := ListSnippets(cfg)
+		require.NoError(t, err)
+		require.Empty(t, names)
+	})
+
+	dir := SnippetsDir(cfg)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "review"), 0o700))
+	for name, content := range map[string]string{
+		"bug.md":           "Steps to reproduce:\n",
+		"bug.txt":          "shadowed by bug.md",
+		"review/rubric.md": "Check errors.",
+		"notes.json":       "{}",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+
+	t.Run("list", func(t *testing.T) {
+		names, err := ListSnippets(cfg)
+		require.NoError(t, err)
+		require.Equal(t, []string{"bug", "review/rubric"}, names)
+	})
+
+	t.Run("read", func(t *testing.T) {
+		text, err := ReadSnippet(cfg, "bug")
+		require.NoError(t, err)
+		require.Equal(t, "Steps to reproduce:", text)
+
+		text, err = ReadSnippet(cfg, "review/rubric")
+		require.NoError(t, err)
+		require.Equal(t, "Check errors.", text)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		for _, name := range []string{"nope", "", "../yai", "/etc/passwd"} {
+			_, err := ReadSnippet(cfg, name)
+			require.ErrorIs(t, err, ErrUnknownSnippet, name)
+		}
+	})
+}
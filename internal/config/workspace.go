@@ -0,0 +1,185 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	stdstrings "strings"
+
+	"github.com/dotcommander/yai/internal/errs"
+)
+
+// WorkspaceEnv names the environment variable with the workspace to use.
+const WorkspaceEnv = "YAI_WORKSPACE"
+
+// DefaultWorkspace is the name of the workspace yai uses when none is
+// chosen: the one with no settings of its own, whose conversations are in
+// cache-path itself.
+const DefaultWorkspace = "default"
+
+// activeWorkspaceFile holds the workspace chosen with `yai workspace use`,
+// in the workspaces directory.
+const activeWorkspaceFile = "active"
+
+var workspaceName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// WorkspacesDir returns ~/.config/yai/workspaces, next to the settings
+// file, which has the settings of each workspace.
+func WorkspacesDir(cfg *Config) string {
+	return filepath.Join(filepath.Dir(cfg.SettingsPath), "workspaces")
+}
+
+// WorkspaceSettingsPath returns the settings file of workspace name. The
+// keys set in it override those of the settings file.
+func WorkspaceSettingsPath(cfg *Config, name string) string {
+	return filepath.Join(WorkspacesDir(cfg), name+".yml")
+}
+
+// CheckWorkspaceName returns an error when name cannot name a workspace.
+func CheckWorkspaceName(name string) error {
+	if !workspaceName.MatchString(name) {
+		return errs.UserErrorf("%q is not a valid workspace name: use letters, digits, '-', '_', and '.', starting with a letter or digit", name)
+	}
+	return nil
+}
+
+// ListWorkspaces returns the names of the workspaces, sorted: the default
+// one, the ones with settings, and the ones with conversations.
+func ListWorkspaces(cfg *Config) ([]string, error) {
+	names := []string{DefaultWorkspace}
+	entries, err := os.ReadDir(WorkspacesDir(cfg))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("read workspaces directory: %w", err)
+	}
+	for _, e := range entries {
+		if name, ok := stdstrings.CutSuffix(e.Name(), ".yml"); ok && !e.IsDir() {
+			names = append(names, name)
+		}
+	}
+	entries, err = os.ReadDir(filepath.Join(cfg.baseCachePath(), "workspaces"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("read workspaces directory: %w", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names), nil
+}
+
+// CreateWorkspace creates the settings file of workspace name, and reports
+// whether it did not exist yet.
+func CreateWorkspace(cfg *Config, name string) (bool, error) {
+	if err := CheckWorkspaceName(name); err != nil {
+		return false, err
+	}
+	if name == DefaultWorkspace {
+		return false, nil
+	}
+	path := WorkspaceSettingsPath(cfg, name)
+	if _, err := os.Stat(path); err == nil {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return false, fmt.Errorf("create workspaces directory: %w", err)
+	}
+	content := fmt.Sprintf("# Settings of the %s workspace. Keys set here override those of\n# %s while it is used.\n", name, cfg.SettingsPath)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		return false, fmt.Errorf("write workspace settings: %w", err)
+	}
+	return true, nil
+}
+
+// UseWorkspace makes name the workspace used when neither --workspace nor
+// YAI_WORKSPACE choose one.
+func UseWorkspace(cfg *Config, name string) error {
+	if err := CheckWorkspaceName(name); err != nil {
+		return err
+	}
+	path := filepath.Join(WorkspacesDir(cfg), activeWorkspaceFile)
+	if name == DefaultWorkspace {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("reset workspace: %w", err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create workspaces directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(name+"\n"), 0o600); err != nil {
+		return fmt.Errorf("write active workspace: %w", err)
+	}
+	return nil
+}
+
+// chooseWorkspace returns the workspace to use: name when given (from
+// --workspace), else $YAI_WORKSPACE, else the one of `yai workspace use`.
+func chooseWorkspace(settingsPath, name string) (string, error) {
+	if name == "" {
+		name = os.Getenv(WorkspaceEnv)
+	}
+	if name == "" {
+		data, err := os.ReadFile(filepath.Join(filepath.Dir(settingsPath), "workspaces", activeWorkspaceFile))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", errs.Wrap(err, "Could not read the active workspace.")
+		}
+		name = stdstrings.TrimSpace(string(data))
+	}
+	if name == "" || name == DefaultWorkspace {
+		return "", nil
+	}
+	if err := CheckWorkspaceName(name); err != nil {
+		return "", errs.Wrap(err, "Invalid workspace.")
+	}
+	return name, nil
+}
+
+// applyWorkspace overrides the settings in c with those of its workspace.
+func applyWorkspace(c *Config) error {
+	content, err := os.ReadFile(WorkspaceSettingsPath(c, c.Workspace))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return errs.Wrap(err, "Could not read the settings of workspace "+c.Workspace+".")
+	}
+	unknown, err := decodeSettings(content, c)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return errs.Wrap(err, "Could not parse the settings of workspace "+c.Workspace+".")
+	}
+	if len(unknown) > 0 {
+		return errs.Wrap(
+			errs.UserErrorf("line %d: %s is not a setting yai knows", unknown[0].Line, unknown[0].Path),
+			"Could not parse the settings of workspace "+c.Workspace+".",
+		)
+	}
+	return nil
+}
+
+// useWorkspaceCache moves the conversations, knowledge indexes, and caches
+// of c to the directory of its workspace in cache-path. The usage ledger and
+// logs stay where they are, so budgets and provider health cover every
+// workspace.
+func useWorkspaceCache(c *Config) {
+	if c.Workspace == "" {
+		return
+	}
+	c.StatePath = c.StateDir()
+	c.CachePath = filepath.Join(c.CachePath, "workspaces", c.Workspace)
+}
+
+// baseCachePath is the cache-path of the default workspace.
+func (c *Config) baseCachePath() string {
+	if c.Workspace != "" && filepath.Base(c.CachePath) == c.Workspace &&
+		filepath.Base(filepath.Dir(c.CachePath)) == "workspaces" {
+		return filepath.Dir(filepath.Dir(c.CachePath))
+	}
+	return c.CachePath
+}
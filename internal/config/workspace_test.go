@@ -0,0 +1,103 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkspaces(t *testing.T) {
+	setup := func(t *testing.T) string {
+		t.Helper()
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		t.Setenv("USERPROFILE", home)
+		for _, name := range []string{ConfigPathEnv, WorkspaceEnv, "XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_STATE_HOME"} {
+			t.Setenv(name, "")
+		}
+		return home
+	}
+
+	t.Run("default", func(t *testing.T) {
+		home := setup(t)
+		c, err := Ensure("", "")
+		require.NoError(t, err)
+		require.Empty(t, c.Workspace)
+		require.Equal(t, filepath.Join(home, ".local", "share", "yai"), c.CachePath)
+
+		names, err := ListWorkspaces(&c)
+		require.NoError(t, err)
+		require.Equal(t, []string{DefaultWorkspace}, names)
+	})
+
+	t.Run("settings and cache of a workspace", func(t *testing.T) {
+		home := setup(t)
+		c, err := Ensure("", "")
+		require.NoError(t, err)
+		created, err := CreateWorkspace(&c, "work")
+		require.NoError(t, err)
+		require.True(t, created)
+		created, err = CreateWorkspace(&c, "work")
+		require.NoError(t, err)
+		require.False(t, created)
+
+		c, err = Ensure("", "work")
+		require.NoError(t, err, "a settings file with only comments is fine")
+		require.Equal(t, "work", c.Workspace)
+		require.Equal(t, filepath.Join(home, ".local", "share", "yai", "workspaces", "work"), c.CachePath)
+		require.Equal(t, filepath.Join(home, ".local", "state", "yai"), c.StateDir())
+		require.DirExists(t, filepath.Join(c.CachePath, "conversations"))
+
+		require.NoError(t, os.WriteFile(WorkspaceSettingsPath(&c, "work"), []byte("default-model: work-model\nword-wrap: 60\n"), 0o600))
+		t.Setenv("YAI_WORD_WRAP", "70")
+		c, err = Ensure("", "work")
+		require.NoError(t, err)
+		require.Equal(t, "work-model", c.Model)
+		require.Equal(t, 70, c.WordWrap, "the environment wins over the workspace")
+
+		names, err := ListWorkspaces(&c)
+		require.NoError(t, err)
+		require.Equal(t, []string{DefaultWorkspace, "work"}, names)
+	})
+
+	t.Run("use", func(t *testing.T) {
+		setup(t)
+		c, err := Ensure("", "")
+		require.NoError(t, err)
+		require.NoError(t, UseWorkspace(&c, "personal"))
+
+		c, err = Ensure("", "")
+		require.NoError(t, err)
+		require.Equal(t, "personal", c.Workspace)
+
+		t.Setenv(WorkspaceEnv, "work")
+		c, err = Ensure("", "")
+		require.NoError(t, err)
+		require.Equal(t, "work", c.Workspace)
+
+		c, err = Ensure("", DefaultWorkspace)
+		require.NoError(t, err)
+		require.Empty(t, c.Workspace)
+
+		t.Setenv(WorkspaceEnv, "")
+		require.NoError(t, UseWorkspace(&c, DefaultWorkspace))
+		c, err = Ensure("", "")
+		require.NoError(t, err)
+		require.Empty(t, c.Workspace)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		setup(t)
+		_, err := Ensure("", "../work")
+		require.ErrorContains(t, err, "not a valid workspace name")
+
+		c, err := Ensure("", "")
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(WorkspacesDir(&c), 0o700))
+		require.NoError(t, os.WriteFile(WorkspaceSettingsPath(&c, "work"), []byte("default-modle: x\n"), 0o600))
+		_, err = Ensure("", "work")
+		require.ErrorContains(t, err, "line 1: default-modle is not a setting yai knows")
+	})
+}
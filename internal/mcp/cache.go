@@ -0,0 +1,76 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/storage/cache"
+)
+
+// toolsCacheID identifies the cached tools of server by how it is reached,
+// so that changing its command or arguments lists them again.
+func toolsCacheID(server config.MCPServerConfig) string {
+	key, _ := json.Marshal([]any{server.Type, server.Command, server.Args, server.URL, server.ProtocolVersion})
+	sum := sha256.Sum256(key)
+	return "mcp-tools-" + hex.EncodeToString(sum[:])[:16]
+}
+
+// toolsCache returns the cache of server tool lists, or nil when
+// mcp-tools-cache-ttl disables it.
+func (s *Service) toolsCache() *cache.ExpiringCache[[]mcp.Tool] {
+	if s.cfg.MCPToolsCacheTTL < 0 || s.cfg.CachePath == "" {
+		return nil
+	}
+	c, err := cache.NewExpiring[[]mcp.Tool](s.cfg.CachePath)
+	if err != nil {
+		return nil
+	}
+	return c
+}
+
+// cachedTools returns the tools server offered within mcp-tools-cache-ttl.
+func (s *Service) cachedTools(server config.MCPServerConfig) ([]mcp.Tool, bool) {
+	c := s.toolsCache()
+	if c == nil {
+		return nil, false
+	}
+	var tools []mcp.Tool
+	if err := c.Read(toolsCacheID(server), func(r io.Reader) error {
+		return json.NewDecoder(r).Decode(&tools)
+	}); err != nil {
+		return nil, false
+	}
+	return tools, true
+}
+
+// cacheTools saves the tools server offers. Failures only cost a listing.
+func (s *Service) cacheTools(server config.MCPServerConfig, tools []mcp.Tool) {
+	c := s.toolsCache()
+	if c == nil {
+		return
+	}
+	expiresAt := time.Now().Add(s.cfg.MCPToolsCacheTTL).Unix()
+	_ = c.Write(toolsCacheID(server), expiresAt, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(tools)
+	})
+}
+
+// Refresh drops the cached tools of the enabled servers and lists them again.
+func (s *Service) Refresh(ctx context.Context) (map[string][]mcp.Tool, error) {
+	if c := s.toolsCache(); c != nil {
+		for _, server := range s.EnabledServers() {
+			if err := c.Delete(toolsCacheID(server)); err != nil {
+				return nil, fmt.Errorf("mcp refresh: %w", err)
+			}
+		}
+	}
+	return s.Tools(ctx)
+}
@@ -0,0 +1,69 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dotcommander/yai/internal/config"
+)
+
+func TestToolsCache(t *testing.T) {
+	srv := server.NewMCPServer("test", "1.0.0")
+	srv.AddTool(mcp.NewTool("read_file", mcp.WithString("path", mcp.Required())), func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+	ctx := context.Background()
+	connect := func(s *Service) {
+		cli, err := client.NewInProcessClient(srv)
+		require.NoError(t, err)
+		require.NoError(t, cli.Start(ctx))
+		_, err = cli.Initialize(ctx, mcp.InitializeRequest{})
+		require.NoError(t, err)
+		s.clients["fs"] = cli
+		t.Cleanup(func() { _ = cli.Close() })
+	}
+
+	cfg := &config.Config{Settings: config.Settings{
+		CachePath:        t.TempDir(),
+		MCPServers:       map[string]config.MCPServerConfig{"fs": {Command: "/nonexistent/mcp-server-fs"}},
+		MCPToolsCacheTTL: time.Hour,
+	}}
+	s := New(cfg)
+	connect(s)
+	tools, err := s.Tools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools["fs"], 1)
+
+	// The server cannot start, so the tools must come from the cache.
+	s = New(cfg)
+	tools, err = s.Tools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools["fs"], 1)
+	require.Equal(t, "read_file", tools["fs"][0].Name)
+	require.Equal(t, []string{"path"}, tools["fs"][0].InputSchema.Required)
+	require.Empty(t, s.clients, "no server was started")
+
+	_, err = s.Refresh(ctx)
+	require.ErrorContains(t, err, "could not setup fs")
+
+	cfg.MCPToolsCacheTTL = -1
+	s = New(cfg)
+	connect(s)
+	_, err = s.Tools(ctx)
+	require.NoError(t, err)
+	_, err = New(cfg).Tools(ctx)
+	require.Error(t, err, "the cache is disabled")
+}
+
+func TestToolsCacheID(t *testing.T) {
+	a := config.MCPServerConfig{Command: "mcp-server-filesystem", Args: []string{"/tmp"}}
+	b := config.MCPServerConfig{Command: "mcp-server-filesystem", Args: []string{"/srv"}}
+	require.Equal(t, toolsCacheID(a), toolsCacheID(a))
+	require.NotEqual(t, toolsCacheID(a), toolsCacheID(b))
+}
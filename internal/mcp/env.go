@@ -0,0 +1,17 @@
+package mcp
+
+import (
+	"os"
+
+	"github.com/dotcommander/yai/internal/config"
+)
+
+// stdioEnv is the environment a stdio server is started with: yai's own and
+// the server's env, or with mcp-no-inherit-env, the server's env and what
+// the platform needs to run programs at all.
+func stdioEnv(cfg *config.Config, server config.MCPServerConfig) []string {
+	if cfg != nil && !cfg.MCPNoInheritEnv {
+		return append(os.Environ(), server.Env...)
+	}
+	return append(systemEnv(os.Environ()), server.Env...)
+}
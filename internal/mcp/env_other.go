@@ -0,0 +1,9 @@
+//go:build !windows
+
+package mcp
+
+// systemEnv returns the variables of environ every program needs. There
+// are none outside Windows.
+func systemEnv([]string) []string {
+	return nil
+}
@@ -0,0 +1,22 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dotcommander/yai/internal/config"
+)
+
+func TestStdioEnv(t *testing.T) {
+	t.Setenv("YAI_TEST_SECRET", "hunter2")
+	server := config.MCPServerConfig{Env: []string{"GITHUB_TOKEN=abc"}}
+
+	env := stdioEnv(&config.Config{}, server)
+	require.Contains(t, env, "YAI_TEST_SECRET=hunter2")
+	require.Equal(t, "GITHUB_TOKEN=abc", env[len(env)-1], "the server's env wins")
+
+	env = stdioEnv(&config.Config{Settings: config.Settings{MCPNoInheritEnv: true}}, server)
+	require.NotContains(t, env, "YAI_TEST_SECRET=hunter2")
+	require.Contains(t, env, "GITHUB_TOKEN=abc")
+}
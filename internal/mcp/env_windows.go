@@ -0,0 +1,26 @@
+package mcp
+
+import (
+	"slices"
+	"strings"
+)
+
+// systemVars are the variables Windows programs fail without: the system
+// directories, where to find programs, and the user's profile and temporary
+// directories.
+var systemVars = []string{ //nolint:gochecknoglobals
+	"APPDATA", "COMSPEC", "LOCALAPPDATA", "PATH", "PATHEXT", "PROGRAMDATA",
+	"PROGRAMFILES", "SYSTEMDRIVE", "SYSTEMROOT", "TEMP", "TMP", "USERPROFILE", "WINDIR",
+}
+
+// systemEnv returns the variables of environ every program needs.
+func systemEnv(environ []string) []string {
+	var out []string
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		if slices.Contains(systemVars, strings.ToUpper(name)) {
+			out = append(out, kv)
+		}
+	}
+	return out
+}
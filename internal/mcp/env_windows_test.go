@@ -0,0 +1,12 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemEnv(t *testing.T) {
+	got := systemEnv([]string{`SystemRoot=C:\Windows`, `Path=C:\Windows\system32`, "GITHUB_TOKEN=abc", `TEMP=C:\Temp`})
+	require.Equal(t, []string{`SystemRoot=C:\Windows`, `Path=C:\Windows\system32`, `TEMP=C:\Temp`}, got)
+}
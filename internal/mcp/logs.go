@@ -0,0 +1,175 @@
+package mcp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dotcommander/yai/internal/config"
+)
+
+const (
+	// maxServerLog is the size at which a server log is rotated, keeping
+	// the previous one as <server>.log.1.
+	maxServerLog = 1 << 20
+	// serverLogTail is how many of the last stderr lines a failed start
+	// shows.
+	serverLogTail = 10
+	// tailWait is how long a failed start waits for the server to finish
+	// writing to stderr.
+	tailWait = 200 * time.Millisecond
+)
+
+// serverLog keeps what a stdio server writes to stderr: in a log file per
+// server under the cache path, and its last lines in memory, to explain a
+// server that fails to start.
+type serverLog struct {
+	path string
+	done chan struct{}
+
+	mu      sync.Mutex
+	f       *os.File
+	size    int64
+	tail    []string
+	partial []byte
+}
+
+// serverLogPath is where the stderr of server name is logged, or "" when
+// there is no state directory.
+func serverLogPath(cfg *config.Config, name string) string {
+	if cfg == nil || cfg.StateDir() == "" {
+		return ""
+	}
+	name = strings.NewReplacer("/", "_", "\\", "_").Replace(name)
+	return filepath.Join(cfg.StateDir(), "mcp-logs", name+".log")
+}
+
+// openServerLog starts the log of a server being started with command. The
+// log is kept in memory only when the file cannot be written. Arguments are
+// left out of it, since they may hold tokens.
+func openServerLog(path, command string) *serverLog {
+	l := &serverLog{path: path, done: make(chan struct{})}
+	if path == "" {
+		return l
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		l.path = ""
+		return l
+	}
+	l.open()
+	l.writeFile(fmt.Appendf(nil, "--- %s: started %s\n", time.Now().Format(time.RFC3339), command))
+	return l
+}
+
+// open opens the log file for appending, rotating it first when it is too
+// big.
+func (l *serverLog) open() {
+	if info, err := os.Stat(l.path); err == nil && info.Size() >= maxServerLog {
+		_ = os.Rename(l.path, l.path+".1")
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		l.path = ""
+		return
+	}
+	l.f = f
+	if info, err := f.Stat(); err == nil {
+		l.size = info.Size()
+	}
+}
+
+func (l *serverLog) writeFile(p []byte) {
+	if l.f == nil {
+		return
+	}
+	if l.size > 0 && l.size+int64(len(p)) > maxServerLog {
+		l.f.Close() //nolint:errcheck,gosec
+		l.f = nil
+		_ = os.Rename(l.path, l.path+".1")
+		l.open()
+		if l.f == nil {
+			return
+		}
+	}
+	n, _ := l.f.Write(p)
+	l.size += int64(n)
+}
+
+// Write implements io.Writer.
+func (l *serverLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.writeFile(p)
+	l.partial = append(l.partial, p...)
+	for {
+		i := bytes.IndexByte(l.partial, '\n')
+		if i < 0 {
+			break
+		}
+		if line := strings.TrimRight(string(l.partial[:i]), "\r"); line != "" {
+			l.tail = append(l.tail, line)
+		}
+		l.partial = l.partial[i+1:]
+	}
+	if len(l.tail) > serverLogTail {
+		l.tail = l.tail[len(l.tail)-serverLogTail:]
+	}
+	return len(p), nil
+}
+
+// capture copies stderr to the log until the server exits.
+func (l *serverLog) capture(stderr io.Reader) {
+	defer close(l.done)
+	if stderr != nil {
+		_, _ = io.Copy(l, stderr)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f != nil {
+		l.f.Close() //nolint:errcheck,gosec
+		l.f = nil
+	}
+}
+
+// explain adds the last lines the server wrote to stderr, and where the
+// rest is, to err, the reason it failed to start.
+func (l *serverLog) explain(err error) error {
+	if l == nil {
+		return err
+	}
+	select {
+	case <-l.done:
+	case <-time.After(tailWait):
+	}
+	l.mu.Lock()
+	tail := l.tail
+	if len(l.partial) > 0 {
+		tail = append(tail[:len(tail):len(tail)], string(l.partial))
+	}
+	if len(tail) > serverLogTail {
+		tail = tail[len(tail)-serverLogTail:]
+	}
+	l.mu.Unlock()
+
+	if len(tail) == 0 {
+		if l.path == "" {
+			return err
+		}
+		return fmt.Errorf("%w (server log: %s)", err, l.path)
+	}
+	var b strings.Builder
+	b.WriteString("server stderr")
+	if l.path != "" {
+		fmt.Fprintf(&b, " (full log: %s)", l.path)
+	}
+	b.WriteString(":")
+	for _, line := range tail {
+		b.WriteString("\n  " + line)
+	}
+	return fmt.Errorf("%w\n%s", err, b.String())
+}
@@ -0,0 +1,65 @@
+package mcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dotcommander/yai/internal/config"
+)
+
+func TestServerLogFailedStart(t *testing.T) {
+	cfg := &config.Config{Settings: config.Settings{CachePath: t.TempDir()}}
+	server := config.MCPServerConfig{
+		Command: "sh",
+		Args:    []string{"-c", "echo 'loading config' >&2; echo 'error: GITHUB_TOKEN is not set' >&2; exit 1"},
+	}
+	_, err := initClient(context.Background(), cfg, "github", server)
+	require.Error(t, err)
+	path := filepath.Join(cfg.CachePath, "mcp-logs", "github.log")
+	require.ErrorContains(t, err, "server stderr (full log: "+path+"):\n  loading config\n  error: GITHUB_TOKEN is not set")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(data), ": started sh\n")
+	require.Contains(t, string(data), "error: GITHUB_TOKEN is not set\n")
+}
+
+func TestServerLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mcp-logs", "fs.log")
+	l := openServerLog(path, "mcp-server-fs")
+	for i := range serverLogTail + 5 {
+		_, err := l.Write([]byte("line " + strings.Repeat("x", i) + "\n"))
+		require.NoError(t, err)
+	}
+	_, _ = l.Write([]byte("no newline"))
+	go l.capture(nil)
+
+	err := l.explain(context.DeadlineExceeded)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	lines := strings.Split(err.Error(), "\n")
+	require.Len(t, lines, serverLogTail+2, "the error, the heading, and the tail")
+	require.Equal(t, "  no newline", lines[len(lines)-1])
+
+	t.Run("rotates", func(t *testing.T) {
+		l := openServerLog(path, "mcp-server-fs")
+		_, err := l.Write([]byte(strings.Repeat("x", maxServerLog)))
+		require.NoError(t, err)
+		l.capture(nil)
+		_, err = os.Stat(path + ".1")
+		require.NoError(t, err)
+	})
+
+	t.Run("without a file", func(t *testing.T) {
+		l := openServerLog("", "mcp-server-fs")
+		l.capture(strings.NewReader("oops\n"))
+		require.EqualError(t, l.explain(context.Canceled), "context canceled\nserver stderr:\n  oops")
+		l = openServerLog("", "mcp-server-fs")
+		l.capture(nil)
+		require.EqualError(t, l.explain(context.Canceled), "context canceled")
+	})
+}
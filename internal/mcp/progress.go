@@ -0,0 +1,66 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/dotcommander/yai/internal/proto"
+)
+
+const methodProgress = "notifications/progress"
+
+type progressKey struct{}
+
+// WithProgress returns ctx asking servers for the progress of the tools
+// called with it, which is passed to report as it comes.
+func WithProgress(ctx context.Context, report func(proto.ToolProgress)) context.Context {
+	return context.WithValue(ctx, progressKey{}, report)
+}
+
+// progressWatch is a tool call that asked for progress.
+type progressWatch struct {
+	name   string
+	report func(proto.ToolProgress)
+}
+
+// watchProgress returns the progress token of a call to tool name, whose
+// progress goes to report until unwatchProgress.
+func (s *Service) watchProgress(name string, report func(proto.ToolProgress)) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastToken++
+	token := fmt.Sprintf("yai-%d", s.lastToken)
+	if s.watches == nil {
+		s.watches = map[string]progressWatch{}
+	}
+	s.watches[token] = progressWatch{name: name, report: report}
+	return token
+}
+
+func (s *Service) unwatchProgress(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.watches, token)
+}
+
+// onNotification passes progress notifications to the call they are for.
+func (s *Service) onNotification(n mcp.JSONRPCNotification) {
+	if n.Method != methodProgress {
+		return
+	}
+	fields := n.Params.AdditionalFields
+	token, _ := fields["progressToken"].(string)
+	s.mu.Lock()
+	watch, ok := s.watches[token]
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	p := proto.ToolProgress{Name: watch.name}
+	p.Progress, _ = fields["progress"].(float64)
+	p.Total, _ = fields["total"].(float64)
+	p.Message, _ = fields["message"].(string)
+	watch.report(p)
+}
@@ -0,0 +1,62 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+)
+
+func TestCallToolProgress(t *testing.T) {
+	var s *Service
+	srv := server.NewMCPServer("test", "1.0.0")
+	srv.AddTool(mcp.NewTool("search_issues"), func(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var token mcp.ProgressToken
+		if req.Params.Meta != nil {
+			token = req.Params.Meta.ProgressToken
+		}
+		// What the server would send while it works.
+		for _, p := range []map[string]any{
+			{"progressToken": token, "progress": 2.0, "total": 5.0},
+			{"progressToken": "someone-else", "progress": 1.0},
+			{"progressToken": token, "progress": 5.0, "total": 5.0, "message": "done"},
+		} {
+			n := mcp.JSONRPCNotification{Notification: mcp.Notification{Method: methodProgress}}
+			n.Params.AdditionalFields = p
+			s.onNotification(n)
+		}
+		return mcp.NewToolResultText("3 issues"), nil
+	})
+	cli, err := client.NewInProcessClient(srv)
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, cli.Start(ctx))
+	_, err = cli.Initialize(ctx, mcp.InitializeRequest{})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = cli.Close() })
+
+	s = New(&config.Config{Settings: config.Settings{
+		MCPServers: map[string]config.MCPServerConfig{"github": {}},
+	}})
+	s.clients["github"] = cli
+
+	var got []string
+	out, err := s.CallTool(WithProgress(ctx, func(p proto.ToolProgress) {
+		got = append(got, p.String())
+	}), "github_search_issues", nil)
+	require.NoError(t, err)
+	require.Equal(t, "3 issues", out)
+	require.Equal(t, []string{"github_search_issues: 40%", "github_search_issues: 100% · done"}, got)
+	require.Empty(t, s.watches, "the call stops watching once it returns")
+
+	got = nil
+	_, err = s.CallTool(ctx, "github_search_issues", nil)
+	require.NoError(t, err)
+	require.Empty(t, got, "no progress was asked for")
+}
@@ -0,0 +1,70 @@
+package mcp
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// mutatingWords are words in tool names that mark tools that change things.
+var mutatingWords = map[string]bool{
+	"add": true, "append": true, "apply": true, "approve": true, "archive": true,
+	"assign": true, "cancel": true, "click": true, "close": true, "comment": true,
+	"commit": true, "copy": true, "cp": true, "create": true, "delete": true,
+	"deploy": true, "dispatch": true, "drop": true, "edit": true, "exec": true,
+	"execute": true, "fill": true, "fork": true, "install": true, "insert": true,
+	"kill": true, "merge": true, "mkdir": true, "modify": true, "move": true,
+	"mv": true, "patch": true, "post": true, "press": true, "publish": true,
+	"push": true, "put": true, "remove": true, "rename": true, "replace": true,
+	"reset": true, "restart": true, "revert": true, "rm": true, "run": true,
+	"send": true, "set": true, "shell": true, "start": true, "stop": true,
+	"submit": true, "terminal": true, "trigger": true, "truncate": true,
+	"uninstall": true, "update": true, "upload": true, "upsert": true,
+	"write": true,
+}
+
+// IsReadOnly reports whether tool only reads: the server marks it
+// read-only, or no word of its name, such as write or delete, marks a
+// change. Other hints are not trusted, as many servers send the spec's
+// defaults for every tool.
+func IsReadOnly(tool mcp.Tool) bool {
+	if hint := tool.Annotations.ReadOnlyHint; hint != nil && *hint {
+		return true
+	}
+	for _, word := range nameWords(tool.Name) {
+		if mutatingWords[word] {
+			return false
+		}
+	}
+	return true
+}
+
+// nameWords splits a tool name such as "createIssue" or "delete-file" into
+// lower-case words.
+func nameWords(name string) []string {
+	var (
+		words []string
+		word  []rune
+	)
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) ||
+			(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))):
+			flush()
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
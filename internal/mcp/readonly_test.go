@@ -0,0 +1,83 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dotcommander/yai/internal/config"
+)
+
+func TestIsReadOnly(t *testing.T) {
+	for name, want := range map[string]bool{
+		"read_file":         true,
+		"list-issues":       true,
+		"getPullRequest":    true,
+		"search_code":       true,
+		"write_file":        false,
+		"delete-branch":     false,
+		"createIssue":       false,
+		"run_command":       false,
+		"browser_click":     false,
+		"executeSQLQuery":   false,
+		"get_rate_limit":    true,
+		"list_set_members":  false,
+		"HTMLToMarkdown":    true,
+		"list_directory_v2": true,
+	} {
+		require.Equal(t, want, IsReadOnly(mcp.NewTool(name)), name)
+	}
+
+	require.True(t, IsReadOnly(mcp.NewTool("run_query", mcp.WithReadOnlyHintAnnotation(true))), "the server knows best")
+	require.True(t, IsReadOnly(mcp.NewTool("fetch", mcp.WithReadOnlyHintAnnotation(false), mcp.WithDestructiveHintAnnotation(true))),
+		"default hints are not trusted")
+}
+
+func TestNameWords(t *testing.T) {
+	require.Equal(t, []string{"get", "pull", "request"}, nameWords("getPullRequest"))
+	require.Equal(t, []string{"execute", "sql", "query"}, nameWords("executeSQLQuery"))
+	require.Equal(t, []string{"list", "directory", "v2"}, nameWords("list_directory-v2"))
+}
+
+func TestToolsReadOnly(t *testing.T) {
+	srv := server.NewMCPServer("test", "1.0.0")
+	handler := func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	}
+	srv.AddTool(mcp.NewTool("read_file"), handler)
+	srv.AddTool(mcp.NewTool("write_file"), handler)
+	cli, err := client.NewInProcessClient(srv)
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, cli.Start(ctx))
+	_, err = cli.Initialize(ctx, mcp.InitializeRequest{})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = cli.Close() })
+
+	cfg := &config.Config{Settings: config.Settings{
+		MCPServers:  map[string]config.MCPServerConfig{"fs": {}},
+		MCPReadOnly: true,
+	}}
+	s := New(cfg)
+	s.clients["fs"] = cli
+
+	tools, err := s.Tools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools["fs"], 1)
+	require.Equal(t, "read_file", tools["fs"][0].Name)
+
+	out, err := s.CallTool(ctx, "fs_read_file", nil)
+	require.NoError(t, err)
+	require.Equal(t, "ok", out)
+	_, err = s.CallTool(ctx, "fs_write_file", []byte(`{}`))
+	require.ErrorContains(t, err, `"fs_write_file" may change things and mcp-read-only is set`)
+
+	cfg.MCPReadOnly = false
+	tools, err = s.Tools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools["fs"], 2)
+}
@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+)
+
+// roots returns the directories servers may work in: mcp-roots, or the
+// working directory when there are none.
+func roots(cfg *config.Config) ([]mcp.Root, error) {
+	paths := cfg.MCPRoots
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	out := make([]mcp.Root, 0, len(paths))
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err == nil {
+			_, err = os.Stat(abs)
+		}
+		if err != nil {
+			return nil, errs.Wrap(
+				errs.UserErrorf("mcp-roots has %q: %v", path, err),
+				"Invalid mcp-roots settings.",
+			)
+		}
+		out = append(out, mcp.Root{URI: fileURI(abs), Name: filepath.Base(abs)})
+	}
+	return out, nil
+}
+
+// fileURI returns the file:// URI of the absolute path abs.
+func fileURI(abs string) string {
+	p := filepath.ToSlash(abs)
+	if !strings.HasPrefix(p, "/") {
+		// A Windows path, such as C:/src.
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
+
+// rootsHandler answers the servers asking which directories they may work
+// in.
+type rootsHandler struct {
+	roots []mcp.Root
+}
+
+func (h rootsHandler) ListRoots(context.Context, mcp.ListRootsRequest) (*mcp.ListRootsResult, error) {
+	return &mcp.ListRootsResult{Roots: h.roots}, nil
+}
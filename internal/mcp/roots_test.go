@@ -0,0 +1,40 @@
+package mcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dotcommander/yai/internal/config"
+)
+
+func TestRoots(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	got, err := roots(&config.Config{})
+	require.NoError(t, err)
+	require.Equal(t, []mcp.Root{{URI: fileURI(wd), Name: filepath.Base(wd)}}, got, "the working directory by default")
+
+	dir := filepath.Join(t.TempDir(), "my project")
+	require.NoError(t, os.Mkdir(dir, 0o700))
+	got, err = roots(&config.Config{Settings: config.Settings{MCPRoots: []string{dir}}})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Equal(t, "my project", got[0].Name)
+	require.Equal(t, "file://"+filepath.ToSlash(filepath.Dir(dir))+"/my%20project", got[0].URI)
+
+	res, err := rootsHandler{roots: got}.ListRoots(context.Background(), mcp.ListRootsRequest{})
+	require.NoError(t, err)
+	require.Equal(t, got, res.Roots)
+
+	_, err = roots(&config.Config{Settings: config.Settings{MCPRoots: []string{filepath.Join(dir, "missing")}}})
+	require.ErrorContains(t, err, "mcp-roots has")
+}
+
+func TestFileURI(t *testing.T) {
+	require.Equal(t, "file:///home/me/src", fileURI("/home/me/src"))
+}
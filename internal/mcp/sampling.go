@@ -0,0 +1,167 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/proto"
+)
+
+// What to do when a server asks for a completion, the values of
+// mcp-sampling.
+const (
+	SamplingOff   = "off"
+	SamplingAsk   = "ask"
+	SamplingAllow = "allow"
+)
+
+// SamplingModes are the values of mcp-sampling, the default first.
+var SamplingModes = []string{SamplingOff, SamplingAsk, SamplingAllow} //nolint:gochecknoglobals
+
+// SamplingRequest is a completion a server asks the model for.
+type SamplingRequest struct {
+	Server      string
+	System      string
+	Messages    []proto.Message
+	MaxTokens   int64
+	Temperature float64
+}
+
+// Sampler runs a SamplingRequest, returning the answer and the model that
+// wrote it.
+type Sampler func(ctx context.Context, req SamplingRequest) (content, model string, err error)
+
+// errSamplingDeclined is returned to servers whose request was not approved.
+var errSamplingDeclined = errors.New("the user declined the sampling request")
+
+type (
+	approvalKey struct{}
+	samplerKey  struct{}
+)
+
+// WithSamplingApproval returns ctx asking approve about the completions
+// servers ask for while the tools called with it run, when mcp-sampling is
+// ask. Without it, those requests are declined.
+func WithSamplingApproval(ctx context.Context, approve func(context.Context, SamplingRequest) bool) context.Context {
+	return context.WithValue(ctx, approvalKey{}, approve)
+}
+
+// WithSampler returns ctx answering the completions servers ask for while
+// the tools called with it run with sampler.
+func WithSampler(ctx context.Context, sampler Sampler) context.Context {
+	return context.WithValue(ctx, samplerKey{}, sampler)
+}
+
+// samplingCall is a running tool call, with the context it was called
+// with, which answers the completions its server asks for.
+type samplingCall struct {
+	ctx context.Context
+}
+
+// startSampling registers a call to a tool of server made with ctx until
+// the returned function is called.
+func (s *Service) startSampling(ctx context.Context, server string) func() {
+	call := &samplingCall{ctx: ctx}
+	s.mu.Lock()
+	s.calls[server] = append(s.calls[server], call)
+	s.mu.Unlock()
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.calls[server] = slices.DeleteFunc(s.calls[server], func(c *samplingCall) bool { return c == call })
+	}
+}
+
+// checkSampling validates mcp-sampling and reports whether servers may ask
+// for completions.
+func checkSampling(cfg *config.Config) (bool, error) {
+	switch cfg.MCPSampling {
+	case "", SamplingOff:
+		return false, nil
+	case SamplingAsk, SamplingAllow:
+		return true, nil
+	}
+	return false, errs.Wrap(
+		errs.UserErrorf("mcp-sampling is %q; want %s, %s, or %s", cfg.MCPSampling, SamplingOff, SamplingAsk, SamplingAllow),
+		"Invalid mcp-sampling settings.",
+	)
+}
+
+// samplingHandler answers the sampling requests of one server.
+type samplingHandler struct {
+	s      *Service
+	server string
+}
+
+func (h samplingHandler) CreateMessage(_ context.Context, request mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	return h.s.createMessage(h.server, request.CreateMessageParams)
+}
+
+// createMessage runs the completion server asks for with the sampler of
+// its running tool call, within the sampling limits and with the approval
+// of the call when needed. Requests do not say which call they are for, so
+// they are declined while none or several calls of server run.
+func (s *Service) createMessage(server string, params mcp.CreateMessageParams) (*mcp.CreateMessageResult, error) {
+	req := SamplingRequest{
+		Server:      server,
+		System:      params.SystemPrompt,
+		MaxTokens:   s.cfg.MCPSamplingMaxTokens,
+		Temperature: params.Temperature,
+	}
+	if n := int64(params.MaxTokens); n > 0 && (req.MaxTokens <= 0 || n < req.MaxTokens) {
+		req.MaxTokens = n
+	}
+	for _, msg := range params.Messages {
+		text, ok := msg.Content.(mcp.TextContent)
+		if !ok {
+			return nil, fmt.Errorf("mcp sampling: only text messages are supported, got %T", msg.Content)
+		}
+		req.Messages = append(req.Messages, proto.Message{Role: string(msg.Role), Content: text.Text})
+	}
+	if len(req.Messages) == 0 || req.Messages[len(req.Messages)-1].Role != proto.RoleUser {
+		return nil, errors.New("mcp sampling: the last message must be from the user")
+	}
+
+	s.mu.Lock()
+	calls := slices.Clone(s.calls[server])
+	used := s.sampled[server]
+	s.mu.Unlock()
+	switch len(calls) {
+	case 0:
+		return nil, fmt.Errorf("mcp sampling: no tool of %s is running", server)
+	case 1:
+	default:
+		return nil, fmt.Errorf("mcp sampling: %d tools of %s are running, so which one asked is unknown", len(calls), server)
+	}
+	ctx := calls[0].ctx
+	sampler, _ := ctx.Value(samplerKey{}).(Sampler)
+	approve, _ := ctx.Value(approvalKey{}).(func(context.Context, SamplingRequest) bool)
+	if sampler == nil {
+		return nil, errors.New("mcp sampling: no model is available")
+	}
+	if limit := s.cfg.MCPSamplingLimit; limit > 0 && used >= limit {
+		return nil, fmt.Errorf("mcp sampling: %s reached mcp-sampling-limit (%d requests)", server, limit)
+	}
+	if s.cfg.MCPSampling != SamplingAllow && (approve == nil || !approve(ctx, req)) {
+		return nil, errSamplingDeclined
+	}
+
+	s.mu.Lock()
+	s.sampled[server]++
+	s.mu.Unlock()
+	content, model, err := sampler(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("mcp sampling: %w", err)
+	}
+	return &mcp.CreateMessageResult{
+		SamplingMessage: mcp.SamplingMessage{Role: mcp.RoleAssistant, Content: mcp.NewTextContent(content)},
+		Model:           model,
+		StopReason:      "endTurn",
+	}, nil
+}
@@ -0,0 +1,133 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dotcommander/yai/internal/config"
+)
+
+func TestCreateMessage(t *testing.T) {
+	ctx := context.Background()
+	params := func(text string, maxTokens int) mcp.CreateMessageParams {
+		return mcp.CreateMessageParams{
+			Messages: []mcp.SamplingMessage{
+				{Role: mcp.RoleUser, Content: mcp.NewTextContent(text)},
+			},
+			SystemPrompt: "Be brief.",
+			MaxTokens:    maxTokens,
+		}
+	}
+	var got []SamplingRequest
+	sampler := func(_ context.Context, req SamplingRequest) (string, string, error) {
+		got = append(got, req)
+		return "a summary", "gpt-4o", nil
+	}
+	ctx = WithSampler(ctx, sampler)
+	newService := func(mode string) (*Service, *[]SamplingRequest) {
+		s := New(&config.Config{Settings: config.Settings{
+			MCPSampling:          mode,
+			MCPSamplingMaxTokens: 100,
+			MCPSamplingLimit:     2,
+		}})
+		got = nil
+		return s, &got
+	}
+
+	t.Run("allow", func(t *testing.T) {
+		s, got := newService(SamplingAllow)
+		defer s.startSampling(ctx, "fs")()
+		defer s.startSampling(ctx, "github")()
+		res, err := s.createMessage("fs", params("summarize this", 500))
+		require.NoError(t, err)
+		require.Equal(t, "gpt-4o", res.Model)
+		require.Equal(t, mcp.RoleAssistant, res.Role)
+		require.Equal(t, "a summary", res.Content.(mcp.TextContent).Text)
+		require.Len(t, *got, 1)
+		require.Equal(t, "fs", (*got)[0].Server)
+		require.Equal(t, "Be brief.", (*got)[0].System)
+		require.Equal(t, int64(100), (*got)[0].MaxTokens, "clamped to mcp-sampling-max-tokens")
+
+		_, err = s.createMessage("fs", params("and this", 10))
+		require.NoError(t, err)
+		require.Equal(t, int64(10), (*got)[1].MaxTokens)
+
+		_, err = s.createMessage("fs", params("and this too", 10))
+		require.ErrorContains(t, err, "mcp-sampling-limit")
+		_, err = s.createMessage("github", params("other server", 10))
+		require.NoError(t, err, "the limit is per server")
+	})
+
+	t.Run("running calls", func(t *testing.T) {
+		s, got := newService(SamplingAllow)
+		_, err := s.createMessage("fs", params("summarize this", 0))
+		require.ErrorContains(t, err, "no tool of fs is running")
+
+		stop := s.startSampling(ctx, "fs")
+		stopOther := s.startSampling(ctx, "fs")
+		_, err = s.createMessage("fs", params("summarize this", 0))
+		require.ErrorContains(t, err, "2 tools of fs are running")
+
+		stopOther()
+		_, err = s.createMessage("fs", params("summarize this", 0))
+		require.NoError(t, err)
+		stop()
+		require.Len(t, *got, 1)
+
+		defer s.startSampling(context.Background(), "fs")()
+		_, err = s.createMessage("fs", params("summarize this", 0))
+		require.ErrorContains(t, err, "no model is available")
+	})
+
+	t.Run("ask", func(t *testing.T) {
+		s, got := newService(SamplingAsk)
+		stop := s.startSampling(ctx, "fs")
+		_, err := s.createMessage("fs", params("summarize this", 0))
+		require.ErrorIs(t, err, errSamplingDeclined, "no one to ask")
+		stop()
+
+		var asked []SamplingRequest
+		answer := false
+		defer s.startSampling(WithSamplingApproval(ctx, func(_ context.Context, req SamplingRequest) bool {
+			asked = append(asked, req)
+			return answer
+		}), "fs")()
+		_, err = s.createMessage("fs", params("summarize this", 0))
+		require.ErrorIs(t, err, errSamplingDeclined)
+		require.Empty(t, *got)
+
+		answer = true
+		_, err = s.createMessage("fs", params("summarize this", 0))
+		require.NoError(t, err)
+		require.Len(t, asked, 2)
+		require.Equal(t, "summarize this", asked[1].Messages[0].Content)
+		require.Len(t, *got, 1)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		s, _ := newService(SamplingAllow)
+		defer s.startSampling(ctx, "fs")()
+		p := params("describe this", 0)
+		p.Messages[0].Content = mcp.NewImageContent("aGk=", "image/png")
+		_, err := s.createMessage("fs", p)
+		require.ErrorContains(t, err, "only text messages")
+
+		p = params("hi", 0)
+		p.Messages[0].Role = mcp.RoleAssistant
+		_, err = s.createMessage("fs", p)
+		require.ErrorContains(t, err, "the last message must be from the user")
+	})
+}
+
+func TestCheckSampling(t *testing.T) {
+	for mode, want := range map[string]bool{"": false, SamplingOff: false, SamplingAsk: true, SamplingAllow: true} {
+		ok, err := checkSampling(&config.Config{Settings: config.Settings{MCPSampling: mode}})
+		require.NoError(t, err)
+		require.Equal(t, want, ok, mode)
+	}
+	_, err := checkSampling(&config.Config{Settings: config.Settings{MCPSampling: "always"}})
+	require.ErrorContains(t, err, `mcp-sampling is "always"`)
+}
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"iter"
 	"maps"
-	"os"
 	"slices"
 	"strings"
 	"sync"
@@ -20,6 +19,7 @@ import (
 
 	"github.com/dotcommander/yai/internal/config"
 	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/proto"
 )
 
 // Service provides access to MCP server discovery and tool execution.
@@ -27,11 +27,29 @@ type Service struct {
 	cfg     *config.Config
 	mu      sync.Mutex
 	clients map[string]*client.Client
+	// readOnly holds the full names of the tools Tools advertised with
+	// mcp-read-only set; CallTool refuses others.
+	readOnly map[string]bool
+	// roleServers caches the mcp-servers frontmatter of roles.
+	roleServers map[string][]string
+	// watches holds the calls waiting for progress, by progress token.
+	watches   map[string]progressWatch
+	lastToken int
+	// calls holds the running tool calls of each server, which answer the
+	// completions it asks for, and sampled counts those requests.
+	calls   map[string][]*samplingCall
+	sampled map[string]int
 }
 
 // New creates a new MCP service.
 func New(cfg *config.Config) *Service {
-	return &Service{cfg: cfg, clients: map[string]*client.Client{}}
+	return &Service{
+		cfg:         cfg,
+		clients:     map[string]*client.Client{},
+		roleServers: map[string][]string{},
+		calls:       map[string][]*samplingCall{},
+		sampled:     map[string]int{},
+	}
 }
 
 // getClient returns a cached client for the named server, creating one if needed.
@@ -43,10 +61,23 @@ func (s *Service) getClient(ctx context.Context, name string, server config.MCPS
 	}
 	s.mu.Unlock()
 
-	cli, err := initClient(ctx, s.cfg, server)
+	sampling, err := checkSampling(s.cfg)
+	if err != nil {
+		return nil, err
+	}
+	rts, err := roots(s.cfg)
 	if err != nil {
 		return nil, err
 	}
+	opts := []client.ClientOption{client.WithRootsHandler(rootsHandler{roots: rts})}
+	if sampling {
+		opts = append(opts, client.WithSamplingHandler(samplingHandler{s: s, server: name}))
+	}
+	cli, err := initClient(ctx, s.cfg, name, server, opts...)
+	if err != nil {
+		return nil, err
+	}
+	cli.OnNotification(s.onNotification)
 
 	s.mu.Lock()
 	if existing, ok := s.clients[name]; ok {
@@ -71,8 +102,31 @@ func (s *Service) Close() {
 
 // IsEnabled reports whether the named MCP server is enabled.
 func (s *Service) IsEnabled(name string) bool {
-	return !slices.Contains(s.cfg.MCPDisable, "*") &&
-		!slices.Contains(s.cfg.MCPDisable, name)
+	if slices.Contains(s.cfg.MCPDisable, "*") || slices.Contains(s.cfg.MCPDisable, name) {
+		return false
+	}
+	only := s.only()
+	return only == nil || slices.Contains(only, name)
+}
+
+// only returns the servers mcp-only, or else the mcp-servers frontmatter of
+// the role, limits the conversation to; nil means all of them.
+func (s *Service) only() []string {
+	if len(s.cfg.MCPOnly) > 0 {
+		return s.cfg.MCPOnly
+	}
+	if s.cfg.Role == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if servers, ok := s.roleServers[s.cfg.Role]; ok {
+		return servers
+	}
+	// Bad frontmatter fails the request when the role is loaded.
+	servers, _ := config.RoleMCPServers(s.cfg.Roles[s.cfg.Role])
+	s.roleServers[s.cfg.Role] = servers
+	return servers
 }
 
 // EnabledServers iterates enabled MCP servers in stable order.
@@ -99,6 +153,9 @@ func (s *Service) Tools(ctx context.Context) (map[string][]mcp.Tool, error) {
 	for sname, server := range s.EnabledServers() {
 		wg.Go(func() error {
 			serverTools, err := s.toolsFor(ctx, sname, server)
+			if s.cfg.MCPReadOnly {
+				serverTools = slices.DeleteFunc(serverTools, func(tool mcp.Tool) bool { return !IsReadOnly(tool) })
+			}
 			if errors.Is(err, context.DeadlineExceeded) {
 				return errs.Wrap(
 					fmt.Errorf("timeout while listing tools for %q - make sure the configuration is correct. If your server requires a docker container, make sure it's running", sname),
@@ -117,6 +174,16 @@ func (s *Service) Tools(ctx context.Context) (map[string][]mcp.Tool, error) {
 	if err := wg.Wait(); err != nil {
 		return nil, fmt.Errorf("mcp tools: %w", err)
 	}
+	if s.cfg.MCPReadOnly {
+		s.mu.Lock()
+		s.readOnly = map[string]bool{}
+		for sname, tools := range result {
+			for _, tool := range tools {
+				s.readOnly[sname+"_"+tool.Name] = true
+			}
+		}
+		s.mu.Unlock()
+	}
 	return result, nil
 }
 
@@ -134,6 +201,14 @@ func (s *Service) CallTool(ctx context.Context, fullName string, data []byte) (s
 	if !s.IsEnabled(sname) {
 		return "", fmt.Errorf("mcp: server is disabled: %q", sname)
 	}
+	if s.cfg.MCPReadOnly {
+		s.mu.Lock()
+		ok := s.readOnly[fullName]
+		s.mu.Unlock()
+		if !ok {
+			return "", fmt.Errorf("mcp: %q may change things and mcp-read-only is set", fullName)
+		}
+	}
 	cli, err := s.getClient(ctx, sname, server)
 	if err != nil {
 		return "", fmt.Errorf("mcp: %w", err)
@@ -147,6 +222,12 @@ func (s *Service) CallTool(ctx context.Context, fullName string, data []byte) (s
 	request := mcp.CallToolRequest{}
 	request.Params.Name = tool
 	request.Params.Arguments = args
+	if report, ok := ctx.Value(progressKey{}).(func(proto.ToolProgress)); ok {
+		token := s.watchProgress(fullName, report)
+		defer s.unwatchProgress(token)
+		request.Params.Meta = &mcp.Meta{ProgressToken: token}
+	}
+	defer s.startSampling(ctx, sname)()
 	result, err := cli.CallTool(ctx, request)
 	if err != nil {
 		return "", fmt.Errorf("mcp: %w", err)
@@ -206,33 +287,38 @@ func renderToolResult(contents []mcp.Content) string {
 // initClient creates and initializes an MCP client for the given server config.
 // For stdio servers, the parent process environment is inherited by default
 // (merged with any explicit server.Env entries) unless MCPNoInheritEnv is set.
-func initClient(ctx context.Context, cfg *config.Config, server config.MCPServerConfig) (*client.Client, error) {
-	var cli *client.Client
+func initClient(ctx context.Context, cfg *config.Config, name string, server config.MCPServerConfig, opts ...client.ClientOption) (*client.Client, error) {
+	var trans transport.Interface
 	var err error
+	// log explains why a stdio server failed to start.
+	var log *serverLog
+
+	if err := validateProtocolVersion(server.ProtocolVersion); err != nil {
+		return nil, err
+	}
 
 	switch server.Type {
 	case "", "stdio":
-		env := server.Env
-		if cfg != nil && !cfg.MCPNoInheritEnv {
-			env = append(os.Environ(), server.Env...)
+		stdio := transport.NewStdioWithOptions(server.Command, stdioEnv(cfg, server), server.Args)
+		// Not ctx: the server outlives the request that starts it.
+		err = stdio.Start(context.Background())
+		trans = stdio
+		if err == nil {
+			log = openServerLog(serverLogPath(cfg, name), server.Command)
+			go log.capture(stdio.Stderr())
 		}
-		cli, err = client.NewStdioMCPClient(
-			server.Command,
-			env,
-			server.Args...,
-		)
 	case "sse":
 		var sseOpts []transport.ClientOption
 		if len(server.Headers) > 0 {
 			sseOpts = append(sseOpts, transport.WithHeaders(server.Headers))
 		}
-		cli, err = client.NewSSEMCPClient(server.URL, sseOpts...)
+		trans, err = transport.NewSSE(server.URL, sseOpts...)
 	case "http":
 		var httpOpts []transport.StreamableHTTPCOption
 		if len(server.Headers) > 0 {
 			httpOpts = append(httpOpts, transport.WithHTTPHeaders(server.Headers))
 		}
-		cli, err = client.NewStreamableHttpClient(server.URL, httpOpts...)
+		trans, err = transport.NewStreamableHTTP(server.URL, httpOpts...)
 	default:
 		return nil, fmt.Errorf("unsupported MCP server type: %q, supported types are: stdio, sse, http", server.Type)
 	}
@@ -240,21 +326,60 @@ func initClient(ctx context.Context, cfg *config.Config, server config.MCPServer
 	if err != nil {
 		return nil, fmt.Errorf("failed to create MCP client: %w", err)
 	}
+	cli := client.NewClient(trans, opts...)
 
+	// The log is read before closing the client, which closes stderr.
 	if err := cli.Start(ctx); err != nil {
+		err = log.explain(fmt.Errorf("failed to start MCP client: %w", err))
 		cli.Close() //nolint:errcheck,gosec
-		return nil, fmt.Errorf("failed to start MCP client: %w", err)
+		return nil, err
 	}
 
-	if _, err := cli.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+	if _, err := cli.Initialize(ctx, initializeRequest(cfg, server)); err != nil {
+		err = log.explain(fmt.Errorf("failed to initialize MCP client: %w", err))
 		cli.Close() //nolint:errcheck,gosec
-		return nil, fmt.Errorf("failed to initialize MCP client: %w", err)
+		return nil, err
 	}
 
 	return cli, nil
 }
 
+// clientName is the implementation name reported to MCP servers.
+const clientName = "yai"
+
+// initializeRequest builds the MCP initialize request for server, identifying
+// yai and its version. The client adds roots to the capabilities, and
+// sampling when mcp-sampling allows it; elicitation is not declared.
+func initializeRequest(cfg *config.Config, server config.MCPServerConfig) mcp.InitializeRequest {
+	version := "dev"
+	if cfg != nil && cfg.ClientVersion != "" {
+		version = cfg.ClientVersion
+	}
+
+	req := mcp.InitializeRequest{}
+	req.Params.ProtocolVersion = server.ProtocolVersion
+	if req.Params.ProtocolVersion == "" {
+		req.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	}
+	req.Params.ClientInfo = mcp.Implementation{Name: clientName, Version: version}
+	req.Params.Capabilities = mcp.ClientCapabilities{}
+	return req
+}
+
+func validateProtocolVersion(version string) error {
+	if version == "" || slices.Contains(mcp.ValidProtocolVersions, version) {
+		return nil
+	}
+	return fmt.Errorf("unsupported MCP protocol version: %q, supported versions are: %s",
+		version, strings.Join(mcp.ValidProtocolVersions, ", "))
+}
+
+// toolsFor returns the tools server offers, from the cache when they were
+// listed recently, so the server only starts once a tool is called.
 func (s *Service) toolsFor(ctx context.Context, name string, server config.MCPServerConfig) ([]mcp.Tool, error) {
+	if tools, ok := s.cachedTools(server); ok {
+		return tools, nil
+	}
 	cli, err := s.getClient(ctx, name, server)
 	if err != nil {
 		return nil, fmt.Errorf("could not setup %s: %w", name, err)
@@ -264,5 +389,25 @@ func (s *Service) toolsFor(ctx context.Context, name string, server config.MCPSe
 	if err != nil {
 		return nil, fmt.Errorf("could not setup %s: %w", name, err)
 	}
+	s.cacheTools(server, tools.Tools)
 	return tools.Tools, nil
 }
+
+// Check starts the named server and lists its tools, bypassing the cache,
+// to tell whether it works. It returns how many tools the server offers.
+func (s *Service) Check(ctx context.Context, name string) (int, error) {
+	server, ok := s.cfg.MCPServers[name]
+	if !ok {
+		return 0, fmt.Errorf("mcp: invalid server name: %q", name)
+	}
+	cli, err := s.getClient(ctx, name, server)
+	if err != nil {
+		return 0, err
+	}
+	tools, err := cli.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		return 0, fmt.Errorf("list tools: %w", err)
+	}
+	s.cacheTools(server, tools.Tools)
+	return len(tools.Tools), nil
+}
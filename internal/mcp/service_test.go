@@ -0,0 +1,65 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dotcommander/yai/internal/config"
+)
+
+func TestInitializeRequest(t *testing.T) {
+	t.Run("identifies yai", func(t *testing.T) {
+		cfg := &config.Config{}
+		cfg.ClientVersion = "v1.2.3"
+
+		req := initializeRequest(cfg, config.MCPServerConfig{})
+		require.Equal(t, mcp.Implementation{Name: "yai", Version: "v1.2.3"}, req.Params.ClientInfo)
+		require.Equal(t, mcp.LATEST_PROTOCOL_VERSION, req.Params.ProtocolVersion)
+	})
+
+	t.Run("pins protocol version", func(t *testing.T) {
+		req := initializeRequest(nil, config.MCPServerConfig{ProtocolVersion: "2024-11-05"})
+		require.Equal(t, "2024-11-05", req.Params.ProtocolVersion)
+		require.Equal(t, "dev", req.Params.ClientInfo.Version)
+	})
+}
+
+func TestValidateProtocolVersion(t *testing.T) {
+	require.NoError(t, validateProtocolVersion(""))
+	require.NoError(t, validateProtocolVersion("2024-11-05"))
+	require.ErrorContains(t, validateProtocolVersion("1999-01-01"), "unsupported MCP protocol version")
+}
+
+func TestIsEnabled(t *testing.T) {
+	role := filepath.Join(t.TempDir(), "coder.md")
+	require.NoError(t, os.WriteFile(role, []byte("---\nmcp-servers: [github]\n---\nYou write code.\n"), 0o644))
+	cfg := &config.Config{Settings: config.Settings{
+		MCPServers: map[string]config.MCPServerConfig{"github": {}, "jira": {}, "fs": {}},
+		Roles:      map[string][]string{"coder": {"file://" + role}, "plain": {"be brief"}},
+	}}
+	enabled := func() []string {
+		var names []string
+		for name := range New(cfg).EnabledServers() {
+			names = append(names, name)
+		}
+		return names
+	}
+
+	require.Equal(t, []string{"fs", "github", "jira"}, enabled())
+
+	cfg.Role = "plain"
+	require.Equal(t, []string{"fs", "github", "jira"}, enabled())
+
+	cfg.Role = "coder"
+	require.Equal(t, []string{"github"}, enabled())
+
+	cfg.MCPOnly = []string{"jira", "fs"}
+	require.Equal(t, []string{"fs", "jira"}, enabled(), "mcp-only takes precedence over the role")
+
+	cfg.MCPDisable = []string{"fs"}
+	require.Equal(t, []string{"jira"}, enabled())
+}
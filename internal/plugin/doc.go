@@ -0,0 +1,9 @@
+// Package plugin runs provider plugins: executables that answer requests
+// for an API yai has no provider for.
+//
+// yai starts the plugin for each request and writes the request to its
+// stdin as one JSON object (see [Request]), then closes stdin. The plugin
+// streams the answer as JSON lines on stdout (see [Event]) and exits; a
+// non-zero exit fails the request with the last line the plugin wrote to
+// stderr.
+package plugin
@@ -0,0 +1,373 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caarlos0/go-shellwords"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/stream"
+)
+
+// Request is what a plugin reads from stdin. Settings that were not given
+// are left out.
+type Request struct {
+	API              string    `json:"api"`
+	Model            string    `json:"model"`
+	BaseURL          string    `json:"base_url,omitempty"`
+	APIKey           string    `json:"api_key,omitempty"` //nolint:gosec // G117: sent to the plugin the user configured
+	Messages         []Message `json:"messages"`
+	User             string    `json:"user,omitempty"`
+	Temperature      *float64  `json:"temperature,omitempty"`
+	TopP             *float64  `json:"top_p,omitempty"`
+	TopK             *int64    `json:"top_k,omitempty"`
+	PresencePenalty  *float64  `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64  `json:"frequency_penalty,omitempty"`
+	Seed             *int64    `json:"seed,omitempty"`
+	MaxTokens        *int64    `json:"max_tokens,omitempty"`
+	Stop             []string  `json:"stop,omitempty"`
+}
+
+// Message is a message of the conversation: role is system, user, or
+// assistant.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Event is a line a plugin writes to stdout. Type is one of:
+//
+//   - text: Text is the next part of the answer.
+//   - usage: InputTokens and OutputTokens are what the request cost.
+//   - warning: Message is shown to the user.
+//   - error: Message fails the request.
+type Event struct {
+	Type         string `json:"type"`
+	Text         string `json:"text,omitempty"`
+	Message      string `json:"message,omitempty"`
+	InputTokens  int64  `json:"input_tokens,omitempty"`
+	OutputTokens int64  `json:"output_tokens,omitempty"`
+}
+
+// Event types.
+const (
+	EventText    = "text"
+	EventUsage   = "usage"
+	EventWarning = "warning"
+	EventError   = "error"
+)
+
+// maxLine caps a line of plugin output.
+const maxLine = 4 * 1024 * 1024
+
+// Config is how to reach a plugin.
+type Config struct {
+	// Command is the plugin's command line, split as a shell would.
+	Command string
+	API     string
+	BaseURL string
+	APIKey  string //nolint:gosec // G117: required plugin config field, not a hardcoded credential
+}
+
+// Client is a stream.Client that runs a plugin for each request.
+type Client struct {
+	args   []string
+	config Config
+}
+
+// New returns a Client for the plugin of cfg.
+func New(cfg Config) (*Client, error) {
+	args, err := shellwords.Parse(cfg.Command)
+	if err != nil {
+		return nil, fmt.Errorf("parse plugin command: %w", err)
+	}
+	if len(args) == 0 {
+		return nil, errors.New("empty plugin command")
+	}
+	return &Client{args: args, config: cfg}, nil
+}
+
+// Request implements stream.Client. Plugins are offered no tools.
+func (c *Client) Request(ctx context.Context, request proto.Request) stream.Stream {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Stream{cancel: cancel, ctx: ctx, messages: request.Messages, start: time.Now()}
+	if err := s.run(c.args, c.newRequest(request)); err != nil {
+		s.err = err
+		cancel()
+	}
+	return s
+}
+
+func (c *Client) newRequest(request proto.Request) Request {
+	req := Request{
+		API:              c.config.API,
+		Model:            request.Model,
+		BaseURL:          c.config.BaseURL,
+		APIKey:           c.config.APIKey,
+		User:             request.User,
+		Temperature:      request.Temperature,
+		TopP:             request.TopP,
+		TopK:             request.TopK,
+		PresencePenalty:  request.PresencePenalty,
+		FrequencyPenalty: request.FrequencyPenalty,
+		Seed:             request.Seed,
+		MaxTokens:        request.MaxTokens,
+		Stop:             request.Stop,
+	}
+	for _, msg := range request.Messages {
+		// Tool calls and their results mean nothing to a plugin.
+		if msg.Role == proto.RoleTool || msg.Content == "" {
+			continue
+		}
+		req.Messages = append(req.Messages, Message{Role: msg.Role, Content: msg.Content})
+	}
+	return req
+}
+
+// Stream is a stream.Stream over the output of a running plugin.
+type Stream struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	cmd    *exec.Cmd
+	stderr *bytes.Buffer
+	lines  chan []byte
+	// readErr is the error reading stdout, set before lines is closed.
+	readErr  error
+	waitOnce sync.Once
+	waitErr  error
+
+	mu       sync.Mutex
+	messages []proto.Message
+	current  string
+	answer   strings.Builder
+	err      error
+	done     bool
+	warnings []string
+	usage    proto.Usage
+	start    time.Time
+	first    time.Duration
+}
+
+func (s *Stream) run(args []string, request Request) error {
+	input, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("encode plugin request: %w", err)
+	}
+	s.cmd = exec.CommandContext(s.ctx, args[0], args[1:]...) //nolint:gosec // G204: the plugin is user-configured in yai.yml
+	s.cmd.Stdin = bytes.NewReader(append(input, '\n'))
+	s.stderr = &bytes.Buffer{}
+	s.cmd.Stderr = s.stderr
+	stdout, err := s.cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("plugin: %w", err)
+	}
+	if err := s.cmd.Start(); err != nil {
+		return fmt.Errorf("start plugin: %w", err)
+	}
+	s.lines = make(chan []byte)
+	go func() {
+		defer close(s.lines)
+		sc := bufio.NewScanner(stdout)
+		sc.Buffer(make([]byte, 0, 64*1024), maxLine)
+		for sc.Scan() {
+			line := append([]byte(nil), sc.Bytes()...)
+			select {
+			case s.lines <- line:
+			case <-s.ctx.Done():
+				return
+			}
+		}
+		s.readErr = sc.Err()
+	}()
+	return nil
+}
+
+// Next implements stream.Stream.
+func (s *Stream) Next() bool {
+	s.mu.Lock()
+	if s.err != nil || s.done {
+		s.mu.Unlock()
+		return false
+	}
+	s.mu.Unlock()
+
+	for {
+		var line []byte
+		var ok bool
+		select {
+		case <-s.ctx.Done():
+			s.fail(s.ctx.Err())
+			return false
+		case line, ok = <-s.lines:
+		}
+		if !ok {
+			s.finish()
+			return false
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var ev Event
+		if err := json.Unmarshal(line, &ev); err != nil {
+			s.fail(fmt.Errorf("plugin wrote an invalid event: %w", err))
+			return false
+		}
+		if s.handle(ev) {
+			return true
+		}
+		if s.Err() != nil {
+			return false
+		}
+	}
+}
+
+// handle applies ev, and reports whether it was text to return.
+func (s *Stream) handle(ev Event) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch ev.Type {
+	case EventText:
+		if s.first == 0 {
+			s.first = time.Since(s.start)
+		}
+		s.current = ev.Text
+		s.answer.WriteString(ev.Text)
+		return true
+	case EventUsage:
+		s.usage = s.usage.Add(proto.Usage{
+			InputTokens:  ev.InputTokens,
+			OutputTokens: ev.OutputTokens,
+			TotalTokens:  ev.InputTokens + ev.OutputTokens,
+		})
+	case EventWarning:
+		s.warnings = append(s.warnings, ev.Message)
+	case EventError:
+		s.err = fmt.Errorf("plugin: %s", ev.Message)
+		s.cancel()
+	default:
+		// Unknown events are left for newer versions of yai.
+	}
+	return false
+}
+
+func (s *Stream) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.cancel()
+}
+
+// wait waits for the plugin to exit, once.
+func (s *Stream) wait() error {
+	s.waitOnce.Do(func() { s.waitErr = s.cmd.Wait() })
+	return s.waitErr
+}
+
+// finish waits for the plugin to exit and records the answer.
+func (s *Stream) finish() {
+	err := s.wait()
+	if err == nil {
+		err = s.readErr
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return
+	}
+	if err != nil {
+		if reason := lastLine(s.stderr.String()); reason != "" {
+			err = fmt.Errorf("plugin: %w: %s", err, reason)
+		} else {
+			err = fmt.Errorf("plugin: %w", err)
+		}
+		s.err = err
+		return
+	}
+	s.done = true
+	if s.answer.Len() == 0 {
+		return
+	}
+	now := time.Now()
+	s.messages = append(s.messages, proto.Message{
+		Role:       proto.RoleAssistant,
+		Content:    s.answer.String(),
+		Time:       now,
+		Latency:    now.Sub(s.start),
+		FirstToken: s.first,
+		Usage:      s.usage,
+	})
+}
+
+func lastLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.TrimSpace(s)
+}
+
+// Current implements stream.Stream.
+func (s *Stream) Current() (proto.Chunk, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return proto.Chunk{Content: s.current}, nil
+}
+
+// Close implements stream.Stream. It stops the plugin if it is running.
+func (s *Stream) Close() error {
+	s.cancel()
+	if s.cmd != nil && s.cmd.Process != nil {
+		_ = s.wait()
+	}
+	return nil
+}
+
+// Err implements stream.Stream.
+func (s *Stream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Messages implements stream.Stream.
+func (s *Stream) Messages() []proto.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.messages
+}
+
+// CallTools implements stream.Stream. Plugins make no tool calls.
+func (s *Stream) CallTools() []proto.ToolCallStatus {
+	return nil
+}
+
+// DrainWarnings implements stream.Stream.
+func (s *Stream) DrainWarnings() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	warnings := s.warnings
+	s.warnings = nil
+	return warnings
+}
+
+// Usage implements stream.Stream.
+func (s *Stream) Usage() proto.Usage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage
+}
+
+// ResponseMeta implements stream.Stream. Plugins report none.
+func (s *Stream) ResponseMeta() proto.ResponseMeta {
+	return proto.ResponseMeta{}
+}
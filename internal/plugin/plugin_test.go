@@ -0,0 +1,112 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain runs the test binary as a plugin when YAI_TEST_PLUGIN is set.
+func TestMain(m *testing.M) {
+	if mode := os.Getenv("YAI_TEST_PLUGIN"); mode != "" {
+		os.Exit(testPlugin(mode))
+	}
+	os.Exit(m.Run())
+}
+
+func testPlugin(mode string) int {
+	var req Request
+	if err := json.NewDecoder(bufio.NewReader(os.Stdin)).Decode(&req); err != nil {
+		fmt.Fprintln(os.Stderr, "bad request:", err)
+		return 2
+	}
+	out := json.NewEncoder(os.Stdout)
+	switch mode {
+	case "echo":
+		last := req.Messages[len(req.Messages)-1]
+		_ = out.Encode(Event{Type: EventWarning, Message: "echoing " + req.Model + " with key " + req.APIKey})
+		_ = out.Encode(Event{Type: EventText, Text: "you said: "})
+		_ = out.Encode(Event{Type: EventText, Text: last.Content})
+		_ = out.Encode(Event{Type: "future"})
+		_ = out.Encode(Event{Type: EventUsage, InputTokens: 3, OutputTokens: 4})
+	case "error":
+		_ = out.Encode(Event{Type: EventText, Text: "partial"})
+		_ = out.Encode(Event{Type: EventError, Message: "model overloaded"})
+	case "exit":
+		fmt.Fprintln(os.Stderr, "starting")
+		fmt.Fprintln(os.Stderr, "invalid credentials")
+		return 3
+	}
+	return 0
+}
+
+func newTestClient(t *testing.T, mode string) *Client {
+	t.Helper()
+	t.Setenv("YAI_TEST_PLUGIN", mode)
+	client, err := New(Config{Command: strconv.Quote(os.Args[0]), API: "gateway", APIKey: "k"})
+	require.NoError(t, err)
+	return client
+}
+
+func TestPlugin(t *testing.T) {
+	request := proto.Request{Model: "m1", Messages: []proto.Message{
+		{Role: proto.RoleSystem, Content: "be brief"},
+		{Role: proto.RoleUser, Content: "hello"},
+	}}
+
+	t.Run("streams text", func(t *testing.T) {
+		st := newTestClient(t, "echo").Request(context.Background(), request)
+		var text string
+		for st.Next() {
+			chunk, err := st.Current()
+			require.NoError(t, err)
+			text += chunk.Content
+		}
+		require.NoError(t, st.Err())
+		require.NoError(t, st.Close())
+		require.Equal(t, "you said: hello", text)
+		require.Equal(t, []string{"echoing m1 with key k"}, st.DrainWarnings())
+		require.Equal(t, proto.Usage{InputTokens: 3, OutputTokens: 4, TotalTokens: 7}, st.Usage())
+
+		msgs := st.Messages()
+		require.Len(t, msgs, 3)
+		require.Equal(t, proto.RoleAssistant, msgs[2].Role)
+		require.Equal(t, "you said: hello", msgs[2].Content)
+		require.Equal(t, st.Usage(), msgs[2].Usage)
+	})
+
+	t.Run("error event", func(t *testing.T) {
+		st := newTestClient(t, "error").Request(context.Background(), request)
+		for st.Next() {
+		}
+		require.EqualError(t, st.Err(), "plugin: model overloaded")
+		require.NoError(t, st.Close())
+		require.Len(t, st.Messages(), 2)
+	})
+
+	t.Run("exit status", func(t *testing.T) {
+		st := newTestClient(t, "exit").Request(context.Background(), request)
+		require.False(t, st.Next())
+		require.ErrorContains(t, st.Err(), "exit status 3: invalid credentials")
+	})
+
+	t.Run("missing command", func(t *testing.T) {
+		client, err := New(Config{Command: "/nonexistent/yai-plugin"})
+		require.NoError(t, err)
+		st := client.Request(context.Background(), request)
+		require.False(t, st.Next())
+		require.ErrorContains(t, st.Err(), "start plugin")
+	})
+}
+
+func TestNewEmptyCommand(t *testing.T) {
+	_, err := New(Config{Command: "  "})
+	require.EqualError(t, err, "empty plugin command")
+}
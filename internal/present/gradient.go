@@ -7,15 +7,21 @@ import (
 	"github.com/lucasb-eyer/go-colorful"
 )
 
-// MakeGradientRamp returns a color ramp of the given length.
+// MakeGradientRamp returns a color ramp of the given length, between the
+// gradient colors of the theme. Without a gradient, the colors are empty.
 func MakeGradientRamp(length int) []lipgloss.Color {
-	const startColor = "#F967DC"
-	const endColor = "#6B50FF"
-	var (
-		c        = make([]lipgloss.Color, length)
-		start, _ = colorful.Hex(startColor)
-		end, _   = colorful.Hex(endColor)
-	)
+	c := make([]lipgloss.Color, length)
+	if len(theme.Gradient) != 2 { //nolint:mnd
+		return c
+	}
+	start, err := colorful.Hex(theme.Gradient[0])
+	if err != nil {
+		return c
+	}
+	end, err := colorful.Hex(theme.Gradient[1])
+	if err != nil {
+		return c
+	}
 	for i := range length {
 		step := start.BlendLuv(end, float64(i)/float64(length))
 		c[i] = lipgloss.Color(step.Hex())
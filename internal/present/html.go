@@ -0,0 +1,104 @@
+package present
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// htmlPage wraps the rendered markdown; it needs nothing from elsewhere, so
+// the file can be opened or passed around on its own.
+const htmlPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>%[1]s</title>
+<style>
+body { max-width: 50rem; margin: 2rem auto; padding: 0 1rem; font: 16px/1.6 system-ui, sans-serif; color: #1f2328; }
+h1, h2, h3 { line-height: 1.25; }
+code { font: 0.9em ui-monospace, SFMono-Regular, Menlo, monospace; }
+:not(pre) > code { background: #eff1f3; padding: 0.1em 0.3em; border-radius: 4px; }
+pre { padding: 1rem; overflow-x: auto; border-radius: 6px; }
+pre:not([style]) { background: #f6f8fa; }
+blockquote { margin: 0; padding-left: 1rem; border-left: 4px solid #d0d7de; color: #59636e; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #d0d7de; padding: 0.3rem 0.6rem; }
+</style>
+</head>
+<body>
+<h1>%[1]s</h1>
+%[2]s</body>
+</html>
+`
+
+// RenderHTML writes markdown as a self-contained HTML page with the given
+// title, with code blocks highlighted. HTML in the markdown is escaped.
+func RenderHTML(w io.Writer, title, markdown string) error {
+	md := goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithRendererOptions(renderer.WithNodeRenderers(
+			util.Prioritized(codeBlockRenderer{}, 100),
+		)),
+	)
+	var body bytes.Buffer
+	if err := md.Convert([]byte(markdown), &body); err != nil {
+		return fmt.Errorf("render html: %w", err)
+	}
+	if _, err := fmt.Fprintf(w, htmlPage, html.EscapeString(title), body.String()); err != nil {
+		return fmt.Errorf("render html: %w", err)
+	}
+	return nil
+}
+
+// codeBlockRenderer renders fenced code blocks highlighted for their
+// language, with inline styles.
+type codeBlockRenderer struct{}
+
+func (codeBlockRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, renderCodeBlock)
+}
+
+func renderCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	block := node.(*ast.FencedCodeBlock) //nolint:forcetypeassert
+	var code bytes.Buffer
+	lines := block.Lines()
+	for i := range lines.Len() {
+		seg := lines.At(i)
+		code.Write(seg.Value(source))
+	}
+
+	lexer := lexers.Get(string(block.Language(source)))
+	if lexer == nil {
+		lexer = lexers.Analyse(code.String())
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	var out bytes.Buffer
+	tokens, err := chroma.Coalesce(lexer).Tokenise(nil, code.String())
+	if err == nil {
+		err = chromahtml.New().Format(&out, styles.Get("github"), tokens)
+	}
+	if err != nil {
+		// Unhighlighted is better than missing.
+		out.Reset()
+		fmt.Fprintf(&out, "<pre><code>%s</code></pre>\n", html.EscapeString(code.String()))
+	}
+	_, _ = w.Write(out.Bytes())
+	return ast.WalkSkipChildren, nil
+}
@@ -1,28 +1,152 @@
 package present
 
 import (
+	"cmp"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
 	"strings"
 	"unicode"
 
 	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/ansi"
+	"github.com/charmbracelet/glamour/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 )
 
 const markdownTabWidth = 4
 
+// NewMarkdownRenderer returns the glamour renderer yai uses for markdown,
+// styled for stdout with MarkdownColors. opts are applied last, so they can
+// pin the style or color profile, or style for another output.
+func NewMarkdownRenderer(wordWrap int, opts ...glamour.TermRendererOption) (*glamour.TermRenderer, error) {
+	r, err := glamour.NewTermRenderer(append([]glamour.TermRendererOption{
+		MarkdownColors(StdoutRenderer()),
+		glamour.WithWordWrap(wordWrap),
+	}, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("new markdown renderer: %w", err)
+	}
+	return r, nil
+}
+
+// markdownStyle is the markdown style set with glamour-style, or empty.
+var markdownStyle string //nolint:gochecknoglobals
+
+// UseMarkdownStyle makes style, a glamour style name or the path of a JSON
+// style file, the style of rendered markdown, over GLAMOUR_STYLE and the
+// theme. Empty or "auto" undoes it.
+func UseMarkdownStyle(style string) {
+	if style == styles.AutoStyle {
+		style = ""
+	}
+	markdownStyle = style
+}
+
+// MarkdownStyleNames returns the names of the glamour styles, sorted.
+func MarkdownStyleNames() []string {
+	return slices.Sorted(maps.Keys(styles.DefaultStyles))
+}
+
+// CheckMarkdownStyle reports whether style is a glamour style name or a
+// JSON style file that can be read.
+func CheckMarkdownStyle(style string) error {
+	if style == "" || style == styles.AutoStyle {
+		return nil
+	}
+	if _, ok := styles.DefaultStyles[style]; ok {
+		return nil
+	}
+	data, err := os.ReadFile(style)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("markdown style %q is not one of %s, nor a file", style, strings.Join(MarkdownStyleNames(), ", "))
+	}
+	if err != nil {
+		return fmt.Errorf("read markdown style: %w", err)
+	}
+	var cfg ansi.StyleConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("markdown style %s: %w", style, err)
+	}
+	return nil
+}
+
+// MarkdownColors styles markdown for the output of r. The style set with
+// UseMarkdownStyle wins, then GLAMOUR_STYLE; otherwise the style is the
+// plain "notty" one when r is not a terminal and CLICOLOR_FORCE is unset,
+// the markdown style of the theme, or the dark or light one by the terminal
+// background, cut down to the basic colors on terminals with 16 colors or
+// fewer. Colors follow r's profile, so NO_COLOR drops them without changing
+// the layout.
+func MarkdownColors(r *lipgloss.Renderer) glamour.TermRendererOption {
+	profile := r.ColorProfile()
+	style := cmp.Or(markdownStyle, os.Getenv("GLAMOUR_STYLE"))
+	if style == "" || style == styles.AutoStyle {
+		switch {
+		case profile == termenv.Ascii && !isTerminal(r.Output()):
+			style = styles.NoTTYStyle
+		case theme.Markdown != "":
+			style = theme.Markdown
+		case profile == termenv.ANSI && r.HasDarkBackground():
+			return glamour.WithOptions(glamour.WithStyles(lowColor(styles.DarkStyleConfig)), glamour.WithColorProfile(profile))
+		case profile == termenv.ANSI:
+			return glamour.WithOptions(glamour.WithStyles(lowColor(styles.LightStyleConfig)), glamour.WithColorProfile(profile))
+		case r.HasDarkBackground():
+			style = styles.DarkStyle
+		default:
+			style = styles.LightStyle
+		}
+	}
+	return glamour.WithOptions(
+		glamour.WithStylePath(style),
+		glamour.WithColorProfile(profile),
+	)
+}
+
+// lowColor cuts s down for terminals with 8 or 16 colors: the text keeps
+// the terminal's own color, backgrounds and syntax highlighting are
+// dropped, and headings, code, and links take basic ANSI colors.
+func lowColor(s ansi.StyleConfig) ansi.StyleConfig {
+	color := func(c string) *string { return &c }
+	s.Document.Color = nil
+	s.BlockQuote.Color = nil
+	s.Heading.Color = color("4")
+	s.H1.Color, s.H1.BackgroundColor = nil, nil
+	s.Code.Color, s.Code.BackgroundColor = color("1"), nil
+	s.CodeBlock.Color, s.CodeBlock.Chroma = color("2"), nil
+	s.Link.Color = color("6")
+	s.LinkText.Color = color("6")
+	s.HorizontalRule.Color = nil
+	s.Item.Color, s.Enumeration.Color = nil, nil
+	s.Table.Color = nil
+	return s
+}
+
+func isTerminal(o *termenv.Output) bool {
+	f := o.TTY()
+	return f != nil && isTerminalFd(f.Fd())
+}
+
 // RenderMarkdownForTTY renders markdown for terminal output.
 //
 // It mirrors the TUI's markdown rendering behavior closely enough for headless
 // commands (e.g. --show / history show) without requiring Bubble Tea.
 func RenderMarkdownForTTY(input string, wordWrap int) (string, error) {
-	r, err := glamour.NewTermRenderer(
-		glamour.WithEnvironmentConfig(),
-		glamour.WithWordWrap(wordWrap),
-	)
+	r, err := NewMarkdownRenderer(wordWrap)
 	if err != nil {
-		return "", fmt.Errorf("new markdown renderer: %w", err)
+		return "", err
 	}
+	return RenderMarkdown(r, input)
+}
 
+// RenderMarkdown renders input with r, trimming trailing whitespace and
+// expanding tabs.
+func RenderMarkdown(r *glamour.TermRenderer, input string) (string, error) {
 	out, err := r.Render(input)
 	if err != nil {
 		return "", fmt.Errorf("render markdown: %w", err)
@@ -1,9 +1,13 @@
 package present
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 	"github.com/stretchr/testify/require"
 )
 
@@ -13,3 +17,77 @@ func TestRenderMarkdownForTTY(t *testing.T) {
 	require.True(t, strings.HasSuffix(out, "\n"))
 	require.False(t, strings.Contains(out, "\t"))
 }
+
+func TestMarkdownColors(t *testing.T) {
+	render := func(t *testing.T) string {
+		t.Helper()
+		var buf strings.Builder
+		r, err := NewMarkdownRenderer(80, MarkdownColors(lipgloss.NewRenderer(&buf)))
+		require.NoError(t, err)
+		out, err := RenderMarkdown(r, "# Title\n\nsome *text*")
+		require.NoError(t, err)
+		return out
+	}
+
+	t.Run("not a terminal", func(t *testing.T) {
+		t.Setenv("GLAMOUR_STYLE", "")
+		out := render(t)
+		require.NotContains(t, out, "\x1b[")
+		require.Contains(t, out, "# Title")
+	})
+
+	t.Run("forced colors", func(t *testing.T) {
+		t.Setenv("GLAMOUR_STYLE", "")
+		t.Setenv("CLICOLOR_FORCE", "1")
+		require.Contains(t, render(t), "\x1b[")
+	})
+
+	t.Run("no color wins over forced colors", func(t *testing.T) {
+		t.Setenv("GLAMOUR_STYLE", "")
+		t.Setenv("CLICOLOR_FORCE", "1")
+		t.Setenv("NO_COLOR", "1")
+		require.NotContains(t, render(t), "\x1b[")
+	})
+
+	t.Run("glamour style", func(t *testing.T) {
+		t.Setenv("GLAMOUR_STYLE", "ascii")
+		t.Setenv("CLICOLOR_FORCE", "1")
+		require.NotContains(t, render(t), "\x1b[")
+	})
+
+	t.Run("glamour-style wins over GLAMOUR_STYLE", func(t *testing.T) {
+		t.Setenv("GLAMOUR_STYLE", "dark")
+		t.Setenv("CLICOLOR_FORCE", "1")
+		UseMarkdownStyle("ascii")
+		t.Cleanup(func() { UseMarkdownStyle("") })
+		require.NotContains(t, render(t), "\x1b[")
+	})
+
+	t.Run("low color", func(t *testing.T) {
+		t.Setenv("GLAMOUR_STYLE", "")
+		var buf strings.Builder
+		lr := lipgloss.NewRenderer(&buf)
+		lr.SetColorProfile(termenv.ANSI)
+		r, err := NewMarkdownRenderer(80, MarkdownColors(lr))
+		require.NoError(t, err)
+		out, err := RenderMarkdown(r, "# Title\n\nsome `code`\n\n```go\nfunc main() {}\n```")
+		require.NoError(t, err)
+		require.Contains(t, out, "\x1b[")
+		require.NotContains(t, out, "\x1b[4", "no backgrounds")
+		require.NotContains(t, out, ";5;", "no 256 colors")
+	})
+}
+
+func TestCheckMarkdownStyle(t *testing.T) {
+	require.NoError(t, CheckMarkdownStyle(""))
+	require.NoError(t, CheckMarkdownStyle("auto"))
+	require.NoError(t, CheckMarkdownStyle("dracula"))
+
+	path := filepath.Join(t.TempDir(), "style.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"document": {"color": "1"}}`), 0o600))
+	require.NoError(t, CheckMarkdownStyle(path))
+
+	require.ErrorContains(t, CheckMarkdownStyle("solarized"), `markdown style "solarized" is not one of ascii, dark, dracula`)
+	require.NoError(t, os.WriteFile(path, []byte(`{`), 0o600))
+	require.ErrorContains(t, CheckMarkdownStyle(path), "markdown style "+path)
+}
@@ -0,0 +1,74 @@
+package present
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Postprocessors are the built-in filters for answers, by name.
+var Postprocessors = map[string]func(string) string{
+	"extract-code":   ExtractCode,
+	"strip-preamble": StripPreamble,
+	"trim":           strings.TrimSpace,
+}
+
+// PostprocessorNames returns the names of the built-in filters, sorted.
+func PostprocessorNames() []string {
+	return slices.Sorted(maps.Keys(Postprocessors))
+}
+
+// ExtractCode returns the contents of the fenced code blocks in s, with a
+// blank line between blocks. s without code blocks is returned as it is.
+func ExtractCode(s string) string {
+	var (
+		blocks []string
+		block  []string
+		fence  string
+	)
+	for line := range strings.SplitSeq(s, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence == "":
+			if f := codeFence(trimmed); f != "" {
+				fence = f
+				block = block[:0]
+			}
+		case strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "":
+			blocks = append(blocks, strings.Join(block, "\n"))
+			fence = ""
+		default:
+			block = append(block, line)
+		}
+	}
+	if fence != "" {
+		// An unclosed block runs to the end, as when the answer was cut off.
+		blocks = append(blocks, strings.Join(block, "\n"))
+	}
+	if len(blocks) == 0 {
+		return s
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+// codeFence returns the fence that line opens, such as "```", or "".
+func codeFence(line string) string {
+	for _, c := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, c))
+		if n >= 3 {
+			return strings.Repeat(c, n)
+		}
+	}
+	return ""
+}
+
+// StripPreamble drops a first line that only introduces the answer, such
+// as "Sure! Here is the script:", along with the blank lines after it.
+func StripPreamble(s string) string {
+	rest := strings.TrimLeft(s, "\n")
+	first, after, ok := strings.Cut(rest, "\n")
+	if !ok || !strings.HasSuffix(strings.TrimSpace(first), ":") || strings.TrimSpace(after) == "" {
+		return s
+	}
+	return strings.TrimLeft(after, "\n")
+}
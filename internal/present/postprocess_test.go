@@ -0,0 +1,45 @@
+package present
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractCode(t *testing.T) {
+	for name, tc := range map[string]struct {
+		in, want string
+	}{
+		"one block":   {"Here you go:\n\n```go\nfmt.Println(1)\n```\n\nEnjoy!", "fmt.Println(1)"},
+		"two blocks":  {"```sh\nmake\n```\nthen\n~~~\nmake test\n~~~", "make\n\nmake test"},
+		"long fence":  {"````md\n```go\nx\n```\n````", "```go\nx\n```"},
+		"unclosed":    {"```py\nprint(1)\nprint(2)", "print(1)\nprint(2)"},
+		"indented":    {"  ```\n  ls -l\n  ```", "  ls -l"},
+		"no code":     {"just prose\n", "just prose\n"},
+		"empty block": {"```\n```", ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.want, ExtractCode(tc.in))
+		})
+	}
+}
+
+func TestStripPreamble(t *testing.T) {
+	for name, tc := range map[string]struct {
+		in, want string
+	}{
+		"preamble":       {"Sure! Here is the script:\n\n```sh\nls\n```", "```sh\nls\n```"},
+		"leading blanks": {"\nHere it is:\nls -l\n", "ls -l\n"},
+		"no colon":       {"The answer is 42.\n\nMore.", "The answer is 42.\n\nMore."},
+		"only line":      {"Steps:", "Steps:"},
+		"nothing after":  {"Steps:\n\n", "Steps:\n\n"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.want, StripPreamble(tc.in))
+		})
+	}
+}
+
+func TestPostprocessorNames(t *testing.T) {
+	require.Equal(t, []string{"extract-code", "strip-preamble", "trim"}, PostprocessorNames())
+}
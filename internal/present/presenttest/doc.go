@@ -0,0 +1,9 @@
+// Package presenttest renders yai's terminal output at a fixed width and color
+// profile, and compares it against golden files, so that style changes show
+// up as reviewable diffs.
+//
+// Golden files live in the calling package's testdata directory, named after
+// the test. Regenerate them with:
+//
+//	go test ./... -update
+package presenttest
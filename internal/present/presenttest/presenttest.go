@@ -0,0 +1,56 @@
+package presenttest
+
+import (
+	"io"
+	"testing"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/exp/golden"
+	"github.com/dotcommander/yai/internal/present"
+	"github.com/muesli/termenv"
+)
+
+// Width is the terminal width snapshots are rendered at.
+const Width = 80
+
+// Renderer returns a lipgloss renderer with a TrueColor profile and a dark
+// background, whatever the terminal running the tests.
+func Renderer() *lipgloss.Renderer {
+	r := lipgloss.NewRenderer(io.Discard)
+	r.SetColorProfile(termenv.TrueColor)
+	r.SetHasDarkBackground(true)
+	return r
+}
+
+// Styles returns the shared styles bound to Renderer.
+func Styles() present.Styles {
+	return present.MakeStyles(Renderer())
+}
+
+// Markdown renders md the way yai does, pinned to the dark glamour style and
+// TrueColor at Width columns.
+func Markdown(tb testing.TB, md string) string {
+	tb.Helper()
+	r, err := present.NewMarkdownRenderer(
+		Width,
+		glamour.WithStandardStyle(styles.DarkStyle),
+		glamour.WithColorProfile(termenv.TrueColor),
+	)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	out, err := present.RenderMarkdown(r, md)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return out
+}
+
+// RequireEqual fails tb unless out matches testdata/<test name>.golden. Escape
+// sequences are quoted in the diff so color changes are readable.
+func RequireEqual(tb testing.TB, out string) {
+	tb.Helper()
+	golden.RequireEqualEscape(tb, []byte(out), true)
+}
@@ -0,0 +1,54 @@
+package present_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dotcommander/yai/internal/present/presenttest"
+	"github.com/dotcommander/yai/internal/proto"
+)
+
+const document = "# Title\n\n" +
+	"Some **bold**, *italic*, and `inline code` with a [link](https://example.com).\n\n" +
+	"## List\n\n" +
+	"- one\n" +
+	"- two\n" +
+	"  1. nested\n\n" +
+	"> a quote\n\n" +
+	"```go\n" +
+	"func main() {\n" +
+	"\tfmt.Println(\"hello\")\n" +
+	"}\n" +
+	"```\n\n" +
+	"| Name | Value |\n" +
+	"|------|-------|\n" +
+	"| a    | 1     |\n"
+
+func TestMarkdown(t *testing.T) {
+	t.Run("document", func(t *testing.T) {
+		presenttest.RequireEqual(t, presenttest.Markdown(t, document))
+	})
+
+	t.Run("tool call", func(t *testing.T) {
+		md := "Looking it up." + proto.ToolCallStatus{Name: "search"}.String() + "Found it."
+		presenttest.RequireEqual(t, presenttest.Markdown(t, md))
+	})
+
+	t.Run("failed tool call", func(t *testing.T) {
+		status := proto.ToolCallStatus{Name: "search", Err: errors.New("rate limited\ntry again later")}
+		presenttest.RequireEqual(t, presenttest.Markdown(t, status.String()))
+	})
+}
+
+func TestStyles(t *testing.T) {
+	s := presenttest.Styles()
+	out := s.AppName.Render("yai") + "\n" +
+		s.Flag.Render("--model") + s.FlagComma.String() + " " + s.FlagDesc.Render("model to use") + "\n" +
+		s.InlineCode.Render("yai -h") + "\n" +
+		s.Link.Render("https://example.com") + "\n" +
+		s.Quote.Render("\"quoted\"") + " " + s.Pipe.Render("|") + "\n" +
+		s.SHA1.Render("abc1234") + " " + s.Timeago.Render("2 hours ago") + "\n" +
+		s.Warning.Render("almost full") + "\n" +
+		s.ErrPadding.Render(s.ErrorHeader.String(), "Something went wrong.") + "\n"
+	presenttest.RequireEqual(t, out)
+}
@@ -20,28 +20,32 @@ type Styles struct {
 	Quote,
 	ConversationList,
 	SHA1,
-	Timeago lipgloss.Style
+	Timeago,
+	Warning lipgloss.Style
 }
 
-// MakeStyles builds styles bound to the given renderer.
+// MakeStyles builds styles bound to the given renderer, in the colors of
+// the theme in use.
 func MakeStyles(r *lipgloss.Renderer) (s Styles) {
 	const horizontalEdgePadding = 2
-	s.AppName = r.NewStyle().Bold(true)
-	s.CliArgs = r.NewStyle().Foreground(lipgloss.Color("#585858"))
-	s.Comment = r.NewStyle().Foreground(lipgloss.Color("#757575"))
-	s.CyclingChars = r.NewStyle().Foreground(lipgloss.Color("#FF87D7"))
-	s.ErrorHeader = r.NewStyle().Foreground(lipgloss.Color("#F1F1F1")).Background(lipgloss.Color("#FF5F87")).Bold(true).Padding(0, 1).SetString("ERROR")
-	s.ErrorDetails = s.Comment
+	t := theme
+	s.AppName = t.AppName.style(r)
+	s.CliArgs = t.CliArgs.style(r)
+	s.Comment = t.Comment.style(r)
+	s.CyclingChars = t.CyclingChars.style(r)
+	s.ErrorHeader = t.ErrorHeader.style(r).Padding(0, 1).SetString("ERROR")
+	s.ErrorDetails = t.ErrorDetails.style(r)
 	s.ErrPadding = r.NewStyle().Padding(0, horizontalEdgePadding)
-	s.Flag = r.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#00B594", Dark: "#3EEFCF"}).Bold(true)
-	s.FlagComma = r.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#5DD6C0", Dark: "#427C72"}).SetString(",")
-	s.FlagDesc = s.Comment
-	s.InlineCode = r.NewStyle().Foreground(lipgloss.Color("#FF5F87")).Background(lipgloss.Color("#3A3A3A")).Padding(0, 1)
-	s.Link = r.NewStyle().Foreground(lipgloss.Color("#00AF87")).Underline(true)
-	s.Quote = r.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#FF71D0", Dark: "#FF78D2"})
-	s.Pipe = r.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#8470FF", Dark: "#745CFF"})
+	s.Flag = t.Flag.style(r)
+	s.FlagComma = t.FlagComma.style(r).SetString(",")
+	s.FlagDesc = t.FlagDesc.style(r)
+	s.InlineCode = t.InlineCode.style(r).Padding(0, 1)
+	s.Link = t.Link.style(r)
+	s.Quote = t.Quote.style(r)
+	s.Pipe = t.Pipe.style(r)
 	s.ConversationList = r.NewStyle().Padding(0, 1)
-	s.SHA1 = s.Flag
-	s.Timeago = r.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#999", Dark: "#555"})
+	s.SHA1 = t.SHA1.style(r)
+	s.Timeago = t.Timeago.style(r)
+	s.Warning = t.Warning.style(r)
 	return s
 }
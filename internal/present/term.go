@@ -1,6 +1,7 @@
 package present
 
 import (
+	"fmt"
 	"os"
 	"sync"
 
@@ -9,6 +10,8 @@ import (
 	"github.com/muesli/termenv"
 )
 
+// isInputTTY leaves out the terminals of Git Bash and MSYS2 on Windows:
+// they are pipes to programs, so keys cannot be read one at a time.
 var isInputTTY = sync.OnceValue(func() bool {
 	return isatty.IsTerminal(os.Stdin.Fd())
 })
@@ -19,7 +22,7 @@ func IsInputTTY() bool {
 }
 
 var isOutputTTY = sync.OnceValue(func() bool {
-	return isatty.IsTerminal(os.Stdout.Fd())
+	return isTerminalFd(os.Stdout.Fd())
 })
 
 // IsOutputTTY reports whether stdout is a TTY.
@@ -27,6 +30,22 @@ func IsOutputTTY() bool {
 	return isOutputTTY()
 }
 
+var isErrorTTY = sync.OnceValue(func() bool {
+	return isTerminalFd(os.Stderr.Fd())
+})
+
+// IsErrorTTY reports whether stderr is a TTY.
+func IsErrorTTY() bool {
+	return isErrorTTY()
+}
+
+// isTerminalFd reports whether fd is a terminal to write to, including the
+// terminals of Git Bash and MSYS2 on Windows, which show colors and
+// redraws like any other.
+func isTerminalFd(fd uintptr) bool {
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
 var stdoutRenderer = sync.OnceValue(func() *lipgloss.Renderer {
 	return lipgloss.DefaultRenderer()
 })
@@ -36,9 +55,7 @@ func StdoutRenderer() *lipgloss.Renderer {
 	return stdoutRenderer()
 }
 
-var stdoutStyles = sync.OnceValue(func() Styles {
-	return MakeStyles(StdoutRenderer())
-})
+var stdoutStyles = stylesFor(StdoutRenderer)
 
 // StdoutStyles returns shared styles bound to stdout.
 func StdoutStyles() Styles {
@@ -54,11 +71,51 @@ func StderrRenderer() *lipgloss.Renderer {
 	return stderrRenderer()
 }
 
-var stderrStyles = sync.OnceValue(func() Styles {
-	return MakeStyles(StderrRenderer())
-})
+var stderrStyles = stylesFor(StderrRenderer)
 
 // StderrStyles returns shared styles bound to stderr.
 func StderrStyles() Styles {
 	return stderrStyles()
 }
+
+// stylesFor makes the styles of the renderer once, on first use.
+func stylesFor(r func() *lipgloss.Renderer) func() Styles {
+	return sync.OnceValue(func() Styles {
+		return MakeStyles(r())
+	})
+}
+
+// Color modes of UseColor.
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+// UseColor sets whether the stdout and stderr renderers write colors. auto
+// leaves it to whether they are terminals, NO_COLOR, and CLICOLOR_FORCE;
+// always writes colors to pipes and files too, and never leaves them out.
+func UseColor(mode string) error {
+	for _, r := range []*lipgloss.Renderer{StdoutRenderer(), StderrRenderer()} {
+		switch mode {
+		case "", ColorAuto:
+			r.SetColorProfile(r.Output().EnvColorProfile())
+		case ColorAlways:
+			r.SetColorProfile(forcedColorProfile(r.Output()))
+		case ColorNever:
+			r.SetColorProfile(termenv.Ascii)
+		default:
+			return fmt.Errorf("color must be %s, %s, or %s, got %q", ColorAuto, ColorAlways, ColorNever, mode)
+		}
+	}
+	return nil
+}
+
+// forcedColorProfile is the color profile of o as if it were a terminal,
+// by TERM and COLORTERM, and at least 16 colors.
+func forcedColorProfile(o *termenv.Output) termenv.Profile {
+	if p := termenv.NewOutput(o.Writer(), termenv.WithTTY(true)).ColorProfile(); p != termenv.Ascii {
+		return p
+	}
+	return termenv.ANSI
+}
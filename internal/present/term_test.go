@@ -0,0 +1,28 @@
+package present
+
+import (
+	"testing"
+
+	"github.com/muesli/termenv"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUseColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv("TERM", "xterm-256color")
+	t.Cleanup(func() { _ = UseColor(ColorAuto) })
+
+	require.NoError(t, UseColor(ColorAlways))
+	require.Equal(t, termenv.ANSI256, StdoutRenderer().ColorProfile())
+	require.Equal(t, termenv.ANSI256, StderrRenderer().ColorProfile())
+	require.Contains(t, StderrStyles().Warning.Render("x"), "\x1b[")
+
+	require.NoError(t, UseColor(ColorNever))
+	require.Equal(t, termenv.Ascii, StdoutRenderer().ColorProfile())
+	require.Equal(t, "x", StderrStyles().Warning.Render("x"))
+
+	require.NoError(t, UseColor(ColorAuto))
+	require.Equal(t, termenv.Ascii, StdoutRenderer().ColorProfile(), "NO_COLOR")
+
+	require.EqualError(t, UseColor("sometimes"), `color must be auto, always, or never, got "sometimes"`)
+}
@@ -0,0 +1,342 @@
+package present
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultTheme is the theme used when none is set.
+const DefaultTheme = "charm"
+
+// Color is a theme color: a hex code such as "#FF5F87" or an ANSI color
+// number, the same on light and dark backgrounds, or a pair that differs.
+// In YAML it is a string, or a mapping with light and dark keys.
+type Color struct {
+	Light string `yaml:"light"`
+	Dark  string `yaml:"dark"`
+}
+
+// Hex is a color that is the same on light and dark backgrounds.
+func Hex(c string) Color {
+	return Color{Light: c, Dark: c}
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *Color) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*c = Hex(node.Value)
+		return nil
+	}
+	type plain Color
+	return node.Decode((*plain)(c))
+}
+
+func (c Color) terminalColor() lipgloss.TerminalColor {
+	if c.Light == c.Dark {
+		return lipgloss.Color(c.Light)
+	}
+	return lipgloss.AdaptiveColor{Light: c.Light, Dark: c.Dark}
+}
+
+// StyleSpec is how a theme draws one kind of text.
+type StyleSpec struct {
+	Foreground Color `yaml:"foreground"`
+	Background Color `yaml:"background"`
+	Bold       bool  `yaml:"bold"`
+	Italic     bool  `yaml:"italic"`
+	Underline  bool  `yaml:"underline"`
+	Reverse    bool  `yaml:"reverse"`
+}
+
+func (s StyleSpec) style(r *lipgloss.Renderer) lipgloss.Style {
+	st := r.NewStyle()
+	if s.Foreground != (Color{}) {
+		st = st.Foreground(s.Foreground.terminalColor())
+	}
+	if s.Background != (Color{}) {
+		st = st.Background(s.Background.terminalColor())
+	}
+	if s.Bold {
+		st = st.Bold(true)
+	}
+	if s.Italic {
+		st = st.Italic(true)
+	}
+	if s.Underline {
+		st = st.Underline(true)
+	}
+	if s.Reverse {
+		st = st.Reverse(true)
+	}
+	return st
+}
+
+// Theme sets the colors of everything yai prints. Theme files are YAML
+// with the same keys; keys they leave out are taken from their base theme.
+type Theme struct {
+	// Markdown is the glamour style of rendered answers: a style name, such
+	// as "dracula", or the path of a JSON style file. Empty picks the dark
+	// or light style by the terminal background.
+	Markdown string `yaml:"markdown"`
+	// Form is the theme of prompts and forms: charm, dracula, catppuccin,
+	// base16, or base.
+	Form string `yaml:"form"`
+	// Gradient is the two colors the name in the help and the waiting
+	// animation fade between. Empty draws them without color.
+	Gradient []string `yaml:"gradient"`
+
+	AppName      StyleSpec `yaml:"app-name"`
+	CliArgs      StyleSpec `yaml:"cli-args"`
+	Comment      StyleSpec `yaml:"comment"`
+	CyclingChars StyleSpec `yaml:"cycling-chars"`
+	ErrorHeader  StyleSpec `yaml:"error-header"`
+	ErrorDetails StyleSpec `yaml:"error-details"`
+	Flag         StyleSpec `yaml:"flag"`
+	FlagComma    StyleSpec `yaml:"flag-comma"`
+	FlagDesc     StyleSpec `yaml:"flag-desc"`
+	// Header is the label of confirmations, as in "WROTE".
+	Header     StyleSpec `yaml:"header"`
+	InlineCode StyleSpec `yaml:"inline-code"`
+	Link       StyleSpec `yaml:"link"`
+	Pipe       StyleSpec `yaml:"pipe"`
+	Quote      StyleSpec `yaml:"quote"`
+	SHA1       StyleSpec `yaml:"sha"`
+	Timeago    StyleSpec `yaml:"timeago"`
+	Warning    StyleSpec `yaml:"warning"`
+}
+
+// Themes are the built-in themes, by name.
+var Themes = map[string]Theme{ //nolint:gochecknoglobals
+	"charm": {
+		Form:         "charm",
+		Gradient:     []string{"#F967DC", "#6B50FF"},
+		AppName:      StyleSpec{Bold: true},
+		CliArgs:      StyleSpec{Foreground: Hex("#585858")},
+		Comment:      StyleSpec{Foreground: Hex("#757575")},
+		CyclingChars: StyleSpec{Foreground: Hex("#FF87D7")},
+		ErrorHeader:  StyleSpec{Foreground: Hex("#F1F1F1"), Background: Hex("#FF5F87"), Bold: true},
+		ErrorDetails: StyleSpec{Foreground: Hex("#757575")},
+		Flag:         StyleSpec{Foreground: Color{Light: "#00B594", Dark: "#3EEFCF"}, Bold: true},
+		FlagComma:    StyleSpec{Foreground: Color{Light: "#5DD6C0", Dark: "#427C72"}},
+		FlagDesc:     StyleSpec{Foreground: Hex("#757575")},
+		Header:       StyleSpec{Foreground: Hex("#F1F1F1"), Background: Hex("#6C50FF"), Bold: true},
+		InlineCode:   StyleSpec{Foreground: Hex("#FF5F87"), Background: Hex("#3A3A3A")},
+		Link:         StyleSpec{Foreground: Hex("#00AF87"), Underline: true},
+		Pipe:         StyleSpec{Foreground: Color{Light: "#8470FF", Dark: "#745CFF"}},
+		Quote:        StyleSpec{Foreground: Color{Light: "#FF71D0", Dark: "#FF78D2"}},
+		SHA1:         StyleSpec{Foreground: Color{Light: "#00B594", Dark: "#3EEFCF"}, Bold: true},
+		Timeago:      StyleSpec{Foreground: Color{Light: "#999", Dark: "#555"}},
+		Warning:      StyleSpec{Foreground: Color{Light: "#D78700", Dark: "#FFAF00"}},
+	},
+	"dracula": {
+		Form:         "dracula",
+		Markdown:     "dracula",
+		Gradient:     []string{"#FF79C6", "#BD93F9"},
+		AppName:      StyleSpec{Bold: true},
+		CliArgs:      StyleSpec{Foreground: Hex("#44475A")},
+		Comment:      StyleSpec{Foreground: Hex("#6272A4")},
+		CyclingChars: StyleSpec{Foreground: Hex("#FF79C6")},
+		ErrorHeader:  StyleSpec{Foreground: Hex("#282A36"), Background: Hex("#FF5555"), Bold: true},
+		ErrorDetails: StyleSpec{Foreground: Hex("#6272A4")},
+		Flag:         StyleSpec{Foreground: Hex("#50FA7B"), Bold: true},
+		FlagComma:    StyleSpec{Foreground: Hex("#6272A4")},
+		FlagDesc:     StyleSpec{Foreground: Hex("#6272A4")},
+		Header:       StyleSpec{Foreground: Hex("#282A36"), Background: Hex("#BD93F9"), Bold: true},
+		InlineCode:   StyleSpec{Foreground: Hex("#FF79C6"), Background: Hex("#44475A")},
+		Link:         StyleSpec{Foreground: Hex("#8BE9FD"), Underline: true},
+		Pipe:         StyleSpec{Foreground: Hex("#BD93F9")},
+		Quote:        StyleSpec{Foreground: Hex("#F1FA8C")},
+		SHA1:         StyleSpec{Foreground: Hex("#50FA7B"), Bold: true},
+		Timeago:      StyleSpec{Foreground: Hex("#6272A4")},
+		Warning:      StyleSpec{Foreground: Hex("#FFB86C")},
+	},
+	// catppuccin is Latte on light backgrounds and Mocha on dark ones.
+	"catppuccin": {
+		Form:         "catppuccin",
+		Gradient:     []string{"#F5C2E7", "#CBA6F7"},
+		AppName:      StyleSpec{Bold: true},
+		CliArgs:      StyleSpec{Foreground: Color{Light: "#ACB0BE", Dark: "#585B70"}},
+		Comment:      StyleSpec{Foreground: Color{Light: "#9CA0B0", Dark: "#6C7086"}},
+		CyclingChars: StyleSpec{Foreground: Color{Light: "#EA76CB", Dark: "#F5C2E7"}},
+		ErrorHeader: StyleSpec{
+			Foreground: Color{Light: "#EFF1F5", Dark: "#1E1E2E"},
+			Background: Color{Light: "#D20F39", Dark: "#F38BA8"},
+			Bold:       true,
+		},
+		ErrorDetails: StyleSpec{Foreground: Color{Light: "#9CA0B0", Dark: "#6C7086"}},
+		Flag:         StyleSpec{Foreground: Color{Light: "#40A02B", Dark: "#A6E3A1"}, Bold: true},
+		FlagComma:    StyleSpec{Foreground: Color{Light: "#179299", Dark: "#94E2D5"}},
+		FlagDesc:     StyleSpec{Foreground: Color{Light: "#9CA0B0", Dark: "#6C7086"}},
+		Header: StyleSpec{
+			Foreground: Color{Light: "#EFF1F5", Dark: "#1E1E2E"},
+			Background: Color{Light: "#8839EF", Dark: "#CBA6F7"},
+			Bold:       true,
+		},
+		InlineCode: StyleSpec{
+			Foreground: Color{Light: "#D20F39", Dark: "#F38BA8"},
+			Background: Color{Light: "#CCD0DA", Dark: "#313244"},
+		},
+		Link:    StyleSpec{Foreground: Color{Light: "#1E66F5", Dark: "#89B4FA"}, Underline: true},
+		Pipe:    StyleSpec{Foreground: Color{Light: "#8839EF", Dark: "#CBA6F7"}},
+		Quote:   StyleSpec{Foreground: Color{Light: "#EA76CB", Dark: "#F5C2E7"}},
+		SHA1:    StyleSpec{Foreground: Color{Light: "#40A02B", Dark: "#A6E3A1"}, Bold: true},
+		Timeago: StyleSpec{Foreground: Color{Light: "#9CA0B0", Dark: "#585B70"}},
+		Warning: StyleSpec{Foreground: Color{Light: "#FE640B", Dark: "#FAB387"}},
+	},
+	"nord": {
+		Form:         "base16",
+		Gradient:     []string{"#88C0D0", "#5E81AC"},
+		AppName:      StyleSpec{Bold: true},
+		CliArgs:      StyleSpec{Foreground: Hex("#4C566A")},
+		Comment:      StyleSpec{Foreground: Hex("#616E88")},
+		CyclingChars: StyleSpec{Foreground: Hex("#88C0D0")},
+		ErrorHeader:  StyleSpec{Foreground: Hex("#2E3440"), Background: Hex("#BF616A"), Bold: true},
+		ErrorDetails: StyleSpec{Foreground: Hex("#616E88")},
+		Flag:         StyleSpec{Foreground: Hex("#A3BE8C"), Bold: true},
+		FlagComma:    StyleSpec{Foreground: Hex("#8FBCBB")},
+		FlagDesc:     StyleSpec{Foreground: Hex("#616E88")},
+		Header:       StyleSpec{Foreground: Hex("#2E3440"), Background: Hex("#88C0D0"), Bold: true},
+		InlineCode:   StyleSpec{Foreground: Hex("#88C0D0"), Background: Hex("#3B4252")},
+		Link:         StyleSpec{Foreground: Hex("#81A1C1"), Underline: true},
+		Pipe:         StyleSpec{Foreground: Hex("#5E81AC")},
+		Quote:        StyleSpec{Foreground: Hex("#B48EAD")},
+		SHA1:         StyleSpec{Foreground: Hex("#A3BE8C"), Bold: true},
+		Timeago:      StyleSpec{Foreground: Hex("#4C566A")},
+		Warning:      StyleSpec{Foreground: Hex("#EBCB8B")},
+	},
+	// base16 uses the terminal's own palette.
+	"base16": {
+		Form:         "base16",
+		AppName:      StyleSpec{Bold: true},
+		CliArgs:      StyleSpec{Foreground: Hex("8")},
+		Comment:      StyleSpec{Foreground: Hex("8")},
+		CyclingChars: StyleSpec{Foreground: Hex("5")},
+		ErrorHeader:  StyleSpec{Foreground: Hex("0"), Background: Hex("1"), Bold: true},
+		ErrorDetails: StyleSpec{Foreground: Hex("8")},
+		Flag:         StyleSpec{Foreground: Hex("2"), Bold: true},
+		FlagComma:    StyleSpec{Foreground: Hex("6")},
+		FlagDesc:     StyleSpec{Foreground: Hex("8")},
+		Header:       StyleSpec{Foreground: Hex("0"), Background: Hex("5"), Bold: true},
+		InlineCode:   StyleSpec{Foreground: Hex("1")},
+		Link:         StyleSpec{Foreground: Hex("4"), Underline: true},
+		Pipe:         StyleSpec{Foreground: Hex("5")},
+		Quote:        StyleSpec{Foreground: Hex("5")},
+		SHA1:         StyleSpec{Foreground: Hex("2"), Bold: true},
+		Timeago:      StyleSpec{Foreground: Hex("8")},
+		Warning:      StyleSpec{Foreground: Hex("3")},
+	},
+	// plain draws without colors, for terminals and readers that do
+	// without them.
+	"plain": {
+		Form:        "base",
+		Markdown:    "notty",
+		AppName:     StyleSpec{Bold: true},
+		ErrorHeader: StyleSpec{Bold: true, Reverse: true},
+		Flag:        StyleSpec{Bold: true},
+		Header:      StyleSpec{Bold: true, Reverse: true},
+		Link:        StyleSpec{Underline: true},
+		SHA1:        StyleSpec{Bold: true},
+	},
+}
+
+// ThemeNames returns the names of the built-in themes and of the theme
+// files in dir, sorted.
+func ThemeNames(dir string) []string {
+	names := slices.Collect(maps.Keys(Themes))
+	var files []string
+	if dir != "" {
+		files, _ = filepath.Glob(filepath.Join(dir, "*.yml"))
+	}
+	for _, f := range files {
+		if name := strings.TrimSuffix(filepath.Base(f), ".yml"); !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// LoadTheme returns the theme called name: the file name.yml in dir when
+// there is one, or else the built-in theme. A name ending in .yml or .yaml
+// is the path of a theme file.
+func LoadTheme(name, dir string) (Theme, error) {
+	if name == "" {
+		name = DefaultTheme
+	}
+	path := filepath.Join(dir, name+".yml")
+	if ext := filepath.Ext(name); ext == ".yml" || ext == ".yaml" {
+		path = name
+	}
+	var data []byte
+	err := fs.ErrNotExist
+	if dir != "" || path == name {
+		data, err = os.ReadFile(path)
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && path != name:
+		if t, ok := Themes[name]; ok {
+			return t, nil
+		}
+		return Theme{}, fmt.Errorf("unknown theme %q; pick one of %s", name, strings.Join(ThemeNames(dir), ", "))
+	case err != nil:
+		return Theme{}, fmt.Errorf("read theme: %w", err)
+	}
+	t, err := parseTheme(data)
+	if err != nil {
+		return Theme{}, fmt.Errorf("theme %s: %w", path, err)
+	}
+	return t, nil
+}
+
+// parseTheme reads a theme file. Its base key names the built-in theme it
+// starts from, charm by default.
+func parseTheme(data []byte) (Theme, error) {
+	var file struct {
+		Base  string `yaml:"base"`
+		Theme `yaml:",inline"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return Theme{}, fmt.Errorf("parse: %w", err)
+	}
+	base := cmp.Or(file.Base, DefaultTheme)
+	t, ok := Themes[base]
+	if !ok {
+		return Theme{}, fmt.Errorf("unknown base theme %q", base)
+	}
+	file.Base, file.Theme = base, t
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return Theme{}, fmt.Errorf("parse: %w", err)
+	}
+	return file.Theme, nil
+}
+
+// theme is the theme styles are made with. It is set at start-up, before
+// the program runs.
+var theme = Themes[DefaultTheme] //nolint:gochecknoglobals
+
+// UseTheme makes t the theme of the styles made from now on, including the
+// shared stdout and stderr styles.
+func UseTheme(t Theme) {
+	theme = t
+	stdoutStyles = stylesFor(StdoutRenderer)
+	stderrStyles = stylesFor(StderrRenderer)
+}
+
+// CurrentTheme returns the theme in use.
+func CurrentTheme() Theme {
+	return theme
+}
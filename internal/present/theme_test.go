@@ -0,0 +1,97 @@
+package present
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTheme(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+
+	t.Run("built-in", func(t *testing.T) {
+		got, err := LoadTheme("dracula", dir)
+		require.NoError(t, err)
+		require.Equal(t, Themes["dracula"], got)
+	})
+
+	t.Run("default", func(t *testing.T) {
+		got, err := LoadTheme("", "")
+		require.NoError(t, err)
+		require.Equal(t, Themes[DefaultTheme], got)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		write("mine.yml", `base: nord
+markdown: ./mine.json
+comment:
+  foreground: "#111111"
+  italic: true
+warning:
+  foreground: {light: "#222222", dark: "#333333"}
+`)
+		got, err := LoadTheme("mine", dir)
+		require.NoError(t, err)
+		require.Equal(t, "./mine.json", got.Markdown)
+		require.Equal(t, StyleSpec{Foreground: Hex("#111111"), Italic: true}, got.Comment)
+		require.Equal(t, Color{Light: "#222222", Dark: "#333333"}, got.Warning.Foreground)
+		require.Equal(t, Themes["nord"].Flag, got.Flag, "keys left out come from the base theme")
+		require.Contains(t, ThemeNames(dir), "mine")
+	})
+
+	t.Run("file overrides built-in", func(t *testing.T) {
+		write("dracula.yml", "gradient: []\n")
+		got, err := LoadTheme("dracula", dir)
+		require.NoError(t, err)
+		require.Empty(t, got.Gradient)
+		require.Equal(t, Themes["charm"].Flag, got.Flag)
+	})
+
+	t.Run("path", func(t *testing.T) {
+		path := filepath.Join(dir, "other.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("base: plain\n"), 0o600))
+		got, err := LoadTheme(path, "")
+		require.NoError(t, err)
+		require.Equal(t, Themes["plain"], got)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := LoadTheme("solarized", dir)
+		require.ErrorContains(t, err, `unknown theme "solarized"; pick one of base16, catppuccin, charm`)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		write("typo.yml", "coment:\n  bold: true\n")
+		_, err := LoadTheme("typo", dir)
+		require.ErrorContains(t, err, "field coment not found")
+	})
+
+	t.Run("unknown base", func(t *testing.T) {
+		write("based.yml", "base: solarized\n")
+		_, err := LoadTheme("based", dir)
+		require.ErrorContains(t, err, `unknown base theme "solarized"`)
+	})
+}
+
+func TestUseTheme(t *testing.T) {
+	t.Cleanup(func() { UseTheme(Themes[DefaultTheme]) })
+
+	r := lipgloss.NewRenderer(os.Stdout)
+	UseTheme(Themes["dracula"])
+	s := MakeStyles(r)
+	require.Equal(t, lipgloss.Color("#6272A4"), s.Comment.GetForeground())
+	require.Equal(t, "dracula", CurrentTheme().Markdown)
+
+	UseTheme(Themes["plain"])
+	s = MakeStyles(r)
+	require.Equal(t, lipgloss.NoColor{}, s.Comment.GetForeground())
+	require.True(t, s.ErrorHeader.GetReverse())
+	require.Equal(t, lipgloss.Color(""), MakeGradientRamp(3)[0])
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package present
+
+// EnableVirtualTerminal makes the Windows console draw the escape
+// sequences written to stdout and stderr, and returns what undoes it. Other
+// terminals always do.
+func EnableVirtualTerminal() (restore func()) {
+	return func() {}
+}
@@ -0,0 +1,25 @@
+package present
+
+import (
+	"os"
+
+	"github.com/muesli/termenv"
+)
+
+// EnableVirtualTerminal makes the Windows console draw the escape
+// sequences written to stdout and stderr, such as colors and the progress
+// line, instead of printing them, and returns what undoes it. Outputs that
+// are not consoles are left alone.
+func EnableVirtualTerminal() (restore func()) {
+	var restores []func() error
+	for _, f := range []*os.File{os.Stdout, os.Stderr} {
+		if r, err := termenv.EnableVirtualTerminalProcessing(termenv.NewOutput(f)); err == nil {
+			restores = append(restores, r)
+		}
+	}
+	return func() {
+		for _, r := range restores {
+			_ = r()
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package present
+
+import "github.com/charmbracelet/x/ansi"
+
+// Wrap soft-wraps s to width columns: lines break between words, and words
+// longer than a line are split. Widths are display widths, so CJK
+// characters and emoji take two columns; ANSI sequences are kept and take
+// none. A width of 0 or less leaves s as it is.
+func Wrap(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+	return ansi.Wrap(s, width, "")
+}
+
+// WrapWidth is the width to wrap markdown at in a terminal width columns
+// wide: wordWrap, or the terminal width when that is known and narrower.
+func WrapWidth(wordWrap, width int) int {
+	if width > 0 && width < wordWrap {
+		return width
+	}
+	return wordWrap
+}
@@ -0,0 +1,40 @@
+package present
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrap(t *testing.T) {
+	for name, tc := range map[string]struct {
+		in    string
+		width int
+		want  string
+	}{
+		"words":        {"the quick brown fox", 10, "the quick\nbrown fox"},
+		"long word":    {"abcdefghij", 4, "abcd\nefgh\nij"},
+		"cjk":          {"你好世界再见", 5, "你好\n世界\n再见"},
+		"emoji":        {"🙂🙂🙂 ok", 4, "🙂🙂\n🙂\nok"},
+		"ansi":         {"\x1b[1mbold\x1b[0m text", 4, "\x1b[1mbold\x1b[0m\ntext"},
+		"fits":         {"short", 80, "short"},
+		"no width":     {"the quick brown fox", 0, "the quick brown fox"},
+		"keeps breaks": {"a b\nc d", 3, "a b\nc d"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got := Wrap(tc.in, tc.width)
+			require.Equal(t, tc.want, got)
+			for _, line := range strings.Split(got, "\n") {
+				require.LessOrEqual(t, lipgloss.Width(line), max(tc.width, lipgloss.Width(tc.in)))
+			}
+		})
+	}
+}
+
+func TestWrapWidth(t *testing.T) {
+	require.Equal(t, 80, WrapWidth(80, 0))
+	require.Equal(t, 80, WrapWidth(80, 120))
+	require.Equal(t, 40, WrapWidth(80, 40))
+}
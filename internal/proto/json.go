@@ -0,0 +1,48 @@
+package proto
+
+// UsageJSON is Usage as yai prints it: in --output-format jsonl events,
+// batch results, and yai serve responses.
+type UsageJSON struct {
+	InputTokens      int64 `json:"input_tokens"`
+	OutputTokens     int64 `json:"output_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+	CacheWriteTokens int64 `json:"cache_write_tokens,omitempty"`
+	CacheReadTokens  int64 `json:"cache_read_tokens,omitempty"`
+}
+
+// NewUsageJSON returns u as yai prints it.
+func NewUsageJSON(u Usage) UsageJSON {
+	return UsageJSON{
+		InputTokens:      u.InputTokens,
+		OutputTokens:     u.OutputTokens,
+		TotalTokens:      u.TotalTokens,
+		CacheWriteTokens: u.CacheWriteTokens,
+		CacheReadTokens:  u.CacheReadTokens,
+	}
+}
+
+// LogprobJSON is TokenLogprob as yai prints it: in --output-format jsonl
+// events, and in conversations shown or imported as JSON.
+type LogprobJSON struct {
+	Token       string        `json:"token"`
+	Logprob     float64       `json:"logprob"`
+	TopLogprobs []LogprobJSON `json:"top_logprobs,omitempty"`
+}
+
+// NewLogprobsJSON returns logprobs as yai prints them.
+func NewLogprobsJSON(logprobs []TokenLogprob) []LogprobJSON {
+	var out []LogprobJSON
+	for _, lp := range logprobs {
+		out = append(out, LogprobJSON{Token: lp.Token, Logprob: lp.Logprob, TopLogprobs: NewLogprobsJSON(lp.Top)})
+	}
+	return out
+}
+
+// LogprobsFromJSON returns the logprobs NewLogprobsJSON printed.
+func LogprobsFromJSON(logprobs []LogprobJSON) []TokenLogprob {
+	var out []TokenLogprob
+	for _, lp := range logprobs {
+		out = append(out, TokenLogprob{Token: lp.Token, Logprob: lp.Logprob, Top: LogprobsFromJSON(lp.TopLogprobs)})
+	}
+	return out
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
@@ -22,6 +23,56 @@ type Chunk struct {
 	Content string
 }
 
+// Usage is the token accounting reported by a provider for a request.
+// Cache counts are the input tokens written to and served from the
+// provider's prompt cache.
+type Usage struct {
+	InputTokens      int64
+	OutputTokens     int64
+	TotalTokens      int64
+	CacheWriteTokens int64
+	CacheReadTokens  int64
+}
+
+// Add returns the sum of u and o.
+func (u Usage) Add(o Usage) Usage {
+	return Usage{
+		InputTokens:      u.InputTokens + o.InputTokens,
+		OutputTokens:     u.OutputTokens + o.OutputTokens,
+		TotalTokens:      u.TotalTokens + o.TotalTokens,
+		CacheWriteTokens: u.CacheWriteTokens + o.CacheWriteTokens,
+		CacheReadTokens:  u.CacheReadTokens + o.CacheReadTokens,
+	}
+}
+
+// ResponseMeta identifies the provider response behind an answer, to
+// reproduce it or quote it in a support request. Fields are empty when the
+// provider does not report them.
+type ResponseMeta struct {
+	// RequestID is the provider's ID of the request, e.g. from the
+	// x-request-id header.
+	RequestID string
+	// ModelVersion is the exact model that answered, e.g.
+	// gpt-4o-2024-11-20 for gpt-4o.
+	ModelVersion string
+}
+
+// CharsPerToken is the rough ratio used to estimate token counts where the
+// provider reports none, such as for saved or pending messages.
+const CharsPerToken = 4
+
+// EstimateTokens estimates the tokens in messages from their length.
+func EstimateTokens(messages []Message) int64 {
+	var chars int
+	for _, msg := range messages {
+		chars += len(msg.Content)
+		for _, call := range msg.ToolCalls {
+			chars += len(call.Function.Arguments)
+		}
+	}
+	return int64((chars + CharsPerToken - 1) / CharsPerToken)
+}
+
 // ToolCallStatus is the status of a tool call.
 type ToolCallStatus struct {
 	Name string
@@ -42,11 +93,80 @@ func (c ToolCallStatus) String() string {
 	return sb.String()
 }
 
+// ToolProgress is a progress report of a tool that is running.
+type ToolProgress struct {
+	Name     string
+	Progress float64
+	// Total is zero when the tool does not know how much there is to do.
+	Total   float64
+	Message string
+}
+
+// String renders p as a status, such as "github_search_issues: 40%".
+func (p ToolProgress) String() string {
+	var status string
+	switch {
+	case p.Total > 0:
+		status = fmt.Sprintf("%.0f%%", 100*p.Progress/p.Total)
+		if p.Message != "" {
+			status += " · " + p.Message
+		}
+	case p.Message != "":
+		status = p.Message
+	default:
+		status = fmt.Sprintf("%g", p.Progress)
+	}
+	return p.Name + ": " + status
+}
+
 // Message is a message in the conversation.
 type Message struct {
 	Role      string
 	Content   string
 	ToolCalls []ToolCall
+	// Partial marks an assistant message that was cut off before the
+	// model finished it.
+	Partial bool `json:",omitempty"`
+	// Time is when the message was written: when a prompt was sent, or
+	// when the model finished an answer. It is zero in messages saved
+	// before it was recorded.
+	Time time.Time `json:",omitzero"`
+	// Latency is how long the model took to write an assistant message,
+	// from sending the request to the last token, and FirstToken how long
+	// it took until the first one.
+	Latency    time.Duration `json:",omitempty"`
+	FirstToken time.Duration `json:",omitempty"`
+	// Usage is what the provider reported for writing an assistant
+	// message.
+	Usage Usage `json:",omitzero"`
+	// Logprobs are the log probabilities of the tokens of an assistant
+	// message, when they were asked for.
+	Logprobs []TokenLogprob `json:",omitempty"`
+}
+
+// TokenLogprob is the log probability of a token the model wrote, with the
+// likeliest alternatives to it.
+type TokenLogprob struct {
+	Token   string
+	Logprob float64
+	Top     []TokenLogprob `json:",omitempty"`
+}
+
+// Timing describes when the message was written and how long the model took
+// to write it, as in "2026-10-16 14:03:05 · 2.412s · first token 604ms". The
+// time is formatted with layout. It is empty when nothing was recorded.
+func (m Message) Timing(layout string) string {
+	var parts []string
+	if !m.Time.IsZero() {
+		parts = append(parts, m.Time.Local().Format(layout))
+	}
+	if m.Latency > 0 {
+		parts = append(parts, m.Latency.Round(time.Millisecond).String())
+	}
+	if m.FirstToken > 0 {
+		parts = append(parts, "first token "+m.FirstToken.Round(time.Millisecond).String())
+	}
+	return strings.Join(parts, " · ")
 }
 
 // ToolCall is a tool call in a message.
@@ -64,34 +184,60 @@ type Function struct {
 
 // Request is a chat request.
 type Request struct {
-	Messages            []Message
-	API                 string
-	Model               string
-	User                string
-	Tools               map[string][]mcp.Tool
-	Temperature         *float64
-	TopP                *float64
-	TopK                *int64
+	Messages         []Message
+	API              string
+	Model            string
+	User             string
+	Tools            map[string][]mcp.Tool
+	Temperature      *float64
+	TopP             *float64
+	TopK             *int64
+	PresencePenalty  *float64
+	FrequencyPenalty *float64
+	Seed             *int64
+	// Logprobs asks for the log probability of each token of the answer,
+	// with this many alternatives; 0 asks for none.
+	Logprobs int64
+	// JSON asks for an answer that is a JSON value, where the API has a
+	// mode for it.
+	JSON                bool
 	Stop                []string
 	MaxTokens           *int64
 	MaxCompletionTokens *int64
 	ToolCaller          func(name string, data []byte) (string, error)
+
+	// CacheSystem marks the leading system messages for provider-side
+	// prompt caching.
+	CacheSystem bool
 }
 
 // Conversation is a conversation.
 type Conversation []Message
 
 func (cc Conversation) String() string {
+	return cc.Transcript(false)
+}
+
+// Transcript renders the conversation as markdown. timings adds when each
+// message was written and how long each answer took, where recorded.
+func (cc Conversation) Transcript(timings bool) string {
 	var sb strings.Builder
 	for _, msg := range cc {
 		if msg.Content == "" {
 			continue
 		}
+		label := func(name string) {
+			sb.WriteString("**" + name + "**")
+			if t := msg.Timing(time.DateTime); timings && t != "" {
+				sb.WriteString(" (" + t + ")")
+			}
+			sb.WriteString(": ")
+		}
 		switch msg.Role {
 		case RoleSystem:
 			sb.WriteString("**System**: ")
 		case RoleUser:
-			sb.WriteString("**User**: ")
+			label("User")
 		case RoleTool:
 			for _, tool := range msg.ToolCalls {
 				s := ToolCallStatus{
@@ -104,7 +250,7 @@ func (cc Conversation) String() string {
 			}
 			continue
 		case RoleAssistant:
-			sb.WriteString("**Assistant**: ")
+			label("Assistant")
 		}
 		sb.WriteString(msg.Content)
 		sb.WriteString("\n\n")
@@ -1,7 +1,10 @@
 package proto
 
 import (
+	"encoding/json"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/charmbracelet/x/exp/golden"
 )
@@ -49,3 +52,58 @@ func TestStringer(t *testing.T) {
 
 	golden.RequireEqual(t, []byte(Conversation(messages).String()))
 }
+
+func TestTranscriptTimings(t *testing.T) {
+	at := time.Date(2026, 10, 16, 14, 3, 5, 0, time.Local)
+	messages := Conversation{
+		{Role: RoleUser, Content: "hi", Time: at},
+		{Role: RoleAssistant, Content: "hello", Time: at.Add(2 * time.Second), Latency: 2412 * time.Millisecond, FirstToken: 604 * time.Millisecond},
+		{Role: RoleAssistant, Content: "saved before timings"},
+	}
+
+	want := "**User** (2026-10-16 14:03:05): hi\n\n" +
+		"**Assistant** (2026-10-16 14:03:07 · 2.412s · first token 604ms): hello\n\n" +
+		"**Assistant**: saved before timings\n\n"
+	if got := messages.Transcript(true); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if got, want := messages.Transcript(false), messages.String(); got != want {
+		t.Errorf("expected no timings, got %q", got)
+	}
+}
+
+func TestToolProgressString(t *testing.T) {
+	for want, p := range map[string]ToolProgress{
+		"github_search_issues: 40%":               {Name: "github_search_issues", Progress: 4, Total: 10},
+		"github_search_issues: 40% · page 2 of 5": {Name: "github_search_issues", Progress: 0.4, Total: 1, Message: "page 2 of 5"},
+		"fs_index: scanning src":                  {Name: "fs_index", Progress: 12, Message: "scanning src"},
+		"fs_index: 12":                            {Name: "fs_index", Progress: 12},
+	} {
+		if got := p.String(); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	}
+}
+
+func TestLogprobsJSON(t *testing.T) {
+	logprobs := []TokenLogprob{
+		{Token: "Hi", Logprob: -0.5, Top: []TokenLogprob{{Token: "Hello", Logprob: -2}}},
+		{Token: "!", Logprob: -0.25},
+	}
+	out, err := json.Marshal(NewLogprobsJSON(logprobs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"token":"Hi","logprob":-0.5,"top_logprobs":[{"token":"Hello","logprob":-2}]},{"token":"!","logprob":-0.25}]`
+	if string(out) != want {
+		t.Errorf("expected %s, got %s", want, out)
+	}
+
+	var back []LogprobJSON
+	if err := json.Unmarshal(out, &back); err != nil {
+		t.Fatal(err)
+	}
+	if got := LogprobsFromJSON(back); !reflect.DeepEqual(got, logprobs) {
+		t.Errorf("expected %v, got %v", logprobs, got)
+	}
+}
@@ -0,0 +1,161 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+
+	"charm.land/fantasy"
+	fopenai "charm.land/fantasy/providers/openai"
+	"github.com/dotcommander/yai/internal/proto"
+)
+
+// bodyFields are the settings of request that are sent as fields of the
+// request body, where api takes them.
+func bodyFields(api string, request proto.Request) map[string]any {
+	fields := map[string]any{}
+	if request.Seed != nil && SupportsSeed(api) {
+		fields["seed"] = *request.Seed
+	}
+	if request.Logprobs > 0 && SupportsLogprobs(api) {
+		fields["logprobs"] = true
+		fields["top_logprobs"] = request.Logprobs
+	}
+	if request.JSON {
+		switch api {
+		case apiAnthropic, apiBedrock:
+			// No JSON mode; the format text asks for JSON.
+		case apiGoogle:
+			// Gemini takes no JSON mode together with function calling.
+			if len(request.Tools) == 0 {
+				fields["generationConfig"] = map[string]any{"responseMimeType": "application/json"}
+			}
+		default:
+			fields["response_format"] = map[string]any{"type": "json_object"}
+		}
+	}
+	return fields
+}
+
+// finishLogprobs returns the log probabilities the OpenAI API reported in
+// the metadata of a finished step.
+func finishLogprobs(metadata fantasy.ProviderMetadata) []proto.TokenLogprob {
+	meta, ok := metadata[fopenai.Name].(*fopenai.ProviderMetadata)
+	if !ok || len(meta.Logprobs) == 0 {
+		return nil
+	}
+	logprobs := make([]proto.TokenLogprob, 0, len(meta.Logprobs))
+	for _, lp := range meta.Logprobs {
+		token := proto.TokenLogprob{Token: lp.Token, Logprob: lp.Logprob}
+		for _, top := range lp.TopLogprobs {
+			token.Top = append(token.Top, proto.TokenLogprob{Token: top.Token, Logprob: top.Logprob})
+		}
+		logprobs = append(logprobs, token)
+	}
+	return logprobs
+}
+
+type bodyFieldsKey struct{}
+
+// withBodyFields has [bodyTransport] add fields to the JSON bodies of the
+// requests made with the returned context.
+func withBodyFields(ctx context.Context, fields map[string]any) context.Context {
+	return context.WithValue(ctx, bodyFieldsKey{}, fields)
+}
+
+// bodyTransport adds the fields in its request's context, if any, to the
+// JSON request body. It carries the request settings the Fantasy providers
+// have no option for, such as the OpenAI seed. Fields already in the body
+// are left as they are; objects are merged into those already there.
+type bodyTransport struct {
+	base http.RoundTripper
+}
+
+// withBodyFieldsClient returns a copy of client whose requests go through
+// [bodyTransport]. A nil client stands for [http.DefaultClient].
+func withBodyFieldsClient(client *http.Client) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	wrapped := *client
+	wrapped.Transport = bodyTransport{base: base}
+	return &wrapped
+}
+
+// RoundTrip implements http.RoundTripper. An API that turns down a request
+// asking for the OpenAI JSON mode with a 400 is asked again without it, as
+// not every OpenAI-compatible API has one; the format text still asks for
+// JSON.
+func (t bodyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fields, _ := req.Context().Value(bodyFieldsKey{}).(map[string]any)
+	if len(fields) == 0 || req.Body == nil || req.Body == http.NoBody {
+		return t.base.RoundTrip(req) //nolint:wrapcheck
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close() //nolint:errcheck,gosec
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	// Values are kept raw, so that the body's numbers keep their precision.
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(data, &body); err != nil || body == nil {
+		return t.send(req, data)
+	}
+	_, hadFormat := body["response_format"]
+	resp, err := t.send(req, withFields(body, fields))
+	if err != nil || resp.StatusCode != http.StatusBadRequest || hadFormat || fields["response_format"] == nil {
+		return resp, err
+	}
+	io.Copy(io.Discard, resp.Body) //nolint:errcheck,gosec
+	resp.Body.Close()              //nolint:errcheck,gosec
+	fields = maps.Clone(fields)
+	delete(fields, "response_format")
+	return t.send(req, withFields(body, fields))
+}
+
+// send sends req with data as its body.
+func (t bodyTransport) send(req *http.Request, data []byte) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+	req.ContentLength = int64(len(data))
+	return t.base.RoundTrip(req) //nolint:wrapcheck
+}
+
+// withFields returns body with fields merged into a copy of it, encoded.
+func withFields(body map[string]json.RawMessage, fields map[string]any) []byte {
+	merged := maps.Clone(body)
+	mergeFields(merged, fields)
+	data, _ := json.Marshal(merged)
+	return data
+}
+
+// mergeFields adds fields to body, leaving the values already there. A field
+// that is an object is merged into an object of the same name.
+func mergeFields(body map[string]json.RawMessage, fields map[string]any) {
+	for name, value := range fields {
+		existing, ok := body[name]
+		if !ok {
+			if raw, err := json.Marshal(value); err == nil {
+				body[name] = raw
+			}
+			continue
+		}
+		nested, isMap := value.(map[string]any)
+		var object map[string]json.RawMessage
+		if !isMap || json.Unmarshal(existing, &object) != nil || object == nil {
+			continue
+		}
+		mergeFields(object, nested)
+		if raw, err := json.Marshal(object); err == nil {
+			body[name] = raw
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyFields(t *testing.T) {
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+		bodies = append(bodies, body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"llama3","choices":[{"index":0,"delta":{"role":"assistant","content":"hi"},"finish_reason":"stop","logprobs":{"content":[{"token":"hi","logprob":-0.1,"bytes":[104,105],"top_logprobs":[{"token":"hi","logprob":-0.1,"bytes":[104,105]}]}],"refusal":null}}]}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := New(Config{API: "ollama", BaseURL: srv.URL, HTTPClient: srv.Client()})
+	require.NoError(t, err)
+	seed, penalty := int64(42), 0.5
+	var answers []proto.Message
+	for _, req := range []proto.Request{
+		{Model: "llama3", Seed: &seed, PresencePenalty: &penalty, Logprobs: 1},
+		{Model: "llama3"},
+		{Model: "llama3", JSON: true},
+	} {
+		req.Messages = []proto.Message{{Role: proto.RoleUser, Content: "hello"}}
+		st := client.Request(context.Background(), req)
+		for st.Next() {
+		}
+		require.NoError(t, st.Err())
+		require.NoError(t, st.Close())
+		msgs := st.Messages()
+		answers = append(answers, msgs[len(msgs)-1])
+	}
+
+	require.Len(t, bodies, 3)
+	require.EqualValues(t, 42, bodies[0]["seed"])
+	require.EqualValues(t, 0.5, bodies[0]["presence_penalty"])
+	require.Equal(t, "llama3", bodies[0]["model"])
+	require.Equal(t, true, bodies[0]["logprobs"])
+	require.EqualValues(t, 1, bodies[0]["top_logprobs"])
+	require.NotContains(t, bodies[1], "seed")
+	require.NotContains(t, bodies[1], "logprobs")
+	require.NotContains(t, bodies[1], "response_format")
+	require.Equal(t, map[string]any{"type": "json_object"}, bodies[2]["response_format"])
+
+	require.Equal(t, []proto.TokenLogprob{{
+		Token: "hi", Logprob: -0.1, Top: []proto.TokenLogprob{{Token: "hi", Logprob: -0.1}},
+	}}, answers[0].Logprobs)
+}
+
+func TestBodyFieldsJSON(t *testing.T) {
+	req := proto.Request{JSON: true}
+	require.Empty(t, bodyFields(apiAnthropic, req))
+	require.Equal(t, map[string]any{
+		"generationConfig": map[string]any{"responseMimeType": "application/json"},
+	}, bodyFields(apiGoogle, req))
+
+	req.Tools = map[string][]mcp.Tool{"fs": {{Name: "read"}}}
+	require.Empty(t, bodyFields(apiGoogle, req), "no JSON mode with tools")
+}
+
+func TestBodyFieldsJSONModeRefused(t *testing.T) {
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+		bodies = append(bodies, body)
+		if _, ok := body["response_format"]; ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"message":"response_format is not supported"}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"llama3","choices":[{"index":0,"delta":{"role":"assistant","content":"{}"},"finish_reason":"stop"}]}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := New(Config{API: "ollama", BaseURL: srv.URL, HTTPClient: srv.Client()})
+	require.NoError(t, err)
+	seed := int64(42)
+	st := client.Request(context.Background(), proto.Request{
+		Model:    "llama3",
+		JSON:     true,
+		Seed:     &seed,
+		Messages: []proto.Message{{Role: proto.RoleUser, Content: "hello"}},
+	})
+	for st.Next() {
+	}
+	require.NoError(t, st.Err())
+	require.NoError(t, st.Close())
+
+	require.Len(t, bodies, 2)
+	require.Contains(t, bodies[0], "response_format")
+	require.NotContains(t, bodies[1], "response_format")
+	require.EqualValues(t, 42, bodies[1]["seed"], "other fields are kept")
+}
+
+func TestMergeFields(t *testing.T) {
+	body := map[string]json.RawMessage{
+		"seed":             json.RawMessage(`1`),
+		"generationConfig": json.RawMessage(`{"temperature":0.5}`),
+	}
+	mergeFields(body, map[string]any{
+		"seed":             2,
+		"top_logprobs":     3,
+		"generationConfig": map[string]any{"responseMimeType": "application/json", "temperature": 1},
+	})
+	require.JSONEq(t, `1`, string(body["seed"]))
+	require.JSONEq(t, `3`, string(body["top_logprobs"]))
+	require.JSONEq(t, `{"temperature":0.5,"responseMimeType":"application/json"}`, string(body["generationConfig"]))
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"charm.land/fantasy"
 	"github.com/dotcommander/yai/internal/proto"
@@ -32,6 +33,9 @@ type Config struct {
 	APIKey         string //nolint:gosec // G117: required provider config field, not a hardcoded credential
 	HTTPClient     *http.Client
 	ThinkingBudget int
+	// Plugin, when set, is the command line of the provider plugin that
+	// answers instead of a Fantasy provider.
+	Plugin string
 }
 
 // Client is a stream.Client backed by charm.land/fantasy.
@@ -51,10 +55,7 @@ func New(cfg Config) (*Client, error) {
 
 // Request implements stream.Client.
 func (c *Client) Request(ctx context.Context, request proto.Request) stream.Stream {
-	streamCtx, cancel := context.WithCancel(ctx)
 	s := &Stream{
-		ctx:         streamCtx,
-		cancel:      cancel,
 		provider:    c.provider,
 		request:     request,
 		messages:    request.Messages,
@@ -62,6 +63,11 @@ func (c *Client) Request(ctx context.Context, request proto.Request) stream.Stre
 		config:      c.config,
 		warningSeen: map[string]struct{}{},
 	}
+	ctx = withMetaRecorder(ctx, &s.meta)
+	if fields := bodyFields(c.config.API, request); len(fields) > 0 {
+		ctx = withBodyFields(ctx, fields)
+	}
+	s.ctx, s.cancel = context.WithCancel(ctx)
 	if err := s.startStep(); err != nil {
 		s.err = err
 	}
@@ -91,6 +97,18 @@ type Stream struct {
 	stepDone         bool
 	warningSeen      map[string]struct{}
 	pendingWarnings  []string
+	usage            proto.Usage
+	meta             metaRecorder
+
+	// stepStart is when the current step was requested, and stepFirst how
+	// long its first text or tool call took; see proto.Message.Latency.
+	// stepUsage is what the provider reported for the step.
+	stepStart time.Time
+	stepFirst time.Duration
+	stepUsage proto.Usage
+	// stepLogprobs are the log probabilities of the tokens of the step,
+	// when they were asked for.
+	stepLogprobs []proto.TokenLogprob
 }
 
 const (
@@ -262,6 +280,18 @@ func (s *Stream) DrainWarnings() []string {
 	return warnings
 }
 
+// ResponseMeta implements stream.Stream.
+func (s *Stream) ResponseMeta() proto.ResponseMeta {
+	return s.meta.get()
+}
+
+// Usage implements stream.Stream.
+func (s *Stream) Usage() proto.Usage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage
+}
+
 func (s *Stream) startStep() error {
 	model, err := s.provider.LanguageModel(s.ctx, s.request.Model)
 	if err != nil {
@@ -276,6 +306,10 @@ func (s *Stream) startStep() error {
 	}
 
 	s.partCh = make(chan fantasy.StreamPart, 64)
+	s.stepStart = time.Now()
+	s.stepFirst = 0
+	s.stepUsage = proto.Usage{}
+	s.stepLogprobs = nil
 	s.stepDone = false
 	s.stepText.Reset()
 	s.stepToolCalls = nil
@@ -297,14 +331,16 @@ func (s *Stream) startStep() error {
 
 func (s *Stream) buildCall() fantasy.Call {
 	call := fantasy.Call{
-		Prompt:          toFantasyPrompt(s.messages),
-		MaxOutputTokens: s.request.MaxTokens,
-		Temperature:     s.request.Temperature,
-		TopP:            s.request.TopP,
-		TopK:            s.request.TopK,
-		Tools:           fromMCPTools(s.request.Tools),
-		ToolChoice:      toolChoiceForRequest(s.request),
-		ProviderOptions: fantasy.ProviderOptions{},
+		Prompt:           toFantasyPrompt(s.messages),
+		MaxOutputTokens:  s.request.MaxTokens,
+		Temperature:      s.request.Temperature,
+		TopP:             s.request.TopP,
+		TopK:             s.request.TopK,
+		PresencePenalty:  s.request.PresencePenalty,
+		FrequencyPenalty: s.request.FrequencyPenalty,
+		Tools:            fromMCPTools(s.request.Tools),
+		ToolChoice:       toolChoiceForRequest(s.request),
+		ProviderOptions:  fantasy.ProviderOptions{},
 	}
 
 	applyProviderOptions(&call, s.api, s.config, s.request)
@@ -313,10 +349,16 @@ func (s *Stream) buildCall() fantasy.Call {
 }
 
 func (s *Stream) finalizeStep() {
+	now := time.Now()
 	msg := proto.Message{
-		Role:      proto.RoleAssistant,
-		Content:   s.stepText.String(),
-		ToolCalls: append([]proto.ToolCall(nil), s.stepToolCalls...),
+		Role:       proto.RoleAssistant,
+		Content:    s.stepText.String(),
+		ToolCalls:  append([]proto.ToolCall(nil), s.stepToolCalls...),
+		Time:       now,
+		Latency:    now.Sub(s.stepStart),
+		FirstToken: s.stepFirst,
+		Usage:      s.stepUsage,
+		Logprobs:   s.stepLogprobs,
 	}
 	if msg.Content != "" || len(msg.ToolCalls) > 0 {
 		s.messages = append(s.messages, msg)
@@ -325,6 +367,9 @@ func (s *Stream) finalizeStep() {
 }
 
 func (s *Stream) consumePart(part fantasy.StreamPart) {
+	if s.stepFirst == 0 && (part.Type == fantasy.StreamPartTypeTextDelta || part.Type == fantasy.StreamPartTypeToolCall) {
+		s.stepFirst = time.Since(s.stepStart)
+	}
 	switch part.Type {
 	case fantasy.StreamPartTypeTextDelta:
 		s.stepText.WriteString(part.Delta)
@@ -353,6 +398,18 @@ func (s *Stream) consumePart(part fantasy.StreamPart) {
 		})
 	case fantasy.StreamPartTypeError:
 		s.err = part.Error
+	case fantasy.StreamPartTypeFinish:
+		usage := proto.Usage{
+			InputTokens:  part.Usage.InputTokens,
+			OutputTokens: part.Usage.OutputTokens,
+			TotalTokens:  part.Usage.TotalTokens,
+
+			CacheWriteTokens: part.Usage.CacheCreationTokens,
+			CacheReadTokens:  part.Usage.CacheReadTokens,
+		}
+		s.usage = s.usage.Add(usage)
+		s.stepUsage = s.stepUsage.Add(usage)
+		s.stepLogprobs = append(s.stepLogprobs, finishLogprobs(part.ProviderMetadata)...)
 	case fantasy.StreamPartTypeWarnings:
 		for _, warning := range part.Warnings {
 			text := strings.TrimSpace(warning.Message)
@@ -382,8 +439,7 @@ func (s *Stream) consumePart(part fantasy.StreamPart) {
 		fantasy.StreamPartTypeToolInputDelta,
 		fantasy.StreamPartTypeToolInputEnd,
 		fantasy.StreamPartTypeToolResult,
-		fantasy.StreamPartTypeSource,
-		fantasy.StreamPartTypeFinish:
+		fantasy.StreamPartTypeSource:
 		return
 	default:
 		return
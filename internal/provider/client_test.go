@@ -2,8 +2,10 @@ package provider
 
 import (
 	"testing"
+	"time"
 
 	"charm.land/fantasy"
+	fanthropic "charm.land/fantasy/providers/anthropic"
 	"charm.land/fantasy/providers/google"
 	fopenai "charm.land/fantasy/providers/openai"
 	fopenaicompat "charm.land/fantasy/providers/openaicompat"
@@ -31,6 +33,27 @@ func TestBuildCallGoogleThinkingBudget(t *testing.T) {
 	require.EqualValues(t, 256, *opts.ThinkingConfig.ThinkingBudget)
 }
 
+func TestBuildCallAnthropicThinkingBudget(t *testing.T) {
+	temp, maxTokens := 1.0, int64(20000)
+	s := &Stream{
+		api:     "anthropic",
+		config:  Config{ThinkingBudget: 8000},
+		request: proto.Request{Temperature: &temp},
+	}
+
+	call := s.buildCall()
+	opts, ok := call.ProviderOptions[fanthropic.Name].(*fanthropic.ProviderOptions)
+	require.True(t, ok)
+	require.NotNil(t, opts.Thinking)
+	require.EqualValues(t, 8000, opts.Thinking.BudgetTokens)
+	require.Nil(t, call.Temperature, "thinking takes no sampling settings")
+	require.EqualValues(t, 8000+thinkingAnswerTokens, *call.MaxOutputTokens)
+
+	s.request.MaxTokens = &maxTokens
+	call = s.buildCall()
+	require.EqualValues(t, 20000, *call.MaxOutputTokens)
+}
+
 func TestBuildCallNonGoogleNoThinkingBudgetOption(t *testing.T) {
 	s := &Stream{
 		api: "openai",
@@ -156,6 +179,53 @@ func TestBuildCallMaxCompletionTokensProviderOptions(t *testing.T) {
 	})
 }
 
+func TestBuildCallCacheSystem(t *testing.T) {
+	messages := []proto.Message{
+		{Role: proto.RoleSystem, Content: "format"},
+		{Role: proto.RoleSystem, Content: "a long role"},
+		{Role: proto.RoleUser, Content: "hi"},
+	}
+
+	t.Run("anthropic marks the last system message", func(t *testing.T) {
+		s := &Stream{
+			api:      "anthropic",
+			messages: messages,
+			request:  proto.Request{Messages: messages, CacheSystem: true},
+		}
+
+		call := s.buildCall()
+		require.Nil(t, fanthropic.GetCacheControl(call.Prompt[0].ProviderOptions))
+		require.NotNil(t, fanthropic.GetCacheControl(call.Prompt[1].ProviderOptions))
+		require.Nil(t, fanthropic.GetCacheControl(call.Prompt[2].ProviderOptions))
+	})
+
+	t.Run("openai sets a stable prompt cache key", func(t *testing.T) {
+		s := &Stream{
+			api:      "openai",
+			messages: messages,
+			request:  proto.Request{Messages: messages, CacheSystem: true},
+		}
+
+		opts, ok := s.buildCall().ProviderOptions[fopenai.Name].(*fopenai.ProviderOptions)
+		require.True(t, ok)
+		require.NotNil(t, opts.PromptCacheKey)
+		require.Equal(t, systemCacheKey(messages), *opts.PromptCacheKey)
+		require.NotEqual(t, systemCacheKey(messages[1:]), *opts.PromptCacheKey)
+	})
+
+	t.Run("not marked without CacheSystem", func(t *testing.T) {
+		s := &Stream{
+			api:      "anthropic",
+			messages: messages,
+			request:  proto.Request{Messages: messages},
+		}
+
+		call := s.buildCall()
+		require.Nil(t, fanthropic.GetCacheControl(call.Prompt[1].ProviderOptions))
+		require.Empty(t, call.ProviderOptions)
+	})
+}
+
 func TestConsumePartSkipsProviderExecutedToolCalls(t *testing.T) {
 	s := &Stream{stepToolCallSeen: map[string]struct{}{}}
 
@@ -185,3 +255,20 @@ func TestDrainWarningsDeduplicates(t *testing.T) {
 	require.Equal(t, []string{"unsupported setting: top_k"}, warnings)
 	require.Empty(t, s.DrainWarnings())
 }
+
+func TestFinalizeStepRecordsTimings(t *testing.T) {
+	s := &Stream{stepToolCallSeen: map[string]struct{}{}, stepStart: time.Now().Add(-time.Second)}
+
+	s.consumePart(fantasy.StreamPart{Type: fantasy.StreamPartTypeTextDelta, Delta: "hi"})
+	s.consumePart(fantasy.StreamPart{Type: fantasy.StreamPartTypeTextDelta, Delta: " there"})
+	first := s.stepFirst
+	s.finalizeStep()
+
+	require.Len(t, s.messages, 1)
+	msg := s.messages[0]
+	require.Equal(t, "hi there", msg.Content)
+	require.GreaterOrEqual(t, msg.FirstToken, time.Second)
+	require.Equal(t, first, msg.FirstToken, "later chunks must not move the first token")
+	require.GreaterOrEqual(t, msg.Latency, msg.FirstToken)
+	require.WithinDuration(t, time.Now(), msg.Time, time.Minute)
+}
@@ -0,0 +1,243 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/dotcommander/yai/internal/proto"
+)
+
+const (
+	apiOllama = "ollama"
+
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+	defaultGoogleBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+)
+
+// defaultEmbedModels maps API names to the embedding model used when none is
+// configured.
+var defaultEmbedModels = map[string]string{
+	apiOpenAI: "text-embedding-3-small",
+	apiGoogle: "gemini-embedding-001",
+	apiOllama: "nomic-embed-text",
+}
+
+// Embeddings is the result of an embeddings request. Vectors are in input
+// order.
+type Embeddings struct {
+	Model   string
+	Vectors [][]float64
+	Usage   proto.Usage
+}
+
+// DefaultEmbedModel returns the default embedding model for api, or an empty
+// string when there is none.
+func DefaultEmbedModel(api string) string {
+	return defaultEmbedModels[api]
+}
+
+// Embed computes embeddings for inputs with model. Google uses the Gemini
+// batch endpoint; every other API is assumed to expose an OpenAI-compatible
+// /embeddings endpoint (OpenAI, Ollama, and most compatible servers do).
+func Embed(ctx context.Context, cfg Config, model string, inputs []string) (Embeddings, error) {
+	if model == "" {
+		model = DefaultEmbedModel(cfg.API)
+	}
+	if model == "" {
+		return Embeddings{}, fmt.Errorf("no embedding model configured for %q", cfg.API)
+	}
+	if len(inputs) == 0 {
+		return Embeddings{Model: model}, nil
+	}
+
+	switch cfg.API {
+	case apiAnthropic, apiAzure, apiBedrock:
+		return Embeddings{}, fmt.Errorf("embeddings are not supported by %q", cfg.API)
+	case apiGoogle:
+		return embedGoogle(ctx, cfg, model, inputs)
+	default:
+		return embedOpenAI(ctx, cfg, model, inputs)
+	}
+}
+
+type openAIEmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type openAIEmbedResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+	Usage struct {
+		PromptTokens int64 `json:"prompt_tokens"`
+		TotalTokens  int64 `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+func embedOpenAI(ctx context.Context, cfg Config, model string, inputs []string) (Embeddings, error) {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+
+	header := http.Header{}
+	if cfg.APIKey != "" {
+		header.Set("Authorization", "Bearer "+cfg.APIKey)
+	}
+
+	var resp openAIEmbedResponse
+	if err := postJSON(ctx, cfg.HTTPClient, strings.TrimSuffix(baseURL, "/")+"/embeddings", header, openAIEmbedRequest{
+		Model: model,
+		Input: inputs,
+	}, &resp); err != nil {
+		return Embeddings{}, err
+	}
+
+	vectors := make([][]float64, len(inputs))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return Embeddings{}, fmt.Errorf("unexpected index %d in response", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	if err := checkVectors(vectors); err != nil {
+		return Embeddings{}, err
+	}
+
+	return Embeddings{
+		Model:   model,
+		Vectors: vectors,
+		Usage: proto.Usage{
+			InputTokens: resp.Usage.PromptTokens,
+			TotalTokens: resp.Usage.TotalTokens,
+		},
+	}, nil
+}
+
+type googleEmbedRequest struct {
+	Requests []googleEmbedContent `json:"requests"`
+}
+
+type googleEmbedContent struct {
+	Model   string `json:"model"`
+	Content struct {
+		Parts []struct {
+			Text string `json:"text"`
+		} `json:"parts"`
+	} `json:"content"`
+}
+
+type googleEmbedResponse struct {
+	Embeddings []struct {
+		Values []float64 `json:"values"`
+	} `json:"embeddings"`
+}
+
+func embedGoogle(ctx context.Context, cfg Config, model string, inputs []string) (Embeddings, error) {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultGoogleBaseURL
+	}
+	name := model
+	if !strings.HasPrefix(name, "models/") {
+		name = "models/" + name
+	}
+
+	req := googleEmbedRequest{Requests: make([]googleEmbedContent, len(inputs))}
+	for i, input := range inputs {
+		req.Requests[i].Model = name
+		req.Requests[i].Content.Parts = []struct {
+			Text string `json:"text"`
+		}{{Text: input}}
+	}
+
+	header := http.Header{}
+	header.Set("x-goog-api-key", cfg.APIKey)
+	endpoint := strings.TrimSuffix(baseURL, "/") + "/" + name + ":batchEmbedContents"
+
+	var resp googleEmbedResponse
+	if err := postJSON(ctx, cfg.HTTPClient, endpoint, header, req, &resp); err != nil {
+		return Embeddings{}, err
+	}
+	if len(resp.Embeddings) != len(inputs) {
+		return Embeddings{}, fmt.Errorf("expected %d vectors, got %d", len(inputs), len(resp.Embeddings))
+	}
+
+	vectors := make([][]float64, len(inputs))
+	for i, e := range resp.Embeddings {
+		vectors[i] = e.Values
+	}
+	if err := checkVectors(vectors); err != nil {
+		return Embeddings{}, err
+	}
+	return Embeddings{Model: model, Vectors: vectors}, nil
+}
+
+func checkVectors(vectors [][]float64) error {
+	for i, v := range vectors {
+		if len(v) == 0 {
+			return fmt.Errorf("missing vector for input %d", i)
+		}
+	}
+	return nil
+}
+
+// postJSON sends body as JSON to endpoint and decodes the response into out.
+func postJSON(ctx context.Context, client *http.Client, endpoint string, header http.Header, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header = header.Clone()
+	req.Header.Set("Content-Type", "application/json")
+	return doJSON(client, req, out)
+}
+
+// doJSON sends req and decodes the JSON response into out. Non-2xx responses
+// are returned as *fantasy.ProviderError so they flow through the same retry
+// and error classification as chat requests.
+func doJSON(client *http.Client, req *http.Request, out any) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		headers := make(map[string]string, len(resp.Header))
+		for k := range resp.Header {
+			headers[strings.ToLower(k)] = resp.Header.Get(k)
+		}
+		return &fantasy.ProviderError{
+			Title:           http.StatusText(resp.StatusCode),
+			Message:         strings.TrimSpace(string(respBody)),
+			URL:             req.URL.String(),
+			StatusCode:      resp.StatusCode,
+			ResponseHeaders: headers,
+			ResponseBody:    respBody,
+		}
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
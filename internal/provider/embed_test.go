@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbedOpenAI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/embeddings", r.URL.Path)
+		require.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+
+		var req openAIEmbedRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "text-embedding-3-small", req.Model)
+		require.Equal(t, []string{"a", "b"}, req.Input)
+
+		// Out of order on purpose: results must be placed by index.
+		_, _ = w.Write([]byte(`{
+			"data": [{"index": 1, "embedding": [0.3, 0.4]}, {"index": 0, "embedding": [0.1, 0.2]}],
+			"usage": {"prompt_tokens": 2, "total_tokens": 2}
+		}`))
+	}))
+	defer srv.Close()
+
+	res, err := Embed(context.Background(), Config{API: "openai", APIKey: "test-key", BaseURL: srv.URL + "/v1"}, "", []string{"a", "b"})
+	require.NoError(t, err)
+	require.Equal(t, "text-embedding-3-small", res.Model)
+	require.Equal(t, [][]float64{{0.1, 0.2}, {0.3, 0.4}}, res.Vectors)
+	require.EqualValues(t, 2, res.Usage.InputTokens)
+}
+
+func TestEmbedGoogle(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/models/gemini-embedding-001:batchEmbedContents", r.URL.Path)
+		require.Equal(t, "test-key", r.Header.Get("x-goog-api-key"))
+
+		var req googleEmbedRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Len(t, req.Requests, 1)
+		require.Equal(t, "models/gemini-embedding-001", req.Requests[0].Model)
+		require.Equal(t, "hello", req.Requests[0].Content.Parts[0].Text)
+
+		_, _ = w.Write([]byte(`{"embeddings": [{"values": [1, 2, 3]}]}`))
+	}))
+	defer srv.Close()
+
+	res, err := Embed(context.Background(), Config{API: "google", APIKey: "test-key", BaseURL: srv.URL}, "", []string{"hello"})
+	require.NoError(t, err)
+	require.Equal(t, [][]float64{{1, 2, 3}}, res.Vectors)
+}
+
+func TestEmbedErrors(t *testing.T) {
+	t.Run("provider error", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error": "slow down"}`))
+		}))
+		defer srv.Close()
+
+		_, err := Embed(context.Background(), Config{API: "ollama", BaseURL: srv.URL}, "", []string{"a"})
+		var perr *fantasy.ProviderError
+		require.True(t, errors.As(err, &perr))
+		require.Equal(t, http.StatusTooManyRequests, perr.StatusCode)
+		require.Equal(t, "2", perr.ResponseHeaders["retry-after"])
+		require.True(t, perr.IsRetryable())
+	})
+
+	t.Run("unsupported api", func(t *testing.T) {
+		_, err := Embed(context.Background(), Config{API: "anthropic"}, "some-model", []string{"a"})
+		require.ErrorContains(t, err, "not supported")
+	})
+
+	t.Run("no default model", func(t *testing.T) {
+		_, err := Embed(context.Background(), Config{API: "localai"}, "", []string{"a"})
+		require.ErrorContains(t, err, "no embedding model configured")
+	})
+}
@@ -0,0 +1,127 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"regexp"
+	"sync"
+
+	"github.com/dotcommander/yai/internal/proto"
+)
+
+// requestIDHeaders are the response headers providers put their request ID
+// in, by preference.
+var requestIDHeaders = []string{
+	"x-request-id",     // OpenAI, Azure, and most compatible APIs
+	"request-id",       // Anthropic
+	"x-amzn-requestid", // Bedrock
+	"apim-request-id",  // Azure API Management
+}
+
+// modelVersionRe finds the model that answered in the start of a response
+// body: "model" for OpenAI-style and Anthropic APIs, "modelVersion" for
+// Google.
+var modelVersionRe = regexp.MustCompile(`"(?:model|modelVersion)"\s*:\s*"([^"]+)"`)
+
+// maxModelSniffBytes caps how much of a response body is searched for the
+// model version.
+const maxModelSniffBytes = 64 << 10
+
+// metaRecorder collects the [proto.ResponseMeta] of the requests made with
+// its context.
+type metaRecorder struct {
+	mu   sync.Mutex
+	meta proto.ResponseMeta
+}
+
+func (r *metaRecorder) get() proto.ResponseMeta {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.meta
+}
+
+func (r *metaRecorder) set(update func(*proto.ResponseMeta)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	update(&r.meta)
+}
+
+type metaRecorderKey struct{}
+
+func withMetaRecorder(ctx context.Context, r *metaRecorder) context.Context {
+	return context.WithValue(ctx, metaRecorderKey{}, r)
+}
+
+// metaTransport records the request ID and model version of each response
+// to the recorder in its request's context, if any.
+type metaTransport struct {
+	base http.RoundTripper
+}
+
+// withResponseMeta returns a copy of client whose responses are recorded
+// by [metaTransport]. A nil client stands for [http.DefaultClient].
+func withResponseMeta(client *http.Client) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	wrapped := *client
+	wrapped.Transport = metaTransport{base: base}
+	return &wrapped
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t metaTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	rec, _ := req.Context().Value(metaRecorderKey{}).(*metaRecorder)
+	if err != nil || rec == nil {
+		return resp, err //nolint:wrapcheck
+	}
+	rec.set(func(meta *proto.ResponseMeta) {
+		// A new step starts over; the model is filled in from its body.
+		*meta = proto.ResponseMeta{RequestID: requestID(resp.Header)}
+	})
+	resp.Body = &modelSniffer{ReadCloser: resp.Body, rec: rec}
+	return resp, nil
+}
+
+func requestID(h http.Header) string {
+	for _, name := range requestIDHeaders {
+		if id := h.Get(name); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// modelSniffer passes a response body through, recording the first model
+// name found in its start.
+type modelSniffer struct {
+	io.ReadCloser
+	rec  *metaRecorder
+	buf  []byte
+	done bool
+}
+
+func (s *modelSniffer) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	if !s.done && n > 0 {
+		s.buf = append(s.buf, p[:n]...)
+		if m := modelVersionRe.FindSubmatch(s.buf); m != nil {
+			version := string(m[1])
+			s.rec.set(func(meta *proto.ResponseMeta) { meta.ModelVersion = version })
+			s.done = true
+		}
+		if len(s.buf) >= maxModelSniffBytes {
+			s.done = true
+		}
+		if s.done {
+			s.buf = nil
+		}
+	}
+	return n, err //nolint:wrapcheck
+}
@@ -0,0 +1,54 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseMeta(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("X-Request-Id", "req_abc")
+		for _, delta := range []string{`{"role":"assistant","content":"hi"}`, `{}`} {
+			finish := "null"
+			if delta == `{}` {
+				finish = `"stop"`
+			}
+			fmt.Fprintf(w, `data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o-2024-11-20","choices":[{"index":0,"delta":%s,"finish_reason":%s}]}`+"\n\n", delta, finish)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := New(Config{API: apiOpenAI, BaseURL: srv.URL, APIKey: "test-key", HTTPClient: srv.Client()})
+	require.NoError(t, err)
+	st := client.Request(context.Background(), proto.Request{
+		Model:    "gpt-4o",
+		Messages: []proto.Message{{Role: proto.RoleUser, Content: "hello"}},
+	})
+	t.Cleanup(func() { _ = st.Close() })
+	for st.Next() {
+	}
+	require.NoError(t, st.Err())
+	require.Equal(t, proto.ResponseMeta{RequestID: "req_abc", ModelVersion: "gpt-4o-2024-11-20"}, st.ResponseMeta())
+}
+
+func TestModelSnifferAcrossReads(t *testing.T) {
+	body := `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","model":"claude-sonnet-4-20250514"}}`
+	rec := &metaRecorder{}
+	s := &modelSniffer{ReadCloser: io.NopCloser(iotest.OneByteReader(strings.NewReader(body))), rec: rec}
+	out, err := io.ReadAll(s)
+	require.NoError(t, err)
+	require.Equal(t, body, string(out))
+	require.Equal(t, "claude-sonnet-4-20250514", rec.get().ModelVersion)
+}
@@ -0,0 +1,58 @@
+package provider
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultAnthropicBaseURL = "https://api.anthropic.com/v1"
+	anthropicVersion        = "2023-06-01"
+)
+
+// ErrPingUnsupported is returned by Ping for APIs it cannot check without
+// sending a request that costs tokens.
+var ErrPingUnsupported = errors.New("no way to check this API for free")
+
+// Ping lists the models of the API, which needs a valid key but costs no
+// tokens, to check that the API is reachable and accepts the key. Failed
+// requests are returned as *fantasy.ProviderError.
+func Ping(ctx context.Context, cfg Config) error {
+	if cfg.Plugin != "" {
+		return ErrPingUnsupported
+	}
+	header := http.Header{}
+	var endpoint string
+	switch cfg.API {
+	case apiAzure, apiAzureAD, apiBedrock:
+		return ErrPingUnsupported
+	case apiAnthropic:
+		endpoint = strings.TrimSuffix(cmp.Or(cfg.BaseURL, defaultAnthropicBaseURL), "/")
+		if !strings.HasSuffix(endpoint, "/v1") {
+			endpoint += "/v1"
+		}
+		header.Set("X-Api-Key", cfg.APIKey)
+		header.Set("Anthropic-Version", anthropicVersion)
+	case apiGoogle:
+		endpoint = strings.TrimSuffix(cmp.Or(cfg.BaseURL, defaultGoogleBaseURL), "/")
+		header.Set("X-Goog-Api-Key", cfg.APIKey)
+	default:
+		endpoint = strings.TrimSuffix(cmp.Or(cfg.BaseURL, defaultOpenAIBaseURL), "/")
+		if cfg.APIKey != "" {
+			header.Set("Authorization", "Bearer "+cfg.APIKey)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/models", nil)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header = header
+	var models json.RawMessage
+	return doJSON(cfg.HTTPClient, req, &models)
+}
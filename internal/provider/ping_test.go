@@ -0,0 +1,44 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPing(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		switch r.URL.Path {
+		case "/v1/models":
+			if r.Header.Get("Authorization") != "Bearer test-key" && r.Header.Get("X-Api-Key") != "test-key" {
+				http.Error(w, `{"error": "invalid key"}`, http.StatusUnauthorized)
+				return
+			}
+			if r.Header.Get("X-Api-Key") != "" {
+				require.Equal(t, anthropicVersion, r.Header.Get("Anthropic-Version"))
+			}
+			_, _ = w.Write([]byte(`{"data": []}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	require.NoError(t, Ping(ctx, Config{API: "openai", APIKey: "test-key", BaseURL: srv.URL + "/v1"}))
+	require.NoError(t, Ping(ctx, Config{API: "anthropic", APIKey: "test-key", BaseURL: srv.URL}))
+
+	err := Ping(ctx, Config{API: "groq", APIKey: "wrong", BaseURL: srv.URL + "/v1/"})
+	var providerErr *fantasy.ProviderError
+	require.True(t, errors.As(err, &providerErr))
+	require.Equal(t, http.StatusUnauthorized, providerErr.StatusCode)
+
+	require.ErrorIs(t, Ping(ctx, Config{API: "bedrock"}), ErrPingUnsupported)
+	require.ErrorIs(t, Ping(ctx, Config{API: "openai", Plugin: "my-plugin"}), ErrPingUnsupported)
+}
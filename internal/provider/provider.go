@@ -13,5 +13,57 @@ func newProvider(cfg Config) (fantasy.Provider, error) {
 		factory = newOpenAICompat
 	}
 
-	return factory(cfg.API, cfg.APIKey, cfg.BaseURL, cfg.HTTPClient)
+	return factory(cfg.API, cfg.APIKey, cfg.BaseURL, withResponseMeta(withBodyFieldsClient(cfg.HTTPClient)))
+}
+
+// SupportsTopK reports whether requests to api honor top-k sampling. The
+// providers built on the OpenAI API drop it with a warning.
+func SupportsTopK(api string) bool {
+	switch api {
+	case apiAnthropic, apiGoogle, apiBedrock:
+		return true
+	default:
+		return false
+	}
+}
+
+// SupportsSeed reports whether requests to api can be given a sampling
+// seed. The providers built on the OpenAI API take one; Anthropic, Google,
+// and Bedrock have none.
+func SupportsSeed(api string) bool {
+	switch api {
+	case apiAnthropic, apiGoogle, apiBedrock:
+		return false
+	default:
+		return true
+	}
+}
+
+// SupportsLogprobs reports whether requests to api can ask for the log
+// probabilities of the answer's tokens. Like the seed, they are an OpenAI
+// API setting.
+func SupportsLogprobs(api string) bool {
+	return SupportsSeed(api)
+}
+
+// SupportsPenalties reports whether requests to api honor presence and
+// frequency penalties. The Anthropic providers drop them with a warning.
+func SupportsPenalties(api string) bool {
+	switch api {
+	case apiAnthropic, apiBedrock:
+		return false
+	default:
+		return true
+	}
+}
+
+// SupportsThinking reports whether requests to api can be given a thinking
+// budget.
+func SupportsThinking(api string) bool {
+	switch api {
+	case apiAnthropic, apiGoogle, apiBedrock:
+		return true
+	default:
+		return false
+	}
 }
@@ -1,7 +1,11 @@
 package provider
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+
 	"charm.land/fantasy"
+	fanthropic "charm.land/fantasy/providers/anthropic"
 	fgoogle "charm.land/fantasy/providers/google"
 	fopenai "charm.land/fantasy/providers/openai"
 	fopenaicompat "charm.land/fantasy/providers/openaicompat"
@@ -33,15 +37,78 @@ func applyProviderOptions(call *fantasy.Call, api string, cfg Config, req proto.
 		}
 	}
 
+	if req.CacheSystem {
+		switch api {
+		case apiAnthropic, apiBedrock:
+			markSystemCacheBreakpoint(call.Prompt)
+		case apiOpenAI, apiAzure, apiAzureAD:
+			// OpenAI caches long prompts automatically; a stable key keeps
+			// requests with the same role on the same cache shard.
+			key := systemCacheKey(req.Messages)
+			openAIOpts.PromptCacheKey = &key
+			hasOpenAIOpts = true
+		}
+	}
+
 	if hasOpenAIOpts {
 		call.ProviderOptions[fopenai.Name] = openAIOpts
 	}
 
-	if api == apiGoogle && cfg.ThinkingBudget > 0 {
-		call.ProviderOptions[fgoogle.Name] = &fgoogle.ProviderOptions{
-			ThinkingConfig: &fgoogle.ThinkingConfig{
-				ThinkingBudget: fantasy.Opt(int64(cfg.ThinkingBudget)),
-			},
+	if cfg.ThinkingBudget > 0 {
+		budget := int64(cfg.ThinkingBudget)
+		switch api {
+		case apiGoogle:
+			call.ProviderOptions[fgoogle.Name] = &fgoogle.ProviderOptions{
+				ThinkingConfig: &fgoogle.ThinkingConfig{
+					ThinkingBudget: fantasy.Opt(budget),
+				},
+			}
+		case apiAnthropic, apiBedrock:
+			call.ProviderOptions[fanthropic.Name] = &fanthropic.ProviderOptions{
+				Thinking: &fanthropic.ThinkingProviderOption{BudgetTokens: budget},
+			}
+			// Extended thinking takes no sampling settings, and the budget
+			// is part of max_tokens, which must leave room for the answer.
+			call.Temperature, call.TopP, call.TopK = nil, nil, nil
+			if call.MaxOutputTokens == nil {
+				maxTokens := budget + thinkingAnswerTokens
+				call.MaxOutputTokens = &maxTokens
+			}
+		}
+	}
+}
+
+// thinkingAnswerTokens is the room left for the answer after the thinking
+// budget when no max-tokens is set.
+const thinkingAnswerTokens = 4096
+
+// markSystemCacheBreakpoint sets an Anthropic cache breakpoint on the last
+// leading system message, so the whole system prefix is cached.
+func markSystemCacheBreakpoint(prompt fantasy.Prompt) {
+	last := -1
+	for i, msg := range prompt {
+		if msg.Role != fantasy.MessageRoleSystem {
+			break
+		}
+		last = i
+	}
+	if last < 0 {
+		return
+	}
+	prompt[last].ProviderOptions = fanthropic.NewProviderCacheControlOptions(&fanthropic.ProviderCacheControlOptions{
+		CacheControl: fanthropic.CacheControl{Type: "ephemeral"},
+	})
+}
+
+// systemCacheKey derives a prompt cache key from the leading system messages.
+func systemCacheKey(messages []proto.Message) string {
+	h := sha256.New()
+	for _, msg := range messages {
+		if msg.Role != proto.RoleSystem {
+			break
 		}
+		h.Write([]byte(msg.Content))
+		h.Write([]byte{0})
 	}
+	return "yai-" + hex.EncodeToString(h.Sum(nil))[:16]
 }
@@ -0,0 +1,198 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// defaultTranscribeModels maps API names to the transcription model used
+// when none is configured.
+var defaultTranscribeModels = map[string]string{
+	apiOpenAI: "whisper-1",
+	apiGoogle: "gemini-2.5-flash",
+}
+
+const transcribeInstruction = "Transcribe this audio verbatim. Reply with the transcript only."
+
+// Audio is an audio clip to transcribe.
+type Audio struct {
+	Data []byte
+	// Filename is used to infer the format; it may be empty for piped audio.
+	Filename string
+}
+
+// MIMEType returns the audio media type, inferred from the file extension or,
+// failing that, the content.
+func (a Audio) MIMEType() string {
+	if ext := strings.ToLower(filepath.Ext(a.Filename)); ext != "" {
+		switch ext {
+		case ".mp3":
+			return "audio/mpeg"
+		case ".m4a":
+			return "audio/mp4"
+		case ".wav":
+			return "audio/wav"
+		}
+		if t := mime.TypeByExtension(ext); strings.HasPrefix(t, "audio/") {
+			return t
+		}
+	}
+	switch t := http.DetectContentType(a.Data); t {
+	case "audio/wave":
+		return "audio/wav"
+	case "application/ogg":
+		return "audio/ogg"
+	default:
+		return t
+	}
+}
+
+// DefaultTranscribeModel returns the default transcription model for api,
+// or an empty string when there is none.
+func DefaultTranscribeModel(api string) string {
+	return defaultTranscribeModels[api]
+}
+
+// Transcribe converts audio to text with model. Google sends the audio inline
+// to Gemini with a transcription instruction; every other API is assumed to
+// expose an OpenAI-compatible /audio/transcriptions endpoint.
+func Transcribe(ctx context.Context, cfg Config, model string, audio Audio) (string, error) {
+	if model == "" {
+		model = DefaultTranscribeModel(cfg.API)
+	}
+	if model == "" {
+		return "", fmt.Errorf("no transcription model configured for %q", cfg.API)
+	}
+	if len(audio.Data) == 0 {
+		return "", fmt.Errorf("audio is empty")
+	}
+
+	switch cfg.API {
+	case apiAnthropic, apiAzure, apiBedrock:
+		return "", fmt.Errorf("transcription is not supported by %q", cfg.API)
+	case apiGoogle:
+		return transcribeGoogle(ctx, cfg, model, audio)
+	default:
+		return transcribeOpenAI(ctx, cfg, model, audio)
+	}
+}
+
+func transcribeOpenAI(ctx context.Context, cfg Config, model string, audio Audio) (string, error) {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+
+	filename := filepath.Base(audio.Filename)
+	if audio.Filename == "" {
+		// The endpoint infers the format from the file name.
+		filename = "audio"
+		if exts, _ := mime.ExtensionsByType(audio.MIMEType()); len(exts) > 0 {
+			filename += exts[0]
+		}
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if err := mw.WriteField("model", model); err != nil {
+		return "", fmt.Errorf("encode request: %w", err)
+	}
+	fw, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		return "", fmt.Errorf("encode request: %w", err)
+	}
+	if _, err := fw.Write(audio.Data); err != nil {
+		return "", fmt.Errorf("encode request: %w", err)
+	}
+	if err := mw.Close(); err != nil {
+		return "", fmt.Errorf("encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(baseURL, "/")+"/audio/transcriptions", &body)
+	if err != nil {
+		return "", fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.APIKey)
+	}
+
+	var resp struct {
+		Text string `json:"text"`
+	}
+	if err := doJSON(cfg.HTTPClient, req, &resp); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(resp.Text), nil
+}
+
+type googleInlineData struct {
+	MIMEType string `json:"mime_type"`
+	Data     string `json:"data"`
+}
+
+type googlePart struct {
+	Text       string            `json:"text,omitempty"`
+	InlineData *googleInlineData `json:"inline_data,omitempty"`
+}
+
+type googleGenerateRequest struct {
+	Contents []struct {
+		Parts []googlePart `json:"parts"`
+	} `json:"contents"`
+}
+
+type googleGenerateResponse struct {
+	Candidates []struct {
+		Content struct {
+			Parts []googlePart `json:"parts"`
+		} `json:"content"`
+	} `json:"candidates"`
+}
+
+func transcribeGoogle(ctx context.Context, cfg Config, model string, audio Audio) (string, error) {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultGoogleBaseURL
+	}
+	name := model
+	if !strings.HasPrefix(name, "models/") {
+		name = "models/" + name
+	}
+
+	var req googleGenerateRequest
+	req.Contents = make([]struct {
+		Parts []googlePart `json:"parts"`
+	}, 1)
+	req.Contents[0].Parts = []googlePart{
+		{Text: transcribeInstruction},
+		{InlineData: &googleInlineData{
+			MIMEType: audio.MIMEType(),
+			Data:     base64.StdEncoding.EncodeToString(audio.Data),
+		}},
+	}
+
+	header := http.Header{}
+	header.Set("x-goog-api-key", cfg.APIKey)
+	endpoint := strings.TrimSuffix(baseURL, "/") + "/" + name + ":generateContent"
+
+	var resp googleGenerateResponse
+	if err := postJSON(ctx, cfg.HTTPClient, endpoint, header, req, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Candidates) == 0 {
+		return "", fmt.Errorf("no transcript in response")
+	}
+	var sb strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		sb.WriteString(part.Text)
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
@@ -0,0 +1,96 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscribeOpenAI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/audio/transcriptions", r.URL.Path)
+		require.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		require.Equal(t, "whisper-1", r.FormValue("model"))
+
+		f, hdr, err := r.FormFile("file")
+		require.NoError(t, err)
+		require.Equal(t, "clip.mp3", hdr.Filename)
+		data, err := io.ReadAll(f)
+		require.NoError(t, err)
+		require.Equal(t, "fake audio", string(data))
+
+		_, _ = w.Write([]byte(`{"text": " hello world \n"}`))
+	}))
+	defer srv.Close()
+
+	text, err := Transcribe(context.Background(), Config{API: "openai", APIKey: "test-key", BaseURL: srv.URL + "/v1"}, "", Audio{
+		Data:     []byte("fake audio"),
+		Filename: "/tmp/clip.mp3",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "hello world", text)
+}
+
+func TestTranscribeGoogle(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/models/gemini-2.5-flash:generateContent", r.URL.Path)
+		require.Equal(t, "test-key", r.Header.Get("x-goog-api-key"))
+
+		var req googleGenerateRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Len(t, req.Contents, 1)
+		parts := req.Contents[0].Parts
+		require.Len(t, parts, 2)
+		require.Equal(t, transcribeInstruction, parts[0].Text)
+		require.Equal(t, "audio/wav", parts[1].InlineData.MIMEType)
+		require.Equal(t, base64.StdEncoding.EncodeToString([]byte("fake audio")), parts[1].InlineData.Data)
+
+		_, _ = w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "hello "}, {"text": "world"}]}}]}`))
+	}))
+	defer srv.Close()
+
+	text, err := Transcribe(context.Background(), Config{API: "google", APIKey: "test-key", BaseURL: srv.URL}, "", Audio{
+		Data:     []byte("fake audio"),
+		Filename: "clip.wav",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "hello world", text)
+}
+
+func TestTranscribeErrors(t *testing.T) {
+	t.Run("unsupported api", func(t *testing.T) {
+		_, err := Transcribe(context.Background(), Config{API: "anthropic"}, "some-model", Audio{Data: []byte("x")})
+		require.ErrorContains(t, err, "not supported")
+	})
+
+	t.Run("no default model", func(t *testing.T) {
+		_, err := Transcribe(context.Background(), Config{API: "ollama"}, "", Audio{Data: []byte("x")})
+		require.ErrorContains(t, err, "no transcription model")
+	})
+
+	t.Run("empty audio", func(t *testing.T) {
+		_, err := Transcribe(context.Background(), Config{API: "openai"}, "", Audio{})
+		require.ErrorContains(t, err, "empty")
+	})
+}
+
+func TestAudioMIMEType(t *testing.T) {
+	t.Run("extension", func(t *testing.T) {
+		require.Equal(t, "audio/mpeg", Audio{Filename: "a.MP3"}.MIMEType())
+		require.Equal(t, "audio/wav", Audio{Filename: "a.wav"}.MIMEType())
+		require.Equal(t, "audio/mp4", Audio{Filename: "a.m4a"}.MIMEType())
+	})
+
+	t.Run("sniffed", func(t *testing.T) {
+		wav := append([]byte("RIFF\x00\x00\x00\x00WAVEfmt "), make([]byte, 32)...)
+		require.Equal(t, "audio/wav", Audio{Data: wav}.MIMEType())
+		require.Equal(t, "audio/ogg", Audio{Data: append([]byte("OggS\x00"), make([]byte, 32)...)}.MIMEType())
+	})
+}
@@ -0,0 +1,71 @@
+package rag
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// defaultChunkChars is the target chunk size in characters.
+	defaultChunkChars = 1500
+	// defaultChunkOverlapLines is the number of trailing lines repeated at the
+	// start of the next chunk so answers spanning a boundary stay retrievable.
+	defaultChunkOverlapLines = 2
+)
+
+// Chunk is a contiguous range of lines from a document.
+type Chunk struct {
+	Source    string `json:"source"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Text      string `json:"text"`
+}
+
+// Label returns a human-readable reference to the chunk, e.g. "docs/a.md:10-42".
+func (c Chunk) Label() string {
+	if c.StartLine == 0 {
+		return c.Source
+	}
+	return fmt.Sprintf("%s:%d-%d", c.Source, c.StartLine, c.EndLine)
+}
+
+// ChunkText splits text into chunks of roughly maxChars characters on line
+// boundaries. Lines longer than maxChars become their own chunk. Blank-only
+// chunks are dropped.
+func ChunkText(source, text string, maxChars, overlapLines int) []Chunk {
+	if maxChars <= 0 {
+		maxChars = defaultChunkChars
+	}
+	lines := strings.Split(text, "\n")
+
+	var chunks []Chunk
+	start := 0
+	for start < len(lines) {
+		end := start
+		size := 0
+		for end < len(lines) && (end == start || size+len(lines[end])+1 <= maxChars) {
+			size += len(lines[end]) + 1
+			end++
+		}
+
+		body := strings.Join(lines[start:end], "\n")
+		if strings.TrimSpace(body) != "" {
+			chunks = append(chunks, Chunk{
+				Source:    source,
+				StartLine: start + 1,
+				EndLine:   end,
+				Text:      body,
+			})
+		}
+		if end >= len(lines) {
+			break
+		}
+
+		next := end - overlapLines
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+	return chunks
+}
@@ -0,0 +1,7 @@
+// Package rag implements local retrieval-augmented generation.
+//
+// Documents (files and saved conversations) are split into chunks, embedded,
+// and kept in a JSON vector index under the cache path. Each prompt is
+// embedded and the most similar chunks are prepended as numbered sources the
+// model is asked to cite.
+package rag
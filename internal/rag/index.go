@@ -0,0 +1,237 @@
+package rag
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha1" //nolint:gosec // G505: used for stable file names, not security
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// embedBatchSize caps the number of chunks sent per embeddings request.
+const embedBatchSize = 64
+
+// Document is a unit of knowledge to index.
+type Document struct {
+	// Source names the document in citations and keys it in the index.
+	Source string
+	// Version changes whenever Text changes (e.g. modification time and
+	// size); unchanged documents are not re-embedded.
+	Version string
+	Text    string
+}
+
+// EmbedFunc computes one vector per input, in input order.
+type EmbedFunc func(ctx context.Context, inputs []string) ([][]float64, error)
+
+// Result is a chunk returned by Search with its cosine similarity to the
+// query.
+type Result struct {
+	Chunk
+	Score float64
+}
+
+// SyncStats summarizes the changes made by Sync.
+type SyncStats struct {
+	Added     int
+	Updated   int
+	Removed   int
+	Unchanged int
+	Chunks    int
+}
+
+type entry struct {
+	Chunk
+	Vector []float64 `json:"vector"`
+}
+
+type docEntry struct {
+	Version string  `json:"version"`
+	Chunks  []entry `json:"chunks"`
+}
+
+// Index is a JSON-persisted vector index over a set of documents.
+type Index struct {
+	path  string
+	Model string              `json:"model"`
+	Docs  map[string]docEntry `json:"docs"`
+}
+
+// IndexPath returns the index file for a knowledge set under cacheDir. The
+// key parts (embedding API/model and knowledge sources) select the file, so
+// different sets never overwrite each other.
+func IndexPath(cacheDir string, key ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(key, "\x00"))) //nolint:gosec
+	return filepath.Join(cacheDir, "rag", hex.EncodeToString(sum[:])[:16]+".json")
+}
+
+// Open loads the index at path. A missing file, or an index built with a
+// different embedding model, yields an empty index.
+func Open(path, model string) (*Index, error) {
+	ix := &Index{path: path, Model: model, Docs: map[string]docEntry{}}
+	data, err := os.ReadFile(path) //nolint:gosec // G304: path is derived from the cache directory
+	if errors.Is(err, os.ErrNotExist) {
+		return ix, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read index: %w", err)
+	}
+
+	var stored Index
+	if err := json.Unmarshal(data, &stored); err != nil || stored.Model != model {
+		// Corrupt or built with another model: rebuild from scratch.
+		return ix, nil //nolint:nilerr
+	}
+	if stored.Docs != nil {
+		ix.Docs = stored.Docs
+	}
+	return ix, nil
+}
+
+// Sync brings the index in line with docs: new and changed documents are
+// chunked and embedded, and documents no longer present are dropped.
+func (ix *Index) Sync(ctx context.Context, docs []Document, embed EmbedFunc) (SyncStats, error) {
+	var stats SyncStats
+	current := make(map[string]struct{}, len(docs))
+	type pending struct {
+		source  string
+		version string
+		chunks  []Chunk
+	}
+	var todo []pending
+	var texts []string
+
+	for _, doc := range docs {
+		current[doc.Source] = struct{}{}
+		existing, ok := ix.Docs[doc.Source]
+		if ok && existing.Version == doc.Version {
+			stats.Unchanged++
+			continue
+		}
+		if ok {
+			stats.Updated++
+		} else {
+			stats.Added++
+		}
+		chunks := ChunkText(doc.Source, doc.Text, defaultChunkChars, defaultChunkOverlapLines)
+		todo = append(todo, pending{source: doc.Source, version: doc.Version, chunks: chunks})
+		for _, c := range chunks {
+			texts = append(texts, c.Text)
+		}
+	}
+	for source := range ix.Docs {
+		if _, ok := current[source]; !ok {
+			delete(ix.Docs, source)
+			stats.Removed++
+		}
+	}
+
+	vectors := make([][]float64, 0, len(texts))
+	for start := 0; start < len(texts); start += embedBatchSize {
+		batch := texts[start:min(start+embedBatchSize, len(texts))]
+		vecs, err := embed(ctx, batch)
+		if err != nil {
+			return stats, err
+		}
+		if len(vecs) != len(batch) {
+			return stats, fmt.Errorf("embed: expected %d vectors, got %d", len(batch), len(vecs))
+		}
+		vectors = append(vectors, vecs...)
+	}
+
+	i := 0
+	for _, p := range todo {
+		entries := make([]entry, len(p.chunks))
+		for j, c := range p.chunks {
+			entries[j] = entry{Chunk: c, Vector: vectors[i]}
+			i++
+		}
+		ix.Docs[p.source] = docEntry{Version: p.version, Chunks: entries}
+	}
+	for _, d := range ix.Docs {
+		stats.Chunks += len(d.Chunks)
+	}
+	return stats, nil
+}
+
+// Search returns the k chunks most similar to query, best first.
+func (ix *Index) Search(query []float64, k int) []Result {
+	var results []Result
+	for _, d := range ix.Docs {
+		for _, e := range d.Chunks {
+			results = append(results, Result{Chunk: e.Chunk, Score: cosine(query, e.Vector)})
+		}
+	}
+	slices.SortFunc(results, func(a, b Result) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Label(), b.Label())
+	})
+	if k > 0 && len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
+
+// Save writes the index atomically.
+func (ix *Index) Save() error {
+	if err := os.MkdirAll(filepath.Dir(ix.path), 0o700); err != nil {
+		return fmt.Errorf("create index directory: %w", err)
+	}
+	data, err := json.Marshal(ix)
+	if err != nil {
+		return fmt.Errorf("encode index: %w", err)
+	}
+	tmp := ix.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write index: %w", err)
+	}
+	if err := os.Rename(tmp, ix.path); err != nil {
+		return fmt.Errorf("write index: %w", err)
+	}
+	return nil
+}
+
+func cosine(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// maxQueryChars bounds the part of the prompt embedded as the search query;
+// long piped inputs would otherwise exceed embedding model limits.
+const maxQueryChars = 8000
+
+// Retrieve embeds prompt and returns the k most similar chunks in ix.
+func Retrieve(ctx context.Context, ix *Index, prompt string, k int, embed EmbedFunc) ([]Result, error) {
+	query := prompt
+	if len(query) > maxQueryChars {
+		query = strings.ToValidUTF8(query[:maxQueryChars], "")
+	}
+	vecs, err := embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	if len(vecs) != 1 {
+		return nil, fmt.Errorf("embed: expected 1 vector, got %d", len(vecs))
+	}
+	return ix.Search(vecs[0], k), nil
+}
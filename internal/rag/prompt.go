@@ -0,0 +1,36 @@
+package rag
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BuildPrompt prepends the retrieved chunks to prompt as numbered sources and
+// asks the model to cite them. With no results, prompt is returned unchanged.
+func BuildPrompt(prompt string, results []Result) string {
+	if len(results) == 0 {
+		return prompt
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Answer using the sources below when they are relevant. ")
+	sb.WriteString("Cite the sources you use by number, like [1]. ")
+	sb.WriteString("If the sources do not contain the answer, say so.\n\n")
+	sb.WriteString("Sources:\n\n")
+	for i, r := range results {
+		fmt.Fprintf(&sb, "[%d] %s\n%s\n\n", i+1, r.Label(), strings.TrimSpace(r.Text))
+	}
+	sb.WriteString("Question:\n")
+	sb.WriteString(prompt)
+	return sb.String()
+}
+
+// FormatSources returns one "[n] source:lines" line per result, matching the
+// numbering used by BuildPrompt.
+func FormatSources(results []Result) string {
+	var sb strings.Builder
+	for i, r := range results {
+		fmt.Fprintf(&sb, "[%d] %s\n", i+1, r.Label())
+	}
+	return sb.String()
+}
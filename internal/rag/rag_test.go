@@ -0,0 +1,117 @@
+package rag
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// keywordEmbed embeds text as a vector of keyword counts so similarity is
+// predictable in tests.
+func keywordEmbed(calls *int) EmbedFunc {
+	keywords := []string{"apple", "banana", "cherry"}
+	return func(_ context.Context, inputs []string) ([][]float64, error) {
+		*calls += len(inputs)
+		out := make([][]float64, len(inputs))
+		for i, in := range inputs {
+			vec := make([]float64, len(keywords))
+			for j, k := range keywords {
+				vec[j] = float64(strings.Count(in, k))
+			}
+			out[i] = vec
+		}
+		return out, nil
+	}
+}
+
+func TestChunkText(t *testing.T) {
+	t.Run("splits on line boundaries with overlap", func(t *testing.T) {
+		text := "aaaa\nbbbb\ncccc\ndddd"
+		chunks := ChunkText("f.txt", text, 10, 1)
+		require.Equal(t, []Chunk{
+			{Source: "f.txt", StartLine: 1, EndLine: 2, Text: "aaaa\nbbbb"},
+			{Source: "f.txt", StartLine: 2, EndLine: 3, Text: "bbbb\ncccc"},
+			{Source: "f.txt", StartLine: 3, EndLine: 4, Text: "cccc\ndddd"},
+		}, chunks)
+		require.Equal(t, "f.txt:2-3", chunks[1].Label())
+	})
+
+	t.Run("keeps overlong lines whole", func(t *testing.T) {
+		chunks := ChunkText("f.txt", strings.Repeat("x", 50), 10, 0)
+		require.Len(t, chunks, 1)
+	})
+
+	t.Run("drops blank chunks", func(t *testing.T) {
+		require.Empty(t, ChunkText("f.txt", "\n\n\n", 10, 0))
+	})
+}
+
+func TestIndex(t *testing.T) {
+	path := IndexPath(t.TempDir(), "openai", "model")
+	var calls int
+	embed := keywordEmbed(&calls)
+
+	docs := []Document{
+		{Source: "fruit/apple.md", Version: "1", Text: "apple apple pie"},
+		{Source: "fruit/banana.md", Version: "1", Text: "banana bread"},
+	}
+
+	ix, err := Open(path, "model")
+	require.NoError(t, err)
+	stats, err := ix.Sync(context.Background(), docs, embed)
+	require.NoError(t, err)
+	require.Equal(t, SyncStats{Added: 2, Chunks: 2}, stats)
+	require.NoError(t, ix.Save())
+
+	results := ix.Search([]float64{0, 1, 0}, 1)
+	require.Len(t, results, 1)
+	require.Equal(t, "fruit/banana.md", results[0].Source)
+
+	t.Run("reuses unchanged documents", func(t *testing.T) {
+		calls = 0
+		ix, err := Open(path, "model")
+		require.NoError(t, err)
+		docs := []Document{docs[0], {Source: "fruit/cherry.md", Version: "1", Text: "cherry"}}
+		stats, err := ix.Sync(context.Background(), docs, embed)
+		require.NoError(t, err)
+		require.Equal(t, SyncStats{Added: 1, Removed: 1, Unchanged: 1, Chunks: 2}, stats)
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("rebuilds for another model", func(t *testing.T) {
+		ix, err := Open(path, "other-model")
+		require.NoError(t, err)
+		require.Empty(t, ix.Docs)
+	})
+}
+
+func TestCollectFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.md"), []byte("hello"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bin"), []byte{0, 1, 2}, 0o600))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".git", "config"), []byte("x"), 0o600))
+
+	docs, err := CollectFiles([]string{dir})
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	require.Equal(t, filepath.ToSlash(filepath.Join(dir, "a.md")), docs[0].Source)
+	require.Equal(t, "hello", docs[0].Text)
+
+	_, err = CollectFiles([]string{filepath.Join(dir, "missing")})
+	require.Error(t, err)
+}
+
+func TestBuildPrompt(t *testing.T) {
+	require.Equal(t, "q", BuildPrompt("q", nil))
+
+	results := []Result{{Chunk: Chunk{Source: "a.md", StartLine: 1, EndLine: 3, Text: "alpha\n"}}}
+	prompt := BuildPrompt("what is alpha?", results)
+	require.Contains(t, prompt, "[1] a.md:1-3\nalpha\n")
+	require.True(t, strings.HasSuffix(prompt, "Question:\nwhat is alpha?"))
+	require.Equal(t, "[1] a.md:1-3\n", FormatSources(results))
+}
@@ -0,0 +1,78 @@
+package rag
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxFileBytes skips files too large to be useful as knowledge.
+const maxFileBytes = 1 << 20
+
+// CollectFiles walks paths and returns a document for every text file found.
+// Hidden files and directories below the given paths are skipped, as are
+// binary files and files larger than 1 MiB.
+func CollectFiles(paths []string) ([]Document, error) {
+	var docs []Document
+	seen := map[string]struct{}{}
+	for _, root := range paths {
+		if _, err := os.Stat(root); err != nil {
+			return nil, fmt.Errorf("knowledge path: %w", err)
+		}
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() || !d.Type().IsRegular() {
+				return nil
+			}
+			if _, ok := seen[path]; ok {
+				return nil
+			}
+			seen[path] = struct{}{}
+
+			doc, ok, err := readTextFile(path, d)
+			if err != nil || !ok {
+				return err
+			}
+			docs = append(docs, doc)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("walk %s: %w", root, err)
+		}
+	}
+	return docs, nil
+}
+
+func readTextFile(path string, d fs.DirEntry) (Document, bool, error) {
+	info, err := d.Info()
+	if err != nil {
+		return Document{}, false, fmt.Errorf("stat: %w", err)
+	}
+	if info.Size() == 0 || info.Size() > maxFileBytes {
+		return Document{}, false, nil
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // G304: user-selected knowledge path
+	if err != nil {
+		return Document{}, false, fmt.Errorf("read: %w", err)
+	}
+	if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		return Document{}, false, nil
+	}
+	return Document{
+		Source:  filepath.ToSlash(path),
+		Version: fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size()),
+		Text:    string(data),
+	}, true, nil
+}
@@ -0,0 +1,2 @@
+// Package redact scrubs secrets from text by regular expression.
+package redact
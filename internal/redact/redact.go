@@ -0,0 +1,105 @@
+package redact
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Placeholder replaces each redacted match.
+const Placeholder = "[REDACTED]"
+
+// Builtin are the patterns of the built-in rules, by name.
+var Builtin = map[string]string{
+	"aws-access-key": `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	"email":          `\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b`,
+	"github-token":   `\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`,
+	"private-key":    `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`,
+}
+
+// BuiltinNames returns the names of the built-in rules, sorted.
+func BuiltinNames() []string {
+	return slices.Sorted(maps.Keys(Builtin))
+}
+
+type rule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// Redactor replaces the matches of its rules with [Placeholder].
+type Redactor struct {
+	rules []rule
+}
+
+// New returns a Redactor for the built-in rules named in builtin and for
+// patterns, which are Go regular expressions. A pattern's rule is named
+// after the pattern.
+func New(builtin, patterns []string) (*Redactor, error) {
+	r := &Redactor{rules: make([]rule, 0, len(builtin)+len(patterns))}
+	for _, name := range builtin {
+		p, ok := Builtin[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown redact rule %q (want one of %s)", name, strings.Join(BuiltinNames(), ", "))
+		}
+		r.rules = append(r.rules, rule{name: name, re: regexp.MustCompile(p)})
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("redact pattern %q: %w", p, err)
+		}
+		r.rules = append(r.rules, rule{name: p, re: re})
+	}
+	return r, nil
+}
+
+// Redact returns s with every match replaced, and what was.
+func (r *Redactor) Redact(s string) (string, Matches) {
+	var found Matches
+	for _, rl := range r.rules {
+		s = rl.re.ReplaceAllStringFunc(s, func(string) string {
+			if found == nil {
+				found = Matches{}
+			}
+			found[rl.name]++
+			return Placeholder
+		})
+	}
+	return s, found
+}
+
+// Matches counts redacted matches by rule name.
+type Matches map[string]int
+
+// Add returns m with the counts of other added.
+func (m Matches) Add(other Matches) Matches {
+	if m == nil && len(other) > 0 {
+		m = Matches{}
+	}
+	for name, n := range other {
+		m[name] += n
+	}
+	return m
+}
+
+// Total is the number of matches.
+func (m Matches) Total() int {
+	total := 0
+	for _, n := range m {
+		total += n
+	}
+	return total
+}
+
+// String lists the counts by rule, such as "1 aws-access-key, 2 email".
+func (m Matches) String() string {
+	parts := make([]string, 0, len(m))
+	for _, name := range slices.Sorted(maps.Keys(m)) {
+		parts = append(parts, strconv.Itoa(m[name])+" "+name)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,97 @@
+package requestbuilder
+
+import (
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+)
+
+// answerShare is the share of the context window kept free for the answer
+// when neither max-completion-tokens nor max-tokens says how long it is.
+const answerShare = 8
+
+// ContextBudget is how many tokens of input fit in the context window of
+// mod, after the room for the answer: max-completion-tokens, max-tokens, or
+// an eighth of the window. It is 0 when the model has no context-window or
+// limits are off.
+func ContextBudget(cfg *config.Config, mod config.Model) int64 {
+	if cfg.NoLimit || mod.ContextWindow <= 0 {
+		return 0
+	}
+	answer := cfg.MaxCompletionTokens
+	if answer <= 0 {
+		answer = cfg.MaxTokens
+	}
+	if answer <= 0 {
+		answer = mod.ContextWindow / answerShare
+	}
+	return max(mod.ContextWindow-answer, 1)
+}
+
+// HistoryBudget is how many tokens of a conversation are sent with the next
+// prompt: three quarters of the input budget, leaving the rest for the
+// prompt and system messages. Without a context-window, the budget is
+// estimated from the model's max-input-chars. It is 0 when there is no
+// limit.
+func HistoryBudget(cfg *config.Config, mod config.Model) int64 {
+	if budget := ContextBudget(cfg, mod); budget > 0 {
+		return budget * 3 / 4
+	}
+	if mod.MaxChars > 0 {
+		return mod.MaxChars * 3 / 4 / proto.CharsPerToken
+	}
+	return 0
+}
+
+// WindowHistory keeps the latest turns of history that fit in budget
+// tokens. History is cut where a user message starts a turn, so tool calls
+// are never sent without their results. The last turn is kept even when it
+// alone is over the budget.
+func WindowHistory(history []proto.Message, budget int64) []proto.Message {
+	if budget <= 0 || len(history) == 0 {
+		return history
+	}
+	start := -1
+	var total int64
+	for i := len(history) - 1; i >= 0; i-- {
+		total += proto.EstimateTokens(history[i : i+1])
+		if total > budget && start >= 0 {
+			break
+		}
+		if history[i].Role == proto.RoleUser || i == 0 {
+			start = i
+			if total > budget {
+				break
+			}
+		}
+	}
+	return history[start:]
+}
+
+// fitPrompt trims prompt to the tokens of the context window that messages
+// leave, keeping its beginning and end. The conversation in messages counts
+// for no more than HistoryBudget, which it is cut down to before it is
+// sent. The prompt is left whole when the model has no context-window, or
+// when messages leave no room at all, for the provider to report.
+func fitPrompt(cfg *config.Config, mod config.Model, messages []proto.Message, prompt string) string {
+	budget := ContextBudget(cfg, mod)
+	if budget <= 0 {
+		return prompt
+	}
+	system, history := SplitSystem(messages)
+	used := proto.EstimateTokens(system) + min(proto.EstimateTokens(history), HistoryBudget(cfg, mod))
+	room := (budget - used) * proto.CharsPerToken
+	if room <= 0 || int64(len(prompt)) <= room {
+		return prompt
+	}
+	return Truncate(prompt, int(room), HeadRatio(cfg))
+}
+
+// SplitSystem splits messages into the system messages they start with and
+// the conversation after them.
+func SplitSystem(messages []proto.Message) ([]proto.Message, []proto.Message) {
+	n := 0
+	for n < len(messages) && messages[n].Role == proto.RoleSystem {
+		n++
+	}
+	return messages[:n], messages[n:]
+}
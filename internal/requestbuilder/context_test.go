@@ -0,0 +1,97 @@
+package requestbuilder
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextBudget(t *testing.T) {
+	cfg := &config.Config{}
+	require.Zero(t, ContextBudget(cfg, config.Model{}))
+
+	mod := config.Model{ContextWindow: 8000}
+	require.Equal(t, int64(7000), ContextBudget(cfg, mod))
+
+	cfg.MaxTokens = 500
+	require.Equal(t, int64(7500), ContextBudget(cfg, mod))
+
+	cfg.MaxCompletionTokens = 2000
+	require.Equal(t, int64(6000), ContextBudget(cfg, mod))
+
+	cfg.NoLimit = true
+	require.Zero(t, ContextBudget(cfg, mod))
+}
+
+func TestWindowHistory(t *testing.T) {
+	// Each message is 10 tokens.
+	msg := func(role string) proto.Message {
+		return proto.Message{Role: role, Content: strings.Repeat("x", 40)}
+	}
+	history := []proto.Message{
+		msg(proto.RoleUser),
+		msg(proto.RoleAssistant),
+		msg(proto.RoleUser),
+		msg(proto.RoleAssistant),
+		msg(proto.RoleTool),
+		msg(proto.RoleAssistant),
+	}
+
+	t.Run("no budget", func(t *testing.T) {
+		require.Equal(t, history, WindowHistory(history, 0))
+	})
+
+	t.Run("everything fits", func(t *testing.T) {
+		require.Equal(t, history, WindowHistory(history, 60))
+	})
+
+	t.Run("whole turns", func(t *testing.T) {
+		// The first turn would fit in part; the tool result never goes
+		// without its call.
+		require.Equal(t, history[2:], WindowHistory(history, 50))
+	})
+
+	t.Run("last turn over budget", func(t *testing.T) {
+		require.Equal(t, history[2:], WindowHistory(history, 15))
+	})
+}
+
+func TestBuildRequestFromHistoryFitsContextWindow(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.MaxCompletionTokens = 100
+	mod := config.Model{Name: "gpt-4.1", ContextWindow: 400}
+
+	history := []proto.Message{
+		{Role: proto.RoleUser, Content: strings.Repeat("a", 800)},
+		{Role: proto.RoleAssistant, Content: strings.Repeat("b", 400)},
+		{Role: proto.RoleUser, Content: "second"},
+		{Role: proto.RoleAssistant, Content: "answer"},
+	}
+	prompt := strings.Repeat("c", 2000)
+
+	req, err := BuildRequestFromHistory(cfg, mod, history, prompt)
+	require.NoError(t, err)
+
+	// History is left for the caller to cut down to three quarters of the
+	// 300 tokens left for input; the prompt takes the quarter after it.
+	require.Len(t, req.Messages, 5)
+	last := req.Messages[4].Content
+	require.LessOrEqual(t, len(last), 75*proto.CharsPerToken)
+	require.True(t, strings.HasPrefix(last, "ccc"))
+	require.True(t, strings.HasSuffix(last, "ccc"))
+	require.Contains(t, last, "bytes omitted")
+}
+
+func TestSplitSystem(t *testing.T) {
+	messages := []proto.Message{
+		{Role: proto.RoleSystem, Content: "role"},
+		{Role: proto.RoleUser, Content: "hi"},
+		{Role: proto.RoleSystem, Content: "late"},
+	}
+	system, rest := SplitSystem(messages)
+	require.Equal(t, messages[:1], system)
+	require.Equal(t, messages[1:], rest)
+}
@@ -2,6 +2,7 @@ package requestbuilder
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -12,6 +13,10 @@ import (
 	"github.com/dotcommander/yai/internal/errs"
 )
 
+// ErrMissingKey is wrapped by the error returned when an API requires a key
+// and none is configured.
+var ErrMissingKey = errors.New("missing API key")
+
 func ensureKey(ctx context.Context, api config.API, defaultEnv, docsURL string) (string, error) {
 	key, err := resolveConfiguredKey(ctx, api)
 	if err != nil {
@@ -24,7 +29,7 @@ func ensureKey(ctx context.Context, api config.API, defaultEnv, docsURL string)
 		return key, nil
 	}
 	return "", errs.Wrap(
-		errs.UserErrorf("You can grab one at %s", docsURL),
+		fmt.Errorf("%w; you can grab one at %s", ErrMissingKey, docsURL),
 		fmt.Sprintf("%s required; set %s or update yai.yml through yai --settings.", defaultEnv, defaultEnv),
 	)
 }
@@ -0,0 +1,172 @@
+package requestbuilder
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+)
+
+// ReplyLanguageAuto is the reply-language that answers in the language of
+// the prompt.
+const ReplyLanguageAuto = "auto"
+
+// Languages are the languages DetectLanguage knows, for completion.
+var Languages = []string{
+	"Arabic", "Chinese", "Dutch", "English", "French", "German", "Greek",
+	"Hebrew", "Hindi", "Italian", "Japanese", "Korean", "Polish",
+	"Portuguese", "Russian", "Spanish", "Swedish", "Thai", "Turkish",
+	"Ukrainian",
+}
+
+// languageCodes maps ISO 639-1 codes to the names the model is told.
+var languageCodes = map[string]string{
+	"ar": "Arabic", "zh": "Chinese", "nl": "Dutch", "en": "English",
+	"fr": "French", "de": "German", "el": "Greek", "he": "Hebrew",
+	"hi": "Hindi", "it": "Italian", "ja": "Japanese", "ko": "Korean",
+	"pl": "Polish", "pt": "Portuguese", "ru": "Russian", "es": "Spanish",
+	"sv": "Swedish", "th": "Thai", "tr": "Turkish", "uk": "Ukrainian",
+}
+
+// stopwords are common words that tell Latin-script languages apart. A
+// word may count for more than one language.
+var stopwords = map[string][]string{
+	"English":    {"the", "and", "is", "are", "of", "to", "in", "that", "it", "with", "for", "this", "was", "you", "not", "be", "have", "what", "how", "on", "my", "can"},
+	"Spanish":    {"el", "la", "los", "las", "de", "que", "y", "en", "es", "por", "para", "con", "una", "un", "del", "se", "no", "como", "pero", "más", "está", "mi"},
+	"French":     {"le", "la", "les", "de", "des", "et", "est", "que", "un", "une", "pour", "dans", "pas", "sur", "avec", "ce", "qui", "je", "vous", "il", "du", "au"},
+	"German":     {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "zu", "mit", "den", "von", "ich", "sie", "es", "auf", "für", "auch", "wie", "dem", "sich", "wir"},
+	"Portuguese": {"o", "os", "de", "que", "e", "do", "da", "em", "um", "uma", "para", "com", "não", "é", "por", "mais", "dos", "das", "se", "como", "você", "são"},
+	"Italian":    {"il", "di", "che", "e", "la", "per", "un", "una", "non", "sono", "con", "del", "della", "gli", "le", "è", "mi", "ho", "questo", "anche", "nel"},
+	"Dutch":      {"de", "het", "een", "en", "van", "is", "dat", "niet", "op", "te", "zijn", "met", "voor", "ik", "je", "ook", "er", "maar", "dit", "wat"},
+	"Polish":     {"i", "w", "nie", "na", "się", "z", "że", "do", "jest", "to", "jak", "co", "ale", "po", "tak", "czy", "dla", "od", "jestem"},
+	"Turkish":    {"bir", "ve", "bu", "da", "de", "için", "ile", "çok", "ne", "mi", "ama", "gibi", "daha", "var", "olarak", "değil", "ben", "nasıl"},
+	"Swedish":    {"och", "att", "det", "som", "en", "är", "på", "för", "med", "inte", "jag", "av", "till", "den", "har", "om", "ett", "vi", "hur"},
+}
+
+// scriptLanguages are the scripts one language is written in.
+var scriptLanguages = []struct {
+	script   *unicode.RangeTable
+	language string
+}{
+	{unicode.Hangul, "Korean"},
+	{unicode.Arabic, "Arabic"},
+	{unicode.Hebrew, "Hebrew"},
+	{unicode.Greek, "Greek"},
+	{unicode.Thai, "Thai"},
+	{unicode.Devanagari, "Hindi"},
+}
+
+// DetectLanguage guesses the language text is written in, from its script
+// and, for Latin script, its most common words. It returns "" when it cannot
+// tell, such as for a prompt of a word or two.
+func DetectLanguage(text string) string {
+	var letters, latin, cyrillic, han, kana int
+	scripts := make([]int, len(scriptLanguages))
+	ukrainian := false
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+			ukrainian = ukrainian || strings.ContainsRune("іїєґІЇЄҐ", r)
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		default:
+			for i, sl := range scriptLanguages {
+				if unicode.Is(sl.script, r) {
+					scripts[i]++
+				}
+			}
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+	// Prompts mix in code and names in Latin script, so another script
+	// wins with a third of the letters.
+	switch {
+	case (kana+han)*3 >= letters && kana > 0:
+		return "Japanese"
+	case han*3 >= letters:
+		return "Chinese"
+	case cyrillic*3 >= letters && ukrainian:
+		return "Ukrainian"
+	case cyrillic*3 >= letters:
+		return "Russian"
+	}
+	for i, n := range scripts {
+		if n*3 >= letters {
+			return scriptLanguages[i].language
+		}
+	}
+	if latin*2 < letters {
+		return ""
+	}
+	return detectLatin(text)
+}
+
+// detectLatin picks the language with the most stopwords in text, when at
+// least two are found and no other language has as many.
+func detectLatin(text string) string {
+	counts := make(map[string]int, len(stopwords))
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		for language, words := range stopwords {
+			if slices.Contains(words, word) {
+				counts[language]++
+			}
+		}
+	}
+	best, bestCount, tied := "", 0, false
+	for language, n := range counts {
+		switch {
+		case n > bestCount:
+			best, bestCount, tied = language, n, false
+		case n == bestCount:
+			tied = true
+		}
+	}
+	if bestCount < 2 || tied {
+		return ""
+	}
+	return best
+}
+
+// replyLanguageMessage is the system message asking for answers in the
+// reply-language, or false when there is none or auto cannot tell the
+// language of prompt.
+func replyLanguageMessage(cfg *config.Config, prompt string) (proto.Message, bool) {
+	setting := strings.TrimSpace(cfg.ReplyLanguage)
+	switch {
+	case setting == "":
+		return proto.Message{}, false
+	case strings.EqualFold(setting, ReplyLanguageAuto):
+		language := DetectLanguage(prompt)
+		if language == "" {
+			return proto.Message{}, false
+		}
+		return proto.Message{
+			Role:    proto.RoleSystem,
+			Content: fmt.Sprintf("Reply in %s, the language the user wrote in.", language),
+		}, true
+	default:
+		if name, ok := languageCodes[strings.ToLower(setting)]; ok {
+			setting = name
+		}
+		return proto.Message{
+			Role:    proto.RoleSystem,
+			Content: fmt.Sprintf("Reply in %s, whatever language the user writes in.", setting),
+		}, true
+	}
+}
@@ -0,0 +1,55 @@
+package requestbuilder
+
+import (
+	"testing"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	for text, want := range map[string]string{
+		"What is the best way to read a file in Go?":                "English",
+		"¿Cuál es la mejor manera de leer un archivo en Go?":        "Spanish",
+		"Quelle est la meilleure façon de lire un fichier avec Go":  "French",
+		"Wie kann ich die Datei in Go lesen, ohne sie zu kopieren?": "German",
+		"Qual é a melhor forma de ler um arquivo em Go?":            "Portuguese",
+		"Dit is een lange tekst over het weer van vandaag":          "Dutch",
+		"Как прочитать файл в Go?":                                  "Russian",
+		"Як прочитати файл у Go? Дякую, їжак":                       "Ukrainian",
+		"Goでファイルを読むにはどうすればいいですか":                                    "Japanese",
+		"如何在Go中读取文件":                                                "Chinese",
+		"Go에서 파일을 읽는 방법은 무엇입니까?":                                    "Korean",
+		"ما هي أفضل طريقة لقراءة ملف في Go؟":                        "Arabic",
+		"hello":         "",
+		"go test ./...": "",
+		"":              "",
+	} {
+		require.Equal(t, want, DetectLanguage(text), text)
+	}
+}
+
+func TestBuildRequestReplyLanguage(t *testing.T) {
+	build := func(setting, prompt string) []proto.Message {
+		t.Helper()
+		cfg := &config.Config{Settings: config.Settings{ReplyLanguage: setting, Role: "default", Roles: map[string][]string{"default": {"be brief"}}}}
+		req, err := BuildRequestFromPrompt(cfg, config.Model{Name: "gpt-4.1"}, nil, prompt)
+		require.NoError(t, err)
+		return req.Messages
+	}
+
+	msgs := build("auto", "¿Cuál es la mejor manera de leer un archivo en Go?")
+	require.Len(t, msgs, 3)
+	require.Equal(t, "be brief", msgs[0].Content)
+	require.Equal(t, proto.RoleSystem, msgs[1].Role)
+	require.Equal(t, "Reply in Spanish, the language the user wrote in.", msgs[1].Content)
+
+	require.Len(t, build("auto", "hello"), 2, "nothing is added when the language is unknown")
+	require.Len(t, build("", "¿Cuál es la mejor manera de leer un archivo en Go?"), 2)
+
+	msgs = build("de", "What is the best way to read a file in Go?")
+	require.Equal(t, "Reply in German, whatever language the user writes in.", msgs[1].Content)
+	msgs = build("Catalan", "hello")
+	require.Equal(t, "Reply in Catalan, whatever language the user writes in.", msgs[1].Content)
+}
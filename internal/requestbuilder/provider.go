@@ -2,7 +2,9 @@ package requestbuilder
 
 import (
 	"context"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/dotcommander/yai/internal/config"
 	"github.com/dotcommander/yai/internal/errs"
@@ -18,19 +20,19 @@ type providerDescriptor struct {
 	defaultURL string // fallback BaseURL when api.BaseURL is empty
 	mapAPI     string // override the API field in provider.Config (e.g. azure-ad → azure)
 	copyUser   bool   // when true, copy api.User → cfg.User
-	thinking   bool   // when true, forward mod.ThinkingBudget to provider.Config
+	thinking   bool   // when true, forward the thinking budget to provider.Config
 }
 
 // providerRegistry maps API names to their config descriptors.
 var providerRegistry = map[string]providerDescriptor{
 	"openrouter": {envKey: "OPENROUTER_API_KEY", docsURL: "https://openrouter.ai/keys", errLabel: "OpenRouter"},
 	"vercel":     {envKey: "VERCEL_API_KEY", docsURL: "https://vercel.com/dashboard/tokens", errLabel: "Vercel AI Gateway"},
-	"bedrock":    {errLabel: "Bedrock"},
+	"bedrock":    {errLabel: "Bedrock", thinking: true},
 	"cohere":     {envKey: "COHERE_API_KEY", docsURL: "https://dashboard.cohere.com/api-keys", errLabel: "Cohere"},
 	"ollama":     {defaultURL: "http://localhost:11434/v1"},
 	"azure":      {envKey: "AZURE_OPENAI_KEY", docsURL: "https://aka.ms/oai/access", errLabel: "Azure", copyUser: true},
 	"azure-ad":   {envKey: "AZURE_OPENAI_KEY", docsURL: "https://aka.ms/oai/access", errLabel: "Azure", mapAPI: "azure", copyUser: true},
-	"anthropic":  {envKey: "ANTHROPIC_API_KEY", docsURL: "https://console.anthropic.com/settings/keys", errLabel: "Anthropic"},
+	"anthropic":  {envKey: "ANTHROPIC_API_KEY", docsURL: "https://console.anthropic.com/settings/keys", errLabel: "Anthropic", thinking: true},
 	"google":     {envKey: "GOOGLE_API_KEY", docsURL: "https://aistudio.google.com/app/apikey", errLabel: "Google", thinking: true},
 }
 
@@ -43,6 +45,15 @@ var defaultProvider = providerDescriptor{
 
 // PrepareProviderConfig builds the provider config for the selected model/API.
 func PrepareProviderConfig(ctx context.Context, mod config.Model, api config.API, cfg *config.Config) (provider.Config, error) {
+	if api.Plugin != "" {
+		// The plugin knows whether it needs a key.
+		key, err := optionalKey(ctx, api)
+		if err != nil {
+			return provider.Config{}, errs.Wrap(err, "Plugin authentication failed")
+		}
+		return provider.Config{API: mod.API, APIKey: key, BaseURL: api.BaseURL, Plugin: api.Plugin}, nil
+	}
+
 	desc, ok := providerRegistry[mod.API]
 	if !ok {
 		desc = defaultProvider
@@ -75,17 +86,36 @@ func PrepareProviderConfig(ctx context.Context, mod config.Model, api config.API
 
 	pcfg := provider.Config{API: providerAPI, APIKey: key, BaseURL: baseURL}
 	if desc.thinking {
-		pcfg.ThinkingBudget = mod.ThinkingBudget
+		budget := ThinkingBudget(cfg, mod)
+		if budget > 0 && cfg.MaxTokens > 0 && cfg.MaxTokens <= int64(budget) {
+			return provider.Config{}, errs.Wrap(
+				errs.UserErrorf("Raise --max-tokens above %d, or lower --thinking-budget.", budget),
+				fmt.Sprintf("The thinking budget of %s does not fit in max-tokens.", mod.Name),
+			)
+		}
+		pcfg.ThinkingBudget = budget
 	}
 
 	return pcfg, nil
 }
 
+// ThinkingBudget is the number of tokens mod may think for: thinking-budget
+// from settings or --thinking-budget when set, and the model's own
+// otherwise. A negative setting sends no budget, even when the model has one.
+func ThinkingBudget(cfg *config.Config, mod config.Model) int {
+	if cfg.ThinkingBudget != 0 {
+		return max(cfg.ThinkingBudget, 0)
+	}
+	return mod.ThinkingBudget
+}
+
 // ApplyHTTPConfig configures the provider HTTP client with hardened transport
-// timeouts. When httpProxy is non-empty, the transport is additionally
-// configured to route through the given HTTP proxy.
-func ApplyHTTPConfig(httpProxy string, providerCfg *provider.Config) error {
-	httpClient, err := config.NewHTTPClient(httpProxy)
+// timeouts, dialing within connectTimeout and waiting for the response
+// headers as long as for the first token, as a model can take that long to
+// start its answer. When httpProxy is non-empty, the transport is
+// additionally configured to route through the given HTTP proxy.
+func ApplyHTTPConfig(httpProxy string, connectTimeout, firstTokenTimeout time.Duration, providerCfg *provider.Config) error {
+	httpClient, err := config.NewHTTPClient(httpProxy, connectTimeout, firstTokenTimeout)
 	if err != nil {
 		if strings.Contains(err.Error(), "parse proxy") {
 			return errs.Wrap(err, "There was an error parsing your proxy URL.")
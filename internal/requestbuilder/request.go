@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/dotcommander/yai/internal/config"
 	"github.com/dotcommander/yai/internal/errs"
@@ -62,7 +63,7 @@ func buildPreparedStream(
 	if err != nil {
 		return PreparedStream{}, err
 	}
-	if err := ApplyHTTPConfig(cfg.HTTPProxy, &providerCfg); err != nil {
+	if err := ApplyHTTPConfig(cfg.HTTPProxy, cfg.ConnectTimeout, cfg.FirstTokenTimeout, &providerCfg); err != nil {
 		return PreparedStream{}, err
 	}
 
@@ -77,15 +78,15 @@ func buildPreparedStream(
 // BuildRequestFromPrompt creates a prompt-only request, optionally loading a
 // cached conversation when cache reading is configured.
 func BuildRequestFromPrompt(cfg *config.Config, mod config.Model, cacheStore *cache.Conversations, prompt string) (proto.Request, error) {
-	messages, err := buildSystemMessages(cfg)
-	if err != nil {
-		return proto.Request{}, err
-	}
-
 	if cfg.Prefix != "" {
 		prompt = strings.TrimSpace(cfg.Prefix + "\n\n" + prompt)
 	}
 
+	messages, err := SystemMessages(cfg, prompt)
+	if err != nil {
+		return proto.Request{}, err
+	}
+
 	if !cfg.NoCache && cfg.CacheReadFromID != "" {
 		if cacheStore == nil {
 			return proto.Request{}, errs.Error{Reason: "Cache is not available"}
@@ -95,71 +96,50 @@ func BuildRequestFromPrompt(cfg *config.Config, mod config.Model, cacheStore *ca
 		}
 	}
 
-	prompt = applyInputLimit(cfg, mod, prompt)
+	prompt = fitPrompt(cfg, mod, messages, applyInputLimit(cfg, mod, prompt))
 
-	messages = append(messages, proto.Message{Role: proto.RoleUser, Content: prompt})
+	messages = append(messages, proto.Message{Role: proto.RoleUser, Content: prompt, Time: time.Now()})
 
 	return BuildRequest(cfg, mod, messages), nil
 }
 
-// BuildRequestFromHistory creates a request using existing conversation messages.
+// BuildRequestFromHistory creates a request using existing conversation
+// messages. History is sent whole; the caller fits it into the context
+// window first.
 func BuildRequestFromHistory(cfg *config.Config, mod config.Model, history []proto.Message, prompt string) (proto.Request, error) {
-	messages, err := buildSystemMessages(cfg)
+	messages, err := SystemMessages(cfg, prompt)
 	if err != nil {
 		return proto.Request{}, err
 	}
 
-	// 75% of the character budget goes to history; the remaining 25% is
-	// reserved for the new prompt and system messages.
-	historyBudget := int64(0)
-	if mod.MaxChars > 0 {
-		historyBudget = mod.MaxChars * 3 / 4
-	}
-	for _, msg := range windowHistory(history, historyBudget) {
+	for _, msg := range history {
 		if msg.Role != proto.RoleSystem {
 			messages = append(messages, msg)
 		}
 	}
 
-	prompt = applyInputLimit(cfg, mod, prompt)
+	prompt = fitPrompt(cfg, mod, messages, applyInputLimit(cfg, mod, prompt))
 
-	messages = append(messages, proto.Message{Role: proto.RoleUser, Content: prompt})
+	messages = append(messages, proto.Message{Role: proto.RoleUser, Content: prompt, Time: time.Now()})
 	return BuildRequest(cfg, mod, messages), nil
 }
 
 // applyInputLimit defaults MaxChars from config and truncates the prompt when
-// input limiting is enabled.
+// input limiting is enabled, keeping its beginning and end.
 func applyInputLimit(cfg *config.Config, mod config.Model, prompt string) string {
 	maxChars := mod.MaxChars
 	if maxChars == 0 {
 		maxChars = cfg.MaxInputChars
 	}
 	if !cfg.NoLimit && maxChars > 0 && int64(len(prompt)) > maxChars {
-		return prompt[:maxChars]
+		return Truncate(prompt, int(maxChars), HeadRatio(cfg))
 	}
 	return prompt
 }
 
-func windowHistory(history []proto.Message, budgetChars int64) []proto.Message {
-	if budgetChars <= 0 || len(history) == 0 {
-		return history
-	}
-	var total int64
-	start := len(history)
-	for i := len(history) - 1; i >= 0; i-- {
-		total += int64(len(history[i].Content))
-		if total > budgetChars {
-			break
-		}
-		start = i
-	}
-	if start >= len(history) {
-		start = len(history) - 1
-	}
-	return history[start:]
-}
-
-func buildSystemMessages(cfg *config.Config) ([]proto.Message, error) {
+// SystemMessages returns the format text, the role, and the
+// reply-language instruction for prompt, in that order.
+func SystemMessages(cfg *config.Config, prompt string) ([]proto.Message, error) {
 	messages := make([]proto.Message, 0, 8)
 
 	if txt := cfg.FormatText[cfg.FormatAs]; cfg.Format && txt != "" {
@@ -180,6 +160,10 @@ func buildSystemMessages(cfg *config.Config) ([]proto.Message, error) {
 		}
 	}
 
+	if msg, ok := replyLanguageMessage(cfg, prompt); ok {
+		messages = append(messages, msg)
+	}
+
 	return messages, nil
 }
 
@@ -201,10 +185,23 @@ func BuildRequest(cfg *config.Config, mod config.Model, messages []proto.Message
 		topK = &v
 	}
 
+	presencePenalty := (*float64)(nil)
+	if cfg.PresencePenalty != 0 {
+		v := cfg.PresencePenalty
+		presencePenalty = &v
+	}
+	frequencyPenalty := (*float64)(nil)
+	if cfg.FrequencyPenalty != 0 {
+		v := cfg.FrequencyPenalty
+		frequencyPenalty = &v
+	}
+
 	if IsReasoningModel(mod.Name) {
 		temperature = nil
 		topP = nil
 		topK = nil
+		presencePenalty = nil
+		frequencyPenalty = nil
 	}
 
 	request := proto.Request{
@@ -216,7 +213,16 @@ func BuildRequest(cfg *config.Config, mod config.Model, messages []proto.Message
 		TopP:        topP,
 		TopK:        topK,
 		Stop:        cfg.Stop,
+
+		PresencePenalty:  presencePenalty,
+		FrequencyPenalty: frequencyPenalty,
+	}
+
+	if cfg.Seed != 0 {
+		request.Seed = &cfg.Seed
 	}
+	request.Logprobs = cfg.Logprobs
+	request.JSON = cfg.Format && cfg.FormatAs == "json" && !cfg.NoJSONMode
 
 	if cfg.MaxTokens > 0 && !IsReasoningModel(mod.Name) {
 		request.MaxTokens = &cfg.MaxTokens
@@ -224,6 +230,22 @@ func BuildRequest(cfg *config.Config, mod config.Model, messages []proto.Message
 	if cfg.MaxCompletionTokens > 0 {
 		request.MaxCompletionTokens = &cfg.MaxCompletionTokens
 	}
+	if cfg.RoleCacheThreshold > 0 && systemPrefixChars(messages) >= cfg.RoleCacheThreshold {
+		request.CacheSystem = true
+	}
 
 	return request
 }
+
+// systemPrefixChars is the length of the leading system messages, which is
+// the part of a request that repeats verbatim across runs of the same role.
+func systemPrefixChars(messages []proto.Message) int {
+	n := 0
+	for _, msg := range messages {
+		if msg.Role != proto.RoleSystem {
+			break
+		}
+		n += len(msg.Content)
+	}
+	return n
+}
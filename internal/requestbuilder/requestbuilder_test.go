@@ -6,6 +6,7 @@ import (
 
 	"github.com/dotcommander/yai/internal/config"
 	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/provider"
 	"github.com/stretchr/testify/require"
 )
 
@@ -33,6 +34,24 @@ func TestResolveModel(t *testing.T) {
 	require.Equal(t, "gpt-4.1", cfg.Model)
 }
 
+func TestResolveModelVirtualModelUsesFirstCandidate(t *testing.T) {
+	cfg := &config.Config{Settings: config.Settings{
+		APIs: config.APIs{
+			{Name: "openai", Models: map[string]config.Model{"gpt-4.1": {Aliases: []string{"gpt-four"}}}},
+			{Name: "anthropic", Models: map[string]config.Model{"claude-sonnet-4": {}}},
+		},
+		VirtualModels: map[string]config.VirtualModel{
+			"smart": {Models: []config.Candidate{{Model: "gpt-four"}, {API: "anthropic", Model: "claude-sonnet-4"}}},
+		},
+		Model: "smart",
+	}}
+
+	api, mod, err := ResolveModel(cfg)
+	require.NoError(t, err)
+	require.Equal(t, "openai", api.Name)
+	require.Equal(t, "gpt-4.1", mod.Name)
+}
+
 func TestResolveModelMissingModelRequiresAPI(t *testing.T) {
 	cfg := &config.Config{Settings: config.Settings{
 		APIs: config.APIs{
@@ -105,12 +124,56 @@ func TestIsReasoningModel(t *testing.T) {
 }
 
 func TestBuildRequestDropsSamplingForReasoningModel(t *testing.T) {
-	cfg := &config.Config{Settings: config.Settings{Temperature: 1, TopP: 0.9, TopK: 40}}
+	cfg := &config.Config{Settings: config.Settings{Temperature: 1, TopP: 0.9, TopK: 40, PresencePenalty: 0.5}}
 	mod := config.Model{Name: "gpt-5"}
 	req := BuildRequest(cfg, mod, nil)
 	require.Nil(t, req.Temperature)
 	require.Nil(t, req.TopP)
 	require.Nil(t, req.TopK)
+	require.Nil(t, req.PresencePenalty)
+}
+
+func TestBuildRequestSeedAndPenalties(t *testing.T) {
+	cfg := &config.Config{Settings: config.Settings{Seed: 7, FrequencyPenalty: -0.5}}
+	req := BuildRequest(cfg, config.Model{Name: "gpt-4o"}, nil)
+	require.EqualValues(t, 7, *req.Seed)
+	require.EqualValues(t, -0.5, *req.FrequencyPenalty)
+	require.Nil(t, req.PresencePenalty, "0 sends no penalty")
+
+	req = BuildRequest(&config.Config{}, config.Model{Name: "gpt-4o"}, nil)
+	require.Nil(t, req.Seed)
+}
+
+func TestBuildRequestJSON(t *testing.T) {
+	cfg := &config.Config{Settings: config.Settings{Format: true, FormatAs: "json"}}
+	require.True(t, BuildRequest(cfg, config.Model{Name: "gpt-4o"}, nil).JSON)
+
+	cfg.NoJSONMode = true
+	require.False(t, BuildRequest(cfg, config.Model{Name: "gpt-4o"}, nil).JSON)
+
+	cfg = &config.Config{Settings: config.Settings{Format: true, FormatAs: "markdown"}}
+	require.False(t, BuildRequest(cfg, config.Model{Name: "gpt-4o"}, nil).JSON)
+}
+
+func TestBuildRequestCacheSystem(t *testing.T) {
+	cfg := &config.Config{Settings: config.Settings{RoleCacheThreshold: 10}}
+	mod := config.Model{Name: "claude-sonnet-4"}
+
+	long := []proto.Message{
+		{Role: proto.RoleSystem, Content: "12345"},
+		{Role: proto.RoleSystem, Content: "67890"},
+		{Role: proto.RoleUser, Content: "a user prompt that does not count"},
+	}
+	require.True(t, BuildRequest(cfg, mod, long).CacheSystem)
+
+	short := []proto.Message{
+		{Role: proto.RoleSystem, Content: "12345"},
+		{Role: proto.RoleUser, Content: "a user prompt that does not count"},
+	}
+	require.False(t, BuildRequest(cfg, mod, short).CacheSystem)
+
+	cfg.RoleCacheThreshold = -1
+	require.False(t, BuildRequest(cfg, mod, long).CacheSystem)
 }
 
 func TestBuildPreparedFromPrompt(t *testing.T) {
@@ -171,3 +234,43 @@ func TestBuildPreparedFromHistory(t *testing.T) {
 	require.Equal(t, proto.RoleUser, prepared.Request.Messages[1].Role)
 	require.Equal(t, "follow up", prepared.Request.Messages[1].Content)
 }
+
+func TestPrepareProviderConfigThinkingBudget(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "key")
+	api := config.API{Name: "anthropic"}
+	mod := config.Model{Name: "claude-sonnet-4", API: "anthropic", ThinkingBudget: 4000}
+	cfg := &config.Config{}
+
+	pcfg, err := PrepareProviderConfig(context.Background(), mod, api, cfg)
+	require.NoError(t, err)
+	require.Equal(t, 4000, pcfg.ThinkingBudget)
+
+	cfg.ThinkingBudget = 10000
+	pcfg, err = PrepareProviderConfig(context.Background(), mod, api, cfg)
+	require.NoError(t, err)
+	require.Equal(t, 10000, pcfg.ThinkingBudget, "--thinking-budget overrides the model's")
+
+	cfg.MaxTokens = 8000
+	_, err = PrepareProviderConfig(context.Background(), mod, api, cfg)
+	require.ErrorContains(t, err, "Raise --max-tokens above 10000")
+
+	cfg.ThinkingBudget = -1
+	pcfg, err = PrepareProviderConfig(context.Background(), mod, api, cfg)
+	require.NoError(t, err)
+	require.Zero(t, pcfg.ThinkingBudget)
+}
+
+func TestPrepareProviderConfigPlugin(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	api := config.API{Name: "gateway", Plugin: "yai-gateway", BaseURL: "https://llm.internal", APIKeyEnv: "GATEWAY_KEY"}
+	mod := config.Model{Name: "house-model", API: "gateway"}
+
+	pcfg, err := PrepareProviderConfig(context.Background(), mod, api, &config.Config{})
+	require.NoError(t, err, "plugins need no key")
+	require.Equal(t, provider.Config{API: "gateway", BaseURL: "https://llm.internal", Plugin: "yai-gateway"}, pcfg)
+
+	t.Setenv("GATEWAY_KEY", "secret")
+	pcfg, err = PrepareProviderConfig(context.Background(), mod, api, &config.Config{})
+	require.NoError(t, err)
+	require.Equal(t, "secret", pcfg.APIKey)
+}
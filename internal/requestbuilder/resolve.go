@@ -11,6 +11,11 @@ import (
 
 // ResolveModel finds the requested API and model in settings.
 func ResolveModel(cfg *config.Config) (config.API, config.Model, error) {
+	if vm, ok := cfg.VirtualModel(cfg.Model); ok && cfg.API == "" && len(vm.Models) > 0 {
+		// A virtual model its policy did not pick a candidate for uses the
+		// first one.
+		cfg.API, cfg.Model = vm.Models[0].API, vm.Models[0].Model
+	}
 	for _, api := range cfg.APIs {
 		if api.Name != cfg.API && cfg.API != "" {
 			continue
@@ -46,6 +51,28 @@ func ResolveModel(cfg *config.Config) (config.API, config.Model, error) {
 	)
 }
 
+// ResolveAPI finds the configured API for non-chat requests (embeddings,
+// transcription). Those models are not listed in settings, so the returned
+// model only carries the given name (empty means the provider default) and
+// the API name.
+func ResolveAPI(cfg *config.Config, model string) (config.API, config.Model, error) {
+	if cfg.API == "" {
+		return config.API{}, config.Model{}, errs.Wrap(
+			errs.UserErrorf("Please specify an API endpoint with --api or set default-api in the settings: yai --settings"),
+			"No API endpoint configured.",
+		)
+	}
+	for _, api := range cfg.APIs {
+		if api.Name == cfg.API {
+			return api, config.Model{Name: model, API: api.Name}, nil
+		}
+	}
+	return config.API{}, config.Model{}, errs.Wrap(
+		errs.UserErrorf("Please configure it in the settings: yai --settings"),
+		fmt.Sprintf("The API endpoint %s is not in the settings file.", cfg.API),
+	)
+}
+
 // IsReasoningModel reports whether the given model name is a reasoning model
 // (e.g. o1, o3, o4, gpt-5 series) that does not support temperature/top-p/top-k.
 func IsReasoningModel(model string) bool {
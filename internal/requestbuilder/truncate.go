@@ -0,0 +1,125 @@
+package requestbuilder
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"unicode/utf8"
+
+	"github.com/dotcommander/yai/internal/config"
+)
+
+// elisionMarker replaces the middle of truncated input.
+const elisionMarker = "\n\n[... %d bytes omitted ...]\n\n"
+
+// markerRoom is the most bytes the marker takes, saved for it whatever the
+// number of bytes omitted.
+var markerRoom = len(fmt.Sprintf(elisionMarker, math.MaxInt64))
+
+// HeadRatio is the share of truncated input kept from its beginning, from
+// truncate-head: 0 is half, and a negative value keeps only the end.
+func HeadRatio(cfg *config.Config) float64 {
+	switch head := cfg.TruncateHead; {
+	case head == 0:
+		return config.Default().TruncateHead
+	case head < 0:
+		return 0
+	default:
+		return min(head, 1)
+	}
+}
+
+// Truncate keeps at most limit bytes of s: head of them from its beginning
+// and the rest from its end, with a marker in place of the middle. Logs
+// and diffs often end with what matters most. When limit is too small for
+// the marker, only the beginning is kept. Runes are never cut.
+func Truncate(s string, limit int, head float64) string {
+	if limit < 0 || len(s) <= limit {
+		return s
+	}
+	headLen, tailLen, ok := truncateSplit(limit, head)
+	if !ok {
+		return s[:runeStart(s, limit)]
+	}
+	headEnd := runeStart(s, headLen)
+	tailStart := runeEnd(s, len(s)-tailLen)
+	return s[:headEnd] + fmt.Sprintf(elisionMarker, tailStart-headEnd) + s[tailStart:]
+}
+
+// ReadTruncated reads r to the end and returns what [Truncate] keeps of
+// it, holding no more than that in memory, so that huge pipes are fine.
+func ReadTruncated(r io.Reader, limit int64, head float64) (string, error) {
+	br := bufio.NewReader(r)
+	data, err := io.ReadAll(io.LimitReader(br, limit+1))
+	if err != nil {
+		return "", err //nolint:wrapcheck
+	}
+	if int64(len(data)) <= limit {
+		return string(data), nil
+	}
+	headLen, tailLen, ok := truncateSplit(int(limit), head)
+	if !ok {
+		s := string(data)
+		// The rest of r is not needed.
+		return s[:runeStart(s, int(limit))], nil
+	}
+
+	// Keep the head, then slide a window of the last tailLen bytes over
+	// the rest.
+	headPart := string(data[:runeStart(string(data), headLen)])
+	tail := data[len(headPart):]
+	total := int64(len(data))
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := br.Read(buf)
+		tail = append(tail, buf[:n]...)
+		total += int64(n)
+		if len(tail) > 2*tailLen+len(buf) {
+			tail = append(tail[:0], tail[len(tail)-tailLen:]...)
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err //nolint:wrapcheck
+		}
+	}
+	if len(tail) > tailLen {
+		tail = tail[len(tail)-tailLen:]
+	}
+	tailPart := string(tail)
+	tailPart = tailPart[runeEnd(tailPart, 0):]
+	omitted := total - int64(len(headPart)) - int64(len(tailPart))
+	return headPart + fmt.Sprintf(elisionMarker, omitted) + tailPart, nil
+}
+
+// truncateSplit divides limit bytes, less the marker, between the head and
+// the tail. It is false when the marker does not fit.
+func truncateSplit(limit int, head float64) (int, int, bool) {
+	kept := limit - markerRoom
+	if kept <= 0 || head >= 1 {
+		return 0, 0, false
+	}
+	headLen := int(float64(kept) * max(head, 0))
+	return headLen, kept - headLen, true
+}
+
+// runeStart moves i back to the start of the rune it is in, so s[:i] ends
+// on a whole rune.
+func runeStart(s string, i int) int {
+	for i > 0 && i < len(s) && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return i
+}
+
+// runeEnd moves i forward to the start of the next rune, so s[i:] starts on
+// a whole rune.
+func runeEnd(s string, i int) int {
+	for i < len(s) && !utf8.RuneStart(s[i]) {
+		i++
+	}
+	return i
+}
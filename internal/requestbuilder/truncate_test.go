@@ -0,0 +1,66 @@
+package requestbuilder
+
+import (
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf8"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncate(t *testing.T) {
+	long := strings.Repeat("h", 100) + strings.Repeat("t", 100)
+
+	t.Run("fits", func(t *testing.T) {
+		require.Equal(t, "short", Truncate("short", 5, 0.5))
+	})
+
+	t.Run("keeps head and tail", func(t *testing.T) {
+		got := Truncate(long, 147, 0.5)
+		require.Equal(t, strings.Repeat("h", 50)+"\n\n[... 100 bytes omitted ...]\n\n"+strings.Repeat("t", 50), got)
+		require.LessOrEqual(t, len(got), 147)
+	})
+
+	t.Run("tail only", func(t *testing.T) {
+		got := Truncate(long, 147, 0)
+		require.Equal(t, "\n\n[... 100 bytes omitted ...]\n\n"+strings.Repeat("t", 100), got)
+	})
+
+	t.Run("head only", func(t *testing.T) {
+		require.Equal(t, long[:147], Truncate(long, 147, 1))
+	})
+
+	t.Run("no room for the marker", func(t *testing.T) {
+		require.Equal(t, "hhhhh", Truncate(long, 5, 0.5))
+	})
+
+	t.Run("whole runes", func(t *testing.T) {
+		s := strings.Repeat("é", 100)
+		for limit := 1; limit < len(s); limit += 7 {
+			got := Truncate(s, limit, 0.5)
+			require.True(t, utf8.ValidString(got), "limit %d", limit)
+			require.LessOrEqual(t, len(got), limit)
+		}
+	})
+}
+
+func TestReadTruncated(t *testing.T) {
+	input := strings.Repeat("début ", 5000) + strings.Repeat("fin ", 5000)
+	for _, limit := range []int64{10, 100, 1000, int64(len(input))} {
+		for _, head := range []float64{0, 0.3, 1} {
+			got, err := ReadTruncated(iotest.HalfReader(strings.NewReader(input)), limit, head)
+			require.NoError(t, err)
+			require.Equal(t, Truncate(input, int(limit), head), got, "limit %d, head %v", limit, head)
+		}
+	}
+}
+
+func TestHeadRatio(t *testing.T) {
+	for head, want := range map[float64]float64{0: 0.5, 0.25: 0.25, -1: 0, 2: 1} {
+		cfg := &config.Config{}
+		cfg.TruncateHead = head
+		require.Equal(t, want, HeadRatio(cfg))
+	}
+}
@@ -7,6 +7,10 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/dotcommander/yai/internal/storage/robustio"
+	"github.com/gofrs/flock"
 )
 
 // Type represents the type of cache being used.
@@ -16,15 +20,20 @@ type Type string
 const (
 	ConversationCache Type = "conversations"
 	TemporaryCache    Type = "temp"
+	TrashCache        Type = "trash"
 )
 
 const (
 	cacheExt       = ".json"
+	lockExt        = ".lock"
 	shardPrefixLen = 2
 )
 
 var errInvalidID = errors.New("invalid id")
 
+// ErrLocked is returned by Lock when another process holds the lock.
+var ErrLocked = errors.New("locked by another process")
+
 // Cache is a generic cache implementation that stores data in files.
 type Cache[T any] struct {
 	baseDir string
@@ -58,6 +67,11 @@ func (c *Cache[T]) legacyFilePath(id string) string {
 	return filepath.Join(c.dir(), id+cacheExt)
 }
 
+// lockPath keeps the lock file next to the item, in the same shard.
+func (c *Cache[T]) lockPath(id string) string {
+	return strings.TrimSuffix(c.filePath(id), cacheExt) + lockExt
+}
+
 func (c *Cache[T]) isSharded() bool {
 	return c.cType == ConversationCache
 }
@@ -113,7 +127,7 @@ func (c *Cache[T]) Write(id string, writeFn func(io.Writer) error) error {
 	if err := tmp.Close(); err != nil {
 		return fmt.Errorf("write: %w", err)
 	}
-	if err := os.Rename(tmpName, path); err != nil { //nolint:gosec // G703: paths are constructed from validated cache dir and ID, not user input
+	if err := robustio.Rename(tmpName, path); err != nil { //nolint:gosec // G703: paths are constructed from validated cache dir and ID, not user input
 		return fmt.Errorf("write: %w", err)
 	}
 	if d, err := os.Open(dir); err == nil {
@@ -123,17 +137,129 @@ func (c *Cache[T]) Write(id string, writeFn func(io.Writer) error) error {
 	return nil
 }
 
+// Lock takes an advisory lock on the item with the given ID and holds it
+// until unlock is called, which also removes the lock file. It does not
+// wait: when another process holds the lock, it returns ErrLocked. Reads and
+// writes do not check the lock.
+func (c *Cache[T]) Lock(id string) (unlock func(), err error) {
+	if id == "" {
+		return nil, fmt.Errorf("lock: %w", errInvalidID)
+	}
+	path := c.lockPath(id)
+	for {
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil { //nolint:gosec
+			return nil, fmt.Errorf("lock: %w", err)
+		}
+		lock := flock.New(path)
+		ok, err := lock.TryLock()
+		if err != nil {
+			return nil, fmt.Errorf("lock: %w", err)
+		}
+		if !ok {
+			return nil, fmt.Errorf("lock: %w", ErrLocked)
+		}
+		// The holder before us may have removed the file after we opened
+		// it; a lock on a removed file keeps nobody out, so take it again.
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			_ = lock.Unlock()
+			continue
+		}
+		return func() { removeHeldLock(lock) }, nil
+	}
+}
+
 // Delete removes a cached item by its ID.
 func (c *Cache[T]) Delete(id string) error {
 	if id == "" {
 		return fmt.Errorf("delete: %w", errInvalidID)
 	}
-	err := os.Remove(c.filePath(id))
+	err := robustio.Remove(c.filePath(id))
 	if c.isSharded() && errors.Is(err, os.ErrNotExist) {
-		err = os.Remove(c.legacyFilePath(id))
+		err = robustio.Remove(c.legacyFilePath(id))
 	}
 	if err != nil {
 		return fmt.Errorf("delete: %w", err)
 	}
+	_ = os.Remove(c.lockPath(id))
+	return nil
+}
+
+// Move renames the item with the given ID into dst, replacing any item
+// already there.
+func (c *Cache[T]) Move(id string, dst *Cache[T]) error {
+	if id == "" {
+		return fmt.Errorf("move: %w", errInvalidID)
+	}
+	to := dst.filePath(id)
+	if err := os.MkdirAll(filepath.Dir(to), os.ModePerm); err != nil { //nolint:gosec
+		return fmt.Errorf("move: %w", err)
+	}
+	err := robustio.Rename(c.filePath(id), to)
+	if c.isSharded() && errors.Is(err, os.ErrNotExist) {
+		err = robustio.Rename(c.legacyFilePath(id), to)
+	}
+	if err != nil {
+		return fmt.Errorf("move: %w", err)
+	}
+	_ = os.Remove(c.lockPath(id))
 	return nil
 }
+
+// VacuumStats reports what Vacuum removed.
+type VacuumStats struct {
+	StaleLocks int
+	EmptyDirs  int
+}
+
+// Vacuum removes lock files of items that no longer exist and are not held,
+// then shard directories left empty.
+func (c *Cache[T]) Vacuum() (VacuumStats, error) {
+	var stats VacuumStats
+	if !c.isSharded() {
+		return stats, nil
+	}
+	shards, err := os.ReadDir(c.dir())
+	if err != nil {
+		return stats, fmt.Errorf("vacuum: %w", err)
+	}
+	for _, shard := range shards {
+		if !shard.IsDir() || len(shard.Name()) != shardPrefixLen {
+			continue
+		}
+		dir := filepath.Join(c.dir(), shard.Name())
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return stats, fmt.Errorf("vacuum: %w", err)
+		}
+		left := len(entries)
+		for _, entry := range entries {
+			if c.removeStaleLock(dir, entry.Name()) {
+				stats.StaleLocks++
+				left--
+			}
+		}
+		// A write may have created a file since; Remove then fails and the
+		// directory stays.
+		if left == 0 && os.Remove(dir) == nil {
+			stats.EmptyDirs++
+		}
+	}
+	return stats, nil
+}
+
+// removeStaleLock removes name if it is the lock file of an item that does
+// not exist and nobody holds it.
+func (c *Cache[T]) removeStaleLock(dir, name string) bool {
+	id, ok := strings.CutSuffix(name, lockExt)
+	if !ok {
+		return false
+	}
+	if _, err := os.Stat(filepath.Join(dir, id+cacheExt)); !errors.Is(err, os.ErrNotExist) {
+		return false
+	}
+	lock := flock.New(filepath.Join(dir, name))
+	if locked, err := lock.TryLock(); err != nil || !locked {
+		return false
+	}
+	return removeHeldLock(lock)
+}
@@ -3,6 +3,7 @@ package cache
 import (
 	"io"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -84,7 +85,92 @@ func TestCache(t *testing.T) {
 		require.ErrorIs(t, err, os.ErrNotExist)
 	})
 
+	t.Run("trash and restore", func(t *testing.T) {
+		cache, err := NewConversations(t.TempDir())
+		require.NoError(t, err)
+
+		messages := []proto.Message{{Role: proto.RoleUser, Content: "hello"}}
+		require.NoError(t, cache.Write("aabbcc", &messages))
+		require.NoError(t, cache.Trash("aabbcc"))
+		require.ErrorIs(t, cache.Read("aabbcc", nil), os.ErrNotExist)
+		_, err = os.Stat(cache.trash.filePath("aabbcc"))
+		require.NoError(t, err)
+
+		require.NoError(t, cache.Restore("aabbcc"))
+		result := []proto.Message{}
+		require.NoError(t, cache.Read("aabbcc", &result))
+		require.Equal(t, messages, result)
+
+		require.NoError(t, cache.Trash("aabbcc"))
+		require.NoError(t, cache.Purge("aabbcc"))
+		require.ErrorIs(t, cache.Restore("aabbcc"), os.ErrNotExist)
+	})
+
+	t.Run("lock", func(t *testing.T) {
+		cache, err := NewConversations(t.TempDir())
+		require.NoError(t, err)
+
+		unlock, err := cache.Lock("aabbcc")
+		require.NoError(t, err)
+		_, err = cache.Lock("aabbcc")
+		require.ErrorIs(t, err, ErrLocked)
+
+		other, err := cache.Lock("ddeeff")
+		require.NoError(t, err)
+		other()
+
+		unlock()
+		_, err = os.Stat(cache.cache.lockPath("aabbcc"))
+		require.ErrorIs(t, err, os.ErrNotExist, "unlock removes the lock file")
+		unlock, err = cache.Lock("aabbcc")
+		require.NoError(t, err)
+
+		require.NoError(t, cache.Write("aabbcc", &[]proto.Message{}))
+		require.NoError(t, cache.Delete("aabbcc"))
+		_, err = os.Stat(cache.cache.lockPath("aabbcc"))
+		require.ErrorIs(t, err, os.ErrNotExist)
+		unlock()
+	})
+
+	t.Run("vacuum", func(t *testing.T) {
+		cache, err := NewConversations(t.TempDir())
+		require.NoError(t, err)
+
+		require.NoError(t, cache.Write("aabbcc", &[]proto.Message{}))
+		// Left behind by a run that was killed: one with its item, one
+		// without.
+		require.NoError(t, os.WriteFile(cache.cache.lockPath("aabbcc"), nil, 0o600))
+		require.NoError(t, os.MkdirAll(filepath.Dir(cache.cache.lockPath("ddeeff")), 0o700))
+		require.NoError(t, os.WriteFile(cache.cache.lockPath("ddeeff"), nil, 0o600))
+		// Still held by a run that has not written yet.
+		held, err := cache.Lock("dd0011")
+		require.NoError(t, err)
+		defer held()
+		// Emptied by a delete.
+		require.NoError(t, cache.Write("ffeedd", &[]proto.Message{}))
+		require.NoError(t, cache.Delete("ffeedd"))
+
+		stats, err := cache.Vacuum()
+		require.NoError(t, err)
+		require.Equal(t, VacuumStats{StaleLocks: 1, EmptyDirs: 1}, stats)
+
+		_, err = os.Stat(cache.cache.lockPath("aabbcc"))
+		require.NoError(t, err)
+		_, err = os.Stat(cache.cache.lockPath("ddeeff"))
+		require.ErrorIs(t, err, os.ErrNotExist)
+		_, err = os.Stat(cache.cache.lockPath("dd0011"))
+		require.NoError(t, err)
+		_, err = os.Stat(filepath.Dir(cache.cache.filePath("ffeedd")))
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
 	t.Run("invalid id", func(t *testing.T) {
+		t.Run("lock", func(t *testing.T) {
+			cache, err := NewConversations(t.TempDir())
+			require.NoError(t, err)
+			_, err = cache.Lock("")
+			require.ErrorIs(t, err, errInvalidID)
+		})
 		t.Run("write", func(t *testing.T) {
 			cache, err := NewConversations(t.TempDir())
 			require.NoError(t, err)
@@ -2,8 +2,10 @@ package cache
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 
 	"github.com/dotcommander/yai/internal/proto"
 )
@@ -11,9 +13,13 @@ import (
 // Conversations is the conversation cache.
 type Conversations struct {
 	cache *Cache[[]proto.Message]
+	trash *Cache[[]proto.Message]
+	// readOnly is a cache Read falls back to, which is never written to.
+	readOnly *Cache[[]proto.Message]
 }
 
-// NewConversations creates a new conversation cache.
+// NewConversations creates a new conversation cache. The trash directory
+// next to it is created on the first Trash.
 func NewConversations(dir string) (*Conversations, error) {
 	cache, err := New[[]proto.Message](dir, ConversationCache)
 	if err != nil {
@@ -21,13 +27,25 @@ func NewConversations(dir string) (*Conversations, error) {
 	}
 	return &Conversations{
 		cache: cache,
+		trash: &Cache[[]proto.Message]{baseDir: dir, cType: TrashCache},
 	}, nil
 }
 
 func (c *Conversations) Read(id string, messages *[]proto.Message) error {
-	return c.cache.Read(id, func(r io.Reader) error {
+	read := func(r io.Reader) error {
 		return decode(r, messages)
-	})
+	}
+	err := c.cache.Read(id, read)
+	if c.readOnly != nil && errors.Is(err, fs.ErrNotExist) {
+		return c.readOnly.Read(id, read)
+	}
+	return err
+}
+
+// ReadFrom makes Read fall back to the conversations in dir, which are only
+// read, for those c does not have.
+func (c *Conversations) ReadFrom(dir string) {
+	c.readOnly = &Cache[[]proto.Message]{baseDir: dir, cType: ConversationCache}
 }
 
 func (c *Conversations) Write(id string, messages *[]proto.Message) error {
@@ -36,11 +54,38 @@ func (c *Conversations) Write(id string, messages *[]proto.Message) error {
 	})
 }
 
+// Lock takes an advisory lock on a conversation so concurrent runs cannot
+// interleave their writes to it. It returns ErrLocked when the conversation
+// is already in use.
+func (c *Conversations) Lock(id string) (unlock func(), err error) {
+	return c.cache.Lock(id)
+}
+
+// Vacuum removes stale lock files and empty shard directories.
+func (c *Conversations) Vacuum() (VacuumStats, error) {
+	return c.cache.Vacuum()
+}
+
 // Delete a conversation.
 func (c *Conversations) Delete(id string) error {
 	return c.cache.Delete(id)
 }
 
+// Trash moves a conversation's messages to the trash.
+func (c *Conversations) Trash(id string) error {
+	return c.cache.Move(id, c.trash)
+}
+
+// Restore moves a conversation's messages back from the trash.
+func (c *Conversations) Restore(id string) error {
+	return c.trash.Move(id, c.cache)
+}
+
+// Purge removes a conversation's messages from the trash.
+func (c *Conversations) Purge(id string) error {
+	return c.trash.Delete(id)
+}
+
 func encode(w io.Writer, messages *[]proto.Message) error {
 	if err := json.NewEncoder(w).Encode(messages); err != nil {
 		return fmt.Errorf("encode: %w", err)
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package cache
+
+import (
+	"os"
+
+	"github.com/gofrs/flock"
+)
+
+// removeHeldLock removes the file of lock, which the caller holds, and
+// releases it.
+func removeHeldLock(lock *flock.Flock) bool {
+	defer func() { _ = lock.Unlock() }()
+	return os.Remove(lock.Path()) == nil
+}
@@ -0,0 +1,15 @@
+package cache
+
+import (
+	"os"
+
+	"github.com/gofrs/flock"
+)
+
+// removeHeldLock releases lock, which the caller holds, and removes its
+// file. Windows does not remove open files, so the lock goes first; when
+// another process takes it in between, the file stays.
+func removeHeldLock(lock *flock.Flock) bool {
+	_ = lock.Unlock()
+	return os.Remove(lock.Path()) == nil
+}
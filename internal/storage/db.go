@@ -9,11 +9,13 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/dotcommander/yai/internal/storage/robustio"
 	"github.com/gofrs/flock"
 )
 
@@ -30,10 +32,14 @@ const (
 	compactScaleFactor = 4
 )
 
+// convoEvent is one line of the index. A delete carries the deleted
+// conversation so it can be restored; deletes written before the trash
+// existed do not, and cannot be.
 type convoEvent struct {
 	Op           string        `json:"op"`
 	ID           string        `json:"id,omitempty"`
 	Conversation *Conversation `json:"conversation,omitempty"`
+	DeletedAt    *time.Time    `json:"deleted_at,omitempty"`
 }
 
 // Open loads the conversation metadata store from the given datasource.
@@ -53,6 +59,7 @@ func Open(ds string) (*DB, error) {
 		indexPath:      filepath.Join(dir, indexFileName),
 		lock:           flock.New(filepath.Join(dir, "index.lock")),
 		conversations:  make(map[string]Conversation),
+		trash:          make(map[string]Trashed),
 		cleanupTempDir: cleanupDir,
 	}
 	if err := c.load(); err != nil {
@@ -68,8 +75,14 @@ type DB struct {
 	indexPath      string
 	lock           *flock.Flock
 	conversations  map[string]Conversation
+	trash          map[string]Trashed
 	ops            int
 	cleanupTempDir string
+
+	// byID and byTitle hold the conversations sorted by ID and by title for
+	// prefix lookups in Completions; nil means stale.
+	byID    []Conversation
+	byTitle []Conversation
 }
 
 // Conversation in the database.
@@ -79,6 +92,27 @@ type Conversation struct {
 	UpdatedAt time.Time `db:"updated_at"`
 	API       *string   `db:"api"`
 	Model     *string   `db:"model"`
+	// TitleGenerated is set when the title was written by the model rather
+	// than derived from a prompt or given by the user.
+	TitleGenerated bool `db:"title_generated"`
+	// FallbackFrom is the model that was asked for when the last answer came
+	// from its fallback model.
+	FallbackFrom *string `db:"fallback_from"`
+	// Pinned conversations are listed first and never pruned by age.
+	Pinned bool `db:"pinned"`
+	// Note is free-form context the user attached to the conversation.
+	Note string `db:"note"`
+	// RequestID and ModelVersion are what the provider reported for the
+	// last answer: its ID of the request, and the exact model that
+	// answered.
+	RequestID    string `db:"request_id"`
+	ModelVersion string `db:"model_version"`
+}
+
+// Trashed is a deleted conversation that can still be restored.
+type Trashed struct {
+	Conversation
+	DeletedAt time.Time
 }
 
 // Close releases temporary resources (used for :memory: stores).
@@ -115,7 +149,14 @@ func (c *DB) Save(id, title, api, model string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if prev, ok := c.conversations[id]; ok {
+		convo.TitleGenerated = prev.TitleGenerated && prev.Title == title
+		convo.Pinned = prev.Pinned
+		convo.Note = prev.Note
+	}
+	delete(c.trash, id)
 	c.conversations[id] = convo
+	c.invalidateCompletionsLocked()
 	if err := c.appendEventLocked(convoEvent{Op: "upsert", Conversation: &convo}); err != nil {
 		return fmt.Errorf("Save: %w", err)
 	}
@@ -126,7 +167,171 @@ func (c *DB) Save(id, title, api, model string) error {
 	return nil
 }
 
-// Delete removes a conversation record by ID.
+// SetGeneratedTitle replaces the title of an existing conversation with one
+// written by the model. The update time is left alone.
+func (c *DB) SetGeneratedTitle(id, title string) error {
+	if strings.TrimSpace(title) == "" {
+		return fmt.Errorf("SetGeneratedTitle: %w", errors.New("empty title"))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	convo, ok := c.conversations[id]
+	if !ok {
+		return fmt.Errorf("SetGeneratedTitle: %w: %s", ErrNoMatches, id)
+	}
+	convo.Title = title
+	convo.TitleGenerated = true
+	c.conversations[id] = convo
+	c.invalidateCompletionsLocked()
+	if err := c.appendEventLocked(convoEvent{Op: "upsert", Conversation: &convo}); err != nil {
+		return fmt.Errorf("SetGeneratedTitle: %w", err)
+	}
+	if err := c.compactIfNeededLocked(); err != nil {
+		return fmt.Errorf("SetGeneratedTitle: %w", err)
+	}
+	return nil
+}
+
+// SetFallbackFrom records that the last answer of an existing conversation
+// came from a fallback of model. The next Save clears it.
+func (c *DB) SetFallbackFrom(id, model string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	convo, ok := c.conversations[id]
+	if !ok {
+		return fmt.Errorf("SetFallbackFrom: %w: %s", ErrNoMatches, id)
+	}
+	modelCopy := model
+	convo.FallbackFrom = &modelCopy
+	c.conversations[id] = convo
+	c.invalidateCompletionsLocked()
+	if err := c.appendEventLocked(convoEvent{Op: "upsert", Conversation: &convo}); err != nil {
+		return fmt.Errorf("SetFallbackFrom: %w", err)
+	}
+	if err := c.compactIfNeededLocked(); err != nil {
+		return fmt.Errorf("SetFallbackFrom: %w", err)
+	}
+	return nil
+}
+
+// SetResponseMeta records the provider's request ID and model version of
+// the last answer of an existing conversation. The next Save clears them.
+func (c *DB) SetResponseMeta(id, requestID, modelVersion string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	convo, ok := c.conversations[id]
+	if !ok {
+		return fmt.Errorf("SetResponseMeta: %w: %s", ErrNoMatches, id)
+	}
+	convo.RequestID = requestID
+	convo.ModelVersion = modelVersion
+	c.conversations[id] = convo
+	c.invalidateCompletionsLocked()
+	if err := c.appendEventLocked(convoEvent{Op: "upsert", Conversation: &convo}); err != nil {
+		return fmt.Errorf("SetResponseMeta: %w", err)
+	}
+	if err := c.compactIfNeededLocked(); err != nil {
+		return fmt.Errorf("SetResponseMeta: %w", err)
+	}
+	return nil
+}
+
+// SetPinned pins or unpins an existing conversation. Like Save, it sets the
+// update time, so the change wins when copies of the history are merged.
+func (c *DB) SetPinned(id string, pinned bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	convo, ok := c.conversations[id]
+	if !ok {
+		return fmt.Errorf("SetPinned: %w: %s", ErrNoMatches, id)
+	}
+	convo.Pinned = pinned
+	convo.UpdatedAt = time.Now().UTC()
+	c.conversations[id] = convo
+	c.invalidateCompletionsLocked()
+	if err := c.appendEventLocked(convoEvent{Op: "upsert", Conversation: &convo}); err != nil {
+		return fmt.Errorf("SetPinned: %w", err)
+	}
+	if err := c.compactIfNeededLocked(); err != nil {
+		return fmt.Errorf("SetPinned: %w", err)
+	}
+	return nil
+}
+
+// SetNote attaches a note to a conversation, replacing any previous one, and
+// sets the update time. An empty note removes it.
+func (c *DB) SetNote(id, note string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	convo, ok := c.conversations[id]
+	if !ok {
+		return fmt.Errorf("SetNote: %w: %s", ErrNoMatches, id)
+	}
+	convo.Note = note
+	convo.UpdatedAt = time.Now().UTC()
+	c.conversations[id] = convo
+	if err := c.appendEventLocked(convoEvent{Op: "upsert", Conversation: &convo}); err != nil {
+		return fmt.Errorf("SetNote: %w", err)
+	}
+	if err := c.compactIfNeededLocked(); err != nil {
+		return fmt.Errorf("SetNote: %w", err)
+	}
+	return nil
+}
+
+// Put stores a conversation record as given, keeping its UpdatedAt. It is
+// used to copy records between stores.
+func (c *DB) Put(convo Conversation) error {
+	if strings.TrimSpace(convo.ID) == "" {
+		return fmt.Errorf("Put: %w", errors.New("empty id"))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.trash, convo.ID)
+	c.conversations[convo.ID] = convo
+	c.invalidateCompletionsLocked()
+	if err := c.appendEventLocked(convoEvent{Op: "upsert", Conversation: &convo}); err != nil {
+		return fmt.Errorf("Put: %w", err)
+	}
+	if err := c.compactIfNeededLocked(); err != nil {
+		return fmt.Errorf("Put: %w", err)
+	}
+	return nil
+}
+
+// IndexSize returns the size of the index file in bytes.
+func (c *DB) IndexSize() (int64, error) {
+	info, err := os.Stat(c.indexPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("IndexSize: %w", err)
+	}
+	return info.Size(), nil
+}
+
+// Compact rewrites the index with one record per conversation.
+func (c *DB) Compact() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.compactLocked(); err != nil {
+		return fmt.Errorf("Compact: %w", err)
+	}
+	return nil
+}
+
+// Delete moves a conversation record to the trash, from where Restore can
+// bring it back until Purge removes it.
 func (c *DB) Delete(id string) error {
 	if strings.TrimSpace(id) == "" {
 		return fmt.Errorf("Delete: %w", errors.New("empty id"))
@@ -135,12 +340,16 @@ func (c *DB) Delete(id string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if _, ok := c.conversations[id]; !ok {
+	convo, ok := c.conversations[id]
+	if !ok {
 		return nil
 	}
+	now := time.Now().UTC()
 	delete(c.conversations, id)
+	c.trash[id] = Trashed{Conversation: convo, DeletedAt: now}
+	c.invalidateCompletionsLocked()
 
-	if err := c.appendEventLocked(convoEvent{Op: "delete", ID: id}); err != nil {
+	if err := c.appendEventLocked(convoEvent{Op: "delete", ID: id, Conversation: &convo, DeletedAt: &now}); err != nil {
 		return fmt.Errorf("Delete: %w", err)
 	}
 	if err := c.compactIfNeededLocked(); err != nil {
@@ -149,14 +358,106 @@ func (c *DB) Delete(id string) error {
 	return nil
 }
 
-// ListOlderThan returns conversations older than the given duration.
+// Restore moves a conversation back from the trash. It counts as updated
+// now, so the restore reaches other machines on the next sync.
+func (c *DB) Restore(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	trashed, ok := c.trash[id]
+	if !ok {
+		return fmt.Errorf("Restore: %w: %s", ErrNoMatches, id)
+	}
+	convo := trashed.Conversation
+	convo.UpdatedAt = time.Now().UTC()
+	delete(c.trash, id)
+	c.conversations[id] = convo
+	c.invalidateCompletionsLocked()
+
+	if err := c.appendEventLocked(convoEvent{Op: "upsert", Conversation: &convo}); err != nil {
+		return fmt.Errorf("Restore: %w", err)
+	}
+	if err := c.compactIfNeededLocked(); err != nil {
+		return fmt.Errorf("Restore: %w", err)
+	}
+	return nil
+}
+
+// Purge removes a conversation record for good, whether it is in the trash
+// or not.
+func (c *DB) Purge(id string) error {
+	if strings.TrimSpace(id) == "" {
+		return fmt.Errorf("Purge: %w", errors.New("empty id"))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, live := c.conversations[id]
+	_, trashed := c.trash[id]
+	if !live && !trashed {
+		return nil
+	}
+	delete(c.conversations, id)
+	delete(c.trash, id)
+	c.invalidateCompletionsLocked()
+
+	if err := c.appendEventLocked(convoEvent{Op: "purge", ID: id}); err != nil {
+		return fmt.Errorf("Purge: %w", err)
+	}
+	if err := c.compactIfNeededLocked(); err != nil {
+		return fmt.Errorf("Purge: %w", err)
+	}
+	return nil
+}
+
+// Trash returns the deleted conversations that can be restored, most
+// recently deleted first.
+func (c *DB) Trash() []Trashed {
+	c.mu.RLock()
+	trashed := make([]Trashed, 0, len(c.trash))
+	for _, t := range c.trash {
+		trashed = append(trashed, t)
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(trashed, func(i, j int) bool {
+		if trashed[i].DeletedAt.Equal(trashed[j].DeletedAt) {
+			return trashed[i].ID < trashed[j].ID
+		}
+		return trashed[i].DeletedAt.After(trashed[j].DeletedAt)
+	})
+	return trashed
+}
+
+// FindTrashed resolves a deleted conversation by ID prefix or exact title,
+// like Find.
+func (c *DB) FindTrashed(in string) (*Trashed, error) {
+	var matches []Trashed
+	for _, t := range c.Trash() {
+		if t.Title == in || (len(in) >= SHA1MinLen && strings.HasPrefix(t.ID, in)) {
+			matches = append(matches, t)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w: %s", ErrNoMatches, in)
+	case 1:
+		return &matches[0], nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrManyMatches, in)
+	}
+}
+
+// ListOlderThan returns unpinned conversations older than the given
+// duration.
 func (c *DB) ListOlderThan(t time.Duration) []Conversation {
 	cutoff := time.Now().Add(-t)
 
 	c.mu.RLock()
 	convos := make([]Conversation, 0, len(c.conversations))
 	for _, convo := range c.conversations {
-		if convo.UpdatedAt.Before(cutoff) {
+		if convo.UpdatedAt.Before(cutoff) && !convo.Pinned {
 			convos = append(convos, convo)
 		}
 	}
@@ -168,7 +469,7 @@ func (c *DB) ListOlderThan(t time.Duration) []Conversation {
 
 // FindHEAD returns the most recently updated conversation.
 func (c *DB) FindHEAD() (*Conversation, error) {
-	list := c.List()
+	list := c.ListRecent()
 	if len(list) == 0 {
 		return nil, fmt.Errorf("FindHead: %w", ErrNoMatches)
 	}
@@ -178,26 +479,26 @@ func (c *DB) FindHEAD() (*Conversation, error) {
 
 // Completions returns shell completion candidates for IDs and titles.
 func (c *DB) Completions(in string) []string {
-	resultSet := make(map[string]struct{})
-
-	c.mu.RLock()
-	for _, convo := range c.conversations {
-		if strings.HasPrefix(convo.ID, in) {
-			displayID := convo.ID
-			if len(in) < SHA1Short && len(convo.ID) > SHA1Short {
-				displayID = convo.ID[:SHA1Short]
-			}
-			resultSet[fmt.Sprintf("%s\t%s", displayID, convo.Title)] = struct{}{}
+	byID, byTitle := c.completionIndex()
+	byID = withPrefix(byID, in, func(c Conversation) string { return c.ID })
+	byTitle = withPrefix(byTitle, in, func(c Conversation) string { return c.Title })
+
+	// Only the matches are formatted.
+	resultSet := make(map[string]struct{}, len(byID)+len(byTitle))
+	for _, convo := range byID {
+		displayID := convo.ID
+		if len(in) < SHA1Short && len(convo.ID) > SHA1Short {
+			displayID = convo.ID[:SHA1Short]
 		}
-		if strings.HasPrefix(convo.Title, in) {
-			displayID := convo.ID
-			if len(convo.ID) > SHA1Short {
-				displayID = convo.ID[:SHA1Short]
-			}
-			resultSet[fmt.Sprintf("%s\t%s", convo.Title, displayID)] = struct{}{}
+		resultSet[displayID+"\t"+convo.Title] = struct{}{}
+	}
+	for _, convo := range byTitle {
+		displayID := convo.ID
+		if len(convo.ID) > SHA1Short {
+			displayID = convo.ID[:SHA1Short]
 		}
+		resultSet[convo.Title+"\t"+displayID] = struct{}{}
 	}
-	c.mu.RUnlock()
 
 	result := make([]string, 0, len(resultSet))
 	for value := range resultSet {
@@ -208,6 +509,47 @@ func (c *DB) Completions(in string) []string {
 	return result
 }
 
+// completionIndex returns the conversations sorted by ID and by title,
+// building them on the first call after a change. The returned slices are
+// never modified afterwards, so they are safe to read unlocked.
+func (c *DB) completionIndex() (byID, byTitle []Conversation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.byID == nil {
+		c.byID = make([]Conversation, 0, len(c.conversations))
+		for _, convo := range c.conversations {
+			c.byID = append(c.byID, convo)
+		}
+		c.byTitle = slices.Clone(c.byID)
+		slices.SortFunc(c.byID, func(a, b Conversation) int {
+			return strings.Compare(a.ID, b.ID)
+		})
+		slices.SortFunc(c.byTitle, func(a, b Conversation) int {
+			return strings.Compare(a.Title, b.Title)
+		})
+	}
+	return c.byID, c.byTitle
+}
+
+// invalidateCompletionsLocked marks the completion index stale after a
+// change to the conversations.
+func (c *DB) invalidateCompletionsLocked() {
+	c.byID, c.byTitle = nil, nil
+}
+
+// withPrefix returns the run of sorted whose key starts with prefix.
+func withPrefix(sorted []Conversation, prefix string, key func(Conversation) string) []Conversation {
+	start := sort.Search(len(sorted), func(i int) bool {
+		return key(sorted[i]) >= prefix
+	})
+	end := start
+	for end < len(sorted) && strings.HasPrefix(key(sorted[end]), prefix) {
+		end++
+	}
+	return sorted[start:end]
+}
+
 // Find resolves a conversation by ID prefix or exact title.
 func (c *DB) Find(in string) (*Conversation, error) {
 	c.mu.RLock()
@@ -237,8 +579,26 @@ func (c *DB) Find(in string) (*Conversation, error) {
 	return nil, fmt.Errorf("%w: %s", ErrNoMatches, in)
 }
 
-// List returns conversations sorted by most recently updated.
+// List returns pinned conversations first, then the rest, each sorted by
+// most recently updated.
 func (c *DB) List() []Conversation {
+	convos := c.ListRecent()
+	slices.SortStableFunc(convos, func(a, b Conversation) int {
+		switch {
+		case a.Pinned == b.Pinned:
+			return 0
+		case a.Pinned:
+			return -1
+		default:
+			return 1
+		}
+	})
+	return convos
+}
+
+// ListRecent returns conversations sorted by most recently updated,
+// pinned or not.
+func (c *DB) ListRecent() []Conversation {
 	c.mu.RLock()
 	convos := make([]Conversation, 0, len(c.conversations))
 	for _, convo := range c.conversations {
@@ -284,6 +644,21 @@ func (c *DB) load() error {
 	})
 }
 
+// LoadReadOnly adds the conversations of the index in dir, which is only
+// read, to those of c, for a store that cannot be written to. Changes to
+// them are saved to c.
+func (c *DB) LoadReadOnly(dir string) error {
+	lines, err := readIndexLines(filepath.Join(dir, indexFileName))
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.applyLines(lines)
+	c.invalidateCompletionsLocked()
+	return nil
+}
+
 func (c *DB) loadLocked() error {
 	lines, err := readIndexLines(c.indexPath)
 	if err != nil {
@@ -385,12 +760,22 @@ func (c *DB) applyEvent(evt *convoEvent) error {
 			return fmt.Errorf("invalid upsert event: empty id")
 		}
 		convo := *evt.Conversation
+		delete(c.trash, convo.ID)
 		c.conversations[convo.ID] = convo
 	case "delete":
 		if strings.TrimSpace(evt.ID) == "" {
 			return fmt.Errorf("invalid delete event: empty id")
 		}
 		delete(c.conversations, evt.ID)
+		if evt.Conversation != nil && evt.DeletedAt != nil {
+			c.trash[evt.ID] = Trashed{Conversation: *evt.Conversation, DeletedAt: *evt.DeletedAt}
+		}
+	case "purge":
+		if strings.TrimSpace(evt.ID) == "" {
+			return fmt.Errorf("invalid purge event: empty id")
+		}
+		delete(c.conversations, evt.ID)
+		delete(c.trash, evt.ID)
 	default:
 		return fmt.Errorf("invalid index event op: %q", evt.Op)
 	}
@@ -427,7 +812,7 @@ func (c *DB) compactIfNeededLocked() error {
 	if c.ops < compactMinOps {
 		return nil
 	}
-	if len(c.conversations) > 0 && c.ops < len(c.conversations)*compactScaleFactor {
+	if records := len(c.conversations) + len(c.trash); records > 0 && c.ops < records*compactScaleFactor {
 		return nil
 	}
 	return c.compactLocked()
@@ -466,6 +851,13 @@ func (c *DB) compactIndexLocked() error {
 			return fmt.Errorf("write compacted index: %w", err)
 		}
 	}
+	for _, trashed := range c.trashLocked() {
+		event := convoEvent{Op: "delete", ID: trashed.ID, Conversation: &trashed.Conversation, DeletedAt: &trashed.DeletedAt}
+		if err := enc.Encode(event); err != nil {
+			_ = file.Close()
+			return fmt.Errorf("write compacted index: %w", err)
+		}
+	}
 	if err := file.Sync(); err != nil {
 		_ = file.Close()
 		return fmt.Errorf("sync compacted index: %w", err)
@@ -474,15 +866,30 @@ func (c *DB) compactIndexLocked() error {
 		return fmt.Errorf("close compacted index: %w", err)
 	}
 
-	if err := os.Rename(tmpPath, c.indexPath); err != nil {
+	if err := robustio.Rename(tmpPath, c.indexPath); err != nil {
 		return fmt.Errorf("replace index with compacted version: %w", err)
 	}
 	_ = syncDir(filepath.Dir(c.indexPath))
 
-	c.ops = len(c.conversations)
+	c.ops = len(c.conversations) + len(c.trash)
 	return nil
 }
 
+// trashLocked returns the trash sorted by deletion time, oldest first.
+func (c *DB) trashLocked() []Trashed {
+	trashed := make([]Trashed, 0, len(c.trash))
+	for _, t := range c.trash {
+		trashed = append(trashed, t)
+	}
+	sort.Slice(trashed, func(i, j int) bool {
+		if trashed[i].DeletedAt.Equal(trashed[j].DeletedAt) {
+			return trashed[i].ID < trashed[j].ID
+		}
+		return trashed[i].DeletedAt.Before(trashed[j].DeletedAt)
+	})
+	return trashed
+}
+
 func syncDir(path string) error {
 	d, err := os.Open(path)
 	if err != nil {
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// completionsScan is the original Completions: a scan of every conversation.
+// It is kept as the reference the prefix index is checked and measured
+// against.
+func completionsScan(c *DB, in string) []string {
+	resultSet := make(map[string]struct{})
+
+	c.mu.RLock()
+	for _, convo := range c.conversations {
+		if strings.HasPrefix(convo.ID, in) {
+			displayID := convo.ID
+			if len(in) < SHA1Short && len(convo.ID) > SHA1Short {
+				displayID = convo.ID[:SHA1Short]
+			}
+			resultSet[fmt.Sprintf("%s\t%s", displayID, convo.Title)] = struct{}{}
+		}
+		if strings.HasPrefix(convo.Title, in) {
+			displayID := convo.ID
+			if len(convo.ID) > SHA1Short {
+				displayID = convo.ID[:SHA1Short]
+			}
+			resultSet[fmt.Sprintf("%s\t%s", convo.Title, displayID)] = struct{}{}
+		}
+	}
+	c.mu.RUnlock()
+
+	result := make([]string, 0, len(resultSet))
+	for value := range resultSet {
+		result = append(result, value)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// benchmarkDB fills an in-memory DB with n conversations without writing
+// the index, which would dominate setup time.
+func benchmarkDB(tb testing.TB, n int) *DB {
+	db := testDB(tb)
+	rng := rand.New(rand.NewPCG(1, 2)) //nolint:gosec
+	words := []string{"go", "rust", "regex", "monads", "sql", "docker", "naturals", "yaml", "json", "kubernetes"}
+	now := time.Now()
+	for i := range n {
+		id := fmt.Sprintf("%016x%016x%08x", rng.Uint64(), rng.Uint64(), rng.Uint32())
+		title := fmt.Sprintf("%s %s %d", words[rng.IntN(len(words))], words[rng.IntN(len(words))], i)
+		db.conversations[id] = Conversation{ID: id, Title: title, UpdatedAt: now}
+	}
+	return db
+}
+
+func TestCompletionsMatchesScan(t *testing.T) {
+	db := benchmarkDB(t, 2000)
+	var someID string
+	for id := range db.conversations {
+		someID = id
+		break
+	}
+	for _, in := range []string{"", "a", "0", "f3", someID[:SHA1Short], someID, "go", "go rust", "zzz", "json yaml 1"} {
+		require.Equal(t, completionsScan(db, in), db.Completions(in), "prefix %q", in)
+	}
+
+	require.NoError(t, db.Save(someID, "brand new title", "openai", "gpt-4o"))
+	for range 2 {
+		require.Equal(t, completionsScan(db, "brand"), db.Completions("brand"))
+	}
+	require.NoError(t, db.Delete(someID))
+	require.Empty(t, db.Completions("brand"))
+}
+
+func BenchmarkCompletions(b *testing.B) {
+	for _, n := range []int{1_000, 10_000, 50_000} {
+		db := benchmarkDB(b, n)
+		// The first call builds the index; keep that out of the timed loop.
+		db.Completions("")
+
+		for _, in := range []string{"ab", "go r", "monads sql 4"} {
+			b.Run(fmt.Sprintf("n=%d/prefix=%q/scan", n, in), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					completionsScan(db, in)
+				}
+			})
+			b.Run(fmt.Sprintf("n=%d/prefix=%q/Completions", n, in), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					db.Completions(in)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkCompletionsCold measures what shell completion pays per
+// keystroke: opening the index and answering one query.
+func BenchmarkCompletionsCold(b *testing.B) {
+	for _, n := range []int{1_000, 10_000} {
+		dir := b.TempDir()
+		db, err := Open(dir)
+		require.NoError(b, err)
+		src := benchmarkDB(b, n)
+		db.conversations = src.conversations
+		require.NoError(b, db.Compact())
+		require.NoError(b, db.Close())
+
+		b.Run(fmt.Sprintf("n=%d/scan", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				db, err := Open(dir)
+				require.NoError(b, err)
+				completionsScan(db, "go r")
+				b.StopTimer()
+				require.NoError(b, db.Close())
+				b.StartTimer()
+			}
+		})
+		b.Run(fmt.Sprintf("n=%d/Completions", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				db, err := Open(dir)
+				require.NoError(b, err)
+				db.Completions("go r")
+				b.StopTimer()
+				require.NoError(b, db.Close())
+				b.StartTimer()
+			}
+		})
+	}
+}
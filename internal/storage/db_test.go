@@ -69,6 +69,149 @@ func TestDB(t *testing.T) {
 		require.Len(t, list, 1)
 	})
 
+	t.Run("generated title", func(t *testing.T) {
+		db := testDB(t)
+
+		require.NoError(t, db.Save(testid, "message 1", "openai", "gpt-4o"))
+		before, err := db.Find(testid)
+		require.NoError(t, err)
+
+		require.NoError(t, db.SetGeneratedTitle(testid, "A better title"))
+		convo, err := db.Find(testid)
+		require.NoError(t, err)
+		require.Equal(t, "A better title", convo.Title)
+		require.True(t, convo.TitleGenerated)
+		require.Equal(t, before.UpdatedAt, convo.UpdatedAt)
+
+		// Saving with the same title keeps the flag; a new title clears it.
+		require.NoError(t, db.Save(testid, "A better title", "openai", "gpt-4o"))
+		convo, err = db.Find(testid)
+		require.NoError(t, err)
+		require.True(t, convo.TitleGenerated)
+
+		require.NoError(t, db.Save(testid, "renamed", "openai", "gpt-4o"))
+		convo, err = db.Find(testid)
+		require.NoError(t, err)
+		require.False(t, convo.TitleGenerated)
+
+		require.ErrorIs(t, db.SetGeneratedTitle(NewConversationID(), "x"), ErrNoMatches)
+	})
+
+	t.Run("fallback from", func(t *testing.T) {
+		db := testDB(t)
+
+		require.NoError(t, db.Save(testid, "message 1", "openai", "gpt-5-mini"))
+		require.NoError(t, db.SetFallbackFrom(testid, "gpt-5"))
+		convo, err := db.Find(testid)
+		require.NoError(t, err)
+		require.NotNil(t, convo.FallbackFrom)
+		require.Equal(t, "gpt-5", *convo.FallbackFrom)
+
+		require.NoError(t, db.Save(testid, "message 2", "openai", "gpt-5"))
+		convo, err = db.Find(testid)
+		require.NoError(t, err)
+		require.Nil(t, convo.FallbackFrom)
+
+		require.ErrorIs(t, db.SetFallbackFrom(NewConversationID(), "gpt-5"), ErrNoMatches)
+	})
+
+	t.Run("response meta", func(t *testing.T) {
+		db := testDB(t)
+
+		require.NoError(t, db.Save(testid, "message 1", "openai", "gpt-4o"))
+		require.NoError(t, db.SetResponseMeta(testid, "req_123", "gpt-4o-2024-11-20"))
+		convo, err := db.Find(testid)
+		require.NoError(t, err)
+		require.Equal(t, "req_123", convo.RequestID)
+		require.Equal(t, "gpt-4o-2024-11-20", convo.ModelVersion)
+
+		require.NoError(t, db.Save(testid, "message 2", "openai", "gpt-4o"))
+		convo, err = db.Find(testid)
+		require.NoError(t, err)
+		require.Empty(t, convo.RequestID)
+		require.Empty(t, convo.ModelVersion)
+
+		require.ErrorIs(t, db.SetResponseMeta(NewConversationID(), "req_123", ""), ErrNoMatches)
+	})
+
+	t.Run("put keeps updated at", func(t *testing.T) {
+		dir := t.TempDir()
+		db, err := Open(dir)
+		require.NoError(t, err)
+
+		api := "openai"
+		when := time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC)
+		require.NoError(t, db.Put(Conversation{ID: testid, Title: "copied", UpdatedAt: when, API: &api}))
+		require.Error(t, db.Put(Conversation{Title: "no id"}))
+		require.NoError(t, db.Compact())
+		require.NoError(t, db.Close())
+
+		db2, err := Open(dir)
+		require.NoError(t, err)
+		convo, err := db2.Find(testid)
+		require.NoError(t, err)
+		require.Equal(t, "copied", convo.Title)
+		require.True(t, when.Equal(convo.UpdatedAt))
+	})
+
+	t.Run("pinned", func(t *testing.T) {
+		db := testDB(t)
+		old := time.Now().Add(-48 * time.Hour)
+		for _, id := range []string{"aaaa", "bbbb", "cccc"} {
+			require.NoError(t, db.Put(Conversation{ID: id, Title: id, UpdatedAt: old}))
+			old = old.Add(time.Hour)
+		}
+
+		require.NoError(t, db.SetPinned("aaaa", true))
+		require.ErrorIs(t, db.SetPinned("zzzz", true), ErrNoMatches)
+
+		var order []string
+		for _, c := range db.List() {
+			order = append(order, c.ID)
+		}
+		require.Equal(t, []string{"aaaa", "cccc", "bbbb"}, order)
+
+		head, err := db.FindHEAD()
+		require.NoError(t, err)
+		require.Equal(t, "aaaa", head.ID, "pinning updates the conversation")
+		require.WithinDuration(t, time.Now(), head.UpdatedAt, time.Minute)
+
+		require.Len(t, db.ListOlderThan(time.Hour), 2)
+
+		// Saving a new turn keeps the pin.
+		require.NoError(t, db.Save("aaaa", "aaaa", "openai", "gpt-4o"))
+		convo, err := db.Find("aaaa")
+		require.NoError(t, err)
+		require.True(t, convo.Pinned)
+
+		require.NoError(t, db.SetPinned("aaaa", false))
+		require.Equal(t, "aaaa", db.List()[0].ID)
+		require.False(t, db.List()[0].Pinned)
+	})
+
+	t.Run("note", func(t *testing.T) {
+		db := testDB(t)
+
+		require.NoError(t, db.Save(testid, "message 1", "openai", "gpt-4o"))
+		require.NoError(t, db.Put(Conversation{ID: testid, Title: "message 1", UpdatedAt: time.Now().Add(-time.Hour)}))
+		require.NoError(t, db.SetNote(testid, "context for this thread"))
+		require.ErrorIs(t, db.SetNote("zzzz", "nope"), ErrNoMatches)
+		convo, err := db.Find(testid)
+		require.NoError(t, err)
+		require.WithinDuration(t, time.Now(), convo.UpdatedAt, time.Minute)
+
+		// Saving a new turn keeps the note.
+		require.NoError(t, db.Save(testid, "message 2", "openai", "gpt-4o"))
+		convo, err = db.Find(testid)
+		require.NoError(t, err)
+		require.Equal(t, "context for this thread", convo.Note)
+
+		require.NoError(t, db.SetNote(testid, ""))
+		convo, err = db.Find(testid)
+		require.NoError(t, err)
+		require.Empty(t, convo.Note)
+	})
+
 	t.Run("find head single", func(t *testing.T) {
 		db := testDB(t)
 
@@ -142,6 +285,87 @@ func TestDB(t *testing.T) {
 		require.Empty(t, list)
 	})
 
+	t.Run("trash", func(t *testing.T) {
+		db := testDB(t)
+
+		require.NoError(t, db.Save(testid, "message 1", "openai", "gpt-4o"))
+		require.NoError(t, db.Delete(testid))
+		_, err := db.Find(testid)
+		require.ErrorIs(t, err, ErrNoMatches)
+
+		trash := db.Trash()
+		require.Len(t, trash, 1)
+		require.Equal(t, testid, trash[0].ID)
+		require.Equal(t, "message 1", trash[0].Title)
+		require.False(t, trash[0].DeletedAt.IsZero())
+
+		found, err := db.FindTrashed("message 1")
+		require.NoError(t, err)
+		require.Equal(t, testid, found.ID)
+
+		require.NoError(t, db.Restore(testid))
+		require.Empty(t, db.Trash())
+		convo, err := db.Find(testid)
+		require.NoError(t, err)
+		require.Equal(t, "message 1", convo.Title)
+		require.ErrorIs(t, db.Restore(testid), ErrNoMatches)
+
+		require.NoError(t, db.Delete(testid))
+		require.NoError(t, db.Purge(testid))
+		require.Empty(t, db.Trash())
+		require.Empty(t, db.List())
+	})
+
+	t.Run("trash persists", func(t *testing.T) {
+		const testid2 = "6c33f71694bf41a18c844a96d1f62f153e5f6f44"
+		dir := t.TempDir()
+
+		db, err := Open(dir)
+		require.NoError(t, err)
+		require.NoError(t, db.Save(testid, "message 1", "openai", "gpt-4o"))
+		require.NoError(t, db.Save(testid2, "message 2", "openai", "gpt-4o"))
+		require.NoError(t, db.Delete(testid))
+		require.NoError(t, db.Delete(testid2))
+		require.NoError(t, db.Purge(testid2))
+		require.NoError(t, db.Close())
+
+		db, err = Open(dir)
+		require.NoError(t, err)
+		require.Len(t, db.Trash(), 1)
+		require.NoError(t, db.Compact())
+		require.NoError(t, db.Close())
+
+		db, err = Open(dir)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, db.Close())
+		})
+		trash := db.Trash()
+		require.Len(t, trash, 1)
+		require.Equal(t, testid, trash[0].ID)
+		require.Empty(t, db.List())
+	})
+
+	t.Run("delete events without a conversation are permanent", func(t *testing.T) {
+		dir := t.TempDir()
+
+		convo := Conversation{ID: testid, Title: "old", UpdatedAt: time.Now().UTC()}
+		upsert, err := json.Marshal(convoEvent{Op: "upsert", Conversation: &convo})
+		require.NoError(t, err)
+		del, err := json.Marshal(convoEvent{Op: "delete", ID: testid})
+		require.NoError(t, err)
+		index := append(append(append(upsert, '\n'), del...), '\n')
+		require.NoError(t, os.WriteFile(filepath.Join(dir, indexFileName), index, 0o600))
+
+		db, err := Open(dir)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, db.Close())
+		})
+		require.Empty(t, db.List())
+		require.Empty(t, db.Trash())
+	})
+
 	t.Run("completions", func(t *testing.T) {
 		db := testDB(t)
 
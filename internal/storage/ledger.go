@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dotcommander/yai/internal/storage/robustio"
+	"github.com/gofrs/flock"
+)
+
+const (
+	ledgerFileName = "usage.jsonl"
+	// ledgerMaxBytes bounds the ledger; past it, the older half is dropped.
+	// It holds months of requests, for the monthly budget.
+	ledgerMaxBytes = 4 << 20
+)
+
+// LedgerEntry is one request in the usage ledger.
+type LedgerEntry struct {
+	Time  time.Time `json:"time"`
+	API   string    `json:"api"`
+	Model string    `json:"model"`
+	// FirstTokenMS and LatencyMS are how long the answer took to start and
+	// to finish, in milliseconds.
+	FirstTokenMS int64 `json:"first_token_ms,omitempty"`
+	LatencyMS    int64 `json:"latency_ms,omitempty"`
+	// Failed marks a request the provider did not answer, and Failure says
+	// how it failed, as in "timeout" or "server".
+	Failed  bool   `json:"failed,omitempty"`
+	Failure string `json:"failure,omitempty"`
+	// InputTokens and OutputTokens are what the provider reported for the
+	// request, or estimates from the text when it reported nothing, as
+	// Estimated says.
+	InputTokens  int64 `json:"input_tokens,omitempty"`
+	OutputTokens int64 `json:"output_tokens,omitempty"`
+	Estimated    bool  `json:"estimated,omitempty"`
+	// Cost is the price of the tokens at the model's input-cost and
+	// output-cost, in dollars.
+	Cost float64 `json:"cost,omitempty"`
+}
+
+// Ledger is the usage ledger: a JSONL record of the requests made to each
+// API and model, kept next to the conversations.
+type Ledger struct {
+	path string
+	lock *flock.Flock
+}
+
+// OpenLedger returns the ledger in the cache directory dir. The file is
+// created on the first Append.
+func OpenLedger(dir string) *Ledger {
+	return &Ledger{
+		path: filepath.Join(dir, ledgerFileName),
+		lock: flock.New(filepath.Join(dir, "usage.lock")),
+	}
+}
+
+// Append adds an entry to the ledger.
+func (l *Ledger) Append(entry LedgerEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encode ledger entry: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return fmt.Errorf("create ledger directory: %w", err)
+	}
+	if err := l.lock.Lock(); err != nil {
+		return fmt.Errorf("lock ledger: %w", err)
+	}
+	defer l.lock.Unlock() //nolint:errcheck
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("open ledger: %w", err)
+	}
+	_, err = f.Write(append(line, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("write ledger: %w", err)
+	}
+	return l.trimLocked()
+}
+
+// trimLocked drops the older half of the ledger once it is over
+// ledgerMaxBytes.
+func (l *Ledger) trimLocked() error {
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return fmt.Errorf("stat ledger: %w", err)
+	}
+	if info.Size() <= ledgerMaxBytes {
+		return nil
+	}
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return fmt.Errorf("read ledger: %w", err)
+	}
+	keep := data[len(data)/2:]
+	if i := bytes.IndexByte(keep, '\n'); i >= 0 {
+		keep = keep[i+1:]
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, keep, 0o600); err != nil {
+		return fmt.Errorf("trim ledger: %w", err)
+	}
+	if err := robustio.Rename(tmp, l.path); err != nil {
+		return fmt.Errorf("trim ledger: %w", err)
+	}
+	return nil
+}
+
+// Since returns the entries recorded at or after t, oldest first. Lines that
+// cannot be read are skipped.
+func (l *Ledger) Since(t time.Time) ([]LedgerEntry, error) {
+	f, err := os.Open(l.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open ledger: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	var entries []LedgerEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry LedgerEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil || entry.Time.Before(t) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read ledger: %w", err)
+	}
+	return entries, nil
+}
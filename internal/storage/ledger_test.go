@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLedger(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	ledger := OpenLedger(dir)
+
+	entries, err := ledger.Since(time.Time{})
+	require.NoError(t, err)
+	require.Empty(t, entries, "a missing ledger is empty")
+
+	now := time.Now().UTC().Truncate(time.Second)
+	old := LedgerEntry{Time: now.Add(-48 * time.Hour), API: "openai", Model: "gpt-4o", FirstTokenMS: 900, LatencyMS: 3000}
+	recent := LedgerEntry{Time: now, API: "azure", Model: "gpt-4o", Failed: true}
+	require.NoError(t, ledger.Append(old))
+	require.NoError(t, ledger.Append(recent))
+
+	entries, err = ledger.Since(now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []LedgerEntry{recent}, entries)
+
+	entries, err = ledger.Since(time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, []LedgerEntry{old, recent}, entries)
+}
+
+func TestLedger_Trim(t *testing.T) {
+	dir := t.TempDir()
+	ledger := OpenLedger(dir)
+	path := filepath.Join(dir, ledgerFileName)
+
+	line := `{"time":"2026-01-01T00:00:00Z","api":"openai","model":"` + strings.Repeat("m", 100) + `"}` + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat(line, ledgerMaxBytes/len(line)+1)), 0o600))
+
+	last := LedgerEntry{Time: time.Now().UTC().Truncate(time.Second), API: "azure", Model: "gpt-4o"}
+	require.NoError(t, ledger.Append(last))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.LessOrEqual(t, info.Size(), int64(ledgerMaxBytes/2+len(line)))
+
+	entries, err := ledger.Since(time.Time{})
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+	require.Equal(t, last, entries[len(entries)-1], "trimming keeps the newest entries")
+	for _, e := range entries[:len(entries)-1] {
+		require.Equal(t, "openai", e.API, "trimming keeps whole lines")
+	}
+}
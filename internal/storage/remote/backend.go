@@ -0,0 +1,181 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Backend names accepted by New.
+const (
+	Git   = "git"
+	S3    = "s3"
+	Rsync = "rsync"
+)
+
+// Backends lists the supported backend names.
+var Backends = []string{Git, S3, Rsync}
+
+// ErrUnknownBackend is returned by New for an unsupported backend name.
+var ErrUnknownBackend = errors.New("unknown sync backend")
+
+// Backend moves the synced copy of the history between a local working
+// directory and the remote.
+type Backend interface {
+	// Pull makes dir match the remote copy.
+	Pull(ctx context.Context, dir string) error
+	// Push makes the remote copy match dir.
+	Push(ctx context.Context, dir string) error
+}
+
+// New returns the backend with the given name, talking to target: a git URL,
+// an s3://bucket/prefix URL, or an rsync destination such as host:path.
+func New(name, target string) (Backend, error) {
+	if strings.TrimSpace(target) == "" {
+		return nil, errors.New("missing sync remote")
+	}
+	switch name {
+	case Git:
+		return gitBackend{url: target}, nil
+	case S3:
+		return s3Backend{url: strings.TrimSuffix(target, "/")}, nil
+	case Rsync:
+		return rsyncBackend{target: strings.TrimSuffix(target, "/")}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownBackend, name)
+	}
+}
+
+// ignored are the files in the working directory that are never synced:
+// lock files and leftovers of interrupted writes.
+var ignored = []string{"*.lock", ".tmp-*", "*.tmp"}
+
+// gitBackend keeps the history in a git repository, one commit per sync.
+type gitBackend struct {
+	url string
+}
+
+func (b gitBackend) Pull(ctx context.Context, dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(dir), 0o700); err != nil {
+			return fmt.Errorf("pull: %w", err)
+		}
+		return run(ctx, "", "git", "clone", "--quiet", b.url, dir)
+	}
+	// Follow the remote if it was changed in settings since the last sync.
+	if err := run(ctx, dir, "git", "remote", "set-url", "origin", b.url); err != nil {
+		return err
+	}
+	heads, err := output(ctx, dir, "git", "ls-remote", "--heads", "origin")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(heads) == "" {
+		// Nothing pushed yet.
+		return nil
+	}
+	// Anything left over from a sync that failed to push is rebuilt from the
+	// local store, so the remote copy always wins here.
+	if err := run(ctx, dir, "git", "fetch", "--quiet", "origin", "HEAD"); err != nil {
+		return err
+	}
+	if err := run(ctx, dir, "git", "reset", "--quiet", "--hard", "FETCH_HEAD"); err != nil {
+		return err
+	}
+	return run(ctx, dir, "git", "clean", "--quiet", "--force", "-d")
+}
+
+func (b gitBackend) Push(ctx context.Context, dir string) error {
+	gitignore := strings.Join(ignored, "\n") + "\n"
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte(gitignore), 0o600); err != nil {
+		return fmt.Errorf("push: %w", err)
+	}
+	if err := run(ctx, dir, "git", "add", "--all"); err != nil {
+		return err
+	}
+	if err := run(ctx, dir, "git", "diff", "--cached", "--quiet"); err == nil {
+		// Nothing changed since the last sync.
+		return nil
+	}
+	host, _ := os.Hostname()
+	if err := run(ctx, dir, "git", "commit", "--quiet", "--message", "yai history sync from "+host); err != nil {
+		return err
+	}
+	return run(ctx, dir, "git", "push", "--quiet", "origin", "HEAD")
+}
+
+// s3Backend mirrors the history under an S3 prefix with the aws CLI.
+type s3Backend struct {
+	url string
+}
+
+func (b s3Backend) Pull(ctx context.Context, dir string) error {
+	return run(ctx, "", "aws", append([]string{"s3", "sync", "--delete", "--only-show-errors", b.url + "/", dir + "/"}, s3Excludes()...)...)
+}
+
+func (b s3Backend) Push(ctx context.Context, dir string) error {
+	return run(ctx, "", "aws", append([]string{"s3", "sync", "--delete", "--only-show-errors", dir + "/", b.url + "/"}, s3Excludes()...)...)
+}
+
+func s3Excludes() []string {
+	var args []string
+	for _, pattern := range ignored {
+		args = append(args, "--exclude", pattern)
+	}
+	return args
+}
+
+// rsyncBackend mirrors the history to a directory, usually over ssh.
+type rsyncBackend struct {
+	target string
+}
+
+func (b rsyncBackend) Pull(ctx context.Context, dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("pull: %w", err)
+	}
+	err := run(ctx, "", "rsync", append(rsyncArgs(), b.target+"/", dir+"/")...)
+	if err != nil && strings.Contains(err.Error(), "No such file or directory") {
+		// Nothing pushed yet; the first Push creates the target.
+		return nil
+	}
+	return err
+}
+
+func (b rsyncBackend) Push(ctx context.Context, dir string) error {
+	return run(ctx, "", "rsync", append(rsyncArgs(), "--mkpath", dir+"/", b.target+"/")...)
+}
+
+func rsyncArgs() []string {
+	args := []string{"--archive", "--delete", "--compress"}
+	for _, pattern := range ignored {
+		args = append(args, "--exclude", pattern)
+	}
+	return args
+}
+
+// run runs a command in dir, folding its stderr into the error.
+func run(ctx context.Context, dir, name string, args ...string) error {
+	_, err := output(ctx, dir, name, args...)
+	return err
+}
+
+func output(ctx context.Context, dir, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...) //nolint:gosec // G204: the remote is user-configured
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s %s: %w: %s", name, args[0], err, msg)
+		}
+		return "", fmt.Errorf("%s %s: %w", name, args[0], err)
+	}
+	return stdout.String(), nil
+}
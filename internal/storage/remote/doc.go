@@ -0,0 +1,8 @@
+// Package remote syncs the conversation store with a copy kept on another
+// machine: a git repository, an S3 prefix, or an rsync target.
+//
+// Each backend mirrors a local working directory laid out like the cache
+// path (conversations/index.jsonl plus the sharded message files). A sync
+// pulls that directory, merges it with the local store, and pushes it back.
+// Conversations changed on both sides keep the most recently updated copy.
+package remote
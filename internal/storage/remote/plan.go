@@ -0,0 +1,122 @@
+package remote
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// Action is what a sync does with one conversation.
+type Action int
+
+// Sync actions.
+const (
+	// Pull copies the remote conversation over the local one.
+	Pull Action = iota
+	// Push copies the local conversation over the remote one.
+	Push
+	// DeleteLocal removes a conversation that was deleted on another host.
+	DeleteLocal
+	// DeleteRemote removes a conversation that was deleted on this host.
+	DeleteRemote
+)
+
+func (a Action) String() string {
+	switch a {
+	case Pull:
+		return "pull"
+	case Push:
+		return "push"
+	case DeleteLocal:
+		return "delete local"
+	case DeleteRemote:
+		return "delete remote"
+	default:
+		return fmt.Sprintf("Action(%d)", int(a))
+	}
+}
+
+// Change is one planned action.
+type Change struct {
+	ID     string
+	Action Action
+}
+
+// Versions maps conversation IDs to their UpdatedAt.
+type Versions map[string]time.Time
+
+// Plan lists the changes that bring local and remote in line. base holds the
+// versions both sides agreed on after the previous sync; it tells a
+// conversation deleted on one side apart from one created on the other.
+// When both sides changed a conversation, the one updated last wins.
+func Plan(local, remote, base Versions) []Change {
+	var changes []Change
+	for id, l := range local {
+		r, ok := remote[id]
+		switch {
+		case ok && l.After(r):
+			changes = append(changes, Change{id, Push})
+		case ok && r.After(l):
+			changes = append(changes, Change{id, Pull})
+		case ok:
+		case deletedSince(base, id, l):
+			changes = append(changes, Change{id, DeleteLocal})
+		default:
+			changes = append(changes, Change{id, Push})
+		}
+	}
+	for id, r := range remote {
+		if _, ok := local[id]; ok {
+			continue
+		}
+		if deletedSince(base, id, r) {
+			changes = append(changes, Change{id, DeleteRemote})
+		} else {
+			changes = append(changes, Change{id, Pull})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].ID < changes[j].ID
+	})
+	return changes
+}
+
+// deletedSince reports whether a conversation that is missing on one side
+// was synced before and has not changed on the other side since, meaning it
+// was deleted rather than never seen.
+func deletedSince(base Versions, id string, updated time.Time) bool {
+	synced, ok := base[id]
+	return ok && !updated.After(synced)
+}
+
+// LoadVersions reads versions saved by SaveVersions. A missing file yields
+// no versions.
+func LoadVersions(path string) (Versions, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Versions{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load sync state: %w", err)
+	}
+	var v Versions
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("load sync state: %w", err)
+	}
+	return v, nil
+}
+
+// SaveVersions writes v to path.
+func SaveVersions(path string, v Versions) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("save sync state: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("save sync state: %w", err)
+	}
+	return nil
+}
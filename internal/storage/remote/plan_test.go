@@ -0,0 +1,82 @@
+package remote
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlan(t *testing.T) {
+	t0 := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Hour)
+
+	for name, tc := range map[string]struct {
+		local, remote, base Versions
+		want                []Change
+	}{
+		"in sync": {
+			local:  Versions{"a": t0},
+			remote: Versions{"a": t0},
+			base:   Versions{"a": t0},
+		},
+		"new locally": {
+			local: Versions{"a": t0},
+			want:  []Change{{"a", Push}},
+		},
+		"new remotely": {
+			remote: Versions{"a": t0},
+			want:   []Change{{"a", Pull}},
+		},
+		"newer locally": {
+			local:  Versions{"a": t1},
+			remote: Versions{"a": t0},
+			base:   Versions{"a": t0},
+			want:   []Change{{"a", Push}},
+		},
+		"newer remotely wins a conflict": {
+			local:  Versions{"a": t0.Add(time.Minute)},
+			remote: Versions{"a": t1},
+			base:   Versions{"a": t0},
+			want:   []Change{{"a", Pull}},
+		},
+		"deleted locally": {
+			remote: Versions{"a": t0},
+			base:   Versions{"a": t0},
+			want:   []Change{{"a", DeleteRemote}},
+		},
+		"deleted remotely": {
+			local: Versions{"a": t0},
+			base:  Versions{"a": t0},
+			want:  []Change{{"a", DeleteLocal}},
+		},
+		"updated after a remote delete": {
+			local: Versions{"a": t1},
+			base:  Versions{"a": t0},
+			want:  []Change{{"a", Push}},
+		},
+		"sorted by id": {
+			local:  Versions{"b": t0},
+			remote: Versions{"a": t0},
+			want:   []Change{{"a", Pull}, {"b", Push}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.want, Plan(tc.local, tc.remote, tc.base))
+		})
+	}
+}
+
+func TestVersions(t *testing.T) {
+	path := t.TempDir() + "/state.json"
+
+	v, err := LoadVersions(path)
+	require.NoError(t, err)
+	require.Empty(t, v)
+
+	when := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, SaveVersions(path, Versions{"a": when}))
+	v, err = LoadVersions(path)
+	require.NoError(t, err)
+	require.True(t, when.Equal(v["a"]))
+}
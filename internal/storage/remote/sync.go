@@ -0,0 +1,206 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/storage"
+	"github.com/dotcommander/yai/internal/storage/cache"
+	"github.com/gofrs/flock"
+)
+
+// ErrBusy is returned by Run when another sync of the same directory is in
+// progress.
+var ErrBusy = errors.New("another sync is in progress")
+
+// Store is one copy of the history: the metadata index and the messages.
+type Store struct {
+	DB    *storage.DB
+	Cache *cache.Conversations
+}
+
+// OpenStore opens the store laid out under dir the way the cache path is.
+func OpenStore(dir string) (Store, error) {
+	convoCache, err := cache.NewConversations(dir)
+	if err != nil {
+		return Store{}, fmt.Errorf("open conversation cache: %w", err)
+	}
+	db, err := storage.Open(filepath.Join(dir, "conversations"))
+	if err != nil {
+		return Store{}, fmt.Errorf("open conversation database: %w", err)
+	}
+	return Store{DB: db, Cache: convoCache}, nil
+}
+
+func (s Store) versions() (Versions, map[string]storage.Conversation) {
+	versions := Versions{}
+	convos := map[string]storage.Conversation{}
+	for _, c := range s.DB.List() {
+		versions[c.ID] = c.UpdatedAt
+		convos[c.ID] = c
+	}
+	return versions, convos
+}
+
+// Summary reports what a sync did, or would do for a dry run.
+type Summary struct {
+	// Changes lists the changes made, or planned for a dry run.
+	Changes []Change
+	// Skipped lists conversations left alone because another yai process
+	// had them open. The next sync picks them up.
+	Skipped []string
+}
+
+// Count returns how many changes have the given action.
+func (s Summary) Count(action Action) int {
+	n := 0
+	for _, c := range s.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Syncer syncs a local store with a backend.
+type Syncer struct {
+	Backend Backend
+	// Dir is the working directory mirrored to the remote. The versions of
+	// the last sync are kept next to it, in Dir+".json".
+	Dir    string
+	Local  Store
+	DryRun bool
+}
+
+// Run pulls the remote copy, merges it with the local store, and pushes the
+// result back.
+func (s *Syncer) Run(ctx context.Context) (Summary, error) {
+	if err := os.MkdirAll(filepath.Dir(s.Dir), 0o700); err != nil {
+		return Summary{}, fmt.Errorf("sync: %w", err)
+	}
+	lock := flock.New(s.Dir + ".lock")
+	ok, err := lock.TryLock()
+	if err != nil {
+		return Summary{}, fmt.Errorf("sync: %w", err)
+	}
+	if !ok {
+		return Summary{}, fmt.Errorf("sync: %w", ErrBusy)
+	}
+	defer func() { _ = lock.Unlock() }()
+
+	if err := s.Backend.Pull(ctx, s.Dir); err != nil {
+		return Summary{}, fmt.Errorf("sync: %w", err)
+	}
+	remote, err := OpenStore(s.Dir)
+	if err != nil {
+		return Summary{}, fmt.Errorf("sync: %w", err)
+	}
+	defer func() { _ = remote.DB.Close() }()
+
+	statePath := s.Dir + ".json"
+	base, err := LoadVersions(statePath)
+	if err != nil {
+		return Summary{}, fmt.Errorf("sync: %w", err)
+	}
+	localVersions, localConvos := s.Local.versions()
+	remoteVersions, remoteConvos := remote.versions()
+
+	changes := Plan(localVersions, remoteVersions, base)
+	if s.DryRun {
+		return Summary{Changes: changes}, nil
+	}
+
+	var summary Summary
+	pushed := false
+	for _, change := range changes {
+		switch change.Action {
+		case Push:
+			err = copyConversation(s.Local, remote, localConvos[change.ID])
+			pushed = true
+		case DeleteRemote:
+			err = purgeConversation(remote, change.ID)
+			pushed = true
+		case Pull, DeleteLocal:
+			var skipped bool
+			skipped, err = s.applyLocal(change, remote, remoteConvos[change.ID])
+			if skipped {
+				summary.Skipped = append(summary.Skipped, change.ID)
+				continue
+			}
+		}
+		if err != nil {
+			return summary, fmt.Errorf("sync %s: %w", change.ID, err)
+		}
+		summary.Changes = append(summary.Changes, change)
+	}
+
+	if pushed {
+		if err := remote.DB.Compact(); err != nil {
+			return summary, fmt.Errorf("sync: %w", err)
+		}
+		if err := s.Backend.Push(ctx, s.Dir); err != nil {
+			return summary, fmt.Errorf("sync: %w", err)
+		}
+	}
+
+	synced, _ := s.Local.versions()
+	if err := SaveVersions(statePath, synced); err != nil {
+		return summary, fmt.Errorf("sync: %w", err)
+	}
+	return summary, nil
+}
+
+// applyLocal pulls or deletes a local conversation unless another process
+// holds it.
+func (s *Syncer) applyLocal(change Change, remote Store, convo storage.Conversation) (skipped bool, err error) {
+	unlock, err := s.Local.Cache.Lock(change.ID)
+	if errors.Is(err, cache.ErrLocked) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err //nolint:wrapcheck
+	}
+	defer unlock()
+
+	if change.Action == DeleteLocal {
+		return false, trashConversation(s.Local, change.ID)
+	}
+	return false, copyConversation(remote, s.Local, convo)
+}
+
+func copyConversation(from, to Store, convo storage.Conversation) error {
+	var messages []proto.Message
+	if err := from.Cache.Read(convo.ID, &messages); err != nil {
+		return err //nolint:wrapcheck
+	}
+	if err := to.Cache.Write(convo.ID, &messages); err != nil {
+		return err //nolint:wrapcheck
+	}
+	// A copy in the trash is superseded by the one just written.
+	if err := to.Cache.Purge(convo.ID); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err //nolint:wrapcheck
+	}
+	return to.DB.Put(convo) //nolint:wrapcheck
+}
+
+// trashConversation moves a local conversation to the trash, so a delete
+// made on another machine can still be undone here.
+func trashConversation(store Store, id string) error {
+	if err := store.Cache.Trash(id); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err //nolint:wrapcheck
+	}
+	return store.DB.Delete(id) //nolint:wrapcheck
+}
+
+// purgeConversation removes a conversation from the remote copy for good;
+// the remote keeps no trash.
+func purgeConversation(store Store, id string) error {
+	if err := store.Cache.Delete(id); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err //nolint:wrapcheck
+	}
+	return store.DB.Purge(id) //nolint:wrapcheck
+}
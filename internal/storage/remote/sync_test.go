@@ -0,0 +1,175 @@
+package remote
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// host is one machine syncing through a shared git remote.
+type host struct {
+	t      *testing.T
+	syncer *Syncer
+}
+
+func newHost(t *testing.T, remote string) *host {
+	t.Helper()
+	dir := t.TempDir()
+	local, err := OpenStore(dir)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = local.DB.Close() })
+	backend, err := New(Git, remote)
+	require.NoError(t, err)
+	return &host{t: t, syncer: &Syncer{
+		Backend: backend,
+		Dir:     filepath.Join(dir, "sync", Git),
+		Local:   local,
+	}}
+}
+
+func (h *host) save(id, title string, when time.Time) {
+	h.t.Helper()
+	messages := []proto.Message{{Role: proto.RoleUser, Content: title}}
+	require.NoError(h.t, h.syncer.Local.Cache.Write(id, &messages))
+	require.NoError(h.t, h.syncer.Local.DB.Put(storage.Conversation{ID: id, Title: title, UpdatedAt: when}))
+}
+
+func (h *host) sync() Summary {
+	h.t.Helper()
+	summary, err := h.syncer.Run(context.Background())
+	require.NoError(h.t, err)
+	return summary
+}
+
+func (h *host) titles() map[string]string {
+	titles := map[string]string{}
+	for _, c := range h.syncer.Local.DB.List() {
+		var messages []proto.Message
+		require.NoError(h.t, h.syncer.Local.Cache.Read(c.ID, &messages))
+		require.Equal(h.t, c.Title, messages[0].Content)
+		titles[c.ID] = c.Title
+	}
+	return titles
+}
+
+func newGitRemote(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(t.TempDir(), "gitconfig"))
+	t.Setenv("GIT_AUTHOR_NAME", "yai")
+	t.Setenv("GIT_AUTHOR_EMAIL", "yai@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "yai")
+	t.Setenv("GIT_COMMITTER_EMAIL", "yai@example.com")
+	remote := filepath.Join(t.TempDir(), "history.git")
+	require.NoError(t, exec.Command("git", "init", "--quiet", "--bare", remote).Run())
+	return remote
+}
+
+func TestSyncer(t *testing.T) {
+	t0 := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("syncs between hosts", func(t *testing.T) {
+		remote := newGitRemote(t)
+		a, b := newHost(t, remote), newHost(t, remote)
+
+		a.save("aaaa", "from a", t0)
+		require.Equal(t, 1, a.sync().Count(Push))
+
+		require.Equal(t, 1, b.sync().Count(Pull))
+		require.Equal(t, map[string]string{"aaaa": "from a"}, b.titles())
+
+		b.save("aaaa", "edited on b", t0.Add(time.Hour))
+		b.save("bbbb", "from b", t0)
+		require.Equal(t, 2, b.sync().Count(Push))
+
+		require.Equal(t, 2, a.sync().Count(Pull))
+		require.Equal(t, map[string]string{"aaaa": "edited on b", "bbbb": "from b"}, a.titles())
+
+		require.Empty(t, a.sync().Changes)
+	})
+
+	t.Run("most recent update wins", func(t *testing.T) {
+		remote := newGitRemote(t)
+		a, b := newHost(t, remote), newHost(t, remote)
+
+		a.save("aaaa", "first", t0)
+		a.sync()
+		b.sync()
+
+		b.save("aaaa", "later on b", t0.Add(2*time.Hour))
+		a.save("aaaa", "earlier on a", t0.Add(time.Hour))
+		b.sync()
+		summary := a.sync()
+		require.Equal(t, []Change{{"aaaa", Pull}}, summary.Changes)
+		require.Equal(t, map[string]string{"aaaa": "later on b"}, a.titles())
+	})
+
+	t.Run("propagates deletes", func(t *testing.T) {
+		remote := newGitRemote(t)
+		a, b := newHost(t, remote), newHost(t, remote)
+
+		a.save("aaaa", "doomed", t0)
+		a.save("bbbb", "kept", t0)
+		a.sync()
+		b.sync()
+
+		require.NoError(t, a.syncer.Local.DB.Delete("aaaa"))
+		require.NoError(t, a.syncer.Local.Cache.Delete("aaaa"))
+		require.Equal(t, 1, a.sync().Count(DeleteRemote))
+
+		require.Equal(t, 1, b.sync().Count(DeleteLocal))
+		require.Equal(t, map[string]string{"bbbb": "kept"}, b.titles())
+
+		// The other machine keeps the conversation in its trash.
+		trash := b.syncer.Local.DB.Trash()
+		require.Len(t, trash, 1)
+		require.Equal(t, "aaaa", trash[0].ID)
+		require.NoError(t, b.syncer.Local.Cache.Restore("aaaa"))
+	})
+
+	t.Run("skips conversations in use", func(t *testing.T) {
+		remote := newGitRemote(t)
+		a, b := newHost(t, remote), newHost(t, remote)
+
+		a.save("aaaa", "first", t0)
+		a.sync()
+		b.sync()
+		a.save("aaaa", "second", t0.Add(time.Hour))
+		a.sync()
+
+		unlock, err := b.syncer.Local.Cache.Lock("aaaa")
+		require.NoError(t, err)
+		require.Equal(t, []string{"aaaa"}, b.sync().Skipped)
+		require.Equal(t, map[string]string{"aaaa": "first"}, b.titles())
+
+		unlock()
+		require.Empty(t, b.sync().Skipped)
+		require.Equal(t, map[string]string{"aaaa": "second"}, b.titles())
+	})
+
+	t.Run("dry run changes nothing", func(t *testing.T) {
+		remote := newGitRemote(t)
+		a := newHost(t, remote)
+		a.save("aaaa", "first", t0)
+
+		a.syncer.DryRun = true
+		require.Equal(t, []Change{{"aaaa", Push}}, a.sync().Changes)
+		a.syncer.DryRun = false
+		require.Equal(t, []Change{{"aaaa", Push}}, a.sync().Changes)
+	})
+}
+
+func TestNew(t *testing.T) {
+	_, err := New("ftp", "example.com")
+	require.ErrorIs(t, err, ErrUnknownBackend)
+	_, err = New(Git, "")
+	require.Error(t, err)
+}
@@ -0,0 +1,17 @@
+// Package robustio renames and removes files the way the storage needs on
+// every platform. On Windows, a file another process has open, such as the
+// index a second yai is reading, cannot be replaced or removed for a moment;
+// those operations are retried there instead of failing.
+package robustio
+
+import "os"
+
+// Rename renames oldpath to newpath, replacing newpath if it exists.
+func Rename(oldpath, newpath string) error {
+	return retry(func() error { return os.Rename(oldpath, newpath) })
+}
+
+// Remove removes the named file or empty directory.
+func Remove(name string) error {
+	return retry(func() error { return os.Remove(name) })
+}
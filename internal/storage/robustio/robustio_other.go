@@ -0,0 +1,7 @@
+//go:build !windows
+
+package robustio
+
+func retry(fn func() error) error {
+	return fn()
+}
@@ -0,0 +1,24 @@
+package robustio
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameReplaces(t *testing.T) {
+	dir := t.TempDir()
+	from, to := filepath.Join(dir, ".tmp-1"), filepath.Join(dir, "index.json")
+	require.NoError(t, os.WriteFile(to, []byte("old"), 0o600))
+	require.NoError(t, os.WriteFile(from, []byte("new"), 0o600))
+
+	require.NoError(t, Rename(from, to))
+	data, err := os.ReadFile(to)
+	require.NoError(t, err)
+	require.Equal(t, "new", string(data))
+
+	require.NoError(t, Remove(to))
+	require.ErrorIs(t, Remove(to), os.ErrNotExist, "a missing file is not retried")
+}
@@ -0,0 +1,40 @@
+package robustio
+
+import (
+	"errors"
+	"syscall"
+	"time"
+)
+
+// retryFor is how long an operation on a file in use is retried.
+const retryFor = 500 * time.Millisecond
+
+// Errors returned while another process has the file open.
+const (
+	errorAccessDenied     syscall.Errno = 5
+	errorSharingViolation syscall.Errno = 32
+	errorLockViolation    syscall.Errno = 33
+)
+
+// retry runs fn until it succeeds, fails for another reason than the file
+// being in use, or retryFor passed.
+func retry(fn func() error) error {
+	deadline := time.Now().Add(retryFor)
+	delay := time.Millisecond
+	for {
+		err := fn()
+		if err == nil || !inUse(err) || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(delay)
+		delay = min(2*delay, 50*time.Millisecond)
+	}
+}
+
+func inUse(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	return errno == errorAccessDenied || errno == errorSharingViolation || errno == errorLockViolation
+}
@@ -41,6 +41,12 @@ type Stream interface {
 
 	// drains provider/model warnings collected during streaming
 	DrainWarnings() []string
+
+	// token usage accumulated across all steps so far
+	Usage() proto.Usage
+
+	// the provider's request ID and model version of the latest step
+	ResponseMeta() proto.ResponseMeta
 }
 
 // CallTool calls a tool using the provided data and caller, and returns the
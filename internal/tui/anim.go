@@ -1,13 +1,18 @@
 package tui
 
 import (
+	"cmp"
+	"fmt"
 	"math/rand"
+	"slices"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/dotcommander/yai/internal/config"
 	"github.com/dotcommander/yai/internal/present"
 	"github.com/muesli/termenv"
 )
@@ -15,9 +20,86 @@ import (
 const (
 	charCyclingFPS  = time.Second / 22
 	colorCycleFPS   = time.Second / 5
+	labelFPS        = time.Second
 	maxCyclingChars = 120
 )
 
+// Spinner styles, the values of --spinner.
+const (
+	// SpinnerCycling is the default: characters that cycle in the theme's
+	// gradient, as many as fanciness, before the status text.
+	SpinnerCycling = "cycling"
+	SpinnerDots    = "dots"
+	SpinnerPulse   = "pulse"
+	SpinnerBraille = "braille"
+	// SpinnerNone shows the status text alone.
+	SpinnerNone = "none"
+)
+
+// SpinnerStyles are the spinner styles, the default first.
+var SpinnerStyles = []string{SpinnerCycling, SpinnerDots, SpinnerPulse, SpinnerBraille, SpinnerNone} //nolint:gochecknoglobals
+
+var spinners = map[string]spinner.Spinner{ //nolint:gochecknoglobals
+	SpinnerDots:    spinner.Points,
+	SpinnerPulse:   spinner.Pulse,
+	SpinnerBraille: spinner.MiniDot,
+}
+
+// CheckSpinner reports an unknown spinner style, or a status text that is
+// not a valid template.
+func CheckSpinner(style, statusText string) error {
+	if style != "" && !slices.Contains(SpinnerStyles, style) {
+		return fmt.Errorf("unknown spinner %q; pick one of %s", style, strings.Join(SpinnerStyles, ", "))
+	}
+	if _, err := parseStatusText(statusText); err != nil {
+		return fmt.Errorf("status text: %w", err)
+	}
+	return nil
+}
+
+// parseStatusText parses the status text, a template that can use
+// {{.model}} and {{.elapsed}}.
+func parseStatusText(text string) (*template.Template, error) {
+	tmpl, err := template.New("status-text").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	if err := tmpl.Execute(&strings.Builder{}, statusData("", 0)); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	return tmpl, nil
+}
+
+func statusData(model string, elapsed time.Duration) map[string]string {
+	return map[string]string{"model": model, "elapsed": formatElapsedClock(elapsed)}
+}
+
+// statusText is the text shown while waiting, with its placeholders filled
+// in.
+type statusText struct {
+	tmpl  *template.Template
+	text  string
+	model string
+}
+
+func newStatusText(text, model string) statusText {
+	tmpl, _ := parseStatusText(text)
+	return statusText{tmpl: tmpl, text: text, model: model}
+}
+
+// render fills in the status text elapsed after the request started. A
+// text that is not a valid template is shown as it is.
+func (s statusText) render(elapsed time.Duration) string {
+	if s.tmpl == nil {
+		return s.text
+	}
+	var b strings.Builder
+	if s.tmpl.Execute(&b, statusData(s.model, elapsed)) != nil {
+		return s.text
+	}
+	return b.String()
+}
+
 var charRunes = []rune("0123456789abcdefABCDEF~!@#$£€%^&*()+=_")
 
 type charState int
@@ -67,34 +149,58 @@ func cycleColors() tea.Cmd {
 	})
 }
 
+// labelTickMsg redraws a status text without an animation, so the elapsed
+// time it shows keeps up.
+type labelTickMsg struct{}
+
+func tickLabel() tea.Cmd {
+	return tea.Tick(labelFPS, func(time.Time) tea.Msg {
+		return labelTickMsg{}
+	})
+}
+
 // anim is the model that manages the animation that displays while the
 // output is being generated.
 type anim struct {
 	start           time.Time
+	style           string
+	status          statusText
+	gap             string
 	cyclingChars    []cyclingChar
 	labelChars      []cyclingChar
 	ramp            []lipgloss.Style
 	label           []rune
+	spinner         spinner.Model
 	ellipsis        spinner.Model
 	ellipsisStarted bool
 	styles          present.Styles
 }
 
-func newAnim(cyclingCharsSize uint, label string, r *lipgloss.Renderer, s present.Styles) anim {
-	// #nosec G115
-	n := min(int(cyclingCharsSize), maxCyclingChars)
-
-	gap := " "
-	if n == 0 {
-		gap = ""
-	}
-
+// newAnim makes the animation cfg asks for: its spinner style, fanciness,
+// and status text, for a request to its model.
+func newAnim(cfg *config.Config, r *lipgloss.Renderer, s present.Styles) anim {
+	style := cmp.Or(cfg.Spinner, SpinnerCycling)
 	c := anim{
 		start:    time.Now(),
-		label:    []rune(gap + label),
+		style:    style,
+		status:   newStatusText(cfg.StatusText, cfg.Model),
 		ellipsis: spinner.New(spinner.WithSpinner(spinner.Ellipsis)),
 		styles:   s,
 	}
+	if sp, ok := spinners[style]; ok {
+		c.spinner = spinner.New(spinner.WithSpinner(sp), spinner.WithStyle(s.CyclingChars))
+		c.gap = " "
+	}
+	if style != SpinnerCycling {
+		return c
+	}
+
+	// #nosec G115
+	n := min(int(cfg.Fanciness), maxCyclingChars)
+	if n > 0 {
+		c.gap = " "
+	}
+	c.label = []rune(c.gap + c.status.render(0))
 
 	// If we're in truecolor mode (and there are enough cycling characters)
 	// color the cycling characters with a gradient ramp.
@@ -143,12 +249,30 @@ func newAnim(cyclingCharsSize uint, label string, r *lipgloss.Renderer, s presen
 }
 
 // Init initializes the animation.
-func (anim) Init() tea.Cmd {
-	return tea.Batch(stepChars(), cycleColors())
+func (a anim) Init() tea.Cmd {
+	switch a.style {
+	case SpinnerCycling:
+		return tea.Batch(stepChars(), cycleColors())
+	case SpinnerNone:
+		return tickLabel()
+	}
+	return a.spinner.Tick
 }
 
 // Update handles messages.
 func (a anim) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if a.style != SpinnerCycling {
+		switch msg.(type) {
+		case labelTickMsg:
+			return a, tickLabel()
+		case spinner.TickMsg:
+			var cmd tea.Cmd
+			a.spinner, cmd = a.spinner.Update(msg)
+			return a, cmd
+		}
+		return a, nil
+	}
+
 	var cmd tea.Cmd
 	switch msg.(type) {
 	case stepCharsMsg:
@@ -204,6 +328,13 @@ func (a *anim) updateChars(chars *[]cyclingChar) {
 
 // View renders the animation.
 func (a anim) View() string {
+	if a.style == SpinnerNone {
+		return a.status.render(time.Since(a.start))
+	}
+	if a.style != SpinnerCycling {
+		return a.spinner.View() + a.gap + a.status.render(time.Since(a.start))
+	}
+
 	var b strings.Builder
 
 	for i, c := range a.cyclingChars {
@@ -214,8 +345,14 @@ func (a anim) View() string {
 		b.WriteRune(c.currentValue)
 	}
 
-	for _, c := range a.labelChars {
-		b.WriteRune(c.currentValue)
+	// Once the label stopped cycling, it is drawn afresh, so the elapsed
+	// time in it keeps up.
+	if a.ellipsisStarted {
+		b.WriteString(a.gap + a.status.render(time.Since(a.start)))
+	} else {
+		for _, c := range a.labelChars {
+			b.WriteRune(c.currentValue)
+		}
 	}
 
 	return b.String() + a.ellipsis.View()
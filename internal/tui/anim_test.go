@@ -0,0 +1,54 @@
+package tui
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/present/presenttest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSpinner(t *testing.T) {
+	for _, style := range append([]string{""}, SpinnerStyles...) {
+		require.NoError(t, CheckSpinner(style, "Generating"), style)
+	}
+	require.ErrorContains(t, CheckSpinner("disco", "Generating"), `unknown spinner "disco"`)
+	require.NoError(t, CheckSpinner("", "Asking {{.model}} ({{.elapsed}})"))
+	require.Error(t, CheckSpinner("", "{{.model"))
+	require.Error(t, CheckSpinner("", "{{.tokens}}"))
+}
+
+func TestStatusText(t *testing.T) {
+	s := newStatusText("Asking {{.model}} ({{.elapsed}})", "gpt-5")
+	require.Equal(t, "Asking gpt-5 (01:05)", s.render(65*time.Second))
+	require.Equal(t, "Generating", newStatusText("Generating", "gpt-5").render(0))
+	require.Equal(t, "{{.model", newStatusText("{{.model", "gpt-5").render(0))
+}
+
+func TestAnimStyles(t *testing.T) {
+	r := lipgloss.NewRenderer(io.Discard)
+	cfg := &config.Config{}
+	cfg.Model = "gpt-5"
+	cfg.StatusText = "Asking {{.model}}"
+	cfg.Fanciness = 10
+
+	cfg.Spinner = SpinnerNone
+	a := newAnim(cfg, r, presenttest.Styles())
+	require.Equal(t, "Asking gpt-5", a.View())
+
+	cfg.Spinner = SpinnerBraille
+	a = newAnim(cfg, r, presenttest.Styles())
+	require.Equal(t, "⠋ Asking gpt-5", ansi.Strip(a.View()))
+
+	cfg.Spinner = ""
+	a = newAnim(cfg, r, presenttest.Styles())
+	require.Len(t, a.cyclingChars, 10)
+	require.Equal(t, " Asking gpt-5", string(a.label))
+	a.ellipsisStarted = true
+	require.True(t, strings.HasSuffix(a.View(), " Asking gpt-5"), a.View())
+}
@@ -0,0 +1,111 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/mcp"
+	"github.com/dotcommander/yai/internal/present"
+)
+
+// approvalMsg is a question about a completion an MCP server asks for, or
+// about a tool result that looks like a prompt injection, waiting for the
+// user's answer on reply.
+type approvalMsg struct {
+	question string
+	reply    chan bool
+}
+
+// approvals relays the questions that come up while the stream command
+// waits for the tools to the program.
+type approvals chan approvalMsg
+
+// newApprovals returns the approvals of a program, or nil when there is
+// nothing to ask or no one to ask: sampling requests are then declined, and
+// tool results that look like prompt injections withheld.
+func newApprovals(cfg *config.Config) approvals {
+	ask := cfg.MCPSampling == mcp.SamplingAsk || cfg.ToolInjection == agent.InjectionAsk
+	if !ask || !present.IsInputTTY() || cfg.Raw {
+		return nil
+	}
+	return make(approvals)
+}
+
+// ask returns the user's answer to question. A question given up on is
+// answered no.
+func (c approvals) ask(ctx context.Context, question string) bool {
+	msg := approvalMsg{question: question, reply: make(chan bool, 1)}
+	select {
+	case c <- msg:
+	case <-ctx.Done():
+		return false
+	}
+	select {
+	case ok := <-msg.reply:
+		return ok
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// wait returns the next question. Run it again after each answer.
+func (c approvals) wait() tea.Cmd {
+	return func() tea.Msg {
+		return <-c
+	}
+}
+
+// with returns ctx asking c about sampling requests and tool results, if
+// it asks.
+func (c approvals) with(ctx context.Context) context.Context {
+	if c == nil {
+		return ctx
+	}
+	ctx = agent.WithSamplingApproval(ctx, func(ctx context.Context, req agent.SamplingRequest) bool {
+		return c.ask(ctx, samplingQuestion(req))
+	})
+	return agent.WithInjectionApproval(ctx, func(ctx context.Context, req agent.InjectionRequest) bool {
+		return c.ask(ctx, injectionQuestion(req))
+	})
+}
+
+// approvalAnswer reads the answer to a question from key: y allows, n, Esc,
+// and Enter decline.
+func approvalAnswer(key tea.KeyMsg) (allow, ok bool) {
+	switch key.String() {
+	case "y", "Y":
+		return true, true
+	case "n", "N", "esc", "enter":
+		return false, true
+	}
+	return false, false
+}
+
+const maxApprovalPreview = 60
+
+// approvalPreview is the first line of text, cut short.
+func approvalPreview(text string) string {
+	preview, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	if r := []rune(preview); len(r) > maxApprovalPreview {
+		preview = string(r[:maxApprovalPreview]) + "…"
+	}
+	return preview
+}
+
+// samplingQuestion is what the user is asked about req.
+func samplingQuestion(req agent.SamplingRequest) string {
+	preview := approvalPreview(req.Messages[len(req.Messages)-1].Content)
+	return fmt.Sprintf("%s asks the model (up to %d tokens): %q. Allow? [y/N]", req.Server, req.MaxTokens, preview)
+}
+
+// injectionQuestion is what the user is asked about req.
+func injectionQuestion(req agent.InjectionRequest) string {
+	return fmt.Sprintf(
+		"Tool %s returned text that looks like a prompt injection (%s): %q. Pass it on to the model? [y/N]",
+		req.Tool, req.Why, approvalPreview(req.Content),
+	)
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"time"
 	"unicode"
@@ -12,7 +13,6 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/dotcommander/yai/internal/agent"
 	"github.com/dotcommander/yai/internal/config"
@@ -32,6 +32,10 @@ const (
 // SaveFn persists conversation messages after each turn.
 type SaveFn func([]proto.Message) error
 
+// ExportFn writes the conversation to a file in the given format ("md" or
+// "json") and returns its path.
+type ExportFn func(format string, messages []proto.Message) (string, error)
+
 // Chat is the Bubble Tea model for an interactive multi-turn REPL.
 type Chat struct {
 	Error *errs.Error
@@ -39,7 +43,7 @@ type Chat struct {
 	state    chatState
 	input    textinput.Model
 	viewport viewport.Model
-	glam     *glamour.TermRenderer
+	glam     markdown
 	renderer *lipgloss.Renderer
 	styles   present.Styles
 	anim     tea.Model
@@ -47,13 +51,23 @@ type Chat struct {
 	history         []proto.Message
 	historyBuf      bytes.Buffer // rendered conversation so far
 	renderedHistory string       // Glamour-rendered cache of historyBuf
+	renderedUpTo    int          // bytes of historyBuf in renderedHistory
 	streamBuf       bytes.Buffer // current response being streamed
 	activeStream    stream.Stream
 	activeCancel    context.CancelFunc
+	// pending holds the request messages of the turn being streamed.
+	pending []proto.Message
+
+	// fittedHistory is renderedHistory wrapped at fittedWidth columns; it is
+	// stale when fittedStale is set.
+	fittedHistory string
+	fittedWidth   int
+	fittedStale   bool
 
 	agent         *agent.Service
 	startStreamFn func(context.Context, []proto.Message, string) (agent.StreamStart, error)
 	saveFn        SaveFn
+	exportFn      ExportFn
 	cfg           *config.Config
 	ctx           context.Context
 
@@ -63,29 +77,57 @@ type Chat struct {
 	renderScheduled bool
 	dirtyOutput     bool
 	stopWarned      bool
-	retries         int
+	retries         *agent.RetryBudget
 	initialPrompt   string
 	waitingSince    time.Time
+
+	picker       *modelPicker
+	search       *chatSearch
+	content      string // viewport content, without search highlights
+	recentModels []ModelChoice
+
+	resolved       config.Model // settings of modelFor
+	modelFor       *ModelChoice
+	contextWarned  bool
+	confirming     string      // prompt waiting for a second Enter
+	confirmedModel ModelChoice // model the last turn was sent to
+	snippets       []string    // snippet texts to put before the next prompt
+	savedTemp      *float64    // temperature to restore after a /retry --temp turn
+
+	// usage is what the provider reported for the session, and tools the
+	// number of tools offered to the model on the last turn.
+	usage proto.Usage
+	tools int
+	// toolStatus is the last progress report of the running tool, shown
+	// in place of the input.
+	toolProgress toolProgress
+	toolStatus   string
+	// approval is the question waiting for the user's answer,
+	// shown in place of the input.
+	approvals approvals
+	approval  *approvalMsg
 }
 
 type ChatOptions struct {
-	Context       context.Context
-	Renderer      *lipgloss.Renderer
-	Config        *config.Config
-	Agent         *agent.Service
-	StartStream   func(context.Context, []proto.Message, string) (agent.StreamStart, error)
-	History       []proto.Message
-	Save          SaveFn
+	Context     context.Context
+	Renderer    *lipgloss.Renderer
+	Config      *config.Config
+	Agent       *agent.Service
+	StartStream func(context.Context, []proto.Message, string) (agent.StreamStart, error)
+	History     []proto.Message
+	Save        SaveFn
+	// Export handles /export; the command is unavailable when it is nil.
+	Export        ExportFn
 	InitialPrompt string
+	// Snippets are put before the first prompt.
+	Snippets []string
+	// RecentModels are listed first in the Ctrl+P model picker, most recent
+	// first.
+	RecentModels []ModelChoice
 }
 
 // NewChat creates the Bubble Tea model for interactive chat.
 func NewChat(opts ChatOptions) *Chat {
-	gr, _ := glamour.NewTermRenderer(
-		glamour.WithEnvironmentConfig(),
-		glamour.WithWordWrap(opts.Config.WordWrap),
-	)
-
 	ti := textinput.New()
 	ti.Prompt = "yai> "
 	ti.Focus()
@@ -98,40 +140,57 @@ func NewChat(opts ChatOptions) *Chat {
 		state:         chatInputState,
 		input:         ti,
 		viewport:      vp,
-		glam:          gr,
+		glam:          newMarkdown(opts.Renderer, opts.Config.WordWrap),
 		renderer:      opts.Renderer,
 		styles:        present.MakeStyles(opts.Renderer),
 		agent:         opts.Agent,
 		saveFn:        opts.Save,
+		exportFn:      opts.Export,
 		cfg:           opts.Config,
 		ctx:           opts.Context,
 		history:       opts.History,
 		startStreamFn: opts.StartStream,
 		initialPrompt: opts.InitialPrompt,
+		recentModels:  opts.RecentModels,
+		snippets:      opts.Snippets,
+		retries:       agent.NewRetryBudget(opts.Config),
 	}
 
 	// Pre-render existing history into historyBuf.
-	if len(opts.History) > 0 {
-		for _, msg := range opts.History {
-			if msg.Role == proto.RoleSystem || msg.Content == "" {
-				continue
-			}
-			switch msg.Role {
-			case proto.RoleUser:
-				fmt.Fprintf(&c.historyBuf, "> %s\n\n", msg.Content)
-			case proto.RoleAssistant:
-				fmt.Fprintf(&c.historyBuf, "%s\n\n", msg.Content)
-			}
+	c.writeHistory()
+
+	return c
+}
+
+// writeHistory writes the conversation so far to historyBuf, replacing the
+// transcript, and renders it.
+func (c *Chat) writeHistory() {
+	c.historyBuf.Reset()
+	c.rerenderHistory()
+	for _, msg := range c.history {
+		if msg.Role == proto.RoleSystem || msg.Content == "" {
+			continue
 		}
-		if c.historyBuf.Len() > 0 {
-			rendered, err := gr.Render(c.historyBuf.String())
-			if err == nil {
-				c.renderedHistory = strings.TrimRightFunc(rendered, unicode.IsSpace)
-			}
+		switch msg.Role {
+		case proto.RoleUser:
+			fmt.Fprintf(&c.historyBuf, "> %s\n\n", msg.Content)
+		case proto.RoleAssistant:
+			fmt.Fprintf(&c.historyBuf, "%s\n\n", msg.Content)
+			c.writeTiming(msg)
 		}
 	}
+	c.renderHistory()
+}
 
-	return c
+// writeTiming notes when an answer finished and how long it took, with
+// chat-timings set.
+func (c *Chat) writeTiming(msg proto.Message) {
+	if !c.cfg.ChatTimings {
+		return
+	}
+	if timing := msg.Timing(time.TimeOnly); timing != "" {
+		fmt.Fprintf(&c.historyBuf, "*%s*\n\n", timing)
+	}
 }
 
 // chatSubmitMsg is sent when the user presses Enter with non-empty input.
@@ -149,6 +208,8 @@ type chatStreamChunkMsg struct {
 // chatStreamDoneMsg signals the stream is complete.
 type chatStreamDoneMsg struct {
 	messages []proto.Message
+	meta     proto.ResponseMeta
+	usage    proto.Usage
 }
 
 type chatRenderMsg struct{}
@@ -158,13 +219,11 @@ type chatWaitingTickMsg struct{}
 // Init implements tea.Model.
 func (c *Chat) Init() tea.Cmd {
 	cmds := []tea.Cmd{textinput.Blink}
-	if !c.cfg.Quiet {
-		c.anim = newAnim(c.cfg.Fanciness, c.cfg.StatusText, c.renderer, c.styles)
-		cmds = append(cmds, c.anim.Init())
-	}
 	if c.initialPrompt != "" {
+		prompt := c.withSnippets(c.initialPrompt)
+		c.snippets = nil
 		cmds = append(cmds, func() tea.Msg {
-			return chatSubmitMsg{prompt: c.initialPrompt}
+			return chatSubmitMsg{prompt: prompt}
 		})
 	}
 	return tea.Batch(cmds...)
@@ -178,6 +237,10 @@ func (c *Chat) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		c.width = msg.Width
 		c.height = msg.Height
+		if c.glam.resize(c.width) {
+			c.rerenderHistory()
+			c.renderHistory()
+		}
 		c.resizeViewport()
 		c.refreshViewport()
 		return c, nil
@@ -190,12 +253,31 @@ func (c *Chat) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case chatSubmitMsg:
 		return c.handleSubmit(msg)
 
+	case chatEditedMsg:
+		return c, c.handleEdited(msg)
+
 	case chatStreamChunkMsg:
 		return c.handleStreamChunk(msg)
 
 	case chatStreamDoneMsg:
 		return c.handleStreamDone(msg)
 
+	case toolProgressMsg:
+		if c.state == chatStreamState {
+			c.toolStatus = proto.ToolProgress(msg).String()
+			c.resizeViewport()
+		}
+		return c, c.toolProgress.wait()
+
+	case approvalMsg:
+		if c.state != chatStreamState {
+			msg.reply <- false
+			return c, c.approvals.wait()
+		}
+		c.approval = &msg
+		c.resizeViewport()
+		return c, nil
+
 	case chatWaitingTickMsg:
 		if c.state == chatStreamState && c.streamBuf.Len() == 0 {
 			return c, c.waitingTickCmd()
@@ -212,11 +294,15 @@ func (c *Chat) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case errs.Error:
 		e := msg
 		c.Error = &e
+		if !agent.Rejected(e) {
+			c.keepPartial()
+		}
 		return c, tea.Quit
 
 	case error:
 		e := errs.Error{Err: msg}
 		c.Error = &e
+		c.keepPartial()
 		return c, tea.Quit
 	}
 
@@ -240,15 +326,65 @@ func (c *Chat) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (c *Chat) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	if c.picker != nil {
+		if done, choice := c.picker.update(msg); done {
+			c.picker = nil
+			if choice != nil {
+				c.switchModel(*choice)
+			}
+		}
+		return c, nil, true
+	}
+	if c.search != nil {
+		return c, c.handleSearchKey(msg), true
+	}
+	if c.approval != nil {
+		if allow, ok := approvalAnswer(msg); ok {
+			c.approval.reply <- allow
+			c.approval = nil
+			c.resizeViewport()
+			return c, c.approvals.wait(), true
+		}
+	}
+
 	switch msg.String() {
+	case "ctrl+f":
+		if c.state != chatInputState {
+			return c, nil, false
+		}
+		c.search = newChatSearch()
+		c.input.Blur()
+		return c, textinput.Blink, true
+	case "ctrl+p":
+		if c.state != chatInputState {
+			return c, nil, false
+		}
+		c.picker = newModelPicker(c.cfg, c.recentModels)
+		return c, nil, true
+	case "ctrl+r":
+		if c.state != chatInputState {
+			return c, nil, false
+		}
+		return c, c.regenerate(""), true
 	case "ctrl+c":
 		if c.state == chatStreamState {
+			var cmd tea.Cmd
+			if c.approval != nil {
+				c.approval.reply <- false
+				c.approval = nil
+				cmd = c.approvals.wait()
+			}
 			c.closeActiveStream()
+			c.restoreTemp()
 			c.waitingSince = time.Time{}
-			c.finishTurn()
+			if c.keepPartial() {
+				c.note("Interrupted; type /resume to finish the answer")
+			} else {
+				c.finishTurn()
+			}
 			c.state = chatInputState
 			c.resizeViewport()
-			return c, nil, true
+			return c, cmd, true
 		}
 		return c, tea.Quit, true
 	case "enter":
@@ -262,6 +398,56 @@ func (c *Chat) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
 		if text == "/exit" || text == "/quit" {
 			return c, tea.Quit, true
 		}
+		if text == "/resume" {
+			c.input.SetValue("")
+			if !agent.CanResume(c.history) {
+				c.note("Nothing to resume")
+				return c, nil, true
+			}
+			return c, func() tea.Msg {
+				return chatSubmitMsg{prompt: agent.ResumePrompt}
+			}, true
+		}
+		if cmd, args, _ := strings.Cut(text, " "); cmd == "/edit" {
+			c.input.SetValue("")
+			return c, c.editLast(args), true
+		}
+		if cmd, args, _ := strings.Cut(text, " "); cmd == "/retry" {
+			c.input.SetValue("")
+			return c, c.regenerate(args), true
+		}
+		if cmd, args, _ := strings.Cut(text, " "); cmd == "/model" || cmd == "/api" {
+			c.input.SetValue("")
+			if cmd == "/model" && strings.TrimSpace(args) == "" {
+				c.picker = newModelPicker(c.cfg, c.recentModels)
+				return c, nil, true
+			}
+			c.switchTo(cmd, strings.TrimSpace(args))
+			return c, nil, true
+		}
+		if cmd, args, _ := strings.Cut(text, " "); cmd == "/export" {
+			c.input.SetValue("")
+			c.export(strings.TrimSpace(args))
+			return c, nil, true
+		}
+		if cmd, args, _ := strings.Cut(text, " "); cmd == "/snippet" {
+			name, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+			rest = strings.TrimSpace(rest)
+			c.input.SetValue(rest)
+			if !c.stageSnippet(name) || rest == "" {
+				return c, nil, true
+			}
+			text = rest
+		}
+		text = c.withSnippets(text)
+		if c.needsConfirm(text) && c.confirming != text {
+			c.confirming = text
+			c.note(c.costPreview(text) + ". Press Enter again to send, or edit the prompt")
+			return c, nil, true
+		}
+		c.confirming = ""
+		c.confirmedModel = ModelChoice{API: c.cfg.API, Model: c.cfg.Model}
+		c.snippets = nil
 		c.input.SetValue("")
 		return c, func() tea.Msg {
 			return chatSubmitMsg{prompt: text}
@@ -270,22 +456,131 @@ func (c *Chat) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
 	return c, nil, false
 }
 
+// switchModel makes choice the model for the following turns and notes the
+// switch in the transcript. A saved conversation records the switch right
+// away, so continuing it later uses the new model.
+func (c *Chat) switchModel(choice ModelChoice) {
+	if choice == (ModelChoice{API: c.cfg.API, Model: c.cfg.Model}) {
+		c.note(fmt.Sprintf("Already using %s", choice))
+		return
+	}
+	c.cfg.API = choice.API
+	c.cfg.Model = choice.Model
+	c.cfg.FallbackFrom = ""
+	c.recentModels = slices.Insert(slices.DeleteFunc(c.recentModels, func(m ModelChoice) bool {
+		return m == choice
+	}), 0, choice)
+
+	c.note(fmt.Sprintf("Switched to %s", choice))
+	if len(c.history) > 0 {
+		c.save()
+	}
+}
+
+// stageSnippet reads the snippet name to put before the next prompt, noting
+// the result in the transcript. Without a name, it lists the snippets.
+func (c *Chat) stageSnippet(name string) bool {
+	if name == "" {
+		names, err := config.ListSnippets(c.cfg)
+		switch {
+		case err != nil:
+			c.note(fmt.Sprintf("Could not list snippets: %v", err))
+		case len(names) == 0:
+			c.note(fmt.Sprintf("No snippets yet; save them as .md or .txt files in %s", config.SnippetsDir(c.cfg)))
+		default:
+			c.note("Snippets: " + strings.Join(names, ", "))
+		}
+		return false
+	}
+	text, err := config.ReadSnippet(c.cfg, name)
+	if err != nil {
+		c.note(fmt.Sprintf("Could not insert snippet: %v", err))
+		return false
+	}
+	if !slices.Contains(c.snippets, text) {
+		c.snippets = append(c.snippets, text)
+	}
+	c.note(fmt.Sprintf("Snippet %s will be added to your next prompt", name))
+	return true
+}
+
+// withSnippets returns prompt after the staged snippets.
+func (c *Chat) withSnippets(prompt string) string {
+	if len(c.snippets) == 0 {
+		return prompt
+	}
+	return strings.Join(append(slices.Clip(c.snippets), prompt), "\n\n")
+}
+
+// export writes the conversation so far with exportFn and notes the result
+// in the transcript.
+func (c *Chat) export(format string) {
+	switch format {
+	case "", "md", "markdown":
+		format = "md"
+	case "json":
+	default:
+		c.note(fmt.Sprintf("Unknown export format %q; use md or json", format))
+		return
+	}
+	switch {
+	case c.exportFn == nil:
+		c.note("Export is not available")
+	case len(c.history) == 0:
+		c.note("Nothing to export yet")
+	default:
+		path, err := c.exportFn(format, c.history)
+		if err != nil {
+			c.note("Export failed: " + err.Error())
+			return
+		}
+		c.note("Exported to " + path)
+	}
+}
+
+// note adds an italic status line to the transcript.
+func (c *Chat) note(text string) {
+	fmt.Fprintf(&c.historyBuf, "*%s*\n\n", text)
+	c.renderHistory()
+	c.refreshViewport()
+}
+
 func (c *Chat) handleSubmit(msg chatSubmitMsg) (tea.Model, tea.Cmd) {
-	c.retries = 0
-	fmt.Fprintf(&c.historyBuf, "> %s\n\n", msg.prompt)
+	c.retries = agent.NewRetryBudget(c.cfg)
+	if msg.prompt != agent.ResumePrompt {
+		fmt.Fprintf(&c.historyBuf, "> %s\n\n", msg.prompt)
+	}
 	c.streamBuf.Reset()
 	c.waitingSince = time.Now()
 	c.state = chatStreamState
+	cmds := []tea.Cmd{c.startStreamCmd(msg.prompt), c.waitingTickCmd()}
+	if c.toolProgress == nil {
+		c.toolProgress = newToolProgress()
+		cmds = append(cmds, c.toolProgress.wait())
+		if c.approvals = newApprovals(c.cfg); c.approvals != nil {
+			cmds = append(cmds, c.approvals.wait())
+		}
+	}
+	if !c.cfg.Quiet {
+		// A fresh animation for each request, so its elapsed time and model
+		// are this request's.
+		c.anim = newAnim(c.cfg, c.renderer, c.styles)
+		cmds = append(cmds, c.anim.Init())
+	}
 	c.resizeViewport()
 	c.dirtyOutput = true
 	c.refreshViewport()
-	return c, tea.Batch(c.startStreamCmd(msg.prompt), c.waitingTickCmd())
+	return c, tea.Batch(cmds...)
 }
 
 func (c *Chat) handleStreamChunk(msg chatStreamChunkMsg) (tea.Model, tea.Cmd) {
 	if msg.stream == nil {
 		return c, nil
 	}
+	if c.toolStatus != "" {
+		c.toolStatus = ""
+		c.resizeViewport()
+	}
 
 	var cmds []tea.Cmd
 	if msg.content != "" {
@@ -310,12 +605,17 @@ func (c *Chat) handleStreamChunk(msg chatStreamChunkMsg) (tea.Model, tea.Cmd) {
 }
 
 func (c *Chat) handleStreamDone(msg chatStreamDoneMsg) (tea.Model, tea.Cmd) {
-	c.history = msg.messages
+	c.history = agent.MergeResumed(msg.messages)
+	recordResponseMeta(c.cfg, msg.meta)
+	c.usage = c.usage.Add(msg.usage)
+	c.restoreTemp()
 	c.waitingSince = time.Time{}
+	c.toolStatus = ""
 	c.finishTurn()
 	c.state = chatInputState
 	c.resizeViewport()
 	c.refreshViewport()
+	c.warnContext()
 	return c, nil
 }
 
@@ -325,10 +625,22 @@ func (c *Chat) View() string {
 		return ""
 	}
 
-	divider := c.styles.Comment.Render(strings.Repeat("─", max(c.width, 1)))
+	divider := c.divider()
 
 	var content string
-	if c.state == chatStreamState && c.streamBuf.Len() == 0 {
+	switch {
+	case c.picker != nil:
+		overlay := c.renderer.NewStyle().
+			Height(c.viewport.Height).
+			MaxHeight(c.viewport.Height).
+			MaxWidth(c.width).
+			Render(c.picker.view(c.styles, c.viewport.Height))
+		content = overlay + "\n" + divider + "\n" + c.input.View()
+	case c.state == chatStreamState && c.approval != nil:
+		content = c.viewport.View() + "\n" + divider + "\n" + c.styles.Comment.Render(c.approval.question)
+	case c.state == chatStreamState && c.toolStatus != "":
+		content = c.viewport.View() + "\n" + divider + "\n" + c.styles.Comment.Render(c.toolStatus)
+	case c.state == chatStreamState && c.streamBuf.Len() == 0:
 		status := c.waitingStatus(time.Now())
 		if !c.cfg.Quiet && c.anim != nil {
 			// Show explicit waiting status plus animation while waiting for first chunk.
@@ -336,7 +648,9 @@ func (c *Chat) View() string {
 		} else {
 			content = c.viewport.View() + "\n" + divider + "\n" + status
 		}
-	} else {
+	case c.search != nil:
+		content = c.viewport.View() + "\n" + divider + "\n" + c.searchView()
+	default:
 		content = c.viewport.View() + "\n" + divider + "\n" + c.input.View()
 	}
 
@@ -364,12 +678,14 @@ func (c *Chat) startStreamCmd(prompt string) tea.Cmd {
 			func(cancel context.CancelFunc) { c.activeCancel = cancel },
 			func(st stream.Stream) { c.activeStream = st },
 			func(ctx context.Context) (agent.StreamStart, error) {
-				return c.startStreamFn(ctx, c.history, prompt)
+				return c.startStreamFn(c.approvals.with(agent.WithToolProgress(ctx, c.toolProgress.report)), c.history, prompt)
 			},
 		)
 		if err != nil {
 			return streamStartErrorMsg(err)
 		}
+		c.pending = res.Messages
+		c.tools = res.Tools
 		mod := res.Model
 
 		warnIgnoredStop(c.cfg.Stop, c.cfg.Quiet, &c.stopWarned, c.emitWarning)
@@ -390,20 +706,25 @@ func (c *Chat) receiveStreamCmd(msg chatStreamChunkMsg) tea.Cmd {
 		func(content string, st stream.Stream, errh func(error) tea.Msg) tea.Msg {
 			return chatStreamChunkMsg{content: content, stream: st, errh: errh}
 		},
+		func(results []proto.ToolCallStatus, st stream.Stream, errh func(error) tea.Msg) tea.Msg {
+			return chatStreamChunkMsg{content: toolCallsContent(results), stream: st, errh: errh}
+		},
 		func(messages []proto.Message) tea.Msg {
-			return chatStreamDoneMsg{messages: messages}
+			return chatStreamDoneMsg{messages: messages, meta: msg.stream.ResponseMeta(), usage: msg.stream.Usage()}
 		},
 	)
 }
 
 func (c *Chat) handleStreamError(err error, mod config.Model, prompt string) tea.Msg {
 	return handleRetryableStreamError(c.agent, c.cfg.NoLimit, func(model string) {
-		c.cfg.Model = model
+		agent.UseFallback(c.cfg, model)
+		fmt.Fprintf(&c.historyBuf, "*%s is not available, %s answers instead*\n\n", mod.Name, model)
+		c.renderHistory()
 	}, c.retry, err, mod, prompt)
 }
 
-func (c *Chat) retry(err errs.Error, content string) tea.Msg {
-	return retryOrFail(c.ctx, &c.retries, c.cfg.MaxRetries, err, content, func(s string) tea.Msg {
+func (c *Chat) retry(action agent.StreamErrorAction, content string) tea.Msg {
+	return retryOrFail(c.ctx, c.retries, action, content, func(s string) tea.Msg {
 		return chatSubmitMsg{prompt: s}
 	})
 }
@@ -413,17 +734,16 @@ func (c *Chat) finishTurn() {
 	if c.streamBuf.Len() > 0 {
 		fmt.Fprintf(&c.historyBuf, "%s\n\n", c.streamBuf.String())
 		c.streamBuf.Reset()
-	}
-	// Cache rendered history so refreshViewport only renders the stream portion.
-	if c.historyBuf.Len() > 0 {
-		rendered, err := c.glam.Render(c.historyBuf.String())
-		if err == nil {
-			c.renderedHistory = strings.TrimRightFunc(rendered, unicode.IsSpace)
+		if n := len(c.history); n > 0 && c.history[n-1].Role == proto.RoleAssistant {
+			c.writeTiming(c.history[n-1])
 		}
 	}
-	c.dirtyOutput = true
+	c.renderHistory()
+	c.save()
+}
 
-	// Persist to cache.
+// save persists the conversation, with the model it uses now.
+func (c *Chat) save() {
 	if c.saveFn != nil {
 		if err := c.saveFn(c.history); err != nil {
 			fmt.Fprintln(os.Stderr, c.styles.Comment.Render("Warning: failed to save conversation: "+err.Error()))
@@ -431,6 +751,52 @@ func (c *Chat) finishTurn() {
 	}
 }
 
+// keepPartial ends a turn cut off mid-response, keeping what was streamed
+// as an unfinished answer that /resume can pick up. It reports whether
+// there was anything to keep.
+func (c *Chat) keepPartial() bool {
+	if c.state != chatStreamState || c.streamBuf.Len() == 0 || c.pending == nil {
+		return false
+	}
+	c.history = agent.MergeResumed(agent.WithPartial(c.pending, c.streamBuf.String()))
+	c.pending = nil
+	c.finishTurn()
+	return true
+}
+
+// renderHistory renders what was written to historyBuf since the last call
+// and adds it to renderedHistory, so each finished block is rendered once
+// however long the conversation gets.
+func (c *Chat) renderHistory() {
+	if c.historyBuf.Len() > c.renderedUpTo {
+		rendered, err := c.glam.Render(c.historyBuf.String()[c.renderedUpTo:])
+		if err == nil {
+			c.renderedHistory = appendRendered(c.renderedHistory, rendered)
+			c.renderedUpTo = c.historyBuf.Len()
+			c.fittedStale = true
+		}
+	}
+	c.dirtyOutput = true
+}
+
+// rerenderHistory drops what was rendered of historyBuf, so the next
+// renderHistory renders all of it again.
+func (c *Chat) rerenderHistory() {
+	c.renderedHistory = ""
+	c.renderedUpTo = 0
+	c.fittedStale = true
+}
+
+// appendRendered adds block, rendered on its own, to the rendered text
+// before it, spaced as if they had been rendered together.
+func appendRendered(rendered, block string) string {
+	block = strings.TrimRightFunc(block, unicode.IsSpace)
+	if rendered == "" {
+		return block
+	}
+	return rendered + "\n" + strings.TrimLeft(block, "\n")
+}
+
 func (c *Chat) closeActiveStream() {
 	closeStream(c.activeStream, c.activeCancel)
 	c.activeStream = nil
@@ -446,32 +812,37 @@ func (c *Chat) refreshViewport() {
 		return
 	}
 
-	var rendered string
+	// Only the block being streamed is rendered on each tick; the history
+	// is wrapped to the window width again only when it or the width
+	// changed.
+	if c.fittedStale || c.fittedWidth != c.width {
+		c.fittedHistory = present.Wrap(c.renderedHistory, c.width)
+		c.fittedWidth = c.width
+		c.fittedStale = false
+	}
+	out := c.fittedHistory
 	if c.streamBuf.Len() > 0 {
 		streamRendered, err := c.glam.Render(c.streamBuf.String())
 		if err != nil {
 			streamRendered = c.streamBuf.String()
 		}
-		streamRendered = strings.TrimRightFunc(streamRendered, unicode.IsSpace)
-		if c.renderedHistory != "" {
-			rendered = c.renderedHistory + "\n" + streamRendered
-		} else {
-			rendered = streamRendered
-		}
-	} else {
-		rendered = c.renderedHistory
+		out = appendRendered(out, present.Wrap(streamRendered, c.width))
 	}
 
-	if rendered == "" {
+	if out == "" {
 		return
 	}
-	rendered += "\n"
-
-	truncated := c.renderer.NewStyle().MaxWidth(c.width).Render(rendered)
+	out += "\n"
 
 	wasAtBottom := c.viewport.ScrollPercent() >= 1.0
-	c.viewport.SetContent(truncated)
-	if wasAtBottom {
+	c.content = out
+	if c.search != nil {
+		c.search.find(out)
+		c.showMatch()
+	} else {
+		c.viewport.SetContent(out)
+	}
+	if wasAtBottom && c.search == nil {
 		c.viewport.GotoBottom()
 	}
 	c.dirtyOutput = false
@@ -491,7 +862,7 @@ func (c *Chat) waitingTickCmd() tea.Cmd {
 }
 
 func (c *Chat) footerLineCount() int {
-	if c.state == chatStreamState && c.streamBuf.Len() == 0 {
+	if c.state == chatStreamState && c.streamBuf.Len() == 0 && c.toolStatus == "" && c.approval == nil {
 		if !c.cfg.Quiet && c.anim != nil {
 			return 3
 		}
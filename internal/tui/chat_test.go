@@ -2,12 +2,15 @@ package tui
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/dotcommander/yai/internal/agent"
 	"github.com/dotcommander/yai/internal/config"
 	"github.com/dotcommander/yai/internal/proto"
 )
@@ -66,6 +69,119 @@ func TestChat_QuitCommand(t *testing.T) {
 	}
 }
 
+func TestChat_ExportCommand(t *testing.T) {
+	var formats []string
+	c := newTestChat(func(c *Chat) {
+		c.history = []proto.Message{
+			{Role: proto.RoleUser, Content: "hi"},
+			{Role: proto.RoleAssistant, Content: "hello"},
+		}
+		c.exportFn = func(format string, msgs []proto.Message) (string, error) {
+			if len(msgs) != 2 {
+				t.Errorf("expected 2 messages, got %d", len(msgs))
+			}
+			formats = append(formats, format)
+			return "hi-2026-01-02." + format, nil
+		}
+	})
+
+	for _, input := range []string{"/export", "/export json", "/export pdf"} {
+		c.input.SetValue(input)
+		_, cmd := c.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		if cmd != nil {
+			t.Fatalf("%s: expected no command, got %T", input, cmd())
+		}
+		if c.input.Value() != "" {
+			t.Errorf("%s: expected the input to be cleared", input)
+		}
+	}
+
+	if strings.Join(formats, ",") != "md,json" {
+		t.Errorf("expected md and json exports, got %v", formats)
+	}
+	transcript := c.historyBuf.String()
+	for _, want := range []string{"Exported to hi-2026-01-02.md", "Exported to hi-2026-01-02.json", `Unknown export format "pdf"`} {
+		if !strings.Contains(transcript, want) {
+			t.Errorf("expected transcript to contain %q, got %q", want, transcript)
+		}
+	}
+}
+
+func TestChat_SnippetCommand(t *testing.T) {
+	c := newTestChat(func(c *Chat) {
+		c.cfg.SettingsPath = filepath.Join(t.TempDir(), "yai.yml")
+	})
+
+	c.input.SetValue("/snippet")
+	c.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !strings.Contains(c.historyBuf.String(), "No snippets yet") {
+		t.Errorf("expected a note that there are no snippets, got %q", c.historyBuf.String())
+	}
+
+	dir := config.SnippetsDir(c.cfg)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bug.md"), []byte("Steps to reproduce:\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c.input.SetValue("/snippet nope")
+	c.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !strings.Contains(c.historyBuf.String(), "unknown snippet") {
+		t.Errorf("expected a note about the unknown snippet, got %q", c.historyBuf.String())
+	}
+
+	c.input.SetValue("/snippet bug")
+	_, cmd := c.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil {
+		t.Fatalf("expected no request when staging a snippet, got %T", cmd())
+	}
+
+	c.input.SetValue("the login page hangs")
+	_, cmd = c.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("expected a command to submit the prompt")
+	}
+	msg, ok := cmd().(chatSubmitMsg)
+	if !ok || msg.prompt != "Steps to reproduce:\n\nthe login page hangs" {
+		t.Fatalf("expected the snippet before the prompt, got %#v", msg)
+	}
+	if len(c.snippets) != 0 {
+		t.Error("expected the snippet to be used once")
+	}
+
+	c.input.SetValue("/snippet bug it crashes")
+	_, cmd = c.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("expected a command to submit the prompt")
+	}
+	if msg, _ := cmd().(chatSubmitMsg); msg.prompt != "Steps to reproduce:\n\nit crashes" {
+		t.Fatalf("expected the snippet before the prompt, got %#v", msg)
+	}
+}
+
+func TestChat_InitialPromptSnippets(t *testing.T) {
+	c := newTestChat(func(c *Chat) {
+		c.initialPrompt = "the login page hangs"
+		c.snippets = []string{"Steps to reproduce:"}
+	})
+
+	var submitted string
+	batch, _ := c.Init()().(tea.BatchMsg)
+	for _, cmd := range batch {
+		if submit, ok := cmd().(chatSubmitMsg); ok {
+			submitted = submit.prompt
+		}
+	}
+	if submitted != "Steps to reproduce:\n\nthe login page hangs" {
+		t.Errorf("expected the snippet before the initial prompt, got %q", submitted)
+	}
+	if len(c.snippets) != 0 {
+		t.Error("expected the snippet to be used once")
+	}
+}
+
 func TestChat_CtrlC_InputState(t *testing.T) {
 	c := newTestChat()
 
@@ -97,6 +213,64 @@ func TestChat_CtrlC_StreamState(t *testing.T) {
 	}
 }
 
+func TestChat_CtrlC_KeepsPartialAnswer(t *testing.T) {
+	var saved []proto.Message
+	c := newTestChat(func(c *Chat) {
+		c.saveFn = func(msgs []proto.Message) error {
+			saved = msgs
+			return nil
+		}
+	})
+	c.state = chatStreamState
+	c.pending = []proto.Message{{Role: proto.RoleUser, Content: "count to ten"}}
+	c.streamBuf.WriteString("one, two, ")
+
+	c.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+
+	if !agent.CanResume(saved) || saved[1].Content != "one, two, " {
+		t.Fatalf("expected the partial answer to be saved, got %+v", saved)
+	}
+	if !strings.Contains(c.historyBuf.String(), "/resume") {
+		t.Error("expected a note pointing at /resume")
+	}
+}
+
+func TestChat_ResumeCommand(t *testing.T) {
+	c := newTestChat()
+
+	c.input.SetValue("/resume")
+	_, cmd := c.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil {
+		t.Error("expected no request when there is nothing to resume")
+	}
+	if !strings.Contains(c.historyBuf.String(), "Nothing to resume") {
+		t.Error("expected a note that there is nothing to resume")
+	}
+
+	c.history = agent.WithPartial([]proto.Message{{Role: proto.RoleUser, Content: "count to ten"}}, "one, two, ")
+	c.input.SetValue("/resume")
+	_, cmd = c.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("expected a command to resume")
+	}
+	msg, ok := cmd().(chatSubmitMsg)
+	if !ok || msg.prompt != agent.ResumePrompt {
+		t.Fatalf("expected the resume prompt to be submitted, got %#v", msg)
+	}
+	c.Update(msg)
+	if strings.Contains(c.historyBuf.String(), agent.ResumePrompt) {
+		t.Error("the resume prompt should not be echoed in the transcript")
+	}
+
+	c.Update(chatStreamDoneMsg{messages: append(c.history,
+		proto.Message{Role: proto.RoleUser, Content: agent.ResumePrompt},
+		proto.Message{Role: proto.RoleAssistant, Content: "three."},
+	)})
+	if len(c.history) != 2 || c.history[1].Content != "one, two, three." || c.history[1].Partial {
+		t.Errorf("expected the resumed answer to be merged, got %+v", c.history)
+	}
+}
+
 func TestChat_EmptyInput_Ignored(t *testing.T) {
 	c := newTestChat()
 
@@ -164,6 +338,75 @@ func TestChat_FinishTurn_CallsSaveFn(t *testing.T) {
 	}
 }
 
+func TestChat_ReflowsOnResize(t *testing.T) {
+	c := newTestChat()
+	answer := "the quick brown fox jumps over the lazy dog 你好世界 again and again"
+	c.historyBuf.WriteString(answer + "\n\n")
+	c.renderHistory()
+	c.refreshViewport()
+
+	c.Update(tea.WindowSizeMsg{Width: 20, Height: 24})
+	if c.glam.wrap != 20 {
+		t.Fatalf("expected markdown wrapped at the window width, got %d", c.glam.wrap)
+	}
+	var words []string
+	for line := range strings.SplitSeq(c.content, "\n") {
+		if lipgloss.Width(line) > 20 {
+			t.Fatalf("expected lines of at most 20 columns, got %q", line)
+		}
+		words = append(words, strings.Fields(line)...)
+	}
+	if got := strings.Join(words, " "); got != answer {
+		t.Errorf("expected every word kept after the resize, got %q", got)
+	}
+
+	c.Update(tea.WindowSizeMsg{Width: 120, Height: 24})
+	if c.glam.wrap != 80 {
+		t.Errorf("expected word-wrap to cap the width again, got %d", c.glam.wrap)
+	}
+}
+
+func TestChat_RendersHistoryIncrementally(t *testing.T) {
+	c := newTestChat()
+	c.width = 80
+
+	c.historyBuf.WriteString("> first prompt\n\n")
+	c.renderHistory()
+	first := c.renderedHistory
+	c.historyBuf.WriteString("first answer\n\n")
+	c.renderHistory()
+	if !strings.HasPrefix(c.renderedHistory, first) || !strings.Contains(c.renderedHistory, "first answer") {
+		t.Fatalf("expected the new block after the rendered one, got %q", c.renderedHistory)
+	}
+	if c.renderedUpTo != c.historyBuf.Len() {
+		t.Errorf("expected all of the history to be rendered, got %d of %d bytes", c.renderedUpTo, c.historyBuf.Len())
+	}
+
+	c.refreshViewport()
+	fitted := c.fittedHistory
+	c.streamBuf.WriteString("streaming")
+	c.refreshViewport()
+	if c.fittedHistory != fitted {
+		t.Error("expected streaming not to refit the history")
+	}
+	if !strings.Contains(c.content, "first answer") || !strings.Contains(c.content, "streaming") {
+		t.Errorf("expected the history and the streamed block, got %q", c.content)
+	}
+
+	c.width = 10
+	c.refreshViewport()
+	for line := range strings.SplitSeq(c.content, "\n") {
+		if lipgloss.Width(line) > 10 {
+			t.Fatalf("expected the content wrapped to the new width, got line %q", line)
+		}
+	}
+
+	c.writeHistory()
+	if c.renderedUpTo != 0 || c.renderedHistory != "" {
+		t.Error("expected rewriting the history to drop the rendered cache")
+	}
+}
+
 func TestChat_StreamDone_ReturnsToInput(t *testing.T) {
 	c := newTestChat()
 	c.state = chatStreamState
@@ -218,3 +461,199 @@ func TestChat_WaitingStatusIncludesElapsedClock(t *testing.T) {
 		t.Fatalf("expected stopwatch in waiting status, got: %q", status)
 	}
 }
+
+func TestChat_ContextStatus(t *testing.T) {
+	c := newTestChat(func(c *Chat) {
+		c.cfg.MaxInputChars = 1000
+	})
+
+	c.history = []proto.Message{{Role: proto.RoleUser, Content: strings.Repeat("x", 100)}}
+	c.input.SetValue(strings.Repeat("y", 20))
+	if got := c.inputUsage(); got != 120 {
+		t.Errorf("expected usage 120, got %d", got)
+	}
+	if status := c.contextStatus(); !strings.Contains(status, "context 120/1.0k chars (12%)") {
+		t.Errorf("unexpected status %q", status)
+	}
+	if !strings.Contains(c.View(), "(12%)") {
+		t.Error("expected the status in the divider")
+	}
+
+	c.input.SetValue("")
+	c.handleStreamDone(chatStreamDoneMsg{messages: []proto.Message{
+		{Role: proto.RoleUser, Content: strings.Repeat("x", 500)},
+		{Role: proto.RoleAssistant, Content: strings.Repeat("z", 300)},
+	}})
+	if !strings.Contains(c.historyBuf.String(), "Context is 80% full") {
+		t.Errorf("expected a context warning, got %q", c.historyBuf.String())
+	}
+
+	c.cfg.NoLimit = true
+	if status := c.contextStatus(); status != "" {
+		t.Errorf("expected no status without a limit, got %q", status)
+	}
+}
+
+func TestChat_ContextStatusTokens(t *testing.T) {
+	c := newTestChat(func(c *Chat) {
+		c.cfg.MaxInputChars = 1000
+		c.cfg.API = "openai"
+		c.cfg.Model = "gpt-4o"
+		c.cfg.APIs = config.APIs{{Name: "openai", Models: map[string]config.Model{"gpt-4o": {ContextWindow: 800}}}}
+	})
+
+	c.history = []proto.Message{{Role: proto.RoleUser, Content: strings.Repeat("x", 100)}}
+	c.input.SetValue(strings.Repeat("y", 20))
+	// An eighth of the window is left for the answer.
+	if status := c.contextStatus(); !strings.Contains(status, "context 30/700 tokens (4%)") {
+		t.Errorf("unexpected status %q", status)
+	}
+}
+
+func TestChat_SessionStatus(t *testing.T) {
+	c := newTestChat(func(c *Chat) {
+		c.cfg.API = "openai"
+		c.cfg.Model = "gpt-4o"
+		c.cfg.Role = "shell"
+	})
+
+	if got := c.sessionStatus(80); got != "openai/gpt-4o · role shell" {
+		t.Errorf("unexpected status %q", got)
+	}
+
+	c.tools = 3
+	c.handleStreamDone(chatStreamDoneMsg{
+		messages: []proto.Message{{Role: proto.RoleUser, Content: "hi"}, {Role: proto.RoleAssistant, Content: "hello"}},
+		usage:    proto.Usage{InputTokens: 1000, OutputTokens: 200, TotalTokens: 1200},
+	})
+	c.handleStreamDone(chatStreamDoneMsg{
+		messages: []proto.Message{{Role: proto.RoleUser, Content: "hi"}, {Role: proto.RoleAssistant, Content: "hello"}},
+		usage:    proto.Usage{InputTokens: 1200, OutputTokens: 100, TotalTokens: 1300},
+	})
+	want := "openai/gpt-4o · role shell · 2.5k tokens · 3 tools"
+	if got := c.sessionStatus(80); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if !strings.Contains(c.View(), "openai/gpt-4o") {
+		t.Error("expected the status in the divider")
+	}
+
+	if got := c.sessionStatus(len("openai/gpt-4o · role shell")); got != "openai/gpt-4o · role shell" {
+		t.Errorf("expected the status to drop parts to fit, got %q", got)
+	}
+	if got := c.sessionStatus(5); got != "" {
+		t.Errorf("expected no status when nothing fits, got %q", got)
+	}
+}
+
+func TestFormatChars(t *testing.T) {
+	for n, want := range map[int64]string{
+		999:       "999",
+		1500:      "1.5k",
+		128000:    "128k",
+		2_500_000: "2.5M",
+	} {
+		if got := formatCompact(n); got != want {
+			t.Errorf("formatCompact(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestChat_ConfirmExpensiveTurn(t *testing.T) {
+	c := newTestChat(func(c *Chat) {
+		c.cfg.ConfirmTokens = 10
+	})
+
+	c.input.SetValue("short")
+	if _, cmd := c.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil {
+		t.Fatal("expected a cheap prompt to be sent right away")
+	}
+
+	long := strings.Repeat("word ", 20)
+	c.input.SetValue(long)
+	if _, cmd := c.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil {
+		t.Fatal("expected the first Enter to ask for confirmation")
+	}
+	if !strings.Contains(c.historyBuf.String(), "About to send ~25 input tokens. Press Enter again") {
+		t.Errorf("expected a cost preview, got %q", c.historyBuf.String())
+	}
+	if c.input.Value() != long {
+		t.Error("expected the prompt to stay in the input")
+	}
+
+	_, cmd := c.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("expected the second Enter to send")
+	}
+	if msg, ok := cmd().(chatSubmitMsg); !ok || msg.prompt != strings.TrimSpace(long) {
+		t.Errorf("expected the prompt to be submitted, got %#v", msg)
+	}
+}
+
+func TestChat_ConfirmModel(t *testing.T) {
+	c := newTestChat(func(c *Chat) {
+		c.cfg.API = "openai"
+		c.cfg.Model = "test"
+		c.cfg.APIs = config.APIs{{Name: "openai", Models: map[string]config.Model{"test": {}}}}
+		c.cfg.ConfirmModels = []string{"test"}
+	})
+
+	c.input.SetValue("hi")
+	if _, cmd := c.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil {
+		t.Fatal("expected the first turn to a listed model to ask for confirmation")
+	}
+	if !strings.Contains(c.historyBuf.String(), "input tokens to test. Press Enter again") {
+		t.Errorf("expected the preview to name the model, got %q", c.historyBuf.String())
+	}
+	if _, cmd := c.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil {
+		t.Fatal("expected the second Enter to send")
+	}
+
+	c.input.SetValue("again")
+	if _, cmd := c.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil {
+		t.Fatal("expected later turns to the same model to be sent right away")
+	}
+}
+
+func TestChat_CostPreview(t *testing.T) {
+	c := newTestChat(func(c *Chat) {
+		c.cfg.API = "openai"
+		c.cfg.Model = "test"
+		c.cfg.APIs = config.APIs{{Name: "openai", Models: map[string]config.Model{"test": {InputCost: 2}}}}
+		c.history = []proto.Message{{Role: proto.RoleUser, Content: strings.Repeat("x", 3_999_996)}}
+	})
+	if got := c.costPreview("four"); got != "About to send ~1.0M input tokens (~$2.0000)" {
+		t.Errorf("unexpected preview %q", got)
+	}
+}
+
+func TestChat_Timings(t *testing.T) {
+	c := newTestChat()
+	answer := proto.Message{
+		Role:       proto.RoleAssistant,
+		Content:    "hello",
+		Time:       time.Date(2026, 10, 16, 14, 3, 5, 0, time.Local),
+		Latency:    2 * time.Second,
+		FirstToken: 500 * time.Millisecond,
+	}
+	done := chatStreamDoneMsg{messages: []proto.Message{{Role: proto.RoleUser, Content: "hi"}, answer}}
+
+	c.streamBuf.WriteString("hello")
+	c.handleStreamDone(done)
+	if strings.Contains(c.historyBuf.String(), "first token") {
+		t.Errorf("expected no timings by default, got %q", c.historyBuf.String())
+	}
+
+	c.cfg.ChatTimings = true
+	c.streamBuf.WriteString("hello")
+	c.handleStreamDone(done)
+	want := "*14:03:05 · 2s · first token 500ms*"
+	if !strings.Contains(c.historyBuf.String(), want) {
+		t.Errorf("expected %q in the transcript, got %q", want, c.historyBuf.String())
+	}
+
+	c.writeHistory()
+	if !strings.Contains(c.historyBuf.String(), want) {
+		t.Errorf("expected %q after rewriting the transcript, got %q", want, c.historyBuf.String())
+	}
+}
@@ -0,0 +1,247 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/requestbuilder"
+)
+
+// Shares of the model's input limit at which the context status turns into
+// a warning, and then a bold one. From contextWarnAt on, requests cut the
+// oldest turns down with context-strategy to stay within the limit.
+const (
+	contextWarnAt   = 0.75
+	contextDangerAt = 0.9
+)
+
+// model returns the settings of the selected model, or the zero Model when
+// it is not in the settings.
+func (c *Chat) model() config.Model {
+	key := ModelChoice{API: c.cfg.API, Model: c.cfg.Model}
+	if c.modelFor == nil || *c.modelFor != key {
+		// ResolveModel rewrites aliases in the config it is given.
+		cfg := *c.cfg
+		_, c.resolved, _ = requestbuilder.ResolveModel(&cfg)
+		c.modelFor = &key
+	}
+	return c.resolved
+}
+
+// contextUnit is what the model's input limit counts: tokens when it has a
+// context-window, characters otherwise.
+func (c *Chat) contextUnit() string {
+	if requestbuilder.ContextBudget(c.cfg, c.model()) > 0 {
+		return "tokens"
+	}
+	return "chars"
+}
+
+// inputLimit returns the input size limit of the selected model in
+// contextUnit, or 0 when there is none.
+func (c *Chat) inputLimit() int64 {
+	if c.cfg.NoLimit {
+		return 0
+	}
+	if budget := requestbuilder.ContextBudget(c.cfg, c.model()); budget > 0 {
+		return budget
+	}
+	if limit := c.model().MaxChars; limit > 0 {
+		return limit
+	}
+	return c.cfg.MaxInputChars
+}
+
+// inputUsage estimates the size of the next request in contextUnit: the
+// conversation so far plus the prompt being typed.
+func (c *Chat) inputUsage() int64 {
+	if c.contextUnit() == "tokens" {
+		return c.turnTokens(c.input.Value())
+	}
+	n := int64(len(c.input.Value()))
+	for _, msg := range c.history {
+		n += int64(len(msg.Content))
+	}
+	return n
+}
+
+// contextShare returns how full the model's input is, or 0 when it has no
+// limit.
+func (c *Chat) contextShare() float64 {
+	limit := c.inputLimit()
+	if limit <= 0 {
+		return 0
+	}
+	return float64(c.inputUsage()) / float64(limit)
+}
+
+// contextStatus renders the estimated usage against the model's input
+// limit, colored as it fills up. It is empty when the model has no limit.
+func (c *Chat) contextStatus() string {
+	limit := c.inputLimit()
+	if limit <= 0 {
+		return ""
+	}
+	share := c.contextShare()
+	text := fmt.Sprintf("context %s/%s %s (%d%%)", formatCompact(c.inputUsage()), formatCompact(limit), c.contextUnit(), int(share*100))
+	var style lipgloss.Style
+	switch {
+	case share >= contextDangerAt:
+		style = c.styles.Warning.Bold(true)
+	case share >= contextWarnAt:
+		style = c.styles.Warning
+	default:
+		style = c.styles.Comment
+	}
+	return style.Render(text)
+}
+
+// divider renders the line above the input, with the session status at its
+// left end and the context status at its right end.
+func (c *Chat) divider() string {
+	width := max(c.width, 1)
+	fill := width
+	status := c.contextStatus()
+	if status != "" && lipgloss.Width(status)+4 <= width {
+		fill -= lipgloss.Width(status) + 3
+	} else {
+		status = ""
+	}
+	var sb strings.Builder
+	if session := c.sessionStatus(fill - 4); session != "" {
+		sb.WriteString(c.styles.Comment.Render("─ ") + session + c.styles.Comment.Render(" "))
+		fill -= lipgloss.Width(session) + 3
+	}
+	if status == "" {
+		sb.WriteString(c.styles.Comment.Render(strings.Repeat("─", fill)))
+		return sb.String()
+	}
+	sb.WriteString(c.styles.Comment.Render(strings.Repeat("─", fill)+" ") + status + c.styles.Comment.Render(" ─"))
+	return sb.String()
+}
+
+// sessionStatus renders who the chat is talking to, e.g. "openai/gpt-4o ·
+// role shell · 1.2k tokens · 3 tools": the model, the role, the tokens the
+// provider reported for the session so far, and the tools offered to the
+// model on the last turn. Parts are left out from the end to fit in width.
+func (c *Chat) sessionStatus(width int) string {
+	model := c.cfg.Model
+	if c.cfg.API != "" {
+		model = c.cfg.API + "/" + model
+	}
+	parts := []string{model}
+	if c.cfg.Role != "" {
+		parts = append(parts, "role "+c.cfg.Role)
+	}
+	tokens := c.usage.TotalTokens
+	if tokens == 0 {
+		tokens = c.usage.InputTokens + c.usage.OutputTokens
+	}
+	if tokens > 0 {
+		parts = append(parts, formatCompact(tokens)+" tokens")
+	}
+	switch {
+	case c.tools == 1:
+		parts = append(parts, "1 tool")
+	case c.tools > 1:
+		parts = append(parts, fmt.Sprintf("%d tools", c.tools))
+	}
+	for ; len(parts) > 0; parts = parts[:len(parts)-1] {
+		if text := strings.Join(parts, " · "); lipgloss.Width(text) <= width {
+			return c.styles.Comment.Render(text)
+		}
+	}
+	return ""
+}
+
+// warnContext notes in the transcript, once per crossing, that the
+// conversation outgrew the share of the limit requests keep in full.
+func (c *Chat) warnContext() {
+	full := c.contextShare() >= contextWarnAt
+	if full && !c.contextWarned {
+		next := "the oldest turns will be left out of the next requests"
+		switch c.cfg.ContextStrategy {
+		case agent.ContextSummarize:
+			next = "the oldest turns will be summarized in the next requests"
+		case agent.ContextError:
+			next = "start a new chat, as the next requests may be refused"
+		}
+		c.note(fmt.Sprintf("Context is %d%% full; %s", int(c.contextShare()*100), next))
+	}
+	c.contextWarned = full
+}
+
+// turnTokens estimates the input tokens of sending prompt after the
+// conversation so far.
+func (c *Chat) turnTokens(prompt string) int64 {
+	return proto.EstimateTokens(append(slices.Clip(c.history), proto.Message{Role: proto.RoleUser, Content: prompt}))
+}
+
+// turnCost estimates what sending prompt costs in input tokens, or returns
+// 0 when the model has no input-cost.
+func (c *Chat) turnCost(prompt string) float64 {
+	return float64(c.turnTokens(prompt)) * c.model().InputCost / 1_000_000
+}
+
+// needsConfirm reports whether prompt is above confirm-tokens or
+// confirm-cost, or is the first turn to a model in confirm-models.
+func (c *Chat) needsConfirm(prompt string) bool {
+	if c.cfg.Yes {
+		return false
+	}
+	if c.cfg.ConfirmsModel(c.model()) && c.confirmedModel != (ModelChoice{API: c.cfg.API, Model: c.cfg.Model}) {
+		return true
+	}
+	return NeedsConfirm(c.cfg, c.turnTokens(prompt), c.turnCost(prompt))
+}
+
+// costPreview describes the estimated input of sending prompt, e.g.
+// "About to send ~12k input tokens (~$0.0300)".
+func (c *Chat) costPreview(prompt string) string {
+	var model string
+	if mod := c.model(); c.cfg.ConfirmsModel(mod) {
+		model = mod.Name
+	}
+	return SendPreview(c.turnTokens(prompt), c.turnCost(prompt), model)
+}
+
+// NeedsConfirm reports whether a request of tokens estimated input tokens,
+// costing cost, is above confirm-tokens or confirm-cost.
+func NeedsConfirm(cfg *config.Config, tokens int64, cost float64) bool {
+	if cfg.ConfirmTokens > 0 && tokens > cfg.ConfirmTokens {
+		return true
+	}
+	return cfg.ConfirmCost > 0 && cost > cfg.ConfirmCost
+}
+
+// SendPreview describes a request about to be sent, e.g. "About to send
+// ~12k input tokens to gpt-4.5 (~$0.0300)". The model is left out when
+// empty, and the cost when it is 0.
+func SendPreview(tokens int64, cost float64, model string) string {
+	text := "About to send ~" + formatCompact(tokens) + " input tokens"
+	if model != "" {
+		text += " to " + model
+	}
+	if cost > 0 {
+		text += fmt.Sprintf(" (~$%.4f)", cost)
+	}
+	return text
+}
+
+func formatCompact(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 10_000:
+		return fmt.Sprintf("%dk", n/1000)
+	case n >= 1000:
+		return fmt.Sprintf("%.1fk", float64(n)/1000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
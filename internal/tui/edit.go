@@ -0,0 +1,121 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/editor"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/storage/robustio"
+)
+
+// chatEditedMsg is sent when the editor opened by /edit exits.
+type chatEditedMsg struct {
+	path string
+	turn int
+	err  error
+}
+
+// lastTurn returns the index of the last user message, or -1.
+func (c *Chat) lastTurn() int {
+	for i := len(c.history) - 1; i >= 0; i-- {
+		if c.history[i].Role == proto.RoleUser {
+			return i
+		}
+	}
+	return -1
+}
+
+// rewind drops the conversation from message i on.
+func (c *Chat) rewind(i int) {
+	c.history = slices.Clip(c.history[:i])
+	c.writeHistory()
+}
+
+// editLast handles /edit: the last prompt goes back into the input with
+// the turn removed, so it can be changed and sent again. Prompts of several
+// lines, or any prompt with "/edit -e", are opened in $EDITOR instead and
+// sent when it exits.
+func (c *Chat) editLast(args string) tea.Cmd {
+	useEditor := false
+	switch strings.TrimSpace(args) {
+	case "":
+	case "-e", "--editor":
+		useEditor = true
+	default:
+		c.note(fmt.Sprintf("Unknown /edit option %q; use /edit [-e]", args))
+		return nil
+	}
+	i := c.lastTurn()
+	if i < 0 {
+		c.note("Nothing to edit")
+		return nil
+	}
+	prompt := c.history[i].Content
+	if !useEditor && !strings.Contains(prompt, "\n") {
+		c.rewind(i)
+		c.input.SetValue(prompt)
+		c.input.CursorEnd()
+		c.note("Editing your last prompt; press Enter to send it again")
+		return nil
+	}
+	return c.openEditor(prompt, i)
+}
+
+func (c *Chat) openEditor(prompt string, turn int) tea.Cmd {
+	f, err := os.CreateTemp("", "yai-prompt-*.md")
+	if err != nil {
+		c.note(fmt.Sprintf("Could not open the editor: %v", err))
+		return nil
+	}
+	_, err = f.WriteString(prompt)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		c.note(fmt.Sprintf("Could not open the editor: %v", err))
+		return nil
+	}
+	cmd, err := editor.Cmd("yai", f.Name())
+	if err != nil {
+		_ = os.Remove(f.Name())
+		c.note(fmt.Sprintf("Could not open the editor: %v", err))
+		return nil
+	}
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return chatEditedMsg{path: f.Name(), turn: turn, err: err}
+	})
+}
+
+// handleEdited sends the prompt saved in the editor in place of the turn
+// it was opened for. An empty file, or an editor that failed, sends
+// nothing.
+func (c *Chat) handleEdited(msg chatEditedMsg) tea.Cmd {
+	data, err := os.ReadFile(msg.path)
+	// On Windows, the editor can still have the file open for a moment.
+	_ = robustio.Remove(msg.path)
+	if msg.err != nil {
+		err = msg.err
+	}
+	if err != nil {
+		c.note(fmt.Sprintf("Could not edit the prompt: %v", err))
+		return nil
+	}
+	prompt := strings.TrimSpace(string(data))
+	if prompt == "" {
+		c.note("The edited prompt is empty; nothing was sent")
+		return nil
+	}
+	if msg.turn >= len(c.history) || c.history[msg.turn].Role != proto.RoleUser {
+		c.note("The conversation changed while editing; nothing was sent")
+		return nil
+	}
+	c.rewind(msg.turn)
+	return func() tea.Msg {
+		return chatSubmitMsg{prompt: prompt}
+	}
+}
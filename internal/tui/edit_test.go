@@ -0,0 +1,98 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dotcommander/yai/internal/proto"
+)
+
+func newEditTestChat(prompt string) *Chat {
+	return newTestChat(func(c *Chat) {
+		c.history = []proto.Message{
+			{Role: proto.RoleUser, Content: "first"},
+			{Role: proto.RoleAssistant, Content: "one"},
+			{Role: proto.RoleUser, Content: prompt},
+			{Role: proto.RoleAssistant, Content: "two"},
+		}
+		c.writeHistory()
+	})
+}
+
+func TestChat_EditCommand(t *testing.T) {
+	c := newEditTestChat("second")
+
+	c.input.SetValue("/edit")
+	_, cmd := c.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil {
+		t.Fatalf("expected the prompt to go back into the input, got %T", cmd())
+	}
+	if c.input.Value() != "second" {
+		t.Errorf("expected the last prompt in the input, got %q", c.input.Value())
+	}
+	if len(c.history) != 2 || strings.Contains(c.historyBuf.String(), "two") {
+		t.Errorf("expected the last turn to be removed, got %+v", c.history)
+	}
+
+	c.input.SetValue("second, shorter")
+	_, cmd = c.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if msg, _ := cmd().(chatSubmitMsg); msg.prompt != "second, shorter" {
+		t.Errorf("expected the edited prompt to be sent, got %#v", msg)
+	}
+}
+
+func TestChat_EditCommand_Nothing(t *testing.T) {
+	c := newTestChat()
+
+	c.input.SetValue("/edit")
+	if _, cmd := c.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil {
+		t.Fatalf("expected no command, got %T", cmd())
+	}
+	if !strings.Contains(c.historyBuf.String(), "Nothing to edit") {
+		t.Errorf("expected a note that there is nothing to edit, got %q", c.historyBuf.String())
+	}
+}
+
+func TestChat_HandleEdited(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		path := filepath.Join(t.TempDir(), "prompt.md")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	t.Run("sends the edited prompt", func(t *testing.T) {
+		c := newEditTestChat("second\nline")
+		path := write(t, "second\nline, edited\n")
+
+		_, cmd := c.Update(chatEditedMsg{path: path, turn: 2})
+		if cmd == nil {
+			t.Fatal("expected the edited prompt to be sent")
+		}
+		if msg, _ := cmd().(chatSubmitMsg); msg.prompt != "second\nline, edited" {
+			t.Errorf("expected the edited prompt, got %#v", msg)
+		}
+		if len(c.history) != 2 {
+			t.Errorf("expected the edited turn to be removed, got %+v", c.history)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Error("expected the temporary file to be removed")
+		}
+	})
+
+	t.Run("empty file", func(t *testing.T) {
+		c := newEditTestChat("second\nline")
+
+		_, cmd := c.Update(chatEditedMsg{path: write(t, "\n"), turn: 2})
+		if cmd != nil {
+			t.Fatalf("expected nothing to be sent, got %T", cmd())
+		}
+		if len(c.history) != 4 {
+			t.Errorf("expected the conversation to be kept, got %+v", c.history)
+		}
+	})
+}
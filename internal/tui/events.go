@@ -0,0 +1,101 @@
+package tui
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/dotcommander/yai/internal/errs"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/stream"
+)
+
+// Types of the events written when Yai.JSONEvents is set.
+const (
+	eventChunk      = "chunk"
+	eventToolCall   = "tool_call"
+	eventToolResult = "tool_result"
+	eventProgress   = "progress"
+	eventWarning    = "warning"
+	eventRetry      = "retry"
+	eventUsage      = "usage"
+	eventLogprobs   = "logprobs"
+	eventError      = "error"
+	eventDone       = "done"
+)
+
+// event is one line of JSON output. Only the fields of its type are set:
+// content for chunks and tool results, id, name, and arguments for tool
+// calls and results, name and message for tool progress, message for
+// warnings, retries, and errors, usage for usage, logprobs for logprobs,
+// and the model and conversation for done.
+type event struct {
+	Type      string              `json:"type"`
+	Content   string              `json:"content,omitempty"`
+	ID        string              `json:"id,omitempty"`
+	Name      string              `json:"name,omitempty"`
+	Arguments json.RawMessage     `json:"arguments,omitempty"`
+	IsError   bool                `json:"is_error,omitempty"`
+	Message   string              `json:"message,omitempty"`
+	Usage     *proto.UsageJSON    `json:"usage,omitempty"`
+	Logprobs  []proto.LogprobJSON `json:"logprobs,omitempty"`
+
+	// Set on done.
+	API          string `json:"api,omitempty"`
+	Model        string `json:"model,omitempty"`
+	Conversation string `json:"conversation,omitempty"`
+	RequestID    string `json:"request_id,omitempty"`
+	ModelVersion string `json:"model_version,omitempty"`
+	Canceled     bool   `json:"canceled,omitempty"`
+}
+
+func writeEvent(w io.Writer, ev event) {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(ev)
+}
+
+// toolEvents returns a tool_call and a tool_result event for each of the
+// calls st just ran, whose messages are the last ones of st.
+func toolEvents(st stream.Stream, results []proto.ToolCallStatus) []event {
+	messages := st.Messages()
+	events := make([]event, 0, 2*len(results))
+	for i, status := range results {
+		call := event{Type: eventToolCall, Name: status.Name}
+		result := event{Type: eventToolResult, Name: status.Name, IsError: status.Err != nil}
+		if status.Err != nil {
+			result.Content = status.Err.Error()
+		}
+		if j := len(messages) - len(results) + i; j >= 0 {
+			if msg := messages[j]; msg.Role == proto.RoleTool && len(msg.ToolCalls) > 0 {
+				tc := msg.ToolCalls[0]
+				call.ID, result.ID = tc.ID, tc.ID
+				call.Arguments = jsonArguments(tc.Function.Arguments)
+				result.Content = msg.Content
+				result.IsError = result.IsError || tc.IsError
+			}
+		}
+		events = append(events, call, result)
+	}
+	return events
+}
+
+// jsonArguments returns tool call arguments as raw JSON, quoting them when
+// the model sent something else.
+func jsonArguments(args []byte) json.RawMessage {
+	if len(args) == 0 {
+		return nil
+	}
+	if json.Valid(args) {
+		return args
+	}
+	quoted, _ := json.Marshal(string(args))
+	return quoted
+}
+
+// errorText is the message of an error event.
+func errorText(e errs.Error) string {
+	if e.Reason == "" || e.Err == nil {
+		return e.Error()
+	}
+	return e.Reason + " " + e.Err.Error()
+}
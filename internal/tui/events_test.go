@@ -0,0 +1,57 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolEvents(t *testing.T) {
+	st := &fakeStream{messages: []proto.Message{
+		{Role: proto.RoleUser, Content: "what time is it?"},
+		{Role: proto.RoleTool, Content: "12:00", ToolCalls: []proto.ToolCall{{
+			ID:       "call-1",
+			Function: proto.Function{Name: "clock", Arguments: []byte(`{"tz":"UTC"}`)},
+		}}},
+		{Role: proto.RoleTool, Content: "not found", ToolCalls: []proto.ToolCall{{
+			ID:       "call-2",
+			IsError:  true,
+			Function: proto.Function{Name: "weather", Arguments: []byte("paris")},
+		}}},
+	}}
+	results := []proto.ToolCallStatus{{Name: "clock"}, {Name: "weather", Err: errors.New("not found")}}
+
+	require.Equal(t, []event{
+		{Type: eventToolCall, ID: "call-1", Name: "clock", Arguments: json.RawMessage(`{"tz":"UTC"}`)},
+		{Type: eventToolResult, ID: "call-1", Name: "clock", Content: "12:00"},
+		{Type: eventToolCall, ID: "call-2", Name: "weather", Arguments: json.RawMessage(`"paris"`)},
+		{Type: eventToolResult, ID: "call-2", Name: "weather", Content: "not found", IsError: true},
+	}, toolEvents(st, results))
+}
+
+func TestToolProgressEvents(t *testing.T) {
+	var out bytes.Buffer
+	m := &Yai{
+		Config:       &config.Config{},
+		Stdout:       &out,
+		JSONEvents:   true,
+		state:        responseState,
+		toolProgress: newToolProgress(),
+		contentMutex: &sync.Mutex{},
+	}
+	m.toolProgress.report(proto.ToolProgress{Name: "github_search_issues", Progress: 2, Total: 5})
+
+	_, cmd := m.Update(m.toolProgress.wait()())
+	require.NotNil(t, cmd, "waits for the next report")
+	require.Equal(t, "github_search_issues: 40%", m.toolStatus)
+	require.JSONEq(t, `{"type":"progress","name":"github_search_issues","message":"github_search_issues: 40%"}`, out.String())
+
+	m.Update(completionOutput{})
+	require.Empty(t, m.toolStatus, "the report is cleared once the tool returns")
+}
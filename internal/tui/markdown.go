@@ -0,0 +1,39 @@
+package tui
+
+import (
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dotcommander/yai/internal/present"
+)
+
+// markdown renders markdown wrapped at word-wrap, or at the width of the
+// window when that is narrower, so text streamed at one width is wrapped
+// again after a resize.
+type markdown struct {
+	*glamour.TermRenderer
+
+	renderer *lipgloss.Renderer
+	wordWrap int
+	wrap     int // the width TermRenderer wraps at
+}
+
+func newMarkdown(r *lipgloss.Renderer, wordWrap int) markdown {
+	gr, _ := present.NewMarkdownRenderer(wordWrap, present.MarkdownColors(r))
+	return markdown{TermRenderer: gr, renderer: r, wordWrap: wordWrap, wrap: wordWrap}
+}
+
+// resize wraps for a window width columns wide. It reports whether the
+// wrap width changed, in which case what was rendered must be rendered
+// again.
+func (m *markdown) resize(width int) bool {
+	wrap := present.WrapWidth(m.wordWrap, width)
+	if wrap == m.wrap {
+		return false
+	}
+	gr, err := present.NewMarkdownRenderer(wrap, present.MarkdownColors(m.renderer))
+	if err != nil {
+		return false
+	}
+	m.TermRenderer, m.wrap = gr, wrap
+	return true
+}
@@ -0,0 +1,281 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/present"
+)
+
+// ModelChoice identifies a configured model.
+type ModelChoice struct {
+	API   string
+	Model string
+}
+
+func (m ModelChoice) String() string {
+	return m.API + "/" + m.Model
+}
+
+// modelPicker is the Ctrl+P overlay that switches the chat model. Typing
+// filters the list by a case-insensitive subsequence of "api/model" or any
+// alias, so names need not be typed exactly.
+type modelPicker struct {
+	choices  []modelPickerItem
+	filter   string
+	filtered []modelPickerItem
+	cursor   int
+	current  ModelChoice
+}
+
+type modelPickerItem struct {
+	ModelChoice
+	aliases []string
+	recent  bool
+}
+
+// newModelPicker lists every configured model, with recents first (most
+// recent first) and the rest in settings order.
+func newModelPicker(cfg *config.Config, recents []ModelChoice) *modelPicker {
+	var all []modelPickerItem
+	for _, api := range cfg.APIs {
+		names := make([]string, 0, len(api.Models))
+		for name := range api.Models {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			all = append(all, modelPickerItem{
+				ModelChoice: ModelChoice{API: api.Name, Model: name},
+				aliases:     api.Models[name].Aliases,
+			})
+		}
+	}
+
+	choices := make([]modelPickerItem, 0, len(all))
+	for _, r := range recents {
+		if i := slices.IndexFunc(all, func(it modelPickerItem) bool { return it.ModelChoice == r }); i >= 0 {
+			it := all[i]
+			it.recent = true
+			choices = append(choices, it)
+			all = slices.Delete(all, i, i+1)
+		}
+	}
+	choices = append(choices, all...)
+
+	p := &modelPicker{
+		choices: choices,
+		current: ModelChoice{API: cfg.API, Model: cfg.Model},
+	}
+	p.applyFilter()
+	return p
+}
+
+// update handles a key press. It returns done when the picker should close,
+// with the selected model or nil when it was dismissed.
+func (p *modelPicker) update(msg tea.KeyMsg) (done bool, choice *ModelChoice) {
+	switch msg.String() {
+	case "esc", "ctrl+c", "ctrl+p":
+		return true, nil
+	case "enter":
+		if len(p.filtered) == 0 {
+			return false, nil
+		}
+		selected := p.filtered[p.cursor].ModelChoice
+		return true, &selected
+	case "up", "ctrl+k":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case "down", "ctrl+j", "ctrl+n", "tab":
+		if p.cursor < len(p.filtered)-1 {
+			p.cursor++
+		}
+	case "backspace":
+		if p.filter != "" {
+			r := []rune(p.filter)
+			p.filter = string(r[:len(r)-1])
+			p.applyFilter()
+		}
+	default:
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			p.filter += string(msg.Runes)
+			p.applyFilter()
+		}
+	}
+	return false, nil
+}
+
+func (p *modelPicker) applyFilter() {
+	p.filtered = p.filtered[:0]
+	for _, it := range p.choices {
+		if p.matches(it) {
+			p.filtered = append(p.filtered, it)
+		}
+	}
+	p.cursor = 0
+}
+
+func (p *modelPicker) matches(it modelPickerItem) bool {
+	if p.filter == "" {
+		return true
+	}
+	if subsequence(it.String(), p.filter) {
+		return true
+	}
+	for _, alias := range it.aliases {
+		if subsequence(alias, p.filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// subsequence reports whether every character of pattern appears in s in
+// order, ignoring case.
+func subsequence(s, pattern string) bool {
+	s, pattern = strings.ToLower(s), strings.ToLower(strings.TrimSpace(pattern))
+	for _, r := range pattern {
+		i := strings.IndexRune(s, r)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(string(r)):]
+	}
+	return true
+}
+
+// view renders the picker in at most height lines.
+func (p *modelPicker) view(styles present.Styles, height int) string {
+	var sb strings.Builder
+	sb.WriteString(styles.AppName.Render("Switch model") + " " + styles.Comment.Render("(enter to select, esc to cancel)") + "\n")
+	sb.WriteString("> " + p.filter + "\n")
+
+	rows := max(height-2, 1)
+	start := 0
+	if p.cursor >= rows {
+		start = p.cursor - rows + 1
+	}
+	if len(p.filtered) == 0 {
+		sb.WriteString(styles.Comment.Render("  no matching models"))
+		return sb.String()
+	}
+	for i := start; i < len(p.filtered) && i < start+rows; i++ {
+		it := p.filtered[i]
+		line := it.String()
+		if it.ModelChoice == p.current {
+			line += " " + styles.Comment.Render("(current)")
+		} else if it.recent {
+			line += " " + styles.Comment.Render("(recent)")
+		}
+		if i == p.cursor {
+			sb.WriteString(styles.Flag.Render("› ") + line)
+		} else {
+			sb.WriteString("  " + line)
+		}
+		if i < len(p.filtered)-1 && i < start+rows-1 {
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+// switchTo handles "/model <name>" and "/api <name>". A model is given by
+// name or alias, optionally as "api/model", and is looked up in the current
+// API first. An API keeps the current model when it has it, and otherwise
+// switches to its most recent model, or its first one.
+func (c *Chat) switchTo(cmd, name string) {
+	var choice ModelChoice
+	var ok bool
+	if cmd == "/api" {
+		choice, ok = findAPIModel(c.cfg, name, c.recentModels)
+	} else {
+		choice, ok = findModel(c.cfg, name)
+	}
+	switch {
+	case ok:
+		c.switchModel(choice)
+	case cmd == "/api" && name == "":
+		names := make([]string, 0, len(c.cfg.APIs))
+		for _, api := range c.cfg.APIs {
+			names = append(names, api.Name)
+		}
+		c.note(fmt.Sprintf("Using %s/%s; APIs: %s", c.cfg.API, c.cfg.Model, strings.Join(names, ", ")))
+	case cmd == "/api":
+		c.note(fmt.Sprintf("No API %q with models in the settings", name))
+	default:
+		c.note(fmt.Sprintf("No model %q in the settings; press Ctrl+P to pick one", name))
+	}
+}
+
+// findModel resolves a model name or alias, or "api/model", preferring the
+// current API.
+func findModel(cfg *config.Config, name string) (ModelChoice, bool) {
+	if apiName, model, found := strings.Cut(name, "/"); found {
+		for _, api := range cfg.APIs {
+			if api.Name != apiName {
+				continue
+			}
+			if resolved, ok := modelIn(api, model); ok {
+				return ModelChoice{API: api.Name, Model: resolved}, true
+			}
+		}
+	}
+	apis := slices.Clone(cfg.APIs)
+	slices.SortStableFunc(apis, func(a, b config.API) int {
+		// The current API sorts first.
+		switch {
+		case a.Name == cfg.API && b.Name != cfg.API:
+			return -1
+		case b.Name == cfg.API && a.Name != cfg.API:
+			return 1
+		}
+		return 0
+	})
+	for _, api := range apis {
+		if resolved, ok := modelIn(api, name); ok {
+			return ModelChoice{API: api.Name, Model: resolved}, true
+		}
+	}
+	return ModelChoice{}, false
+}
+
+// findAPIModel returns the model to use with the API name: the current one
+// when the API has it, else its most recent one, else its first by name.
+func findAPIModel(cfg *config.Config, name string, recents []ModelChoice) (ModelChoice, bool) {
+	i := slices.IndexFunc(cfg.APIs, func(api config.API) bool { return api.Name == name })
+	if i < 0 || len(cfg.APIs[i].Models) == 0 {
+		return ModelChoice{}, false
+	}
+	api := cfg.APIs[i]
+	if model, ok := modelIn(api, cfg.Model); ok {
+		return ModelChoice{API: name, Model: model}, true
+	}
+	for _, r := range recents {
+		if _, ok := api.Models[r.Model]; ok && r.API == name {
+			return r, true
+		}
+	}
+	names := make([]string, 0, len(api.Models))
+	for model := range api.Models {
+		names = append(names, model)
+	}
+	slices.Sort(names)
+	return ModelChoice{API: name, Model: names[0]}, true
+}
+
+// modelIn returns the name of the model of api called name or aliased so.
+func modelIn(api config.API, name string) (string, bool) {
+	if _, ok := api.Models[name]; ok {
+		return name, true
+	}
+	for model, settings := range api.Models {
+		if slices.Contains(settings.Aliases, name) {
+			return model, true
+		}
+	}
+	return "", false
+}
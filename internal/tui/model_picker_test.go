@@ -0,0 +1,186 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+)
+
+func testPickerConfig() *config.Config {
+	return &config.Config{
+		Settings: config.Settings{
+			API:   "openai",
+			Model: "gpt-5-mini",
+			APIs: config.APIs{
+				{Name: "anthropic", Models: map[string]config.Model{
+					"claude-sonnet-4": {Aliases: []string{"sonnet"}},
+				}},
+				{Name: "openai", Models: map[string]config.Model{
+					"gpt-5":      {},
+					"gpt-5-mini": {},
+				}},
+			},
+			WordWrap: 80,
+			Quiet:    true,
+		},
+	}
+}
+
+func typeKeys(p *modelPicker, s string) {
+	for _, r := range s {
+		p.update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+}
+
+func TestModelPicker_RecentsFirst(t *testing.T) {
+	p := newModelPicker(testPickerConfig(), []ModelChoice{{API: "openai", Model: "gpt-5"}, {API: "gone", Model: "x"}})
+
+	got := make([]string, 0, len(p.filtered))
+	for _, it := range p.filtered {
+		got = append(got, it.String())
+	}
+	want := "openai/gpt-5,anthropic/claude-sonnet-4,openai/gpt-5-mini"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("expected %s, got %s", want, strings.Join(got, ","))
+	}
+}
+
+func TestModelPicker_FilterAndSelect(t *testing.T) {
+	p := newModelPicker(testPickerConfig(), nil)
+
+	typeKeys(p, "o5m")
+	if len(p.filtered) != 1 || p.filtered[0].Model != "gpt-5-mini" {
+		t.Fatalf("expected only gpt-5-mini to match, got %v", p.filtered)
+	}
+
+	p.update(tea.KeyMsg{Type: tea.KeyBackspace})
+	p.update(tea.KeyMsg{Type: tea.KeyBackspace})
+	p.update(tea.KeyMsg{Type: tea.KeyBackspace})
+	typeKeys(p, "sonnet")
+	done, choice := p.update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !done || choice == nil || *choice != (ModelChoice{API: "anthropic", Model: "claude-sonnet-4"}) {
+		t.Fatalf("expected alias match to select claude-sonnet-4, got %v %v", done, choice)
+	}
+}
+
+func TestChat_CtrlP_SwitchesModel(t *testing.T) {
+	c := newTestChat(func(c *Chat) {
+		c.cfg = testPickerConfig()
+	})
+
+	c.Update(tea.KeyMsg{Type: tea.KeyCtrlP})
+	if c.picker == nil {
+		t.Fatal("expected ctrl+p to open the model picker")
+	}
+	if v := c.View(); !strings.Contains(v, "Switch model") {
+		t.Fatalf("expected picker in view, got: %q", v)
+	}
+
+	c.Update(tea.KeyMsg{Type: tea.KeyDown})
+	c.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if c.picker != nil {
+		t.Fatal("expected picker to close after selection")
+	}
+	if c.cfg.API != "openai" || c.cfg.Model != "gpt-5" {
+		t.Fatalf("expected openai/gpt-5, got %s/%s", c.cfg.API, c.cfg.Model)
+	}
+	if len(c.recentModels) != 1 || c.recentModels[0].Model != "gpt-5" {
+		t.Fatalf("expected gpt-5 in recents, got %v", c.recentModels)
+	}
+}
+
+func TestChat_CtrlP_EscKeepsModel(t *testing.T) {
+	c := newTestChat(func(c *Chat) {
+		c.cfg = testPickerConfig()
+	})
+
+	c.Update(tea.KeyMsg{Type: tea.KeyCtrlP})
+	c.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if c.picker != nil {
+		t.Fatal("expected esc to close the picker")
+	}
+	if c.cfg.Model != "gpt-5-mini" {
+		t.Fatalf("expected model to be unchanged, got %s", c.cfg.Model)
+	}
+}
+
+func TestChat_ModelCommand(t *testing.T) {
+	var saved int
+	c := newTestChat(func(c *Chat) {
+		c.cfg = testPickerConfig()
+		c.saveFn = func([]proto.Message) error {
+			saved++
+			return nil
+		}
+	})
+	send := func(text string) {
+		c.input.SetValue(text)
+		c.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	}
+
+	send("/model gpt-5")
+	if c.cfg.API != "openai" || c.cfg.Model != "gpt-5" {
+		t.Fatalf("expected openai/gpt-5, got %s/%s", c.cfg.API, c.cfg.Model)
+	}
+	if saved != 0 {
+		t.Error("expected an empty conversation not to be saved")
+	}
+
+	c.history = []proto.Message{{Role: proto.RoleUser, Content: "hi"}}
+	send("/model sonnet")
+	if c.cfg.API != "anthropic" || c.cfg.Model != "claude-sonnet-4" {
+		t.Fatalf("expected the alias in another API to resolve, got %s/%s", c.cfg.API, c.cfg.Model)
+	}
+	if saved != 1 {
+		t.Errorf("expected the switch to be saved, got %d saves", saved)
+	}
+	if !strings.Contains(c.historyBuf.String(), "Switched to anthropic/claude-sonnet-4") {
+		t.Errorf("expected a note, got %q", c.historyBuf.String())
+	}
+
+	send("/model openai/gpt-5-mini")
+	if c.cfg.API != "openai" || c.cfg.Model != "gpt-5-mini" {
+		t.Fatalf("expected openai/gpt-5-mini, got %s/%s", c.cfg.API, c.cfg.Model)
+	}
+
+	send("/model nope")
+	if c.cfg.Model != "gpt-5-mini" || !strings.Contains(c.historyBuf.String(), `No model "nope"`) {
+		t.Errorf("expected an unknown model to be reported, got %q", c.historyBuf.String())
+	}
+
+	send("/model")
+	if c.picker == nil {
+		t.Error("expected /model without a name to open the picker")
+	}
+}
+
+func TestChat_APICommand(t *testing.T) {
+	c := newTestChat(func(c *Chat) {
+		c.cfg = testPickerConfig()
+		c.recentModels = []ModelChoice{{API: "openai", Model: "gpt-5"}}
+	})
+	send := func(text string) {
+		c.input.SetValue(text)
+		c.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	}
+
+	send("/api anthropic")
+	if c.cfg.API != "anthropic" || c.cfg.Model != "claude-sonnet-4" {
+		t.Fatalf("expected the first anthropic model, got %s/%s", c.cfg.API, c.cfg.Model)
+	}
+	send("/api openai")
+	if c.cfg.API != "openai" || c.cfg.Model != "gpt-5" {
+		t.Fatalf("expected the recent openai model, got %s/%s", c.cfg.API, c.cfg.Model)
+	}
+	send("/api nope")
+	if c.cfg.API != "openai" || !strings.Contains(c.historyBuf.String(), `No API "nope"`) {
+		t.Errorf("expected an unknown API to be reported, got %q", c.historyBuf.String())
+	}
+	send("/api")
+	if !strings.Contains(c.historyBuf.String(), "APIs: anthropic, openai") {
+		t.Errorf("expected the APIs to be listed, got %q", c.historyBuf.String())
+	}
+}
@@ -0,0 +1,120 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/stream"
+)
+
+const progressInterval = 250 * time.Millisecond
+
+type progressTickMsg struct{}
+
+// progress is the one-line status drawn on stderr while the response goes
+// to a pipe, so a long run does not look hung: elapsed time, estimated
+// tokens received, and the tool being run. It is erased before anything
+// else is written to stderr and when the run ends.
+type progress struct {
+	w     io.Writer
+	style func(...string) string
+	start time.Time
+
+	mu    sync.Mutex
+	chars int
+	tool  string
+	shown bool
+}
+
+func newProgress(w io.Writer, style func(...string) string) *progress {
+	return &progress{w: w, style: style, start: time.Now()}
+}
+
+func (p *progress) tick() tea.Cmd {
+	return tea.Tick(progressInterval, func(time.Time) tea.Msg {
+		return progressTickMsg{}
+	})
+}
+
+// received counts streamed response text.
+func (p *progress) received(s string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.chars += len(s)
+	p.mu.Unlock()
+}
+
+func (p *progress) setTool(name string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.tool = name
+	p.mu.Unlock()
+}
+
+// text renders the status, e.g. "12s · ~340 tokens · running search".
+func (p *progress) text() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	parts := []string{time.Since(p.start).Truncate(time.Second).String()}
+	if p.chars > 0 {
+		parts = append(parts, fmt.Sprintf("~%d tokens", (p.chars+proto.CharsPerToken-1)/proto.CharsPerToken))
+	}
+	if p.tool != "" {
+		parts = append(parts, "running "+p.tool)
+	}
+	return strings.Join(parts, " · ")
+}
+
+func (p *progress) draw() {
+	text := p.style(p.text())
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprint(p.w, "\r"+text+"\x1b[K")
+	p.shown = true
+}
+
+// clear erases the status line, if drawn. The next tick draws it again.
+func (p *progress) clear() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.shown {
+		fmt.Fprint(p.w, "\r\x1b[K")
+		p.shown = false
+	}
+}
+
+// watch returns st reporting the tools it runs to p.
+func (p *progress) watch(st stream.Stream) stream.Stream {
+	return &progressStream{Stream: st, progress: p}
+}
+
+type progressStream struct {
+	stream.Stream
+	progress *progress
+}
+
+// CallTools names the tools the model asked for while they run. They are
+// on the last message, which the step ends with.
+func (s *progressStream) CallTools() []proto.ToolCallStatus {
+	if messages := s.Messages(); len(messages) > 0 {
+		var names []string
+		for _, call := range messages[len(messages)-1].ToolCalls {
+			names = append(names, call.Function.Name)
+		}
+		s.progress.setTool(strings.Join(names, ", "))
+	}
+	defer s.progress.setTool("")
+	return s.Stream.CallTools()
+}
@@ -0,0 +1,52 @@
+package tui
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgress(t *testing.T) {
+	var buf bytes.Buffer
+	p := newProgress(&buf, func(s ...string) string { return s[0] })
+
+	require.Equal(t, "0s", p.text())
+	p.received("twelve chars")
+	require.Equal(t, "0s · ~3 tokens", p.text())
+
+	t.Run("names the running tools", func(t *testing.T) {
+		var running string
+		st := &toolsStream{fakeStream: fakeStream{messages: []proto.Message{{
+			Role: proto.RoleAssistant,
+			ToolCalls: []proto.ToolCall{
+				{Function: proto.Function{Name: "search"}},
+				{Function: proto.Function{Name: "fetch"}},
+			},
+		}}}, run: func() { running = p.text() }}
+		p.watch(st).CallTools()
+		require.Equal(t, "0s · ~3 tokens · running search, fetch", running)
+		require.Equal(t, "0s · ~3 tokens", p.text())
+	})
+
+	t.Run("is erased", func(t *testing.T) {
+		buf.Reset()
+		p.clear()
+		require.Empty(t, buf.String())
+		p.draw()
+		p.clear()
+		require.Equal(t, "\r0s · ~3 tokens\x1b[K\r\x1b[K", buf.String())
+	})
+}
+
+// toolsStream calls run while its tools run.
+type toolsStream struct {
+	fakeStream
+	run func()
+}
+
+func (s *toolsStream) CallTools() []proto.ToolCallStatus {
+	s.run()
+	return nil
+}
@@ -1,6 +1,9 @@
 package tui
 
-import "github.com/dotcommander/yai/internal/proto"
+import (
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/proto"
+)
 
 // GlamourOutput returns the last rendered formatted output.
 func (m *Yai) GlamourOutput() string {
@@ -11,3 +14,9 @@ func (m *Yai) GlamourOutput() string {
 func (m *Yai) Messages() []proto.Message {
 	return m.messages
 }
+
+// Interrupted reports whether the response was cut off before the model
+// finished it.
+func (m *Yai) Interrupted() bool {
+	return agent.CanResume(m.messages)
+}
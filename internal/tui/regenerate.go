@@ -0,0 +1,72 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// regenerate drops the last answer, with the tool calls that led to it, and
+// asks for it again. args may set the temperature of that turn only, as in
+// "/retry --temp 1.0".
+func (c *Chat) regenerate(args string) tea.Cmd {
+	temp, ok, err := parseRetryArgs(args)
+	if err != nil {
+		c.note(fmt.Sprintf("Could not retry: %v; use /retry [--temp <value>]", err))
+		return nil
+	}
+	i := c.lastTurn()
+	if i < 0 || i == len(c.history)-1 {
+		c.note("Nothing to retry")
+		return nil
+	}
+	prompt := c.history[i].Content
+	c.rewind(i)
+	if ok {
+		c.overrideTemp(temp)
+		c.note(fmt.Sprintf("Retrying with temperature %g", temp))
+	}
+	return func() tea.Msg {
+		return chatSubmitMsg{prompt: prompt}
+	}
+}
+
+// parseRetryArgs parses the arguments of /retry: nothing, or "--temp X".
+func parseRetryArgs(args string) (temp float64, ok bool, err error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return 0, false, nil
+	}
+	value, found := strings.CutPrefix(fields[0], "--temp=")
+	switch {
+	case found && len(fields) == 1:
+	case fields[0] == "--temp" && len(fields) == 2:
+		value = fields[1]
+	default:
+		return 0, false, fmt.Errorf("unknown arguments %q", args)
+	}
+	temp, err = strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid temperature %q", value)
+	}
+	return temp, true, nil
+}
+
+// overrideTemp sets the temperature until the turn ends.
+func (c *Chat) overrideTemp(temp float64) {
+	if c.savedTemp == nil {
+		saved := c.cfg.Temperature
+		c.savedTemp = &saved
+	}
+	c.cfg.Temperature = temp
+}
+
+// restoreTemp undoes overrideTemp once the turn it was for has ended.
+func (c *Chat) restoreTemp() {
+	if c.savedTemp != nil {
+		c.cfg.Temperature = *c.savedTemp
+		c.savedTemp = nil
+	}
+}
@@ -0,0 +1,78 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dotcommander/yai/internal/proto"
+)
+
+func TestChat_RetryCommand(t *testing.T) {
+	c := newTestChat(func(c *Chat) {
+		c.history = []proto.Message{
+			{Role: proto.RoleUser, Content: "first"},
+			{Role: proto.RoleAssistant, Content: "one"},
+			{Role: proto.RoleUser, Content: "second"},
+			{Role: proto.RoleAssistant, ToolCalls: []proto.ToolCall{{ID: "1"}}},
+			{Role: proto.RoleTool, Content: "result"},
+			{Role: proto.RoleAssistant, Content: "two"},
+		}
+		c.cfg.Temperature = 0.2
+		c.writeHistory()
+	})
+
+	c.input.SetValue("/retry --temp 1.0")
+	_, cmd := c.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("expected a command to retry")
+	}
+	msg, ok := cmd().(chatSubmitMsg)
+	if !ok || msg.prompt != "second" {
+		t.Fatalf("expected the last prompt to be submitted again, got %#v", msg)
+	}
+	if len(c.history) != 2 {
+		t.Errorf("expected the last turn to be removed, got %+v", c.history)
+	}
+	if strings.Contains(c.historyBuf.String(), "two") {
+		t.Error("expected the last answer to be removed from the transcript")
+	}
+	if c.cfg.Temperature != 1.0 {
+		t.Errorf("expected temperature 1.0 for the retry, got %g", c.cfg.Temperature)
+	}
+
+	c.Update(chatStreamDoneMsg{messages: append(c.history,
+		proto.Message{Role: proto.RoleUser, Content: "second"},
+		proto.Message{Role: proto.RoleAssistant, Content: "deux"},
+	)})
+	if c.cfg.Temperature != 0.2 {
+		t.Errorf("expected the temperature to be restored, got %g", c.cfg.Temperature)
+	}
+
+	_, cmd = c.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if cmd == nil {
+		t.Fatal("expected ctrl+r to retry")
+	}
+	if msg, _ := cmd().(chatSubmitMsg); msg.prompt != "second" {
+		t.Errorf("expected ctrl+r to submit the last prompt again, got %#v", msg)
+	}
+}
+
+func TestChat_RetryCommand_Invalid(t *testing.T) {
+	c := newTestChat()
+
+	for input, want := range map[string]string{
+		"/retry":            "Nothing to retry",
+		"/retry --temp hot": `invalid temperature "hot"`,
+		"/retry --top-p 1":  "unknown arguments",
+	} {
+		c.input.SetValue(input)
+		_, cmd := c.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		if cmd != nil {
+			t.Errorf("%s: expected no request, got %T", input, cmd())
+		}
+		if !strings.Contains(c.historyBuf.String(), want) {
+			t.Errorf("%s: expected a note containing %q, got %q", input, want, c.historyBuf.String())
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// chatSearch is the Ctrl+F search over the rendered conversation. Lines
+// match when their text, without styling, contains the query in any case;
+// n and N move between them, / starts a new search, and Esc closes it.
+type chatSearch struct {
+	input  textinput.Model
+	typing bool
+	query  string
+	// lines are the indexes of the matching lines of the viewport content,
+	// and current is the one shown.
+	lines   []int
+	current int
+}
+
+func newChatSearch() *chatSearch {
+	s := &chatSearch{}
+	s.input = textinput.New()
+	s.input.Prompt = "/"
+	s.edit()
+	return s
+}
+
+// edit starts typing a new query.
+func (s *chatSearch) edit() {
+	s.typing = true
+	s.input.SetValue("")
+	s.input.Focus()
+}
+
+// find records the lines of content matching the query.
+func (s *chatSearch) find(content string) {
+	s.lines = s.lines[:0]
+	if s.query == "" {
+		return
+	}
+	query := strings.ToLower(s.query)
+	for i, line := range strings.Split(content, "\n") {
+		if strings.Contains(strings.ToLower(ansi.Strip(line)), query) {
+			s.lines = append(s.lines, i)
+		}
+	}
+	s.current = min(s.current, max(len(s.lines)-1, 0))
+}
+
+// first selects the first match at or below line, wrapping around.
+func (s *chatSearch) first(line int) {
+	s.current = 0
+	for i, l := range s.lines {
+		if l >= line {
+			s.current = i
+			return
+		}
+	}
+}
+
+// step moves to the next match, or the previous one when delta is -1.
+func (s *chatSearch) step(delta int) {
+	if len(s.lines) == 0 {
+		return
+	}
+	s.current = (s.current + delta + len(s.lines)) % len(s.lines)
+}
+
+// line returns the index of the selected matching line, or -1.
+func (s *chatSearch) line() int {
+	if len(s.lines) == 0 {
+		return -1
+	}
+	return s.lines[s.current]
+}
+
+// highlight marks the query in the matching lines of content. The marked
+// lines lose their other styling.
+func (s *chatSearch) highlight(content string, match, current lipgloss.Style) string {
+	if len(s.lines) == 0 {
+		return content
+	}
+	lines := strings.Split(content, "\n")
+	for i, n := range s.lines {
+		style := match
+		if i == s.current {
+			style = current
+		}
+		lines[n] = markQuery(ansi.Strip(lines[n]), s.query, style)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// markQuery renders each case-insensitive occurrence of query in text with
+// style.
+func markQuery(text, query string, style lipgloss.Style) string {
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		// Case folding changed the byte offsets; mark the whole line.
+		return style.Render(text)
+	}
+	query = strings.ToLower(query)
+	var sb strings.Builder
+	for {
+		i := strings.Index(lower, query)
+		if i < 0 {
+			sb.WriteString(text)
+			return sb.String()
+		}
+		sb.WriteString(text[:i])
+		sb.WriteString(style.Render(text[i : i+len(query)]))
+		text, lower = text[i+len(query):], lower[i+len(query):]
+	}
+}
+
+// status describes the search for the footer.
+func (s *chatSearch) status() string {
+	if len(s.lines) == 0 {
+		return fmt.Sprintf("No matches for %q · / new search · esc close", s.query)
+	}
+	return fmt.Sprintf("Match %d/%d for %q · n next · N previous · / new search · esc close", s.current+1, len(s.lines), s.query)
+}
+
+// handleSearchKey handles a key press while the search is open.
+func (c *Chat) handleSearchKey(msg tea.KeyMsg) tea.Cmd {
+	s := c.search
+	if s.typing {
+		switch msg.String() {
+		case "esc", "ctrl+c", "ctrl+f":
+			c.closeSearch()
+		case "enter":
+			s.query = strings.TrimSpace(s.input.Value())
+			if s.query == "" {
+				c.closeSearch()
+				return nil
+			}
+			s.typing = false
+			s.input.Blur()
+			s.find(c.content)
+			s.first(c.viewport.YOffset)
+			c.showMatch()
+		default:
+			var cmd tea.Cmd
+			s.input, cmd = s.input.Update(msg)
+			return cmd
+		}
+		return nil
+	}
+
+	switch msg.String() {
+	case "esc", "ctrl+c", "ctrl+f", "q":
+		c.closeSearch()
+	case "n":
+		s.step(1)
+		c.showMatch()
+	case "N":
+		s.step(-1)
+		c.showMatch()
+	case "/":
+		s.edit()
+		c.showMatch()
+		return textinput.Blink
+	default:
+		var cmd tea.Cmd
+		c.viewport, cmd = c.viewport.Update(msg)
+		return cmd
+	}
+	return nil
+}
+
+func (c *Chat) closeSearch() {
+	c.search = nil
+	c.input.Focus()
+	c.viewport.SetContent(c.content)
+}
+
+// showMatch highlights the matches and scrolls the selected one to the
+// middle of the viewport.
+func (c *Chat) showMatch() {
+	s := c.search
+	c.viewport.SetContent(s.highlight(c.content, c.styles.Quote.Reverse(true), c.styles.Flag.Reverse(true)))
+	if line := s.line(); line >= 0 && !s.typing {
+		c.viewport.SetYOffset(max(line-c.viewport.Height/2, 0))
+	}
+}
+
+// searchView renders the footer line of the search.
+func (c *Chat) searchView() string {
+	if c.search.typing {
+		return c.search.input.View()
+	}
+	return c.styles.Comment.Render(c.search.status())
+}
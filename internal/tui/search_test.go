@@ -0,0 +1,64 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestChat_Search(t *testing.T) {
+	c := newTestChat()
+	c.viewport.Height = 3
+	lines := make([]string, 20)
+	for i := range lines {
+		lines[i] = "filler"
+	}
+	lines[2] = "first \x1b[1mNeedle\x1b[0m here"
+	lines[15] = "second needle"
+	c.content = strings.Join(lines, "\n")
+	c.viewport.SetContent(c.content)
+
+	c.Update(tea.KeyMsg{Type: tea.KeyCtrlF})
+	if c.search == nil {
+		t.Fatal("expected ctrl+f to open the search")
+	}
+	for _, r := range "needle" {
+		c.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	if c.input.Value() != "" {
+		t.Fatalf("expected the query to stay out of the prompt, got %q", c.input.Value())
+	}
+	c.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if got := c.search.lines; len(got) != 2 || got[0] != 2 || got[1] != 15 {
+		t.Fatalf("expected matches on lines 2 and 15, got %v", got)
+	}
+	if c.viewport.YOffset != 1 {
+		t.Errorf("expected the first match in view, got offset %d", c.viewport.YOffset)
+	}
+	if !strings.Contains(c.searchView(), "Match 1/2") {
+		t.Errorf("expected the match count, got %q", c.searchView())
+	}
+
+	c.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+	if c.search.line() != 15 || c.viewport.YOffset != 14 {
+		t.Errorf("expected n to show line 15, got line %d at offset %d", c.search.line(), c.viewport.YOffset)
+	}
+	c.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+	if c.search.line() != 2 {
+		t.Errorf("expected n to wrap around to line 2, got %d", c.search.line())
+	}
+	c.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'N'}})
+	if c.search.line() != 15 {
+		t.Errorf("expected N to go back to line 15, got %d", c.search.line())
+	}
+
+	c.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if c.search != nil {
+		t.Fatal("expected esc to close the search")
+	}
+	if !c.input.Focused() {
+		t.Error("expected the prompt to be focused again")
+	}
+}
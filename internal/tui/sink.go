@@ -0,0 +1,94 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Formats of a Sink.
+const (
+	SinkRaw      = "raw"
+	SinkMarkdown = "markdown"
+	SinkJSONL    = "jsonl"
+)
+
+// SinkFormats are the formats a Sink can write, in the order help lists
+// them.
+var SinkFormats = []string{SinkRaw, SinkMarkdown, SinkJSONL}
+
+// Sink gets a copy of the response besides stdout. A raw sink gets the
+// answer as it streams, a markdown sink the answer once it is complete,
+// without what a retry dropped, and a jsonl sink the events of
+// --output-format jsonl.
+type Sink struct {
+	format string
+	w      io.WriteCloser
+	// answer is the markdown held back until Close.
+	answer strings.Builder
+	// last is the last byte written, to end the output with a newline.
+	last byte
+	err  error
+}
+
+// NewSink returns a sink writing format to w. The sink closes w.
+func NewSink(format string, w io.WriteCloser) (*Sink, error) {
+	switch format {
+	case SinkRaw, SinkMarkdown, SinkJSONL:
+		return &Sink{format: format, w: w}, nil
+	default:
+		return nil, fmt.Errorf("unknown sink format %q", format)
+	}
+}
+
+// write passes ev to the sink. Once a write fails, the sink drops the rest,
+// and Close reports the error.
+func (s *Sink) write(ev event) {
+	if s.err != nil {
+		return
+	}
+	switch s.format {
+	case SinkRaw:
+		if ev.Type == eventChunk {
+			s.writeText(ev.Content)
+		}
+	case SinkMarkdown:
+		switch ev.Type {
+		case eventChunk:
+			s.answer.WriteString(ev.Content)
+		case eventRetry:
+			s.answer.Reset()
+		}
+	case SinkJSONL:
+		enc := json.NewEncoder(s.w)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(ev); err != nil {
+			s.err = fmt.Errorf("write event: %w", err)
+		}
+	}
+}
+
+func (s *Sink) writeText(text string) {
+	if text == "" || s.err != nil {
+		return
+	}
+	if _, err := io.WriteString(s.w, text); err != nil {
+		s.err = fmt.Errorf("write answer: %w", err)
+		return
+	}
+	s.last = text[len(text)-1]
+}
+
+// Close writes what the sink held back, ends text with a newline, and
+// closes it. It returns the first error the sink had.
+func (s *Sink) Close() error {
+	s.writeText(s.answer.String())
+	if s.format != SinkJSONL && s.last != 0 && s.last != '\n' {
+		s.writeText("\n")
+	}
+	if err := s.w.Close(); err != nil && s.err == nil {
+		s.err = fmt.Errorf("close: %w", err)
+	}
+	return s.err
+}
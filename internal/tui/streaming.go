@@ -43,6 +43,34 @@ func startManagedStream(
 	return res, nil
 }
 
+// toolProgressMsg is a progress report of a running tool.
+type toolProgressMsg proto.ToolProgress
+
+// toolProgress relays the progress reports of running tools, which arrive
+// while the stream command waits for the tools, to the program.
+type toolProgress chan proto.ToolProgress
+
+func newToolProgress() toolProgress {
+	return make(toolProgress, 16)
+}
+
+// report passes p on, or drops it when the program is behind: a later
+// report says more anyway.
+func (c toolProgress) report(p proto.ToolProgress) {
+	select {
+	case c <- p:
+	default:
+	}
+}
+
+// wait returns the next report. Run it once the first request is sent, and
+// again after each report.
+func (c toolProgress) wait() tea.Cmd {
+	return func() tea.Msg {
+		return toolProgressMsg(<-c)
+	}
+}
+
 func streamStartErrorMsg(err error) tea.Msg {
 	var e errs.Error
 	if errors.As(err, &e) {
@@ -58,6 +86,7 @@ func receiveManagedStreamCmd(
 	closeActive func(),
 	errh func(error) tea.Msg,
 	onChunk func(string, stream.Stream, func(error) tea.Msg) tea.Msg,
+	onTools func([]proto.ToolCallStatus, stream.Stream, func(error) tea.Msg) tea.Msg,
 	onDone func([]proto.Message) tea.Msg,
 ) tea.Cmd {
 	return func() tea.Msg {
@@ -83,11 +112,7 @@ func receiveManagedStreamCmd(
 
 		results := st.CallTools()
 		if len(results) > 0 {
-			var content strings.Builder
-			for _, call := range results {
-				content.WriteString(call.String())
-			}
-			return onChunk(content.String(), st, errh)
+			return onTools(results, st, errh)
 		}
 
 		messages := st.Messages()
@@ -96,11 +121,28 @@ func receiveManagedStreamCmd(
 	}
 }
 
+// recordResponseMeta keeps what the provider reported about the last
+// answer in cfg, to be saved with the conversation.
+func recordResponseMeta(cfg *config.Config, meta proto.ResponseMeta) {
+	cfg.RequestID = meta.RequestID
+	cfg.ModelVersion = meta.ModelVersion
+}
+
+// toolCallsContent renders the tool calls run between steps the way they
+// appear in the response.
+func toolCallsContent(results []proto.ToolCallStatus) string {
+	var content strings.Builder
+	for _, call := range results {
+		content.WriteString(call.String())
+	}
+	return content.String()
+}
+
 func handleRetryableStreamError(
 	agentSvc *agent.Service,
 	noLimit bool,
 	setModel func(string),
-	retry func(errs.Error, string) tea.Msg,
+	retry func(agent.StreamErrorAction, string) tea.Msg,
 	err error,
 	mod config.Model,
 	prompt string,
@@ -114,7 +156,7 @@ func handleRetryableStreamError(
 		if next == "" {
 			next = prompt
 		}
-		return retry(action.Err, next)
+		return retry(action, next)
 	}
 	if action.Err.Err == nil {
 		return errs.Error{Err: err}
@@ -144,16 +186,18 @@ func emitCommentWarning(commentRenderer func(...string) string, message string)
 
 func retryOrFail(
 	ctx context.Context,
-	retries *int,
-	maxRetries int,
-	err errs.Error,
+	budget *agent.RetryBudget,
+	action agent.StreamErrorAction,
 	content string,
 	submit func(string) tea.Msg,
 ) tea.Msg {
-	*retries++
-	if *retries >= maxRetries {
-		return err
+	delay, ok := budget.Next(action)
+	if !ok {
+		return action.Err
+	}
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
 	}
-	waitForRetryDelay(ctx, *retries, err.Err)
 	return submit(content)
 }
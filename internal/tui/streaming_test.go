@@ -28,6 +28,8 @@ func (f *fakeStream) Err() error                        { return f.err }
 func (f *fakeStream) Messages() []proto.Message         { return f.messages }
 func (f *fakeStream) CallTools() []proto.ToolCallStatus { return f.tools }
 func (f *fakeStream) DrainWarnings() []string           { out := f.warnings; f.warnings = nil; return out }
+func (f *fakeStream) Usage() proto.Usage                { return proto.Usage{} }
+func (f *fakeStream) ResponseMeta() proto.ResponseMeta  { return proto.ResponseMeta{} }
 
 func TestReceiveManagedStreamCmdReturnsToolOutput(t *testing.T) {
 	st := &fakeStream{tools: []proto.ToolCallStatus{{Name: "demo"}}}
@@ -40,6 +42,9 @@ func TestReceiveManagedStreamCmdReturnsToolOutput(t *testing.T) {
 		func(content string, st stream.Stream, errh func(error) tea.Msg) tea.Msg {
 			return completionOutput{content: content, stream: st, errh: errh}
 		},
+		func(results []proto.ToolCallStatus, st stream.Stream, errh func(error) tea.Msg) tea.Msg {
+			return completionOutput{content: toolCallsContent(results), stream: st, errh: errh}
+		},
 		func([]proto.Message) tea.Msg { return completionOutput{} },
 	)()
 
@@ -59,6 +64,7 @@ func TestReceiveManagedStreamCmdClosesOnStreamError(t *testing.T) {
 		func() { closed = true },
 		func(err error) tea.Msg { return err },
 		func(content string, st stream.Stream, errh func(error) tea.Msg) tea.Msg { return nil },
+		func([]proto.ToolCallStatus, stream.Stream, func(error) tea.Msg) tea.Msg { return nil },
 		func([]proto.Message) tea.Msg { return nil },
 	)()
 
@@ -0,0 +1,6 @@
+// Package tuitest drives the Yai and Chat models end-to-end in tests.
+//
+// Models run in a real tea.Program (via teatest) with an agent whose
+// provider client replays scripted responses, so tests exercise the same
+// request building, streaming, and retry paths as yai does at runtime.
+package tuitest
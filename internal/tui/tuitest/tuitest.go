@@ -0,0 +1,233 @@
+package tuitest
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/exp/teatest"
+	"github.com/dotcommander/yai/internal/agent"
+	"github.com/dotcommander/yai/internal/config"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/provider"
+	"github.com/dotcommander/yai/internal/stream"
+	"github.com/dotcommander/yai/internal/tui"
+)
+
+// Terminal size every test program starts with.
+const (
+	Width  = 80
+	Height = 24
+)
+
+// Script is one scripted model response: the chunks it streams, then Err if
+// set.
+type Script struct {
+	Chunks []string
+	Err    error
+	Usage  proto.Usage
+	Meta   proto.ResponseMeta
+	// Logprobs are set on the answer.
+	Logprobs []proto.TokenLogprob
+}
+
+// Client is a stream.Client that answers each request with the next script.
+// Requests beyond the last script get an empty response.
+type Client struct {
+	mu       sync.Mutex
+	scripts  []Script
+	requests []proto.Request
+}
+
+// NewClient returns a client that replays scripts in order.
+func NewClient(scripts ...Script) *Client {
+	return &Client{scripts: scripts}
+}
+
+// Request implements stream.Client.
+func (c *Client) Request(ctx context.Context, req proto.Request) stream.Stream {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var script Script
+	if n := len(c.requests); n < len(c.scripts) {
+		script = c.scripts[n]
+	}
+	c.requests = append(c.requests, req)
+	return &scriptStream{ctx: ctx, script: script, messages: req.Messages}
+}
+
+// Requests returns the requests the client received, in order.
+func (c *Client) Requests() []proto.Request {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]proto.Request(nil), c.requests...)
+}
+
+// Factory returns an agent.ClientFactory that always hands out c.
+func (c *Client) Factory() agent.ClientFactory {
+	return func(provider.Config) (stream.Client, error) {
+		return c, nil
+	}
+}
+
+// scriptStream replays one script.
+type scriptStream struct {
+	ctx      context.Context
+	script   Script
+	pos      int
+	err      error
+	messages []proto.Message
+	text     strings.Builder
+}
+
+func (s *scriptStream) Next() bool {
+	if s.err != nil {
+		return false
+	}
+	if err := s.ctx.Err(); err != nil {
+		s.err = err
+		return false
+	}
+	if s.pos >= len(s.script.Chunks) {
+		if s.script.Err != nil {
+			s.err = s.script.Err
+		}
+		return false
+	}
+	s.text.WriteString(s.script.Chunks[s.pos])
+	s.pos++
+	return true
+}
+
+func (s *scriptStream) Current() (proto.Chunk, error) {
+	if s.pos == 0 {
+		return proto.Chunk{}, stream.ErrNoContent
+	}
+	return proto.Chunk{Content: s.script.Chunks[s.pos-1]}, nil
+}
+
+func (s *scriptStream) Close() error { return nil }
+
+func (s *scriptStream) Err() error { return s.err }
+
+func (s *scriptStream) Messages() []proto.Message {
+	return append(append([]proto.Message(nil), s.messages...), proto.Message{
+		Role:     proto.RoleAssistant,
+		Content:  s.text.String(),
+		Logprobs: s.script.Logprobs,
+	})
+}
+
+func (s *scriptStream) CallTools() []proto.ToolCallStatus { return nil }
+
+func (s *scriptStream) DrainWarnings() []string { return nil }
+
+func (s *scriptStream) Usage() proto.Usage { return s.script.Usage }
+
+func (s *scriptStream) ResponseMeta() proto.ResponseMeta { return s.script.Meta }
+
+// Config returns quiet, raw settings for a single "test" model on the openai
+// API, with the built-in defaults for everything else.
+func Config() *config.Config {
+	cfg := config.Default()
+	cfg.API = "openai"
+	cfg.Model = "test"
+	cfg.APIs = config.APIs{{
+		Name:   "openai",
+		APIKey: "test-key",
+		Models: map[string]config.Model{"test": {MaxChars: 100000}},
+	}}
+	cfg.Quiet = true
+	cfg.Raw = true
+	cfg.WordWrap = Width
+	cfg.MaxRetries = 3
+	cfg.Retry.InitialBackoff = 1
+	cfg.Retry.MaxBackoff = 1
+	return &cfg
+}
+
+// Yai is a running one-shot Yai program.
+type Yai struct {
+	*teatest.TestModel
+
+	stdout *syncBuffer
+}
+
+// NewYai runs the Yai model with stdin as its piped input and cfg.Prefix as
+// the prompt arguments. opts adjust the model before it starts.
+func NewYai(tb testing.TB, cfg *config.Config, client *Client, stdin string, opts ...func(*tui.Yai)) *Yai {
+	tb.Helper()
+	svc := agent.New(cfg, nil, nil, client.Factory())
+	m := tui.NewYai(context.Background(), lipgloss.DefaultRenderer(), cfg, svc, svc.Stream)
+	m.Stdin = strings.NewReader(stdin)
+	out := &syncBuffer{}
+	m.Stdout = out
+	for _, opt := range opts {
+		opt(m)
+	}
+	return &Yai{
+		TestModel: teatest.NewTestModel(tb, m, teatest.WithInitialTermSize(Width, Height)),
+		stdout:    out,
+	}
+}
+
+// Result waits for the program to exit and returns the final model and
+// everything it printed to stdout.
+func (y *Yai) Result(tb testing.TB) (*tui.Yai, string) {
+	tb.Helper()
+	m, ok := y.FinalModel(tb).(*tui.Yai)
+	if !ok {
+		tb.Fatalf("final model is not *tui.Yai")
+	}
+	return m, y.stdout.String()
+}
+
+// NewChat runs the Chat model. Context, Renderer, Config, Agent, and
+// StartStream are filled in when opts leaves them unset.
+func NewChat(tb testing.TB, cfg *config.Config, client *Client, opts tui.ChatOptions) *teatest.TestModel {
+	tb.Helper()
+	if opts.Context == nil {
+		opts.Context = context.Background()
+	}
+	if opts.Renderer == nil {
+		opts.Renderer = lipgloss.DefaultRenderer()
+	}
+	if opts.Config == nil {
+		opts.Config = cfg
+	}
+	if opts.Agent == nil {
+		opts.Agent = agent.New(cfg, nil, nil, client.Factory())
+	}
+	if opts.StartStream == nil {
+		opts.StartStream = opts.Agent.StreamContinue
+	}
+	return teatest.NewTestModel(tb, tui.NewChat(opts), teatest.WithInitialTermSize(Width, Height))
+}
+
+// Submit types line into a chat and presses enter.
+func Submit(tm *teatest.TestModel, line string) {
+	tm.Type(line)
+	tm.Send(tea.KeyMsg{Type: tea.KeyEnter})
+}
+
+// syncBuffer collects output written from the program goroutine.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p) //nolint:wrapcheck
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
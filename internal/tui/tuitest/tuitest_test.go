@@ -0,0 +1,192 @@
+package tuitest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/x/exp/teatest"
+	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/tui"
+	"github.com/stretchr/testify/require"
+)
+
+func TestYai(t *testing.T) {
+	t.Run("streams the response to stdout", func(t *testing.T) {
+		client := NewClient(Script{Chunks: []string{"hello", " world"}})
+		cfg := Config()
+		cfg.Prefix = "summarize"
+
+		m, out := NewYai(t, cfg, client, "some notes").Result(t)
+		require.Nil(t, m.Error)
+		require.Equal(t, "hello world", strings.TrimSpace(out))
+
+		requests := client.Requests()
+		require.Len(t, requests, 1)
+		prompt := requests[0].Messages[len(requests[0].Messages)-1]
+		require.Equal(t, proto.RoleUser, prompt.Role)
+		require.Contains(t, prompt.Content, "summarize")
+		require.Contains(t, prompt.Content, "some notes")
+	})
+
+	t.Run("retries retryable errors", func(t *testing.T) {
+		client := NewClient(
+			Script{Err: &fantasy.ProviderError{StatusCode: http.StatusServiceUnavailable}},
+			Script{Chunks: []string{"ok"}},
+		)
+		cfg := Config()
+		cfg.Prefix = "hi"
+
+		m, out := NewYai(t, cfg, client, "").Result(t)
+		require.Nil(t, m.Error)
+		require.Equal(t, "ok", strings.TrimSpace(out))
+		require.Len(t, client.Requests(), 2)
+	})
+
+	t.Run("writes JSON events", func(t *testing.T) {
+		client := NewClient(Script{
+			Chunks: []string{"hello", " world"},
+			Usage:  proto.Usage{InputTokens: 3, OutputTokens: 2, TotalTokens: 5},
+		})
+		cfg := Config()
+		cfg.Prefix = "hi"
+
+		m, out := NewYai(t, cfg, client, "", func(m *tui.Yai) { m.JSONEvents = true }).Result(t)
+		require.Nil(t, m.Error)
+		require.Equal(t, strings.Join([]string{
+			`{"type":"chunk","content":"hello"}`,
+			`{"type":"chunk","content":" world"}`,
+			`{"type":"usage","usage":{"input_tokens":3,"output_tokens":2,"total_tokens":5}}`,
+			`{"type":"done","api":"openai","model":"test"}`,
+		}, "\n")+"\n", out)
+	})
+
+	t.Run("writes logprobs events", func(t *testing.T) {
+		client := NewClient(Script{
+			Chunks: []string{"yes"},
+			Logprobs: []proto.TokenLogprob{{
+				Token: "yes", Logprob: -0.25,
+				Top: []proto.TokenLogprob{{Token: "yes", Logprob: -0.25}, {Token: "no", Logprob: -1.5}},
+			}},
+		})
+		cfg := Config()
+		cfg.Prefix = "hi"
+
+		m, out := NewYai(t, cfg, client, "", func(m *tui.Yai) { m.JSONEvents = true }).Result(t)
+		require.Nil(t, m.Error)
+		require.Equal(t, strings.Join([]string{
+			`{"type":"chunk","content":"yes"}`,
+			`{"type":"logprobs","logprobs":[{"token":"yes","logprob":-0.25,"top_logprobs":[{"token":"yes","logprob":-0.25},{"token":"no","logprob":-1.5}]}]}`,
+			`{"type":"done","api":"openai","model":"test"}`,
+		}, "\n")+"\n", out)
+	})
+
+	t.Run("post-processes the answer", func(t *testing.T) {
+		client := NewClient(Script{Chunks: []string{"Here you go:\n\n```sh\n", "ls -l\n```\n"}})
+		cfg := Config()
+		cfg.Prefix = "list files"
+
+		m, out := NewYai(t, cfg, client, "", func(m *tui.Yai) {
+			m.Postprocess = func(_ context.Context, s string) (string, error) {
+				return strings.ToUpper(s), nil
+			}
+		}).Result(t)
+		require.Nil(t, m.Error)
+		require.Equal(t, "HERE YOU GO:\n\n```SH\nLS -L\n```", strings.TrimSpace(out))
+		msgs := m.Messages()
+		require.Equal(t, "HERE YOU GO:\n\n```SH\nLS -L\n```\n", msgs[len(msgs)-1].Content, "the filtered answer is saved")
+	})
+
+	t.Run("copies the response to sinks", func(t *testing.T) {
+		client := NewClient(
+			Script{Chunks: []string{"dropped"}, Err: &fantasy.ProviderError{StatusCode: http.StatusServiceUnavailable}},
+			Script{Chunks: []string{"hello", " world"}},
+		)
+		cfg := Config()
+		cfg.Prefix = "hi"
+
+		var raw, md, events bytes.Buffer
+		sinks := make([]*tui.Sink, 0, 3)
+		for format, w := range map[string]*bytes.Buffer{tui.SinkRaw: &raw, tui.SinkMarkdown: &md, tui.SinkJSONL: &events} {
+			s, err := tui.NewSink(format, nopCloser{w})
+			require.NoError(t, err)
+			sinks = append(sinks, s)
+		}
+		m, out := NewYai(t, cfg, client, "", func(m *tui.Yai) { m.Sinks = sinks }).Result(t)
+		require.Nil(t, m.Error)
+		for _, s := range sinks {
+			require.NoError(t, s.Close())
+		}
+
+		require.Equal(t, "droppedhello world", strings.TrimSpace(out))
+		require.Equal(t, "droppedhello world\n", raw.String(), "what was streamed cannot be taken back")
+		require.Equal(t, "hello world\n", md.String(), "the answer without what the retry dropped")
+		var types []string
+		for line := range strings.Lines(events.String()) {
+			var ev struct{ Type string }
+			require.NoError(t, json.Unmarshal([]byte(line), &ev))
+			types = append(types, ev.Type)
+		}
+		require.Equal(t, []string{"chunk", "retry", "chunk", "chunk", "done"}, types)
+	})
+
+	t.Run("reports errors", func(t *testing.T) {
+		client := NewClient(Script{Err: errors.New("boom")})
+		cfg := Config()
+		cfg.Prefix = "hi"
+
+		m, _ := NewYai(t, cfg, client, "").Result(t)
+		require.NotNil(t, m.Error)
+		require.EqualError(t, m.Error, "boom")
+	})
+}
+
+func TestChat(t *testing.T) {
+	client := NewClient(
+		Script{Chunks: []string{"first", " answer"}},
+		Script{Chunks: []string{"second answer"}},
+	)
+	var saved [][]proto.Message
+	tm := NewChat(t, Config(), client, tui.ChatOptions{
+		Save: func(msgs []proto.Message) error {
+			saved = append(saved, msgs)
+			return nil
+		},
+	})
+
+	Submit(tm, "one")
+	waitFor(t, tm, "first answer")
+	Submit(tm, "two")
+	waitFor(t, tm, "second answer")
+	Submit(tm, "/exit")
+
+	c, ok := tm.FinalModel(t, teatest.WithFinalTimeout(5*time.Second)).(*tui.Chat)
+	require.True(t, ok)
+	history := c.Messages()
+	require.Len(t, history, 4)
+	require.Equal(t, "one", history[0].Content)
+	require.Equal(t, "first answer", history[1].Content)
+	require.Equal(t, "two", history[2].Content)
+	require.Equal(t, "second answer", history[3].Content)
+	require.Len(t, saved, 2)
+
+	// The second request carries the first turn as history.
+	require.Len(t, client.Requests()[1].Messages, 3)
+}
+
+func waitFor(t *testing.T, tm *teatest.TestModel, s string) {
+	t.Helper()
+	teatest.WaitFor(t, tm.Output(), func(b []byte) bool {
+		return bytes.Contains(b, []byte(s))
+	}, teatest.WithDuration(5*time.Second))
+}
+
+type nopCloser struct{ *bytes.Buffer }
+
+func (nopCloser) Close() error { return nil }
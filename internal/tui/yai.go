@@ -4,9 +4,11 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -14,13 +16,13 @@ import (
 
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/dotcommander/yai/internal/agent"
 	"github.com/dotcommander/yai/internal/config"
 	"github.com/dotcommander/yai/internal/errs"
 	"github.com/dotcommander/yai/internal/present"
 	"github.com/dotcommander/yai/internal/proto"
+	"github.com/dotcommander/yai/internal/requestbuilder"
 	"github.com/dotcommander/yai/internal/stream"
 )
 
@@ -41,11 +43,29 @@ type Yai struct {
 	Input  string
 	Styles present.Styles
 	Error  *errs.Error
+	// Canceled is set when the run was stopped with Ctrl+C or SIGINT.
+	Canceled bool
+
+	// Stdin and Stdout replace the process streams when set, so the model
+	// can be driven without a terminal (see the tuitest package). A set
+	// Stdin is always read as piped input.
+	Stdin  io.Reader
+	Stdout io.Writer
+	// JSONEvents writes the response to Stdout as JSON events, one per
+	// line, instead of text.
+	JSONEvents bool
+	// Sinks get a copy of the response, each in its format, whatever
+	// goes to stdout. The caller closes them.
+	Sinks []*Sink
+	// Postprocess, when set, rewrites the answer once it is complete. The
+	// answer is held back until then, and written as received if the run
+	// is cut off.
+	Postprocess func(context.Context, string) (string, error)
 
 	state        state
-	retries      int
+	retries      *agent.RetryBudget
 	renderer     *lipgloss.Renderer
-	glam         *glamour.TermRenderer
+	glam         markdown
 	glamViewport viewport.Model
 	glamOutput   string
 	glamHeight   int
@@ -58,6 +78,8 @@ type Yai struct {
 	agent         *agent.Service
 	startStreamFn func(context.Context, string) (agent.StreamStart, error)
 
+	// content is raw output not written to stdout yet: in a pipe, the
+	// line still being streamed.
 	content      []string
 	contentMutex *sync.Mutex
 
@@ -65,12 +87,27 @@ type Yai struct {
 	outputTruncated bool
 	activeStream    stream.Stream
 	activeCancel    context.CancelFunc
+	// response is the text streamed by the current request, kept as a
+	// partial answer when the request is cut off.
+	response bytes.Buffer
 
 	renderScheduled bool
 	dirtyOutput     bool
 	stopWarned      bool
 	mcpNonTTYWarned bool
 	streamStartedAt time.Time
+	usage           proto.Usage
+	// sent is how many of messages were sent with the request; the ones
+	// after it are what the model wrote.
+	sent     int
+	model    config.Model
+	progress *progress
+	// toolStatus is the last progress report of the running tool.
+	toolProgress toolProgress
+	toolStatus   string
+	// approval is the question waiting for the user's answer.
+	approvals approvals
+	approval  *approvalMsg
 
 	ctx context.Context
 }
@@ -83,17 +120,13 @@ func NewYai(
 	agentSvc *agent.Service,
 	startStreamFn func(context.Context, string) (agent.StreamStart, error),
 ) *Yai {
-	gr, _ := glamour.NewTermRenderer(
-		glamour.WithEnvironmentConfig(),
-		glamour.WithWordWrap(cfg.WordWrap),
-	)
 	vp := viewport.New(0, 0)
 	vp.GotoBottom()
 	// agentSvc must be provided by the caller so that the TUI stays focused on
 	// rendering and streaming (no config resolution, cache wiring, etc.).
 	return &Yai{
 		Styles:        present.MakeStyles(r),
-		glam:          gr,
+		glam:          newMarkdown(r, cfg.WordWrap),
 		state:         startState,
 		renderer:      r,
 		glamViewport:  vp,
@@ -101,6 +134,7 @@ func NewYai(
 		startStreamFn: startStreamFn,
 		Config:        cfg,
 		agent:         agentSvc,
+		retries:       agent.NewRetryBudget(cfg),
 		ctx:           ctx,
 	}
 }
@@ -113,8 +147,11 @@ type completionInput struct {
 // completionOutput a tea.Msg that wraps the content returned from the provider.
 type completionOutput struct {
 	content string
-	stream  stream.Stream
-	errh    func(error) tea.Msg
+	// toolText is set when content reports tool calls rather than being
+	// part of the answer.
+	toolText bool
+	stream   stream.Stream
+	errh     func(error) tea.Msg
 }
 
 type renderOutputMsg struct{}
@@ -123,9 +160,13 @@ type renderOutputMsg struct{}
 func (m *Yai) Init() tea.Cmd {
 	cmds := []tea.Cmd{m.readStdinCmd}
 	if !m.Config.Quiet {
-		m.anim = newAnim(m.Config.Fanciness, m.Config.StatusText, m.renderer, m.Styles)
+		m.anim = newAnim(m.Config, m.renderer, m.Styles)
 		cmds = append(cmds, m.anim.Init())
 	}
+	if m.showProgress() {
+		m.progress = newProgress(os.Stderr, m.Styles.Comment.Render)
+		cmds = append(cmds, m.progress.tick())
+	}
 	return tea.Batch(cmds...)
 }
 
@@ -139,6 +180,19 @@ func (m *Yai) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case completionOutput:
 		return m.handleCompletionOutput(msg)
 
+	case progressTickMsg:
+		if m.state == doneState || m.state == errorState {
+			return m, nil
+		}
+		m.progress.draw()
+		return m, m.progress.tick()
+
+	case toolProgressMsg:
+		return m.handleToolProgress(proto.ToolProgress(msg))
+
+	case approvalMsg:
+		return m.handleApproval(msg)
+
 	case renderOutputMsg:
 		m.renderScheduled = false
 		if m.dirtyOutput {
@@ -146,30 +200,28 @@ func (m *Yai) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case errs.Error:
-		e := msg
-		m.Error = &e
-		m.state = errorState
-		return m, m.quit
+		return m.fail(msg)
 	case error:
-		e := errs.Error{Err: msg}
-		m.Error = &e
-		m.state = errorState
-		return m, m.quit
+		return m.fail(errs.Error{Err: msg})
 
 	case tea.WindowSizeMsg:
 		m.width, m.height = msg.Width, msg.Height
 		m.glamViewport.Width = m.width
 		m.glamViewport.Height = m.height
+		m.glam.resize(m.width)
 		if m.shouldRenderFormattedOutput() && m.outputBuf.Len() > 0 {
 			m.renderFormattedOutput()
 		}
 		return m, nil
 	case tea.KeyMsg:
+		if m.approval != nil {
+			if allow, ok := approvalAnswer(msg); ok {
+				return m.answerApproval(allow)
+			}
+		}
 		switch msg.String() {
 		case "q", "ctrl+c":
-			m.closeActiveStream()
-			m.state = doneState
-			return m, m.quit
+			return m.cancel()
 		}
 	}
 	if !m.Config.Quiet && m.state == requestState {
@@ -187,6 +239,47 @@ func (m *Yai) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// fail ends the run with e, after writing out what was received.
+func (m *Yai) fail(e errs.Error) (tea.Model, tea.Cmd) {
+	// SIGINT also cancels the context, so the request may fail before the
+	// program is told about the signal.
+	if errors.Is(e, context.Canceled) && m.ctx.Err() != nil {
+		return m.cancel()
+	}
+	m.Error = &e
+	m.progress.clear()
+	if !agent.Rejected(e) {
+		m.releaseHeld()
+		m.keepPartial()
+	}
+	m.flushBufferedContent()
+	m.emit(event{Type: eventError, Message: errorText(e)})
+	m.state = errorState
+	return m, m.quit
+}
+
+// cancel stops the run at the user's request. Everything received so far
+// is written out, so a pipe gets the whole partial response.
+func (m *Yai) cancel() (tea.Model, tea.Cmd) {
+	if m.approval != nil {
+		m.approval.reply <- false
+		m.approval = nil
+	}
+	m.closeActiveStream()
+	m.progress.clear()
+	m.releaseHeld()
+	m.keepPartial()
+	m.Output = m.outputBuf.String()
+	m.flushBufferedContent()
+	if m.shouldRenderFormattedOutput() && m.dirtyOutput {
+		m.renderFormattedOutput()
+	}
+	m.Canceled = true
+	m.writeDoneEvent()
+	m.state = doneState
+	return m, m.quit
+}
+
 func (m *Yai) handleCompletionInput(msg completionInput) (tea.Model, tea.Cmd) {
 	if msg.content != "" {
 		m.Input = present.RemoveWhitespace(msg.content)
@@ -207,11 +300,62 @@ func (m *Yai) handleCompletionInput(msg completionInput) (tea.Model, tea.Cmd) {
 		m.appendToOutput(strings.Join(parts, "\n") + "\n")
 	}
 	m.state = requestState
+	m.response.Reset()
+	if m.toolProgress == nil {
+		m.toolProgress = newToolProgress()
+		cmds := []tea.Cmd{m.startCompletionCmd(msg.content), m.toolProgress.wait()}
+		if m.approvals = newApprovals(m.Config); m.approvals != nil {
+			cmds = append(cmds, m.approvals.wait())
+		}
+		return m, tea.Batch(cmds...)
+	}
 	return m, m.startCompletionCmd(msg.content)
 }
 
+// handleApproval asks the user msg's question: below the spinner or the
+// response, or on stderr when the response goes to a pipe.
+func (m *Yai) handleApproval(msg approvalMsg) (tea.Model, tea.Cmd) {
+	if m.state == doneState || m.state == errorState {
+		msg.reply <- false
+		return m, nil
+	}
+	m.approval = &msg
+	if !present.IsOutputTTY() {
+		m.progress.clear()
+		fmt.Fprintln(os.Stderr, m.Styles.Comment.Render(msg.question))
+	}
+	return m, nil
+}
+
+// answerApproval answers the question waiting for the user.
+func (m *Yai) answerApproval(allow bool) (tea.Model, tea.Cmd) {
+	m.approval.reply <- allow
+	m.approval = nil
+	return m, m.approvals.wait()
+}
+
+// handleToolProgress shows the progress a running tool reported: below the
+// spinner or the response, on the progress line, or as an event.
+func (m *Yai) handleToolProgress(p proto.ToolProgress) (tea.Model, tea.Cmd) {
+	if m.state == doneState || m.state == errorState {
+		return m, nil
+	}
+	m.toolStatus = p.String()
+	m.emit(event{Type: eventProgress, Name: p.Name, Message: m.toolStatus})
+	m.progress.setTool(m.toolStatus)
+	return m, m.toolProgress.wait()
+}
+
 func (m *Yai) handleCompletionOutput(msg completionOutput) (tea.Model, tea.Cmd) {
+	m.toolStatus = ""
 	if msg.stream == nil {
+		m.progress.clear()
+		if m.Postprocess != nil {
+			if err := m.postprocess(); err != nil {
+				m.response.Reset()
+				return m.fail(errs.Wrap(err, "Could not post-process the answer."))
+			}
+		}
 		m.Output = m.outputBuf.String()
 		if !present.IsOutputTTY() || m.Config.Raw {
 			m.flushBufferedContent()
@@ -219,18 +363,32 @@ func (m *Yai) handleCompletionOutput(msg completionOutput) (tea.Model, tea.Cmd)
 		if m.shouldRenderFormattedOutput() && m.dirtyOutput {
 			m.renderFormattedOutput()
 		}
+		m.writeDoneEvent()
 		m.state = doneState
 		return m, m.quit
 	}
 
 	var cmds []tea.Cmd
 	if msg.content != "" {
-		if m.state == requestState && !m.streamStartedAt.IsZero() && !m.Config.Quiet {
+		if m.state == requestState && m.response.Len() == 0 && !m.streamStartedAt.IsZero() && !m.Config.Quiet {
 			ttft := time.Since(m.streamStartedAt)
+			m.progress.clear()
 			fmt.Fprintln(os.Stderr, m.Styles.Comment.Render(fmt.Sprintf(ttftFormat, ttft.Milliseconds())))
 		}
-		m.appendToOutput(msg.content)
-		m.state = responseState
+		switch {
+		case m.Postprocess != nil:
+			// Held in response until the answer is complete; the spinner
+			// keeps going meanwhile.
+		case msg.toolText:
+			m.appendToOutput(msg.content)
+		default:
+			m.writeChunk(msg.content)
+		}
+		m.response.WriteString(msg.content)
+		m.progress.received(msg.content)
+		if m.Postprocess == nil {
+			m.state = responseState
+		}
 		if m.shouldRenderFormattedOutput() && m.dirtyOutput && !m.renderScheduled {
 			m.renderScheduled = true
 			cmds = append(cmds, m.renderOutputCmd())
@@ -255,37 +413,61 @@ func (m *Yai) View() string {
 		return ""
 	case requestState:
 		if !m.Config.Quiet {
-			return m.anim.View()
+			return m.anim.View() + m.toolStatusView()
+		}
+		if m.approval != nil {
+			return m.toolStatusView()
 		}
 	case responseState:
 		if !m.Config.Raw && present.IsOutputTTY() {
 			if m.viewportNeeded() {
-				return m.glamViewport.View()
+				return m.glamViewport.View() + m.toolStatusView()
 			}
 			// We don't need the viewport yet.
-			return m.glamOutput
+			return m.glamOutput + m.toolStatusView()
 		}
 
 		if present.IsOutputTTY() && !m.Config.Raw {
 			return m.Output
 		}
-
-		m.flushBufferedContent()
 	case doneState:
-		if !present.IsOutputTTY() {
-			fmt.Printf("\n")
+		if !present.IsOutputTTY() && !m.JSONEvents {
+			fmt.Fprint(m.stdout(), "\n")
 		}
 		return ""
 	}
 	return ""
 }
 
+// InterruptFilter turns SIGINT, which Bubble Tea receives instead of a
+// Ctrl+C key press when input is not a terminal, into that key press, so the
+// run is torn down the same way. Use it with tea.WithFilter.
+func InterruptFilter(_ tea.Model, msg tea.Msg) tea.Msg {
+	if _, ok := msg.(tea.InterruptMsg); ok {
+		return tea.KeyMsg{Type: tea.KeyCtrlC}
+	}
+	return msg
+}
+
+// toolStatusView is the line showing the progress of the running tool, if
+// it reported any, or the sampling request waiting for the user.
+func (m *Yai) toolStatusView() string {
+	if m.approval != nil {
+		return "\n" + m.Styles.Comment.Render(m.approval.question)
+	}
+	if m.toolStatus == "" || m.Config.Quiet {
+		return ""
+	}
+	return "\n" + m.Styles.Comment.Render(m.toolStatus)
+}
+
 func (m *Yai) quit() tea.Msg {
 	return tea.Quit()
 }
 
-func (m *Yai) retry(content string, err errs.Error) tea.Msg {
-	return retryOrFail(m.ctx, &m.retries, m.Config.MaxRetries, err, content, func(s string) tea.Msg {
+func (m *Yai) retry(content string, action agent.StreamErrorAction) tea.Msg {
+	m.emit(event{Type: eventRetry, Message: errorText(action.Err)})
+	return retryOrFail(m.ctx, m.retries, action, content, func(s string) tea.Msg {
 		return completionInput{s}
 	})
 }
@@ -306,14 +488,20 @@ func (m *Yai) startCompletionCmd(content string) tea.Cmd {
 			func(cancel context.CancelFunc) { m.activeCancel = cancel },
 			func(st stream.Stream) { m.activeStream = st },
 			func(ctx context.Context) (agent.StreamStart, error) {
-				return m.startStreamFn(ctx, content)
+				res, err := m.startStreamFn(m.approvals.with(agent.WithToolProgress(ctx, m.toolProgress.report)), content)
+				if err == nil && m.progress != nil {
+					res.Stream = m.progress.watch(res.Stream)
+				}
+				return res, err
 			},
 		)
 		if err != nil {
 			return streamStartErrorMsg(err)
 		}
 		m.messages = res.Messages
+		m.sent = len(res.Messages)
 		mod := res.Model
+		m.model = mod
 
 		warnIgnoredStop(m.Config.Stop, m.Config.Quiet, &m.stopWarned, m.emitWarning)
 		warnMCPDisabledForNonTTY(m.Config, &m.mcpNonTTYWarned, m.emitWarning)
@@ -334,35 +522,121 @@ func (m *Yai) receiveCompletionStreamCmd(msg completionOutput) tea.Cmd {
 		func(content string, st stream.Stream, errh func(error) tea.Msg) tea.Msg {
 			return completionOutput{content: content, stream: st, errh: errh}
 		},
+		func(results []proto.ToolCallStatus, st stream.Stream, errh func(error) tea.Msg) tea.Msg {
+			if m.JSONEvents || len(m.Sinks) > 0 {
+				for _, ev := range toolEvents(st, results) {
+					m.emit(ev)
+				}
+			}
+			if !m.JSONEvents {
+				return completionOutput{content: toolCallsContent(results), toolText: true, stream: st, errh: errh}
+			}
+			return completionOutput{stream: st, errh: errh}
+		},
 		func(messages []proto.Message) tea.Msg {
 			m.messages = messages
+			m.usage = msg.stream.Usage()
+			recordResponseMeta(m.Config, msg.stream.ResponseMeta())
 			return completionOutput{errh: msg.errh}
 		},
 	)
 }
 
 func (m *Yai) readStdinCmd() tea.Msg {
-	if !present.IsInputTTY() {
-		reader := io.Reader(bufio.NewReader(os.Stdin))
+	stdin := m.Stdin
+	if stdin == nil && !present.IsInputTTY() {
+		stdin = os.Stdin
+	}
+	if stdin != nil {
+		var input string
+		var err error
 		if !m.Config.NoLimit && m.Config.MaxInputChars > 0 {
-			// Read at most MaxInputChars bytes (+1 sentinel) so we never OOM on huge pipes.
-			reader = io.LimitReader(reader, m.Config.MaxInputChars+1)
+			// Keep at most MaxInputChars bytes, from the beginning and end, so we
+			// never OOM on huge pipes.
+			input, err = requestbuilder.ReadTruncated(stdin, m.Config.MaxInputChars, requestbuilder.HeadRatio(m.Config))
+		} else {
+			var data []byte
+			data, err = io.ReadAll(bufio.NewReader(stdin))
+			input = string(data)
 		}
-		stdinBytes, err := io.ReadAll(reader)
 		if err != nil {
 			return errs.Wrap(err, "Unable to read stdin.")
 		}
-		if !m.Config.NoLimit && m.Config.MaxInputChars > 0 && int64(len(stdinBytes)) > m.Config.MaxInputChars {
-			stdinBytes = stdinBytes[:m.Config.MaxInputChars]
-		}
 
-		return completionInput{increaseIndent(string(stdinBytes))}
+		return completionInput{increaseIndent(input)}
 	}
 	return completionInput{""}
 }
 
 const tabWidth = 4
 
+func (m *Yai) stdout() io.Writer {
+	if m.Stdout != nil {
+		return m.Stdout
+	}
+	return os.Stdout
+}
+
+// keepPartial records the response streamed so far as an unfinished
+// assistant message when a request is cut off mid-response.
+func (m *Yai) keepPartial() {
+	if m.response.Len() == 0 {
+		return
+	}
+	m.messages = agent.WithPartial(m.messages, m.response.String())
+	m.response.Reset()
+}
+
+// postprocess writes out the answer as Postprocess rewrites it, which is
+// also what is saved. Only the last answer is kept: the text of steps
+// before tool calls is dropped.
+func (m *Yai) postprocess() error {
+	n := len(m.messages)
+	if n == 0 || m.messages[n-1].Role != proto.RoleAssistant {
+		return nil
+	}
+	out, err := m.Postprocess(m.ctx, m.messages[n-1].Content)
+	if err != nil {
+		return err
+	}
+	m.messages = slices.Clone(m.messages)
+	m.messages[n-1].Content = out
+	m.response.Reset()
+	m.state = responseState
+	m.writeChunk(out)
+	return nil
+}
+
+// releaseHeld writes out the answer held back for Postprocess as it was
+// received, for a run that is cut off.
+func (m *Yai) releaseHeld() {
+	if m.Postprocess == nil || m.response.Len() == 0 {
+		return
+	}
+	m.state = responseState
+	m.writeChunk(m.response.String())
+}
+
+// writeChunk writes a piece of the answer: as an event or text on stdout,
+// and to the sinks.
+func (m *Yai) writeChunk(content string) {
+	m.emit(event{Type: eventChunk, Content: content})
+	if !m.JSONEvents {
+		m.appendToOutput(content)
+	}
+}
+
+// emit writes ev to stdout when the output is JSON events, and to the
+// sinks.
+func (m *Yai) emit(ev event) {
+	if m.JSONEvents {
+		writeEvent(m.stdout(), ev)
+	}
+	for _, s := range m.Sinks {
+		s.write(ev)
+	}
+}
+
 func (m *Yai) closeActiveStream() {
 	closeStream(m.activeStream, m.activeCancel)
 	m.activeStream = nil
@@ -370,9 +644,43 @@ func (m *Yai) closeActiveStream() {
 }
 
 func (m *Yai) emitWarning(message string) {
+	m.emit(event{Type: eventWarning, Message: message})
+	if m.JSONEvents {
+		return
+	}
+	m.progress.clear()
 	emitCommentWarning(m.Styles.Comment.Render, message)
 }
 
+// writeDoneEvent ends the JSON events with the log probabilities and the
+// usage, when the provider reported them, and the done event.
+func (m *Yai) writeDoneEvent() {
+	if !m.JSONEvents && len(m.Sinks) == 0 {
+		return
+	}
+	for _, msg := range m.messages[min(m.sent, len(m.messages)):] {
+		if msg.Role == proto.RoleAssistant && len(msg.Logprobs) > 0 {
+			m.emit(event{Type: eventLogprobs, Logprobs: proto.NewLogprobsJSON(msg.Logprobs)})
+		}
+	}
+	if m.usage != (proto.Usage{}) {
+		usage := proto.NewUsageJSON(m.usage)
+		m.emit(event{Type: eventUsage, Usage: &usage})
+	}
+	done := event{
+		Type:         eventDone,
+		API:          m.model.API,
+		Model:        m.model.Name,
+		RequestID:    m.Config.RequestID,
+		ModelVersion: m.Config.ModelVersion,
+		Canceled:     m.Canceled,
+	}
+	if !m.Config.NoCache {
+		done.Conversation = m.Config.CacheWriteToID
+	}
+	m.emit(done)
+}
+
 func (m *Yai) outputStringForRender() string {
 	if m.outputBuf.Len() == 0 {
 		return ""
@@ -389,6 +697,7 @@ func (m *Yai) appendToOutput(s string) {
 		m.contentMutex.Lock()
 		m.content = append(m.content, s)
 		m.contentMutex.Unlock()
+		m.flushLines()
 		return
 	}
 
@@ -409,15 +718,42 @@ func (m *Yai) appendToOutput(s string) {
 	m.dirtyOutput = true
 }
 
+// flushLines writes the buffered output up to its last complete line as it
+// arrives, so consumers reading a pipe line by line see each line as soon as
+// it ends. A terminal gets everything right away.
+func (m *Yai) flushLines() {
+	m.contentMutex.Lock()
+	defer m.contentMutex.Unlock()
+	pending := strings.Join(m.content, "")
+	n := len(pending)
+	if !present.IsOutputTTY() {
+		n = strings.LastIndexByte(pending, '\n') + 1
+	}
+	if n == 0 {
+		return
+	}
+	fmt.Fprint(m.stdout(), pending[:n])
+	m.content = m.content[:0]
+	if rest := pending[n:]; rest != "" {
+		m.content = append(m.content, rest)
+	}
+}
+
 func (m *Yai) flushBufferedContent() {
 	m.contentMutex.Lock()
 	defer m.contentMutex.Unlock()
 	for _, c := range m.content {
-		fmt.Print(c)
+		fmt.Fprint(m.stdout(), c)
 	}
 	m.content = []string{}
 }
 
+// showProgress reports whether to draw the progress line: the response goes
+// to a pipe, so there is no spinner, but stderr is a terminal.
+func (m *Yai) showProgress() bool {
+	return !m.Config.Quiet && !present.IsOutputTTY() && present.IsErrorTTY()
+}
+
 func (m Yai) shouldRenderFormattedOutput() bool {
 	return present.IsOutputTTY() && !m.Config.Raw
 }
@@ -434,12 +770,11 @@ func (m *Yai) renderFormattedOutput() {
 	m.glamOutput, _ = m.glam.Render(m.outputStringForRender())
 	m.glamOutput = strings.TrimRightFunc(m.glamOutput, unicode.IsSpace)
 	m.glamOutput = strings.ReplaceAll(m.glamOutput, "\t", strings.Repeat(" ", tabWidth))
+	// Lines glamour does not break, such as code, are wrapped too.
+	m.glamOutput = present.Wrap(m.glamOutput, m.width)
 	m.glamHeight = lipgloss.Height(m.glamOutput)
 	m.glamOutput += "\n"
-	truncatedGlamOutput := m.renderer.NewStyle().
-		MaxWidth(m.width).
-		Render(m.glamOutput)
-	m.glamViewport.SetContent(truncatedGlamOutput)
+	m.glamViewport.SetContent(m.glamOutput)
 	if oldHeight < m.glamHeight && wasAtBottom {
 		// If the viewport's at the bottom and we've received a new
 		// line of content, follow the output by auto scrolling to
@@ -6,8 +6,10 @@ import (
 	"sync"
 	"testing"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/dotcommander/yai/internal/config"
 	"github.com/dotcommander/yai/internal/present"
+	"github.com/dotcommander/yai/internal/proto"
 	"github.com/stretchr/testify/require"
 )
 
@@ -34,6 +36,47 @@ func TestUpdateFlushesBufferedContentForRawOutput(t *testing.T) {
 	require.Equal(t, doneState, m.state)
 }
 
+func TestRawOutputIsWrittenLineByLine(t *testing.T) {
+	m := &Yai{Config: &config.Config{Settings: config.Settings{Raw: true}}, contentMutex: &sync.Mutex{}}
+	m.state = responseState
+
+	output := captureStdout(t, func() {
+		m.appendToOutput("one\ntw")
+	})
+	require.Equal(t, "one\n", output, "complete lines are written as they arrive")
+	require.Empty(t, m.View())
+
+	output = captureStdout(t, func() {
+		m.appendToOutput("o\nthr")
+		m.appendToOutput("ee")
+	})
+	require.Equal(t, "two\n", output)
+
+	output = captureStdout(t, func() {
+		_, _ = m.Update(completionOutput{})
+	})
+	require.Equal(t, "three", output, "the last line is written when the answer ends")
+}
+
+func TestInterruptFlushesBufferedContent(t *testing.T) {
+	m := &Yai{Config: &config.Config{Settings: config.Settings{Raw: true}}, contentMutex: &sync.Mutex{}}
+	m.state = responseState
+	m.messages = []proto.Message{{Role: proto.RoleUser, Content: "count to ten"}}
+	m.content = []string{"one, ", "two, "}
+	m.response.WriteString("one, two, ")
+
+	msg := InterruptFilter(m, tea.InterruptMsg{})
+	output := captureStdout(t, func() {
+		_, _ = m.Update(msg)
+	})
+
+	require.Equal(t, "one, two, ", output)
+	require.True(t, m.Canceled)
+	require.Nil(t, m.Error)
+	require.True(t, m.Interrupted())
+	require.Equal(t, doneState, m.state)
+}
+
 func captureStdout(t *testing.T, fn func()) string {
 	t.Helper()
 
@@ -2,6 +2,8 @@
 package main
 
 import (
+	"os"
+
 	"github.com/dotcommander/yai/internal/cmd"
 	"github.com/dotcommander/yai/internal/config"
 )
@@ -15,6 +17,6 @@ var (
 )
 
 func main() {
-	cfg, cfgErr := config.Ensure()
+	cfg, cfgErr := config.Ensure(config.FlagArg(os.Args[1:], "config"), config.FlagArg(os.Args[1:], "workspace"))
 	cmd.Execute(cmd.BuildInfo{Version: Version, CommitSHA: CommitSHA}, cfg, cfgErr)
 }